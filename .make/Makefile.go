@@ -49,6 +49,10 @@ test: ## Runs vet, lint and ALL tests
 	@$(MAKE) lint
 	@go test ./... -v
 
+test-race: ## Runs vet and ALL tests with the race detector (concurrent events must not share state)
+	@$(MAKE) vet
+	@go test ./... -race
+
 test-short: ## Runs vet, lint and tests (excludes integration tests)
 	@$(MAKE) vet
 	@$(MAKE) lint
@@ -0,0 +1,41 @@
+/*
+Package main is statusctl, the CLI to post the statuses of the pipelines again by hand or in CI
+(IE: after an outage or a misconfigured token), with the same environment variables as the function
+
+	statusctl sync --pipeline X --execution-id Y
+	statusctl backfill --pipeline X --since 24h
+	statusctl replay --max 500
+
+The AWS profile of a laptop is used (AWS_PROFILE, its region and SSO), paths such as ~/environments.yml are
+expanded in PowerShell and cmd.exe too
+
+More information: https://github.com/mrz1836/codepipeline-to-github
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mrz1836/codepipeline-to-github/pkg/pipelinestatus"
+)
+
+// usage is printed without a command
+const usage = `usage: statusctl <command> [flags]
+
+commands:
+  sync      post the status of an execution again (--pipeline X --execution-id Y)
+  backfill  post the statuses of the recent executions of a pipeline again (--pipeline X --since 24h)
+  replay    process the events of the dead-letter queue again after an outage (--max 500)`
+
+// Run the command of the arguments
+func main() {
+	if len(os.Args) < 2 {
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := pipelinestatus.RunCommand(os.Args[1], os.Args[2:], os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
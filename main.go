@@ -0,0 +1,13 @@
+/*
+Package main is the CodePipeline status event receiver, the function is the pipelinestatus package
+
+More information: https://github.com/mrz1836/codepipeline-to-github
+*/
+package main
+
+import "github.com/mrz1836/codepipeline-to-github/pkg/pipelinestatus"
+
+// Start the lambda event handler (or run a command: status permissions)
+func main() {
+	pipelinestatus.Main()
+}
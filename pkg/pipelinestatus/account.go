@@ -0,0 +1,149 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+)
+
+// accountConfig is the configuration of a member account whose events arrive on a central event bus
+type accountConfig struct {
+	ContextPrefix     string `json:"context_prefix"`
+	GithubAccessToken string `json:"github_access_token"`
+	RoleARN           string `json:"role_arn"`
+}
+
+// accountMap is a map of account ids to their configuration (IE: {"123456789012":{"role_arn":"..."}})
+type accountMap map[string]accountConfig
+
+// Decode will parse the map from a JSON object
+func (m *accountMap) Decode(value string) error {
+	return json.Unmarshal([]byte(value), (*map[string]accountConfig)(m))
+}
+
+// assumeRole will return the CodePipeline and CloudTrail services of a member account using the role (cached per
+// container to reuse the assumed role credentials)
+func (c *containerCache) assumeRole(awsSession *session.Session, awsConfig awsv2.Config, roleARN string) Dependencies {
+	c.accountsMu.Lock()
+	defer c.accountsMu.Unlock()
+	if deps, ok := c.accounts[roleARN]; ok {
+		return deps
+	}
+	creds := stscreds.NewCredentials(awsSession, roleARN)
+	deps := Dependencies{
+		CloudTrail:   cloudtrail.New(awsSession, &aws.Config{Credentials: creds}),
+		CodePipeline: codepipeline.NewFromConfig(assumeRoleConfig(awsConfig, roleARN)),
+	}
+	c.accounts[roleARN] = deps
+	return deps
+}
+
+// decryptAccounts will return the accounts with their tokens decrypted (encrypted like the default token), the
+// tokens are decrypted once with the configuration so they are redacted from the log lines like it
+func (c *containerCache) decryptAccounts(ctx context.Context, kmsSvc KMSAPI, accounts accountMap) (decrypted accountMap, err error) {
+	if len(accounts) == 0 {
+		return accounts, nil
+	}
+	decrypted = make(accountMap, len(accounts))
+	for accountID, account := range accounts {
+		if len(account.GithubAccessToken) > 0 {
+			if account.GithubAccessToken, err = c.decryptString(ctx, kmsSvc, account.GithubAccessToken); err != nil {
+				return nil, err
+			}
+		}
+		decrypted[accountID] = account
+	}
+	return
+}
+
+// pipelineRoleARN will return the role of the account of a pipeline: the role of the pipeline (PIPELINE_CONFIG), or
+// ASSUME_ROLE_ARN unless the account of the event has its own role (ACCOUNTS)
+func (h *Handler) pipelineRoleARN(accountID, pipelineName string) string {
+	if roleARN := h.pipelines[pipelineName].RoleARN; len(roleARN) > 0 {
+		return roleARN
+	} else if len(h.cfg.Accounts[accountID].RoleARN) > 0 {
+		return ""
+	}
+	return h.cfg.AssumeRoleARN
+}
+
+// forPipeline will return a handler reading the pipeline with the role of its account (pipelines of the account
+// of the function use the defaults)
+func (h *Handler) forPipeline(accountID, pipelineName string) (*Handler, error) {
+	roleARN := h.pipelineRoleARN(accountID, pipelineName)
+	if len(roleARN) == 0 {
+		return h, nil
+	} else if h.deps.AssumeRole == nil {
+		return nil, fmt.Errorf("unable to assume role %s for pipeline %s: missing dependency: AssumeRole", roleARN, pipelineName)
+	}
+
+	// New handler so the defaults are untouched
+	pipelineHandler := Handler{
+		accountContextPrefix: h.accountContextPrefix,
+		cfg:                  h.cfg,
+		deps:                 h.deps,
+		githubURL:            h.githubURL,
+		logSettings:          h.logSettings,
+		pipelines:            h.pipelines,
+	}
+	member := h.deps.AssumeRole(roleARN)
+	pipelineHandler.deps.CloudTrail = member.CloudTrail
+	pipelineHandler.deps.CodePipeline = member.CodePipeline
+	return &pipelineHandler, nil
+}
+
+// forExecution will return the handler of a pipeline event: the configuration of the account that sent it, the
+// role of the account and the region of the pipeline
+func (h *Handler) forExecution(ctx context.Context, accountID, region, pipelineName string) (*Handler, error) {
+	account, err := h.forAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := account.forPipeline(accountID, pipelineName)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.forRegion(accountID, region, pipelineName)
+}
+
+// forAccount will return a handler for the account of an event: the role, token and context prefix
+// of the account replace the defaults (events from unknown accounts are rejected once ACCOUNTS is set)
+func (h *Handler) forAccount(ctx context.Context, accountID string) (*Handler, error) {
+	if len(h.cfg.Accounts) == 0 {
+		return h, nil
+	}
+	account, ok := h.cfg.Accounts[accountID]
+	if !ok {
+		return nil, &permanentError{err: fmt.Errorf("account %s is not configured", accountID)}
+	}
+
+	// New handler so the defaults are untouched (the GitHub version and token expiry of the default handler are
+	// written by concurrent events and are not copied, the container caches are shared through the dependencies)
+	accountHandler := Handler{cfg: h.cfg, deps: h.deps, githubURL: h.githubURL, logSettings: h.logSettings, pipelines: h.pipelines}
+
+	// Use the services of the member account
+	if len(account.RoleARN) > 0 {
+		if h.deps.AssumeRole == nil {
+			return nil, fmt.Errorf("unable to assume role %s for account %s: missing dependency: AssumeRole", account.RoleARN, accountID)
+		}
+		member := h.deps.AssumeRole(account.RoleARN)
+		accountHandler.deps.CloudTrail = member.CloudTrail
+		accountHandler.deps.CodePipeline = member.CodePipeline
+	}
+
+	// Use the token of the account (decrypted with the configuration)
+	if len(account.GithubAccessToken) > 0 {
+		accountHandler.cfg.GithubAccessToken = account.GithubAccessToken
+	}
+
+	// The account prefix is used for pipelines without their own prefix
+	accountHandler.accountContextPrefix = account.ContextPrefix
+	return &accountHandler, nil
+}
@@ -0,0 +1,178 @@
+package pipelinestatus
+
+import (
+	"context"
+	"testing"
+)
+
+// TestAccountMapDecode will test accountMap.Decode()
+func TestAccountMapDecode(t *testing.T) {
+	var accounts accountMap
+	if err := accounts.Decode(`{"123456789012":{"role_arn":"arn:aws:iam::123456789012:role/status","context_prefix":"team-a/ci"}}`); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if accounts["123456789012"].RoleARN != "arn:aws:iam::123456789012:role/status" {
+		t.Fatal("role was not as expected", accounts["123456789012"].RoleARN)
+	} else if accounts["123456789012"].ContextPrefix != "team-a/ci" {
+		t.Fatal("context prefix was not as expected", accounts["123456789012"].ContextPrefix)
+	}
+
+	if err := accounts.Decode(`not-json`); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestNewHandlerAccounts will test NewHandler() decrypting the tokens of the accounts (redacted from the log lines)
+func TestNewHandlerAccounts(t *testing.T) {
+	accounts := accountMap{
+		"111111111111": {},
+		"222222222222": {GithubAccessToken: "dGVzdC10b2tlbi12YWx1ZQ=="},
+	}
+	h, err := NewHandler(Config{Accounts: accounts, GithubAccessToken: "dGVzdC10b2tlbi12YWx1ZQ==", Stage: stageProduction},
+		newTestHandler(Config{}).deps)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if h.cfg.Accounts["222222222222"].GithubAccessToken != "some-encrypted-text" {
+		t.Fatal("token was not decrypted", h.cfg.Accounts["222222222222"].GithubAccessToken)
+	} else if len(h.cfg.Accounts["111111111111"].GithubAccessToken) > 0 {
+		t.Fatal("account without a token should not have one", h.cfg.Accounts["111111111111"])
+	} else if accounts["222222222222"].GithubAccessToken != "dGVzdC10b2tlbi12YWx1ZQ==" {
+		t.Fatal("accounts of the configuration should not have changed", accounts)
+	}
+
+	// Token of the account is redacted from the log lines
+	if redacted := redactLog(h.withSettings(context.Background()), "token some-encrypted-text"); redacted != "token "+redactedText {
+		t.Fatal("token was not redacted", redacted)
+	} else if secrets := configSecrets(Config{Accounts: accountMap{"222222222222": {GithubAccessToken: "account-token"}}}); len(secrets) != 1 ||
+		secrets[0] != "account-token" {
+		t.Fatal("secrets were not as expected", secrets)
+	}
+}
+
+// TestForAccount will test forAccount()
+func TestForAccount(t *testing.T) {
+	memberPipeline := &mockCodePipelineClient{}
+	var assumed []string
+
+	h := newTestHandler(Config{GithubAccessToken: "default-token", Stage: stageProduction})
+	h.deps.AssumeRole = func(roleARN string) Dependencies {
+		assumed = append(assumed, roleARN)
+		return Dependencies{CloudTrail: &mockCloudTrailClient{}, CodePipeline: memberPipeline}
+	}
+
+	// No accounts configured
+	if accountHandler, err := h.forAccount(context.Background(), "123456789012"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if accountHandler != h {
+		t.Fatal("handler should not have changed")
+	}
+
+	h.cfg.Accounts = accountMap{
+		"111111111111": {},
+		"222222222222": {
+			ContextPrefix:     "team-b/ci",
+			GithubAccessToken: "dGVzdC10b2tlbi12YWx1ZQ==",
+			RoleARN:           "arn:aws:iam::222222222222:role/status",
+		},
+	}
+
+	// Account with the defaults
+	accountHandler, err := h.forAccount(context.Background(), "111111111111")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if accountHandler.cfg.GithubAccessToken != "default-token" {
+		t.Fatal("token was not as expected", accountHandler.cfg.GithubAccessToken)
+	} else if len(assumed) > 0 {
+		t.Fatal("no role should have been assumed", assumed)
+	}
+
+	// Account with its own role, token and prefix
+	if accountHandler, err = h.forAccount(context.Background(), "222222222222"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if accountHandler.cfg.GithubAccessToken != "dGVzdC10b2tlbi12YWx1ZQ==" {
+		t.Fatal("token was not as expected", accountHandler.cfg.GithubAccessToken)
+	} else if accountHandler.deps.CodePipeline != memberPipeline {
+		t.Fatal("pipeline service should be from the member account")
+	} else if len(assumed) != 1 || assumed[0] != "arn:aws:iam::222222222222:role/status" {
+		t.Fatal("role was not as expected", assumed)
+	} else if h.cfg.GithubAccessToken != "default-token" {
+		t.Fatal("default token should not have changed", h.cfg.GithubAccessToken)
+	}
+
+	// Prefix of the account is used for the status context
+	var accountContext string
+	if accountContext, err = accountHandler.statusContext(context.Background(), "search", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if accountContext != "team-b/ci/search" {
+		t.Fatal("context was not as expected", accountContext)
+	}
+
+	// Unknown account
+	if _, err = h.forAccount(context.Background(), "333333333333"); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "account 333333333333 is not configured" {
+		t.Fatal("error was not as expected", err.Error())
+	} else if !isPermanent(err) {
+		t.Fatal("error should be permanent", err.Error())
+	}
+
+	// Missing role dependency
+	h.deps.AssumeRole = nil
+	if _, err = h.forAccount(context.Background(), "222222222222"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestForPipeline will test forPipeline() assuming the role of the account of the pipeline
+func TestForPipeline(t *testing.T) {
+	memberPipeline := &mockCodePipelineClient{}
+	var assumed []string
+
+	h := newTestHandler(Config{GithubAccessToken: "default-token", Stage: stageProduction})
+	h.deps.AssumeRole = func(roleARN string) Dependencies {
+		assumed = append(assumed, roleARN)
+		return Dependencies{CloudTrail: &mockCloudTrailClient{}, CodePipeline: memberPipeline}
+	}
+
+	// No role configured
+	if pipelineHandler, err := h.forPipeline("123456789012", "payments"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if pipelineHandler != h || len(assumed) > 0 {
+		t.Fatal("handler should not have changed", assumed)
+	}
+
+	h.cfg.AssumeRoleARN = "arn:aws:iam::111111111111:role/status"
+	h.cfg.Accounts = accountMap{"222222222222": {RoleARN: "arn:aws:iam::222222222222:role/status"}}
+	h.pipelines = pipelineConfigs{"search": {RoleARN: "arn:aws:iam::333333333333:role/status"}}
+
+	var tests = []struct {
+		account  string
+		pipeline string
+		expected string
+	}{
+		{"123456789012", "payments", "arn:aws:iam::111111111111:role/status"},
+		{"222222222222", "payments", ""},
+		{"222222222222", "search", "arn:aws:iam::333333333333:role/status"},
+		{"123456789012", "search", "arn:aws:iam::333333333333:role/status"},
+	}
+	for _, test := range tests {
+		if output := h.pipelineRoleARN(test.account, test.pipeline); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.account, test.pipeline, test.expected, output)
+		}
+	}
+
+	// Role of the pipeline
+	pipelineHandler, err := h.forPipeline("123456789012", "search")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if pipelineHandler.deps.CodePipeline != memberPipeline || h.deps.CodePipeline == memberPipeline {
+		t.Fatal("pipeline service should be from the account of the pipeline")
+	} else if len(assumed) != 1 || assumed[0] != "arn:aws:iam::333333333333:role/status" {
+		t.Fatal("role was not as expected", assumed)
+	}
+
+	// Missing role dependency
+	h.deps.AssumeRole = nil
+	if _, err = h.forPipeline("123456789012", "payments"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
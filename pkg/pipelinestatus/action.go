@@ -0,0 +1,85 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ingestionModeAction runs the function as a Lambda invoke action inside the pipeline (INGESTION_MODE=action)
+const ingestionModeAction = "action"
+
+// actionStates are the states an action can report (UserParameters of the action, STARTED by default)
+var actionStates = map[string]string{
+	"FAILED":    githubStateFailure,
+	"STARTED":   githubStatePending,
+	"SUCCEEDED": githubStateSuccess,
+}
+
+// ProcessJobEvent is triggered by a Lambda invoke action of a pipeline
+func ProcessJobEvent(ctx context.Context, jobEvent events.CodePipelineEvent) error {
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessJob(ctx, jobEvent.CodePipelineJob)
+}
+
+// ProcessJob will update the GitHub commit status for the execution running the action and report the
+// result to CodePipeline (a failed status update fails the action)
+func (h *Handler) ProcessJob(ctx context.Context, job events.CodePipelineJob) error {
+	ctx = h.withSettings(ctx)
+	err := h.processJob(ctx, job)
+	if err != nil {
+		logErrorf(ctx, "unable to process job %s: %s", job.ID, err.Error())
+		_, err = h.deps.CodePipeline.PutJobFailureResult(ctx, &codepipeline.PutJobFailureResultInput{
+			FailureDetails: &types.FailureDetails{
+				Message: aws.String(joinDescription(err.Error())),
+				Type:    types.FailureTypeJobFailed,
+			},
+			JobId: aws.String(job.ID),
+		})
+		return err
+	}
+	_, err = h.deps.CodePipeline.PutJobSuccessResult(ctx, &codepipeline.PutJobSuccessResultInput{JobId: aws.String(job.ID)})
+	return err
+}
+
+// processJob will process the execution of the job as an event with the state of the action parameters
+func (h *Handler) processJob(ctx context.Context, job events.CodePipelineJob) error {
+
+	// The state of the action (IE: SUCCEEDED for an action at the end of the pipeline)
+	state := strings.ToUpper(strings.TrimSpace(job.Data.ActionConfiguration.Configuration.UserParameters))
+	if len(state) == 0 {
+		state = "STARTED"
+	}
+	githubStatus, ok := actionStates[state]
+	if !ok {
+		return fmt.Errorf("invalid action state: %s", state)
+	}
+
+	// Find the execution running the action
+	output, err := h.deps.CodePipeline.GetJobDetails(ctx, &codepipeline.GetJobDetailsInput{JobId: aws.String(job.ID)})
+	if err != nil {
+		return err
+	} else if output.JobDetails == nil || output.JobDetails.Data == nil || output.JobDetails.Data.PipelineContext == nil {
+		return fmt.Errorf("missing pipeline context for job: %s", job.ID)
+	}
+	pipelineContext := output.JobDetails.Data.PipelineContext
+
+	return h.ProcessEventWithContext(ctx, Event{
+		Account: job.AccountID,
+		Detail: &Detail{
+			ExecutionID:  aws.StringValue(pipelineContext.PipelineExecutionId),
+			Pipeline:     aws.StringValue(pipelineContext.PipelineName),
+			State:        state,
+			githubStatus: githubStatus,
+		},
+	})
+}
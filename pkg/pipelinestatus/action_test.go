@@ -0,0 +1,93 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockJobPipelineClient records the job results of a pipeline action
+type mockJobPipelineClient struct {
+	mockCodePipelineClient
+	failures  []string
+	successes []string
+}
+
+// GetJobDetails is a mock request for codepipeline (the job id is the pipeline name)
+func (m *mockJobPipelineClient) GetJobDetails(_ context.Context, input *codepipeline.GetJobDetailsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetJobDetailsOutput, error) {
+	if aws.StringValue(input.JobId) == "missing" {
+		return nil, fmt.Errorf("job not found")
+	}
+	return &codepipeline.GetJobDetailsOutput{JobDetails: &types.JobDetails{
+		Data: &types.JobData{PipelineContext: &types.PipelineContext{
+			PipelineExecutionId: aws.String("12345678"),
+			PipelineName:        input.JobId,
+		}},
+		Id: input.JobId,
+	}}, nil
+}
+
+// PutJobFailureResult is a mock request for codepipeline
+func (m *mockJobPipelineClient) PutJobFailureResult(_ context.Context, input *codepipeline.PutJobFailureResultInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.PutJobFailureResultOutput, error) {
+	m.failures = append(m.failures, aws.StringValue(input.JobId))
+	return &codepipeline.PutJobFailureResultOutput{}, nil
+}
+
+// PutJobSuccessResult is a mock request for codepipeline
+func (m *mockJobPipelineClient) PutJobSuccessResult(_ context.Context, input *codepipeline.PutJobSuccessResultInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.PutJobSuccessResultOutput, error) {
+	m.successes = append(m.successes, aws.StringValue(input.JobId))
+	return &codepipeline.PutJobSuccessResultOutput{}, nil
+}
+
+// TestHandlerProcessJob will test ProcessJob()
+func TestHandlerProcessJob(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	mockPipeline := &mockJobPipelineClient{}
+	h.deps.CodePipeline = mockPipeline
+
+	var received payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var tests = []struct {
+		jobID          string
+		userParameters string
+		expectedState  string
+		expectedResult string
+	}{
+		{"status-in-progress", "", githubStatePending, "success"},
+		{"status-in-progress", "succeeded", githubStateSuccess, "success"},
+		{"status-in-progress", "FAILED", githubStateFailure, "success"},
+		{"status-in-progress", "DEPLOYED", "", "failure"},
+		{"missing", "", "", "failure"},
+		{"nil", "", "", "failure"},
+	}
+
+	for _, test := range tests {
+		received = payload{}
+		mockPipeline.failures, mockPipeline.successes = nil, nil
+		job := events.CodePipelineJob{ID: test.jobID}
+		job.Data.ActionConfiguration.Configuration.UserParameters = test.userParameters
+		if err := h.ProcessJob(context.Background(), job); err != nil {
+			t.Errorf("%s Failed: [%s] [%s] inputted and error occurred: %s", t.Name(), test.jobID, test.userParameters, err.Error())
+		} else if received.State != test.expectedState {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected [%s] but got [%s]", t.Name(), test.jobID, test.userParameters, test.expectedState, received.State)
+		} else if test.expectedResult == "success" && len(mockPipeline.successes) != 1 {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected a job success", t.Name(), test.jobID, test.userParameters)
+		} else if test.expectedResult == "failure" && len(mockPipeline.failures) != 1 {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected a job failure", t.Name(), test.jobID, test.userParameters)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Artifact resolution failures (the Reason dimension of the metric and the code in the logs)
+const (
+	artifactBadSHA              = "BadSHA"
+	artifactNameMismatch        = "NameMismatch"
+	artifactNoArtifacts         = "NoArtifacts"
+	artifactNonGithubURL        = "NonGithubURL"
+	metricArtifactResolution    = "ArtifactResolutionFailure"
+	githubHost                  = "github.com"
+	revisionURLCommitPathLength = 5 // /owner/repo/commit/sha
+)
+
+// Forges hosting the code of the revision urls
+const (
+	azureGitPath      = "/_git/" // /organization/project/_git/repo/commit/sha
+	azureHost         = "dev.azure.com"
+	azureLegacyDomain = ".visualstudio.com" // organization.visualstudio.com/project/_git/repo/commit/sha
+	bitbucketHost     = "bitbucket.org"     // /workspace/repo/commits/sha
+	forgeAzureDevOps  = "azure-devops"
+	forgeBitbucket    = "bitbucket"
+	forgeGitea        = "gitea" // also Forgejo
+	forgeGithub       = "github"
+	forgeGitlab       = "gitlab"
+	gitlabCommitPath  = "/-/commit/" // /group/subgroup/project/-/commit/sha
+	gitlabHost        = "gitlab.com"
+)
+
+// commitSHA matches a full git commit sha
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// artifactError is a source artifact that cannot be resolved to a GitHub commit
+type artifactError struct {
+	Artifact string
+	Message  string
+	Reason   string
+}
+
+// Error will return the reason and message
+func (e *artifactError) Error() string {
+	artifactName := e.Artifact
+	if len(artifactName) == 0 {
+		artifactName = sourceArtifactName
+	}
+	return fmt.Sprintf("unable to resolve the %s artifact [%s]: %s", artifactName, e.Reason, e.Message)
+}
+
+// primaryArtifact will return the name of the source artifact the statuses are posted for
+// (pipelines building several branches pick theirs in PRIMARY_ARTIFACTS)
+func (h *Handler) primaryArtifact(pipelineName string) string {
+	if artifactName, ok := h.cfg.PrimaryArtifacts[pipelineName]; ok && len(artifactName) > 0 {
+		return artifactName
+	}
+	return sourceArtifactName
+}
+
+// secondaryRevisions will return the other source revisions of the execution (IE: overlay@abc1234)
+func secondaryRevisions(executionOutput *codepipeline.GetPipelineExecutionOutput, artifactName string) (revisions []string) {
+	for _, artifact := range executionOutput.PipelineExecution.ArtifactRevisions {
+		if name := aws.StringValue(artifact.Name); name != artifactName && len(aws.StringValue(artifact.RevisionId)) > 0 {
+			revisions = append(revisions, name+"@"+shortSHA(aws.StringValue(artifact.RevisionId)))
+		}
+	}
+	sort.Strings(revisions)
+	return
+}
+
+// missingArtifactError will describe why the execution has no source artifact
+func missingArtifactError(executionOutput *codepipeline.GetPipelineExecutionOutput, artifactName string) *artifactError {
+	artifacts := executionOutput.PipelineExecution.ArtifactRevisions
+	if len(artifacts) == 0 {
+		return &artifactError{Artifact: artifactName, Message: "execution has no artifacts", Reason: artifactNoArtifacts}
+	}
+	names := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		names = append(names, aws.StringValue(artifact.Name))
+	}
+	return &artifactError{
+		Artifact: artifactName,
+		Message:  fmt.Sprintf("no artifact named %s (found: %s)", artifactName, strings.Join(names, ", ")),
+		Reason:   artifactNameMismatch,
+	}
+}
+
+// revisionForge will return the forge hosting the revision url (self-hosted GitLab is recognized by
+// its commit path, other self-hosted forges by their host), empty if unknown
+func revisionForge(revisionURL *url.URL, forgeHosts map[string]string) string {
+	if forge, ok := forgeHosts[revisionURL.Host]; ok {
+		return forge
+	} else if revisionURL.Host == githubHost {
+		return forgeGithub
+	} else if revisionURL.Host == bitbucketHost {
+		return forgeBitbucket
+	} else if revisionURL.Host == gitlabHost || strings.Contains(revisionURL.Path, gitlabCommitPath) {
+		return forgeGitlab
+	} else if revisionURL.Host == azureHost || strings.HasSuffix(revisionURL.Host, azureLegacyDomain) {
+		return forgeAzureDevOps
+	}
+	return ""
+}
+
+// revisionRepository will return the owner (the namespace on GitLab, organization/project on Azure DevOps)
+// and the repository of the revision url, both are empty if the url is not a commit
+func revisionRepository(revisionURL *url.URL, forgeHosts map[string]string) (owner, repo string) {
+	switch revisionForge(revisionURL, forgeHosts) {
+	case forgeBitbucket, forgeGitea, forgeGithub:
+		if parts := strings.Split(revisionURL.Path, "/"); len(parts) >= revisionURLCommitPathLength {
+			return parts[1], parts[2]
+		}
+	case forgeGitlab:
+		if i := strings.Index(revisionURL.Path, gitlabCommitPath); i > 0 {
+			project := strings.Trim(revisionURL.Path[:i], "/")
+			if j := strings.LastIndex(project, "/"); j > 0 {
+				return project[:j], project[j+1:]
+			}
+		}
+	case forgeAzureDevOps:
+		i := strings.Index(revisionURL.Path, azureGitPath)
+		if i <= 0 {
+			return
+		}
+		owner = strings.Trim(revisionURL.Path[:i], "/")
+		if strings.HasSuffix(revisionURL.Host, azureLegacyDomain) {
+			owner = strings.TrimSuffix(revisionURL.Host, azureLegacyDomain) + "/" + owner
+		}
+		if parts := strings.Split(revisionURL.Path[i+len(azureGitPath):], "/"); len(parts) == 3 && parts[1] == "commit" &&
+			strings.Count(owner, "/") == 1 {
+			return owner, parts[0]
+		}
+		return "", ""
+	}
+	return
+}
+
+// validateArtifact will check the commit and revision url of the source artifact
+func validateArtifact(commit string, revisionURL *url.URL, forgeHosts map[string]string) error {
+	if owner, repo := revisionRepository(revisionURL, forgeHosts); len(owner) == 0 || len(repo) == 0 {
+		return &artifactError{Message: "revision url is not a commit of a known forge: " + revisionURL.String(), Reason: artifactNonGithubURL}
+	} else if !commitSHA.MatchString(commit) {
+		return &artifactError{Message: "revision is not a commit sha: " + commit, Reason: artifactBadSHA}
+	}
+	return nil
+}
+
+// reportArtifactError will log and emit the metric of an artifact resolution failure (other errors are ignored)
+func reportArtifactError(ctx context.Context, pipelineName, executionID string, err error) {
+	artifactErr, ok := err.(*artifactError)
+	if !ok {
+		return
+	}
+	logWarnf(ctx, "artifact resolution failed [%s] pipeline: %s execution: %s: %s",
+		artifactErr.Reason, pipelineName, executionID, artifactErr.Message)
+	printMetric(metricArtifactResolution, 1, "Count", map[string]string{
+		"Pipeline": pipelineName,
+		"Reason":   artifactErr.Reason,
+	}, time.Now())
+}
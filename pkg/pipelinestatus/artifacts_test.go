@@ -0,0 +1,170 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestMissingArtifactError will test missingArtifactError()
+func TestMissingArtifactError(t *testing.T) {
+	noArtifacts := &codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{}}
+	if err := missingArtifactError(noArtifacts, sourceArtifactName); err.Reason != artifactNoArtifacts {
+		t.Fatal("reason was not as expected", err.Reason)
+	}
+
+	mismatch := &codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{
+		ArtifactRevisions: []types.ArtifactRevision{{Name: aws.String("SourceArtifact")}},
+	}}
+	if err := missingArtifactError(mismatch, sourceArtifactName); err.Reason != artifactNameMismatch {
+		t.Fatal("reason was not as expected", err.Reason)
+	} else if err.Error() != "unable to resolve the SourceCode artifact [NameMismatch]: no artifact named SourceCode (found: SourceArtifact)" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
+
+// TestValidateArtifact will test validateArtifact()
+func TestValidateArtifact(t *testing.T) {
+	var tests = []struct {
+		commit         string
+		revisionURL    string
+		expectedReason string
+	}{
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", ""},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/repo/commit/25c0c3e", artifactNonGithubURL},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://github.com/mrz1836", artifactNonGithubURL},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://gitlab.com/mrz1836/codepipeline-to-github/-/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", ""},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://git.example.com/group/sub/project/-/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", ""},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://gitlab.com/mrz1836/-/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", artifactNonGithubURL},
+		{"25c0c3e", "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e", artifactBadSHA},
+		{"s3-object-version", "https://github.com/mrz1836/codepipeline-to-github/commit/s3-object-version", artifactBadSHA},
+	}
+
+	for _, test := range tests {
+		revisionURL, _ := url.Parse(test.revisionURL)
+		err := validateArtifact(test.commit, revisionURL, nil)
+		if len(test.expectedReason) == 0 && err != nil {
+			t.Errorf("%s Failed: [%s] [%s] inputted, error occurred [%s]", t.Name(), test.commit, test.revisionURL, err.Error())
+		} else if len(test.expectedReason) > 0 && (err == nil || err.(*artifactError).Reason != test.expectedReason) {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected [%s] but got [%v]", t.Name(), test.commit, test.revisionURL, test.expectedReason, err)
+		}
+	}
+}
+
+// TestRevisionRepository will test revisionRepository() and revisionForge()
+func TestRevisionRepository(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		revisionURL   string
+		expectedForge string
+		expectedOwner string
+		expectedRepo  string
+	}{
+		{"https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e", forgeGithub, "mrz1836", "codepipeline-to-github"},
+		{"https://gitlab.com/mrz1836/codepipeline-to-github/-/commit/25c0c3e", forgeGitlab, "mrz1836", "codepipeline-to-github"},
+		{"https://git.example.com/group/sub/project/-/commit/25c0c3e", forgeGitlab, "group/sub", "project"},
+		{"https://github.com/mrz1836", forgeGithub, "", ""},
+		{"https://bitbucket.org/mrz1836/repo/commits/25c0c3e", forgeBitbucket, "mrz1836", "repo"},
+		{"https://example.com/mrz1836/repo/commit/25c0c3e", "", "", ""},
+		{"https://dev.azure.com/contoso/platform/_git/api/commit/25c0c3e", forgeAzureDevOps, "contoso/platform", "api"},
+		{"https://contoso.visualstudio.com/platform/_git/api/commit/25c0c3e", forgeAzureDevOps, "contoso/platform", "api"},
+		{"https://dev.azure.com/contoso/_git/api/commit/25c0c3e", forgeAzureDevOps, "", ""},
+		{"https://forgejo.example.com/mrz1836/codepipeline-to-github/commit/25c0c3e", forgeGitea, "mrz1836", "codepipeline-to-github"},
+	}
+	forgeHosts := map[string]string{"forgejo.example.com": forgeGitea}
+
+	for _, test := range tests {
+		revisionURL, _ := url.Parse(test.revisionURL)
+		if forge := revisionForge(revisionURL, forgeHosts); forge != test.expectedForge {
+			t.Errorf("%s Failed: [%s] inputted and [%s] forge expected, received: [%s]", t.Name(), test.revisionURL, test.expectedForge, forge)
+		} else if owner, repo := revisionRepository(revisionURL, forgeHosts); owner != test.expectedOwner || repo != test.expectedRepo {
+			t.Errorf("%s Failed: [%s] inputted and [%s/%s] expected, received: [%s/%s]", t.Name(), test.revisionURL,
+				test.expectedOwner, test.expectedRepo, owner, repo)
+		}
+	}
+}
+
+// TestHandlerProcessEventArtifactErrors will test ProcessEvent() with unresolvable artifacts
+func TestHandlerProcessEventArtifactErrors(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+
+	err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "bad-artifact-name", State: "STARTED"}})
+	if artifactErr, ok := err.(*artifactError); !ok {
+		t.Fatal("error was not an artifact error", err)
+	} else if artifactErr.Reason != artifactNameMismatch {
+		t.Fatal("reason was not as expected", artifactErr.Reason)
+	}
+}
+
+// TestPrimaryArtifact will test Handler.primaryArtifact()
+func TestPrimaryArtifact(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{PrimaryArtifacts: stringMap{"multi-branch": "Trunk", "empty": ""}})
+	if name := h.primaryArtifact("multi-branch"); name != "Trunk" {
+		t.Fatal("artifact was not as expected", name)
+	} else if name = h.primaryArtifact("empty"); name != sourceArtifactName {
+		t.Fatal("artifact was not as expected", name)
+	} else if name = h.primaryArtifact("some-pipeline"); name != sourceArtifactName {
+		t.Fatal("artifact was not as expected", name)
+	}
+
+	// Named in the resolution error
+	err := missingArtifactError(&codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{
+		ArtifactRevisions: []types.ArtifactRevision{{Name: aws.String("SourceCode")}},
+	}}, "Trunk")
+	if err.Error() != "unable to resolve the Trunk artifact [NameMismatch]: no artifact named Trunk (found: SourceCode)" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
+
+// TestSecondaryRevisions will test secondaryRevisions()
+func TestSecondaryRevisions(t *testing.T) {
+	t.Parallel()
+
+	executionOutput := &codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{
+		ArtifactRevisions: []types.ArtifactRevision{
+			{Name: aws.String("Trunk"), RevisionId: aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")},
+			{Name: aws.String("Overlay"), RevisionId: aws.String("1111111111111111111111111111111111111111")},
+			{Name: aws.String("Assets")},
+			{Name: aws.String("Config"), RevisionId: aws.String("2222222222222222222222222222222222222222")},
+		},
+	}}
+	if revisions := secondaryRevisions(executionOutput, "Trunk"); len(revisions) != 2 ||
+		revisions[0] != "Config@2222222" || revisions[1] != "Overlay@1111111" {
+		t.Fatal("revisions were not as expected", revisions)
+	}
+}
+
+// TestHandlerProcessEventPrimaryArtifact will test ProcessEvent() posting for the primary revision of a multi-branch pipeline
+func TestHandlerProcessEventPrimaryArtifact(t *testing.T) {
+	h := newTestHandler(Config{
+		AnnotateSecondaryRevisions: true,
+		GithubAccessToken:          "1234567",
+		GithubMaxConcurrency:       1,
+		PrimaryArtifacts:           stringMap{"multi-branch": "Trunk"},
+		Stage:                      stageTesting,
+	})
+
+	var path string
+	var posted payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "multi-branch", State: "STARTED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path != "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("status was not posted on the primary revision", path)
+	} else if posted.Description != "with Overlay@1111111" {
+		t.Fatal("description was not as expected", posted.Description)
+	}
+}
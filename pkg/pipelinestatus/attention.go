@@ -0,0 +1,77 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// attentionReasonArchived is the reason of the pipelines of archived (read-only) repositories
+const attentionReasonArchived = "repository archived"
+
+// isGithubArchived will return true if the error is a write to an archived (read-only) repository
+// (IE: 403 Repository was archived so is read-only.)
+func isGithubArchived(err error) bool {
+	ghErr, ok := err.(*githubError)
+	return ok && ghErr.Code == http.StatusForbidden && strings.Contains(strings.ToLower(ghErr.Body), "archived")
+}
+
+// githubPathRepository will return the repository (owner/repo) of a GitHub API path (/repos/owner/repo/...)
+func githubPathRepository(path string) string {
+	parts := strings.SplitN(path, "/", 5)
+	if len(parts) < 4 || parts[1] != "repos" {
+		return ""
+	}
+	return parts[2] + "/" + parts[3]
+}
+
+// markNeedsAttention will store the pipeline as needing attention, false if it was already marked
+func markNeedsAttention(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, repository, reason string,
+	now time.Time) (bool, error) {
+	_, err := dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(pipeline)"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"pipeline":   {S: aws.String(pipelineName)},
+			"reason":     {S: aws.String(reason)},
+			"repository": {S: aws.String(repository)},
+			"since":      {S: aws.String(now.UTC().Format(time.RFC3339))},
+		},
+		TableName: aws.String(table),
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// handleArchivedRepository will acknowledge an event of a pipeline pointed at an archived repository instead of
+// failing (and being retried and alarming) on every execution: the pipeline is marked as needing attention
+// (ATTENTION_TABLE) and the notifiers are told once
+func (h *Handler) handleArchivedRepository(ctx context.Context, ghErr *githubError) error {
+	pipelineName := logFieldsFromContext(ctx).Pipeline
+	logWarnf(ctx, "skipping the archived repository %s of pipeline %s", ghErr.Repository, pipelineName)
+	if len(h.cfg.AttentionTable) == 0 || len(pipelineName) == 0 {
+		return nil
+	}
+
+	// Only the first event of the pipeline notifies
+	marked, err := markNeedsAttention(ctx, h.deps.DynamoDB, h.cfg.AttentionTable, pipelineName, ghErr.Repository,
+		attentionReasonArchived, time.Now())
+	if err != nil || !marked {
+		return err
+	}
+	h.notifyMessage(ctx, notification{
+		Pipeline: pipelineName,
+		State:    githubStateError,
+		Text: fmt.Sprintf("%s is archived, the statuses of %s are not posted until the pipeline is pointed at another repository",
+			ghErr.Repository, pipelineName),
+		Title: pipelineName + ": needs attention",
+	})
+	return nil
+}
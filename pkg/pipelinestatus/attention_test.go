@@ -0,0 +1,110 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockAttentionDynamoClient keeps the pipelines needing attention (a second put of a pipeline fails its condition)
+type mockAttentionDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+// PutItemWithContext is a mock request for dynamodb (attribute_not_exists(pipeline))
+func (m *mockAttentionDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	pipelineName := aws.StringValue(input.Item["pipeline"].S)
+	if _, ok := m.items[pipelineName]; ok {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	m.items[pipelineName] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// TestIsGithubArchived will test isGithubArchived()
+func TestIsGithubArchived(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		err      error
+		expected bool
+	}{
+		{&githubError{Body: `{"message":"Repository was archived so is read-only."}`, Code: http.StatusForbidden}, true},
+		{&githubError{Body: `{"message":"Resource not accessible by integration"}`, Code: http.StatusForbidden}, false},
+		{&githubError{Body: `{"message":"Repository was archived so is read-only."}`, Code: http.StatusNotFound}, false},
+		{&artifactError{Reason: "missing"}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if output := isGithubArchived(test.err); output != test.expected {
+			t.Errorf("%s Failed: [%v] inputted and [%t] expected, but got [%t]", t.Name(), test.err, test.expected, output)
+		}
+	}
+}
+
+// TestGithubPathRepository will test githubPathRepository()
+func TestGithubPathRepository(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e", "mrz1836/codepipeline-to-github"},
+		{"/repos/mrz1836/codepipeline-to-github", "mrz1836/codepipeline-to-github"},
+		{"/repos/mrz1836", ""},
+		{"/graphql", ""},
+	}
+
+	for _, test := range tests {
+		if output := githubPathRepository(test.path); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.path, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessEventArchivedRepository will test ProcessEvent() acknowledging the events of an archived
+// repository and notifying once
+func TestHandlerProcessEventArchivedRepository(t *testing.T) {
+	svc := &mockAttentionDynamoClient{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	var messages []teamsMessage
+	teams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message teamsMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer teams.Close()
+
+	h := newTestHandler(Config{
+		AttentionTable:       "attention",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+		TeamsWebhookURL:      teams.URL,
+	})
+	h.deps.DynamoDB = svc
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Repository was archived so is read-only."}`))
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+			t.Fatal("error should not have occurred", err.Error())
+		}
+	}
+	if item, ok := svc.items["status-succeed"]; !ok || aws.StringValue(item["repository"].S) != "mrz1836/codepipeline-to-github" {
+		t.Fatal("pipeline should have been marked", svc.items)
+	} else if len(messages) != 1 || messages[0].Attachments[0].Content.Body[0].Text != "status-succeed: needs attention" {
+		t.Fatal("notifications were not as expected", messages)
+	}
+}
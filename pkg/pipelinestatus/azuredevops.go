@@ -0,0 +1,116 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Azure DevOps (Azure Repos) commit status API
+const (
+	azureAPIURL     = "https://" + azureHost
+	azureAPIVersion = "7.1"
+)
+
+// azureStates are the Azure DevOps states of the GitHub states
+var azureStates = map[string]string{
+	githubStateError:   "error",
+	githubStateFailure: "failed",
+	githubStatePending: "pending",
+	githubStateSuccess: "succeeded",
+}
+
+// azureStatus is the payload of an Azure DevOps commit status
+type azureStatus struct {
+	Context     azureStatusContext `json:"context"`
+	Description string             `json:"description,omitempty"`
+	State       string             `json:"state"`
+	TargetURL   string             `json:"targetUrl,omitempty"`
+}
+
+// azureStatusContext identifies the status (IE: continuous-integration/codepipeline is the genre
+// continuous-integration and the name codepipeline)
+type azureStatusContext struct {
+	Genre string `json:"genre,omitempty"`
+	Name  string `json:"name"`
+}
+
+// newAzureStatusContext will split the status context on its last slash
+func newAzureStatusContext(statusCtx string) azureStatusContext {
+	if i := strings.LastIndex(statusCtx, "/"); i > 0 {
+		return azureStatusContext{Genre: statusCtx[:i], Name: statusCtx[i+1:]}
+	}
+	return azureStatusContext{Name: statusCtx}
+}
+
+// azureDevOpsReporter posts the commit statuses with the Azure DevOps API (personal access token)
+type azureDevOpsReporter struct {
+	apiURL     string
+	client     HTTPClient
+	repository string // organization/project/repo of AZURE_DEVOPS_REPOSITORIES (replaces the revision url)
+	token      string
+}
+
+// Name will return the name of the forge
+func (r *azureDevOpsReporter) Name() string {
+	return forgeAzureDevOps
+}
+
+// PostStatus will create the commit status of the repository
+func (r *azureDevOpsReporter) PostStatus(ctx context.Context, status StatusUpdate) error {
+	state, ok := azureStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported Azure DevOps state: %s", status.State)
+	}
+
+	// Find the organization, project and repository
+	parts := strings.Split(status.Owner+"/"+status.Repo, "/")
+	if len(r.repository) > 0 {
+		parts = strings.Split(r.repository, "/")
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid Azure DevOps repository: %s (IE: organization/project/repo)", strings.Join(parts, "/"))
+	}
+
+	// Encode the status
+	body, err := json.Marshal(&azureStatus{
+		Context:     newAzureStatusContext(status.Context),
+		Description: status.Description,
+		State:       state,
+		TargetURL:   status.TargetURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create the request (basic auth with an empty user and the token)
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(
+		"%s/%s/%s/_apis/git/repositories/%s/commits/%s/statuses?api-version=%s", r.apiURL, url.PathEscape(parts[0]),
+		url.PathEscape(parts[1]), url.PathEscape(parts[2]), status.Commit, azureAPIVersion,
+	), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+r.token)))
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = r.client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusCreated {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Azure DevOps, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
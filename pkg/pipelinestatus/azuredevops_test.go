@@ -0,0 +1,103 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestNewAzureStatusContext will test newAzureStatusContext()
+func TestNewAzureStatusContext(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		context       string
+		expectedGenre string
+		expectedName  string
+	}{
+		{defaultStatusContext, "continuous-integration", "codepipeline"},
+		{"ci/pipeline/build", "ci/pipeline", "build"},
+		{"codepipeline", "", "codepipeline"},
+	}
+
+	for _, test := range tests {
+		if output := newAzureStatusContext(test.context); output.Genre != test.expectedGenre || output.Name != test.expectedName {
+			t.Errorf("%s Failed: [%s] inputted and [%s] [%s] expected, received: [%s] [%s]", t.Name(), test.context,
+				test.expectedGenre, test.expectedName, output.Genre, output.Name)
+		}
+	}
+}
+
+// TestAzureDevOpsReporter will test azureDevOpsReporter.PostStatus()
+func TestAzureDevOpsReporter(t *testing.T) {
+	var received azureStatus
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		if received.Context.Name == "forbidden" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}}
+	r := &azureDevOpsReporter{apiURL: azureAPIURL, client: client, token: "1234567"}
+
+	status := StatusUpdate{Commit: "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", Context: defaultStatusContext, Owner: "mrz1836/Web Apps",
+		Repo: "codepipeline-to-github", State: githubStateFailure, TargetURL: "https://console.aws.amazon.com"}
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req := client.requests[0]; req.URL.String() != "https://dev.azure.com/mrz1836/Web%20Apps/_apis/git/repositories/"+
+		"codepipeline-to-github/commits/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08/statuses?api-version=7.1" {
+		t.Fatal("url was not as expected", req.URL.String())
+	} else if req.Header.Get("Authorization") != "Basic OjEyMzQ1Njc=" {
+		t.Fatal("authorization was not as expected", req.Header.Get("Authorization"))
+	} else if received.State != "failed" || received.Context.Genre != "continuous-integration" ||
+		received.TargetURL != "https://console.aws.amazon.com" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Configured repository
+	r.repository = "contoso/platform/api"
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path := client.requests[1].URL.Path; path !=
+		"/contoso/platform/_apis/git/repositories/api/commits/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08/statuses" {
+		t.Fatal("path was not as expected", path)
+	}
+
+	// Rejected
+	status.Context = "forbidden"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid repository
+	r.repository = "contoso/api"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventAzureDevOps will test ProcessEvent() posting the status of a configured pipeline to Azure Repos
+func TestHandlerProcessEventAzureDevOps(t *testing.T) {
+	h := newTestHandler(Config{
+		AzureDevOpsRepositories: stringMap{"status-succeed": "contoso/platform/api"},
+		AzureDevOpsToken:        "1234567",
+		GithubAccessToken:       "1234567",
+		GithubMaxConcurrency:    1,
+		Stage:                   stageTesting,
+	})
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h.deps.AzureDevOps = client
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("github should not have been called", r.URL.Path)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].URL.Host != azureHost {
+		t.Fatal("status was not posted to azure devops", client.requests)
+	}
+}
@@ -0,0 +1,93 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// bitbucketAPIURL is the API of Bitbucket Cloud
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// bitbucketStates are the Bitbucket build states of the GitHub states
+var bitbucketStates = map[string]string{
+	githubStateError:   "FAILED",
+	githubStateFailure: "FAILED",
+	githubStatePending: "INPROGRESS",
+	githubStateSuccess: "SUCCESSFUL",
+}
+
+// bitbucketStatus is the payload of a Bitbucket commit build status (the key identifies the status to update)
+type bitbucketStatus struct {
+	Description string `json:"description,omitempty"`
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+}
+
+// bitbucketReporter posts the commit build statuses with the Bitbucket Cloud API, authenticated with an
+// app password (username) or an OAuth access token
+type bitbucketReporter struct {
+	accessToken string
+	apiURL      string
+	appPassword string
+	client      HTTPClient
+	username    string
+}
+
+// Name will return the name of the forge
+func (r *bitbucketReporter) Name() string {
+	return forgeBitbucket
+}
+
+// PostStatus will create (or update) the build status of the commit
+func (r *bitbucketReporter) PostStatus(ctx context.Context, status StatusUpdate) error {
+	state, ok := bitbucketStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported Bitbucket state: %s", status.State)
+	}
+
+	// Encode the status
+	body, err := json.Marshal(&bitbucketStatus{
+		Description: status.Description,
+		Key:         status.Context,
+		Name:        status.Context,
+		State:       state,
+		URL:         status.TargetURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(
+		"%s/repositories/%s/%s/commit/%s/statuses/build", r.apiURL, status.Owner, status.Repo, status.Commit,
+	), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(r.accessToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+r.accessToken)
+	} else {
+		req.SetBasicAuth(r.username, r.appPassword)
+	}
+
+	// Fire the request and check for success (updates of an existing key return a 200)
+	var response *http.Response
+	if response, err = r.client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Bitbucket, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestBitbucketReporter will test bitbucketReporter.PostStatus()
+func TestBitbucketReporter(t *testing.T) {
+	var received bitbucketStatus
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		switch received.Key {
+		case "ci/existing":
+			w.WriteHeader(http.StatusOK)
+		case "ci/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}}
+	r := &bitbucketReporter{apiURL: bitbucketAPIURL, appPassword: "secret", client: client, username: "mrz1836"}
+
+	status := StatusUpdate{Commit: "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", Context: "ci/pipeline", Owner: "mrz1836",
+		Repo: "codepipeline-to-github", State: githubStatePending, TargetURL: "https://console.aws.amazon.com"}
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req := client.requests[0]; req.URL.String() != "https://api.bitbucket.org/2.0/repositories/mrz1836/codepipeline-to-github/"+
+		"commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08/statuses/build" {
+		t.Fatal("url was not as expected", req.URL.String())
+	} else if username, password, ok := req.BasicAuth(); !ok || username != "mrz1836" || password != "secret" {
+		t.Fatal("app password was not sent", req.Header)
+	} else if received.State != "INPROGRESS" || received.Key != "ci/pipeline" || received.URL != "https://console.aws.amazon.com" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Existing key with an access token
+	r.accessToken = "oauth-token"
+	status.Context = "ci/existing"
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if auth := client.requests[1].Header.Get("Authorization"); auth != "Bearer oauth-token" {
+		t.Fatal("access token was not sent", auth)
+	}
+
+	// Rejected
+	status.Context = "ci/forbidden"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventBitbucket will test ProcessEvent() posting the status of a revision hosted on Bitbucket
+func TestHandlerProcessEventBitbucket(t *testing.T) {
+	h := newTestHandler(Config{
+		BitbucketAccessToken: "oauth-token",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	var received bitbucketStatus
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h.deps.Bitbucket = client
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("github should not have been called", r.URL.Path)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "bitbucket", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].URL.Path !=
+		"/2.0/repositories/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08/statuses/build" {
+		t.Fatal("status was not posted to bitbucket", client.requests)
+	} else if received.State != "INPROGRESS" {
+		t.Fatal("state was not as expected", received.State)
+	}
+}
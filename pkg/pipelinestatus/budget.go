@@ -0,0 +1,150 @@
+package pipelinestatus
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Budget defaults
+const (
+	budgetDayFormat       = "2006-01-02"
+	metricGithubCallsDay  = "GithubApiCallsToday"
+	metricGithubBudgetPct = "GithubApiBudgetUsedPercent"
+)
+
+// budgetUsage is the GitHub API consumption of a day (UTC) against the daily budget
+type budgetUsage struct {
+	Budget     int64  `json:"budget"`
+	Calls      int64  `json:"calls"`
+	Coalescing bool   `json:"coalescing"` // low-priority pending updates are skipped
+	Day        string `json:"day"`
+}
+
+// githubCallsKey is the context key of the GitHub calls counter of an event
+type githubCallsKey struct{}
+
+// withGithubCalls will return a context counting the GitHub calls made with it (the counter of the event, the
+// calls of concurrent events are not counted)
+func withGithubCalls(ctx context.Context) (context.Context, *int64) {
+	calls := new(int64)
+	return context.WithValue(ctx, githubCallsKey{}, calls), calls
+}
+
+// countGithubCall will count a GitHub call against the counter of the context (if any)
+func countGithubCall(ctx context.Context) {
+	if calls, ok := ctx.Value(githubCallsKey{}).(*int64); ok {
+		atomic.AddInt64(calls, 1)
+	}
+}
+
+// budgetDay will return the day (UTC) the calls are counted under
+func budgetDay(now time.Time) string {
+	return now.UTC().Format(budgetDayFormat)
+}
+
+// addBudgetCalls will add GitHub calls to the day's count and return the new total
+func addBudgetCalls(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, calls int64, now time.Time) (total int64, err error) {
+	var output *dynamodb.UpdateItemOutput
+	if output, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":calls": {N: aws.String(strconv.FormatInt(calls, 10))},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"day": {S: aws.String(budgetDay(now))},
+		},
+		ReturnValues:     aws.String(dynamodb.ReturnValueUpdatedNew),
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("ADD github_calls :calls"),
+	}); err != nil {
+		return
+	} else if output.Attributes != nil && output.Attributes["github_calls"] != nil {
+		total, err = strconv.ParseInt(aws.StringValue(output.Attributes["github_calls"].N), 10, 64)
+	}
+	return
+}
+
+// getBudgetCalls will return the GitHub calls counted for the day
+func getBudgetCalls(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, now time.Time) (total int64, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"day": {S: aws.String(budgetDay(now))},
+		},
+		TableName: aws.String(table),
+	}); err != nil {
+		return
+	} else if output.Item != nil && output.Item["github_calls"] != nil {
+		total, err = strconv.ParseInt(aws.StringValue(output.Item["github_calls"].N), 10, 64)
+	}
+	return
+}
+
+// newBudgetUsage will return the usage of the day, coalescing starts once the calls reach the threshold of the budget
+func (h *Handler) newBudgetUsage(calls int64, now time.Time) budgetUsage {
+	return budgetUsage{
+		Budget:     h.cfg.GithubDailyBudget,
+		Calls:      calls,
+		Coalescing: h.cfg.GithubDailyBudget > 0 && float64(calls) >= float64(h.cfg.GithubDailyBudget)*h.cfg.GithubBudgetCoalesceAt,
+		Day:        budgetDay(now),
+	}
+}
+
+// recordBudget will count the GitHub calls of the event against the daily budget and emit the consumption
+// metrics (BUDGET_TABLE)
+func (h *Handler) recordBudget(ctx context.Context, githubCalls *int64) {
+	if len(h.cfg.BudgetTable) == 0 {
+		return
+	}
+	calls := atomic.LoadInt64(githubCalls)
+	if calls <= 0 {
+		return
+	}
+	now := time.Now()
+	total, err := addBudgetCalls(ctx, h.deps.DynamoDB, h.cfg.BudgetTable, calls, now)
+	if err != nil {
+		logWarnf(ctx, "unable to record the github budget: %s", err.Error())
+		return
+	}
+	usage := h.newBudgetUsage(total, now)
+	h.deps.cache.budgetSeenMu.Lock()
+	h.deps.cache.budgetSeen = usage
+	h.deps.cache.budgetSeenMu.Unlock()
+
+	printMetric(metricGithubCallsDay, float64(total), "Count", nil, now)
+	if usage.Budget > 0 {
+		printMetric(metricGithubBudgetPct, float64(total)*100/float64(usage.Budget), "Percent", nil, now)
+	}
+}
+
+// coalescing will return true if low-priority updates (pending statuses after the first) should be skipped
+// because the day's calls seen by this container are nearing the budget
+func (h *Handler) coalescing() bool {
+	if len(h.cfg.BudgetTable) == 0 {
+		return false
+	}
+	h.deps.cache.budgetSeenMu.Lock()
+	defer h.deps.cache.budgetSeenMu.Unlock()
+	return h.deps.cache.budgetSeen.Day == budgetDay(time.Now()) && h.deps.cache.budgetSeen.Coalescing
+}
+
+// budget will return the consumption of the day for the info (nil if no budget table is set)
+func (h *Handler) budget(ctx context.Context) *budgetUsage {
+	if len(h.cfg.BudgetTable) == 0 {
+		return nil
+	}
+	now := time.Now()
+	calls, err := getBudgetCalls(ctx, h.deps.DynamoDB, h.cfg.BudgetTable, now)
+	if err != nil {
+		logWarnf(ctx, "unable to get the github budget: %s", err.Error())
+		return nil
+	}
+	usage := h.newBudgetUsage(calls, now)
+	return &usage
+}
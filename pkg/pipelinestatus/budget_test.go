@@ -0,0 +1,143 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockBudgetDynamoClient keeps the calls counted per day
+type mockBudgetDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	calls map[string]int64
+}
+
+// UpdateItemWithContext is a mock request for dynamodb (adds the calls of the day)
+func (m *mockBudgetDynamoClient) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if m.calls == nil {
+		m.calls = make(map[string]int64)
+	}
+	day := aws.StringValue(input.Key["day"].S)
+	added, _ := strconv.ParseInt(aws.StringValue(input.ExpressionAttributeValues[":calls"].N), 10, 64)
+	m.calls[day] += added
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+		"github_calls": {N: aws.String(strconv.FormatInt(m.calls[day], 10))},
+	}}, nil
+}
+
+// GetItemWithContext is a mock request for dynamodb (returns the calls of the day)
+func (m *mockBudgetDynamoClient) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	calls, ok := m.calls[aws.StringValue(input.Key["day"].S)]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		"github_calls": {N: aws.String(strconv.FormatInt(calls, 10))},
+	}}, nil
+}
+
+// TestAddBudgetCalls will test addBudgetCalls() and getBudgetCalls()
+func TestAddBudgetCalls(t *testing.T) {
+	t.Parallel()
+
+	mockDynamo := &mockBudgetDynamoClient{}
+	now := time.Date(2020, 5, 1, 23, 0, 0, 0, time.UTC)
+	if total, err := addBudgetCalls(context.Background(), mockDynamo, "budget", 3, now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if total != 3 {
+		t.Fatal("total was not as expected", total)
+	}
+	if total, err := addBudgetCalls(context.Background(), mockDynamo, "budget", 2, now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if total != 5 {
+		t.Fatal("total was not as expected", total)
+	}
+
+	// Counted per day
+	if total, err := getBudgetCalls(context.Background(), mockDynamo, "budget", now.Add(2*time.Hour)); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if total != 0 {
+		t.Fatal("the next day should start at zero", total)
+	} else if total, err = getBudgetCalls(context.Background(), mockDynamo, "budget", now); err != nil || total != 5 {
+		t.Fatal("total was not as expected", total, err)
+	}
+}
+
+// TestNewBudgetUsage will test Handler.newBudgetUsage()
+func TestNewBudgetUsage(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		budget     int64
+		calls      int64
+		coalescing bool
+	}{
+		{0, 1000, false},
+		{100, 79, false},
+		{100, 80, true},
+		{100, 150, true},
+	}
+
+	for _, test := range tests {
+		h := newTestHandler(Config{GithubBudgetCoalesceAt: 0.8, GithubDailyBudget: test.budget})
+		if usage := h.newBudgetUsage(test.calls, time.Now()); usage.Coalescing != test.coalescing {
+			t.Errorf("%s Failed: [%d/%d] inputted, expected coalescing [%t]", t.Name(), test.calls, test.budget, test.coalescing)
+		}
+	}
+}
+
+// TestHandlerProcessEventBudget will test ProcessEvent() skipping pending updates near the budget
+func TestHandlerProcessEventBudget(t *testing.T) {
+	h := newTestHandler(Config{
+		BudgetTable:            "budget",
+		GithubAccessToken:      "1234567",
+		GithubBudgetCoalesceAt: 0.5,
+		GithubDailyBudget:      2,
+		GithubMaxConcurrency:   1,
+		Stage:                  stageTesting,
+	})
+	mockDynamo := &mockBudgetDynamoClient{}
+	h.deps.DynamoDB = mockDynamo
+
+	var posted int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posted++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Under the budget, every update is posted and counted
+	ev := Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", State: "STARTED"}}
+	if err := h.ProcessEventWithContext(context.Background(), ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posted != 1 || mockDynamo.calls[budgetDay(time.Now())] != 1 {
+		t.Fatal("update was not posted and counted", posted, mockDynamo.calls)
+	} else if !h.coalescing() {
+		t.Fatal("should be coalescing at half of the budget")
+	}
+
+	// Near the budget, resumed executions are skipped but new executions are posted
+	ev.Detail.State = "RESUMED"
+	if err := h.ProcessEventWithContext(context.Background(), ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posted != 1 {
+		t.Fatal("pending update should have been skipped", posted)
+	}
+	ev.Detail.State = "STARTED"
+	if err := h.ProcessEventWithContext(context.Background(), ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posted != 2 {
+		t.Fatal("first pending update should have been posted", posted)
+	}
+
+	// Reported in the info
+	if usage := h.info(context.Background()).Budget; usage == nil || usage.Calls != 2 || usage.Budget != 2 || !usage.Coalescing {
+		t.Fatal("budget info was not as expected", usage)
+	}
+}
@@ -0,0 +1,46 @@
+package pipelinestatus
+
+import "sync"
+
+// containerCache is the state shared by the handlers of a container (the handlers are created per event and
+// read it through their dependencies), each cache has its own lock
+type containerCache struct {
+	accounts   map[string]Dependencies
+	accountsMu sync.Mutex
+
+	budgetSeen   budgetUsage
+	budgetSeenMu sync.Mutex
+
+	calendars   map[string]cachedCalendar
+	calendarsMu sync.Mutex
+
+	decrypted   map[string]string
+	decryptedMu sync.Mutex
+
+	installationTokens   map[int64]installationToken
+	installationTokensMu sync.Mutex
+
+	regions   map[string]Dependencies
+	regionsMu sync.Mutex
+
+	secrets   map[string]cachedSecret
+	secretsMu sync.Mutex
+
+	verifiedToken   string
+	verifiedTokenMu sync.Mutex
+
+	writeSlots     chan struct{}
+	writeSlotsOnce sync.Once
+}
+
+// newContainerCache will create the empty caches of a container
+func newContainerCache() *containerCache {
+	return &containerCache{
+		accounts:           make(map[string]Dependencies),
+		calendars:          make(map[string]cachedCalendar),
+		decrypted:          make(map[string]string),
+		installationTokens: make(map[int64]installationToken),
+		regions:            make(map[string]Dependencies),
+		secrets:            make(map[string]cachedSecret),
+	}
+}
@@ -0,0 +1,78 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Features that are not available on older GitHub Enterprise Server versions
+const (
+	githubFeatureEnvironments = "environments"
+)
+
+// githubFeatureVersions are the minimum GitHub Enterprise Server versions of the features
+// (github.com supports every feature)
+var githubFeatureVersions = map[string]string{
+	githubFeatureEnvironments: "3.1",
+}
+
+// githubMeta is the response of the meta endpoint (installed_version is only set by GitHub Enterprise Server)
+type githubMeta struct {
+	InstalledVersion string `json:"installed_version"`
+}
+
+// githubVersionMu guards the cached server version of the handlers
+var githubVersionMu sync.Mutex
+
+// githubServerVersion will return the version of GitHub Enterprise Server (empty for github.com), the
+// version is only requested once per handler
+func (h *Handler) githubServerVersion(ctx context.Context) (string, error) {
+	githubVersionMu.Lock()
+	defer githubVersionMu.Unlock()
+	if h.githubVersion == nil {
+		var meta githubMeta
+		if err := h.githubGet(ctx, "/meta", &meta); err != nil {
+			return "", err
+		}
+		h.githubVersion = &meta.InstalledVersion
+	}
+	return *h.githubVersion, nil
+}
+
+// requireGithubFeature will return an error if the GitHub server is too old for the feature
+func (h *Handler) requireGithubFeature(ctx context.Context, feature string) error {
+	installed, err := h.githubServerVersion(ctx)
+	if err != nil {
+		return err
+	} else if len(installed) == 0 {
+		return nil
+	}
+	if minimum := githubFeatureVersions[feature]; compareVersions(installed, minimum) < 0 {
+		return fmt.Errorf("%s requires GitHub Enterprise Server %s or later (installed: %s)", feature, minimum, installed)
+	}
+	return nil
+}
+
+// compareVersions will compare two dotted versions (IE: 3.1.4 and 3.2), returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r int
+		if i < len(left) {
+			l, _ = strconv.Atoi(left[i])
+		}
+		if i < len(right) {
+			r, _ = strconv.Atoi(right[i])
+		}
+		if l != r {
+			if l < r {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// TestCompareVersions will test compareVersions()
+func TestCompareVersions(t *testing.T) {
+	var tests = []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"3.1", "3.1", 0},
+		{"3.1.0", "3.1", 0},
+		{"3.0.12", "3.1", -1},
+		{"3.10", "3.9", 1},
+		{"2.22.5", "3.1", -1},
+	}
+
+	for _, test := range tests {
+		if result := compareVersions(test.a, test.b); result != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected [%d] but got [%d]", t.Name(), test.a, test.b, test.expected, result)
+		}
+	}
+}
+
+// TestRequireGithubFeature will test requireGithubFeature()
+func TestRequireGithubFeature(t *testing.T) {
+	var tests = []struct {
+		meta          string
+		expectedError bool
+	}{
+		{`{}`, false},
+		{`{"installed_version":"3.9.2"}`, false},
+		{`{"installed_version":"2.22.5"}`, true},
+	}
+
+	for _, test := range tests {
+		h := newTestHandler(Config{})
+		var calls int
+		meta := test.meta
+		newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			_, _ = w.Write([]byte(meta))
+		})
+		for i := 0; i < 2; i++ {
+			if err := h.requireGithubFeature(context.Background(), githubFeatureEnvironments); err == nil && test.expectedError {
+				t.Errorf("%s Failed: [%s] inputted, expected to throw an error, but no error", t.Name(), test.meta)
+			} else if err != nil && !test.expectedError {
+				t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.meta, err.Error())
+			}
+		}
+		if calls != 1 {
+			t.Errorf("%s Failed: [%s] inputted, expected the version to be requested once but got [%d]", t.Name(), test.meta, calls)
+		}
+	}
+
+	// Unable to read the version
+	h := newTestHandler(Config{})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if err := h.requireGithubFeature(context.Background(), githubFeatureEnvironments); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
@@ -0,0 +1,136 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gopkg.in/yaml.v2"
+)
+
+// Backstage catalog values of the pipeline entities (CATALOG_BUCKET)
+const (
+	catalogAPIVersion  = "backstage.io/v1alpha1"
+	catalogContentType = "application/yaml"
+	catalogEntityType  = "codepipeline"
+	catalogKind        = "Resource"
+	catalogNameLimit   = 63
+)
+
+// catalogNameInvalid matches the characters that are not allowed in an entity name
+var catalogNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Per-container cache of the exported entities, unchanged entities are not written again
+var (
+	catalogExported   = make(map[string]string)
+	catalogExportedMu sync.Mutex
+)
+
+// catalogEntity is a Backstage catalog entity (catalog-info format)
+type catalogEntity struct {
+	APIVersion string          `yaml:"apiVersion"`
+	Kind       string          `yaml:"kind"`
+	Metadata   catalogMetadata `yaml:"metadata"`
+	Spec       catalogSpec     `yaml:"spec"`
+}
+
+// catalogMetadata is the metadata of a catalog entity
+type catalogMetadata struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Links       []catalogLink     `yaml:"links,omitempty"`
+}
+
+// catalogLink is a link of a catalog entity
+type catalogLink struct {
+	Title string `yaml:"title"`
+	URL   string `yaml:"url"`
+}
+
+// catalogSpec is the spec of a catalog resource
+type catalogSpec struct {
+	Type         string   `yaml:"type"`
+	Owner        string   `yaml:"owner"`
+	DependencyOf []string `yaml:"dependencyOf,omitempty"`
+}
+
+// catalogName will return the entity name of a pipeline (letters, digits, -, _ and . up to 63 characters)
+func catalogName(pipelineName string) string {
+	name := strings.Trim(catalogNameInvalid.ReplaceAllString(pipelineName, "-"), "-_.")
+	if len(name) > catalogNameLimit {
+		name = strings.TrimRight(name[:catalogNameLimit], "-_.")
+	}
+	return name
+}
+
+// newCatalogEntity will create the catalog entity of a pipeline: the repository it builds, the component of
+// the repository (PORTAL_ENTITIES) and the environment it deploys to, owned by the group of the repository owner
+func (h *Handler) newCatalogEntity(ev Event, revisionURL *url.URL, owner, repo string) catalogEntity {
+	repositoryURL := fmt.Sprintf("%s://%s/%s/%s/", revisionURL.Scheme, revisionURL.Host, owner, repo)
+	entity := catalogEntity{
+		APIVersion: catalogAPIVersion,
+		Kind:       catalogKind,
+		Metadata: catalogMetadata{
+			Name:        catalogName(ev.Detail.Pipeline),
+			Description: fmt.Sprintf("CodePipeline %s of %s/%s", ev.Detail.Pipeline, owner, repo),
+			Annotations: map[string]string{
+				"backstage.io/source-location": "url:" + repositoryURL,
+			},
+			Labels: map[string]string{"environment": strings.ToLower(h.cfg.Stage)},
+			Links: []catalogLink{{
+				Title: "Executions",
+				URL: consoleURL(h.eventPartition(ev), h.cfg.AWSRegion, fmt.Sprintf(
+					"/codesuite/codepipeline/pipelines/%s/executions", ev.Detail.Pipeline)),
+			}},
+		},
+		Spec: catalogSpec{
+			DependencyOf: []string{h.portalEntity(ev.Detail.Pipeline, owner, repo).String()},
+			Owner:        "group:" + defaultPortalNamespace + "/" + owner,
+			Type:         catalogEntityType,
+		},
+	}
+	if revisionURL.Host == h.githubWebHost() {
+		entity.Metadata.Annotations["github.com/project-slug"] = owner + "/" + repo
+	}
+	if len(ev.Resources) > 0 {
+		entity.Metadata.Annotations["aws.amazon.com/codepipeline-arn"] = ev.Resources[0]
+	}
+	return entity
+}
+
+// exportCatalogEntity will write the catalog entity of the pipeline to CATALOG_BUCKET (one catalog-info file
+// per pipeline for the S3 discovery of the portal), skipped if the container already wrote the same entity
+func (h *Handler) exportCatalogEntity(ctx context.Context, ev Event, revisionURL *url.URL, owner, repo string) error {
+	body, err := yaml.Marshal(h.newCatalogEntity(ev, revisionURL, owner, repo))
+	if err != nil {
+		return err
+	}
+	key := h.cfg.CatalogPrefix + catalogName(ev.Detail.Pipeline) + ".yaml"
+	catalogExportedMu.Lock()
+	exported := catalogExported[h.cfg.CatalogBucket+"/"+key] == string(body)
+	catalogExportedMu.Unlock()
+	if exported {
+		return nil
+	}
+
+	if _, err = h.deps.S3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Body:        bytes.NewReader(body),
+		Bucket:      aws.String(h.cfg.CatalogBucket),
+		ContentType: aws.String(catalogContentType),
+		Key:         aws.String(key),
+	}); err != nil {
+		return err
+	}
+	catalogExportedMu.Lock()
+	catalogExported[h.cfg.CatalogBucket+"/"+key] = string(body)
+	catalogExportedMu.Unlock()
+	return nil
+}
@@ -0,0 +1,139 @@
+package pipelinestatus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"gopkg.in/yaml.v2"
+)
+
+// mockS3Client keeps the written objects
+type mockS3Client struct {
+	s3iface.S3API
+	objects map[string]string
+	puts    int
+}
+
+// PutObjectWithContext is a mock request for s3 (keeps the object by bucket/key)
+func (m *mockS3Client) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	body, _ := ioutil.ReadAll(input.Body)
+	if m.objects == nil {
+		m.objects = make(map[string]string)
+	}
+	m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)] = string(body)
+	m.puts++
+	return &s3.PutObjectOutput{}, nil
+}
+
+// TestCatalogName will test catalogName()
+func TestCatalogName(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		pipeline string
+		expected string
+	}{
+		{"payments", "payments"},
+		{"payments@v2", "payments-v2"},
+		{"-payments.", "payments"},
+		{strings.Repeat("a", 62) + "-b", strings.Repeat("a", 62)},
+	}
+
+	for _, test := range tests {
+		if name := catalogName(test.pipeline); name != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.pipeline, test.expected, name)
+		}
+	}
+}
+
+// TestNewCatalogEntity will test Handler.newCatalogEntity()
+func TestNewCatalogEntity(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{AWSRegion: "us-east-1", PortalEntities: stringMap{"payments": "payments/payments-api"}, Stage: "Production"})
+	revisionURL, _ := url.Parse("https://github.com/mrz1836/payments/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")
+	entity := h.newCatalogEntity(Event{Detail: &Detail{Pipeline: "payments"},
+		Resources: []string{"arn:aws:codepipeline:us-east-1:123456789012:payments"}}, revisionURL, "mrz1836", "payments")
+
+	if entity.Kind != catalogKind || entity.Metadata.Name != "payments" || entity.Metadata.Labels["environment"] != "production" {
+		t.Fatal("entity was not as expected", entity)
+	} else if entity.Metadata.Annotations["github.com/project-slug"] != "mrz1836/payments" ||
+		entity.Metadata.Annotations["backstage.io/source-location"] != "url:https://github.com/mrz1836/payments/" ||
+		entity.Metadata.Annotations["aws.amazon.com/codepipeline-arn"] != "arn:aws:codepipeline:us-east-1:123456789012:payments" {
+		t.Fatal("annotations were not as expected", entity.Metadata.Annotations)
+	} else if entity.Spec.Owner != "group:default/mrz1836" || len(entity.Spec.DependencyOf) != 1 ||
+		entity.Spec.DependencyOf[0] != "component:payments/payments-api" {
+		t.Fatal("spec was not as expected", entity.Spec)
+	}
+
+	// Other forges have no project slug
+	revisionURL, _ = url.Parse("https://gitlab.com/mrz1836/payments/-/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")
+	if entity = h.newCatalogEntity(Event{Detail: &Detail{Pipeline: "payments"}}, revisionURL, "mrz1836", "payments"); len(entity.Metadata.Annotations) != 1 {
+		t.Fatal("annotations were not as expected", entity.Metadata.Annotations)
+	}
+}
+
+// TestExportCatalogEntity will test Handler.exportCatalogEntity() writing changed entities only
+func TestExportCatalogEntity(t *testing.T) {
+	t.Parallel()
+
+	svc := &mockS3Client{}
+	h := newTestHandler(Config{AWSRegion: "us-east-1", CatalogBucket: "portal-export", CatalogPrefix: "catalog/", Stage: "production"})
+	h.deps.S3 = svc
+	revisionURL, _ := url.Parse("https://github.com/mrz1836/search/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")
+	ev := Event{Detail: &Detail{Pipeline: "search-export"}}
+
+	for i := 0; i < 2; i++ {
+		if err := h.exportCatalogEntity(context.Background(), ev, revisionURL, "mrz1836", "search"); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+	}
+	if svc.puts != 1 {
+		t.Fatal("unchanged entity should not have been written again", svc.puts)
+	}
+	var entity catalogEntity
+	if err := yaml.Unmarshal([]byte(svc.objects["portal-export/catalog/search-export.yaml"]), &entity); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if entity.APIVersion != catalogAPIVersion || entity.Metadata.Name != "search-export" {
+		t.Fatal("entity was not as expected", entity)
+	}
+
+	// Changed entities are written again
+	h.cfg.Stage = "staging"
+	if err := h.exportCatalogEntity(context.Background(), ev, revisionURL, "mrz1836", "search"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if svc.puts != 2 {
+		t.Fatal("changed entity should have been written", svc.puts)
+	}
+}
+
+// TestHandlerProcessEventCatalog will test ProcessEvent() exporting the catalog entity of the pipeline
+func TestHandlerProcessEventCatalog(t *testing.T) {
+	svc := &mockS3Client{}
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		CatalogBucket:        "portal",
+		CatalogPrefix:        "catalog/",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	h.deps.S3 = svc
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !strings.Contains(svc.objects["portal/catalog/status-succeed.yaml"], "github.com/project-slug: mrz1836/codepipeline-to-github") {
+		t.Fatal("catalog entity was not as expected", svc.objects)
+	}
+}
@@ -0,0 +1,152 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// CDEvents defaults (https://cdevents.dev)
+const (
+	cdEventsSource                = "codepipeline-to-github"
+	cdEventsSpecVersion           = "0.3.0"
+	cdEventTypePipelineRunStarted = "dev.cdevents.pipelinerun.started.0.1.1"
+	cdEventTypePipelineRunDone    = "dev.cdevents.pipelinerun.finished.0.1.1"
+	cdEventTypeServiceDeployed    = "dev.cdevents.service.deployed.0.1.1"
+)
+
+// cdEventOutcomes maps the final execution states to the pipeline run outcomes
+var cdEventOutcomes = map[string]string{
+	"CANCELED":   "cancel",
+	"FAILED":     "failure",
+	"STOPPED":    "cancel",
+	"SUCCEEDED":  "success",
+	"SUPERSEDED": "cancel",
+}
+
+// cdEvent is a CDEvents-conformant event
+type cdEvent struct {
+	Context cdEventContext `json:"context"`
+	Subject cdEventSubject `json:"subject"`
+}
+
+// cdEventContext is the context of a CDEvent
+type cdEventContext struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Version   string    `json:"version"`
+}
+
+// cdEventSubject is the subject of a CDEvent
+type cdEventSubject struct {
+	Content map[string]interface{} `json:"content"`
+	ID      string                 `json:"id"`
+	Source  string                 `json:"source"`
+}
+
+// cdEventInput is the pipeline execution described by the events
+type cdEventInput struct {
+	Commit      string
+	Environment string
+	ExecutionID string
+	Owner       string
+	Pipeline    string
+	Repo        string
+	Source      string
+	State       string
+	Time        time.Time
+	URL         string
+}
+
+// newCDEvents will create the CDEvents for a pipeline state change (none if the state has no event)
+func newCDEvents(input cdEventInput) (events []cdEvent) {
+	subject := cdEventSubject{ID: input.ExecutionID, Source: input.Source}
+	newEvent := func(eventType string, content map[string]interface{}) cdEvent {
+		s := subject
+		s.Content = content
+		return cdEvent{
+			Context: cdEventContext{
+				ID:        fmt.Sprintf("%s-%s-%s", input.ExecutionID, input.State, eventType),
+				Source:    input.Source,
+				Timestamp: input.Time.UTC(),
+				Type:      eventType,
+				Version:   cdEventsSpecVersion,
+			},
+			Subject: s,
+		}
+	}
+
+	// Pipeline run started or finished
+	switch outcome, finished := cdEventOutcomes[input.State]; {
+	case input.State == "STARTED" || input.State == "RESUMED":
+		events = append(events, newEvent(cdEventTypePipelineRunStarted, map[string]interface{}{
+			"pipelineName": input.Pipeline,
+			"url":          input.URL,
+		}))
+	case finished:
+		events = append(events, newEvent(cdEventTypePipelineRunDone, map[string]interface{}{
+			"outcome":      outcome,
+			"pipelineName": input.Pipeline,
+			"url":          input.URL,
+		}))
+	}
+
+	// Deployment performed
+	if input.State == stateSucceeded && len(input.Environment) > 0 {
+		deployed := newEvent(cdEventTypeServiceDeployed, map[string]interface{}{
+			"artifactId":  fmt.Sprintf("pkg:github/%s/%s@%s", input.Owner, input.Repo, input.Commit),
+			"environment": map[string]string{"id": input.Environment},
+		})
+		deployed.Subject.ID = fmt.Sprintf("%s/%s", input.Owner, input.Repo)
+		events = append(events, deployed)
+	}
+	return
+}
+
+// publishCDEvents will send the events to the configured EventBridge bus and/or SNS topic
+func (h *Handler) publishCDEvents(ctx context.Context, events []cdEvent) error {
+	for _, event := range events {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		// EventBridge
+		if len(h.cfg.CDEventsBus) > 0 {
+			var output *eventbridge.PutEventsOutput
+			if output, err = h.deps.EventBridge.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+				Entries: []*eventbridge.PutEventsRequestEntry{{
+					Detail:       aws.String(string(b)),
+					DetailType:   aws.String(event.Context.Type),
+					EventBusName: aws.String(h.cfg.CDEventsBus),
+					Source:       aws.String(cdEventsSource),
+				}},
+			}); err != nil {
+				return err
+			} else if aws.Int64Value(output.FailedEntryCount) > 0 {
+				return fmt.Errorf("unable to put event %s: %s", event.Context.ID, aws.StringValue(output.Entries[0].ErrorMessage))
+			}
+		}
+
+		// SNS
+		if len(h.cfg.CDEventsTopicARN) > 0 {
+			if _, err = h.deps.SNS.PublishWithContext(ctx, &sns.PublishInput{
+				Message: aws.String(string(b)),
+				MessageAttributes: map[string]*sns.MessageAttributeValue{
+					"type": {DataType: aws.String("String"), StringValue: aws.String(event.Context.Type)},
+				},
+				TopicArn: aws.String(h.cfg.CDEventsTopicARN),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
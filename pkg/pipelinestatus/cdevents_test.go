@@ -0,0 +1,132 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// Mocking eventbridge client
+type mockEventBridgeClient struct {
+	eventbridgeiface.EventBridgeAPI
+	entries []*eventbridge.PutEventsRequestEntry
+}
+
+// PutEventsWithContext is a mock request for eventbridge
+func (m *mockEventBridgeClient) PutEventsWithContext(_ aws.Context, input *eventbridge.PutEventsInput, _ ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	if aws.StringValue(input.Entries[0].EventBusName) == "missing" {
+		return &eventbridge.PutEventsOutput{
+			Entries:          []*eventbridge.PutEventsResultEntry{{ErrorMessage: aws.String("bus not found")}},
+			FailedEntryCount: aws.Int64(1),
+		}, nil
+	}
+	m.entries = append(m.entries, input.Entries...)
+	return &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+// Mocking sns client
+type mockSNSClient struct {
+	snsiface.SNSAPI
+	messages []string
+}
+
+// PublishWithContext is a mock request for sns
+func (m *mockSNSClient) PublishWithContext(_ aws.Context, input *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	if len(aws.StringValue(input.TopicArn)) == 0 {
+		return nil, fmt.Errorf("missing topic")
+	}
+	m.messages = append(m.messages, aws.StringValue(input.Message))
+	return &sns.PublishOutput{}, nil
+}
+
+// TestNewCDEvents will test newCDEvents()
+func TestNewCDEvents(t *testing.T) {
+	t.Parallel()
+
+	input := cdEventInput{
+		Commit:      "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08",
+		Environment: "production",
+		ExecutionID: "12345678",
+		Owner:       "mrz1836",
+		Pipeline:    "some-pipeline",
+		Repo:        "codepipeline-to-github",
+		Source:      "arn:aws:codepipeline:us-east-1:123:some-pipeline",
+		Time:        time.Date(2020, 4, 30, 3, 31, 47, 0, time.UTC),
+	}
+
+	var tests = []struct {
+		state    string
+		expected []string
+	}{
+		{"STARTED", []string{cdEventTypePipelineRunStarted}},
+		{"SUCCEEDED", []string{cdEventTypePipelineRunDone, cdEventTypeServiceDeployed}},
+		{"FAILED", []string{cdEventTypePipelineRunDone}},
+		{"STOPPING", nil},
+	}
+
+	for _, test := range tests {
+		input.State = test.state
+		events := newCDEvents(input)
+		if len(events) != len(test.expected) {
+			t.Errorf("%s Failed: [%s] inputted, expected [%d] events but got [%d]", t.Name(), test.state, len(test.expected), len(events))
+			continue
+		}
+		for i, event := range events {
+			if event.Context.Type != test.expected[i] {
+				t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.state, test.expected[i], event.Context.Type)
+			} else if event.Context.Version != cdEventsSpecVersion {
+				t.Errorf("%s Failed: [%s] inputted, version was [%s]", t.Name(), test.state, event.Context.Version)
+			}
+		}
+	}
+
+	// Outcome and artifact
+	input.State = "SUCCEEDED"
+	events := newCDEvents(input)
+	if events[0].Subject.Content["outcome"] != "success" {
+		t.Fatal("outcome was not as expected", events[0].Subject.Content)
+	} else if events[1].Subject.Content["artifactId"] != "pkg:github/mrz1836/codepipeline-to-github@25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("artifact was not as expected", events[1].Subject.Content)
+	}
+}
+
+// TestPublishCDEvents will test publishCDEvents()
+func TestPublishCDEvents(t *testing.T) {
+	mockBus := &mockEventBridgeClient{}
+	mockTopic := &mockSNSClient{}
+
+	h := newTestHandler(Config{CDEventsBus: "cdevents", CDEventsTopicARN: "arn:aws:sns:us-east-1:123:cdevents"})
+	h.deps.EventBridge = mockBus
+	h.deps.SNS = mockTopic
+
+	events := newCDEvents(cdEventInput{ExecutionID: "12345678", Pipeline: "some-pipeline", State: "FAILED", Time: time.Now()})
+	if err := h.publishCDEvents(context.Background(), events); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(mockBus.entries) != 1 || aws.StringValue(mockBus.entries[0].DetailType) != cdEventTypePipelineRunDone {
+		t.Fatal("eventbridge entries were not as expected", mockBus.entries)
+	} else if len(mockTopic.messages) != 1 {
+		t.Fatal("sns messages were not as expected", mockTopic.messages)
+	}
+
+	var event cdEvent
+	if err := json.Unmarshal([]byte(mockTopic.messages[0]), &event); err != nil {
+		t.Fatal("message was not valid json", err.Error())
+	} else if event.Subject.Content["outcome"] != "failure" {
+		t.Fatal("outcome was not as expected", event.Subject.Content)
+	}
+
+	// Failed entry
+	h.cfg.CDEventsBus = "missing"
+	if err := h.publishCDEvents(context.Background(), events); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
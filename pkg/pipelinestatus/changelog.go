@@ -0,0 +1,171 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Changelog defaults
+const (
+	changelogMaxCommits = 20
+	shortSHALength      = 7
+	stateSucceeded      = "SUCCEEDED"
+)
+
+// pullRequestNumber matches the pull request of a merge or squash commit message
+var pullRequestNumber = regexp.MustCompile(`(?:Merge pull request #(\d+)|\(#(\d+)\)$)`)
+
+// compareResult is the part of the GitHub compare response used for the changelog (and orphaned commits)
+type compareResult struct {
+	Commits      []compareCommit `json:"commits"`
+	HTMLURL      string          `json:"html_url"`
+	Status       string          `json:"status"`
+	TotalCommits int             `json:"total_commits"`
+}
+
+// compareCommit is a single commit of a GitHub compare response
+type compareCommit struct {
+	Author *githubUser `json:"author"`
+	Commit struct {
+		Message string `json:"message"`
+	} `json:"commit"`
+	SHA string `json:"sha"`
+}
+
+// githubUser is a GitHub account
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// issueComment is the payload of a GitHub issue (pull request) comment
+type issueComment struct {
+	Body string `json:"body"`
+	ID   int64  `json:"id,omitempty"`
+}
+
+// recordDeploy will store the commit deployed by a pipeline and return the previously deployed commit
+func recordDeploy(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, commit string, now time.Time) (previous string, err error) {
+	var output *dynamodb.UpdateItemOutput
+	if output, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":sha":     {S: aws.String(commit)},
+			":updated": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"pipeline": {S: aws.String(pipelineName)},
+		},
+		ReturnValues:     aws.String(dynamodb.ReturnValueAllOld),
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("SET sha = :sha, updated_at = :updated"),
+	}); err != nil || output == nil || output.Attributes["sha"] == nil {
+		return
+	}
+	return aws.StringValue(output.Attributes["sha"].S), nil
+}
+
+// getDeploy will return the commit last deployed by a pipeline and when (empty if nothing was recorded)
+func getDeploy(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName string) (commit string, updatedAt time.Time, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"pipeline": {S: aws.String(pipelineName)},
+		},
+		TableName: aws.String(table),
+	}); err != nil || output == nil || output.Item["sha"] == nil {
+		return
+	}
+	commit = aws.StringValue(output.Item["sha"].S)
+	if output.Item["updated_at"] != nil {
+		updated, _ := strconv.ParseInt(aws.StringValue(output.Item["updated_at"].N), 10, 64)
+		updatedAt = time.Unix(updated, 0).UTC()
+	}
+	return
+}
+
+// formatChangelog will create a markdown changelog of the commits between two deploys
+func formatChangelog(pipelineName string, compare *compareResult) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "Deployed by `%s` ([compare](%s)):\n\n", pipelineName, compare.HTMLURL)
+	for i, commit := range compare.Commits {
+		if i == changelogMaxCommits {
+			break
+		}
+		line := strings.SplitN(strings.TrimSpace(commit.Commit.Message), "\n", 2)[0]
+		_, _ = fmt.Fprintf(&b, "- %s %s", shortSHA(commit.SHA), line)
+		if commit.Author != nil && len(commit.Author.Login) > 0 {
+			_, _ = fmt.Fprintf(&b, " (@%s)", commit.Author.Login)
+		}
+		b.WriteString("\n")
+	}
+	if more := compare.TotalCommits - changelogMaxCommits; more > 0 {
+		_, _ = fmt.Fprintf(&b, "- ...and %d more\n", more)
+	}
+	return b.String()
+}
+
+// shortSHA will return the abbreviated commit sha (as shown by GitHub)
+func shortSHA(sha string) string {
+	if len(sha) > shortSHALength {
+		return sha[:shortSHALength]
+	}
+	return sha
+}
+
+// changelogPullRequests will return the pull requests merged between two deploys (from the commit messages)
+func changelogPullRequests(compare *compareResult) (numbers []int) {
+	seen := make(map[int]bool)
+	for _, commit := range compare.Commits {
+		line := strings.SplitN(strings.TrimSpace(commit.Commit.Message), "\n", 2)[0]
+		match := pullRequestNumber.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(match[1] + match[2])
+		if number > 0 && !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	return
+}
+
+// postChangelog will compare the deployed commit with the previous deploy of the pipeline and
+// comment the changelog on the pull requests that were shipped
+func (h *Handler) postChangelog(ctx context.Context, pipelineName, owner, repo, commit string) error {
+
+	// Record this deploy and get the previous one
+	previous, err := recordDeploy(ctx, h.deps.DynamoDB, h.cfg.EnvironmentTable, pipelineName, commit, time.Now())
+	if err != nil || len(previous) == 0 || previous == commit {
+		return err
+	}
+
+	// Get the commits between the deploys
+	var compare compareResult
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/compare/%s...%s", owner, repo, previous, commit), &compare); err != nil {
+		return err
+	}
+
+	// Comment on each pull request that was shipped
+	changelog := formatChangelog(pipelineName, &compare)
+	for _, number := range changelogPullRequests(&compare) {
+		var req *http.Request
+		if req, err = h.newGithubRequest(
+			ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number), &issueComment{Body: changelog},
+		); err != nil {
+			return err
+		}
+		if err = h.doGithubRequest(req, http.StatusCreated, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCompareResult will create a compare result from commit messages
+func newCompareResult(messages ...string) *compareResult {
+	compare := &compareResult{HTMLURL: "https://github.com/owner/repo/compare/aaa...bbb", TotalCommits: len(messages)}
+	for _, message := range messages {
+		var commit compareCommit
+		commit.Commit.Message = message
+		commit.SHA = "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"
+		compare.Commits = append(compare.Commits, commit)
+	}
+	return compare
+}
+
+// TestRecordDeploy will test recordDeploy()
+func TestRecordDeploy(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+
+	// First deploy has no previous commit
+	previous, err := recordDeploy(context.Background(), mockDynamo, "environments", "production", "aaa", time.Now())
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(previous) > 0 {
+		t.Fatal("previous should be empty", previous)
+	}
+
+	// Second deploy returns the first commit
+	if previous, err = recordDeploy(context.Background(), mockDynamo, "environments", "production", "bbb", time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if previous != "aaa" {
+		t.Fatal("previous was not as expected", previous)
+	}
+
+	// Missing table
+	if _, err = recordDeploy(context.Background(), mockDynamo, "", "production", "ccc", time.Now()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestGetDeploy will test getDeploy()
+func TestGetDeploy(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+
+	// Nothing deployed yet
+	if commit, _, err := getDeploy(context.Background(), mockDynamo, "environments", "production"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(commit) > 0 {
+		t.Fatal("commit should be empty", commit)
+	}
+
+	// The recorded deploy
+	if _, err := recordDeploy(context.Background(), mockDynamo, "environments", "production", "aaa", time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commit, _, err := getDeploy(context.Background(), mockDynamo, "environments", "production"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commit != "aaa" {
+		t.Fatal("commit was not as expected", commit)
+	}
+
+	// Missing table
+	if _, _, err := getDeploy(context.Background(), mockDynamo, "", "production"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestChangelogPullRequests will test changelogPullRequests()
+func TestChangelogPullRequests(t *testing.T) {
+	numbers := changelogPullRequests(newCompareResult(
+		"Merge pull request #12 from owner/feature\n\nSome feature",
+		"Fix the build (#15)",
+		"Fix the build again (#15)",
+		"Direct commit to master",
+	))
+	if len(numbers) != 2 || numbers[0] != 12 || numbers[1] != 15 {
+		t.Fatal("pull requests were not as expected", numbers)
+	}
+}
+
+// TestFormatChangelog will test formatChangelog()
+func TestFormatChangelog(t *testing.T) {
+	compare := newCompareResult("Fix the build (#15)\n\nLonger description")
+	compare.Commits[0].Author = &githubUser{Login: "mrz1836"}
+	compare.TotalCommits = changelogMaxCommits + 3
+
+	changelog := formatChangelog("production", compare)
+	if !strings.Contains(changelog, "([compare](https://github.com/owner/repo/compare/aaa...bbb))") {
+		t.Fatal("missing compare link", changelog)
+	} else if !strings.Contains(changelog, "- 25c0c3e Fix the build (#15) (@mrz1836)\n") {
+		t.Fatal("missing commit", changelog)
+	} else if strings.Contains(changelog, "Longer description") {
+		t.Fatal("only the first line of the message should be used", changelog)
+	} else if !strings.Contains(changelog, "- ...and 3 more") {
+		t.Fatal("missing remaining commits", changelog)
+	}
+}
+
+// TestPostChangelog will test postChangelog()
+func TestPostChangelog(t *testing.T) {
+	h := newTestHandler(Config{EnvironmentTable: "environments", GithubAccessToken: "1234567"})
+
+	var comments []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/compare/aaa...bbb":
+			_ = json.NewEncoder(w).Encode(newCompareResult("Merge pull request #12 from owner/feature"))
+		case "/repos/owner/repo/issues/12/comments":
+			comments = append(comments, r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// First deploy, nothing to compare
+	if err := h.postChangelog(context.Background(), "production", "owner", "repo", "aaa"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(comments) > 0 {
+		t.Fatal("no comments should have been posted", comments)
+	}
+
+	// Second deploy
+	if err := h.postChangelog(context.Background(), "production", "owner", "repo", "bbb"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(comments) != 1 {
+		t.Fatal("one comment should have been posted", comments)
+	}
+
+	// Compare fails
+	if err := h.postChangelog(context.Background(), "production", "owner", "repo", "ccc"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
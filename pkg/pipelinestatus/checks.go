@@ -0,0 +1,219 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Check run values of the GitHub Checks API
+const (
+	checkAnnotationFailure   = "failure"
+	checkAnnotationPath      = "." // CodePipeline failures are not tied to a file of the repository
+	checkAnnotationsLimit    = 50  // max annotations per request
+	checkConclusionCancelled = "cancelled"
+	checkConclusionFailure   = "failure"
+	checkConclusionNeutral   = "neutral"
+	checkConclusionSuccess   = "success"
+	checkStatusCompleted     = "completed"
+	checkStatusInProgress    = "in_progress"
+)
+
+// checkRun is a check run of the Checks API (the execution ID is the external ID)
+type checkRun struct {
+	Conclusion string          `json:"conclusion,omitempty"`
+	DetailsURL string          `json:"details_url,omitempty"`
+	ExternalID string          `json:"external_id,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	ID         int64           `json:"id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Output     *checkRunOutput `json:"output,omitempty"`
+	Status     string          `json:"status,omitempty"`
+}
+
+// checkRunOutput is the summary of a check run shown in the checks tab
+type checkRunOutput struct {
+	Annotations []checkAnnotation `json:"annotations,omitempty"`
+	Summary     string            `json:"summary"`
+	Title       string            `json:"title"`
+}
+
+// checkAnnotation is a failed action of the execution
+type checkAnnotation struct {
+	AnnotationLevel string `json:"annotation_level"`
+	EndLine         int    `json:"end_line"`
+	Message         string `json:"message"`
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	Title           string `json:"title"`
+}
+
+// checkRunList is the check runs of a commit
+type checkRunList struct {
+	CheckRuns []checkRun `json:"check_runs"`
+}
+
+// checkRunState will return the status and conclusion of a check run from the GitHub status and the
+// execution status (stopped and superseded executions are not failures)
+func checkRunState(githubStatus string, executionStatus types.PipelineExecutionStatus) (status, conclusion string) {
+	switch githubStatus {
+	case githubStatePending:
+		return checkStatusInProgress, ""
+	case githubStateSuccess:
+		return checkStatusCompleted, checkConclusionSuccess
+	}
+	switch executionStatus {
+	case types.PipelineExecutionStatusStopped, types.PipelineExecutionStatusCancelled:
+		return checkStatusCompleted, checkConclusionCancelled
+	case types.PipelineExecutionStatusSuperseded:
+		return checkStatusCompleted, checkConclusionNeutral
+	}
+	return checkStatusCompleted, checkConclusionFailure
+}
+
+// getActionExecutions will return the actions of a pipeline execution
+func getActionExecutions(ctx context.Context, pipelineName, executionID string,
+	pipeline CodePipelineAPI) (actions []*types.ActionExecutionDetail, err error) {
+	paginator := codepipeline.NewListActionExecutionsPaginator(pipeline, &codepipeline.ListActionExecutionsInput{
+		Filter: &types.ActionExecutionFilter{
+			PipelineExecutionId: aws.String(executionID),
+		},
+		PipelineName: aws.String(pipelineName),
+	})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListActionExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for i := range page.ActionExecutionDetails {
+			actions = append(actions, &page.ActionExecutionDetails[i])
+		}
+	}
+	return
+}
+
+// stageSummary will return a markdown table of the stages of an execution (in the order they started)
+// and an annotation for each failed action
+func stageSummary(actions []*types.ActionExecutionDetail) (summary string, annotations []checkAnnotation) {
+	type stage struct {
+		actions []string
+		name    string
+		started time.Time
+		status  types.ActionExecutionStatus
+	}
+	stages := make(map[string]*stage)
+	for _, action := range actions {
+		name := aws.StringValue(action.StageName)
+		s, ok := stages[name]
+		if !ok {
+			s = &stage{name: name, started: aws.TimeValue(action.StartTime), status: types.ActionExecutionStatusSucceeded}
+			stages[name] = s
+		}
+		s.actions = append(s.actions, aws.StringValue(action.ActionName))
+		if started := aws.TimeValue(action.StartTime); started.Before(s.started) {
+			s.started = started
+		}
+		switch status := action.Status; {
+		case status == types.ActionExecutionStatusFailed:
+			s.status = status
+			if len(annotations) < checkAnnotationsLimit {
+				annotations = append(annotations, checkAnnotation{
+					AnnotationLevel: checkAnnotationFailure,
+					EndLine:         1,
+					Message:         failureMessage(action),
+					Path:            checkAnnotationPath,
+					StartLine:       1,
+					Title:           name + "/" + aws.StringValue(action.ActionName),
+				})
+			}
+		case status != types.ActionExecutionStatusSucceeded && s.status != types.ActionExecutionStatusFailed:
+			s.status = status
+		}
+	}
+
+	ordered := make([]*stage, 0, len(stages))
+	for _, s := range stages {
+		ordered = append(ordered, s)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].started.Equal(ordered[j].started) {
+			return ordered[i].name < ordered[j].name
+		}
+		return ordered[i].started.Before(ordered[j].started)
+	})
+
+	lines := []string{"| Stage | Actions | Status |", "| --- | --- | --- |"}
+	for _, s := range ordered {
+		sort.Strings(s.actions)
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s |", s.name, strings.Join(s.actions, ", "), s.status))
+	}
+	return strings.Join(lines, "\n"), annotations
+}
+
+// newCheckRun will create the check run of an execution, the stages of the execution are summarized
+func (h *Handler) newCheckRun(ctx context.Context, pipelineName, executionID string,
+	executionOutput *codepipeline.GetPipelineExecutionOutput, commit, statusCtx, description, githubStatus,
+	targetURL string) (run checkRun, err error) {
+	executionStatus := executionOutput.PipelineExecution.Status
+	run = checkRun{
+		DetailsURL: targetURL,
+		ExternalID: executionID,
+		HeadSHA:    commit,
+		Name:       statusCtx,
+		Output:     &checkRunOutput{Summary: description, Title: description},
+	}
+	run.Status, run.Conclusion = checkRunState(githubStatus, executionStatus)
+	if len(run.Output.Title) == 0 {
+		run.Output.Title = pipelineName + ": " + string(executionStatus)
+	}
+
+	var actions []*types.ActionExecutionDetail
+	if actions, err = getActionExecutions(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil {
+		return
+	} else if len(actions) > 0 {
+		var table string
+		table, run.Output.Annotations = stageSummary(actions)
+		run.Output.Summary = strings.TrimSpace(description + "\n\n" + table)
+	}
+	return
+}
+
+// postCheckRun will update the check run of the execution on the commit or create it if there is none
+func (h *Handler) postCheckRun(ctx context.Context, owner, repo string, run checkRun) (err error) {
+
+	// Find the check run of the execution
+	var runs checkRunList
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?check_name=%s",
+		owner, repo, run.HeadSHA, url.QueryEscape(run.Name)), &runs); err != nil {
+		return
+	}
+	for _, existing := range runs.CheckRuns {
+		if existing.ExternalID == run.ExternalID {
+			run.ID = existing.ID
+			break
+		}
+	}
+
+	// Create or update the check run
+	var req *http.Request
+	if run.ID == 0 {
+		if req, err = h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/check-runs", owner, repo), &run); err != nil {
+			return
+		}
+		return h.doGithubRequest(req, http.StatusCreated, nil)
+	}
+	path := fmt.Sprintf("/repos/%s/%s/check-runs/%d", owner, repo, run.ID)
+	run.HeadSHA, run.ID = "", 0
+	if req, err = h.newGithubRequest(ctx, http.MethodPatch, path, &run); err != nil {
+		return
+	}
+	return h.doGithubRequest(req, http.StatusOK, nil)
+}
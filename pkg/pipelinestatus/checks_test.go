@@ -0,0 +1,115 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestCheckRunState will test checkRunState()
+func TestCheckRunState(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		githubStatus       string
+		executionStatus    types.PipelineExecutionStatus
+		expectedStatus     string
+		expectedConclusion string
+	}{
+		{githubStatePending, types.PipelineExecutionStatusInProgress, checkStatusInProgress, ""},
+		{githubStateSuccess, types.PipelineExecutionStatusSucceeded, checkStatusCompleted, checkConclusionSuccess},
+		{githubStateFailure, types.PipelineExecutionStatusFailed, checkStatusCompleted, checkConclusionFailure},
+		{githubStateFailure, types.PipelineExecutionStatusStopped, checkStatusCompleted, checkConclusionCancelled},
+		{githubStateFailure, types.PipelineExecutionStatusSuperseded, checkStatusCompleted, checkConclusionNeutral},
+		{githubStateError, types.PipelineExecutionStatusSucceeded, checkStatusCompleted, checkConclusionFailure},
+	}
+
+	for _, test := range tests {
+		if status, conclusion := checkRunState(test.githubStatus, test.executionStatus); status != test.expectedStatus {
+			t.Errorf("%s Failed: [%s/%s] inputted, expected status [%s] but got [%s]", t.Name(), test.githubStatus, test.executionStatus, test.expectedStatus, status)
+		} else if conclusion != test.expectedConclusion {
+			t.Errorf("%s Failed: [%s/%s] inputted, expected conclusion [%s] but got [%s]", t.Name(), test.githubStatus, test.executionStatus, test.expectedConclusion, conclusion)
+		}
+	}
+}
+
+// TestStageSummary will test stageSummary()
+func TestStageSummary(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	summary, annotations := stageSummary([]*types.ActionExecutionDetail{
+		{ActionName: aws.String("Deploy"), StageName: aws.String("Deploy"), StartTime: aws.Time(started.Add(time.Minute)), Status: types.ActionExecutionStatusFailed,
+			Output: &types.ActionExecutionOutput{ExecutionResult: &types.ActionExecutionResult{ExternalExecutionSummary: aws.String("stack rolled back")}}},
+		{ActionName: aws.String("Migrate"), StageName: aws.String("Deploy"), StartTime: aws.Time(started.Add(time.Minute)), Status: types.ActionExecutionStatusSucceeded},
+		{ActionName: aws.String("Build"), StageName: aws.String("Build"), StartTime: aws.Time(started), Status: types.ActionExecutionStatusSucceeded},
+	})
+
+	expected := "| Stage | Actions | Status |\n| --- | --- | --- |\n| Build | Build | Succeeded |\n| Deploy | Deploy, Migrate | Failed |"
+	if summary != expected {
+		t.Fatal("summary was not as expected", summary)
+	} else if len(annotations) != 1 || annotations[0].Title != "Deploy/Deploy" || annotations[0].Message != "stack rolled back" {
+		t.Fatal("annotations were not as expected", annotations)
+	}
+}
+
+// TestHandlerProcessEventChecksAPI will test ProcessEvent() creating and updating a check run
+func TestHandlerProcessEventChecksAPI(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+		UseChecksAPI:         true,
+	})
+
+	var created, updated checkRun
+	var existing string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		repoPath := "/repos/mrz1836/codepipeline-to-github"
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/check-runs"):
+			_, _ = w.Write([]byte(`{"check_runs":[` + existing + `]}`))
+		case r.Method == http.MethodPost && r.URL.Path == repoPath+"/check-runs":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && r.URL.Path == repoPath+"/check-runs/42":
+			_ = json.NewDecoder(r.Body).Decode(&updated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// Create the check run of a failed execution
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-fail",
+		State:       "FAILED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if created.HeadSHA != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" || created.ExternalID != "12345678" {
+		t.Fatal("check run was not as expected", created)
+	} else if created.Status != checkStatusCompleted || created.Conclusion != checkConclusionFailure {
+		t.Fatal("check run state was not as expected", created.Status, created.Conclusion)
+	} else if created.Output == nil || !strings.Contains(created.Output.Summary, "| Build | Build-and-Deploy-Stack | Failed |") {
+		t.Fatal("check run output was not as expected", created.Output)
+	} else if len(created.Output.Annotations) != 1 {
+		t.Fatal("annotations were not as expected", created.Output.Annotations)
+	}
+
+	// Update the check run of the same execution
+	existing = `{"id":42,"external_id":"12345678"}`
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if updated.Conclusion != checkConclusionSuccess || len(updated.HeadSHA) != 0 {
+		t.Fatal("updated check run was not as expected", updated)
+	}
+}
@@ -0,0 +1,98 @@
+package pipelinestatus
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// CodePipelineAPI is the part of the CodePipeline client (aws-sdk-go-v2) used by the handler, replace it with a mock
+// to test without network access
+type CodePipelineAPI interface {
+	GetJobDetails(ctx context.Context, input *codepipeline.GetJobDetailsInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.GetJobDetailsOutput, error)
+	GetPipeline(ctx context.Context, input *codepipeline.GetPipelineInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.GetPipelineOutput, error)
+	GetPipelineExecution(ctx context.Context, input *codepipeline.GetPipelineExecutionInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.GetPipelineExecutionOutput, error)
+	GetPipelineState(ctx context.Context, input *codepipeline.GetPipelineStateInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.GetPipelineStateOutput, error)
+	ListActionExecutions(ctx context.Context, input *codepipeline.ListActionExecutionsInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error)
+	ListPipelineExecutions(ctx context.Context, input *codepipeline.ListPipelineExecutionsInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error)
+	ListPipelines(ctx context.Context, input *codepipeline.ListPipelinesInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.ListPipelinesOutput, error)
+	ListTagsForResource(ctx context.Context, input *codepipeline.ListTagsForResourceInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.ListTagsForResourceOutput, error)
+	PutApprovalResult(ctx context.Context, input *codepipeline.PutApprovalResultInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.PutApprovalResultOutput, error)
+	PutJobFailureResult(ctx context.Context, input *codepipeline.PutJobFailureResultInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.PutJobFailureResultOutput, error)
+	PutJobSuccessResult(ctx context.Context, input *codepipeline.PutJobSuccessResultInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.PutJobSuccessResultOutput, error)
+	StartPipelineExecution(ctx context.Context, input *codepipeline.StartPipelineExecutionInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.StartPipelineExecutionOutput, error)
+	StopPipelineExecution(ctx context.Context, input *codepipeline.StopPipelineExecutionInput,
+		optFns ...func(*codepipeline.Options)) (*codepipeline.StopPipelineExecutionOutput, error)
+}
+
+// KMSAPI is the part of the KMS client (aws-sdk-go-v2) used to decrypt the configuration
+type KMSAPI interface {
+	Decrypt(ctx context.Context, input *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// awsConfig is the shared configuration of the aws-sdk-go-v2 clients for the container (CodePipeline and KMS)
+var awsConfig awsv2.Config
+
+// loadAWSConfig will load the configuration of the aws-sdk-go-v2 clients (in the region of AWS_REGION), their calls
+// are logged and traced like the calls of the session
+func loadAWSConfig(ctx context.Context) (awsv2.Config, error) {
+	return config.LoadDefaultConfig(ctx,
+		config.WithRegion(os.Getenv("AWS_REGION")),
+		config.WithAPIOptions([]func(*middleware.Stack) error{observeAWSCall}),
+	)
+}
+
+// assumeRoleConfig will return the configuration using a role (IE: the role of a member account), the credentials
+// are cached until they expire
+func assumeRoleConfig(cfg awsv2.Config, roleARN string) awsv2.Config {
+	assumed := cfg.Copy()
+	assumed.Credentials = awsv2.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN))
+	return assumed
+}
+
+// observeAWSCall is a middleware of the aws-sdk-go-v2 clients writing the latency of each call and sending its
+// subsegment (IE: CodePipeline GetPipelineExecution), like logAWSRequest and traceAWSRequest for the session
+func observeAWSCall(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ObserveAWSCall", func(ctx context.Context,
+		in middleware.InitializeInput, next middleware.InitializeHandler) (out middleware.InitializeOutput,
+		metadata middleware.Metadata, err error) {
+		start := time.Now()
+		out, metadata, err = next.HandleInitialize(ctx, in)
+
+		var statusCode int
+		if response, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+			statusCode = response.StatusCode
+		}
+		service, operation := strings.ToLower(awsmiddleware.GetServiceID(ctx)), awsmiddleware.GetOperationName(ctx)
+		logExternalCall(ctx, service, operation, statusCode, time.Since(start), err)
+		if segment := newSubsegment(ctx, service, "aws", start, time.Now(), statusCode, err); segment != nil {
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			segment.AWS = &xrayAWS{Operation: operation, Region: awsmiddleware.GetRegion(ctx), RequestID: requestID}
+			sendSubsegment(segment)
+		}
+		return
+	}), middleware.Before)
+}
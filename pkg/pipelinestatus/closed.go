@@ -0,0 +1,85 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Executions of the closed pull requests and the deleted branches (CLOSED_EXECUTIONS, INGESTION_MODE=webhook)
+const (
+	closedExecutionsAbandon  = "abandon" // stops without waiting for the actions in progress
+	closedExecutionsSearched = 20        // recent executions of a pipeline searched for the commit
+	closedExecutionsStop     = "stop"    // lets the actions in progress finish
+	closedPullRequestAction  = "closed"
+)
+
+// closedReason will return why the executions of the change are stopped
+func closedReason(change webhookChange) string {
+	if change.PullRequest > 0 {
+		return fmt.Sprintf("pull request #%d closed", change.PullRequest)
+	}
+	return "branch " + change.HeadBranch + " deleted"
+}
+
+// stopClosedExecutions will stop the executions in progress of the commit of a closed pull request (or of a deleted
+// branch) to free the capacity of the pipelines, their statuses are marked as cancelled. Executions that can no
+// longer be stopped (IE: they just finished) are logged and skipped
+func (h *Handler) stopClosedExecutions(ctx context.Context, change webhookChange) (stopped int, err error) {
+	reason := closedReason(change)
+	paginator := codepipeline.NewListPipelinesPaginator(h.deps.CodePipeline, &codepipeline.ListPipelinesInput{})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListPipelinesOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, pipeline := range page.Pipelines {
+			pipelineName := aws.StringValue(pipeline.Name)
+			var output *codepipeline.ListPipelineExecutionsOutput
+			if output, err = h.deps.CodePipeline.ListPipelineExecutions(ctx, &codepipeline.ListPipelineExecutionsInput{
+				MaxResults:   aws.Int32(closedExecutionsSearched),
+				PipelineName: aws.String(pipelineName),
+			}); err != nil {
+				return
+			} else if output == nil {
+				continue
+			}
+			for _, execution := range output.PipelineExecutionSummaries {
+				if execution.Status != types.PipelineExecutionStatusInProgress || !hasSourceRevision(execution, change.Commit) {
+					continue
+				}
+				executionID := aws.StringValue(execution.PipelineExecutionId)
+				if _, err = h.deps.CodePipeline.StopPipelineExecution(ctx, &codepipeline.StopPipelineExecutionInput{
+					Abandon:             h.cfg.ClosedExecutions == closedExecutionsAbandon,
+					PipelineExecutionId: aws.String(executionID),
+					PipelineName:        aws.String(pipelineName),
+					Reason:              aws.String(reason),
+				}); err != nil {
+					logWarnf(ctx, "unable to stop execution %s of pipeline %s: %s", executionID, pipelineName, err.Error())
+					err = nil
+					continue
+				}
+				logf(ctx, "stopped execution %s of pipeline %s: %s", executionID, pipelineName, reason)
+				stopped++
+				if err = h.postChangeStatus(ctx, change, pipelineName, executionID, githubStateError,
+					checkConclusionCancelled, joinDescription("cancelled: "+reason)); err != nil {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+// hasSourceRevision will return true if the execution runs the commit
+func hasSourceRevision(execution types.PipelineExecutionSummary, commit string) bool {
+	for _, revision := range execution.SourceRevisions {
+		if aws.StringValue(revision.RevisionId) == commit {
+			return true
+		}
+	}
+	return false
+}
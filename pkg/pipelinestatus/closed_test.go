@@ -0,0 +1,123 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockClosedPipelineClient lists the executions in progress and records the stopped ones
+type mockClosedPipelineClient struct {
+	mockCodePipelineClient
+	stopped []*codepipeline.StopPipelineExecutionInput
+}
+
+// ListPipelines is a mock request for codepipeline
+func (m *mockClosedPipelineClient) ListPipelines(_ context.Context, _ *codepipeline.ListPipelinesInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelinesOutput, error) {
+	return &codepipeline.ListPipelinesOutput{Pipelines: []types.PipelineSummary{{Name: aws.String("web")}}}, nil
+}
+
+// ListPipelineExecutions is a mock request for codepipeline (execution 8 finished before it could be stopped)
+func (m *mockClosedPipelineClient) ListPipelineExecutions(_ context.Context, _ *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{PipelineExecutionId: aws.String("9"), Status: types.PipelineExecutionStatusInProgress, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("bbb222")}}},
+		{PipelineExecutionId: aws.String("8"), Status: types.PipelineExecutionStatusInProgress, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+		{PipelineExecutionId: aws.String("7"), Status: types.PipelineExecutionStatusInProgress, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+		{PipelineExecutionId: aws.String("6"), Status: types.PipelineExecutionStatusSucceeded, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+	}}, nil
+}
+
+// StopPipelineExecution is a mock request for codepipeline
+func (m *mockClosedPipelineClient) StopPipelineExecution(_ context.Context, input *codepipeline.StopPipelineExecutionInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.StopPipelineExecutionOutput, error) {
+	if aws.StringValue(input.PipelineExecutionId) == "8" {
+		return nil, errors.New("PipelineExecutionNotStoppableException: execution is not in progress")
+	}
+	m.stopped = append(m.stopped, input)
+	return &codepipeline.StopPipelineExecutionOutput{PipelineExecutionId: input.PipelineExecutionId}, nil
+}
+
+// TestProcessWebhookClosedExecutions will test Handler.ProcessWebhook() stopping the executions of the closed
+// pull requests and the deleted branches
+func TestProcessWebhookClosedExecutions(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		ClosedExecutions:     closedExecutionsStop,
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		GithubWebhookSecret:  "secret",
+		Stage:                stageTesting,
+	})
+	mockPipeline := &mockClosedPipelineClient{}
+	h.deps.CodePipeline = mockPipeline
+
+	var posted []payload
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var received payload
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		posted = append(posted, received)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	send := func(eventType, body string) events.APIGatewayV2HTTPResponse {
+		return h.ProcessWebhook(context.Background(), events.APIGatewayV2HTTPRequest{Body: body, Headers: map[string]string{
+			"x-github-event":      eventType,
+			"x-hub-signature-256": signWebhook("secret", body),
+		}})
+	}
+
+	// A pull request closed without merging stops the executions of its head commit and cancels their statuses
+	body := `{"action":"closed","number":12,"repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"pull_request":{"base":{"ref":"main"},"head":{"ref":"feature","sha":"aaa111"},"merged":false}}`
+	if response := send(webhookEventPullRequest, body); response.StatusCode != http.StatusOK || response.Body != "stopped 1 executions" {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.stopped) != 1 || aws.StringValue(mockPipeline.stopped[0].PipelineExecutionId) != "7" ||
+		mockPipeline.stopped[0].Abandon || aws.StringValue(mockPipeline.stopped[0].Reason) != "pull request #12 closed" {
+		t.Fatal("stopped executions were not as expected", mustJSON(mockPipeline.stopped))
+	} else if len(posted) != 1 || paths[0] != "/repos/mrz1836/codepipeline-to-github/statuses/aaa111" ||
+		posted[0].State != githubStateError || posted[0].Description != "cancelled: pull request #12 closed" ||
+		posted[0].TargetURL != "https://us-east-1.console.aws.amazon.com/codesuite/codepipeline/pipelines/web/executions/7" {
+		t.Fatal("statuses were not as expected", paths, mustJSON(posted))
+	}
+
+	// A deleted branch stops the executions of its last commit (abandoned)
+	h.cfg.ClosedExecutions = closedExecutionsAbandon
+	mockPipeline.stopped, posted = nil, nil
+	body = `{"ref":"refs/heads/feature","before":"bbb222","after":"0000000000000000000000000000000000000000","deleted":true,` +
+		`"repository":{"full_name":"mrz1836/codepipeline-to-github"}}`
+	if response := send(webhookEventPush, body); response.StatusCode != http.StatusOK || response.Body != "stopped 1 executions" {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.stopped) != 1 || aws.StringValue(mockPipeline.stopped[0].PipelineExecutionId) != "9" ||
+		!mockPipeline.stopped[0].Abandon || aws.StringValue(mockPipeline.stopped[0].Reason) != "branch feature deleted" {
+		t.Fatal("stopped executions were not as expected", mustJSON(mockPipeline.stopped))
+	} else if len(posted) != 1 || posted[0].Description != "cancelled: branch feature deleted" {
+		t.Fatal("statuses were not as expected", mustJSON(posted))
+	}
+
+	// Merged pull requests and closures without CLOSED_EXECUTIONS stop nothing
+	mockPipeline.stopped = nil
+	body = `{"action":"closed","number":12,"repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"pull_request":{"base":{"ref":"main"},"head":{"ref":"feature","sha":"aaa111"},"merged":true}}`
+	if response := send(webhookEventPullRequest, body); response.StatusCode != http.StatusAccepted {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	}
+	h.cfg.ClosedExecutions = ""
+	body = `{"action":"closed","number":12,"repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"pull_request":{"base":{"ref":"main"},"head":{"ref":"feature","sha":"aaa111"},"merged":false}}`
+	if response := send(webhookEventPullRequest, body); response.StatusCode != http.StatusAccepted {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.stopped) != 0 {
+		t.Fatal("stopped executions were not as expected", mustJSON(mockPipeline.stopped))
+	}
+}
@@ -0,0 +1,184 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+)
+
+// CodeBuild events (builds started directly, IE: by a webhook)
+const (
+	codebuildContextPrefix     = "codebuild/"
+	codebuildPipelineInitiator = "codepipeline/"
+	detailTypeBuildState       = "CodeBuild Build State Change"
+)
+
+// buildStates are the GitHub statuses of the build states
+var buildStates = map[string]string{
+	"FAILED":      githubStateFailure,
+	"FAULT":       githubStateError,
+	"IN_PROGRESS": githubStatePending,
+	"STOPPED":     githubStateError,
+	"SUCCEEDED":   githubStateSuccess,
+	"TIMED_OUT":   githubStateFailure,
+}
+
+// buildInformation is the additional information of a build event
+type buildInformation struct {
+	Initiator     string      `json:"initiator"`
+	Source        buildSource `json:"source"`
+	SourceVersion string      `json:"source-version"`
+}
+
+// buildSource is the primary source of a build (IE: GITHUB https://github.com/owner/repo.git)
+type buildSource struct {
+	Location string `json:"location"`
+	Type     string `json:"type"`
+}
+
+// isBuildEvent will return true if the event is a state change of a CodeBuild build
+func isBuildEvent(ev Event) bool {
+	return ev.Detail != nil && ev.DetailType == detailTypeBuildState
+}
+
+// validateBuildEvent will check the build event for the required parameters
+func validateBuildEvent(ev Event) error {
+	if len(ev.Detail.ProjectName) == 0 {
+		return errors.New("missing event param project-name")
+	} else if len(ev.Detail.BuildID) == 0 {
+		return errors.New("missing event param build-id")
+	} else if ev.Detail.BuildInformation == nil {
+		return errors.New("missing event param additional-information")
+	} else if _, ok := buildStates[ev.Detail.BuildStatus]; !ok {
+		return fmt.Errorf("unknown build status: %s", ev.Detail.BuildStatus)
+	}
+	return nil
+}
+
+// buildRevisionURL will return the commit url of the build source (the url of the revision of an artifact)
+func buildRevisionURL(source buildSource, commit string) (*url.URL, error) {
+	location := strings.TrimSuffix(strings.TrimSuffix(source.Location, "/"), ".git")
+	commitPath := "/commit/"
+	if strings.HasPrefix(source.Type, codebuild.SourceTypeGitlab) {
+		commitPath = gitlabCommitPath
+	}
+	return url.Parse(location + commitPath + commit)
+}
+
+// buildCommit will return the commit of the build, the source version of builds started for a branch
+// or a pull request is resolved by CodeBuild
+func (h *Handler) buildCommit(ctx context.Context, ev Event) (string, error) {
+	if version := ev.Detail.BuildInformation.SourceVersion; commitSHA.MatchString(version) {
+		return version, nil
+	}
+	output, err := h.deps.CodeBuild.BatchGetBuildsWithContext(ctx, &codebuild.BatchGetBuildsInput{
+		Ids: []*string{aws.String(ev.Detail.BuildID)},
+	})
+	if err != nil {
+		return "", err
+	} else if len(output.Builds) == 0 || !commitSHA.MatchString(aws.StringValue(output.Builds[0].ResolvedSourceVersion)) {
+		return "", fmt.Errorf("unable to resolve the commit of build: %s (source version: %s)",
+			ev.Detail.BuildID, ev.Detail.BuildInformation.SourceVersion)
+	}
+	return aws.StringValue(output.Builds[0].ResolvedSourceVersion), nil
+}
+
+// buildLogsURL will return the link to the CloudWatch log stream of the build of a failed CodeBuild action
+// (LINK_BUILD_LOGS), empty for other actions or builds without logs
+func (h *Handler) buildLogsURL(ctx context.Context, action *types.ActionExecutionDetail) (string, error) {
+	if action.Input == nil || action.Input.ActionTypeId == nil ||
+		aws.StringValue(action.Input.ActionTypeId.Provider) != actionProviderCodeBuild ||
+		action.Output == nil || action.Output.ExecutionResult == nil {
+		return "", nil
+	}
+	buildID := aws.StringValue(action.Output.ExecutionResult.ExternalExecutionId)
+	if len(buildID) == 0 {
+		return "", nil
+	}
+	output, err := h.deps.CodeBuild.BatchGetBuildsWithContext(ctx, &codebuild.BatchGetBuildsInput{
+		Ids: []*string{aws.String(buildID)},
+	})
+	if err != nil {
+		return "", err
+	} else if len(output.Builds) == 0 || output.Builds[0].Logs == nil {
+		return "", nil
+	}
+	return aws.StringValue(output.Builds[0].Logs.DeepLink), nil
+}
+
+// processBuildEvent will post the status of a build under the codebuild/<project> context (builds
+// started by a pipeline are reported by the pipeline)
+func (h *Handler) processBuildEvent(ctx context.Context, ev Event) error {
+	if !h.cfg.CodeBuildEvents {
+		logf(ctx, "skipping build of %s (CODEBUILD_EVENTS is not enabled)", ev.Detail.ProjectName)
+		return nil
+	} else if err := validateBuildEvent(ev); err != nil {
+		return err
+	}
+	logf(ctx, "Incoming Build Details: %+v", ev.Detail)
+	if strings.HasPrefix(ev.Detail.BuildInformation.Initiator, codebuildPipelineInitiator) {
+		return nil
+	}
+
+	// Use the configuration of the account that sent the event
+	account, err := h.forAccount(ctx, ev.Account)
+	if err != nil {
+		return err
+	}
+	return account.postBuildStatus(ctx, ev)
+}
+
+// postBuildStatus will post the status of a build with the configuration of the account that sent the event
+func (h *Handler) postBuildStatus(ctx context.Context, ev Event) (err error) {
+
+	// Muted projects are acknowledged without posting a status
+	if h.isMuted(ctx, ev.Detail.ProjectName) {
+		return nil
+	}
+
+	// Pending build updates are low priority near the daily budget
+	state := buildStates[ev.Detail.BuildStatus]
+	if state == githubStatePending && h.coalescing() {
+		logf(ctx, "skipping build %s update of %s near the github budget", ev.Detail.BuildStatus, ev.Detail.ProjectName)
+		return nil
+	}
+	ctx, githubCalls := withGithubCalls(ctx)
+	defer h.recordBudget(ctx, githubCalls)
+
+	// Find the commit and the repository of the build
+	var commit string
+	if commit, err = h.buildCommit(ctx, ev); err != nil {
+		return err
+	}
+	var revisionURL *url.URL
+	if revisionURL, err = buildRevisionURL(ev.Detail.BuildInformation.Source, commit); err != nil {
+		return err
+	} else if err = validateArtifact(commit, revisionURL, h.forgeHosts()); err != nil {
+		reportArtifactError(ctx, ev.Detail.ProjectName, ev.Detail.BuildID, err)
+		return err
+	}
+	owner, repo := revisionRepository(revisionURL, h.forgeHosts())
+	ctx = withLogCommit(ctx, owner, repo, commit)
+
+	// Link the build in the console (the id is project:build)
+	buildID := ev.Detail.BuildID
+	if i := strings.LastIndex(buildID, "/"); i >= 0 {
+		buildID = buildID[i+1:]
+	}
+	return h.postStatus(ctx, ev.Detail.ProjectName, revisionURL, StatusUpdate{
+		Commit:      commit,
+		Context:     codebuildContextPrefix + ev.Detail.ProjectName,
+		Description: joinDescription("build " + strings.ToLower(strings.ReplaceAll(ev.Detail.BuildStatus, "_", " "))),
+		Owner:       owner,
+		Repo:        repo,
+		State:       state,
+		TargetURL: consoleURL(h.eventPartition(ev), h.cfg.AWSRegion, fmt.Sprintf(
+			"/codesuite/codebuild/projects/%s/build/%s", ev.Detail.ProjectName, url.PathEscape(buildID))),
+	})
+}
@@ -0,0 +1,282 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/codebuild/codebuildiface"
+)
+
+// testBuildLogsURL is the log stream of the builds of mockCodeBuildClient
+const testBuildLogsURL = "https://console.aws.amazon.com/cloudwatch/home?region=us-east-1#logEvent:group=/aws/codebuild/web;stream=4f2a9c1b"
+
+// mockCodeBuildClient resolves the source version of builds
+type mockCodeBuildClient struct {
+	codebuildiface.CodeBuildAPI
+}
+
+// BatchGetBuildsWithContext will return the build with the resolved source version
+func (m *mockCodeBuildClient) BatchGetBuildsWithContext(_ aws.Context, input *codebuild.BatchGetBuildsInput,
+	_ ...request.Option) (*codebuild.BatchGetBuildsOutput, error) {
+	if aws.StringValue(input.Ids[0]) == "web:missing" {
+		return &codebuild.BatchGetBuildsOutput{}, nil
+	}
+	return &codebuild.BatchGetBuildsOutput{Builds: []*codebuild.Build{{
+		Id:                    input.Ids[0],
+		Logs:                  &codebuild.LogsLocation{DeepLink: aws.String(testBuildLogsURL)},
+		ResolvedSourceVersion: aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+	}}}, nil
+}
+
+// newBuildEvent will return a build state change event of the web project
+func newBuildEvent(status, initiator, sourceVersion string) Event {
+	return Event{DetailType: detailTypeBuildState, Detail: &Detail{
+		BuildID: "arn:aws:codebuild:us-east-1:1234567890123:build/web:0123",
+		BuildInformation: &buildInformation{
+			Initiator:     initiator,
+			Source:        buildSource{Location: "https://github.com/mrz1836/codepipeline-to-github.git", Type: codebuild.SourceTypeGithub},
+			SourceVersion: sourceVersion,
+		},
+		BuildStatus: status,
+		ProjectName: "web",
+	}}
+}
+
+// TestValidateBuildEvent will test validateBuildEvent()
+func TestValidateBuildEvent(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		modify   func(d *Detail)
+		expected string
+	}{
+		{"valid", func(d *Detail) {}, ""},
+		{"project", func(d *Detail) { d.ProjectName = "" }, "missing event param project-name"},
+		{"build", func(d *Detail) { d.BuildID = "" }, "missing event param build-id"},
+		{"information", func(d *Detail) { d.BuildInformation = nil }, "missing event param additional-information"},
+		{"status", func(d *Detail) { d.BuildStatus = "QUEUED" }, "unknown build status: QUEUED"},
+	}
+
+	for _, test := range tests {
+		ev := newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "pr/12")
+		test.modify(ev.Detail)
+		if err := validateBuildEvent(ev); err == nil && len(test.expected) > 0 {
+			t.Errorf("%s Failed: [%s] expected to throw an error, but no error", t.Name(), test.name)
+		} else if err != nil && err.Error() != test.expected {
+			t.Errorf("%s Failed: [%s] expected [%s] but got [%s]", t.Name(), test.name, test.expected, err.Error())
+		}
+	}
+}
+
+// TestBuildRevisionURL will test buildRevisionURL()
+func TestBuildRevisionURL(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		source   buildSource
+		expected string
+	}{
+		{buildSource{Location: "https://github.com/mrz1836/repo.git", Type: codebuild.SourceTypeGithub}, "https://github.com/mrz1836/repo/commit/25c0c3e"},
+		{buildSource{Location: "https://github.example.com/mrz1836/repo/", Type: codebuild.SourceTypeGithubEnterprise}, "https://github.example.com/mrz1836/repo/commit/25c0c3e"},
+		{buildSource{Location: "https://gitlab.com/group/repo.git", Type: codebuild.SourceTypeGitlab}, "https://gitlab.com/group/repo/-/commit/25c0c3e"},
+		{buildSource{Location: "https://gitlab.example.com/group/repo", Type: codebuild.SourceTypeGitlabSelfManaged}, "https://gitlab.example.com/group/repo/-/commit/25c0c3e"},
+		{buildSource{Location: "https://bitbucket.org/mrz1836/repo.git", Type: codebuild.SourceTypeBitbucket}, "https://bitbucket.org/mrz1836/repo/commit/25c0c3e"},
+	}
+
+	for _, test := range tests {
+		if output, err := buildRevisionURL(test.source, "25c0c3e"); err != nil {
+			t.Errorf("%s Failed: [%v] inputted and error not expected but got: %s", t.Name(), test.source, err.Error())
+		} else if output.String() != test.expected {
+			t.Errorf("%s Failed: [%v] inputted and [%s] expected, but got: %s", t.Name(), test.source, test.expected, output.String())
+		}
+	}
+}
+
+// TestHandlerProcessEventBuild will test ProcessEvent() posting the status of a build
+func TestHandlerProcessEventBuild(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		CodeBuildEvents:      true,
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+
+	var received []payload
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var p payload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		received = append(received, p)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// The source version of a pull request build is resolved
+	if err := h.ProcessEvent(newBuildEvent("FAILED", "GitHub-Hookshot/1234567", "pr/12")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(received) != 1 {
+		t.Fatal("status was not posted", received)
+	} else if paths[0] != "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("path was not as expected", paths[0])
+	} else if received[0].Context != "codebuild/web" || received[0].State != githubStateFailure ||
+		received[0].Description != "build failed" {
+		t.Fatal("status was not as expected", received[0])
+	} else if received[0].TargetURL != "https://us-east-1.console.aws.amazon.com/codesuite/codebuild/projects/web/build/web:0123" {
+		t.Fatal("target url was not as expected", received[0].TargetURL)
+	}
+
+	// Builds of a pipeline are reported by the pipeline
+	if err := h.ProcessEvent(newBuildEvent("SUCCEEDED", "codepipeline/some-pipeline", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(received) != 1 {
+		t.Fatal("status should not have been posted", received)
+	}
+
+	// Unresolved source versions
+	ev := newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "refs/heads/main")
+	ev.Detail.BuildID = "web:missing"
+	if err := h.ProcessEvent(ev); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Disabled
+	h.cfg.CodeBuildEvents = false
+	if err := h.ProcessEvent(newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "pr/12")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(received) != 1 {
+		t.Fatal("status should not have been posted", received)
+	}
+}
+
+// TestHandleRequestBuild will test HandleRequest() and ProcessKinesisEvent() accepting build events
+func TestHandleRequestBuild(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", stageTesting)
+	defer os.Clearenv()
+	setupSession()
+
+	// Builds are validated with their own parameters (skipped as CODEBUILD_EVENTS is not enabled)
+	if _, err := HandleRequest(context.Background(), newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "pr/12")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	ev := newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "pr/12")
+	ev.Detail.ProjectName = ""
+	if _, err := HandleRequest(context.Background(), ev); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "missing event param project-name" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Build records are posted in Kinesis mode
+	h := newTestHandler(Config{CodeBuildEvents: true, GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	var posts int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusCreated)
+	})
+	response := h.ProcessKinesisEvent(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		newKinesisRecord(t, "1", newBuildEvent("SUCCEEDED", "GitHub-Hookshot/1234567", "pr/12")),
+	}})
+	if posts != 1 || len(response.BatchItemFailures) != 0 {
+		t.Fatal("build record was not posted", posts, response.BatchItemFailures)
+	}
+}
+
+// TestNewHandlerCodeBuild will test NewHandler() requiring the CodeBuild dependency
+func TestNewHandlerCodeBuild(t *testing.T) {
+	deps := newTestHandler(Config{}).deps
+	deps.CodeBuild = nil
+	if _, err := NewHandler(Config{CodeBuildEvents: true, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "missing dependency: CodeBuild" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
+
+// TestIsBuildEvent will test isBuildEvent()
+func TestIsBuildEvent(t *testing.T) {
+	t.Parallel()
+
+	if !isBuildEvent(newBuildEvent("SUCCEEDED", "", "")) {
+		t.Fatal("build event was not detected")
+	} else if isBuildEvent(Event{Detail: &Detail{Pipeline: "some-pipeline"}}) {
+		t.Fatal("pipeline event should not be a build event")
+	}
+}
+
+// TestBuildLogsURL will test Handler.buildLogsURL() linking the log stream of failed CodeBuild actions only
+func TestBuildLogsURL(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{LinkBuildLogs: true})
+	build := func(provider, buildID string) *types.ActionExecutionDetail {
+		return &types.ActionExecutionDetail{
+			Input: &types.ActionExecutionInput{ActionTypeId: &types.ActionTypeId{Provider: aws.String(provider)}},
+			Output: &types.ActionExecutionOutput{
+				ExecutionResult: &types.ActionExecutionResult{ExternalExecutionId: aws.String(buildID)},
+			},
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		action   *types.ActionExecutionDetail
+		expected string
+	}{
+		{"failed build", build(actionProviderCodeBuild, "web:4f2a9c1b"), testBuildLogsURL},
+		{"missing build", build(actionProviderCodeBuild, "web:missing"), ""},
+		{"no build id", build(actionProviderCodeBuild, ""), ""},
+		{"other provider", build("CloudFormation", "stack-id"), ""},
+		{"no input", &types.ActionExecutionDetail{}, ""},
+	}
+
+	for _, test := range tests {
+		if logsURL, err := h.buildLogsURL(context.Background(), test.action); err != nil {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.name, err.Error())
+		} else if logsURL != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.name, test.expected, logsURL)
+		}
+	}
+}
+
+// TestHandlerProcessEventLinkBuildLogs will test ProcessEvent() linking the log stream of the failed build
+func TestHandlerProcessEventLinkBuildLogs(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		LinkBuildLogs:        true,
+		Stage:                stageTesting,
+	})
+
+	var received payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.TargetURL != testBuildLogsURL {
+		t.Fatal("target url was not as expected", received.TargetURL)
+	}
+
+	// Other executions link the execution
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.TargetURL != "https://us-east-1.console.aws.amazon.com/codesuite/codepipeline/pipelines/status-succeed/executions/12345678" {
+		t.Fatal("target url was not as expected", received.TargetURL)
+	}
+}
@@ -0,0 +1,46 @@
+package pipelinestatus
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// codeCommitRepositoriesPath is the path of the CodeCommit revision urls in the console
+// (IE: https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/repo/commit/sha)
+const codeCommitRepositoriesPath = "/codesuite/codecommit/repositories/"
+
+// codeCommitRepository will return the CodeCommit repository of a revision url, empty for the other forges
+func codeCommitRepository(revisionURL *url.URL) string {
+	if !strings.HasPrefix(revisionURL.Path, codeCommitRepositoriesPath) {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(revisionURL.Path, codeCommitRepositoriesPath), "/")
+	if len(parts) < 3 || parts[1] != "commit" {
+		return ""
+	}
+	return parts[0]
+}
+
+// mirrorRevisionURL will return the url of the commit in the GitHub mirror of a CodeCommit repository
+// (CODECOMMIT_MIRRORS), the revision urls of the other forges are returned as they are
+func mirrorRevisionURL(revisionURL *url.URL, commit string, forgeHosts, mirrors map[string]string) (*url.URL, error) {
+	repository := codeCommitRepository(revisionURL)
+	if len(repository) == 0 {
+		return revisionURL, nil
+	}
+	mirror, ok := mirrors[repository]
+	if !ok {
+		return nil, &artifactError{
+			Message: "CodeCommit repository " + repository + " has no GitHub mirror in CODECOMMIT_MIRRORS",
+			Reason:  artifactNonGithubURL,
+		}
+	}
+	host := githubHost
+	for forgeHost, forge := range forgeHosts {
+		if forge == forgeGithub {
+			host = forgeHost
+		}
+	}
+	return url.Parse(fmt.Sprintf("https://%s/%s/commit/%s", host, mirror, commit))
+}
@@ -0,0 +1,97 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestCodeCommitRepository will test codeCommitRepository()
+func TestCodeCommitRepository(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		revisionURL        string
+		expectedRepository string
+	}{
+		{"https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/web/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "web"},
+		{"https://console.aws.amazon.com/codesuite/codecommit/repositories/web/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08?region=us-east-1", "web"},
+		{"https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/web/browse", ""},
+		{"https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", ""},
+	}
+
+	for _, test := range tests {
+		revisionURL, _ := url.Parse(test.revisionURL)
+		if output := codeCommitRepository(revisionURL); output != test.expectedRepository {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.revisionURL, test.expectedRepository, output)
+		}
+	}
+}
+
+// TestMirrorRevisionURL will test mirrorRevisionURL()
+func TestMirrorRevisionURL(t *testing.T) {
+	t.Parallel()
+
+	commit := "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"
+	mirrors := map[string]string{"web": "mrz1836/codepipeline-to-github"}
+	var tests = []struct {
+		revisionURL    string
+		forgeHosts     map[string]string
+		expectedURL    string
+		expectedReason string
+	}{
+		{"https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/web/commit/" + commit, nil,
+			"https://github.com/mrz1836/codepipeline-to-github/commit/" + commit, ""},
+		{"https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/web/commit/" + commit,
+			map[string]string{"github.example.com": forgeGithub}, "https://github.example.com/mrz1836/codepipeline-to-github/commit/" + commit, ""},
+		{"https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/api/commit/" + commit, nil, "", artifactNonGithubURL},
+		{"https://gitlab.com/mrz1836/codepipeline-to-github/-/commit/" + commit, nil,
+			"https://gitlab.com/mrz1836/codepipeline-to-github/-/commit/" + commit, ""},
+	}
+
+	for _, test := range tests {
+		revisionURL, _ := url.Parse(test.revisionURL)
+		output, err := mirrorRevisionURL(revisionURL, commit, test.forgeHosts, mirrors)
+		if len(test.expectedReason) > 0 {
+			if err == nil || err.(*artifactError).Reason != test.expectedReason {
+				t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%v]", t.Name(), test.revisionURL, test.expectedReason, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.revisionURL, err.Error())
+		} else if output.String() != test.expectedURL {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.revisionURL, test.expectedURL, output)
+		}
+	}
+}
+
+// TestGetCommitFromExecutionCodeCommit will test getCommitFromExecution() with a mirrored CodeCommit revision
+func TestGetCommitFromExecutionCodeCommit(t *testing.T) {
+	t.Parallel()
+
+	executionOutput := &codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{
+		ArtifactRevisions: []types.ArtifactRevision{{
+			Name:        aws.String(sourceArtifactName),
+			RevisionId:  aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+			RevisionUrl: aws.String("https://us-east-1.console.aws.amazon.com/codesuite/codecommit/repositories/web/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+		}},
+	}}
+
+	_, _, revisionURL, err := getCommitFromExecution(context.Background(), executionOutput, sourceArtifactName, nil,
+		map[string]string{"web": "mrz1836/codepipeline-to-github"})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if owner, repo := revisionRepository(revisionURL, nil); owner != "mrz1836" || repo != "codepipeline-to-github" {
+		t.Fatal("repository was not as expected", revisionURL)
+	}
+
+	// Without a mirror
+	if _, _, _, err = getCommitFromExecution(context.Background(), executionOutput, sourceArtifactName, nil, nil); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.(*artifactError).Reason != artifactNonGithubURL {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
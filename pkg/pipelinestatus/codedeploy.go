@@ -0,0 +1,237 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+)
+
+// CodeDeploy events (the deployments of a commit become GitHub deployments of the deployment group environment)
+const (
+	codedeployDeploymentTask  = "deploy:codedeploy"
+	detailTypeDeploymentState = "CodeDeploy Deployment State-change Notification"
+)
+
+// deploymentStates are the GitHub deployment states of the CodeDeploy deployment states
+var deploymentStates = map[string]string{
+	"FAILURE": githubStateFailure,
+	"READY":   githubDeploymentInProgress, // blue/green, waiting to reroute the traffic
+	"START":   githubDeploymentInProgress,
+	"STOP":    githubStateError,
+	"SUCCESS": githubStateSuccess,
+}
+
+// isDeploymentEvent will return true if the event is a state change of a CodeDeploy deployment
+func isDeploymentEvent(ev Event) bool {
+	return ev.Detail != nil && ev.DetailType == detailTypeDeploymentState
+}
+
+// validateDeploymentEvent will check the deployment event for the required parameters
+func validateDeploymentEvent(ev Event) error {
+	if len(ev.Detail.Application) == 0 {
+		return errors.New("missing event param application")
+	} else if len(ev.Detail.DeploymentGroup) == 0 {
+		return errors.New("missing event param deploymentGroup")
+	} else if len(ev.Detail.DeploymentID) == 0 {
+		return errors.New("missing event param deploymentId")
+	} else if _, ok := deploymentStates[ev.Detail.State]; !ok {
+		return fmt.Errorf("unknown deployment state: %s", ev.Detail.State)
+	}
+	return nil
+}
+
+// getDeployment will return the details of a CodeDeploy deployment
+func (h *Handler) getDeployment(ctx context.Context, deploymentID string) (*codedeploy.DeploymentInfo, error) {
+	output, err := h.deps.CodeDeploy.GetDeploymentWithContext(ctx, &codedeploy.GetDeploymentInput{
+		DeploymentId: aws.String(deploymentID),
+	})
+	if err != nil {
+		return nil, err
+	} else if output.DeploymentInfo == nil {
+		return nil, fmt.Errorf("missing deployment: %s", deploymentID)
+	}
+	return output.DeploymentInfo, nil
+}
+
+// deploymentCommit will return the repository and the commit of a deployment, GitHub revisions name them,
+// other revisions (IE: S3 artifacts) are found in the executions of the pipeline in CODEDEPLOY_PIPELINES
+func (h *Handler) deploymentCommit(ctx context.Context, ev Event, info *codedeploy.DeploymentInfo) (owner, repo, commit string, err error) {
+	if info.Revision != nil && info.Revision.GitHubLocation != nil {
+		location := info.Revision.GitHubLocation
+		parts := strings.Split(aws.StringValue(location.Repository), "/")
+		if len(parts) != 2 || !commitSHA.MatchString(aws.StringValue(location.CommitId)) {
+			err = fmt.Errorf("invalid GitHub revision of deployment %s: %s@%s", ev.Detail.DeploymentID,
+				aws.StringValue(location.Repository), aws.StringValue(location.CommitId))
+			return
+		}
+		return parts[0], parts[1], aws.StringValue(location.CommitId), nil
+	}
+
+	// Find the execution of the pipeline that ran the deployment
+	pipelineName, ok := h.cfg.CodeDeployPipelines[ev.Detail.Application+"/"+ev.Detail.DeploymentGroup]
+	if !ok {
+		err = fmt.Errorf("deployment %s has no GitHub revision and %s/%s is not in CODEDEPLOY_PIPELINES",
+			ev.Detail.DeploymentID, ev.Detail.Application, ev.Detail.DeploymentGroup)
+		return
+	}
+	var executionID string
+	if executionID, err = deploymentExecution(ctx, pipelineName, ev.Detail.DeploymentID, h.deps.CodePipeline); err != nil {
+		return
+	}
+	var revisionURL *url.URL
+	if commit, _, revisionURL, err = getCommit(ctx, pipelineName, executionID, h.primaryArtifact(pipelineName),
+		h.forgeHosts(), h.cfg.CodeCommitMirrors, h.deps.CodePipeline); err != nil {
+		return
+	} else if revisionURL == nil || revisionForge(revisionURL, h.forgeHosts()) != forgeGithub {
+		err = fmt.Errorf("no GitHub revision in execution %s of pipeline %s", executionID, pipelineName)
+		return
+	}
+	owner, repo = revisionRepository(revisionURL, h.forgeHosts())
+	return
+}
+
+// deploymentExecution will return the pipeline execution of the action that started the deployment
+// (the latest action executions of the pipeline, newest first)
+func deploymentExecution(ctx context.Context, pipelineName, deploymentID string,
+	pipeline CodePipelineAPI) (executionID string, err error) {
+	var page *codepipeline.ListActionExecutionsOutput
+	if page, err = pipeline.ListActionExecutions(ctx, &codepipeline.ListActionExecutionsInput{
+		MaxResults:   aws.Int32(100),
+		PipelineName: aws.String(pipelineName),
+	}); err != nil {
+		return
+	}
+	for _, action := range page.ActionExecutionDetails {
+		if action.Output != nil && action.Output.ExecutionResult != nil &&
+			aws.StringValue(action.Output.ExecutionResult.ExternalExecutionId) == deploymentID {
+			executionID = aws.StringValue(action.PipelineExecutionId)
+			break
+		}
+	}
+	if len(executionID) == 0 {
+		err = fmt.Errorf("deployment %s not found in the latest executions of pipeline %s", deploymentID, pipelineName)
+	}
+	return
+}
+
+// deploymentWindow will return the boundaries of a deployment, from its creation to its completion
+// (the time of the event while it is in progress)
+func deploymentWindow(ev Event, info *codedeploy.DeploymentInfo) (start, end time.Time) {
+	start, end = aws.TimeValue(info.CreateTime), aws.TimeValue(info.CompleteTime)
+	if end.IsZero() {
+		end = ev.Time
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() || start.After(end) {
+		start = end
+	}
+	return
+}
+
+// processDeploymentEvent will create (or update) the GitHub deployment of a CodeDeploy deployment in the
+// environment named after the deployment group, so the deploy history shows in the Environments tab
+func (h *Handler) processDeploymentEvent(ctx context.Context, ev Event) error {
+	if !h.cfg.CodeDeployEvents {
+		logf(ctx, "skipping deployment of %s (CODEDEPLOY_EVENTS is not enabled)", ev.Detail.Application)
+		return nil
+	} else if err := validateDeploymentEvent(ev); err != nil {
+		return err
+	}
+	logf(ctx, "Incoming Deployment Details: %+v", ev.Detail)
+
+	// Use the configuration of the account that sent the event
+	account, err := h.forAccount(ctx, ev.Account)
+	if err != nil {
+		return err
+	}
+	return account.postDeployment(ctx, ev)
+}
+
+// postDeployment will create (or update) the GitHub deployment with the configuration of the account that sent
+// the event
+func (h *Handler) postDeployment(ctx context.Context, ev Event) (err error) {
+
+	// Muted applications are acknowledged without a deployment
+	if h.isMuted(ctx, ev.Detail.Application) {
+		return nil
+	}
+	ctx, githubCalls := withGithubCalls(ctx)
+	defer h.recordBudget(ctx, githubCalls)
+
+	// Find the commit that is deployed
+	var info *codedeploy.DeploymentInfo
+	if info, err = h.getDeployment(ctx, ev.Detail.DeploymentID); err != nil {
+		return err
+	}
+	var owner, repo, commit string
+	if owner, repo, commit, err = h.deploymentCommit(ctx, ev, info); err != nil {
+		return err
+	}
+	ctx = withLogCommit(ctx, owner, repo, commit)
+
+	// Mark the finished deployment in Honeycomb (the status links the traces of the deployment window)
+	state := deploymentStates[ev.Detail.State]
+	start, end := deploymentWindow(ev, info)
+	if state != githubDeploymentInProgress {
+		if err = h.postHoneycombMarker(ctx, honeycombMarker{
+			EndTime:   end.Unix(),
+			Message:   fmt.Sprintf("%s %s %s@%s %s", ev.Detail.Application, ev.Detail.DeploymentGroup, repo, shortSHA(commit), state),
+			StartTime: start.Unix(),
+			Type:      honeycombMarkerType,
+			URL:       fmt.Sprintf("https://%s/%s/%s/commit/%s", h.githubWebHost(), owner, repo, commit),
+		}); err != nil {
+			logWarnf(ctx, "unable to post the Honeycomb marker: %s", err.Error())
+		}
+	}
+
+	// Wait for our turn to write to GitHub
+	var release func()
+	if release, err = h.acquireGithubWrite(ctx); err != nil {
+		return err
+	}
+	defer release()
+
+	// Find or create the deployment, then post its state
+	environment := ev.Detail.DeploymentGroup
+	var deployment githubDeployment
+	if deployment, err = h.upsertGithubDeployment(ctx, owner, repo, &githubDeployment{
+		Description: joinDescription("CodeDeploy " + ev.Detail.Application + " " + ev.Detail.DeploymentID),
+		Environment: environment,
+		Payload: map[string]string{
+			"application":       ev.Detail.Application,
+			"deployment_group":  ev.Detail.DeploymentGroup,
+			"deployment_id":     ev.Detail.DeploymentID,
+			deploymentStartedAt: start.UTC().Format(time.RFC3339),
+		},
+		Ref:  commit,
+		Task: codedeployDeploymentTask,
+	}); err != nil {
+		return err
+	}
+	description := "deployment " + strings.ToLower(ev.Detail.State)
+	if state != githubDeploymentInProgress {
+		description = strings.TrimSpace(description + " " + deploymentDuration(deployment, end))
+	}
+	status := &githubDeploymentStatus{
+		AutoInactive: true,
+		Description:  joinDescription(description),
+		Environment:  environment,
+		LogURL: consoleURL(h.eventPartition(ev), h.cfg.AWSRegion,
+			"/codesuite/codedeploy/deployments/"+url.PathEscape(ev.Detail.DeploymentID)),
+		State: state,
+	}
+	if traceURL := h.honeycombTraceURL(start, end); len(traceURL) > 0 {
+		status.Description = joinDescription(status.Description, "traces of "+h.cfg.HoneycombDataset+" in Honeycomb")
+		status.EnvironmentURL = traceURL
+	}
+	return h.postDeploymentStatus(ctx, owner, repo, deployment.ID, status)
+}
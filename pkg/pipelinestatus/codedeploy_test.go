@@ -0,0 +1,266 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
+)
+
+// mockCodeDeployClient returns the revisions of the deployments (d-GITHUB is a GitHub revision,
+// d-PIPELINE an S3 revision of a pipeline)
+type mockCodeDeployClient struct {
+	codedeployiface.CodeDeployAPI
+}
+
+// GetDeploymentWithContext will return the deployment with its revision
+func (m *mockCodeDeployClient) GetDeploymentWithContext(_ aws.Context, input *codedeploy.GetDeploymentInput,
+	_ ...request.Option) (*codedeploy.GetDeploymentOutput, error) {
+	revision := &codedeploy.RevisionLocation{RevisionType: aws.String(codedeploy.RevisionLocationTypeS3)}
+	switch aws.StringValue(input.DeploymentId) {
+	case "d-GITHUB":
+		revision = &codedeploy.RevisionLocation{
+			GitHubLocation: &codedeploy.GitHubLocation{
+				CommitId:   aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+				Repository: aws.String("mrz1836/codepipeline-to-github"),
+			},
+			RevisionType: aws.String(codedeploy.RevisionLocationTypeGitHub),
+		}
+	case "d-INVALID":
+		revision = &codedeploy.RevisionLocation{GitHubLocation: &codedeploy.GitHubLocation{
+			CommitId:   aws.String("main"),
+			Repository: aws.String("mrz1836/codepipeline-to-github"),
+		}}
+	case "d-MISSING":
+		return nil, errors.New("DeploymentDoesNotExistException")
+	}
+	return &codedeploy.GetDeploymentOutput{DeploymentInfo: &codedeploy.DeploymentInfo{
+		CreateTime:   aws.Time(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)),
+		DeploymentId: input.DeploymentId,
+		Revision:     revision,
+	}}, nil
+}
+
+// mockDeployPipelineClient returns the deploy action of d-PIPELINE
+type mockDeployPipelineClient struct {
+	mockCodePipelineClient
+}
+
+// ListActionExecutions is a mock request for codepipeline
+func (m *mockDeployPipelineClient) ListActionExecutions(_ context.Context, _ *codepipeline.ListActionExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+	return &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: []types.ActionExecutionDetail{{
+		Output: &types.ActionExecutionOutput{
+			ExecutionResult: &types.ActionExecutionResult{ExternalExecutionId: aws.String("d-PIPELINE")},
+		},
+		PipelineExecutionId: aws.String("12345678"),
+	}}}, nil
+}
+
+// newDeploymentEvent will return a deployment state change event of the web application
+func newDeploymentEvent(deploymentID, state string) Event {
+	return Event{DetailType: detailTypeDeploymentState, Detail: &Detail{
+		Application:     "web",
+		DeploymentGroup: "production",
+		DeploymentID:    deploymentID,
+		State:           state,
+	}}
+}
+
+// TestValidateDeploymentEvent will test validateDeploymentEvent()
+func TestValidateDeploymentEvent(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		modify   func(d *Detail)
+		expected string
+	}{
+		{"valid", func(d *Detail) {}, ""},
+		{"application", func(d *Detail) { d.Application = "" }, "missing event param application"},
+		{"group", func(d *Detail) { d.DeploymentGroup = "" }, "missing event param deploymentGroup"},
+		{"deployment", func(d *Detail) { d.DeploymentID = "" }, "missing event param deploymentId"},
+		{"state", func(d *Detail) { d.State = "QUEUED" }, "unknown deployment state: QUEUED"},
+	}
+
+	for _, test := range tests {
+		ev := newDeploymentEvent("d-GITHUB", "START")
+		test.modify(ev.Detail)
+		if err := validateDeploymentEvent(ev); err == nil && len(test.expected) > 0 {
+			t.Errorf("%s Failed: [%s] expected to throw an error, but no error", t.Name(), test.name)
+		} else if err != nil && err.Error() != test.expected {
+			t.Errorf("%s Failed: [%s] expected [%s] but got [%s]", t.Name(), test.name, test.expected, err.Error())
+		}
+	}
+}
+
+// TestDeploymentCommit will test Handler.deploymentCommit()
+func TestDeploymentCommit(t *testing.T) {
+	h := newTestHandler(Config{CodeDeployPipelines: stringMap{"web/production": "status-succeed"}})
+	h.deps.CodePipeline = &mockDeployPipelineClient{}
+
+	var tests = []struct {
+		deploymentID  string
+		expectedError bool
+	}{
+		{"d-GITHUB", false},
+		{"d-PIPELINE", false},
+		{"d-INVALID", true},
+		{"d-MISSING", true},
+		{"d-UNKNOWN", true},
+	}
+
+	for _, test := range tests {
+		var owner, repo, commit string
+		info, err := h.getDeployment(context.Background(), test.deploymentID)
+		if err == nil {
+			owner, repo, commit, err = h.deploymentCommit(context.Background(), newDeploymentEvent(test.deploymentID, "START"), info)
+		}
+		if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.deploymentID, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.deploymentID)
+		} else if err == nil && (owner != "mrz1836" || repo != "codepipeline-to-github" || commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08") {
+			t.Errorf("%s Failed: [%s] inputted, but got: %s/%s@%s", t.Name(), test.deploymentID, owner, repo, commit)
+		}
+	}
+
+	// Deployments of other groups are not in the map
+	ev := newDeploymentEvent("d-PIPELINE", "START")
+	ev.Detail.DeploymentGroup = "staging"
+	info, _ := h.getDeployment(context.Background(), "d-PIPELINE")
+	if _, _, _, err := h.deploymentCommit(context.Background(), ev, info); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventDeployment will test ProcessEvent() creating and updating a GitHub deployment
+func TestHandlerProcessEventDeployment(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		CodeDeployEvents:     true,
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+
+	var deployments []githubDeployment
+	var statuses []githubDeploymentStatus
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			if r.URL.Query().Get("sha") != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" || r.URL.Query().Get("environment") != "production" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(deployments)
+		case r.URL.Path == "/repos/mrz1836/codepipeline-to-github/deployments":
+			var deployment githubDeployment
+			_ = json.NewDecoder(r.Body).Decode(&deployment)
+			deployment.ID = int64(len(deployments) + 1)
+			deployments = append(deployments, deployment)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(deployment)
+		case r.URL.Path == "/repos/mrz1836/codepipeline-to-github/deployments/1/statuses":
+			var status githubDeploymentStatus
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			statuses = append(statuses, status)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// The deployment is created once and updated with each state (the final state notes how long it took from
+	// the creation of the deployment in CodeDeploy)
+	for _, state := range []string{"START", "SUCCESS"} {
+		ev := newDeploymentEvent("d-GITHUB", state)
+		ev.Time = time.Date(2020, 5, 1, 12, 3, 20, 0, time.UTC)
+		if err := h.ProcessEvent(ev); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+	}
+	if len(deployments) != 1 || deployments[0].Ref != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" ||
+		deployments[0].Environment != "production" || deployments[0].Payload["deployment_id"] != "d-GITHUB" ||
+		deployments[0].Payload[deploymentStartedAt] != "2020-05-01T12:00:00Z" {
+		t.Fatal("deployments were not as expected", deployments)
+	} else if len(statuses) != 2 || statuses[0].State != "in_progress" || statuses[1].State != githubStateSuccess ||
+		statuses[1].Description != "deployment success in 3m 20s" {
+		t.Fatal("statuses were not as expected", statuses)
+	} else if statuses[1].LogURL != "https://us-east-1.console.aws.amazon.com/codesuite/codedeploy/deployments/d-GITHUB" {
+		t.Fatal("log url was not as expected", statuses[1].LogURL)
+	}
+
+	// Invalid events
+	if err := h.ProcessEvent(newDeploymentEvent("d-GITHUB", "QUEUED")); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Disabled
+	h.cfg.CodeDeployEvents = false
+	if err := h.ProcessEvent(newDeploymentEvent("d-GITHUB", "FAILURE")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(statuses) != 2 {
+		t.Fatal("status should not have been posted", statuses)
+	}
+}
+
+// TestHandleRequestDeployment will test HandleRequest() and ProcessKinesisEvent() accepting deployment events
+func TestHandleRequestDeployment(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", stageTesting)
+	defer os.Clearenv()
+	setupSession()
+
+	// Deployments are validated with their own parameters (skipped as CODEDEPLOY_EVENTS is not enabled)
+	if _, err := HandleRequest(context.Background(), newDeploymentEvent("d-GITHUB", "START")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = HandleRequest(context.Background(), newDeploymentEvent("", "START")); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "missing event param deploymentId" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Deployment records are posted in Kinesis mode
+	h := newTestHandler(Config{CodeDeployEvents: true, GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	var posts int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		posts++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	})
+	response := h.ProcessKinesisEvent(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		newKinesisRecord(t, "1", newDeploymentEvent("d-GITHUB", "START")),
+	}})
+	if posts != 2 || len(response.BatchItemFailures) != 0 {
+		t.Fatal("deployment record was not posted", posts, response.BatchItemFailures)
+	}
+}
+
+// TestNewHandlerCodeDeploy will test NewHandler() requiring the CodeDeploy dependency
+func TestNewHandlerCodeDeploy(t *testing.T) {
+	deps := newTestHandler(Config{}).deps
+	deps.CodeDeploy = nil
+	if _, err := NewHandler(Config{CodeDeployEvents: true, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "missing dependency: CodeDeploy" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
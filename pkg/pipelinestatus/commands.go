@@ -0,0 +1,401 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Available commands (IE: status permissions)
+const (
+	commandBackfill      = "backfill"
+	commandCosts         = "costs"
+	commandEnvironments  = "environments"
+	commandMigrate       = "migrate"
+	commandMute          = "mute"
+	commandPermissions   = "permissions"
+	commandReplay        = "replay"
+	commandSupportBundle = "support-bundle"
+	commandSync          = "sync"
+	commandTimeline      = "timeline"
+	commandTombstone     = "tombstone"
+)
+
+// RunCommand will run a command with the AWS session of the environment (IE: the sync and backfill commands of
+// cmd/statusctl)
+func RunCommand(name string, args []string, out io.Writer) error {
+	setupSession()
+	return runCommand(name, args, out)
+}
+
+// runCommand will run a command instead of the lambda handler
+func runCommand(name string, args []string, out io.Writer) error {
+	switch name {
+	case commandBackfill:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return backfillCommand(args, out, h)
+	case commandCosts:
+		return costsCommand(args, out, dynamodb.New(awsSession))
+	case commandEnvironments:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return environmentsCommand(args, out, h)
+	case commandMigrate:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return migrateCommand(args, out, h)
+	case commandMute:
+		return muteCommand(args, out, dynamodb.New(awsSession))
+	case commandPermissions:
+		return permissionsCommand(args, out)
+	case commandReplay:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return replayCommand(args, out, h, sqs.New(awsSession))
+	case commandSetupApp:
+		return setupAppCommand(args, out, os.Stderr, secretsmanager.New(awsSession), ssm.New(awsSession))
+	case commandSupportBundle:
+		return supportBundleCommand(args, out, supportBundleServices{
+			DynamoDB: dynamodb.New(awsSession), IAM: iam.New(awsSession), Lambda: lambda.New(awsSession),
+		})
+	case commandSync:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return syncCommand(args, out, h)
+	case commandTimeline:
+		return timelineCommand(args, out, dynamodb.New(awsSession))
+	case commandTombstone:
+		h, err := handlerFromEnvironment(context.Background())
+		if err != nil {
+			return err
+		}
+		return tombstoneCommand(args, out, h)
+	default:
+		return fmt.Errorf("unknown command: %s (available: %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)", name,
+			commandBackfill, commandCosts, commandEnvironments, commandMigrate, commandMute, commandPermissions, commandReplay,
+			commandSetupApp, commandSupportBundle, commandSync, commandTimeline, commandTombstone)
+	}
+}
+
+// costsReport is the usage of each pipeline for a month
+type costsReport struct {
+	Period    string        `json:"period"`
+	Pipelines []usageRecord `json:"pipelines"`
+}
+
+// expandPath will expand a leading ~ to the home directory and use the separators of the platform
+// (shells like PowerShell and cmd.exe do not expand ~ and paths are often written with /, a \ is only a
+// separator on Windows)
+func expandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(os.PathSeparator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = home + path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), nil
+}
+
+// outputFlag will add the output format flag to a command
+func outputFlag(flags *flag.FlagSet, defaultFormat string) *string {
+	return flags.String("output", defaultFormat, "output format ("+outputJSON+", "+outputTable+" or "+outputYAML+")")
+}
+
+// costsCommand will print the usage of each pipeline for a month (IE: status costs -period 2020-05 -output json)
+func costsCommand(args []string, out io.Writer, dynamoSvc dynamodbiface.DynamoDBAPI) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandCosts, flag.ContinueOnError)
+	output := outputFlag(flags, outputTable)
+	period := flags.String("period", usagePeriod(time.Now()), "month to report on (YYYY-MM)")
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	// Load the configuration
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	} else if len(cfg.UsageTable) == 0 {
+		return errors.New("missing USAGE_TABLE, usage is not being recorded")
+	}
+
+	// Get the usage for the period
+	report := costsReport{Period: *period}
+	if report.Pipelines, err = getUsage(context.Background(), dynamoSvc, cfg.UsageTable, *period); err != nil {
+		return
+	} else if report.Pipelines == nil {
+		report.Pipelines = []usageRecord{}
+	}
+	sort.Slice(report.Pipelines, func(i, j int) bool {
+		return report.Pipelines[i].Pipeline < report.Pipelines[j].Pipeline
+	})
+
+	// Write the report
+	return writeOutput(out, *output, report, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tINVOCATIONS\tGITHUB CALLS\tCODEBUILD MINUTES")
+		for _, record := range report.Pipelines {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\n",
+				record.Pipeline, record.Invocations, record.GithubCalls, float64(record.CodeBuildSeconds)/60)
+		}
+	})
+}
+
+// environmentsCommand will sync the GitHub environment protection rules (required reviewers and wait timers)
+// of each pipeline from a YAML definition (IE: status environments -file environments.yml -dry-run)
+func environmentsCommand(args []string, out io.Writer, h *Handler) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandEnvironments, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the environments without changing GitHub")
+	file := flags.String("file", "", "YAML definition of the environments of each pipeline")
+	output := outputFlag(flags, outputTable)
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*file) == 0 {
+		return errors.New("missing flag -file")
+	}
+
+	// Load the definitions
+	var path string
+	if path, err = expandPath(*file); err != nil {
+		return
+	}
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var definitions environmentDefinitions
+	if definitions, err = loadEnvironmentDefinitions(f); err != nil {
+		return
+	}
+
+	// Sync the environments
+	var results []environmentSync
+	if results, err = h.syncEnvironments(context.Background(), definitions, *dryRun); err != nil {
+		return
+	} else if results == nil {
+		results = []environmentSync{}
+	}
+
+	// Write the results
+	return writeOutput(out, *output, results, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tREPOSITORY\tENVIRONMENT\tREVIEWERS\tWAIT TIMER")
+		for _, result := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%dm\n",
+				result.Pipeline, result.Repository, result.Environment, strings.Join(result.Reviewers, ","), result.WaitTimer)
+		}
+	})
+}
+
+// migrateCommand will move the recent statuses of a pipeline's repository from legacy contexts to the
+// pipeline's context (IE: status migrate -pipeline X -legacy ci/jenkins,ci/travis -dry-run)
+func migrateCommand(args []string, out io.Writer, h *Handler) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandMigrate, flag.ContinueOnError)
+	commits := flags.Int("commits", 20, "number of recent commits of the branch to scan (at most 100)")
+	dryRun := flags.Bool("dry-run", false, "report without posting statuses")
+	legacy := flags.String("legacy", "", "comma separated legacy status contexts")
+	output := outputFlag(flags, outputTable)
+	pipelineName := flags.String("pipeline", "", "name of the pipeline")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*pipelineName) == 0 {
+		return errors.New("missing flag -pipeline")
+	} else if len(*legacy) == 0 {
+		return errors.New("missing flag -legacy")
+	} else if *commits < 1 || *commits > 100 {
+		return errors.New("flag -commits must be between 1 and 100")
+	}
+
+	// Migrate the statuses
+	var report migrationReport
+	if report, err = h.migrateStatuses(context.Background(), *pipelineName, strings.Split(*legacy, ","), *commits, *dryRun); err != nil {
+		return
+	}
+
+	// Write the report
+	return writeOutput(out, *output, report, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "%s@%s -> %s\n", report.Repository, report.Branch, report.Context)
+		_, _ = fmt.Fprintln(w, "COMMIT\tLEGACY CONTEXTS\tREPLAYED STATE")
+		for _, commit := range report.Commits {
+			state := commit.State
+			if len(state) == 0 {
+				state = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", shortSHA(commit.SHA), strings.Join(commit.LegacyContexts, ","), state)
+		}
+		for _, update := range report.ProtectionUpdates {
+			_, _ = fmt.Fprintf(w, "branch protection: %s\n", update)
+		}
+	})
+}
+
+// muteCommand will stop posting the statuses of a pipeline for a while (IE: status mute -pipeline X -for 2h),
+// a duration of 0 unmutes the pipeline
+func muteCommand(args []string, out io.Writer, dynamoSvc dynamodbiface.DynamoDBAPI) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandMute, flag.ContinueOnError)
+	duration := flags.Duration("for", 0, "how long to mute the pipeline (IE: 2h, 0 to unmute)")
+	output := outputFlag(flags, outputTable)
+	pipelineName := flags.String("pipeline", "", "name of the pipeline")
+	reason := flags.String("reason", "", "why the pipeline is muted")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*pipelineName) == 0 {
+		return errors.New("missing flag -pipeline")
+	} else if *duration < 0 {
+		return errors.New("flag -for cannot be negative")
+	}
+
+	// Load the configuration
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	} else if len(cfg.MuteTable) == 0 {
+		return errors.New("missing MUTE_TABLE, pipelines cannot be muted")
+	}
+
+	// Store the mute window
+	window := muteWindow{Pipeline: *pipelineName, Reason: *reason, Until: time.Now().Add(*duration).UTC().Truncate(time.Second)}
+	if err = muteStatuses(context.Background(), dynamoSvc, cfg.MuteTable, window); err != nil {
+		return
+	}
+
+	// Write the mute window
+	return writeOutput(out, *output, window, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tMUTED UNTIL\tREASON")
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", window.Pipeline, window.Until.Format(time.RFC3339), window.Reason)
+	})
+}
+
+// permissionsCommand will print the IAM policy needed by the function for the current configuration
+func permissionsCommand(args []string, out io.Writer) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandPermissions, flag.ContinueOnError)
+	output := outputFlag(flags, outputJSON)
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	// Load the configuration
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	}
+
+	// Write the policy
+	policy := requiredPolicy(cfg)
+	return writeOutput(out, *output, policy, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "SID\tACTIONS\tRESOURCES")
+		for _, statement := range policy.Statement {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n",
+				statement.Sid, strings.Join(statement.Action, ","), strings.Join(statement.Resource, ","))
+		}
+	})
+}
+
+// timelineCommand will print the state transitions of a commit (IE: status timeline -commit 25c0c3e)
+func timelineCommand(args []string, out io.Writer, dynamoSvc dynamodbiface.DynamoDBAPI) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandTimeline, flag.ContinueOnError)
+	commit := flags.String("commit", "", "full sha of the commit")
+	output := outputFlag(flags, outputTable)
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*commit) == 0 {
+		return errors.New("missing flag -commit")
+	}
+
+	// Load the configuration
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	} else if len(cfg.TimelineTable) == 0 {
+		return errors.New("missing TIMELINE_TABLE, the timeline is not being recorded")
+	}
+
+	// Get the timeline of the commit
+	var entries []timelineEntry
+	if entries, err = getTimeline(context.Background(), dynamoSvc, cfg.TimelineTable, *commit); err != nil {
+		return
+	} else if entries == nil {
+		entries = []timelineEntry{}
+	}
+
+	// Write the timeline
+	return writeOutput(out, *output, entries, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "TIME\tPIPELINE\tEXECUTION\tSTATE")
+		for _, entry := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				entry.Time.Format(time.RFC3339), entry.Pipeline, entry.ExecutionID, entry.State)
+		}
+	})
+}
+
+// tombstoneCommand will retire the obsolete contexts of the renamed pipelines (PIPELINE_RENAMES) on the open
+// pull requests (IE: status tombstone -pipeline old-name -dry-run)
+func tombstoneCommand(args []string, out io.Writer, h *Handler) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandTombstone, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report without posting statuses")
+	output := outputFlag(flags, outputTable)
+	pipelineName := flags.String("pipeline", "", "old name of the renamed pipeline (all renamed pipelines if empty)")
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	// Retire the contexts
+	var reports []tombstoneReport
+	if reports, err = h.tombstoneContexts(context.Background(), *pipelineName, *dryRun); err != nil {
+		return
+	}
+
+	// Write the reports
+	return writeOutput(out, *output, reports, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tRENAMED TO\tOBSOLETE CONTEXT\tCOMMITS")
+		for _, report := range reports {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", report.Pipeline, report.RenamedTo, report.Context, len(report.Commits))
+		}
+		for _, report := range reports {
+			for _, update := range report.ProtectionUpdates {
+				_, _ = fmt.Fprintf(w, "branch protection of %s@%s: %s\n", report.Repository, report.Branch, update)
+			}
+		}
+	})
+}
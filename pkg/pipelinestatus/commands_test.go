@@ -0,0 +1,82 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunCommand will test runCommand()
+func TestRunCommand(t *testing.T) {
+
+	os.Clearenv()
+
+	t.Run("unknown command", func(t *testing.T) {
+		if err := runCommand("unknown", nil, &bytes.Buffer{}); err == nil {
+			t.Fatal("error should have occurred")
+		}
+	})
+
+	t.Run("permissions missing configuration", func(t *testing.T) {
+		if err := runCommand(commandPermissions, nil, &bytes.Buffer{}); err == nil {
+			t.Fatal("error should have occurred")
+		}
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		_ = os.Setenv("AWS_REGION", "us-east-1")
+		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+		_ = os.Setenv("APPLICATION_STAGE_NAME", "production")
+		_ = os.Setenv("RATE_LIMIT_TABLE", "limits")
+		defer os.Clearenv()
+
+		var out bytes.Buffer
+		if err := runCommand(commandPermissions, nil, &out); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+
+		var policy policyDocument
+		if err := json.Unmarshal(out.Bytes(), &policy); err != nil {
+			t.Fatal("output was not valid json", err.Error())
+		} else if !hasAction(policy, "dynamodb:PutItem") {
+			t.Fatal("missing dynamodb:PutItem", out.String())
+		}
+	})
+}
+
+// TestExpandPath will test expandPath()
+func TestExpandPath(t *testing.T) {
+	// Other tests clear the environment
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = t.TempDir()
+		_ = os.Setenv("HOME", home)
+		_ = os.Setenv("USERPROFILE", home)
+		defer func() {
+			_ = os.Unsetenv("HOME")
+			_ = os.Unsetenv("USERPROFILE")
+		}()
+	}
+
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"~", home},
+		{"~/environments.yml", filepath.Join(home, "environments.yml")},
+		{"config/environments.yml", filepath.Join("config", "environments.yml")},
+		{"./environments.yml", "environments.yml"},
+		{"~other/environments.yml", filepath.Join("~other", "environments.yml")},
+	}
+	tests = append(tests, platformPaths(home)...)
+
+	for _, test := range tests {
+		if path, err := expandPath(test.path); err != nil {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.path, err.Error())
+		} else if path != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.path, test.expected, path)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package pipelinestatus
+
+// platformPaths will return the paths of TestExpandPath written for unix (a \ is part of the name of the file)
+func platformPaths(_ string) []struct {
+	path     string
+	expected string
+} {
+	return []struct {
+		path     string
+		expected string
+	}{
+		{`~\environments.yml`, `~\environments.yml`},
+		{`config\environments.yml`, `config\environments.yml`},
+	}
+}
@@ -0,0 +1,18 @@
+package pipelinestatus
+
+import "path/filepath"
+
+// platformPaths will return the paths of TestExpandPath written for Windows (PowerShell and cmd.exe)
+func platformPaths(home string) []struct {
+	path     string
+	expected string
+} {
+	return []struct {
+		path     string
+		expected string
+	}{
+		{`~\environments.yml`, filepath.Join(home, "environments.yml")},
+		{`C:/config/environments.yml`, `C:\config\environments.yml`},
+		{`config\environments.yml`, `config\environments.yml`},
+	}
+}
@@ -0,0 +1,225 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// SSM provider defaults
+const (
+	configSSMPrefixEnv = "CONFIG_SSM_PREFIX"
+	configSSMTTLEnv    = "CONFIG_SSM_TTL"
+	defaultConfigTTL   = 5 * time.Minute
+)
+
+// ConfigProvider is a source of settings other than the environment, the settings are keyed by environment
+// variable name (IE: GITHUB_ACCESS_TOKEN) and returned decrypted
+type ConfigProvider interface {
+	Settings(ctx context.Context) (map[string]string, error)
+}
+
+// providerSettings will return the settings of the providers (later providers win)
+func providerSettings(ctx context.Context, providers []ConfigProvider) (settings map[string]string, err error) {
+	settings = make(map[string]string)
+	for _, provider := range providers {
+		var values map[string]string
+		if values, err = provider.Settings(ctx); err != nil {
+			return
+		}
+		for name, value := range values {
+			settings[name] = value
+		}
+	}
+	return
+}
+
+// processConfiguration will load the configuration from the environment and then the settings (IE: of the
+// providers), the required settings are checked once both are loaded
+func processConfiguration(cfg *Config, settings map[string]string) (provided map[string]bool, err error) {
+	if err = envconfig.Process("", cfg); err != nil {
+		return
+	} else if provided, err = applySettings(cfg, settings); err != nil {
+		return
+	} else if len(cfg.AWSRegion) == 0 {
+		err = errors.New("required key AWS_REGION missing value")
+	} else if len(cfg.Stage) == 0 {
+		err = errors.New("required key APPLICATION_STAGE_NAME missing value")
+	}
+	return
+}
+
+// applySettings will set the settings on the fields of the configuration by their envconfig name, the
+// environment wins over a provider, and return the names of the settings that came from a provider
+func applySettings(cfg *Config, settings map[string]string) (provided map[string]bool, err error) {
+	provided = make(map[string]bool)
+	value := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("envconfig")
+		setting, ok := settings[name]
+		if len(name) == 0 || !ok {
+			continue
+		} else if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		if err = decodeSetting(value.Field(i), setting); err != nil {
+			return nil, fmt.Errorf("invalid provided setting %s: %s", name, err.Error())
+		}
+		provided[name] = true
+	}
+	return
+}
+
+// decodeSetting will set the field from the value of the setting, the same way envconfig decodes the
+// environment variables (custom types implement envconfig.Decoder)
+func decodeSetting(field reflect.Value, value string) error {
+	if decoder, ok := field.Addr().Interface().(envconfig.Decoder); ok {
+		return decoder.Decode(value)
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		var values []string
+		if len(strings.TrimSpace(value)) > 0 {
+			values = strings.Split(value, ",")
+		}
+		field.Set(reflect.ValueOf(values).Convert(field.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// SSMProvider loads the settings from the parameters under a prefix of SSM Parameter Store (SecureString
+// parameters are decrypted), the parameters are cached for the TTL
+//
+// IE: /codepipeline-to-github/production/GITHUB_ACCESS_TOKEN, or a key of a JSON map setting per pipeline:
+// /codepipeline-to-github/production/CONTEXT_PREFIXES/payments
+type SSMProvider struct {
+	client    ssmiface.SSMAPI
+	fetchedAt time.Time
+	mu        sync.Mutex
+	prefix    string
+	settings  map[string]string
+	ttl       time.Duration
+}
+
+// NewSSMProvider will create a provider for the parameters under the prefix
+func NewSSMProvider(client ssmiface.SSMAPI, prefix string, ttl time.Duration) *SSMProvider {
+	return &SSMProvider{client: client, prefix: "/" + strings.Trim(prefix, "/"), ttl: ttl}
+}
+
+// Settings will return the settings of the parameters (fetched again once the TTL expires)
+func (p *SSMProvider) Settings(ctx context.Context) (map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.settings != nil && time.Since(p.fetchedAt) < p.ttl {
+		return p.settings, nil
+	}
+
+	settings := make(map[string]string)
+	maps := make(map[string]map[string]string)
+	if err := p.client.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path:           aws.String(p.prefix),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			name := strings.TrimPrefix(strings.TrimPrefix(aws.StringValue(parameter.Name), p.prefix), "/")
+			parts := strings.SplitN(name, "/", 2)
+			if len(parts) == 1 {
+				settings[name] = aws.StringValue(parameter.Value)
+				continue
+			}
+			if maps[parts[0]] == nil {
+				maps[parts[0]] = make(map[string]string)
+			}
+			maps[parts[0]][parts[1]] = aws.StringValue(parameter.Value)
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	// Keys of a map setting are merged into its JSON object
+	for name, values := range maps {
+		merged := make(map[string]string)
+		if existing, ok := settings[name]; ok {
+			if err := json.Unmarshal([]byte(existing), &merged); err != nil {
+				return nil, err
+			}
+		}
+		for key, value := range values {
+			merged[key] = value
+		}
+		b, err := json.Marshal(merged)
+		if err != nil {
+			return nil, err
+		}
+		settings[name] = string(b)
+	}
+
+	p.settings, p.fetchedAt = settings, time.Now()
+	return settings, nil
+}
+
+// Per-container SSM provider (keeps its cache between invocations)
+var (
+	ssmProvider     *SSMProvider
+	ssmProviderOnce sync.Once
+)
+
+// environmentProviders will return the providers configured in the environment (CONFIG_SSM_PREFIX)
+func environmentProviders(deps Dependencies) (providers []ConfigProvider) {
+	prefix := os.Getenv(configSSMPrefixEnv)
+	if len(prefix) == 0 || deps.SSM == nil {
+		return
+	}
+	ssmProviderOnce.Do(func() {
+		ttl, err := time.ParseDuration(os.Getenv(configSSMTTLEnv))
+		if err != nil {
+			ttl = defaultConfigTTL
+		}
+		ssmProvider = NewSSMProvider(deps.SSM, prefix, ttl)
+	})
+	return []ConfigProvider{ssmProvider}
+}
@@ -0,0 +1,148 @@
+package pipelinestatus
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// mockSSMClient returns the parameters under the path and counts the requests
+type mockSSMClient struct {
+	ssmiface.SSMAPI
+	calls      int
+	parameters map[string]string
+}
+
+// GetParametersByPathPagesWithContext is used for mocking the parameters of a path (one per page)
+func (m *mockSSMClient) GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput,
+	fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	m.calls++
+	for name, value := range m.parameters {
+		if strings.HasPrefix(name, aws.StringValue(input.Path)+"/") {
+			fn(&ssm.GetParametersByPathOutput{Parameters: []*ssm.Parameter{{Name: aws.String(name), Value: aws.String(value)}}}, false)
+		}
+	}
+	return nil
+}
+
+// staticProvider is a provider with fixed settings
+type staticProvider map[string]string
+
+// Settings will return the fixed settings
+func (p staticProvider) Settings(_ context.Context) (map[string]string, error) {
+	return p, nil
+}
+
+// TestSSMProviderSettings will test SSMProvider.Settings()
+func TestSSMProviderSettings(t *testing.T) {
+	t.Parallel()
+
+	mockSSM := &mockSSMClient{parameters: map[string]string{
+		"/codepipeline-to-github/production/APPLICATION_STAGE_NAME":    "production",
+		"/codepipeline-to-github/production/CONTEXT_PREFIXES":          `{"billing":"team-billing/ci"}`,
+		"/codepipeline-to-github/production/CONTEXT_PREFIXES/payments": "team-payments/ci",
+		"/codepipeline-to-github/development/APPLICATION_STAGE_NAME":   "development",
+	}}
+	provider := NewSSMProvider(mockSSM, "codepipeline-to-github/production/", time.Minute)
+
+	settings, err := provider.Settings(context.Background())
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(settings) != 2 || settings["APPLICATION_STAGE_NAME"] != "production" {
+		t.Fatal("settings were not as expected", settings)
+	} else if settings["CONTEXT_PREFIXES"] != `{"billing":"team-billing/ci","payments":"team-payments/ci"}` {
+		t.Fatal("map setting was not merged", settings["CONTEXT_PREFIXES"])
+	}
+
+	// Cached for the TTL
+	if _, err = provider.Settings(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if mockSSM.calls != 1 {
+		t.Fatal("parameters should have been fetched once", mockSSM.calls)
+	}
+}
+
+// TestProviderSettings will test providerSettings()
+func TestProviderSettings(t *testing.T) {
+	t.Parallel()
+
+	// Later providers win over earlier ones
+	settings, err := providerSettings(context.Background(), []ConfigProvider{
+		staticProvider{"MUTE_TABLE": "first", "SCHEDULED_CONTEXT": "from-provider"},
+		staticProvider{"MUTE_TABLE": "second"},
+	})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(settings) != 2 || settings["MUTE_TABLE"] != "second" || settings["SCHEDULED_CONTEXT"] != "from-provider" {
+		t.Fatal("settings were not as expected", settings)
+	}
+}
+
+// TestApplySettings will test applySettings()
+func TestApplySettings(t *testing.T) {
+	_ = os.Setenv("SCHEDULED_CONTEXT", "from-environment")
+	defer func() {
+		_ = os.Unsetenv("SCHEDULED_CONTEXT")
+	}()
+
+	// The environment wins, the environment is left untouched
+	cfg := Config{ScheduledContext: "from-environment"}
+	provided, err := applySettings(&cfg, map[string]string{
+		"NOTIFY_STATES":     "failure, success",
+		"FAILURE_COMMENT":   "true",
+		"NOTIFIER_TIMEOUT":  "5s",
+		"MUTE_TABLE":        "mute",
+		"PIPELINE_RENAMES":  `{"payments":"payments-v2"}`,
+		"SCHEDULED_CONTEXT": "from-provider",
+	})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if cfg.ScheduledContext != "from-environment" || provided["SCHEDULED_CONTEXT"] {
+		t.Fatal("environment should have been kept", cfg.ScheduledContext)
+	} else if cfg.MuteTable != "mute" || !provided["MUTE_TABLE"] {
+		t.Fatal("provided setting was not as expected", cfg.MuteTable)
+	} else if _, set := os.LookupEnv("MUTE_TABLE"); set {
+		t.Fatal("provided setting should not be set in the environment")
+	} else if !cfg.FailureComment || cfg.NotifierTimeout != 5*time.Second || cfg.PipelineRenames["payments"] != "payments-v2" {
+		t.Fatal("provided settings were not decoded", cfg.FailureComment, cfg.NotifierTimeout, cfg.PipelineRenames)
+	} else if len(cfg.NotifyStates) != 2 || cfg.NotifyStates[1] != " success" {
+		t.Fatal("provided list was not as expected", cfg.NotifyStates)
+	}
+
+	// Invalid values are returned as errors
+	if _, err = applySettings(&cfg, map[string]string{"FAILURE_COMMENT": "maybe"}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestLoadConfigurationProviders will test loadConfiguration() with the settings of a provider
+func TestLoadConfigurationProviders(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+
+	mockSSM := &mockSSMClient{parameters: map[string]string{
+		"/codepipeline-to-github/APPLICATION_STAGE_NAME":       "production",
+		"/codepipeline-to-github/GITHUB_ACCESS_TOKEN":          "ghp_decrypted",
+		"/codepipeline-to-github/PIPELINE_RENAMES/payments":    "payments-v2",
+		"/codepipeline-to-github/TOKEN_EXPIRY_WARNING_DAYS":    "7",
+		"/codepipeline-to-github-other/GITHUB_ACCESS_TOKEN":    "ghp_other",
+		"/codepipeline-to-github-other/APPLICATION_STAGE_NAME": "other",
+	}}
+	cfg, err := loadConfiguration(context.Background(), newContainerCache(), &mockKmsClient{}, nil, NewSSMProvider(mockSSM, "/codepipeline-to-github", time.Minute))
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if cfg.Stage != "production" || cfg.TokenExpiryWarningDays != 7 {
+		t.Fatal("configuration was not as expected", cfg.Stage, cfg.TokenExpiryWarningDays)
+	} else if cfg.GithubAccessToken != "ghp_decrypted" {
+		t.Fatal("provided token should not be decrypted again", cfg.GithubAccessToken)
+	} else if cfg.PipelineRenames["payments"] != "payments-v2" {
+		t.Fatal("per-pipeline setting was not as expected", cfg.PipelineRenames)
+	}
+}
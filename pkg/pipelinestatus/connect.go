@@ -0,0 +1,159 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Connect API of the server mode (the JSON codec of the Connect protocol, unary calls only), typed clients are
+// generated from status.proto (IE: with buf generate) and send SERVER_SECRET in the X-Api-Key header
+const (
+	connectCodeFailedPrecondition = "failed_precondition"
+	connectCodeInternal           = "internal"
+	connectCodeInvalidArgument    = "invalid_argument"
+	connectCodeNotFound           = "not_found"
+	connectCodeUnauthenticated    = "unauthenticated"
+	connectContentType            = "application/json"
+	connectService                = "/codepipelinetogithub.v1.StatusService/"
+	connectMethodEnvironmentState = connectService + "GetEnvironmentState"
+	connectMethodReplayEvent      = connectService + "ReplayEvent"
+	connectMethodSyncStatus       = connectService + "SyncStatus"
+)
+
+// connectStatusCodes are the HTTP statuses of the Connect error codes
+var connectStatusCodes = map[string]int{
+	connectCodeFailedPrecondition: http.StatusBadRequest,
+	connectCodeInternal:           http.StatusInternalServerError,
+	connectCodeInvalidArgument:    http.StatusBadRequest,
+	connectCodeNotFound:           http.StatusNotFound,
+	connectCodeUnauthenticated:    http.StatusUnauthorized,
+}
+
+// connectError is the error of a Connect call
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error will return the message of the error
+func (e *connectError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// syncStatusRequest is the request of SyncStatus (the fields are named like the JSON of the generated clients)
+type syncStatusRequest struct {
+	DryRun      bool   `json:"dryRun"`
+	ExecutionID string `json:"executionId"`
+	Pipeline    string `json:"pipeline"`
+}
+
+// environmentStateRequest is the request of GetEnvironmentState
+type environmentStateRequest struct {
+	Pipeline string `json:"pipeline"`
+}
+
+// replayEventRequest is the request of ReplayEvent (IE: an event copied from the dead-letter queue or the logs)
+type replayEventRequest struct {
+	Event *Event `json:"event"`
+}
+
+// environmentState is the commit last deployed by a pipeline (ENVIRONMENT_TABLE)
+type environmentState struct {
+	Commit    string    `json:"commit"`
+	Pipeline  string    `json:"pipeline"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// environmentState will return the commit last deployed by the pipeline (ENVIRONMENT_TABLE)
+func (h *Handler) environmentState(ctx context.Context, pipelineName string) (state environmentState, err error) {
+	if len(h.cfg.EnvironmentTable) == 0 {
+		return state, &connectError{Code: connectCodeFailedPrecondition,
+			Message: "missing ENVIRONMENT_TABLE, the deploys are not being recorded"}
+	}
+	state.Pipeline = pipelineName
+	if state.Commit, state.UpdatedAt, err = getDeploy(ctx, h.deps.DynamoDB, h.cfg.EnvironmentTable, pipelineName); err != nil {
+		return
+	} else if len(state.Commit) == 0 {
+		err = &connectError{Code: connectCodeNotFound, Message: "no deploy recorded for pipeline " + pipelineName}
+	}
+	return
+}
+
+// connectCall will decode the request of a Connect call and write the response of the method (or its error)
+func connectCall(w http.ResponseWriter, r *http.Request, secret string, request interface{},
+	method func(ctx context.Context) (interface{}, error)) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	} else if !strings.HasPrefix(r.Header.Get("Content-Type"), connectContentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	} else if !authorized(r, secret) {
+		writeConnectError(r.Context(), w, &connectError{Code: connectCodeUnauthenticated, Message: "unauthorized"})
+		return
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, serverMaxEventBytes)).Decode(request); err != nil {
+		writeConnectError(r.Context(), w, &connectError{Code: connectCodeInvalidArgument, Message: "invalid request: " + err.Error()})
+		return
+	}
+	response, err := method(r.Context())
+	if err != nil {
+		writeConnectError(r.Context(), w, err)
+		return
+	}
+	w.Header().Set("Content-Type", connectContentType)
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		logWarnf(r.Context(), "unable to write the response: %s", err.Error())
+	}
+}
+
+// writeConnectError will write the error of a Connect call (other errors are internal)
+func writeConnectError(ctx context.Context, w http.ResponseWriter, err error) {
+	e, ok := err.(*connectError)
+	if !ok {
+		logErrorf(ctx, "unable to complete the call: %s", err.Error())
+		e = &connectError{Code: connectCodeInternal, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", connectContentType)
+	w.WriteHeader(connectStatusCodes[e.Code])
+	if err = json.NewEncoder(w).Encode(e); err != nil {
+		logWarnf(ctx, "unable to write the response: %s", err.Error())
+	}
+}
+
+// handleConnect will route the methods of the Connect API on the mux of the server
+func handleConnect(mux *http.ServeMux, secret string, h serverHandler) {
+	mux.HandleFunc(connectMethodSyncStatus, func(w http.ResponseWriter, r *http.Request) {
+		var request syncStatusRequest
+		connectCall(w, r, secret, &request, func(ctx context.Context) (interface{}, error) {
+			if len(request.Pipeline) == 0 || len(request.ExecutionID) == 0 {
+				return nil, &connectError{Code: connectCodeInvalidArgument, Message: "missing pipeline or executionId"}
+			}
+			return h.syncStatus(ctx, request.Pipeline, request.ExecutionID, request.DryRun)
+		})
+	})
+	mux.HandleFunc(connectMethodEnvironmentState, func(w http.ResponseWriter, r *http.Request) {
+		var request environmentStateRequest
+		connectCall(w, r, secret, &request, func(ctx context.Context) (interface{}, error) {
+			if len(request.Pipeline) == 0 {
+				return nil, &connectError{Code: connectCodeInvalidArgument, Message: "missing pipeline"}
+			}
+			return h.environmentState(ctx, request.Pipeline)
+		})
+	})
+	mux.HandleFunc(connectMethodReplayEvent, func(w http.ResponseWriter, r *http.Request) {
+		var request replayEventRequest
+		connectCall(w, r, secret, &request, func(ctx context.Context) (interface{}, error) {
+			if request.Event == nil {
+				return nil, &connectError{Code: connectCodeInvalidArgument, Message: "missing event"}
+			} else if err := validateEvent(*request.Event); err != nil {
+				return nil, &connectError{Code: connectCodeInvalidArgument, Message: err.Error()}
+			}
+			return struct{}{}, h.processServerEvent(ctx, *request.Event)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package pipelinestatus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestConnectAPI will test the methods of the Connect API of the server
+func TestConnectAPI(t *testing.T) {
+	t.Parallel()
+
+	h := &mockServerHandler{}
+	server := httptest.NewServer(newServerMux("s3cr3t", h))
+	defer server.Close()
+
+	call := func(method, path, contentType, secret, body string) (int, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(serverHeaderSecret, secret)
+		var response *http.Response
+		if response, err = http.DefaultClient.Do(req); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+		defer func() {
+			_ = response.Body.Close()
+		}()
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return response.StatusCode, strings.TrimSpace(string(resBody))
+	}
+
+	var tests = []struct {
+		name         string
+		method       string
+		path         string
+		contentType  string
+		secret       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"sync", http.MethodPost, connectMethodSyncStatus, connectContentType, "s3cr3t", `{"pipeline":"web","executionId":"1"}`,
+			http.StatusOK, `{"executions":[{"execution_id":"1","result":"synced","state":"SUCCEEDED"}],"pipeline":"web"}`},
+		{"sync dry run", http.MethodPost, connectMethodSyncStatus, connectContentType, "s3cr3t", `{"pipeline":"web","executionId":"1","dryRun":true}`,
+			http.StatusOK, `{"executions":[{"execution_id":"1","result":"ready","state":"SUCCEEDED"}],"pipeline":"web"}`},
+		{"sync failed", http.MethodPost, connectMethodSyncStatus, connectContentType, "s3cr3t", `{"pipeline":"web","executionId":"missing"}`,
+			http.StatusInternalServerError, `{"code":"internal","message":"execution not found"}`},
+		{"sync without execution", http.MethodPost, connectMethodSyncStatus, connectContentType, "s3cr3t", `{"pipeline":"web"}`,
+			http.StatusBadRequest, `{"code":"invalid_argument","message":"missing pipeline or executionId"}`},
+		{"environment", http.MethodPost, connectMethodEnvironmentState, connectContentType, "s3cr3t", `{"pipeline":"web"}`,
+			http.StatusOK, `{"commit":"25c0c3e","pipeline":"web","updated_at":"2020-05-01T12:00:00Z"}`},
+		{"unknown environment", http.MethodPost, connectMethodEnvironmentState, connectContentType, "s3cr3t", `{"pipeline":"unknown"}`,
+			http.StatusNotFound, `{"code":"not_found","message":"no deploy recorded for pipeline unknown"}`},
+		{"replay", http.MethodPost, connectMethodReplayEvent, connectContentType, "s3cr3t",
+			`{"event":{"detail":{"execution-id":"1","pipeline":"web","state":"STARTED"}}}`, http.StatusOK, `{}`},
+		{"replay invalid event", http.MethodPost, connectMethodReplayEvent, connectContentType, "s3cr3t", `{"event":{"detail":{}}}`,
+			http.StatusBadRequest, `{"code":"invalid_argument","message":"missing event param execution-id"}`},
+		{"replay without event", http.MethodPost, connectMethodReplayEvent, connectContentType, "s3cr3t", `{}`,
+			http.StatusBadRequest, `{"code":"invalid_argument","message":"missing event"}`},
+		{"invalid request", http.MethodPost, connectMethodSyncStatus, connectContentType, "s3cr3t", `{`,
+			http.StatusBadRequest, `{"code":"invalid_argument","message":"invalid request: unexpected EOF"}`},
+		{"missing secret", http.MethodPost, connectMethodSyncStatus, connectContentType, "", `{"pipeline":"web","executionId":"1"}`,
+			http.StatusUnauthorized, `{"code":"unauthenticated","message":"unauthorized"}`},
+		{"protobuf codec", http.MethodPost, connectMethodSyncStatus, "application/proto", "s3cr3t", "",
+			http.StatusUnsupportedMediaType, ""},
+		{"wrong method", http.MethodGet, connectMethodSyncStatus, connectContentType, "s3cr3t", "",
+			http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, test := range tests {
+		if code, body := call(test.method, test.path, test.contentType, test.secret, test.body); code != test.expectedCode || body != test.expectedBody {
+			t.Errorf("%s Failed: [%s] inputted and [%d %s] expected, but got [%d %s]", t.Name(), test.name,
+				test.expectedCode, test.expectedBody, code, body)
+		}
+	}
+
+	if len(h.processed) != 1 || h.processed[0].Detail.ExecutionID != "1" {
+		t.Fatal("replayed event was not as expected", h.processed)
+	}
+}
+
+// TestHandlerEnvironmentState will test Handler.environmentState()
+func TestHandlerEnvironmentState(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{})
+	if _, err := h.environmentState(context.Background(), "web"); err == nil {
+		t.Fatal("error should have occurred")
+	} else if e, ok := err.(*connectError); !ok || e.Code != connectCodeFailedPrecondition {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	h = newTestHandler(Config{EnvironmentTable: "environments"})
+	h.deps.DynamoDB = &mockDynamoClient{}
+	if _, err := h.environmentState(context.Background(), "web"); err == nil {
+		t.Fatal("error should have occurred")
+	} else if e, ok := err.(*connectError); !ok || e.Code != connectCodeNotFound {
+		t.Fatal("error was not as expected", err.Error())
+	}
+	if _, err := recordDeploy(context.Background(), h.deps.DynamoDB, "environments", "web", "25c0c3e", time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if state, err := h.environmentState(context.Background(), "web"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if state.Commit != "25c0c3e" || state.Pipeline != "web" {
+		t.Fatal("state was not as expected", state)
+	}
+}
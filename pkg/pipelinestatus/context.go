@@ -0,0 +1,67 @@
+package pipelinestatus
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// defaultStatusContext is used when a pipeline has no context prefix
+const defaultStatusContext = "continuous-integration/codepipeline"
+
+// statusContext will return the GitHub status context for a pipeline: configured (PIPELINE_CONFIG), rendered with
+// STATUS_CONTEXT_TEMPLATE or namespaced by its configured prefix (IE: team-payments/ci/<pipeline>), the prefix found in the pipeline
+// tags or the prefix of the account that sent the event
+func (h *Handler) statusContext(ctx context.Context, pipelineName, pipelineARN string) (statusCtx string, err error) {
+	if statusCtx = h.pipelines[pipelineName].Context; len(statusCtx) > 0 {
+		return statusCtx, nil
+	} else if len(h.cfg.StatusContextTemplate) > 0 {
+		return h.renderStatusContext(pipelineName, "")
+	}
+
+	// Configured prefix takes priority over tags
+	prefix := h.cfg.ContextPrefixes[pipelineName]
+	if len(prefix) == 0 && len(h.cfg.ContextPrefixTag) > 0 && len(pipelineARN) > 0 {
+		if prefix, err = getPipelineTag(ctx, pipelineARN, h.cfg.ContextPrefixTag, h.deps.CodePipeline); err != nil {
+			return
+		}
+	}
+	if len(prefix) == 0 {
+		prefix = h.accountContextPrefix
+	}
+
+	// No prefix, use the default context
+	if prefix = strings.Trim(prefix, "/ "); len(prefix) == 0 {
+		return defaultStatusContext, nil
+	}
+	return prefix + "/" + pipelineName, nil
+}
+
+// getPipelineARN will return the ARN of a pipeline (the events carry it, the listed pipelines do not)
+func getPipelineARN(ctx context.Context, pipelineName string, pipeline CodePipelineAPI) (string, error) {
+	output, err := pipeline.GetPipeline(ctx, &codepipeline.GetPipelineInput{Name: aws.String(pipelineName)})
+	if err != nil || output == nil || output.Metadata == nil {
+		return "", err
+	}
+	return aws.StringValue(output.Metadata.PipelineArn), nil
+}
+
+// getPipelineTag will return the value of a tag on the pipeline (empty if not found)
+func getPipelineTag(ctx context.Context, pipelineARN, key string, pipeline CodePipelineAPI) (value string, err error) {
+	paginator := codepipeline.NewListTagsForResourcePaginator(pipeline,
+		&codepipeline.ListTagsForResourceInput{ResourceArn: aws.String(pipelineARN)})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListTagsForResourceOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, tag := range page.Tags {
+			if aws.StringValue(tag.Key) == key {
+				return aws.StringValue(tag.Value), nil
+			}
+		}
+	}
+	return
+}
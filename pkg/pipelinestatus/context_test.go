@@ -0,0 +1,100 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ListTagsForResource is a mock request for codepipeline
+func (m *mockCodePipelineClient) ListTagsForResource(_ context.Context, input *codepipeline.ListTagsForResourceInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListTagsForResourceOutput, error) {
+	if aws.StringValue(input.ResourceArn) == "arn:aws:codepipeline:us-east-1:123:missing" {
+		return nil, fmt.Errorf("pipeline not found")
+	}
+	return &codepipeline.ListTagsForResourceOutput{Tags: []types.Tag{
+		{Key: aws.String("Stage"), Value: aws.String("production")},
+		{Key: aws.String("github-context-prefix"), Value: aws.String("team-search/ci/")},
+	}}, nil
+}
+
+// TestStatusContext will test statusContext()
+func TestStatusContext(t *testing.T) {
+	h := newTestHandler(Config{
+		ContextPrefixes:  stringMap{"payments": "team-payments/ci"},
+		ContextPrefixTag: "github-context-prefix",
+	})
+
+	var tests = []struct {
+		pipelineName    string
+		pipelineARN     string
+		expectedContext string
+		expectedError   bool
+	}{
+		{"payments", "arn:aws:codepipeline:us-east-1:123:payments", "team-payments/ci/payments", false},
+		{"search", "arn:aws:codepipeline:us-east-1:123:search", "team-search/ci/search", false},
+		{"search", "", defaultStatusContext, false},
+		{"missing", "arn:aws:codepipeline:us-east-1:123:missing", "", true},
+	}
+
+	for _, test := range tests {
+		statusCtx, err := h.statusContext(context.Background(), test.pipelineName, test.pipelineARN)
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: pipeline [%s], expected to throw an error, but no error", t.Name(), test.pipelineName)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: pipeline [%s], error occurred [%s]", t.Name(), test.pipelineName, err.Error())
+		} else if statusCtx != test.expectedContext {
+			t.Errorf("%s Failed: pipeline [%s], expected [%s] but got [%s]", t.Name(), test.pipelineName, test.expectedContext, statusCtx)
+		}
+	}
+
+	// No tag configured
+	h.cfg.ContextPrefixTag = ""
+	if statusCtx, _ := h.statusContext(context.Background(), "search", "arn:aws:codepipeline:us-east-1:123:search"); statusCtx != defaultStatusContext {
+		t.Fatal("context was not as expected", statusCtx)
+	}
+}
+
+// TestStatusContextTemplate will test the contexts of the pipelines and the stages rendered with STATUS_CONTEXT_TEMPLATE
+func TestStatusContextTemplate(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:             "us-east-1",
+		ContextPrefixes:       stringMap{"payments": "team-payments/ci"},
+		StatusContextTemplate: "ci/{{.Pipeline}}/{{.Stage | lower}}",
+	})
+
+	// The prefixes are not used
+	if statusCtx, err := h.statusContext(context.Background(), "payments", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if statusCtx != "ci/payments" {
+		t.Fatal("context was not as expected", statusCtx)
+	}
+	if statusCtx, err := h.stageStatusContext("ci/payments", "payments", "Build", false); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if statusCtx != "ci/payments/build" {
+		t.Fatal("context was not as expected", statusCtx)
+	}
+
+	// Stages of the scheduled context are nested
+	if statusCtx, err := h.stageStatusContext("nightly", "payments", "Build", true); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if statusCtx != "nightly/build" {
+		t.Fatal("context was not as expected", statusCtx)
+	}
+
+	// Region in the middle of the context
+	h.cfg.StatusContextTemplate = "{{.Region}}/{{.Stage}}/{{.Pipeline}}"
+	if statusCtx, _ := h.statusContext(context.Background(), "payments", ""); statusCtx != "us-east-1/payments" {
+		t.Fatal("context was not as expected", statusCtx)
+	}
+
+	// Empty context
+	h.cfg.StatusContextTemplate = "{{.Stage}}"
+	if _, err := h.statusContext(context.Background(), "payments", ""); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
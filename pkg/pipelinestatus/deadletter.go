@@ -0,0 +1,200 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// Dead-letter queue of the failed events (INGESTION_MODE=dlq drains it as an event source, the replay command by hand)
+const (
+	ingestionModeDLQ      = "dlq"
+	replayReceiveBatch    = 10 // most messages of a ReceiveMessage call
+	replayReceiveWaitTime = 1  // seconds, the queue is drained once no message is returned
+)
+
+// Replay results of the messages
+const (
+	replayResultFailed   = "failed"
+	replayResultInvalid  = "invalid"
+	replayResultReplayed = "replayed"
+	replayResultReady    = "ready"
+)
+
+// sqsBatchResponse reports the failed messages of a batch (requires ReportBatchItemFailures on the event source
+// mapping), SQS uses the same response as Kinesis
+type sqsBatchResponse = kinesisBatchResponse
+
+// replayReport is the result of draining the dead-letter queue
+type replayReport struct {
+	Messages []replayMessage `json:"messages"`
+	Queue    string          `json:"queue"`
+}
+
+// replayMessage is the result of one message of the queue
+type replayMessage struct {
+	Error       string `json:"error,omitempty"`
+	ExecutionID string `json:"execution_id,omitempty"`
+	ID          string `json:"id"`
+	Pipeline    string `json:"pipeline,omitempty"`
+	Result      string `json:"result"`
+	State       string `json:"state,omitempty"`
+}
+
+// deadLetterEvent will decode the pipeline event of a message: the event itself (EventBridge target and Lambda
+// DLQs) or the request payload of a Lambda on-failure destination
+func deadLetterEvent(body string) (ev Event, err error) {
+	var destination struct {
+		RequestPayload json.RawMessage `json:"requestPayload"`
+	}
+	payload := []byte(body)
+	if err = json.Unmarshal(payload, &destination); err != nil {
+		return ev, fmt.Errorf("invalid dead-letter message: %s", err.Error())
+	} else if len(destination.RequestPayload) > 0 {
+		payload = destination.RequestPayload
+	}
+	if err = json.Unmarshal(payload, &ev); err != nil {
+		return ev, fmt.Errorf("invalid dead-letter message: %s", err.Error())
+	} else if ev.Detail == nil {
+		return ev, errors.New("invalid dead-letter message: missing param event.detail")
+	}
+	return ev, nil
+}
+
+// ProcessDeadLetterEvent is triggered by the dead-letter queue of the failed events (an SQS event source)
+func ProcessDeadLetterEvent(ctx context.Context, sqsEvent events.SQSEvent) (sqsBatchResponse, error) {
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return sqsBatchResponse{}, err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessDeadLetterEvent(ctx, sqsEvent), nil
+}
+
+// ProcessDeadLetterEvent will process the failed events again, the messages of the events that failed again are
+// returned to the queue (invalid messages are skipped and deleted, they can never be processed)
+func (h *Handler) ProcessDeadLetterEvent(ctx context.Context, sqsEvent events.SQSEvent) (response sqsBatchResponse) {
+	ctx = h.withSettings(ctx)
+	response.BatchItemFailures = []kinesisBatchItemFailure{}
+	for _, message := range sqsEvent.Records {
+		ev, err := deadLetterEvent(message.Body)
+		if err != nil {
+			logf(ctx, "skipping message %s: %s", message.MessageId, err.Error())
+			continue
+		} else if err = h.ProcessEventWithContext(ctx, ev); err != nil {
+			logErrorf(ctx, "unable to process message %s: %s", message.MessageId, err.Error())
+			response.BatchItemFailures = append(response.BatchItemFailures, kinesisBatchItemFailure{
+				ItemIdentifier: message.MessageId,
+			})
+		}
+	}
+	return
+}
+
+// replayDeadLetters will receive the messages of the queue, process their events and delete the processed messages
+// (messages that fail again or are invalid are left in the queue, they are visible again after the visibility timeout)
+func (h *Handler) replayDeadLetters(ctx context.Context, sqsSvc sqsiface.SQSAPI, queueURL string, max int,
+	dryRun bool) (report replayReport, err error) {
+	report = replayReport{Messages: []replayMessage{}, Queue: queueURL}
+	for len(report.Messages) < max {
+		batch := max - len(report.Messages)
+		if batch > replayReceiveBatch {
+			batch = replayReceiveBatch
+		}
+		var output *sqs.ReceiveMessageOutput
+		if output, err = sqsSvc.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			MaxNumberOfMessages: aws.Int64(int64(batch)),
+			QueueUrl:            aws.String(queueURL),
+			WaitTimeSeconds:     aws.Int64(replayReceiveWaitTime),
+		}); err != nil {
+			return
+		} else if len(output.Messages) == 0 {
+			return
+		}
+
+		for _, message := range output.Messages {
+			result := replayMessage{ID: aws.StringValue(message.MessageId)}
+			ev, decodeErr := deadLetterEvent(aws.StringValue(message.Body))
+			switch {
+			case decodeErr != nil:
+				result.Result, result.Error = replayResultInvalid, decodeErr.Error()
+			case dryRun:
+				result.Result = replayResultReady
+			default:
+				if processErr := h.ProcessEventWithContext(ctx, ev); processErr != nil {
+					result.Result, result.Error = replayResultFailed, processErr.Error()
+				} else if _, err = sqsSvc.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(queueURL),
+					ReceiptHandle: message.ReceiptHandle,
+				}); err != nil {
+					return
+				} else {
+					result.Result = replayResultReplayed
+				}
+			}
+			if ev.Detail != nil {
+				result.ExecutionID, result.Pipeline, result.State = ev.Detail.ExecutionID, ev.Detail.Pipeline, ev.Detail.State
+			}
+			report.Messages = append(report.Messages, result)
+		}
+	}
+	return
+}
+
+// queueARN will return the ARN of an SQS queue from its url (IE: https://sqs.us-east-1.amazonaws.com/123456789012/name)
+func queueARN(partition, queueURL string) (string, error) {
+	parsed, err := url.Parse(queueURL)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	hostParts := strings.Split(parsed.Host, ".")
+	if len(parts) != 2 || len(hostParts) < 3 || hostParts[0] != "sqs" {
+		return "", fmt.Errorf("invalid queue url: %s (IE: https://sqs.us-east-1.amazonaws.com/123456789012/name)", queueURL)
+	}
+	return fmt.Sprintf("arn:%s:sqs:%s:%s:%s", partition, hostParts[1], parts[0], parts[1]), nil
+}
+
+// replayCommand will drain the dead-letter queue after an outage (IE: status replay -max 500), the events are
+// processed again and their messages deleted on success
+func replayCommand(args []string, out io.Writer, h *Handler, sqsSvc sqsiface.SQSAPI) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandReplay, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "list the messages without processing them")
+	max := flags.Int("max", 100, "most messages to replay")
+	output := outputFlag(flags, outputTable)
+	queueURL := flags.String("queue", h.cfg.DeadLetterQueueURL, "url of the dead-letter queue (default: DEAD_LETTER_QUEUE_URL)")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*queueURL) == 0 {
+		return errors.New("missing flag -queue (or DEAD_LETTER_QUEUE_URL)")
+	} else if *max < 1 {
+		return errors.New("flag -max must be at least 1")
+	}
+
+	// Replay the messages
+	var report replayReport
+	if report, err = h.replayDeadLetters(context.Background(), sqsSvc, *queueURL, *max, *dryRun); err != nil {
+		return
+	}
+
+	// Write the report
+	return writeOutput(out, *output, report, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "MESSAGE\tPIPELINE\tEXECUTION\tSTATE\tRESULT\tERROR")
+		for _, message := range report.Messages {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", message.ID, message.Pipeline, message.ExecutionID, message.State,
+				message.Result, message.Error)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// mockSQSClient is a queue of messages (deleted messages are removed)
+type mockSQSClient struct {
+	sqsiface.SQSAPI
+	messages []*sqs.Message
+}
+
+// ReceiveMessageWithContext is a mock request for sqs (messages stay in the queue until deleted)
+func (m *mockSQSClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput,
+	opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	output := &sqs.ReceiveMessageOutput{}
+	for _, message := range m.messages {
+		if int64(len(output.Messages)) < aws.Int64Value(input.MaxNumberOfMessages) {
+			output.Messages = append(output.Messages, message)
+		}
+	}
+	return output, nil
+}
+
+// DeleteMessageWithContext is a mock request for sqs
+func (m *mockSQSClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput,
+	opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	for i, message := range m.messages {
+		if aws.StringValue(message.ReceiptHandle) == aws.StringValue(input.ReceiptHandle) {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			break
+		}
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// newDeadLetter will create a message of the dead-letter queue with a pipeline event
+func newDeadLetter(id, pipelineName string) *sqs.Message {
+	body, _ := json.Marshal(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: pipelineName, State: "SUCCEEDED"}})
+	return &sqs.Message{Body: aws.String(string(body)), MessageId: aws.String(id), ReceiptHandle: aws.String("receipt-" + id)}
+}
+
+// TestDeadLetterEvent will test deadLetterEvent()
+func TestDeadLetterEvent(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		body             string
+		expectedPipeline string
+		expectedError    bool
+	}{
+		{`{"detail":{"execution-id":"12345678","pipeline":"web","state":"SUCCEEDED"}}`, "web", false},
+		{`{"requestPayload":{"detail":{"execution-id":"12345678","pipeline":"api","state":"FAILED"}},"responsePayload":{}}`, "api", false},
+		{`{"source":"aws.codepipeline"}`, "", true},
+		{`not json`, "", true},
+	}
+
+	for _, test := range tests {
+		if ev, err := deadLetterEvent(test.body); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.body, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.body)
+		} else if err == nil && ev.Detail.Pipeline != test.expectedPipeline {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.body, test.expectedPipeline, ev.Detail.Pipeline)
+		}
+	}
+}
+
+// TestQueueARN will test queueARN()
+func TestQueueARN(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		url           string
+		expected      string
+		expectedError bool
+	}{
+		{"https://sqs.us-east-1.amazonaws.com/123456789012/status-dlq", "arn:aws:sqs:us-east-1:123456789012:status-dlq", false},
+		{"https://sqs.eu-west-1.amazonaws.com/123456789012/status-dlq/", "arn:aws:sqs:eu-west-1:123456789012:status-dlq", false},
+		{"https://queue.amazonaws.com/status-dlq", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		if output, err := queueARN("aws", test.url); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.url, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.url)
+		} else if output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.url, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessDeadLetterEvent will test Handler.ProcessDeadLetterEvent() returning the failed messages
+func TestHandlerProcessDeadLetterEvent(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	response := h.ProcessDeadLetterEvent(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: aws.StringValue(newDeadLetter("1", "status-succeed").Body)},
+		{MessageId: "2", Body: "not json"},
+		{MessageId: "3", Body: aws.StringValue(newDeadLetter("3", "bad-artifact-name").Body)},
+	}})
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "3" {
+		t.Fatal("failures were not as expected", response.BatchItemFailures)
+	}
+}
+
+// TestReplayCommand will test replayCommand() draining the queue
+func TestReplayCommand(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	var posts int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusCreated)
+	})
+	queue := &mockSQSClient{messages: []*sqs.Message{
+		newDeadLetter("1", "status-succeed"),
+		newDeadLetter("2", "bad-artifact-name"),
+		{Body: aws.String("not json"), MessageId: aws.String("3"), ReceiptHandle: aws.String("receipt-3")},
+	}}
+
+	// Missing queue
+	var out bytes.Buffer
+	if err := replayCommand([]string{}, &out, h, queue); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Dry run lists the messages without processing them
+	if err := replayCommand([]string{"-queue", "https://sqs.us-east-1.amazonaws.com/123456789012/status-dlq", "-max", "3", "-dry-run"},
+		&out, h, queue); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 0 || len(queue.messages) != 3 || !strings.Contains(out.String(), replayResultReady) {
+		t.Fatal("dry run was not as expected", out.String())
+	}
+
+	// The replayed message is deleted, the failed and invalid ones stay in the queue
+	out.Reset()
+	h.cfg.DeadLetterQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/status-dlq"
+	if err := replayCommand([]string{"-max", "3", "-output", outputJSON}, &out, h, queue); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	var report replayReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(report.Messages) != 3 || report.Messages[0].Result != replayResultReplayed ||
+		report.Messages[1].Result != replayResultFailed || report.Messages[2].Result != replayResultInvalid {
+		t.Fatal("report was not as expected", report.Messages)
+	} else if len(queue.messages) != 2 || posts == 0 {
+		t.Fatal("queue was not as expected", queue.messages)
+	}
+}
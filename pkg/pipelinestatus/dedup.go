@@ -0,0 +1,68 @@
+package pipelinestatus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// dedupTTL is how long a reported status is remembered (EventBridge retries an event for up to 24 hours)
+const dedupTTL = 48 * time.Hour
+
+// dedupKey will return the key of a status reported for an execution (the event state of a context)
+func dedupKey(executionID, statusContext, state string) string {
+	return executionID + "#" + statusContext + "#" + state
+}
+
+// claimStatus will store the status as reported, false if it was already reported (a duplicate delivery)
+func claimStatus(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, key string, now time.Time) (bool, error) {
+	_, err := dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"expires": {N: aws.String(strconv.FormatInt(now.Add(dedupTTL).Unix(), 10))},
+			"id":      {S: aws.String(key)},
+		},
+		TableName: aws.String(table),
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// releaseStatus will forget a claimed status (the post failed, a retry has to post it)
+func releaseStatus(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, key string) error {
+	_, err := dynamoSvc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(key)}},
+		TableName: aws.String(table),
+	})
+	return err
+}
+
+// firstReport will claim the status of the event before it is posted (DEDUP_TABLE), false if the same state of the
+// context was already reported for the execution: EventBridge delivers an event at least once and Lambda retries after
+// a partial failure. The claim is forgotten if the post fails, errors of the table never block the status
+func (h *Handler) firstReport(ctx context.Context, ev Event, statusContext string) (first bool, forget func()) {
+	forget = func() {}
+	if len(h.cfg.DedupTable) == 0 {
+		return true, forget
+	}
+	key := dedupKey(ev.Detail.ExecutionID, statusContext, ev.Detail.State)
+	claimed, err := claimStatus(ctx, h.deps.DynamoDB, h.cfg.DedupTable, key, time.Now())
+	if err != nil {
+		logWarnf(ctx, "unable to check for a duplicate status: %s", err.Error())
+		return true, forget
+	} else if !claimed {
+		logf(ctx, "skipping the %s status of %s, it was already reported", ev.Detail.State, statusContext)
+		return false, forget
+	}
+	return true, func() {
+		if err = releaseStatus(ctx, h.deps.DynamoDB, h.cfg.DedupTable, key); err != nil {
+			logWarnf(ctx, "unable to forget the failed status: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package pipelinestatus
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockDedupDynamoClient keeps the reported statuses (a second put of a key fails its condition)
+type mockDedupDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	keys map[string]bool
+}
+
+// PutItemWithContext is a mock request for dynamodb (attribute_not_exists(id))
+func (m *mockDedupDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	key := aws.StringValue(input.Item["id"].S)
+	if m.keys[key] {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	m.keys[key] = true
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// DeleteItemWithContext is a mock request for dynamodb
+func (m *mockDedupDynamoClient) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	delete(m.keys, aws.StringValue(input.Key["id"].S))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// TestDedupKey will test dedupKey()
+func TestDedupKey(t *testing.T) {
+	t.Parallel()
+
+	if key := dedupKey("12345678", "ci/web", "SUCCEEDED"); key != "12345678#ci/web#SUCCEEDED" {
+		t.Fatal("key was not as expected", key)
+	} else if key == dedupKey("12345678", "ci/web/build", "SUCCEEDED") || key == dedupKey("12345678", "ci/web", "FAILED") {
+		t.Fatal("key should be unique per context and state")
+	}
+}
+
+// TestHandlerProcessEventDuplicate will test ProcessEvent() posting a status once for duplicate deliveries
+func TestHandlerProcessEventDuplicate(t *testing.T) {
+	h := newTestHandler(Config{
+		DedupTable:           "dedup",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	dynamo := &mockDedupDynamoClient{keys: make(map[string]bool)}
+	h.deps.DynamoDB = dynamo
+
+	var posts int
+	failing := true
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// A failed post is forgotten, so the retry posts it
+	ev := Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}
+	if err := h.ProcessEvent(ev); err == nil {
+		t.Fatal("error should have occurred")
+	} else if len(dynamo.keys) != 0 {
+		t.Fatal("failed status should have been forgotten", dynamo.keys)
+	}
+	failing = false
+	posts = 0
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 1 || !dynamo.keys[dedupKey("12345678", defaultStatusContext, "SUCCEEDED")] {
+		t.Fatal("status should have been posted once", posts, dynamo.keys)
+	}
+
+	// Duplicate delivery
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 1 {
+		t.Fatal("duplicate status should have been skipped", posts)
+	}
+
+	// Stage statuses are separate
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678", Pipeline: "status-succeed", Stage: "Build", State: "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 2 {
+		t.Fatal("stage status should have been posted", posts)
+	}
+}
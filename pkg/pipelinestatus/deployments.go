@@ -0,0 +1,161 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// GitHub deployments (the deploy history of the Environments tab)
+const (
+	deploymentStartedAt        = "started_at"
+	githubDeploymentInProgress = "in_progress"
+	stageDeploymentTask        = "deploy:codepipeline"
+)
+
+// githubDeployment is a deployment of the GitHub API (the payload links the deployment of AWS)
+type githubDeployment struct {
+	AutoMerge        bool              `json:"auto_merge"`
+	Description      string            `json:"description,omitempty"`
+	Environment      string            `json:"environment"`
+	ID               int64             `json:"id,omitempty"`
+	Payload          map[string]string `json:"payload"`
+	Ref              string            `json:"ref"`
+	RequiredContexts []string          `json:"required_contexts"`
+	Task             string            `json:"task"`
+}
+
+// githubDeploymentStatus is the payload to create a status of a GitHub deployment
+type githubDeploymentStatus struct {
+	AutoInactive   bool   `json:"auto_inactive"`
+	Description    string `json:"description"`
+	Environment    string `json:"environment"`
+	EnvironmentURL string `json:"environment_url,omitempty"`
+	LogURL         string `json:"log_url"`
+	State          string `json:"state"`
+}
+
+// upsertGithubDeployment will return the GitHub deployment of the commit with the same deployment_id in its
+// payload (created if missing, the statuses of the commit are not required, the pipeline already ran)
+func (h *Handler) upsertGithubDeployment(ctx context.Context, owner, repo string, deployment *githubDeployment) (githubDeployment, error) {
+	var deployments []githubDeployment
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/deployments?sha=%s&environment=%s&per_page=100",
+		owner, repo, deployment.Ref, url.QueryEscape(deployment.Environment)), &deployments); err != nil {
+		return githubDeployment{}, err
+	}
+	for _, existing := range deployments {
+		if existing.Payload["deployment_id"] == deployment.Payload["deployment_id"] {
+			return existing, nil
+		}
+	}
+
+	deployment.RequiredContexts = []string{}
+	req, err := h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/deployments", owner, repo), deployment)
+	if err != nil {
+		return githubDeployment{}, err
+	}
+	var created githubDeployment
+	if err = h.doGithubRequest(req, http.StatusCreated, &created); err != nil {
+		return githubDeployment{}, err
+	}
+	if created.Payload == nil {
+		created.Payload = deployment.Payload
+	}
+	return created, nil
+}
+
+// deploymentDuration will describe how long the deployment took until it completed, from the started_at of its
+// payload: GitHub dates the deployments and their statuses when they are posted, the payload and the description
+// keep the timing of AWS (empty if the start is unknown)
+func deploymentDuration(deployment githubDeployment, completedAt time.Time) string {
+	startedAt, err := time.Parse(time.RFC3339, deployment.Payload[deploymentStartedAt])
+	if err != nil || completedAt.Before(startedAt) {
+		return ""
+	}
+	return "in " + formatElapsed(completedAt.Sub(startedAt))
+}
+
+// postDeploymentStatus will add a status to a GitHub deployment
+func (h *Handler) postDeploymentStatus(ctx context.Context, owner, repo string, deploymentID int64, status *githubDeploymentStatus) error {
+	req, err := h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/deployments/%d/statuses", owner, repo, deploymentID), status)
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusCreated, nil)
+}
+
+// isDeploymentStage will return true if the stage name matches DEPLOYMENT_STAGE_PATTERN (IE: Deploy*)
+func (h *Handler) isDeploymentStage(stage string) bool {
+	if len(h.cfg.DeploymentStagePattern) == 0 {
+		return false
+	}
+	matched, err := path.Match(h.cfg.DeploymentStagePattern, stage)
+	return err == nil && matched
+}
+
+// stageStartedAt will return when the stage of the execution started: the time of the event that started it, or
+// the start of its first action if the deployment is created by a later event (IE: the started event was lost)
+func (h *Handler) stageStartedAt(ctx context.Context, ev Event) time.Time {
+	startedAt := ev.Time
+	if ev.Detail.State != "STARTED" {
+		actions, err := getActionExecutions(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, h.deps.CodePipeline)
+		if err != nil {
+			logWarnf(ctx, "unable to get the start of the stage: %s", err.Error())
+		}
+		for _, action := range actions {
+			if start := aws.TimeValue(action.StartTime); aws.StringValue(action.StageName) == ev.Detail.Stage &&
+				!start.IsZero() && (startedAt.IsZero() || start.Before(startedAt)) {
+				startedAt = start
+			}
+		}
+	}
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	return startedAt.UTC()
+}
+
+// postStageDeployment will create the GitHub deployment of a deploy stage of the execution (in the environment
+// of APPLICATION_STAGE_NAME) and post the state of the stage on it, the final state notes how long the stage took
+func (h *Handler) postStageDeployment(ctx context.Context, ev Event, owner, repo, commit, state, targetURL string) error {
+	if state == githubStatePending {
+		state = githubDeploymentInProgress
+	}
+	deployment, err := h.upsertGithubDeployment(ctx, owner, repo, &githubDeployment{
+		Description: joinDescription(ev.Detail.Pipeline + " " + ev.Detail.Stage),
+		Environment: h.cfg.Stage,
+		Payload: map[string]string{
+			"deployment_id":     ev.Detail.Pipeline + "/" + ev.Detail.ExecutionID + "/" + ev.Detail.Stage,
+			"execution_id":      ev.Detail.ExecutionID,
+			"pipeline":          ev.Detail.Pipeline,
+			"stage":             ev.Detail.Stage,
+			deploymentStartedAt: h.stageStartedAt(ctx, ev).Format(time.RFC3339),
+		},
+		Ref:  commit,
+		Task: stageDeploymentTask,
+	})
+	if err != nil {
+		return err
+	}
+	description := ev.Detail.Stage + " " + strings.ToLower(ev.Detail.State)
+	if state != githubDeploymentInProgress {
+		completedAt := ev.Time
+		if completedAt.IsZero() {
+			completedAt = time.Now()
+		}
+		description = strings.TrimSpace(description + " " + deploymentDuration(deployment, completedAt))
+	}
+	return h.postDeploymentStatus(ctx, owner, repo, deployment.ID, &githubDeploymentStatus{
+		AutoInactive: true,
+		Description:  joinDescription(description),
+		Environment:  h.cfg.Stage,
+		LogURL:       targetURL,
+		State:        state,
+	})
+}
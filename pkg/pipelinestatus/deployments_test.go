@@ -0,0 +1,157 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIsDeploymentStage will test Handler.isDeploymentStage()
+func TestIsDeploymentStage(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		pattern  string
+		stage    string
+		expected bool
+	}{
+		{"Deploy*", "Deploy", true},
+		{"Deploy*", "DeployProduction", true},
+		{"Deploy*", "Build", false},
+		{"*-deploy", "eu-deploy", true},
+		{"", "Deploy", false},
+		{"[", "Deploy", false},
+	}
+
+	for _, test := range tests {
+		if output := newTestHandler(Config{DeploymentStagePattern: test.pattern}).isDeploymentStage(test.stage); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%t] expected, but got: %t", t.Name(), test.pattern, test.stage, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessEventStageDeployment will test ProcessEvent() tracking a deploy stage with a GitHub deployment
+func TestHandlerProcessEventStageDeployment(t *testing.T) {
+	h := newTestHandler(Config{
+		DeploymentStagePattern: "Deploy*",
+		GithubAccessToken:      "1234567",
+		GithubMaxConcurrency:   1,
+		Stage:                  stageTesting,
+	})
+
+	var deployments []githubDeployment
+	var statuses []githubDeploymentStatus
+	var commitStatuses int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(deployments)
+		case r.URL.Path == "/repos/mrz1836/codepipeline-to-github/deployments":
+			var deployment githubDeployment
+			_ = json.NewDecoder(r.Body).Decode(&deployment)
+			deployment.ID = 3
+			deployments = append(deployments, deployment)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(deployment)
+		case r.URL.Path == "/repos/mrz1836/codepipeline-to-github/deployments/3/statuses":
+			var status githubDeploymentStatus
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			statuses = append(statuses, status)
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(r.URL.Path, "/repos/mrz1836/codepipeline-to-github/statuses/"):
+			commitStatuses++
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// The deployment starts at the time of the started event and the final state notes how long the stage took
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, state := range []string{"STARTED", "FAILED"} {
+		if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+			ExecutionID: "12345678",
+			Pipeline:    "status-succeed",
+			Stage:       "DeployProduction",
+			State:       state,
+		}, Time: started.Add(time.Duration(i) * 150 * time.Second)}); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+	}
+	if commitStatuses != 2 {
+		t.Fatal("commit statuses should have been posted", commitStatuses)
+	} else if len(deployments) != 1 || deployments[0].Environment != stageTesting || deployments[0].Task != stageDeploymentTask ||
+		deployments[0].Payload["deployment_id"] != "status-succeed/12345678/DeployProduction" ||
+		deployments[0].Payload[deploymentStartedAt] != "2020-05-01T12:00:00Z" {
+		t.Fatal("deployments were not as expected", deployments)
+	} else if len(statuses) != 2 || statuses[0].State != githubDeploymentInProgress || statuses[1].State != githubStateFailure ||
+		statuses[1].Description != "DeployProduction failed in 2m 30s" {
+		t.Fatal("statuses were not as expected", statuses)
+	}
+
+	// Other stages only get the commit status
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Build",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commitStatuses != 3 || len(statuses) != 2 {
+		t.Fatal("deployment status should not have been posted", statuses)
+	}
+}
+
+// TestNewHandlerDeploymentStagePattern will test NewHandler() validating DEPLOYMENT_STAGE_PATTERN
+func TestNewHandlerDeploymentStagePattern(t *testing.T) {
+	deps := newTestHandler(Config{}).deps
+	if _, err := NewHandler(Config{DeploymentStagePattern: "Deploy[", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{DeploymentStagePattern: "Deploy*", Stage: stageTesting}, deps); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+}
+
+// TestDeploymentDuration will test deploymentDuration()
+func TestDeploymentDuration(t *testing.T) {
+	t.Parallel()
+
+	completed := time.Date(2020, 5, 1, 12, 3, 20, 0, time.UTC)
+	var tests = []struct {
+		startedAt string
+		expected  string
+	}{
+		{"2020-05-01T12:00:00Z", "in 3m 20s"},
+		{"2020-05-01T12:03:20Z", "in 0s"},
+		{"2020-05-01T12:05:00Z", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		deployment := githubDeployment{Payload: map[string]string{deploymentStartedAt: test.startedAt}}
+		if output := deploymentDuration(deployment, completed); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.startedAt, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerStageStartedAt will test Handler.stageStartedAt() for a deployment created by the final event
+func TestHandlerStageStartedAt(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{})
+	ev := Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", Stage: "Build", State: "FAILED"},
+		Time: time.Date(2020, 5, 1, 12, 2, 30, 0, time.UTC)}
+	if startedAt := h.stageStartedAt(context.Background(), ev); !startedAt.Equal(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatal("start of the stage was not as expected", startedAt)
+	}
+
+	// The started event has the start
+	ev.Detail.State = "STARTED"
+	if startedAt := h.stageStartedAt(context.Background(), ev); !startedAt.Equal(ev.Time) {
+		t.Fatal("start of the stage was not as expected", startedAt)
+	}
+}
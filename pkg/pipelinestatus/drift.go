@@ -0,0 +1,77 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// driftDescription is added to the final status when the pipeline definition changed
+const driftDescription = "pipeline definition changed since last run"
+
+// definitionHash will return the hash of the pipeline definition (the version is ignored, only the content counts)
+func definitionHash(ctx context.Context, pipelineName string, pipeline CodePipelineAPI) (hash string, err error) {
+	var output *codepipeline.GetPipelineOutput
+	if output, err = pipeline.GetPipeline(ctx, &codepipeline.GetPipelineInput{
+		Name: aws.String(pipelineName),
+	}); err != nil {
+		return
+	} else if output == nil || output.Pipeline == nil {
+		err = fmt.Errorf("missing pipeline: %s", pipelineName)
+		return
+	}
+
+	declaration := *output.Pipeline
+	declaration.Version = nil
+	var b []byte
+	if b, err = json.Marshal(declaration); err != nil {
+		return
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordDefinition will store the definition hash of a pipeline and return the previously stored hash
+func recordDefinition(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, hash string, now time.Time) (previous string, err error) {
+	var output *dynamodb.UpdateItemOutput
+	if output, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#hash": aws.String("hash"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":hash":    {S: aws.String(hash)},
+			":updated": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"pipeline": {S: aws.String(pipelineName)},
+		},
+		ReturnValues:     aws.String(dynamodb.ReturnValueAllOld),
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("SET #hash = :hash, updated_at = :updated"),
+	}); err != nil || output == nil || output.Attributes["hash"] == nil {
+		return
+	}
+	return aws.StringValue(output.Attributes["hash"].S), nil
+}
+
+// definitionDrifted will return true if the pipeline definition changed since the last final status
+// (the first run of a pipeline only stores the definition)
+func (h *Handler) definitionDrifted(ctx context.Context, pipelineName string) (drifted bool, err error) {
+	var hash, previous string
+	if hash, err = definitionHash(ctx, pipelineName, h.deps.CodePipeline); err != nil {
+		return
+	}
+	if previous, err = recordDefinition(ctx, h.deps.DynamoDB, h.cfg.DefinitionTable, pipelineName, hash, time.Now()); err != nil {
+		return
+	}
+	return len(previous) > 0 && previous != hash, nil
+}
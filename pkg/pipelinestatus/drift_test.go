@@ -0,0 +1,71 @@
+package pipelinestatus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// TestDefinitionHash will test definitionHash()
+func TestDefinitionHash(t *testing.T) {
+	t.Parallel()
+
+	mockPipeline := &mockCodePipelineClient{}
+
+	hash, err := definitionHash(context.Background(), "some-pipeline", mockPipeline)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(hash) != 64 {
+		t.Fatal("hash was not as expected", hash)
+	}
+
+	// Different definition
+	var other string
+	if other, err = definitionHash(context.Background(), "codestar-pipeline", mockPipeline); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if other == hash {
+		t.Fatal("hash should be different for another definition")
+	}
+
+	// Missing pipeline
+	if _, err = definitionHash(context.Background(), "nil", mockPipeline); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestDefinitionDrifted will test definitionDrifted()
+func TestDefinitionDrifted(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	h := newTestHandler(Config{DefinitionTable: "definitions"})
+	h.deps.DynamoDB = mockDynamo
+
+	// First run only stores the definition
+	if drifted, err := h.definitionDrifted(context.Background(), "some-pipeline"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if drifted {
+		t.Fatal("first run should not drift")
+	}
+
+	// Same definition
+	if drifted, err := h.definitionDrifted(context.Background(), "some-pipeline"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if drifted {
+		t.Fatal("same definition should not drift")
+	}
+
+	// Changed definition
+	mockDynamo.item = map[string]*dynamodb.AttributeValue{"hash": {S: aws.String("previous-hash")}}
+	if drifted, err := h.definitionDrifted(context.Background(), "some-pipeline"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !drifted {
+		t.Fatal("changed definition should drift")
+	}
+
+	// Missing table
+	h.cfg.DefinitionTable = ""
+	if _, err := h.definitionDrifted(context.Background(), "some-pipeline"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
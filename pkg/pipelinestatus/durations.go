@@ -0,0 +1,186 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Stage duration trends (STAGE_DURATION_TABLE)
+const (
+	checkAnnotationWarning   = "warning"
+	metricStageDurationRatio = "StageDurationRatio"
+	stageDurationMinSamples  = 5  // fewer recorded executions are not a trend yet
+	stageDurationSamples     = 30 // trailing executions of the median
+	stageDurationTTL         = 90 * 24 * time.Hour
+)
+
+// stageTrend is a stage that took much longer than its trailing median
+type stageTrend struct {
+	Median time.Duration
+	Ratio  float64
+	Stage  string
+	Took   time.Duration
+}
+
+// String will return the description of the trend (IE: Build 2.4x slower than usual)
+func (t stageTrend) String() string {
+	return fmt.Sprintf("%s %.1fx slower than usual", t.Stage, t.Ratio)
+}
+
+// stageDurations will return how long each stage of the execution took, from the start of its first action to
+// the last update of its last action (stages with unfinished or failed actions are left out, they are not comparable)
+func stageDurations(actions []*types.ActionExecutionDetail) map[string]time.Duration {
+	type window struct {
+		finished time.Time
+		skip     bool
+		started  time.Time
+	}
+	windows := make(map[string]*window)
+	for _, action := range actions {
+		name := aws.StringValue(action.StageName)
+		w, ok := windows[name]
+		if !ok {
+			w = &window{started: aws.TimeValue(action.StartTime)}
+			windows[name] = w
+		}
+		if action.Status != types.ActionExecutionStatusSucceeded {
+			w.skip = true
+		}
+		if started := aws.TimeValue(action.StartTime); started.Before(w.started) {
+			w.started = started
+		}
+		if updated := aws.TimeValue(action.LastUpdateTime); updated.After(w.finished) {
+			w.finished = updated
+		}
+	}
+	durations := make(map[string]time.Duration)
+	for name, w := range windows {
+		if !w.skip && w.finished.After(w.started) {
+			durations[name] = w.finished.Sub(w.started)
+		}
+	}
+	return durations
+}
+
+// medianDuration will return the median of the durations (zero without any)
+func medianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+// recentStageDurations will return the durations of the latest executions of a stage (newest first)
+func recentStageDurations(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, stage string) (durations []time.Duration, err error) {
+	var output *dynamodb.QueryOutput
+	if output, err = dynamoSvc.QueryWithContext(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#stage": aws.String("stage"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":stage": {S: aws.String(pipelineName + "#" + stage)},
+		},
+		KeyConditionExpression: aws.String("#stage = :stage"),
+		Limit:                  aws.Int64(stageDurationSamples),
+		ScanIndexForward:       aws.Bool(false),
+		TableName:              aws.String(table),
+	}); err != nil {
+		return
+	}
+	for _, item := range output.Items {
+		if item["milliseconds"] == nil {
+			continue
+		}
+		var milliseconds int64
+		if milliseconds, err = strconv.ParseInt(aws.StringValue(item["milliseconds"].N), 10, 64); err != nil {
+			return
+		}
+		durations = append(durations, time.Duration(milliseconds)*time.Millisecond)
+	}
+	return
+}
+
+// recordStageDuration will store how long the stage of an execution took (the sort key orders the executions
+// by time and keeps a duplicate delivery of the event from counting twice)
+func recordStageDuration(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, stage, executionID string,
+	took time.Duration, now time.Time) error {
+	_, err := dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"execution_id": {S: aws.String(executionID)},
+			"expires":      {N: aws.String(strconv.FormatInt(now.Add(stageDurationTTL).Unix(), 10))},
+			"milliseconds": {N: aws.String(strconv.FormatInt(int64(took/time.Millisecond), 10))},
+			"sort":         {S: aws.String(now.UTC().Format(eventOrderTimeLayout) + "#" + executionID)},
+			"stage":        {S: aws.String(pipelineName + "#" + stage)},
+		},
+		TableName: aws.String(table),
+	})
+	return err
+}
+
+// stageTrends will compare how long each stage of the finished execution took to its trailing median, emit the
+// ratio as a metric and return the stages over STAGE_DURATION_THRESHOLD (the execution is recorded for the next ones)
+func (h *Handler) stageTrends(ctx context.Context, pipelineName, executionID string) (trends []stageTrend, err error) {
+	var actions []*types.ActionExecutionDetail
+	if actions, err = getActionExecutions(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil {
+		return
+	}
+	durations := stageDurations(actions)
+	stages := make([]string, 0, len(durations))
+	for stage := range durations {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	now := time.Now()
+	for _, stage := range stages {
+		took := durations[stage]
+		var history []time.Duration
+		if history, err = recentStageDurations(ctx, h.deps.DynamoDB, h.cfg.StageDurationTable, pipelineName, stage); err != nil {
+			return
+		} else if err = recordStageDuration(ctx, h.deps.DynamoDB, h.cfg.StageDurationTable, pipelineName, stage, executionID,
+			took, now); err != nil {
+			return
+		}
+		median := medianDuration(history)
+		if len(history) < stageDurationMinSamples || median <= 0 {
+			continue
+		}
+		ratio := float64(took) / float64(median)
+		printMetric(metricStageDurationRatio, ratio, "None", map[string]string{"Pipeline": pipelineName, "Stage": stage}, now)
+		if ratio >= h.cfg.StageDurationThreshold {
+			logf(ctx, "stage %s took %s, the median is %s", stage, took, median)
+			trends = append(trends, stageTrend{Median: median, Ratio: ratio, Stage: stage, Took: took})
+		}
+	}
+	return
+}
+
+// trendAnnotations will return a warning annotation of the check run for each slow stage
+func trendAnnotations(trends []stageTrend) (annotations []checkAnnotation) {
+	for _, trend := range trends {
+		annotations = append(annotations, checkAnnotation{
+			AnnotationLevel: checkAnnotationWarning,
+			EndLine:         1,
+			Message: fmt.Sprintf("%s took %s, the median of the last %d executions is %s", trend.Stage,
+				trend.Took.Round(time.Second), stageDurationSamples, trend.Median.Round(time.Second)),
+			Path:      checkAnnotationPath,
+			StartLine: 1,
+			Title:     trend.String(),
+		})
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package pipelinestatus
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockDurationsCodePipelineClient returns a finished execution: Build took 240 seconds, Deploy 60 seconds
+type mockDurationsCodePipelineClient struct {
+	mockCodePipelineClient
+}
+
+// ListActionExecutions is a mock request for codepipeline
+func (m *mockDurationsCodePipelineClient) ListActionExecutions(_ context.Context, input *codepipeline.ListActionExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+	var details []types.ActionExecutionDetail
+	for _, action := range newStageActions(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		details = append(details, *action)
+	}
+	return &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: details}, nil
+}
+
+// mockDurationsDynamoClient keeps the recorded durations of each stage (newest last)
+type mockDurationsDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string][]map[string]*dynamodb.AttributeValue
+}
+
+// QueryWithContext is a mock request for dynamodb (newest first)
+func (m *mockDurationsDynamoClient) QueryWithContext(_ aws.Context, input *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	items := m.items[aws.StringValue(input.ExpressionAttributeValues[":stage"].S)]
+	output := &dynamodb.QueryOutput{}
+	for i := len(items) - 1; i >= 0 && int64(len(output.Items)) < aws.Int64Value(input.Limit); i-- {
+		output.Items = append(output.Items, items[i])
+	}
+	return output, nil
+}
+
+// PutItemWithContext is a mock request for dynamodb
+func (m *mockDurationsDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	stage := aws.StringValue(input.Item["stage"].S)
+	m.items[stage] = append(m.items[stage], input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// newStageActions will create the actions of an execution: Build (two actions, 240 seconds) then Deploy (60 seconds)
+func newStageActions(started time.Time) []*types.ActionExecutionDetail {
+	newAction := func(stage, name string, start, end time.Duration, status types.ActionExecutionStatus) *types.ActionExecutionDetail {
+		return &types.ActionExecutionDetail{
+			ActionName:     aws.String(name),
+			LastUpdateTime: aws.Time(started.Add(end)),
+			StageName:      aws.String(stage),
+			StartTime:      aws.Time(started.Add(start)),
+			Status:         status,
+		}
+	}
+	return []*types.ActionExecutionDetail{
+		newAction("Build", "Compile", 0, 180*time.Second, types.ActionExecutionStatusSucceeded),
+		newAction("Build", "Test", 30*time.Second, 240*time.Second, types.ActionExecutionStatusSucceeded),
+		newAction("Deploy", "Deploy", 240*time.Second, 300*time.Second, types.ActionExecutionStatusSucceeded),
+	}
+}
+
+// TestStageDurations will test stageDurations()
+func TestStageDurations(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	durations := stageDurations(newStageActions(started))
+	if len(durations) != 2 || durations["Build"] != 240*time.Second || durations["Deploy"] != 60*time.Second {
+		t.Fatal("durations were not as expected", durations)
+	}
+
+	// Failed (and unfinished) stages are not comparable
+	actions := newStageActions(started)
+	actions[1].Status = types.ActionExecutionStatusFailed
+	if durations = stageDurations(actions); len(durations) != 1 || durations["Deploy"] != 60*time.Second {
+		t.Fatal("durations were not as expected", durations)
+	}
+}
+
+// TestMedianDuration will test medianDuration()
+func TestMedianDuration(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		durations []time.Duration
+		expected  time.Duration
+	}{
+		{nil, 0},
+		{[]time.Duration{5 * time.Second}, 5 * time.Second},
+		{[]time.Duration{9 * time.Second, time.Second, 5 * time.Second}, 5 * time.Second},
+		{[]time.Duration{4 * time.Second, time.Second, 2 * time.Second, 100 * time.Second}, 3 * time.Second},
+	}
+
+	for _, test := range tests {
+		if output := medianDuration(test.durations); output != test.expected {
+			t.Errorf("%s Failed: [%v] inputted and [%s] expected, but got [%s]", t.Name(), test.durations, test.expected, output)
+		}
+	}
+}
+
+// TestStageTrends will test Handler.stageTrends() flagging the stages slower than the trailing median
+func TestStageTrends(t *testing.T) {
+	h := newTestHandler(Config{StageDurationTable: "stage-durations", StageDurationThreshold: 1.5})
+	h.deps.CodePipeline = &mockDurationsCodePipelineClient{}
+	dynamo := &mockDurationsDynamoClient{items: make(map[string][]map[string]*dynamodb.AttributeValue)}
+	h.deps.DynamoDB = dynamo
+
+	// Not enough history yet
+	if trends, err := h.stageTrends(context.Background(), "web", "1"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(trends) != 0 || len(dynamo.items["web#Build"]) != 1 {
+		t.Fatal("trends were not as expected", trends)
+	}
+
+	// Build usually takes 100 seconds, Deploy 50 seconds
+	for i := 0; i < stageDurationMinSamples; i++ {
+		dynamo.items["web#Build"] = append(dynamo.items["web#Build"], map[string]*dynamodb.AttributeValue{
+			"milliseconds": {N: aws.String(strconv.Itoa(100000))},
+		})
+		dynamo.items["web#Deploy"] = append(dynamo.items["web#Deploy"], map[string]*dynamodb.AttributeValue{
+			"milliseconds": {N: aws.String(strconv.Itoa(50000))},
+		})
+	}
+	trends, err := h.stageTrends(context.Background(), "web", "2")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(trends) != 1 || trends[0].String() != "Build 2.4x slower than usual" || trends[0].Median != 100*time.Second {
+		t.Fatal("trends were not as expected", trends)
+	}
+
+	annotations := trendAnnotations(trends)
+	if len(annotations) != 1 || annotations[0].AnnotationLevel != checkAnnotationWarning || annotations[0].Title != "Build 2.4x slower than usual" ||
+		annotations[0].Message != "Build took 4m0s, the median of the last 30 executions is 1m40s" {
+		t.Fatal("annotations were not as expected", annotations)
+	}
+}
@@ -0,0 +1,95 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pemPrefix starts an inline certificate bundle (otherwise GITHUB_CA_BUNDLE is a file path)
+const pemPrefix = "-----BEGIN"
+
+// githubAPIURL will return the base url of the GitHub API (GITHUB_API_BASE_URL for GitHub Enterprise
+// Server, IE: https://github.mycorp.com/api/v3)
+func githubAPIURL(cfg Config) (string, error) {
+	if len(cfg.GithubAPIBaseURL) == 0 {
+		return defaultGithubAPIURL, nil
+	}
+	if apiURL, err := url.Parse(cfg.GithubAPIBaseURL); err != nil || len(apiURL.Host) == 0 {
+		return "", fmt.Errorf("invalid GITHUB_API_BASE_URL: %s (IE: https://github.mycorp.com/api/v3)", cfg.GithubAPIBaseURL)
+	}
+	return strings.TrimSuffix(cfg.GithubAPIBaseURL, "/"), nil
+}
+
+// githubWebHost will return the host of the GitHub revision urls (the enterprise host of GITHUB_API_BASE_URL)
+func (h *Handler) githubWebHost() string {
+	if apiURL, err := url.Parse(h.cfg.GithubAPIBaseURL); err == nil && len(apiURL.Host) > 0 {
+		return apiURL.Host
+	}
+	return githubHost
+}
+
+// Per-container cache of the GitHub clients of the servers with their own certificates (the handler of
+// each event reuses the open connections)
+var (
+	githubClients   = make(map[string]*http.Client)
+	githubClientsMu sync.Mutex
+)
+
+// cachedGithubClient will return the HTTP client of the certificate settings, created once per container
+func cachedGithubClient(cfg Config) (*http.Client, error) {
+	githubClientsMu.Lock()
+	defer githubClientsMu.Unlock()
+	key := strconv.FormatBool(cfg.GithubInsecureSkipVerify) + "|" + cfg.GithubCABundle
+	if client, ok := githubClients[key]; ok {
+		return client, nil
+	}
+	client, err := newGithubClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	githubClients[key] = client
+	return client, nil
+}
+
+// newGithubClient will create the HTTP client of a GitHub Enterprise Server with a private certificate
+// authority (GITHUB_CA_BUNDLE) or without verifying its certificate (GITHUB_INSECURE_SKIP_VERIFY)
+func newGithubClient(cfg Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.GithubInsecureSkipVerify}
+	if cfg.GithubInsecureSkipVerify {
+		logWarnf(context.Background(), "the certificate of the GitHub server is not verified (GITHUB_INSECURE_SKIP_VERIFY)")
+	}
+
+	// Trust the certificate authority of the server next to the system ones
+	if len(cfg.GithubCABundle) > 0 {
+		bundle := []byte(cfg.GithubCABundle)
+		if !strings.HasPrefix(strings.TrimSpace(cfg.GithubCABundle), pemPrefix) {
+			path, err := expandPath(cfg.GithubCABundle)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read GITHUB_CA_BUNDLE: %s", err.Error())
+			} else if bundle, err = ioutil.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("unable to read GITHUB_CA_BUNDLE: %s", err.Error())
+			}
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("no certificate found in GITHUB_CA_BUNDLE")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,122 @@
+package pipelinestatus
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGithubAPIURL will test githubAPIURL()
+func TestGithubAPIURL(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		baseURL       string
+		expected      string
+		expectedError bool
+	}{
+		{"", defaultGithubAPIURL, false},
+		{"https://github.mycorp.com/api/v3", "https://github.mycorp.com/api/v3", false},
+		{"https://github.mycorp.com/api/v3/", "https://github.mycorp.com/api/v3", false},
+		{"github.mycorp.com", "", true},
+	}
+
+	for _, test := range tests {
+		if output, err := githubAPIURL(Config{GithubAPIBaseURL: test.baseURL}); (err != nil) != test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, error expected [%t] received [%v]", t.Name(), test.baseURL, test.expectedError, err)
+		} else if output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.baseURL, test.expected, output)
+		}
+	}
+}
+
+// TestEnterpriseRevisionURL will test the revision urls of GitHub Enterprise Server
+func TestEnterpriseRevisionURL(t *testing.T) {
+	h := newTestHandler(Config{GithubAPIBaseURL: "https://github.mycorp.com/api/v3"})
+	if host := h.githubWebHost(); host != "github.mycorp.com" {
+		t.Fatal("host was not as expected", host)
+	}
+
+	revisionURL, _ := url.Parse("https://github.mycorp.com/org/repo/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")
+	if err := validateArtifact("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", revisionURL, h.forgeHosts()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if owner, repo := revisionRepository(revisionURL, h.forgeHosts()); owner != "org" || repo != "repo" {
+		t.Fatal("repository was not as expected", owner, repo)
+	} else if reporter := h.statusReporter("web", revisionURL); reporter.Name() != forgeGithub {
+		t.Fatal("reporter was not as expected", reporter.Name())
+	}
+
+	// github.com is still a GitHub host
+	if h = newTestHandler(Config{}); h.githubWebHost() != githubHost {
+		t.Fatal("host was not as expected", h.githubWebHost())
+	}
+}
+
+// TestNewGithubClient will test newGithubClient() trusting a private certificate authority
+func TestNewGithubClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	// Not trusted by default
+	client, err := newGithubClient(Config{})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = client.Get(server.URL); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Inline bundle
+	if client, err = newGithubClient(Config{GithubCABundle: bundle}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = client.Get(server.URL); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Bundle file
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err = ioutil.WriteFile(path, []byte(bundle), 0600); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if client, err = newGithubClient(Config{GithubCABundle: path}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = client.Get(server.URL); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Bundle file in the home directory
+	home, previousHome, previousProfile := t.TempDir(), os.Getenv("HOME"), os.Getenv("USERPROFILE")
+	_ = os.Setenv("HOME", home)
+	_ = os.Setenv("USERPROFILE", home)
+	defer func() {
+		_ = os.Setenv("HOME", previousHome)
+		_ = os.Setenv("USERPROFILE", previousProfile)
+	}()
+	if err = ioutil.WriteFile(filepath.Join(home, "ca.pem"), []byte(bundle), 0600); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if client, err = newGithubClient(Config{GithubCABundle: "~/ca.pem"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = client.Get(server.URL); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Skipping the verification
+	if client, err = newGithubClient(Config{GithubInsecureSkipVerify: true}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if _, err = client.Get(server.URL); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Invalid bundles
+	if _, err = newGithubClient(Config{GithubCABundle: "-----BEGIN CERTIFICATE-----\nnot a certificate"}); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = newGithubClient(Config{GithubCABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
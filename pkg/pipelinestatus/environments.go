@@ -0,0 +1,153 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GitHub limits of the environment protection rules
+const (
+	environmentMaxReviewers = 6
+	environmentMaxWaitTimer = 43200
+)
+
+// GitHub reviewer types
+const (
+	reviewerTypeTeam = "Team"
+	reviewerTypeUser = "User"
+)
+
+// environmentDefinitions are the protection rules of the GitHub environments of each pipeline
+// (IE: my-pipeline: {production: {reviewers: [jane-doe, my-org/platform], wait_timer: 30}})
+type environmentDefinitions map[string]map[string]environmentRules
+
+// environmentRules are the protection rules of a GitHub environment
+type environmentRules struct {
+	Reviewers []string `yaml:"reviewers"`  // users (login) or teams (org/slug)
+	WaitTimer int      `yaml:"wait_timer"` // minutes
+}
+
+// environmentProtection is the GitHub payload to create or update an environment
+type environmentProtection struct {
+	Reviewers []environmentReviewer `json:"reviewers"`
+	WaitTimer int                   `json:"wait_timer"`
+}
+
+// environmentReviewer is a required reviewer of a GitHub environment
+type environmentReviewer struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// environmentSync is the result of syncing an environment
+type environmentSync struct {
+	Environment string   `json:"environment"`
+	Pipeline    string   `json:"pipeline"`
+	Repository  string   `json:"repository"`
+	Reviewers   []string `json:"reviewers"`
+	WaitTimer   int      `json:"wait_timer"`
+}
+
+// loadEnvironmentDefinitions will read and validate the environment definitions (YAML)
+func loadEnvironmentDefinitions(r io.Reader) (definitions environmentDefinitions, err error) {
+	var b []byte
+	if b, err = ioutil.ReadAll(r); err != nil {
+		return
+	} else if err = yaml.UnmarshalStrict(b, &definitions); err != nil {
+		return
+	}
+	for pipeline, environments := range definitions {
+		for name, rules := range environments {
+			if len(rules.Reviewers) > environmentMaxReviewers {
+				return nil, fmt.Errorf("%s/%s: at most %d reviewers are allowed", pipeline, name, environmentMaxReviewers)
+			} else if rules.WaitTimer < 0 || rules.WaitTimer > environmentMaxWaitTimer {
+				return nil, fmt.Errorf("%s/%s: wait_timer must be between 0 and %d minutes", pipeline, name, environmentMaxWaitTimer)
+			}
+		}
+	}
+	return
+}
+
+// getReviewer will resolve a user (login) or team (org/slug) to a required reviewer
+func (h *Handler) getReviewer(ctx context.Context, name string) (reviewer environmentReviewer, err error) {
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		reviewer.Type = reviewerTypeTeam
+		err = h.githubGet(ctx, fmt.Sprintf("/orgs/%s/teams/%s", parts[0], parts[1]), &reviewer)
+	} else {
+		reviewer.Type = reviewerTypeUser
+		err = h.githubGet(ctx, "/users/"+name, &reviewer)
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to find reviewer %s: %s", name, err.Error())
+	}
+	return
+}
+
+// syncEnvironment will create or update the GitHub environment with the protection rules
+func (h *Handler) syncEnvironment(ctx context.Context, owner, repo, name string, rules environmentRules) error {
+	protection := environmentProtection{Reviewers: []environmentReviewer{}, WaitTimer: rules.WaitTimer}
+	for _, reviewer := range rules.Reviewers {
+		r, err := h.getReviewer(ctx, reviewer)
+		if err != nil {
+			return err
+		}
+		protection.Reviewers = append(protection.Reviewers, r)
+	}
+	req, err := h.newGithubRequest(ctx, http.MethodPut, fmt.Sprintf("/repos/%s/%s/environments/%s", owner, repo, name), &protection)
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusOK, nil)
+}
+
+// syncEnvironments will apply the definitions to the repositories of the pipelines (sorted by pipeline
+// and environment), nothing is changed on a dry run
+func (h *Handler) syncEnvironments(ctx context.Context, definitions environmentDefinitions,
+	dryRun bool) (results []environmentSync, err error) {
+	if !dryRun {
+		if err = h.requireGithubFeature(ctx, githubFeatureEnvironments); err != nil {
+			return
+		}
+	}
+
+	pipelines := make([]string, 0, len(definitions))
+	for pipeline := range definitions {
+		pipelines = append(pipelines, pipeline)
+	}
+	sort.Strings(pipelines)
+
+	for _, pipeline := range pipelines {
+		var owner, repo string
+		if owner, repo, _, err = getSourceBranch(ctx, pipeline, h.deps.CodePipeline); err != nil {
+			return
+		}
+		names := make([]string, 0, len(definitions[pipeline]))
+		for name := range definitions[pipeline] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rules := definitions[pipeline][name]
+			if !dryRun {
+				if err = h.syncEnvironment(ctx, owner, repo, name, rules); err != nil {
+					return
+				}
+			}
+			results = append(results, environmentSync{
+				Environment: name,
+				Pipeline:    pipeline,
+				Repository:  owner + "/" + repo,
+				Reviewers:   append([]string{}, rules.Reviewers...),
+				WaitTimer:   rules.WaitTimer,
+			})
+		}
+	}
+	return
+}
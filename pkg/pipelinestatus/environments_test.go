@@ -0,0 +1,121 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadEnvironmentDefinitions will test loadEnvironmentDefinitions()
+func TestLoadEnvironmentDefinitions(t *testing.T) {
+	var tests = []struct {
+		definition    string
+		expectedError bool
+	}{
+		{"some-pipeline:\n  production:\n    reviewers: [jane-doe, my-org/platform]\n    wait_timer: 30\n", false},
+		{"some-pipeline:\n  staging: {}\n", false},
+		{"some-pipeline:\n  production:\n    reviewers: [a, b, c, d, e, f, g]\n", true},
+		{"some-pipeline:\n  production:\n    wait_timer: 50000\n", true},
+		{"some-pipeline:\n  production:\n    approvers: [jane-doe]\n", true},
+		{"not: [valid", true},
+	}
+
+	for _, test := range tests {
+		if _, err := loadEnvironmentDefinitions(strings.NewReader(test.definition)); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, expected to throw an error, but no error", t.Name(), test.definition)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.definition, err.Error())
+		}
+	}
+}
+
+// TestSyncEnvironments will test syncEnvironments()
+func TestSyncEnvironments(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	var received map[string]environmentProtection
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/meta":
+			_, _ = w.Write([]byte(`{}`))
+		case "/users/jane-doe":
+			_, _ = w.Write([]byte(`{"id":1}`))
+		case "/orgs/my-org/teams/platform":
+			_, _ = w.Write([]byte(`{"id":2}`))
+		case "/repos/mrz1836/codepipeline-to-github/environments/production",
+			"/repos/mrz1836/codepipeline-to-github/environments/staging":
+			var protection environmentProtection
+			_ = json.NewDecoder(r.Body).Decode(&protection)
+			received[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = protection
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	definitions := environmentDefinitions{"some-pipeline": {
+		"production": {Reviewers: []string{"jane-doe", "my-org/platform"}, WaitTimer: 30},
+		"staging":    {},
+	}}
+
+	// Dry run
+	received = make(map[string]environmentProtection)
+	results, err := h.syncEnvironments(context.Background(), definitions, true)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(received) != 0 {
+		t.Fatal("nothing should have been changed", received)
+	} else if len(results) != 2 || results[0].Environment != "production" || results[0].Repository != "mrz1836/codepipeline-to-github" {
+		t.Fatal("results were not as expected", results)
+	}
+
+	// Sync
+	if _, err = h.syncEnvironments(context.Background(), definitions, false); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if production := received["production"]; production.WaitTimer != 30 || len(production.Reviewers) != 2 {
+		t.Fatal("production was not as expected", production)
+	} else if production.Reviewers[0] != (environmentReviewer{ID: 1, Type: reviewerTypeUser}) {
+		t.Fatal("user reviewer was not as expected", production.Reviewers[0])
+	} else if production.Reviewers[1] != (environmentReviewer{ID: 2, Type: reviewerTypeTeam}) {
+		t.Fatal("team reviewer was not as expected", production.Reviewers[1])
+	} else if staging, ok := received["staging"]; !ok || len(staging.Reviewers) != 0 {
+		t.Fatal("staging was not as expected", staging)
+	}
+
+	// Unknown reviewer
+	definitions["some-pipeline"]["production"] = environmentRules{Reviewers: []string{"missing"}}
+	if _, err = h.syncEnvironments(context.Background(), definitions, false); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Pipeline without a GitHub source
+	if _, err = h.syncEnvironments(context.Background(), environmentDefinitions{"s3-pipeline": {"production": {}}}, true); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestEnvironmentsCommand will test environmentsCommand()
+func TestEnvironmentsCommand(t *testing.T) {
+	h := newTestHandler(Config{})
+
+	// Missing file
+	if err := environmentsCommand(nil, &bytes.Buffer{}, h); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	file := filepath.Join(t.TempDir(), "environments.yml")
+	if err := ioutil.WriteFile(file, []byte("some-pipeline:\n  production:\n    wait_timer: 30\n"), 0600); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	var out bytes.Buffer
+	if err := environmentsCommand([]string{"-file", file, "-dry-run"}, &out, h); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !strings.Contains(out.String(), "mrz1836/codepipeline-to-github") || !strings.Contains(out.String(), "30m") {
+		t.Fatal("output was not as expected", out.String())
+	}
+}
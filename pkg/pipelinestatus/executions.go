@@ -0,0 +1,100 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// executionTTL is how long the start of an execution is kept (manual approvals can hold an execution for 7 days)
+const executionTTL = 30 * 24 * time.Hour
+
+// executionDurationStates are the final states that report the duration of the execution
+var executionDurationStates = map[string]bool{
+	"FAILED":       true,
+	stateSucceeded: true,
+}
+
+// formatElapsed will format a duration in whole seconds (IE: 1h 2m 5s, 7m 32s or 45s)
+func formatElapsed(d time.Duration) string {
+	seconds := int64(d.Round(time.Second) / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	var parts []string
+	if hours := seconds / 3600; hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes := seconds % 3600 / 60; minutes > 0 || len(parts) > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(append(parts, fmt.Sprintf("%ds", seconds%60)), " ")
+}
+
+// recordExecutionStart will store the start time of an execution
+func recordExecutionStart(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, executionID, pipeline string, started, now time.Time) error {
+	_, err := dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"execution_id": {S: aws.String(executionID)},
+			"expires":      {N: aws.String(strconv.FormatInt(now.Add(executionTTL).Unix(), 10))},
+			"pipeline":     {S: aws.String(pipeline)},
+			"started":      {S: aws.String(started.UTC().Format(time.RFC3339Nano))},
+		},
+		TableName: aws.String(table),
+	})
+	return err
+}
+
+// getExecutionStart will return the start time of an execution (zero if the start was not recorded)
+func getExecutionStart(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, executionID string) (started time.Time, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"execution_id": {S: aws.String(executionID)},
+		},
+		TableName: aws.String(table),
+	}); err != nil {
+		return
+	} else if output.Item != nil && output.Item["started"] != nil {
+		started, err = time.Parse(time.RFC3339Nano, aws.StringValue(output.Item["started"].S))
+	}
+	return
+}
+
+// stateTitle will return a state of an event as a word of a description (IE: SUCCEEDED is Succeeded)
+func stateTitle(state string) string {
+	if len(state) == 0 {
+		return state
+	}
+	return state[:1] + strings.ToLower(state[1:])
+}
+
+// executionDuration will record the start of an execution (STARTED) and return the total duration of a
+// finished execution, zero for the other states or when the start is unknown
+func (h *Handler) executionDuration(ctx context.Context, ev Event) (time.Duration, error) {
+	eventTime := ev.Time
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	if ev.Detail.State == "STARTED" {
+		return 0, recordExecutionStart(ctx, h.deps.DynamoDB, h.cfg.ExecutionTable, ev.Detail.ExecutionID, ev.Detail.Pipeline,
+			eventTime, time.Now())
+	} else if !executionDurationStates[ev.Detail.State] {
+		return 0, nil
+	}
+	started, err := getExecutionStart(ctx, h.deps.DynamoDB, h.cfg.ExecutionTable, ev.Detail.ExecutionID)
+	if err != nil {
+		return 0, err
+	} else if started.IsZero() {
+		logf(ctx, "the start of execution %s was not recorded", ev.Detail.ExecutionID)
+		return 0, nil
+	}
+	return eventTime.Sub(started), nil
+}
@@ -0,0 +1,109 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockExecutionsDynamoClient keeps the items of the executions table
+type mockExecutionsDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+// GetItemWithContext is a mock request for dynamodb
+func (m *mockExecutionsDynamoClient) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.items[aws.StringValue(input.Key["execution_id"].S)]}, nil
+}
+
+// PutItemWithContext is a mock request for dynamodb
+func (m *mockExecutionsDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.items[aws.StringValue(input.Item["execution_id"].S)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// TestFormatElapsed will test formatElapsed()
+func TestFormatElapsed(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{-time.Second, "0s"},
+		{45*time.Second + 400*time.Millisecond, "45s"},
+		{7*time.Minute + 32*time.Second, "7m 32s"},
+		{time.Hour + 5*time.Second, "1h 0m 5s"},
+		{26*time.Hour + 2*time.Minute, "26h 2m 0s"},
+	}
+
+	for _, test := range tests {
+		if output := formatElapsed(test.duration); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.duration, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessEventExecutionDuration will test ProcessEvent() reporting the duration of the execution
+func TestHandlerProcessEventExecutionDuration(t *testing.T) {
+	h := newTestHandler(Config{
+		ExecutionTable:       "executions",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	dynamo := &mockExecutionsDynamoClient{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	h.deps.DynamoDB = dynamo
+
+	var descriptions []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status struct {
+			Description string `json:"description"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		descriptions = append(descriptions, status.Description)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "STARTED"},
+		Time: started}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if dynamo.items["12345678"] == nil || aws.StringValue(dynamo.items["12345678"]["started"].S) != "2020-05-01T12:00:00Z" {
+		t.Fatal("start was not recorded", dynamo.items)
+	}
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: started.Add(7*time.Minute + 32*time.Second)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(descriptions) != 2 || descriptions[0] != "" || descriptions[1] != "Succeeded in 7m 32s" {
+		t.Fatal("descriptions were not as expected", descriptions)
+	}
+
+	// Rendered with the description template
+	h.cfg.AWSRegion = "us-east-1"
+	h.cfg.DescriptionTemplate = "{{.Status}} in {{.Region}} ({{.Duration}})"
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: started.Add(time.Hour)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if descriptions[2] != "Succeeded in us-east-1 (1h 0m 0s)" {
+		t.Fatal("description was not as expected", descriptions[2])
+	}
+	h.cfg.DescriptionTemplate = ""
+
+	// Unknown start
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "87654321", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: started}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if descriptions[3] != "" {
+		t.Fatal("description was not as expected", descriptions[3])
+	}
+}
@@ -0,0 +1,95 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// commitComment is the payload of a commit comment
+type commitComment struct {
+	Body string `json:"body"`
+}
+
+// failureDetail will return the stage, the action and the error summary of a failed action
+// (IE: Build/CodeBuild failed: Build failed in container 4f2a9c1b after 312 seconds)
+func failureDetail(action *types.ActionExecutionDetail) string {
+	detail := aws.StringValue(action.StageName) + "/" + aws.StringValue(action.ActionName) + " failed"
+	if message := failureMessage(action); message != aws.StringValue(action.ActionName) {
+		detail += ": " + strings.Join(strings.Fields(message), " ")
+	}
+	return detail
+}
+
+// failureComment will return the markdown of the comment of a failed action (only the pipeline if the
+// failed action is not known), with the links to the execution and to the logs of the action
+func failureComment(pipelineName string, action *types.ActionExecutionDetail, targetURL string) string {
+	if action == nil {
+		body := fmt.Sprintf("**%s** failed", pipelineName)
+		if len(targetURL) > 0 {
+			body += "\n\n[View the execution](" + targetURL + ")"
+		}
+		return body
+	}
+	body := fmt.Sprintf("**%s** failed in stage **%s**, action **%s**", pipelineName,
+		aws.StringValue(action.StageName), aws.StringValue(action.ActionName))
+	if message := failureMessage(action); message != aws.StringValue(action.ActionName) {
+		body += "\n\n```\n" + strings.TrimSpace(message) + "\n```"
+	}
+	var links []string
+	if len(targetURL) > 0 {
+		links = append(links, "[View the execution]("+targetURL+")")
+	}
+	if action.Output != nil && action.Output.ExecutionResult != nil {
+		if logsURL := aws.StringValue(action.Output.ExecutionResult.ExternalExecutionUrl); len(logsURL) > 0 && logsURL != targetURL {
+			links = append(links, "[View the logs]("+logsURL+")")
+		}
+	}
+	if len(links) > 0 {
+		body += "\n\n" + strings.Join(links, " · ")
+	}
+	return body
+}
+
+// postFailureComment will comment the failed action on the commit (FAILURE_COMMENT)
+func (h *Handler) postFailureComment(ctx context.Context, owner, repo, commit, pipelineName string, action *types.ActionExecutionDetail,
+	targetURL string) error {
+	req, err := h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/commits/%s/comments", owner, repo, commit),
+		&commitComment{Body: failureComment(pipelineName, action, targetURL)})
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusCreated, nil)
+}
+
+// postFailurePullRequestComments will comment the failure on the open pull requests containing the commit
+// (FAILURE_PULL_REQUEST_COMMENT), statuses are easy to miss in a pull request
+func (h *Handler) postFailurePullRequestComments(ctx context.Context, owner, repo, commit, pipelineName string,
+	action *types.ActionExecutionDetail, targetURL string) error {
+
+	// Pull requests of the commit
+	var pulls []pullRequest
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/pulls", owner, repo, commit), &pulls); err != nil {
+		return err
+	}
+
+	// Comment on each open pull request
+	body := failureComment(pipelineName, action, targetURL)
+	for _, pull := range pulls {
+		if pull.State != "open" {
+			continue
+		}
+		req, err := h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, pull.Number),
+			&issueComment{Body: body})
+		if err != nil {
+			return err
+		} else if err = h.doGithubRequest(req, http.StatusCreated, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestFailureDetail will test failureDetail()
+func TestFailureDetail(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		summary  string
+		expected string
+	}{
+		{"Build failed in container 4f2a9c1b", "Build/CodeBuild failed: Build failed in container 4f2a9c1b"},
+		{"exit status 1\n\n  npm ERR! test failed", "Build/CodeBuild failed: exit status 1 npm ERR! test failed"},
+		{"", "Build/CodeBuild failed"},
+	}
+
+	for _, test := range tests {
+		action := &types.ActionExecutionDetail{
+			ActionName: aws.String("CodeBuild"),
+			Output: &types.ActionExecutionOutput{
+				ExecutionResult: &types.ActionExecutionResult{ExternalExecutionSummary: aws.String(test.summary)},
+			},
+			StageName: aws.String("Build"),
+		}
+		if output := failureDetail(action); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got: %s", t.Name(), test.summary, test.expected, output)
+		}
+	}
+}
+
+// TestFailureComment will test failureComment()
+func TestFailureComment(t *testing.T) {
+	t.Parallel()
+
+	action := &types.ActionExecutionDetail{
+		ActionName: aws.String("CodeBuild"),
+		Output: &types.ActionExecutionOutput{
+			ExecutionResult: &types.ActionExecutionResult{ExternalExecutionSummary: aws.String("npm ERR! test failed\n")},
+		},
+		StageName: aws.String("Build"),
+	}
+	if body := failureComment("web", action, "https://console.aws.amazon.com"); body !=
+		"**web** failed in stage **Build**, action **CodeBuild**\n\n```\nnpm ERR! test failed\n```\n\n[View the execution](https://console.aws.amazon.com)" {
+		t.Fatal("comment was not as expected", body)
+	}
+
+	// Link to the logs of the action
+	action.Output.ExecutionResult.ExternalExecutionUrl = aws.String("https://console.aws.amazon.com/codebuild/web:4f2a9c1b")
+	if body := failureComment("web", action, "https://console.aws.amazon.com"); !strings.HasSuffix(body,
+		"[View the execution](https://console.aws.amazon.com) · [View the logs](https://console.aws.amazon.com/codebuild/web:4f2a9c1b)") {
+		t.Fatal("comment was not as expected", body)
+	}
+
+	// No summary or link
+	action.Output = nil
+	if body := failureComment("web", action, ""); body != "**web** failed in stage **Build**, action **CodeBuild**" {
+		t.Fatal("comment was not as expected", body)
+	}
+
+	// Unknown action
+	if body := failureComment("web", nil, "https://console.aws.amazon.com"); body !=
+		"**web** failed\n\n[View the execution](https://console.aws.amazon.com)" {
+		t.Fatal("comment was not as expected", body)
+	}
+}
+
+// TestHandlerProcessEventFailureDetails will test ProcessEvent() describing and commenting the failed action
+func TestHandlerProcessEventFailureDetails(t *testing.T) {
+	h := newTestHandler(Config{
+		FailureComment:       true,
+		FailureDetails:       true,
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+
+	var received payload
+	var comment commitComment
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			_ = json.NewDecoder(r.Body).Decode(&comment)
+		} else {
+			_ = json.NewDecoder(r.Body).Decode(&received)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStateFailure ||
+		received.Description != "Build/Build-and-Deploy-Stack failed: Build failed in container 4f2a9c1b after 312 seconds" {
+		t.Fatal("status was not as expected", received)
+	} else if !strings.HasPrefix(comment.Body, "**status-fail** failed in stage **Build**, action **Build-and-Deploy-Stack**") {
+		t.Fatal("comment was not as expected", comment.Body)
+	}
+
+	// Successful executions are not described
+	comment = commitComment{}
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStateSuccess || len(received.Description) > 0 || len(comment.Body) > 0 {
+		t.Fatal("status was not as expected", received, comment)
+	}
+}
+
+// TestHandlerProcessEventFailurePullRequestComment will test ProcessEvent() commenting the failure on the open pull requests
+func TestHandlerProcessEventFailurePullRequestComment(t *testing.T) {
+	h := newTestHandler(Config{
+		FailurePullRequestComment: true,
+		GithubAccessToken:         "1234567",
+		GithubMaxConcurrency:      1,
+		Stage:                     stageTesting,
+	})
+
+	comments := make(map[string]issueComment)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pulls"):
+			_, _ = w.Write([]byte(`[{"number":1,"state":"open"},{"number":2,"state":"closed"},{"number":3,"state":"open"}]`))
+		case strings.HasSuffix(r.URL.Path, "/comments"):
+			var comment issueComment
+			_ = json.NewDecoder(r.Body).Decode(&comment)
+			comments[r.URL.Path] = comment
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(comments) != 2 {
+		t.Fatal("comments were not as expected", comments)
+	} else if comment := comments["/repos/mrz1836/codepipeline-to-github/issues/3/comments"]; !strings.HasPrefix(comment.Body,
+		"**status-fail** failed in stage **Build**, action **Build-and-Deploy-Stack**") {
+		t.Fatal("comment was not as expected", comment.Body)
+	}
+
+	// Successful executions are not commented
+	comments = make(map[string]issueComment)
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(comments) != 0 {
+		t.Fatal("comments were not as expected", comments)
+	}
+}
@@ -0,0 +1,147 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Flaky failure defaults
+const (
+	flakyFailureTTL     = 14 * 24 * time.Hour
+	fingerprintHashSize = 12
+)
+
+// volatileTokens matches the parts of a failure message that change between runs (ids, hashes, numbers)
+var volatileTokens = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{7,}|\d+`)
+
+// flakyFailureDescription will fingerprint the failed action of an execution and return a description if the
+// same failure has been seen often enough this week (counting this one), the failure is only counted when
+// record is called once its status is posted (not for the duplicate deliveries or the failed posts)
+func (h *Handler) flakyFailureDescription(ctx context.Context, pipelineName,
+	executionID string) (description string, record func(), err error) {
+	record = func() {}
+
+	// Find the action that failed
+	var action *types.ActionExecutionDetail
+	if action, err = getFailedAction(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil || action == nil {
+		return
+	}
+	stageName, fingerprint, now := aws.StringValue(action.StageName), failureFingerprint(failureMessage(action)), time.Now().UTC()
+	record = func() {
+		if _, recordErr := recordFailure(ctx, h.deps.DynamoDB, h.cfg.FlakyFailureTable, pipelineName, stageName,
+			fingerprint, now); recordErr != nil {
+			logWarnf(ctx, "unable to record the failure: %s", recordErr.Error())
+		}
+	}
+
+	// Only tag failures that keep happening
+	var count int64
+	if count, err = failureCount(ctx, h.deps.DynamoDB, h.cfg.FlakyFailureTable, pipelineName, stageName, fingerprint, now); err != nil {
+		return
+	} else if count+1 >= int64(h.cfg.FlakyFailureThreshold) {
+		description = fmt.Sprintf("known flaky failure (seen %dx this week)", count+1)
+	}
+	return
+}
+
+// getFailedAction will return the first failed action of a pipeline execution
+func getFailedAction(ctx context.Context, pipelineName, executionID string,
+	pipeline CodePipelineAPI) (action *types.ActionExecutionDetail, err error) {
+	paginator := codepipeline.NewListActionExecutionsPaginator(pipeline, &codepipeline.ListActionExecutionsInput{
+		Filter: &types.ActionExecutionFilter{
+			PipelineExecutionId: aws.String(executionID),
+		},
+		PipelineName: aws.String(pipelineName),
+	})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListActionExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for i := range page.ActionExecutionDetails {
+			if page.ActionExecutionDetails[i].Status == types.ActionExecutionStatusFailed {
+				return &page.ActionExecutionDetails[i], nil
+			}
+		}
+	}
+	return
+}
+
+// failureMessage will return the summary of a failed action (or the action name if there is none)
+func failureMessage(action *types.ActionExecutionDetail) string {
+	if action.Output != nil && action.Output.ExecutionResult != nil {
+		if summary := aws.StringValue(action.Output.ExecutionResult.ExternalExecutionSummary); len(summary) > 0 {
+			return summary
+		}
+	}
+	return aws.StringValue(action.ActionName)
+}
+
+// failureFingerprint will hash a failure message after removing the volatile parts
+func failureFingerprint(message string) string {
+	normalized := volatileTokens.ReplaceAllString(strings.ToLower(strings.TrimSpace(message)), "#")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])[:fingerprintHashSize]
+}
+
+// failureKey is the key of the weekly count of a failure fingerprint
+func failureKey(pipelineName, stageName, fingerprint string, now time.Time) string {
+	year, week := now.ISOWeek()
+	return fmt.Sprintf("%s#%s#%s#%d-W%02d", pipelineName, stageName, fingerprint, year, week)
+}
+
+// failureCount will return the weekly count of a failure fingerprint (before this failure is recorded)
+func failureCount(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, stageName, fingerprint string,
+	now time.Time) (count int64, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"fingerprint": {S: aws.String(failureKey(pipelineName, stageName, fingerprint, now))},
+		},
+		TableName: aws.String(table),
+	}); err != nil || output == nil || output.Item["count"] == nil {
+		return
+	}
+	return strconv.ParseInt(aws.StringValue(output.Item["count"].N), 10, 64)
+}
+
+// recordFailure will increment the weekly count of a failure fingerprint and return the new count
+func recordFailure(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName, stageName, fingerprint string,
+	now time.Time) (count int64, err error) {
+
+	var output *dynamodb.UpdateItemOutput
+	if output, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#count": aws.String("count"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expires": {N: aws.String(strconv.FormatInt(now.Add(flakyFailureTTL).Unix(), 10))},
+			":one":     {N: aws.String("1")},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"fingerprint": {S: aws.String(failureKey(pipelineName, stageName, fingerprint, now))},
+		},
+		ReturnValues:     aws.String(dynamodb.ReturnValueUpdatedNew),
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("ADD #count :one SET expires_at = :expires"),
+	}); err != nil {
+		return
+	} else if output == nil || output.Attributes["count"] == nil {
+		err = fmt.Errorf("missing count for fingerprint: %s", fingerprint)
+		return
+	}
+	return strconv.ParseInt(aws.StringValue(output.Attributes["count"].N), 10, 64)
+}
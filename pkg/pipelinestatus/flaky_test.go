@@ -0,0 +1,154 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ListActionExecutions is a mock request for codepipeline
+func (m *mockCodePipelineClient) ListActionExecutions(_ context.Context, input *codepipeline.ListActionExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+
+	var details []types.ActionExecutionDetail
+	if aws.StringValue(input.PipelineName) == "status-fail" {
+		started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+		details = append(details, types.ActionExecutionDetail{
+			ActionName: aws.String("Build-and-Deploy-Stack"),
+			Input: &types.ActionExecutionInput{
+				ActionTypeId: &types.ActionTypeId{Provider: aws.String(actionProviderCodeBuild)},
+			},
+			LastUpdateTime: aws.Time(started.Add(150 * time.Second)),
+			StartTime:      aws.Time(started),
+			Output: &types.ActionExecutionOutput{
+				ExecutionResult: &types.ActionExecutionResult{
+					ExternalExecutionId:      aws.String("web:4f2a9c1b"),
+					ExternalExecutionSummary: aws.String("Build failed in container 4f2a9c1b after 312 seconds"),
+				},
+			},
+			StageName: aws.String("Build"),
+			Status:    types.ActionExecutionStatusFailed,
+		})
+	}
+	return &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: details}, nil
+}
+
+// TestFailureFingerprint will test failureFingerprint()
+func TestFailureFingerprint(t *testing.T) {
+	t.Parallel()
+
+	first := failureFingerprint("Build failed in container 4f2a9c1b after 312 seconds")
+	second := failureFingerprint("build failed in container 9e8d7c6b after 98 seconds ")
+	if first != second {
+		t.Fatal("fingerprints should ignore volatile values", first, second)
+	} else if len(first) != fingerprintHashSize {
+		t.Fatal("fingerprint length was not as expected", first)
+	} else if first == failureFingerprint("Unit tests failed") {
+		t.Fatal("fingerprints should differ for different failures")
+	}
+}
+
+// TestGetFailedAction will test getFailedAction()
+func TestGetFailedAction(t *testing.T) {
+	t.Parallel()
+
+	mockPipeline := &mockCodePipelineClient{}
+
+	action, err := getFailedAction(context.Background(), "status-fail", "12345", mockPipeline)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if action == nil {
+		t.Fatal("action was nil, expected a pointer")
+	} else if aws.StringValue(action.StageName) != "Build" {
+		t.Fatal("stage name was not as expected", aws.StringValue(action.StageName))
+	}
+
+	if action, err = getFailedAction(context.Background(), "status-succeed", "12345", mockPipeline); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if action != nil {
+		t.Fatal("action should have been nil")
+	}
+}
+
+// TestFlakyFailureDescription will test flakyFailureDescription()
+func TestFlakyFailureDescription(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	h := newTestHandler(Config{FlakyFailureTable: "failures", FlakyFailureThreshold: 2})
+	h.deps.DynamoDB = mockDynamo
+
+	// First failure is not flaky yet
+	description, record, err := h.flakyFailureDescription(context.Background(), "status-fail", "12345")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(description) > 0 {
+		t.Fatal("description should be empty", description)
+	}
+
+	// Failures are only counted once recorded (IE: their status was posted)
+	if description, _, err = h.flakyFailureDescription(context.Background(), "status-fail", "12345"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(description) > 0 {
+		t.Fatal("description should be empty, the failure was not recorded", description)
+	}
+	record()
+
+	// Second failure reaches the threshold
+	if description, _, err = h.flakyFailureDescription(context.Background(), "status-fail", "67890"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if description != "known flaky failure (seen 2x this week)" {
+		t.Fatal("description was not as expected", description)
+	}
+
+	// Missing table
+	if _, err = recordFailure(context.Background(), mockDynamo, "", "pipeline", "Build", "abc", time.Now()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventFlakyRecords will test ProcessEvent() counting the failure, the usage and the timeline
+// only once the status is posted
+func TestHandlerProcessEventFlakyRecords(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	h := newTestHandler(Config{
+		FlakyFailureTable:     "failures",
+		FlakyFailureThreshold: 1,
+		GithubAccessToken:     "1234567",
+		GithubMaxConcurrency:  1,
+		Stage:                 stageTesting,
+		TimelineTable:         "timeline",
+		UsageTable:            "usage",
+	})
+	h.deps.DynamoDB = mockDynamo
+
+	var received payload
+	status := http.StatusInternalServerError
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	})
+
+	// Failed posts are not counted
+	ev := Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}
+	if err := h.ProcessEvent(ev); err == nil {
+		t.Fatal("error should have occurred")
+	} else if len(mockDynamo.counts) > 0 || mockDynamo.item != nil {
+		t.Fatal("nothing should have been recorded", mockDynamo.counts, mockDynamo.item)
+	}
+
+	// The retry is
+	status = http.StatusCreated
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !strings.Contains(received.Description, "known flaky failure (seen 1x this week)") {
+		t.Fatal("description was not as expected", received.Description)
+	} else if len(mockDynamo.counts) != 2 || mockDynamo.item == nil {
+		t.Fatal("failure, usage and timeline should have been recorded", mockDynamo.counts, mockDynamo.item)
+	}
+}
@@ -0,0 +1,517 @@
+package pipelinestatus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Formats of the freeze windows (FREEZE_WINDOWS) and of the iCal dates (FREEZE_CALENDAR_URL)
+const (
+	freezeCalendarTTL   = 5 * time.Minute
+	freezeWindowDivider = "/"
+	icalDateFormat      = "20060102"
+	icalDateTimeFormat  = "20060102T150405"
+)
+
+// Frequencies of the recurring iCal events (RRULE) and the most periods read from one rule
+const (
+	icalFrequencyDaily   = "DAILY"
+	icalFrequencyMonthly = "MONTHLY"
+	icalFrequencyWeekly  = "WEEKLY"
+	icalFrequencyYearly  = "YEARLY"
+	icalMaxPeriods       = 100000
+)
+
+// freezeUnknownDescription is the description of the statuses held when the freeze calendar cannot be checked
+const freezeUnknownDescription = "deploy freeze unknown (unable to check the freeze calendar)"
+
+// icalWeekdays are the days of the BYDAY and WKST parts of a recurrence rule
+var icalWeekdays = map[string]time.Weekday{
+	"FR": time.Friday,
+	"MO": time.Monday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+	"TH": time.Thursday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+}
+
+// freezeWindow is a period where the approvals of the production stages are held (its first occurrence
+// if it recurs, without the excluded occurrences by their start)
+type freezeWindow struct {
+	end        time.Time
+	excluded   map[int64]bool
+	recurrence *freezeRecurrence
+	start      time.Time
+}
+
+// calendarEvent is a VEVENT of the freeze calendar (an event with a RECURRENCE-ID replaces that occurrence
+// of the recurring event with the same UID)
+type calendarEvent struct {
+	allDay       bool
+	cancelled    bool
+	err          error
+	recurrenceID time.Time
+	uid          string
+	window       freezeWindow
+}
+
+// freezeRecurrence is the rule of a recurring freeze (RRULE with FREQ, INTERVAL, COUNT, UNTIL, BYDAY and WKST)
+type freezeRecurrence struct {
+	byDay     map[time.Weekday]bool
+	count     int
+	frequency string
+	interval  int
+	until     time.Time
+	weekStart time.Weekday
+}
+
+// cachedCalendar is a freeze calendar fetched from FREEZE_CALENDAR_URL
+type cachedCalendar struct {
+	fetchedAt time.Time
+	windows   []freezeWindow
+}
+
+// parseFreezeWindows will parse the freeze windows of the configuration (IE: 2026-12-20T00:00:00Z/2027-01-04T00:00:00Z)
+func parseFreezeWindows(values []string) (windows []freezeWindow, err error) {
+	for _, value := range values {
+		parts := strings.Split(value, freezeWindowDivider)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid FREEZE_WINDOWS window: %s (IE: 2026-12-20T00:00:00Z/2027-01-04T00:00:00Z)", value)
+		}
+		var window freezeWindow
+		if window.start, err = time.Parse(time.RFC3339, strings.TrimSpace(parts[0])); err != nil {
+			return nil, fmt.Errorf("invalid FREEZE_WINDOWS start: %s", parts[0])
+		} else if window.end, err = time.Parse(time.RFC3339, strings.TrimSpace(parts[1])); err != nil {
+			return nil, fmt.Errorf("invalid FREEZE_WINDOWS end: %s", parts[1])
+		} else if !window.end.After(window.start) {
+			return nil, fmt.Errorf("invalid FREEZE_WINDOWS window: %s (ends before it starts)", value)
+		}
+		windows = append(windows, window)
+	}
+	return
+}
+
+// parseICalDate will parse the value of a DTSTART or DTEND property, with its parameters
+// (IE: DTSTART;VALUE=DATE:20261220 or DTSTART;TZID=Europe/Berlin:20261220T090000)
+func parseICalDate(params, value string) (date time.Time, allDay bool, err error) {
+	location := time.UTC
+	for _, param := range strings.Split(params, ";") {
+		if strings.HasPrefix(param, "TZID=") {
+			if location, err = time.LoadLocation(strings.Trim(strings.TrimPrefix(param, "TZID="), `"`)); err != nil {
+				return
+			}
+		}
+	}
+	switch {
+	case len(value) == len(icalDateFormat):
+		date, err = time.ParseInLocation(icalDateFormat, value, location)
+		allDay = true
+	case strings.HasSuffix(value, "Z"):
+		date, err = time.Parse(icalDateTimeFormat, strings.TrimSuffix(value, "Z"))
+	default:
+		date, err = time.ParseInLocation(icalDateTimeFormat, value, location)
+	}
+	return
+}
+
+// parseRecurrence will parse the RRULE of a recurring event (IE: FREQ=WEEKLY;BYDAY=FR,SA), the rules that
+// cannot be read exactly are errors (BYDAY is only read for the daily and weekly rules)
+func parseRecurrence(rule string) (*freezeRecurrence, error) {
+	recurrence := &freezeRecurrence{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(rule, ";") {
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part: %s", part)
+		}
+		var err error
+		var ok bool
+		switch pair[0] {
+		case "BYDAY":
+			recurrence.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(pair[1], ",") {
+				var weekday time.Weekday
+				if weekday, ok = icalWeekdays[day]; !ok {
+					return nil, fmt.Errorf("unsupported RRULE BYDAY: %s", day)
+				}
+				recurrence.byDay[weekday] = true
+			}
+		case "COUNT":
+			if recurrence.count, err = strconv.Atoi(pair[1]); err != nil || recurrence.count < 1 {
+				return nil, fmt.Errorf("invalid RRULE COUNT: %s", pair[1])
+			}
+		case "FREQ":
+			recurrence.frequency = pair[1]
+		case "INTERVAL":
+			if recurrence.interval, err = strconv.Atoi(pair[1]); err != nil || recurrence.interval < 1 {
+				return nil, fmt.Errorf("invalid RRULE INTERVAL: %s", pair[1])
+			}
+		case "UNTIL":
+			if recurrence.until, _, err = parseICalDate("", pair[1]); err != nil {
+				return nil, fmt.Errorf("invalid RRULE UNTIL: %s", pair[1])
+			}
+		case "WKST":
+			if recurrence.weekStart, ok = icalWeekdays[pair[1]]; !ok {
+				return nil, fmt.Errorf("invalid RRULE WKST: %s", pair[1])
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part: %s", pair[0])
+		}
+	}
+	switch recurrence.frequency {
+	case icalFrequencyDaily, icalFrequencyWeekly:
+	case icalFrequencyMonthly, icalFrequencyYearly:
+		if len(recurrence.byDay) > 0 {
+			return nil, fmt.Errorf("unsupported RRULE BYDAY with FREQ=%s", recurrence.frequency)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ: %s", recurrence.frequency)
+	}
+	return recurrence, nil
+}
+
+// periodStarts will return the starts of the occurrences in a period of the rule (IE: the days of the second
+// week for a weekly rule), the dates missing from a period are skipped (IE: the 31st of a monthly rule)
+func (r *freezeRecurrence) periodStarts(start time.Time, period int) []time.Time {
+	switch r.frequency {
+	case icalFrequencyDaily:
+		day := start.AddDate(0, 0, period*r.interval)
+		if len(r.byDay) > 0 && !r.byDay[day.Weekday()] {
+			return nil
+		}
+		return []time.Time{day}
+	case icalFrequencyWeekly:
+		week := start.AddDate(0, 0, period*7*r.interval)
+		if len(r.byDay) == 0 {
+			return []time.Time{week}
+		}
+		week = week.AddDate(0, 0, -((int(week.Weekday()) - int(r.weekStart) + 7) % 7))
+		var starts []time.Time
+		for day := 0; day < 7; day++ {
+			if date := week.AddDate(0, 0, day); r.byDay[date.Weekday()] {
+				starts = append(starts, date)
+			}
+		}
+		return starts
+	case icalFrequencyMonthly:
+		if month := start.AddDate(0, period*r.interval, 0); month.Day() == start.Day() {
+			return []time.Time{month}
+		}
+	default:
+		if year := start.AddDate(period*r.interval, 0, 0); year.Day() == start.Day() {
+			return []time.Time{year}
+		}
+	}
+	return nil
+}
+
+// activeUntil will return the end of the window (or of its occurrence) active at the time, zero if it is not active
+func (w freezeWindow) activeUntil(now time.Time) (until time.Time) {
+	if w.recurrence == nil {
+		if !now.Before(w.start) && now.Before(w.end) {
+			until = w.end
+		}
+		return
+	}
+
+	// Occurrences can overlap (they end with the last one)
+	duration := w.end.Sub(w.start)
+	var occurrences int
+	for period := 0; period < icalMaxPeriods; period++ {
+		for _, start := range w.recurrence.periodStarts(w.start, period) {
+			if start.Before(w.start) {
+				continue
+			}
+			occurrences++
+			if start.After(now) || (w.recurrence.count > 0 && occurrences > w.recurrence.count) ||
+				(!w.recurrence.until.IsZero() && start.After(w.recurrence.until)) {
+				return
+			}
+			if w.excluded[start.Unix()] {
+				continue
+			}
+			if end := start.Add(duration); now.Before(end) && end.After(until) {
+				until = end
+			}
+		}
+	}
+	return
+}
+
+// parseFreezeCalendar will return the events of an iCal calendar as freeze windows (recurring events keep
+// their RRULE and EXDATE, an event without an end lasts its day or is skipped), the cancelled events and the
+// events with a rule that cannot be read exactly are skipped (and logged)
+func parseFreezeCalendar(ctx context.Context, calendar string) (windows []freezeWindow, err error) {
+
+	// Unfold the long lines (continued on the next line after a space or a tab)
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(calendar))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	var events []*calendarEvent
+	var event *calendarEvent
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			event = &calendarEvent{}
+			continue
+		case "END:VEVENT":
+			if event != nil && event.err != nil {
+				logWarnf(ctx, "skipping the freeze calendar event %s: %s", event.uid, event.err.Error())
+			} else if event != nil {
+				if event.window.end.IsZero() && event.allDay {
+					event.window.end = event.window.start.AddDate(0, 0, 1)
+				}
+				events = append(events, event)
+			}
+			event = nil
+			continue
+		}
+		separator := strings.Index(line, ":")
+		if event == nil || separator < 0 {
+			continue
+		}
+		name, value := line[:separator], line[separator+1:]
+		var params string
+		if index := strings.Index(name, ";"); index >= 0 {
+			name, params = name[:index], name[index+1:]
+		}
+		switch name {
+		case "DTEND":
+			if event.window.end, _, err = parseICalDate(params, value); err != nil {
+				return nil, fmt.Errorf("invalid DTEND: %s", value)
+			}
+		case "DTSTART":
+			if event.window.start, event.allDay, err = parseICalDate(params, value); err != nil {
+				return nil, fmt.Errorf("invalid DTSTART: %s", value)
+			}
+		case "EXDATE":
+			if event.window.excluded == nil {
+				event.window.excluded = make(map[int64]bool)
+			}
+			for _, date := range strings.Split(value, ",") {
+				var excluded time.Time
+				if excluded, _, err = parseICalDate(params, date); err != nil {
+					return nil, fmt.Errorf("invalid EXDATE: %s", date)
+				}
+				event.window.excluded[excluded.Unix()] = true
+			}
+		case "RECURRENCE-ID":
+			if event.recurrenceID, _, err = parseICalDate(params, value); err != nil {
+				return nil, fmt.Errorf("invalid RECURRENCE-ID: %s", value)
+			}
+		case "RRULE":
+			event.window.recurrence, event.err = parseRecurrence(value)
+		case "STATUS":
+			event.cancelled = value == "CANCELLED"
+		case "UID":
+			event.uid = value
+		}
+	}
+
+	// The occurrences replaced (or cancelled) by an event of their own are excluded from the recurring event
+	for _, override := range events {
+		if override.recurrenceID.IsZero() {
+			continue
+		}
+		for _, recurring := range events {
+			if recurring.uid == override.uid && recurring.recurrenceID.IsZero() && recurring.window.recurrence != nil {
+				if recurring.window.excluded == nil {
+					recurring.window.excluded = make(map[int64]bool)
+				}
+				recurring.window.excluded[override.recurrenceID.Unix()] = true
+			}
+		}
+	}
+	for _, event = range events {
+		if !event.cancelled && event.window.end.After(event.window.start) {
+			windows = append(windows, event.window)
+		}
+	}
+	return
+}
+
+// freezeCalendar will return the freeze windows of FREEZE_CALENDAR_URL (cached per container and refreshed after
+// freezeCalendarTTL, the calendar is fetched outside the lock so a slow calendar does not hold the events of the
+// other urls)
+func (h *Handler) freezeCalendar(ctx context.Context, now time.Time) ([]freezeWindow, error) {
+	h.deps.cache.calendarsMu.Lock()
+	cached, ok := h.deps.cache.calendars[h.cfg.FreezeCalendarURL]
+	h.deps.cache.calendarsMu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < freezeCalendarTTL {
+		return cached.windows, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.cfg.FreezeCalendarURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var response *http.Response
+	if response, err = h.deps.Calendar.Do(req); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	var body []byte
+	if body, err = ioutil.ReadAll(response.Body); err != nil {
+		return nil, err
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from the freeze calendar, code: %d body: %s", response.StatusCode,
+			strings.TrimSpace(string(body)))
+	}
+
+	var windows []freezeWindow
+	if windows, err = parseFreezeCalendar(ctx, string(body)); err != nil {
+		return nil, fmt.Errorf("unable to parse the freeze calendar: %s", err.Error())
+	}
+	h.deps.cache.calendarsMu.Lock()
+	h.deps.cache.calendars[h.cfg.FreezeCalendarURL] = cachedCalendar{fetchedAt: now, windows: windows}
+	h.deps.cache.calendarsMu.Unlock()
+	return windows, nil
+}
+
+// activeFreeze will return the end of the freeze windows active at the time (zero if there is no freeze),
+// overlapping windows end with the last one
+func (h *Handler) activeFreeze(ctx context.Context, now time.Time) (until time.Time, err error) {
+	windows, _ := parseFreezeWindows(h.cfg.FreezeWindows)
+	if len(h.cfg.FreezeCalendarURL) > 0 {
+		var calendar []freezeWindow
+		if calendar, err = h.freezeCalendar(ctx, now); err != nil {
+			return
+		}
+		windows = append(windows, calendar...)
+	}
+	for _, window := range windows {
+		if end := window.activeUntil(now); end.After(until) {
+			until = end
+		}
+	}
+	return
+}
+
+// isFreezeStage will return true if the approvals of the stage are held during a freeze (FREEZE_STAGE_PATTERN)
+func (h *Handler) isFreezeStage(stage string) bool {
+	if len(h.cfg.FreezeWindows) == 0 && len(h.cfg.FreezeCalendarURL) == 0 {
+		return false
+	}
+	matched, err := path.Match(h.cfg.FreezeStagePattern, stage)
+	return err == nil && matched
+}
+
+// freezeDescription is the description of the statuses held by a freeze
+func freezeDescription(until time.Time) string {
+	return "deploy freeze active until " + until.UTC().Format(time.RFC1123)
+}
+
+// stageApprovals will return the manual approval actions of the stage in the execution
+func (h *Handler) stageApprovals(ctx context.Context, pipelineName, executionID, stage string) (approvals map[string]bool, err error) {
+	var actions []*types.ActionExecutionDetail
+	if actions, err = getActionExecutions(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil {
+		return
+	}
+	approvals = make(map[string]bool)
+	for _, action := range actions {
+		if aws.StringValue(action.StageName) == stage && action.Input != nil && action.Input.ActionTypeId != nil &&
+			action.Input.ActionTypeId.Category == types.ActionCategoryApproval {
+			approvals[aws.StringValue(action.ActionName)] = true
+		}
+	}
+	return
+}
+
+// rejectFrozenApprovals will reject the approvals of the stage that are waiting for a reviewer
+// (FREEZE_REJECT_APPROVALS), the pipeline can be retried after the freeze
+func (h *Handler) rejectFrozenApprovals(ctx context.Context, pipelineName, stage string, approvals map[string]bool,
+	until time.Time) error {
+	output, err := h.deps.CodePipeline.GetPipelineState(ctx, &codepipeline.GetPipelineStateInput{Name: aws.String(pipelineName)})
+	if err != nil {
+		return err
+	} else if output == nil {
+		return fmt.Errorf("missing state of pipeline: %s", pipelineName)
+	}
+	for _, stageState := range output.StageStates {
+		if aws.StringValue(stageState.StageName) != stage {
+			continue
+		}
+		for _, actionState := range stageState.ActionStates {
+			if !approvals[aws.StringValue(actionState.ActionName)] || actionState.LatestExecution == nil ||
+				actionState.LatestExecution.Status != types.ActionExecutionStatusInProgress ||
+				len(aws.StringValue(actionState.LatestExecution.Token)) == 0 {
+				continue
+			}
+			if _, err = h.deps.CodePipeline.PutApprovalResult(ctx, &codepipeline.PutApprovalResultInput{
+				ActionName:   actionState.ActionName,
+				PipelineName: aws.String(pipelineName),
+				Result: &types.ApprovalResult{
+					Status:  types.ApprovalStatusRejected,
+					Summary: aws.String(freezeDescription(until)),
+				},
+				StageName: aws.String(stage),
+				Token:     actionState.LatestExecution.Token,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// holdForFreeze will return the description of the status if the stage holds manual approvals that must not
+// report a success (empty otherwise), the waiting approvals are rejected with FREEZE_REJECT_APPROVALS
+//
+// The statuses are held when the freeze or the approvals cannot be checked, unless FREEZE_FAIL_OPEN is set
+func (h *Handler) holdForFreeze(ctx context.Context, ev Event, state string) string {
+	if (state != githubStateSuccess && state != githubStatePending) || !h.isFreezeStage(ev.Detail.Stage) {
+		return ""
+	}
+	until, freezeErr := h.activeFreeze(ctx, time.Now())
+	if freezeErr != nil {
+		logErrorf(ctx, "unable to check the freeze calendar: %s", freezeErr.Error())
+		if h.cfg.FreezeFailOpen {
+			return ""
+		}
+	} else if until.IsZero() {
+		return ""
+	}
+
+	approvals, err := h.stageApprovals(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, ev.Detail.Stage)
+	if err != nil {
+		logErrorf(ctx, "unable to check the approvals of the stage: %s", err.Error())
+		if h.cfg.FreezeFailOpen {
+			return ""
+		}
+	} else if len(approvals) == 0 {
+		return ""
+	}
+	if freezeErr != nil {
+		return freezeUnknownDescription
+	}
+	if h.cfg.FreezeRejectApprovals && state == githubStatePending && len(approvals) > 0 {
+		if err = h.rejectFrozenApprovals(ctx, ev.Detail.Pipeline, ev.Detail.Stage, approvals, until); err != nil {
+			logWarnf(ctx, "unable to reject the approvals of the stage: %s", err.Error())
+		}
+	}
+	return freezeDescription(until)
+}
@@ -0,0 +1,358 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockFreezeCodePipelineClient has a manual approval waiting in the Production stage and keeps the rejected approvals
+type mockFreezeCodePipelineClient struct {
+	mockCodePipelineClient
+	rejected []*codepipeline.PutApprovalResultInput
+}
+
+// ListActionExecutions is a mock request for codepipeline (an approval then a deploy in Production)
+func (m *mockFreezeCodePipelineClient) ListActionExecutions(_ context.Context, input *codepipeline.ListActionExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+	return &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: []types.ActionExecutionDetail{{
+		ActionName: aws.String("Approve"),
+		Input: &types.ActionExecutionInput{
+			ActionTypeId: &types.ActionTypeId{Category: types.ActionCategoryApproval},
+		},
+		StageName: aws.String("Production"),
+		Status:    types.ActionExecutionStatusInProgress,
+	}, {
+		ActionName: aws.String("Build"),
+		Input: &types.ActionExecutionInput{
+			ActionTypeId: &types.ActionTypeId{Category: types.ActionCategoryBuild},
+		},
+		StageName: aws.String("Build"),
+		Status:    types.ActionExecutionStatusSucceeded,
+	}}}, nil
+}
+
+// GetPipelineState is a mock request for codepipeline (the approval is waiting for a reviewer)
+func (m *mockFreezeCodePipelineClient) GetPipelineState(_ context.Context, input *codepipeline.GetPipelineStateInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineStateOutput, error) {
+	return &codepipeline.GetPipelineStateOutput{PipelineName: input.Name, StageStates: []types.StageState{{
+		ActionStates: []types.ActionState{{
+			ActionName: aws.String("Approve"),
+			LatestExecution: &types.ActionExecution{
+				Status: types.ActionExecutionStatusInProgress,
+				Token:  aws.String("approval-token"),
+			},
+		}},
+		StageName: aws.String("Production"),
+	}}}, nil
+}
+
+// PutApprovalResult is a mock request for codepipeline
+func (m *mockFreezeCodePipelineClient) PutApprovalResult(_ context.Context, input *codepipeline.PutApprovalResultInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.PutApprovalResultOutput, error) {
+	m.rejected = append(m.rejected, input)
+	return &codepipeline.PutApprovalResultOutput{}, nil
+}
+
+// TestParseFreezeWindows will test parseFreezeWindows()
+func TestParseFreezeWindows(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		window        string
+		expectedError bool
+	}{
+		{"2026-12-20T00:00:00Z/2027-01-04T00:00:00Z", false},
+		{"2026-12-20T00:00:00+01:00/2026-12-21T00:00:00+01:00", false},
+		{"2026-12-20T00:00:00Z", true},
+		{"2026-12-20/2027-01-04", true},
+		{"2027-01-04T00:00:00Z/2026-12-20T00:00:00Z", true},
+	}
+
+	for _, test := range tests {
+		if _, err := parseFreezeWindows([]string{test.window}); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.window, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.window)
+		}
+	}
+}
+
+// TestParseFreezeCalendar will test parseFreezeCalendar()
+func TestParseFreezeCalendar(t *testing.T) {
+	t.Parallel()
+
+	windows, err := parseFreezeCalendar(context.Background(), "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"+
+		"BEGIN:VEVENT\r\nSUMMARY:Holiday\r\n  freeze\r\nDTSTART;VALUE=DATE:20261224\r\nDTEND;VALUE=DATE:20261227\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART:20261130T170000Z\r\nDTEND:20261201T090000Z\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART;TZID=America/New_York:20261126T000000\r\nDTEND;TZID=America/New_York:20261127T000000\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261231\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART:20261001T090000Z\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nUID:monthly-release\r\nDTSTART:20261001T090000Z\r\nDTEND:20261001T170000Z\r\nRRULE:FREQ=MONTHLY;BYMONTHDAY=1,15\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nUID:cancelled\r\nSTATUS:CANCELLED\r\nDTSTART:20261001T090000Z\r\nDTEND:20261001T170000Z\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(windows) != 4 {
+		t.Fatal("windows were not as expected", windows)
+	} else if !windows[0].start.Equal(time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)) ||
+		!windows[0].end.Equal(time.Date(2026, 12, 27, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("all-day window was not as expected", windows[0])
+	} else if !windows[1].end.Equal(time.Date(2026, 12, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatal("utc window was not as expected", windows[1])
+	} else if !windows[2].start.Equal(time.Date(2026, 11, 26, 5, 0, 0, 0, time.UTC)) {
+		t.Fatal("zoned window was not as expected", windows[2])
+	} else if !windows[3].end.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("window without an end was not as expected", windows[3])
+	}
+
+	if _, err = parseFreezeCalendar(context.Background(), "BEGIN:VEVENT\r\nDTSTART:tomorrow\r\nEND:VEVENT\r\n"); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = parseFreezeCalendar(context.Background(), "BEGIN:VEVENT\r\nDTSTART:20261001T090000Z\r\nEXDATE:tomorrow\r\nEND:VEVENT\r\n"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestParseFreezeCalendarExceptions will test parseFreezeCalendar() with the excluded, replaced and cancelled
+// occurrences of a recurring event
+func TestParseFreezeCalendarExceptions(t *testing.T) {
+	t.Parallel()
+
+	// Every friday from 18:00 to 20:00 (starting Friday 2026-10-02)
+	weekly := "BEGIN:VEVENT\r\nUID:weekly-freeze\r\nDTSTART:20261002T180000Z\r\nDTEND:20261002T200000Z\r\n" +
+		"RRULE:FREQ=WEEKLY\r\n%sEND:VEVENT\r\n"
+	var tests = []struct {
+		name          string
+		calendar      string
+		now           time.Time
+		expectedUntil time.Time
+	}{
+		{"occurrence", fmt.Sprintf(weekly, ""), time.Date(2026, 10, 9, 19, 0, 0, 0, time.UTC), time.Date(2026, 10, 9, 20, 0, 0, 0, time.UTC)},
+		{"exdate", fmt.Sprintf(weekly, "EXDATE:20261009T180000Z,20261023T180000Z\r\n"), time.Date(2026, 10, 9, 19, 0, 0, 0, time.UTC), time.Time{}},
+		{"exdate other occurrence", fmt.Sprintf(weekly, "EXDATE:20261009T180000Z\r\n"), time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC), time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)},
+		{"recurrence-id moved", fmt.Sprintf(weekly, "") + "BEGIN:VEVENT\r\nUID:weekly-freeze\r\nRECURRENCE-ID:20261009T180000Z\r\n" +
+			"DTSTART:20261010T180000Z\r\nDTEND:20261010T200000Z\r\nEND:VEVENT\r\n", time.Date(2026, 10, 9, 19, 0, 0, 0, time.UTC), time.Time{}},
+		{"recurrence-id new time", fmt.Sprintf(weekly, "") + "BEGIN:VEVENT\r\nUID:weekly-freeze\r\nRECURRENCE-ID:20261009T180000Z\r\n" +
+			"DTSTART:20261010T180000Z\r\nDTEND:20261010T200000Z\r\nEND:VEVENT\r\n", time.Date(2026, 10, 10, 19, 0, 0, 0, time.UTC), time.Date(2026, 10, 10, 20, 0, 0, 0, time.UTC)},
+		{"cancelled occurrence", fmt.Sprintf(weekly, "") + "BEGIN:VEVENT\r\nUID:weekly-freeze\r\nRECURRENCE-ID:20261009T180000Z\r\n" +
+			"STATUS:CANCELLED\r\nDTSTART:20261009T180000Z\r\nDTEND:20261009T200000Z\r\nEND:VEVENT\r\n", time.Date(2026, 10, 9, 19, 0, 0, 0, time.UTC), time.Time{}},
+		{"cancelled event", fmt.Sprintf(weekly, "STATUS:CANCELLED\r\n"), time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, test := range tests {
+		windows, err := parseFreezeCalendar(context.Background(), test.calendar)
+		if err != nil {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.name, err.Error())
+			continue
+		}
+		var until time.Time
+		for _, window := range windows {
+			if windowUntil := window.activeUntil(test.now); windowUntil.After(until) {
+				until = windowUntil
+			}
+		}
+		if !until.Equal(test.expectedUntil) {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.name, test.expectedUntil, until)
+		}
+	}
+}
+
+// TestParseRecurrence will test parseRecurrence()
+func TestParseRecurrence(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		rule          string
+		expectedError bool
+	}{
+		{"FREQ=WEEKLY;BYDAY=FR,SA", false},
+		{"FREQ=DAILY;INTERVAL=2;COUNT=5", false},
+		{"FREQ=MONTHLY;UNTIL=20271231T000000Z", false},
+		{"FREQ=YEARLY;UNTIL=20301231", false},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=SU", false},
+		{"FREQ=HOURLY", true},
+		{"FREQ=MONTHLY;BYDAY=1MO", true},
+		{"FREQ=WEEKLY;BYDAY=1MO", true},
+		{"FREQ=WEEKLY;BYMONTH=12", true},
+		{"FREQ=DAILY;INTERVAL=0", true},
+		{"FREQ=DAILY;COUNT=none", true},
+		{"FREQ=DAILY;UNTIL=tomorrow", true},
+		{"FREQ", true},
+	}
+
+	for _, test := range tests {
+		if _, err := parseRecurrence(test.rule); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.rule, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.rule)
+		}
+	}
+}
+
+// TestFreezeWindowActiveUntil will test freezeWindow.activeUntil() with the recurring windows
+func TestFreezeWindowActiveUntil(t *testing.T) {
+	t.Parallel()
+
+	// Every friday and saturday from 18:00 to 06:00 (starting Friday 2026-10-02)
+	weekend, err := parseFreezeCalendar(context.Background(), "BEGIN:VEVENT\r\nDTSTART:20261002T180000Z\r\nDTEND:20261003T060000Z\r\n"+
+		"RRULE:FREQ=WEEKLY;BYDAY=FR,SA;UNTIL=20261231T000000Z\r\nEND:VEVENT\r\n")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Every month, 3 times (all day on the 31st, the months without a 31st are skipped)
+	var monthly []freezeWindow
+	if monthly, err = parseFreezeCalendar(context.Background(), "BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20260131\r\n"+
+		"RRULE:FREQ=MONTHLY;COUNT=3\r\nEND:VEVENT\r\n"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	var tests = []struct {
+		window        freezeWindow
+		now           time.Time
+		expectedUntil time.Time
+	}{
+		{weekend[0], time.Date(2026, 10, 1, 20, 0, 0, 0, time.UTC), time.Time{}},
+		{weekend[0], time.Date(2026, 10, 2, 20, 0, 0, 0, time.UTC), time.Date(2026, 10, 3, 6, 0, 0, 0, time.UTC)},
+		{weekend[0], time.Date(2026, 10, 17, 5, 0, 0, 0, time.UTC), time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC)},
+		{weekend[0], time.Date(2026, 10, 17, 19, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)},
+		{weekend[0], time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), time.Time{}},
+		{weekend[0], time.Date(2026, 10, 21, 19, 0, 0, 0, time.UTC), time.Time{}},
+		{weekend[0], time.Date(2027, 1, 1, 19, 0, 0, 0, time.UTC), time.Time{}},
+		{monthly[0], time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{monthly[0], time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{monthly[0], time.Date(2026, 5, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{monthly[0], time.Date(2026, 7, 31, 12, 0, 0, 0, time.UTC), time.Time{}},
+		{monthly[0], time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), time.Time{}},
+		{monthly[0], time.Date(2026, 4, 30, 12, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, test := range tests {
+		if until := test.window.activeUntil(test.now); !until.Equal(test.expectedUntil) {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.now, test.expectedUntil, until)
+		}
+	}
+}
+
+// TestActiveFreeze will test Handler.activeFreeze() with the windows of the configuration and the calendar
+func TestActiveFreeze(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20261220T000000Z\r\nDTEND:20270104T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	}))
+	defer server.Close()
+
+	h := newTestHandler(Config{
+		FreezeCalendarURL: server.URL + "/freeze.ics",
+		FreezeWindows:     []string{"2026-12-31T00:00:00Z/2027-01-10T00:00:00Z"},
+	})
+	h.deps.Calendar = server.Client()
+
+	var tests = []struct {
+		now           time.Time
+		expectedUntil time.Time
+	}{
+		{time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2027, 1, 10, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, test := range tests {
+		if until, err := h.activeFreeze(context.Background(), test.now); err != nil {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.now, err.Error())
+		} else if !until.Equal(test.expectedUntil) {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.now, test.expectedUntil, until)
+		}
+	}
+
+	// The calendar is cached per container (within the ttl)
+	requests = 0
+	if _, err := h.activeFreeze(context.Background(), time.Date(2027, 1, 10, 0, 1, 0, 0, time.UTC)); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if requests != 0 {
+		t.Fatal("calendar should have been cached", requests)
+	}
+}
+
+// TestHandlerProcessEventFreeze will test ProcessEvent() holding the approval of a production stage during a freeze
+func TestHandlerProcessEventFreeze(t *testing.T) {
+	now := time.Now().UTC()
+	h := newTestHandler(Config{
+		FreezeRejectApprovals: true,
+		FreezeStagePattern:    "Prod*",
+		FreezeWindows:         []string{now.Add(-time.Hour).Format(time.RFC3339) + "/" + now.Add(time.Hour).Format(time.RFC3339)},
+		GithubAccessToken:     "1234567",
+		GithubMaxConcurrency:  1,
+		Stage:                 stageTesting,
+	})
+	pipeline := &mockFreezeCodePipelineClient{}
+	h.deps.CodePipeline = pipeline
+
+	var received payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Production",
+		State:       "STARTED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStatePending || received.Description != freezeDescription(now.Add(time.Hour).Truncate(time.Second)) {
+		t.Fatal("status was not as expected", received)
+	} else if len(pipeline.rejected) != 1 || aws.StringValue(pipeline.rejected[0].Token) != "approval-token" ||
+		pipeline.rejected[0].Result.Status != types.ApprovalStatusRejected {
+		t.Fatal("approval should have been rejected", pipeline.rejected)
+	}
+
+	// Stages without an approval are not held
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Production-Canary",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStateSuccess {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// The approvals are held when the calendar cannot be checked
+	calendar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer calendar.Close()
+	h.cfg.FreezeCalendarURL, h.cfg.FreezeWindows = calendar.URL+"/broken.ics", nil
+	h.deps.Calendar = calendar.Client()
+	approved := Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Production",
+		State:       "SUCCEEDED",
+	}}
+	if err := h.ProcessEvent(approved); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStatePending || received.Description != freezeUnknownDescription {
+		t.Fatal("status was not as expected", received)
+	} else if len(pipeline.rejected) != 1 {
+		t.Fatal("approval should not have been rejected without a known freeze", pipeline.rejected)
+	}
+
+	// Unless FREEZE_FAIL_OPEN is set
+	h.cfg.FreezeFailOpen = true
+	if err := h.ProcessEvent(approved); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStateSuccess {
+		t.Fatal("status was not as expected", received)
+	}
+}
@@ -0,0 +1,63 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// giteaAPIPath is the API of a Gitea (or Forgejo) instance
+const giteaAPIPath = "/api/v1"
+
+// giteaReporter posts the commit statuses with the Gitea API (the states are the same as on GitHub)
+type giteaReporter struct {
+	apiURL string
+	client HTTPClient
+	token  string
+}
+
+// Name will return the name of the forge
+func (r *giteaReporter) Name() string {
+	return forgeGitea
+}
+
+// PostStatus will create the commit status
+func (r *giteaReporter) PostStatus(ctx context.Context, status StatusUpdate) error {
+
+	// Encode the status
+	body, err := json.Marshal(&payload{
+		Context:     status.Context,
+		Description: status.Description,
+		State:       status.State,
+		TargetURL:   status.TargetURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/repos/%s/%s/statuses/%s", r.apiURL, status.Owner, status.Repo, status.Commit), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+r.token)
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = r.client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusCreated {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Gitea, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
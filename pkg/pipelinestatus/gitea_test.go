@@ -0,0 +1,95 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestGiteaReporter will test giteaReporter.PostStatus()
+func TestGiteaReporter(t *testing.T) {
+	var received payload
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		if received.Context == "ci/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}}
+	r := &giteaReporter{apiURL: "https://git.example.com/api/v1", client: client, token: "1234567"}
+
+	status := StatusUpdate{Commit: "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", Context: "ci/pipeline", Owner: "mrz1836",
+		Repo: "codepipeline-to-github", State: githubStateSuccess}
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req := client.requests[0]; req.URL.String() !=
+		"https://git.example.com/api/v1/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("url was not as expected", req.URL.String())
+	} else if req.Header.Get("Authorization") != "token 1234567" {
+		t.Fatal("token was not sent", req.Header)
+	} else if received.State != githubStateSuccess || received.Context != "ci/pipeline" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	status.Context = "ci/forbidden"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestStatusReporter will test Handler.statusReporter() picking the forge by host or pipeline
+func TestStatusReporter(t *testing.T) {
+	h := newTestHandler(Config{GiteaPipelines: []string{"mirrored"}, GiteaURL: "https://git.example.com/"})
+
+	var tests = []struct {
+		pipeline    string
+		revisionURL string
+		expected    string
+	}{
+		{"web", "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e", forgeGithub},
+		{"web", "https://gitlab.com/mrz1836/codepipeline-to-github/-/commit/25c0c3e", forgeGitlab},
+		{"web", "https://git.example.com/mrz1836/codepipeline-to-github/commit/25c0c3e", forgeGitea},
+		{"mirrored", "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e", forgeGitea},
+	}
+
+	for _, test := range tests {
+		revisionURL, _ := url.Parse(test.revisionURL)
+		if reporter := h.statusReporter(test.pipeline, revisionURL); reporter.Name() != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.pipeline,
+				test.revisionURL, test.expected, reporter.Name())
+		}
+	}
+
+	if reporter := h.statusReporter("mirrored", &url.URL{}).(*giteaReporter); reporter.apiURL != "https://git.example.com/api/v1" {
+		t.Fatal("api url was not as expected", reporter.apiURL)
+	}
+}
+
+// TestHandlerProcessEventGitea will test ProcessEvent() posting the status of a mirrored pipeline to Gitea
+func TestHandlerProcessEventGitea(t *testing.T) {
+	h := newTestHandler(Config{
+		GiteaAccessToken:     "1234567",
+		GiteaPipelines:       []string{"status-succeed"},
+		GiteaURL:             "https://git.example.com",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h.deps.Gitea = client
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("github should not have been called", r.URL.Path)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].URL.Path !=
+		"/api/v1/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("status was not posted to gitea", client.requests)
+	}
+}
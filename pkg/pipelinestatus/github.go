@@ -0,0 +1,143 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultGithubAPIURL is the base url of the GitHub API
+const defaultGithubAPIURL = "https://api.github.com"
+
+// HTTPClient is the interface used to send requests to GitHub (IE: http.DefaultClient)
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// githubError is an unexpected response from the GitHub API
+type githubError struct {
+	Body       string
+	Code       int
+	Repository string // owner/repo of the request (if any)
+}
+
+// Error will return the response code and body
+func (e *githubError) Error() string {
+	return fmt.Sprintf("unexpected response from GitHub, code: %d body: %s", e.Code, e.Body)
+}
+
+// isGithubNotFound will return true if the error is a 404 from the GitHub API
+func isGithubNotFound(err error) bool {
+	ghErr, ok := err.(*githubError)
+	return ok && ghErr.Code == http.StatusNotFound
+}
+
+// newGithubRequest will create an authenticated GitHub API request with an optional JSON body
+func (h *Handler) newGithubRequest(ctx context.Context, method, path string, body interface{}) (req *http.Request, err error) {
+
+	// Encode the body
+	var b bytes.Buffer
+	if body != nil {
+		if err = json.NewEncoder(&b).Encode(body); err != nil {
+			return
+		}
+	}
+
+	// Create the request
+	if req, err = http.NewRequestWithContext(ctx, method, h.githubURL+path, &b); err != nil {
+		return
+	}
+
+	// Set the headers (pinned API version and opted-in previews)
+	accept := []string{"application/json"}
+	for _, preview := range h.cfg.GithubPreviews {
+		accept = append(accept, "application/vnd.github."+preview+"-preview+json")
+	}
+	req.Header.Set("Accept", strings.Join(accept, ", "))
+	req.Header.Set("Authorization", "token "+h.cfg.GithubAccessToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if len(h.cfg.GithubAPIVersion) > 0 {
+		req.Header.Set("X-GitHub-Api-Version", h.cfg.GithubAPIVersion)
+	}
+	return
+}
+
+// doGithubRequest will fire the request, check for the expected response code and decode the response into v (if set)
+func (h *Handler) doGithubRequest(req *http.Request, expectedCode int, v interface{}) error {
+
+	// Staging copies only audit their writes (or send them to the shadow repository)
+	if len(h.cfg.ShadowMode) > 0 && req.Method != http.MethodGet {
+		if handled, err := h.shadowWrite(req); handled {
+			return err
+		}
+	}
+
+	// Requests are paused after a secondary rate limit (more requests would extend the penalty)
+	if until := githubCooldown(time.Now()); !until.IsZero() {
+		return &secondaryRateLimitError{RetryAt: until}
+	}
+
+	// Fire the request (logged and traced with the settings of the handler)
+	req = req.WithContext(h.withSettings(req.Context()))
+	countGithubCall(req.Context())
+	started := time.Now()
+	response, err := h.deps.GitHub.Do(req)
+	if err != nil {
+		logExternalCall(req.Context(), forgeGithub, req.Method+" "+req.URL.Path, 0, time.Since(started), err)
+		traceHTTPCall(req, started, 0, err)
+		return err
+	}
+	logExternalCall(req.Context(), forgeGithub, req.Method+" "+req.URL.Path, response.StatusCode, time.Since(started), nil)
+	traceHTTPCall(req, started, response.StatusCode, nil)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	// Keep track of when the token expires
+	if expiresAt, ok := parseTokenExpiration(response.Header); ok {
+		h.setTokenExpiry(expiresAt)
+	}
+
+	// A rotated token is fetched again by the next handler (or a new installation token is created)
+	if response.StatusCode == http.StatusUnauthorized && len(h.cfg.GithubTokenSecretARN) > 0 {
+		h.deps.cache.forgetSecret(h.cfg.GithubTokenSecretARN)
+	} else if response.StatusCode == http.StatusUnauthorized && len(h.cfg.GithubAppSecretARN) > 0 {
+		h.deps.cache.forgetInstallationToken(h.cfg.GithubAppInstallationID)
+	}
+
+	// Check for success (a secondary rate limit pauses the requests of the container for the Retry-After, the limit
+	// profile of GitHub Enterprise Server also recognizes its own messages and the exhausted primary limit)
+	if response.StatusCode != expectedCode {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		if limits := githubLimits(h.cfg); limits.isLimited(response.StatusCode, response.Header, string(resBody)) {
+			now := time.Now()
+			retryAt := limits.retryAt(response.Header, now)
+			startGithubCooldown(retryAt)
+			logWarnf(req.Context(), "github rate limit (%s) on %s %s, pausing the requests until %s", limits.name,
+				req.Method, req.URL.Path, retryAt.UTC().Format(time.RFC3339))
+			printMetric(metricGithubSecondaryRateLimit, 1, "Count", nil, now)
+			return &secondaryRateLimitError{RetryAt: retryAt}
+		}
+		return &githubError{Body: string(resBody), Code: response.StatusCode, Repository: githubPathRepository(req.URL.Path)}
+	}
+
+	// Decode the response
+	if v != nil {
+		return json.NewDecoder(response.Body).Decode(v)
+	}
+	return nil
+}
+
+// githubGet will get a resource from the GitHub API
+func (h *Handler) githubGet(ctx context.Context, path string, v interface{}) error {
+	req, err := h.newGithubRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusOK, v)
+}
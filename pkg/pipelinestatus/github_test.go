@@ -0,0 +1,71 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newGithubServer will start a fake GitHub API and point the handler at it
+func newGithubServer(t *testing.T, h *Handler, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	h.githubURL = server.URL
+	t.Cleanup(server.Close)
+}
+
+// TestNewGithubRequest will test newGithubRequest()
+func TestNewGithubRequest(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+
+	req, err := h.newGithubRequest(context.Background(), http.MethodPost, "/repos/owner/repo/statuses/abc", &payload{State: githubStatePending})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req.URL.String() != defaultGithubAPIURL+"/repos/owner/repo/statuses/abc" {
+		t.Fatal("url was not as expected", req.URL.String())
+	} else if req.Header.Get("Authorization") != "token 1234567" {
+		t.Fatal("authorization header was not as expected", req.Header.Get("Authorization"))
+	}
+
+	// Pinned API version and previews
+	h.cfg.GithubAPIVersion = "2022-11-28"
+	h.cfg.GithubPreviews = []string{"antiope"}
+	if req, err = h.newGithubRequest(context.Background(), http.MethodGet, "/", nil); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req.Header.Get("X-GitHub-Api-Version") != "2022-11-28" {
+		t.Fatal("api version header was not as expected", req.Header.Get("X-GitHub-Api-Version"))
+	} else if req.Header.Get("Accept") != "application/json, application/vnd.github.antiope-preview+json" {
+		t.Fatal("accept header was not as expected", req.Header.Get("Accept"))
+	}
+
+	// Body that cannot be encoded
+	if _, err = h.newGithubRequest(context.Background(), http.MethodPost, "/", make(chan int)); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestGithubGet will test githubGet()
+func TestGithubGet(t *testing.T) {
+	h := newTestHandler(Config{})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sha":"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"}`))
+	})
+
+	var head branchCommit
+	if err := h.githubGet(context.Background(), "/repos/owner/repo/commits/master", &head); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if head.SHA != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("sha was not as expected", head.SHA)
+	}
+
+	if err := h.githubGet(context.Background(), "/missing", &head); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != `unexpected response from GitHub, code: 404 body: {"message":"Not Found"}` {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
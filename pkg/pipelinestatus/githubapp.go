@@ -0,0 +1,189 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// GitHub App installation tokens (GITHUB_APP_SECRET_ARN), created with the private key of the app instead of
+// storing a personal access token
+const (
+	githubAppJWTLifetime    = 9 * time.Minute // GitHub allows at most 10 minutes
+	githubAppTokenRefresh   = 5 * time.Minute // before the installation token expires (after an hour)
+	githubTokenInstallation = "installation"
+	installationTokenPrefix = "ghs_"
+)
+
+// githubAppSecret is the secret of a GitHub App (stored by the setup-app command)
+type githubAppSecret struct {
+	AppID         int64  `json:"app_id"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	PrivateKey    string `json:"private_key"`
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// installationToken is a token of an installation of the GitHub App with the permissions it was granted
+type installationToken struct {
+	ExpiresAt   time.Time         `json:"expires_at"`
+	Permissions map[string]string `json:"permissions"`
+	Token       string            `json:"token"`
+}
+
+// githubAppClient creates the installation tokens, it is replaced in tests
+var githubAppClient HTTPClient = http.DefaultClient
+
+// parseGithubAppKey will parse the private key of the app (PEM, PKCS#1 as downloaded from GitHub or PKCS#8)
+func parseGithubAppKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("the private key of the app is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key of the app is not an RSA key")
+	}
+	return key, nil
+}
+
+// githubAppJWT will create the JSON Web Token authenticating as the app (RS256, backdated a minute for clock drift)
+func githubAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	encode := func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b), err
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	var claims string
+	if claims, err = encode(map[string]interface{}{
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iat": now.Add(-time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	}); err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(header + "." + claims))
+	var signature []byte
+	if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+		return "", err
+	}
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// getGithubAppSecret will read the secret of the app
+func getGithubAppSecret(ctx context.Context, secrets secretsmanageriface.SecretsManagerAPI, secretARN string) (secret githubAppSecret, err error) {
+	var output *secretsmanager.GetSecretValueOutput
+	if output, err = secrets.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	}); err != nil {
+		return
+	} else if err = json.Unmarshal([]byte(aws.StringValue(output.SecretString)), &secret); err != nil {
+		err = fmt.Errorf("secret %s: %s", secretARN, err.Error())
+	} else if secret.AppID == 0 || len(secret.PrivateKey) == 0 {
+		err = fmt.Errorf("secret %s: missing app_id or private_key", secretARN)
+	}
+	return
+}
+
+// getInstallationToken will return a token of the installation of the app, created with the key in the secret
+// (cached per container until shortly before it expires, or sooner if GitHub rejects the token)
+func (c *containerCache) getInstallationToken(ctx context.Context, secrets secretsmanageriface.SecretsManagerAPI, apiURL, secretARN string,
+	installationID int64, now time.Time) (token installationToken, err error) {
+	c.installationTokensMu.Lock()
+	defer c.installationTokensMu.Unlock()
+	if cached, ok := c.installationTokens[installationID]; ok && now.Add(githubAppTokenRefresh).Before(cached.ExpiresAt) {
+		return cached, nil
+	}
+
+	// Authenticate as the app
+	var secret githubAppSecret
+	if secret, err = getGithubAppSecret(ctx, secrets, secretARN); err != nil {
+		return
+	}
+	var key *rsa.PrivateKey
+	if key, err = parseGithubAppKey(secret.PrivateKey); err != nil {
+		return
+	}
+	var jwt string
+	if jwt, err = githubAppJWT(secret.AppID, key, now); err != nil {
+		return
+	}
+
+	// Create a token of the installation
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, installationID), nil); err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	var response *http.Response
+	if response, err = githubAppClient.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusCreated {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		err = fmt.Errorf("unable to create a token of installation %d, code: %d body: %s", installationID, response.StatusCode,
+			strings.TrimSpace(string(resBody)))
+		return
+	} else if err = json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return
+	}
+	c.installationTokens[installationID] = token
+	return
+}
+
+// forgetInstallationToken will drop the cached token of the installation so the next handler creates a new one
+func (c *containerCache) forgetInstallationToken(installationID int64) {
+	c.installationTokensMu.Lock()
+	defer c.installationTokensMu.Unlock()
+	delete(c.installationTokens, installationID)
+}
+
+// missingInstallationPermissions will return the required permissions the installation was not granted (the
+// permissions are returned with the token, tokens created elsewhere are not checked)
+func (h *Handler) missingInstallationPermissions(required []string) (missing []string) {
+	h.deps.cache.installationTokensMu.Lock()
+	token, ok := h.deps.cache.installationTokens[h.cfg.GithubAppInstallationID]
+	h.deps.cache.installationTokensMu.Unlock()
+	if !ok || token.Token != h.cfg.GithubAccessToken {
+		return
+	}
+	for _, permission := range required {
+		parts := strings.SplitN(permission, ":", 2)
+		if level := token.Permissions[parts[0]]; level != parts[1] && level != "admin" {
+			missing = append(missing, permission)
+		}
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGithubAppKey will create a private key of an app (PKCS#1 PEM, as downloaded from GitHub)
+func newGithubAppKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+// TestGithubAppJWT will test githubAppJWT() and parseGithubAppKey()
+func TestGithubAppJWT(t *testing.T) {
+	t.Parallel()
+
+	key, privateKey := newGithubAppKey(t)
+	parsed, err := parseGithubAppKey(privateKey)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	var jwt string
+	if jwt, err = githubAppJWT(12345, parsed, now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatal("jwt was not as expected", jwt)
+	}
+
+	// Claims (backdated a minute, expires within ten minutes)
+	b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	if err = json.Unmarshal(b, &claims); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if claims["iss"] != "12345" || int64(claims["iat"].(float64)) != now.Add(-time.Minute).Unix() ||
+		int64(claims["exp"].(float64)) != now.Add(githubAppJWTLifetime).Unix() {
+		t.Fatal("claims were not as expected", claims)
+	}
+
+	// Signed with the key of the app
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatal("signature was not valid", err.Error())
+	}
+
+	// Invalid keys
+	if _, err = parseGithubAppKey("not a key"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestGetInstallationToken will test containerCache.getInstallationToken() creating and caching the token of the installation
+func TestGetInstallationToken(t *testing.T) {
+	_, privateKey := newGithubAppKey(t)
+	secret, _ := json.Marshal(githubAppSecret{AppID: 12345, PrivateKey: privateKey})
+	mockSecrets := &mockSecretsManagerClient{secret: string(secret)}
+	now := time.Now()
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/777/access_tokens" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"ghs_first","expires_at":"` + now.Add(time.Hour).Format(time.RFC3339) +
+			`","permissions":{"contents":"read","statuses":"write"}}`))
+	}))
+	defer server.Close()
+	githubAppClient = server.Client()
+	defer func() {
+		githubAppClient = http.DefaultClient
+	}()
+	cache := newContainerCache()
+
+	// Created once, then cached
+	for i := 0; i < 2; i++ {
+		if token, err := cache.getInstallationToken(context.Background(), mockSecrets, server.URL, "arn:aws:secretsmanager:app", 777, now); err != nil {
+			t.Fatal("error occurred", err.Error())
+		} else if token.Token != "ghs_first" || token.Permissions["statuses"] != "write" {
+			t.Fatal("token was not as expected", token)
+		}
+	}
+	if requests != 1 || mockSecrets.calls != 1 {
+		t.Fatal("token should have been created once", requests, mockSecrets.calls)
+	}
+
+	// Created again shortly before it expires
+	if _, err := cache.getInstallationToken(context.Background(), mockSecrets, server.URL, "arn:aws:secretsmanager:app", 777,
+		now.Add(time.Hour-githubAppTokenRefresh)); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if requests != 2 {
+		t.Fatal("token should have been created again", requests)
+	}
+
+	// Rejected by GitHub
+	if _, err := cache.getInstallationToken(context.Background(), mockSecrets, server.URL, "arn:aws:secretsmanager:app", 888, now); err == nil {
+		t.Fatal("error should have occurred")
+	} else if !strings.HasPrefix(err.Error(), "unable to create a token of installation 888, code: 401") {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Secrets without a key
+	if _, err := cache.getInstallationToken(context.Background(), &mockSecretsManagerClient{secret: `{"app_id":12345}`},
+		server.URL, "arn:aws:secretsmanager:app", 999, now); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestMissingInstallationPermissions will test Handler.missingInstallationPermissions()
+func TestMissingInstallationPermissions(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "ghs_granted", GithubAppInstallationID: 555})
+	h.deps.cache.installationTokens[555] = installationToken{Permissions: map[string]string{"checks": "admin", "statuses": "read"}, Token: "ghs_granted"}
+	if missing := h.missingInstallationPermissions([]string{githubPermissionChecks, githubPermissionStatuses}); len(missing) != 1 ||
+		missing[0] != githubPermissionStatuses {
+		t.Fatal("missing permissions were not as expected", missing)
+	}
+
+	// Tokens created elsewhere are not checked
+	h.cfg.GithubAccessToken = "ghs_other"
+	if missing := h.missingInstallationPermissions([]string{githubPermissionStatuses}); len(missing) != 0 {
+		t.Fatal("missing permissions were not as expected", missing)
+	}
+}
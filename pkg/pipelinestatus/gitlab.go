@@ -0,0 +1,97 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLab commit status API
+const (
+	gitlabAPIPath        = "/api/v4"
+	gitlabSameTransition = "Cannot transition status"
+)
+
+// gitlabStates are the GitLab states of the GitHub states (running pipelines are pending on GitHub)
+var gitlabStates = map[string]string{
+	githubStateError:   "failed",
+	githubStateFailure: "failed",
+	githubStatePending: "running",
+	githubStateSuccess: "success",
+}
+
+// gitlabStatus is the payload of a GitLab commit status
+type gitlabStatus struct {
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+// gitlabAPIURL will return the API url of the GitLab instance of the revision url (gitlab.com or self-hosted)
+func gitlabAPIURL(revisionURL *url.URL) string {
+	return revisionURL.Scheme + "://" + revisionURL.Host + gitlabAPIPath
+}
+
+// gitlabReporter posts the commit statuses with the GitLab API (code mirrored from GitLab)
+type gitlabReporter struct {
+	apiURL string
+	client HTTPClient
+	token  string
+}
+
+// Name will return the name of the forge
+func (r *gitlabReporter) Name() string {
+	return forgeGitlab
+}
+
+// PostStatus will create the commit status of the project (posting the current state again is not an error)
+func (r *gitlabReporter) PostStatus(ctx context.Context, status StatusUpdate) error {
+	state, ok := gitlabStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported GitLab state: %s", status.State)
+	}
+
+	// Encode the status
+	body, err := json.Marshal(&gitlabStatus{
+		Description: status.Description,
+		Name:        status.Context,
+		State:       state,
+		TargetURL:   status.TargetURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create the request (the project is its url encoded path)
+	project := url.PathEscape(status.Owner + "/" + status.Repo)
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/projects/%s/statuses/%s", r.apiURL, project, status.Commit), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", r.token)
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = r.client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode == http.StatusCreated {
+		return nil
+	}
+	resBody, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode == http.StatusBadRequest && strings.Contains(string(resBody), gitlabSameTransition) {
+		return nil
+	}
+	return fmt.Errorf("unexpected response from GitLab, code: %d body: %s", response.StatusCode, string(resBody))
+}
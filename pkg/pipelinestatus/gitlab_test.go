@@ -0,0 +1,110 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockForgeClient answers the requests of a forge (GitLab, Gitea) with a handler (the requests keep their url)
+type mockForgeClient struct {
+	handler  http.HandlerFunc
+	requests []*http.Request
+}
+
+// Do is a mock request for the forge
+func (m *mockForgeClient) Do(req *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, req)
+	recorder := httptest.NewRecorder()
+	m.handler(recorder, req)
+	return recorder.Result(), nil
+}
+
+// TestGitlabReporter will test gitlabReporter.PostStatus()
+func TestGitlabReporter(t *testing.T) {
+	var received gitlabStatus
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		switch received.Name {
+		case "ci/same":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Cannot transition status via :run from :running"}`))
+		case "ci/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"403 Forbidden"}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}}
+	r := &gitlabReporter{apiURL: "https://gitlab.example.com/api/v4", client: client, token: "glpat-1234567"}
+
+	status := StatusUpdate{Commit: "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", Context: "ci/pipeline", Description: "Deploy",
+		Owner: "group/sub", Repo: "project", State: githubStatePending, TargetURL: "https://console.aws.amazon.com"}
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if req := client.requests[0]; req.URL.String() !=
+		"https://gitlab.example.com/api/v4/projects/group%2Fsub%2Fproject/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("url was not as expected", req.URL.String())
+	} else if req.Header.Get("PRIVATE-TOKEN") != "glpat-1234567" {
+		t.Fatal("token was not sent", req.Header)
+	} else if received.State != "running" || received.Name != "ci/pipeline" || received.TargetURL != "https://console.aws.amazon.com" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Same state again
+	status.Context = "ci/same"
+	if err := r.PostStatus(context.Background(), status); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// Rejected
+	status.Context = "ci/forbidden"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	} else if !strings.HasPrefix(err.Error(), "unexpected response from GitLab, code: 403") {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Unknown state
+	status.State = "neutral"
+	if err := r.PostStatus(context.Background(), status); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventGitlab will test ProcessEvent() posting the status of a revision hosted on GitLab
+func TestHandlerProcessEventGitlab(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		GitlabAccessToken:    "glpat-1234567",
+		RollupComment:        true,
+		Stage:                stageTesting,
+		UseChecksAPI:         true,
+	})
+	var body []byte
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h.deps.GitLab = client
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("github should not have been called", r.URL.Path)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "gitlab-mirror", State: "STARTED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].URL.Host != "gitlab.example.com" {
+		t.Fatal("status was not posted to gitlab", client.requests)
+	}
+	var received gitlabStatus
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.Name != defaultStatusContext || received.State != "running" {
+		t.Fatal("status was not as expected", received)
+	}
+}
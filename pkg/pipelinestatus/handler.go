@@ -0,0 +1,998 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/codebuild/codebuildiface"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// Dependencies are the external services used by the handler, replace them with mocks
+// to test without network access
+type Dependencies struct {
+	AssumeRole     func(roleARN string) Dependencies
+	AzureDevOps    HTTPClient
+	Bitbucket      HTTPClient
+	Calendar       HTTPClient
+	CloudTrail     cloudtrailiface.CloudTrailAPI
+	CodeBuild      codebuildiface.CodeBuildAPI
+	CodeDeploy     codedeployiface.CodeDeployAPI
+	CodePipeline   CodePipelineAPI
+	DynamoDB       dynamodbiface.DynamoDBAPI
+	EventBridge    eventbridgeiface.EventBridgeAPI
+	ForRegion      func(region, roleARN string) Dependencies
+	GitHub         HTTPClient
+	GitLab         HTTPClient
+	Gitea          HTTPClient
+	Honeycomb      HTTPClient
+	KMS            KMSAPI
+	Opsgenie       HTTPClient
+	Prometheus     HTTPClient
+	Resolver       Resolver
+	S3             s3iface.S3API
+	SNS            snsiface.SNSAPI
+	SecretsManager secretsmanageriface.SecretsManagerAPI
+	Slack          HTTPClient
+	SSM            ssmiface.SSMAPI
+	Teams          HTTPClient
+
+	// cache is the state shared by the handlers of the container (the member account and regional services
+	// share the cache of the dependencies they were created from)
+	cache *containerCache
+}
+
+// Handler processes CodePipeline events using its own configuration and dependencies
+type Handler struct {
+	accountContextPrefix string
+	cfg                  Config
+	deps                 Dependencies
+	githubURL            string
+	githubVersion        *string
+	logSettings          *logSettings
+	pipelines            pipelineConfigs
+	tokenExpiresAt       time.Time
+}
+
+// NewDependencies will create the AWS services from a session (and CodePipeline and KMS from the configuration of
+// aws-sdk-go-v2) and use the default HTTP client for the forges and Slack
+func NewDependencies(awsSession *session.Session, awsConfig awsv2.Config) Dependencies {
+	cache := newContainerCache()
+	return Dependencies{
+		AssumeRole: func(roleARN string) Dependencies {
+			return cache.assumeRole(awsSession, awsConfig, roleARN)
+		},
+		AzureDevOps:  http.DefaultClient,
+		Bitbucket:    http.DefaultClient,
+		Calendar:     http.DefaultClient,
+		CloudTrail:   cloudtrail.New(awsSession),
+		CodeBuild:    codebuild.New(awsSession),
+		CodeDeploy:   codedeploy.New(awsSession),
+		CodePipeline: codepipeline.NewFromConfig(awsConfig),
+		DynamoDB:     dynamodb.New(awsSession),
+		EventBridge:  eventbridge.New(awsSession),
+		ForRegion: func(region, roleARN string) Dependencies {
+			return cache.regionalDependencies(awsSession, awsConfig, region, roleARN)
+		},
+		GitHub:         http.DefaultClient,
+		GitLab:         http.DefaultClient,
+		Gitea:          http.DefaultClient,
+		Honeycomb:      http.DefaultClient,
+		KMS:            kms.NewFromConfig(awsConfig),
+		Opsgenie:       http.DefaultClient,
+		Prometheus:     http.DefaultClient,
+		S3:             s3.New(awsSession),
+		SNS:            sns.New(awsSession),
+		SecretsManager: secretsmanager.New(awsSession),
+		Slack:          http.DefaultClient,
+		SSM:            ssm.New(awsSession),
+		Teams:          http.DefaultClient,
+		cache:          cache,
+	}
+}
+
+// NewHandler will create a new handler, the GitHub token in the configuration is decrypted
+// using the KMS dependency (unless the stage is testing)
+func NewHandler(cfg Config, deps Dependencies) (*Handler, error) {
+	if deps.cache == nil {
+		deps.cache = newContainerCache()
+	}
+	if cfg.Stage != stageTesting {
+		if deps.KMS == nil {
+			return nil, errors.New("missing dependency: KMS")
+		}
+		var err error
+		if cfg.GithubAccessToken, err = deps.cache.decryptString(context.Background(), deps.KMS, cfg.GithubAccessToken); err != nil {
+			return nil, err
+		} else if cfg.Accounts, err = deps.cache.decryptAccounts(context.Background(), deps.KMS, cfg.Accounts); err != nil {
+			return nil, err
+		}
+	}
+	return newHandler(cfg, deps)
+}
+
+// newHandler will create a new handler with a configuration that is already decrypted
+func newHandler(cfg Config, deps Dependencies) (*Handler, error) {
+	if deps.CodePipeline == nil {
+		return nil, errors.New("missing dependency: CodePipeline")
+	} else if deps.DynamoDB == nil {
+		return nil, errors.New("missing dependency: DynamoDB")
+	} else if deps.CloudTrail == nil {
+		return nil, errors.New("missing dependency: CloudTrail")
+	} else if (cfg.CodeBuildEvents || cfg.LinkBuildLogs) && deps.CodeBuild == nil {
+		return nil, errors.New("missing dependency: CodeBuild")
+	} else if cfg.CodeDeployEvents && deps.CodeDeploy == nil {
+		return nil, errors.New("missing dependency: CodeDeploy")
+	} else if len(cfg.CatalogBucket) > 0 && deps.S3 == nil {
+		return nil, errors.New("missing dependency: S3")
+	} else if len(cfg.CDEventsBus) > 0 && deps.EventBridge == nil {
+		return nil, errors.New("missing dependency: EventBridge")
+	} else if (len(cfg.CDEventsTopicARN) > 0 || len(cfg.StatusTopicARN) > 0) && deps.SNS == nil {
+		return nil, errors.New("missing dependency: SNS")
+	}
+	if deps.cache == nil {
+		deps.cache = newContainerCache()
+	}
+	if deps.GitHub == nil {
+		deps.GitHub = http.DefaultClient
+	}
+	if deps.AzureDevOps == nil {
+		deps.AzureDevOps = http.DefaultClient
+	}
+	if deps.Bitbucket == nil {
+		deps.Bitbucket = http.DefaultClient
+	}
+	if deps.GitLab == nil {
+		deps.GitLab = http.DefaultClient
+	}
+	if deps.Gitea == nil {
+		deps.Gitea = http.DefaultClient
+	}
+	if len(cfg.BitbucketAppPassword) > 0 && len(cfg.BitbucketUsername) == 0 {
+		return nil, errors.New("BITBUCKET_APP_PASSWORD requires BITBUCKET_USERNAME")
+	}
+	for pipelineName, repository := range cfg.AzureDevOpsRepositories {
+		if len(strings.Split(repository, "/")) != 3 {
+			return nil, fmt.Errorf("invalid AZURE_DEVOPS_REPOSITORIES repository of %s: %s (IE: organization/project/repo)",
+				pipelineName, repository)
+		}
+	}
+	for repository, mirror := range cfg.CodeCommitMirrors {
+		if len(strings.Split(mirror, "/")) != 2 {
+			return nil, fmt.Errorf("invalid CODECOMMIT_MIRRORS mirror of %s: %s (IE: owner/repo)", repository, mirror)
+		}
+	}
+	if len(cfg.GiteaPipelines) > 0 && len(cfg.GiteaURL) == 0 {
+		return nil, errors.New("GITEA_PIPELINES requires GITEA_URL")
+	} else if giteaURL, err := url.Parse(cfg.GiteaURL); err != nil || (len(cfg.GiteaURL) > 0 && len(giteaURL.Host) == 0) {
+		return nil, fmt.Errorf("invalid GITEA_URL: %s (IE: https://git.example.com)", cfg.GiteaURL)
+	}
+	if _, err := path.Match(cfg.DeploymentStagePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid DEPLOYMENT_STAGE_PATTERN: %s (IE: Deploy*)", cfg.DeploymentStagePattern)
+	}
+	if _, err := path.Match(cfg.FreezeStagePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid FREEZE_STAGE_PATTERN: %s (IE: Prod*)", cfg.FreezeStagePattern)
+	} else if _, err = parseFreezeWindows(cfg.FreezeWindows); err != nil {
+		return nil, err
+	}
+	if deps.Calendar == nil {
+		deps.Calendar = http.DefaultClient
+	}
+	if deps.Honeycomb == nil {
+		deps.Honeycomb = http.DefaultClient
+	}
+	if deps.Opsgenie == nil {
+		deps.Opsgenie = http.DefaultClient
+	}
+	if deps.Prometheus == nil {
+		deps.Prometheus = http.DefaultClient
+	}
+	if deps.Slack == nil {
+		deps.Slack = http.DefaultClient
+	}
+	if deps.Teams == nil {
+		deps.Teams = http.DefaultClient
+	}
+	if err := validateSeverities(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.LogSampleRate < 0 || cfg.LogSampleRate > 1 {
+		return nil, fmt.Errorf("invalid LOG_SAMPLE_RATE: %v (between 0 and 1)", cfg.LogSampleRate)
+	}
+	if len(cfg.StatusContextTemplate) > 0 {
+		if _, err := template.New("context").Funcs(TemplateFuncs(nil)).Parse(cfg.StatusContextTemplate); err != nil {
+			return nil, fmt.Errorf("invalid STATUS_CONTEXT_TEMPLATE: %s", err.Error())
+		}
+	}
+	switch cfg.OrphanedCommits {
+	case "", orphanedCommitsNeutral, orphanedCommitsSkip:
+	default:
+		return nil, fmt.Errorf("invalid ORPHANED_COMMITS: %s (available: %s, %s)", cfg.OrphanedCommits, orphanedCommitsNeutral, orphanedCommitsSkip)
+	}
+	switch cfg.ClosedExecutions {
+	case "", closedExecutionsAbandon, closedExecutionsStop:
+	default:
+		return nil, fmt.Errorf("invalid CLOSED_EXECUTIONS: %s (available: %s, %s)", cfg.ClosedExecutions, closedExecutionsAbandon, closedExecutionsStop)
+	}
+	for name, state := range map[string]string{"APPROVAL_TIMEOUT_STATE": cfg.ApprovalTimeoutState, "SKIPPED_STAGE_STATE": cfg.SkippedStageState} {
+		switch state {
+		case "", githubStateError, githubStateFailure, githubStatePending, githubStateSuccess:
+		default:
+			return nil, fmt.Errorf("invalid %s: %s (available: %s, %s, %s, %s)", name, state,
+				githubStateError, githubStateFailure, githubStatePending, githubStateSuccess)
+		}
+	}
+	switch cfg.ShadowMode {
+	case "":
+	case shadowModeAudit:
+		if len(cfg.ShadowAuditTable) == 0 {
+			return nil, errors.New("SHADOW_MODE audit requires SHADOW_AUDIT_TABLE")
+		}
+	case shadowModeRepository:
+		if len(strings.Split(cfg.ShadowRepository, "/")) != 2 {
+			return nil, fmt.Errorf("invalid SHADOW_REPOSITORY: %s (IE: owner/repo)", cfg.ShadowRepository)
+		}
+	default:
+		return nil, fmt.Errorf("invalid SHADOW_MODE: %s (available: %s, %s)", cfg.ShadowMode, shadowModeAudit, shadowModeRepository)
+	}
+	if len(cfg.DeadLetterQueueURL) > 0 {
+		if _, err := queueARN(configPartition(cfg), cfg.DeadLetterQueueURL); err != nil {
+			return nil, fmt.Errorf("invalid DEAD_LETTER_QUEUE_URL: %s", err.Error())
+		}
+	}
+	switch cfg.IngestionMode {
+	case "", ingestionModeAction, ingestionModeDLQ, ingestionModeEventBridge, ingestionModeKinesis:
+	case ingestionModeServer:
+		if len(cfg.ServerSecret) == 0 {
+			return nil, errors.New("INGESTION_MODE server requires SERVER_SECRET")
+		}
+	case ingestionModeWebhook:
+		if len(cfg.GithubWebhookSecret) == 0 {
+			return nil, errors.New("INGESTION_MODE webhook requires GITHUB_WEBHOOK_SECRET")
+		}
+	default:
+		return nil, fmt.Errorf("invalid INGESTION_MODE: %s (available: %s, %s, %s, %s, %s, %s)", cfg.IngestionMode,
+			ingestionModeAction, ingestionModeDLQ, ingestionModeEventBridge, ingestionModeKinesis, ingestionModeServer,
+			ingestionModeWebhook)
+	}
+	githubURL, err := githubAPIURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if (len(cfg.GithubCABundle) > 0 || cfg.GithubInsecureSkipVerify) && deps.GitHub == http.DefaultClient {
+		if deps.GitHub, err = cachedGithubClient(cfg); err != nil {
+			return nil, err
+		}
+	}
+	settings, err := newLogSettings(cfg.LogLevel, configSecrets(cfg)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{cfg: cfg, deps: deps, githubURL: githubURL, logSettings: settings}, nil
+}
+
+// withSettings will return a context carrying the log and tracing settings of the handler (LOG_LEVEL, the secrets
+// of its configuration and TRACING_DISABLED) for the log lines and the AWS calls of an event
+func (h *Handler) withSettings(ctx context.Context) context.Context {
+	return withTracing(withLogSettings(ctx, h.logSettings), !h.cfg.TracingDisabled)
+}
+
+// validateEvent will check the event for the required parameters
+func validateEvent(ev Event) error {
+	if ev.Detail == nil {
+		return errors.New("missing param event.detail")
+	}
+	if isTransitionEvent(ev) {
+		return validateTransitionEvent(ev)
+	}
+	if isBuildEvent(ev) {
+		return validateBuildEvent(ev)
+	}
+	if isDeploymentEvent(ev) {
+		return validateDeploymentEvent(ev)
+	}
+	if len(ev.Detail.ExecutionID) == 0 {
+		return errors.New("missing event param execution-id")
+	}
+	if len(ev.Detail.Pipeline) == 0 {
+		return errors.New("missing event param pipeline")
+	}
+	return nil
+}
+
+// ProcessEvent will update the GitHub commit status for the pipeline execution in the event
+// (errors are counted for the info of the deployment)
+func (h *Handler) ProcessEvent(ev Event) error {
+	return h.ProcessEventWithContext(context.Background(), ev)
+}
+
+// ProcessEventWithContext is the same as ProcessEvent, the AWS requests are cancelled when the
+// context is done (IE: the deadline of the Lambda invocation)
+func (h *Handler) ProcessEventWithContext(ctx context.Context, ev Event) error {
+
+	// Every log line of the event shares the fields of the execution (and the event is in the log sample or not)
+	ctx = withLogSample(withLogFields(h.withSettings(ctx), eventLogFields(ctx, ev)), h.cfg.LogSampleRate)
+	err := h.processEvent(ctx, ev)
+	if isGithubArchived(err) {
+		err = h.handleArchivedRepository(ctx, err.(*githubError))
+	}
+	if err != nil {
+		logErrorf(ctx, "unable to process the event: %s", err.Error())
+		countError(err)
+	}
+	return err
+}
+
+// processEvent will update the GitHub commit status for the pipeline execution in the event
+func (h *Handler) processEvent(ctx context.Context, ev Event) error {
+
+	// Pipelines ignored in the pipeline configuration
+	if pipeline := eventLogFields(ctx, ev).Pipeline; len(pipeline) > 0 && h.pipelines[pipeline].Ignore {
+		logf(ctx, "skipping the event of %s, the pipeline is ignored", pipeline)
+		return nil
+	}
+
+	// Stage transitions that are disabled or enabled
+	if isTransitionEvent(ev) {
+		return h.processTransitionEvent(ctx, ev)
+	}
+
+	// Stages of an execution that changed state
+	if isStageEvent(ev) {
+		return h.processStageEvent(ctx, ev)
+	}
+
+	// Builds that changed state (started without a pipeline)
+	if isBuildEvent(ev) {
+		return h.processBuildEvent(ctx, ev)
+	}
+
+	// Deployments that changed state (GitHub deployments of the commit)
+	if isDeploymentEvent(ev) {
+		return h.processDeploymentEvent(ctx, ev)
+	}
+
+	// Check for required parameters
+	if err := validateEvent(ev); err != nil {
+		return err
+	}
+	logf(ctx, "Incoming Event Details: %+v", ev.Detail)
+
+	// Use the configuration of the account that sent the event (and the role of the account and the region
+	// of the pipeline)
+	scoped, err := h.forExecution(ctx, ev.Account, ev.Region, ev.Detail.Pipeline)
+	if err != nil {
+		return err
+	}
+	return scoped.processExecutionEvent(ctx, ev)
+}
+
+// executionReport is the status of an execution, built up by the steps of processExecutionEvent
+type executionReport struct {
+	artifactName    string
+	commit          string
+	deepLink        string
+	description     string
+	descriptions    []string
+	duration        string
+	entity          portalEntity
+	executionOutput *codepipeline.GetPipelineExecutionOutput
+	failedAction    *types.ActionExecutionDetail
+	githubStatus    string
+	onGithub        bool
+	orphaned        bool
+	owner           string
+	pipelineARN     string
+	recordFlaky     func()
+	repo            string
+	reporter        StatusReporter
+	revisionURL     *url.URL
+	rolledBack      string
+	rolledBackURL   *url.URL
+	run             checkRun
+	scheduled       bool
+	statusCtx       string
+	tag             string
+	targetURL       string
+	trends          []stageTrend
+	useChecksAPI    bool
+}
+
+// data will return the data of the templates and the notifiers for the status of the execution
+func (r *executionReport) data(ev Event, region string) templateData {
+	return templateData{
+		Commit:      r.commit,
+		Description: r.description,
+		Duration:    r.duration,
+		Entity:      r.entity,
+		ExecutionID: ev.Detail.ExecutionID,
+		Forge:       r.reporter.Name(),
+		Owner:       r.owner,
+		Pipeline:    ev.Detail.Pipeline,
+		Region:      region,
+		Repo:        r.repo,
+		State:       r.githubStatus,
+		Status:      stateTitle(ev.Detail.State),
+		Tag:         r.tag,
+		TargetURL:   r.targetURL,
+		Variables:   executionVariables(r.executionOutput),
+	}
+}
+
+// processExecutionEvent will post the status of an execution (with the configuration of its account, region and
+// pipeline) and tell the other integrations about it
+func (h *Handler) processExecutionEvent(ctx context.Context, ev Event) error {
+
+	// Muted pipelines are acknowledged without posting a status
+	if h.isMuted(ctx, ev.Detail.Pipeline) {
+		return nil
+	}
+
+	// Count the GitHub calls of the event against the daily budget
+	ctx, githubCalls := withGithubCalls(ctx)
+	defer h.recordBudget(ctx, githubCalls)
+
+	// Record the usage of the pipeline once the status of the event is posted
+	var posted bool
+	if len(h.cfg.UsageTable) > 0 {
+		defer func() {
+			if posted {
+				h.recordEventUsage(ctx, ev, atomic.LoadInt64(githubCalls))
+			}
+		}()
+	}
+
+	// Find the commit of the execution and describe the status
+	r, err := h.executionCommit(ctx, ev)
+	if err != nil {
+		return err
+	}
+	ctx = withLogCommit(ctx, r.owner, r.repo, r.commit)
+	var skip bool
+	if skip, err = h.describeCommit(ctx, ev, r); err != nil || skip {
+		return err
+	}
+	h.describeFailure(ctx, ev, r)
+	h.describeHistory(ctx, ev, r)
+	if err = h.renderStatus(ctx, ev, r); err != nil {
+		return err
+	}
+
+	// Skip the statuses that are late or were already reported
+	report, forget := h.shouldReport(ctx, ev, r)
+	if !report {
+		return nil
+	}
+
+	// Post the status (or the check run) once it is our turn to write to GitHub
+	if err = h.newExecutionCheckRun(ctx, ev, r); err != nil {
+		forget()
+		return err
+	}
+	var release func()
+	if release, err = h.acquireGithubWrite(ctx); err != nil {
+		forget()
+		return err
+	}
+	defer release()
+	if err = h.postExecutionStatus(ctx, r); err != nil {
+		forget()
+		return err
+	}
+
+	// Count the failure, the usage and the transition once the status is posted (not for the duplicate
+	// deliveries or the failed posts), then tell the other integrations
+	posted = true
+	r.recordFlaky()
+	h.recordStatusPosted(ctx, ev, r)
+	h.notifyStatus(ctx, ev, r)
+	h.postRelatedStatuses(ctx, ev, r)
+	h.exportStatus(ctx, ev, r)
+
+	// Check the health of the token
+	h.checkTokenExpiry(time.Now())
+	return nil
+}
+
+// recordEventUsage will record the GitHub calls (and the CodeBuild time of the final states) of the event
+func (h *Handler) recordEventUsage(ctx context.Context, ev Event, githubCalls int64) {
+	var seconds int64
+	var err error
+	if h.cfg.UsageCodeBuildMinutes && finalStates[ev.Detail.State] {
+		if seconds, err = codeBuildSeconds(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, h.deps.CodePipeline); err != nil {
+			logWarnf(ctx, "unable to get codebuild time: %s", err.Error())
+		}
+	}
+	if err = recordUsage(ctx,
+		h.deps.DynamoDB, h.cfg.UsageTable, ev.Detail.Pipeline,
+		githubCalls, seconds, time.Now(),
+	); err != nil {
+		logWarnf(ctx, "unable to record usage: %s", err.Error())
+	}
+}
+
+// executionCommit will find the commit the execution reports on (executions of a tag whose revision is not a
+// commit report on the commit of the tag) and the forge its status is posted to
+func (h *Handler) executionCommit(ctx context.Context, ev Event) (*executionReport, error) {
+
+	// Get the execution details
+	executionOutput, err := getExecutionOutput(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, h.deps.CodePipeline)
+	if err != nil {
+		return nil, err
+	}
+	r := &executionReport{
+		artifactName:    h.primaryArtifact(ev.Detail.Pipeline),
+		executionOutput: executionOutput,
+		recordFlaky:     func() {},
+	}
+
+	// Get the commit info from the pipeline execution
+	r.commit, r.githubStatus, r.revisionURL, err = getCommitFromExecution(
+		ctx, executionOutput, r.artifactName, h.forgeHosts(), h.cfg.CodeCommitMirrors,
+	)
+	r.tag = executionTag(executionOutput, r.artifactName)
+	if len(r.tag) > 0 && (err != nil || r.revisionURL == nil) {
+		if r.commit, r.revisionURL, err = h.getTagCommit(ctx, ev.Detail.Pipeline, r.tag); err != nil {
+			return nil, err
+		}
+		r.githubStatus = getStatus(executionOutput)
+	}
+	if err != nil {
+		reportArtifactError(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, err)
+		return nil, err
+	}
+
+	// Scheduled executions without a source revision (yet) report on the branch head
+	r.scheduled = isScheduled(executionOutput)
+	if r.scheduled && r.revisionURL == nil {
+		if r.commit, r.revisionURL, r.tag, err = h.getBranchHead(ctx, ev.Detail.Pipeline); err != nil {
+			return nil, err
+		}
+		r.githubStatus = getStatus(executionOutput)
+	}
+	if r.revisionURL == nil {
+		err = missingArtifactError(executionOutput, r.artifactName)
+		reportArtifactError(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, err)
+		return nil, err
+	}
+	if len(ev.Detail.githubStatus) > 0 {
+		r.githubStatus = ev.Detail.githubStatus
+	}
+
+	// Break apart the components (and find the forge the statuses are posted to)
+	r.owner, r.repo = h.pipelineRepository(ev.Detail.Pipeline, r.revisionURL)
+	r.reporter = h.statusReporter(ev.Detail.Pipeline, r.revisionURL)
+	r.onGithub = r.reporter.Name() == forgeGithub
+
+	// Setup the links (the events carry the ARN of the pipeline)
+	r.deepLink = consoleURL(h.eventPartition(ev), h.cfg.AWSRegion, fmt.Sprintf(
+		"/codesuite/codepipeline/pipelines/%s/executions/%s", ev.Detail.Pipeline, ev.Detail.ExecutionID))
+	if len(ev.Resources) > 0 {
+		r.pipelineARN = ev.Resources[0]
+	}
+	return r, nil
+}
+
+// describeCommit will describe the commit of the execution: the total duration, orphaned commits (skipped if
+// configured), the tag, the other branches and unverified signatures
+func (h *Handler) describeCommit(ctx context.Context, ev Event, r *executionReport) (skip bool, err error) {
+
+	// Track the start of the execution and report the total duration on the final status
+	if len(h.cfg.ExecutionTable) > 0 && !r.scheduled {
+		var took time.Duration
+		if took, err = h.executionDuration(ctx, ev); err != nil {
+			logWarnf(ctx, "unable to track the execution duration: %s", err.Error())
+		} else if took > 0 {
+			r.duration = formatElapsed(took)
+			r.descriptions = append(r.descriptions, stateTitle(ev.Detail.State)+" in "+r.duration)
+		}
+	}
+
+	// Commits that are no longer on the branch (force-pushed) are skipped or get a neutral status (pending,
+	// commit statuses have no neutral state)
+	if len(h.cfg.OrphanedCommits) > 0 && !r.scheduled && r.onGithub {
+		if r.orphaned, err = h.isOrphaned(ctx, ev.Detail.Pipeline, r.owner, r.repo, r.commit); err != nil {
+			logWarnf(ctx, "unable to check for an orphaned commit: %s", err.Error())
+		} else if r.orphaned && h.cfg.OrphanedCommits == orphanedCommitsSkip {
+			logf(ctx, "skipping orphaned commit: %s/%s@%s", r.owner, r.repo, r.commit)
+			return true, nil
+		} else if r.orphaned {
+			r.githubStatus = githubStatePending
+			r.descriptions = append(r.descriptions, orphanedCommitsDescription)
+		}
+	}
+
+	// Note the tag that was built (release pipelines)
+	if len(r.tag) > 0 {
+		r.descriptions = append(r.descriptions, "tag "+r.tag)
+	}
+
+	// Note the other branches built with the primary revision
+	if h.cfg.AnnotateSecondaryRevisions {
+		if revisions := secondaryRevisions(r.executionOutput, r.artifactName); len(revisions) > 0 {
+			r.descriptions = append(r.descriptions, "with "+strings.Join(revisions, ", "))
+		}
+	}
+
+	// Unsigned (or unverified) commits get an error status if signatures are required
+	if h.cfg.RequireVerifiedCommits && r.onGithub {
+		var verified bool
+		var reason string
+		if verified, reason, err = h.getVerification(ctx, r.owner, r.repo, r.commit); err != nil {
+			return false, err
+		} else if !verified {
+			r.githubStatus = githubStateError
+			r.descriptions = append(r.descriptions, fmt.Sprintf("commit signature is not verified (%s)", reason))
+		}
+	}
+	return false, nil
+}
+
+// describeFailure will describe a failed execution: the failed action (first, the description is truncated, and
+// the log stream of a failed build instead of the execution), flaky stages and who started the execution
+func (h *Handler) describeFailure(ctx context.Context, ev Event, r *executionReport) {
+	if r.githubStatus != githubStateFailure {
+		return
+	}
+	var err error
+	if h.cfg.FailureDetails || h.cfg.LinkBuildLogs || ((h.cfg.FailureComment || h.cfg.FailurePullRequestComment) && r.onGithub) {
+		if r.failedAction, err = getFailedAction(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, h.deps.CodePipeline); err != nil {
+			logWarnf(ctx, "unable to find the failed action: %s", err.Error())
+		} else if r.failedAction != nil && h.cfg.FailureDetails {
+			r.descriptions = append([]string{failureDetail(r.failedAction)}, r.descriptions...)
+		}
+	}
+	if r.failedAction != nil && h.cfg.LinkBuildLogs {
+		var logsURL string
+		if logsURL, err = h.buildLogsURL(ctx, r.failedAction); err != nil {
+			logWarnf(ctx, "unable to find the logs of the failed build: %s", err.Error())
+		} else if len(logsURL) > 0 {
+			r.deepLink = logsURL
+		}
+	}
+	if len(h.cfg.FlakyFailureTable) > 0 {
+		var flaky string
+		if flaky, r.recordFlaky, err = h.flakyFailureDescription(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID); err != nil {
+			logWarnf(ctx, "unable to fingerprint failure: %s", err.Error())
+		}
+		r.descriptions = append(r.descriptions, flaky)
+	}
+	var initiator string
+	if initiator, err = h.getInitiator(ctx, r.pipelineARN, r.executionOutput); err != nil {
+		logWarnf(ctx, "unable to resolve the initiator: %s", err.Error())
+	} else if len(initiator) > 0 {
+		r.descriptions = append(r.descriptions, "started by "+initiator)
+	}
+}
+
+// describeHistory will compare the execution with the previous ones: the definition changes, the stages that
+// were much slower than usual and the commit being rolled back
+func (h *Handler) describeHistory(ctx context.Context, ev Event, r *executionReport) {
+	var err error
+
+	// Note when the pipeline definition changed since the last run
+	if len(h.cfg.DefinitionTable) > 0 && finalStates[ev.Detail.State] {
+		var drifted bool
+		if drifted, err = h.definitionDrifted(ctx, ev.Detail.Pipeline); err != nil {
+			logWarnf(ctx, "unable to check the pipeline definition: %s", err.Error())
+		} else if drifted {
+			r.descriptions = append(r.descriptions, driftDescription)
+		}
+	}
+
+	// Note the stages that were much slower than usual
+	if len(h.cfg.StageDurationTable) > 0 && finalStates[ev.Detail.State] {
+		if r.trends, err = h.stageTrends(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID); err != nil {
+			logWarnf(ctx, "unable to compare the stage durations: %s", err.Error())
+		}
+		for _, trend := range r.trends {
+			r.descriptions = append(r.descriptions, trend.String())
+		}
+	}
+
+	// Rollbacks also report on the commit being rolled back (both descriptions link the other commit)
+	if isRollback(r.executionOutput) && r.onGithub {
+		if r.rolledBack, r.rolledBackURL, err = h.getRolledBackCommit(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID); err != nil {
+			logWarnf(ctx, "unable to find the rolled back commit: %s", err.Error())
+		} else if len(r.rolledBack) > 0 && r.rolledBack != r.commit {
+			r.descriptions = append(r.descriptions, "rollback of "+shortSHA(r.rolledBack))
+		} else {
+			r.rolledBack = ""
+		}
+	}
+}
+
+// renderStatus will render the status context, the description and the target URL of the execution (with the
+// templates and the execution variables), then let the custom resolver override them
+func (h *Handler) renderStatus(ctx context.Context, ev Event, r *executionReport) (err error) {
+
+	// Get the status context for the pipeline
+	if r.scheduled {
+		r.statusCtx = h.cfg.ScheduledContext
+	} else if r.statusCtx, err = h.statusContext(ctx, ev.Detail.Pipeline, r.pipelineARN); err != nil {
+		return
+	}
+
+	// Render the custom description and target URL (with the execution variables)
+	r.description = joinDescription(r.descriptions...)
+	r.targetURL = r.deepLink
+	r.entity = h.portalEntity(ev.Detail.Pipeline, r.owner, r.repo)
+	if len(h.cfg.DescriptionTemplate) > 0 || len(h.cfg.TargetURLTemplate) > 0 {
+		data := r.data(ev, h.cfg.AWSRegion)
+		funcs := TemplateFuncs(h.cfg.TemplateEnvAllowlist)
+		if len(h.cfg.DescriptionTemplate) > 0 {
+			if r.description, err = renderTemplate("description", h.cfg.DescriptionTemplate, data, funcs); err != nil {
+				return
+			}
+			r.description = joinDescription(r.description)
+		}
+		if len(h.cfg.TargetURLTemplate) > 0 {
+			if r.targetURL, err = renderTemplate("target_url", h.cfg.TargetURLTemplate, data, funcs); err != nil {
+				return
+			}
+		}
+	}
+
+	// Let the custom resolver override the status (for conventions the templates can't express)
+	if h.deps.Resolver != nil {
+		var resolved ResolvedStatus
+		if resolved, err = resolveStatus(h.deps.Resolver, ResolverInput{
+			Commit:      r.commit,
+			ExecutionID: ev.Detail.ExecutionID,
+			Owner:       r.owner,
+			Pipeline:    ev.Detail.Pipeline,
+			Region:      h.cfg.AWSRegion,
+			Repo:        r.repo,
+			State:       ev.Detail.State,
+			Status: ResolvedStatus{
+				Context:     r.statusCtx,
+				Description: r.description,
+				State:       r.githubStatus,
+				TargetURL:   r.targetURL,
+			},
+			Variables: executionVariables(r.executionOutput),
+		}); err != nil {
+			return
+		}
+		r.statusCtx, r.description, r.githubStatus, r.targetURL = resolved.Context, resolved.Description, resolved.State, resolved.TargetURL
+	}
+	return
+}
+
+// shouldReport will return false for the statuses skipped near the budget, delivered late or already reported
+// (duplicate deliveries and retries), forget lets a failed post be retried
+func (h *Handler) shouldReport(ctx context.Context, ev Event, r *executionReport) (report bool, forget func()) {
+
+	// Near the daily budget, only the first pending status of an execution is posted
+	if r.githubStatus == githubStatePending && ev.Detail.State != "STARTED" && h.coalescing() {
+		logf(ctx, "skipping %s update of %s near the github budget", ev.Detail.State, ev.Detail.Pipeline)
+		return false, nil
+	}
+
+	// Drop the events delivered after a later event of the execution (IE: a STARTED after the SUCCEEDED)
+	if !h.inOrder(ctx, ev, "", r.statusCtx) {
+		return false, nil
+	}
+	return h.firstReport(ctx, ev, r.statusCtx)
+}
+
+// newExecutionCheckRun will create the check run that replaces the status with the Checks API
+func (h *Handler) newExecutionCheckRun(ctx context.Context, ev Event, r *executionReport) (err error) {
+	r.useChecksAPI = h.cfg.UseChecksAPI && r.onGithub
+	if !r.useChecksAPI {
+		return
+	}
+	if r.run, err = h.newCheckRun(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, r.executionOutput, r.commit, r.statusCtx,
+		r.description, r.githubStatus, r.targetURL); err != nil {
+		return
+	}
+	if r.orphaned && r.githubStatus == githubStatePending {
+		r.run.Status, r.run.Conclusion = checkStatusCompleted, checkConclusionNeutral
+	}
+	r.run.Output.Annotations = append(r.run.Output.Annotations, trendAnnotations(r.trends)...)
+	if len(r.run.Output.Annotations) > checkAnnotationsLimit {
+		r.run.Output.Annotations = r.run.Output.Annotations[:checkAnnotationsLimit]
+	}
+	return
+}
+
+// postExecutionStatus will post the status (or the check run) of the execution
+func (h *Handler) postExecutionStatus(ctx context.Context, r *executionReport) error {
+	if r.useChecksAPI {
+		return h.postCheckRun(ctx, r.owner, r.repo, r.run)
+	}
+	return r.reporter.PostStatus(ctx, StatusUpdate{
+		Commit:      r.commit,
+		Context:     r.statusCtx,
+		Description: r.description,
+		Owner:       r.owner,
+		Repo:        r.repo,
+		State:       r.githubStatus,
+		TargetURL:   r.targetURL,
+	})
+}
+
+// recordStatusPosted will record the transition of a posted status on the timeline and measure how long the
+// status took to reach GitHub (and to be readable, if verified)
+func (h *Handler) recordStatusPosted(ctx context.Context, ev Event, r *executionReport) {
+	if len(h.cfg.TimelineTable) > 0 {
+		transitionTime := ev.Time
+		if transitionTime.IsZero() {
+			transitionTime = time.Now()
+		}
+		if err := recordTransition(ctx, h.deps.DynamoDB, h.cfg.TimelineTable, timelineEntry{
+			Commit:      r.commit,
+			ExecutionID: ev.Detail.ExecutionID,
+			Pipeline:    ev.Detail.Pipeline,
+			State:       ev.Detail.State,
+			Time:        transitionTime,
+		}); err != nil {
+			logWarnf(ctx, "unable to record the timeline: %s", err.Error())
+		}
+	}
+
+	recordLatency(metricStatusWriteLatency, ev.Detail.Pipeline, ev.Time, time.Now())
+	if h.cfg.VerifyStatusVisibility && r.onGithub && !r.useChecksAPI && len(h.cfg.ShadowMode) == 0 {
+		if visibleAt, err := h.statusVisible(ctx, r.owner, r.repo, r.commit, r.statusCtx, r.githubStatus); err != nil {
+			logWarnf(ctx, "unable to verify the status visibility: %s", err.Error())
+		} else {
+			recordLatency(metricStatusVisibleLatency, ev.Detail.Pipeline, ev.Time, visibleAt)
+		}
+	}
+}
+
+// notifyStatus will tell about a posted status: the failure comments, the notifiers (IE: Slack), the OpsGenie
+// alert and the subscribers of the status topic
+func (h *Handler) notifyStatus(ctx context.Context, ev Event, r *executionReport) {
+	var err error
+
+	// Comment the failed action on the commit
+	if r.failedAction != nil && h.cfg.FailureComment && r.onGithub {
+		if err = h.postFailureComment(ctx, r.owner, r.repo, r.commit, ev.Detail.Pipeline, r.failedAction, r.targetURL); err != nil {
+			logWarnf(ctx, "unable to comment the failure: %s", err.Error())
+		}
+	}
+	if r.githubStatus == githubStateFailure && h.cfg.FailurePullRequestComment && r.onGithub {
+		if err = h.postFailurePullRequestComments(ctx, r.owner, r.repo, r.commit, ev.Detail.Pipeline, r.failedAction,
+			r.targetURL); err != nil {
+			logWarnf(ctx, "unable to comment the failure on the pull requests: %s", err.Error())
+		}
+	}
+
+	// Tell the notifiers (IE: Slack) about the status
+	h.notifyPipeline(ctx, r.data(ev, h.cfg.AWSRegion))
+
+	// Open (or close) the OpsGenie alert of the pipeline
+	if err = h.alertOpsgenie(ctx, ev.Detail.Pipeline, "", r.githubStatus, r.description, r.targetURL); err != nil {
+		logWarnf(ctx, "unable to alert OpsGenie: %s", err.Error())
+	}
+
+	// Fan out the normalized status event to the subscribers of the topic (not from shadow copies)
+	if len(h.cfg.StatusTopicARN) > 0 && len(h.cfg.ShadowMode) == 0 {
+		if err = h.publishStatusEvent(ctx, statusEvent{
+			Commit:         r.commit,
+			Context:        r.statusCtx,
+			Description:    r.description,
+			EventTime:      ev.Time,
+			ExecutionID:    ev.Detail.ExecutionID,
+			ExecutionState: ev.Detail.State,
+			Owner:          r.owner,
+			Pipeline:       ev.Detail.Pipeline,
+			Repo:           r.repo,
+			State:          r.githubStatus,
+			TargetURL:      r.targetURL,
+			UpdatedTime:    time.Now().UTC(),
+		}); err != nil {
+			logWarnf(ctx, "unable to publish the status event: %s", err.Error())
+		}
+	}
+}
+
+// postRelatedStatuses will post the statuses that follow the status of the execution: the commit being rolled
+// back, the release train and the stages that did not run
+func (h *Handler) postRelatedStatuses(ctx context.Context, ev Event, r *executionReport) {
+	if len(r.rolledBack) > 0 {
+		if err := h.postRolledBackStatus(ctx, ev.Detail.Pipeline, r.rolledBackURL, r.rolledBack, r.commit, r.githubStatus,
+			r.statusCtx, r.targetURL); err != nil {
+			logWarnf(ctx, "unable to post the rolled back status: %s", err.Error())
+		}
+	}
+
+	// Aggregate the pipelines of a release train (triggered from the same tag) into one status
+	if train, ok := h.cfg.ReleaseTrains[ev.Detail.Pipeline]; ok && len(h.cfg.ReleaseTrainTable) > 0 && !r.scheduled && r.onGithub {
+		if err := h.postReleaseTrainStatus(ctx, train, ev.Detail.Pipeline, r.owner, r.repo, r.commit, r.githubStatus,
+			r.targetURL); err != nil {
+			logWarnf(ctx, "unable to post the release train status: %s", err.Error())
+		}
+	}
+
+	// Stages that did not run are not left pending
+	if len(h.cfg.SkippedStageState) > 0 && finalStates[ev.Detail.State] && r.onGithub {
+		if err := h.postSkippedStages(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, r.owner, r.repo, r.commit, r.statusCtx,
+			r.targetURL, r.scheduled); err != nil {
+			logWarnf(ctx, "unable to post the skipped stages: %s", err.Error())
+		}
+	}
+}
+
+// exportStatus will hand the execution to the other tools: the CDEvents, the rollup and changelog comments of the
+// pull requests and the catalog of the developer portal
+func (h *Handler) exportStatus(ctx context.Context, ev Event, r *executionReport) {
+	var err error
+
+	// Emit the CDEvents for observability tools
+	if len(h.cfg.CDEventsBus) > 0 || len(h.cfg.CDEventsTopicARN) > 0 {
+		source := r.pipelineARN
+		if len(source) == 0 {
+			source = "/codepipeline/" + ev.Detail.Pipeline
+		}
+		eventTime := ev.Time
+		if eventTime.IsZero() {
+			eventTime = time.Now()
+		}
+		if err = h.publishCDEvents(ctx, newCDEvents(cdEventInput{
+			Commit:      r.commit,
+			Environment: h.cfg.CDEventsEnvironment,
+			ExecutionID: ev.Detail.ExecutionID,
+			Owner:       r.owner,
+			Pipeline:    ev.Detail.Pipeline,
+			Repo:        r.repo,
+			Source:      source,
+			State:       ev.Detail.State,
+			Time:        eventTime,
+			URL:         r.deepLink,
+		})); err != nil {
+			logWarnf(ctx, "unable to publish the cdevents: %s", err.Error())
+		}
+	}
+
+	// Keep the rollup of the statuses of the commit on its pull requests
+	if h.cfg.RollupComment && r.onGithub && !r.useChecksAPI {
+		if err = h.postRollupComment(ctx, r.owner, r.repo, r.commit); err != nil {
+			logWarnf(ctx, "unable to post the rollup comment: %s", err.Error())
+		}
+	}
+
+	// Comment the changelog on the pull requests that were deployed
+	if len(h.cfg.EnvironmentTable) > 0 && ev.Detail.State == stateSucceeded && !r.scheduled && r.onGithub {
+		if err = h.postChangelog(ctx, ev.Detail.Pipeline, r.owner, r.repo, r.commit); err != nil {
+			logWarnf(ctx, "unable to post the changelog: %s", err.Error())
+		}
+	}
+
+	// Keep the pipeline in the catalog of the developer portal
+	if len(h.cfg.CatalogBucket) > 0 && !r.scheduled {
+		if err = h.exportCatalogEntity(ctx, ev, r.revisionURL, r.owner, r.repo); err != nil {
+			logWarnf(ctx, "unable to export the catalog entity: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// newTestHandler will create a handler with mocked dependencies
+func newTestHandler(cfg Config) *Handler {
+	return &Handler{
+		cfg: cfg,
+		deps: Dependencies{
+			CloudTrail:   &mockCloudTrailClient{},
+			CodeBuild:    &mockCodeBuildClient{},
+			CodeDeploy:   &mockCodeDeployClient{},
+			CodePipeline: &mockCodePipelineClient{},
+			DynamoDB:     &mockDynamoClient{},
+			EventBridge:  &mockEventBridgeClient{},
+			GitHub:       http.DefaultClient,
+			KMS:          &mockKmsClient{},
+			SNS:          &mockSNSClient{},
+			Slack:        http.DefaultClient,
+			Teams:        http.DefaultClient,
+			cache:        newContainerCache(),
+		},
+		githubURL: defaultGithubAPIURL,
+	}
+}
+
+// TestNewHandler will test NewHandler()
+func TestNewHandler(t *testing.T) {
+	deps := newTestHandler(Config{}).deps
+
+	// Token is decrypted outside of the testing stage
+	h, err := NewHandler(Config{GithubAccessToken: "dGVzdC10b2tlbi12YWx1ZQ==", Stage: stageProduction}, deps)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if h.cfg.GithubAccessToken != "some-encrypted-text" {
+		t.Fatal("token was not as expected", h.cfg.GithubAccessToken)
+	} else if h.githubURL != defaultGithubAPIURL {
+		t.Fatal("github url was not as expected", h.githubURL)
+	}
+
+	// Token is used as-is in the testing stage
+	if h, err = NewHandler(Config{GithubAccessToken: "1234567", Stage: stageTesting}, deps); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if h.cfg.GithubAccessToken != "1234567" {
+		t.Fatal("token was not as expected", h.cfg.GithubAccessToken)
+	}
+
+	// Missing GitHub client uses the default
+	deps.GitHub = nil
+	if h, err = NewHandler(Config{Stage: stageTesting}, deps); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if h.deps.GitHub != http.DefaultClient {
+		t.Fatal("github client should be the default client")
+	}
+
+	// Invalid orphaned commits mode
+	if _, err = NewHandler(Config{OrphanedCommits: "delete", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid closed executions mode
+	if _, err = NewHandler(Config{ClosedExecutions: "cancel", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid severity
+	if _, err = NewHandler(Config{PipelineSeverities: stringMap{"docs": "low"}, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid stage outcome state
+	if _, err = NewHandler(Config{SkippedStageState: "skipped", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid log level
+	if _, err = NewHandler(Config{LogLevel: "verbose", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid ingestion mode
+	if _, err = NewHandler(Config{IngestionMode: "sqs", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{IngestionMode: ingestionModeWebhook, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{IngestionMode: ingestionModeServer, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid shadow modes
+	if _, err = NewHandler(Config{ShadowMode: "mirror", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{ShadowMode: shadowModeAudit, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{ShadowMode: shadowModeRepository, ShadowRepository: "mirror", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// App password without a username
+	if _, err = NewHandler(Config{BitbucketAppPassword: "secret", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid GitHub Enterprise Server url
+	if _, err = NewHandler(Config{GithubAPIBaseURL: "github.mycorp.com", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid Azure DevOps repository
+	if _, err = NewHandler(Config{AzureDevOpsRepositories: stringMap{"web": "contoso/api"}, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Invalid Gitea settings
+	if _, err = NewHandler(Config{GiteaPipelines: []string{"mirrored"}, Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	} else if _, err = NewHandler(Config{GiteaURL: "git.example.com", Stage: stageTesting}, deps); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Missing dependencies
+	var tests = []struct {
+		name     string
+		modify   func(d *Dependencies)
+		expected string
+	}{
+		{"kms", func(d *Dependencies) { d.KMS = nil }, "missing dependency: KMS"},
+		{"codepipeline", func(d *Dependencies) { d.CodePipeline = nil }, "missing dependency: CodePipeline"},
+		{"dynamodb", func(d *Dependencies) { d.DynamoDB = nil }, "missing dependency: DynamoDB"},
+		{"cloudtrail", func(d *Dependencies) { d.CloudTrail = nil }, "missing dependency: CloudTrail"},
+	}
+
+	for _, test := range tests {
+		missing := newTestHandler(Config{}).deps
+		test.modify(&missing)
+		if _, err = NewHandler(Config{GithubAccessToken: "dGVzdC10b2tlbi12YWx1ZQ==", Stage: stageProduction}, missing); err == nil {
+			t.Errorf("%s Failed: dependency [%s], expected to throw an error, but no error", t.Name(), test.name)
+		} else if err.Error() != test.expected {
+			t.Errorf("%s Failed: dependency [%s], expected [%s] but got [%s]", t.Name(), test.name, test.expected, err.Error())
+		}
+	}
+}
+
+// TestHandlerProcessEvent will test the Handler.ProcessEvent() method
+func TestHandlerProcessEvent(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:             "us-east-1",
+		GithubAccessToken:     "1234567",
+		GithubMaxConcurrency:  1,
+		ScheduledContext:      "nightly/codepipeline",
+		Stage:                 stageTesting,
+		FlakyFailureThreshold: 3,
+	})
+
+	var received payload
+	var path string
+	var calls int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Missing event detail
+	if err := h.ProcessEvent(Event{}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Valid event
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path != "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("path was not as expected", path)
+	} else if received.State != githubStateSuccess {
+		t.Fatal("state was not as expected", received.State)
+	} else if received.Context != defaultStatusContext {
+		t.Fatal("context was not as expected", received.Context)
+	} else if calls != 1 {
+		t.Fatal("github calls was not as expected", calls)
+	}
+
+	// Missing pipeline execution
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "nil",
+	}}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventConcurrent will process events of different accounts and pipelines at the same
+// time on one handler (like warm invocations sharing a container) and check that no status, token or context
+// leaks into another event (run with -race)
+func TestHandlerProcessEventConcurrent(t *testing.T) {
+	states := map[string]string{
+		"status-fail":    githubStateFailure,
+		"status-started": githubStatePending,
+		"status-succeed": githubStateSuccess,
+	}
+	accounts := accountMap{
+		"111111111111": {ContextPrefix: "team-a", GithubAccessToken: "token-a"},
+		"222222222222": {ContextPrefix: "team-b", GithubAccessToken: "token-b"},
+	}
+
+	for _, withAccounts := range []bool{false, true} {
+		cfg := Config{
+			AWSRegion:              "us-east-1",
+			ContextPrefixes:        stringMap{},
+			GithubAccessToken:      "token-default",
+			GithubMaxConcurrency:   4,
+			Stage:                  stageTesting,
+			TokenExpiryWarningDays: 14,
+		}
+		if withAccounts {
+			cfg.Accounts = accounts
+		} else {
+			for pipeline := range states {
+				cfg.ContextPrefixes[pipeline] = "team-default"
+			}
+		}
+		h := newTestHandler(cfg)
+
+		var mu sync.Mutex
+		received := make(map[string]payload)
+		tokens := make(map[string]string)
+		newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+			var status payload
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			mu.Lock()
+			received[status.TargetURL] = status
+			tokens[status.TargetURL] = r.Header.Get("Authorization")
+			mu.Unlock()
+			w.Header().Set(tokenExpirationHeader, time.Now().Add(90*24*time.Hour).UTC().Format("2006-01-02 15:04:05 UTC"))
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		// Fire the events at the same time
+		var wg sync.WaitGroup
+		events := make(map[string]Event)
+		for i := 0; i < 30; i++ {
+			account := "111111111111"
+			if i%2 == 1 {
+				account = "222222222222"
+			}
+			pipeline := []string{"status-fail", "status-started", "status-succeed"}[i%3]
+			ev := Event{Account: account, Detail: &Detail{
+				ExecutionID: fmt.Sprintf("execution-%d", i),
+				Pipeline:    pipeline,
+				State:       "STARTED",
+			}}
+			events[consoleURL(endpoints.AwsPartitionID, "us-east-1", fmt.Sprintf(
+				"/codesuite/codepipeline/pipelines/%s/executions/%s", pipeline, ev.Detail.ExecutionID))] = ev
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := h.ProcessEvent(ev); err != nil {
+					t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), ev.Detail.ExecutionID, err.Error())
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Every status belongs to its own event
+		if len(received) != len(events) {
+			t.Fatalf("%s Failed: expected [%d] statuses but got [%d]", t.Name(), len(events), len(received))
+		}
+		for targetURL, ev := range events {
+			prefix, token := "team-default", "token token-default"
+			if withAccounts {
+				prefix, token = accounts[ev.Account].ContextPrefix, "token "+accounts[ev.Account].GithubAccessToken
+			}
+			if status := received[targetURL]; status.Context != prefix+"/"+ev.Detail.Pipeline {
+				t.Errorf("%s Failed: [%s] inputted, expected context [%s] but got [%s]", t.Name(), ev.Detail.ExecutionID, prefix+"/"+ev.Detail.Pipeline, status.Context)
+			} else if status.State != states[ev.Detail.Pipeline] {
+				t.Errorf("%s Failed: [%s] inputted, expected state [%s] but got [%s]", t.Name(), ev.Detail.ExecutionID, states[ev.Detail.Pipeline], status.State)
+			} else if tokens[targetURL] != token {
+				t.Errorf("%s Failed: [%s] inputted, expected token [%s] but got [%s]", t.Name(), ev.Detail.ExecutionID, token, tokens[targetURL])
+			} else if !strings.HasSuffix(targetURL, ev.Detail.ExecutionID) {
+				t.Errorf("%s Failed: [%s] inputted, target url was [%s]", t.Name(), ev.Detail.ExecutionID, targetURL)
+			}
+		}
+	}
+}
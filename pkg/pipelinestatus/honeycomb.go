@@ -0,0 +1,105 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Honeycomb deploy markers and trace links
+const (
+	defaultHoneycombAPIURL = "https://api.honeycomb.io"
+	honeycombMarkerType    = "deploy"
+)
+
+// honeycombMarker is a marker of the Markers API (times are unix seconds)
+type honeycombMarker struct {
+	EndTime   int64  `json:"end_time,omitempty"`
+	Message   string `json:"message"`
+	StartTime int64  `json:"start_time"`
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+}
+
+// honeycombQuery is the query of a trace link (the events of the service within the boundaries)
+type honeycombQuery struct {
+	Breakdowns   []string               `json:"breakdowns"`
+	Calculations []map[string]string    `json:"calculations"`
+	EndTime      int64                  `json:"end_time"`
+	Filters      []honeycombQueryFilter `json:"filters"`
+	StartTime    int64                  `json:"start_time"`
+}
+
+// honeycombQueryFilter is a filter of a query
+type honeycombQueryFilter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  string `json:"value"`
+}
+
+// honeycombTraceURL will return the link to the traces of the service (HONEYCOMB_DATASET) between the boundaries,
+// empty without HONEYCOMB_UI_URL
+func (h *Handler) honeycombTraceURL(start, end time.Time) string {
+	if len(h.cfg.HoneycombUIURL) == 0 || len(h.cfg.HoneycombDataset) == 0 {
+		return ""
+	}
+	query, err := json.Marshal(&honeycombQuery{
+		Breakdowns:   []string{"name"},
+		Calculations: []map[string]string{{"op": "COUNT"}, {"column": "duration_ms", "op": "P99"}},
+		EndTime:      end.Unix(),
+		Filters:      []honeycombQueryFilter{{Column: "service.name", Op: "=", Value: h.cfg.HoneycombDataset}},
+		StartTime:    start.Unix(),
+	})
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(h.cfg.HoneycombUIURL, "/") + "/datasets/" + url.PathEscape(h.cfg.HoneycombDataset) +
+		"?query=" + url.QueryEscape(string(query))
+}
+
+// postHoneycombMarker will add a marker to the dataset of the service (skipped without HONEYCOMB_API_KEY
+// and in shadow mode)
+func (h *Handler) postHoneycombMarker(ctx context.Context, marker honeycombMarker) error {
+	if len(h.cfg.HoneycombAPIKey) == 0 || len(h.cfg.HoneycombDataset) == 0 || len(h.cfg.ShadowMode) > 0 {
+		return nil
+	}
+
+	// Encode the marker
+	body, err := json.Marshal(&marker)
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	apiURL := h.cfg.HoneycombAPIURL
+	if len(apiURL) == 0 {
+		apiURL = defaultHoneycombAPIURL
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/1/markers/"+url.PathEscape(h.cfg.HoneycombDataset),
+		bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Honeycomb-Team", h.cfg.HoneycombAPIKey)
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = h.deps.Honeycomb.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Honeycomb, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
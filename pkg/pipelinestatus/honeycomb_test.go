@@ -0,0 +1,154 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+)
+
+// TestHoneycombTraceURL will test Handler.honeycombTraceURL()
+func TestHoneycombTraceURL(t *testing.T) {
+	t.Parallel()
+
+	start, end := time.Unix(1588334400, 0), time.Unix(1588334700, 0)
+	h := newTestHandler(Config{HoneycombDataset: "web", HoneycombUIURL: "https://ui.honeycomb.io/mrz/environments/production/"})
+	traceURL := h.honeycombTraceURL(start, end)
+	if !strings.HasPrefix(traceURL, "https://ui.honeycomb.io/mrz/environments/production/datasets/web?query=") {
+		t.Fatal("trace url was not as expected", traceURL)
+	}
+	parsed, _ := url.Parse(traceURL)
+	var query honeycombQuery
+	if err := json.Unmarshal([]byte(parsed.Query().Get("query")), &query); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if query.StartTime != 1588334400 || query.EndTime != 1588334700 {
+		t.Fatal("boundaries were not as expected", query)
+	} else if len(query.Filters) != 1 || query.Filters[0].Column != "service.name" || query.Filters[0].Value != "web" {
+		t.Fatal("filters were not as expected", query.Filters)
+	}
+
+	// Not configured
+	if traceURL = newTestHandler(Config{HoneycombDataset: "web"}).honeycombTraceURL(start, end); len(traceURL) > 0 {
+		t.Fatal("trace url should be empty", traceURL)
+	}
+}
+
+// TestPostHoneycombMarker will test Handler.postHoneycombMarker()
+func TestPostHoneycombMarker(t *testing.T) {
+	var marker honeycombMarker
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Honeycomb-Team") != "secret-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unknown API key"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&marker)
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h := newTestHandler(Config{HoneycombAPIKey: "secret-key", HoneycombDataset: "web"})
+	h.deps.Honeycomb = client
+
+	if err := h.postHoneycombMarker(context.Background(), honeycombMarker{Message: "web production", StartTime: 1588334400,
+		Type: honeycombMarkerType}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].URL.String() != "https://api.honeycomb.io/1/markers/web" {
+		t.Fatal("request was not as expected", client.requests)
+	} else if marker.Message != "web production" || marker.StartTime != 1588334400 || marker.Type != honeycombMarkerType {
+		t.Fatal("marker was not as expected", marker)
+	}
+
+	// Invalid key
+	h.cfg.HoneycombAPIKey = "invalid"
+	if err := h.postHoneycombMarker(context.Background(), honeycombMarker{}); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != `unexpected response from Honeycomb, code: 401 body: {"error":"unknown API key"}` {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Disabled
+	h.cfg.HoneycombAPIKey = ""
+	if err := h.postHoneycombMarker(context.Background(), honeycombMarker{}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 {
+		t.Fatal("marker should not have been sent", len(client.requests))
+	}
+}
+
+// TestDeploymentWindow will test deploymentWindow()
+func TestDeploymentWindow(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	eventTime := created.Add(5 * time.Minute)
+	if start, end := deploymentWindow(Event{Time: eventTime}, &codedeploy.DeploymentInfo{CreateTime: aws.Time(created)}); !start.Equal(created) || !end.Equal(eventTime) {
+		t.Fatal("window was not as expected", start, end)
+	} else if start, end = deploymentWindow(Event{Time: eventTime}, &codedeploy.DeploymentInfo{CreateTime: aws.Time(created),
+		CompleteTime: aws.Time(created.Add(time.Minute))}); !end.Equal(created.Add(time.Minute)) {
+		t.Fatal("window was not as expected", start, end)
+	} else if start, end = deploymentWindow(Event{Time: eventTime}, &codedeploy.DeploymentInfo{}); !start.Equal(eventTime) || !end.Equal(eventTime) {
+		t.Fatal("window was not as expected", start, end)
+	}
+}
+
+// TestHandlerProcessEventDeploymentHoneycomb will test ProcessEvent() marking the deployment and linking the traces
+func TestHandlerProcessEventDeploymentHoneycomb(t *testing.T) {
+	var markers []honeycombMarker
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		var marker honeycombMarker
+		_ = json.NewDecoder(r.Body).Decode(&marker)
+		markers = append(markers, marker)
+		w.WriteHeader(http.StatusCreated)
+	}}
+	h := newTestHandler(Config{
+		CodeDeployEvents:     true,
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		HoneycombAPIKey:      "secret-key",
+		HoneycombDataset:     "web",
+		HoneycombUIURL:       "https://ui.honeycomb.io/mrz/environments/production",
+		Stage:                stageTesting,
+	})
+	h.deps.Honeycomb = client
+
+	var statuses []githubDeploymentStatus
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`[{"id":7,"payload":{"deployment_id":"d-GITHUB"}}]`))
+		case r.URL.Path == "/repos/mrz1836/codepipeline-to-github/deployments/7/statuses":
+			var status githubDeploymentStatus
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			statuses = append(statuses, status)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// In progress deployments are not marked yet
+	ev := newDeploymentEvent("d-GITHUB", "START")
+	ev.Time = time.Date(2020, 5, 1, 12, 0, 30, 0, time.UTC)
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(markers) != 0 || len(statuses) != 1 {
+		t.Fatal("marker should not have been sent", markers)
+	}
+
+	ev = newDeploymentEvent("d-GITHUB", "SUCCESS")
+	ev.Time = time.Date(2020, 5, 1, 12, 5, 0, 0, time.UTC)
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(markers) != 1 || markers[0].StartTime != 1588334400 || markers[0].EndTime != 1588334700 ||
+		markers[0].Message != "web production codepipeline-to-github@25c0c3e success" {
+		t.Fatal("marker was not as expected", markers)
+	} else if len(statuses) != 2 || statuses[1].Description != "deployment success; traces of web in Honeycomb" ||
+		!strings.HasPrefix(statuses[1].EnvironmentURL, "https://ui.honeycomb.io/mrz/environments/production/datasets/web?query=") {
+		t.Fatal("status was not as expected", statuses[1])
+	}
+}
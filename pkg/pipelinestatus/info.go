@@ -0,0 +1,217 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// version is the release of the function (set when building: -ldflags "-X github.com/mrz1836/codepipeline-to-github/pkg/pipelinestatus.version=v1.2.3")
+var version = "dev"
+
+// actionInfo is the payload that returns the info of the deployment instead of processing an event
+const actionInfo = "info"
+
+// redactedSettings are never included in the info
+var redactedSettings = map[string]bool{
+	"ACCOUNTS":               true,
+	"AZURE_DEVOPS_TOKEN":     true,
+	"BITBUCKET_ACCESS_TOKEN": true,
+	"BITBUCKET_APP_PASSWORD": true,
+	"GITEA_ACCESS_TOKEN":     true,
+	"GITHUB_ACCESS_TOKEN":    true,
+	"GITHUB_WEBHOOK_SECRET":  true,
+	"GITLAB_ACCESS_TOKEN":    true,
+	"HONEYCOMB_API_KEY":      true,
+	"OPSGENIE_API_KEY":       true,
+	"SERVER_SECRET":          true,
+	"SLACK_WEBHOOK_URL":      true,
+	"TEAMS_WEBHOOK_URL":      true,
+	"TEAMS_WEBHOOKS":         true,
+}
+
+// recentErrorsSize is the number of recent errors kept per container
+const recentErrorsSize = 20
+
+// Per-container error counts and the most recent errors (since the container started)
+var (
+	containerStarted = time.Now()
+	errorCounts      = make(map[string]int64)
+	errorCountsMu    sync.Mutex
+	recentErrors     []recentError
+)
+
+// recentError is a processing error of the container
+type recentError struct {
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// deploymentInfo describes a running deployment (IE: {"action":"info"})
+type deploymentInfo struct {
+	Budget       *budgetUsage      `json:"github_budget,omitempty"`
+	Config       map[string]string `json:"config"`
+	ErrorCounts  map[string]int64  `json:"error_counts"`
+	ErrorsSince  time.Time         `json:"errors_since"`
+	Integrations []string          `json:"integrations"`
+	RecentErrors []recentError     `json:"recent_errors"`
+	Version      string            `json:"version"`
+}
+
+// HandleRequest is the EventBridge entry point: pipeline events, or the info of the deployment
+func HandleRequest(ctx context.Context, ev Event) (*deploymentInfo, error) {
+	switch ev.Action {
+	case "":
+		return nil, ProcessEvent(ctx, ev)
+	case actionInfo:
+		h, err := handlerFromEnvironment(ctx)
+		if err != nil {
+			return nil, err
+		}
+		info := h.info(ctx)
+		return &info, nil
+	default:
+		return nil, fmt.Errorf("unknown action: %s (available: %s)", ev.Action, actionInfo)
+	}
+}
+
+// countError will count a processing error by its kind (IE: github:403, artifact:NameMismatch) and keep
+// it with the most recent errors
+func countError(err error) {
+	kind := "other"
+	switch e := err.(type) {
+	case *artifactError:
+		kind = "artifact:" + e.Reason
+	case *githubError:
+		kind = fmt.Sprintf("github:%d", e.Code)
+	case *secondaryRateLimitError:
+		kind = "github:secondary-rate-limit"
+	}
+	errorCountsMu.Lock()
+	errorCounts[kind]++
+	recentErrors = append(recentErrors, recentError{Kind: kind, Message: err.Error(), Time: time.Now().UTC()})
+	if len(recentErrors) > recentErrorsSize {
+		recentErrors = recentErrors[len(recentErrors)-recentErrorsSize:]
+	}
+	errorCountsMu.Unlock()
+}
+
+// info will return the version, the configuration (secrets are left out), the enabled integrations
+// and the errors of the container (counts and the most recent ones)
+func (h *Handler) info(ctx context.Context) deploymentInfo {
+	info := deploymentInfo{
+		Budget:       h.budget(ctx),
+		Config:       configSummary(h.cfg),
+		ErrorCounts:  make(map[string]int64),
+		ErrorsSince:  containerStarted.UTC(),
+		Integrations: h.integrations(),
+		Version:      version,
+	}
+	errorCountsMu.Lock()
+	for kind, count := range errorCounts {
+		info.ErrorCounts[kind] = count
+	}
+	info.RecentErrors = append([]recentError{}, recentErrors...)
+	errorCountsMu.Unlock()
+	return info
+}
+
+// configSummary will return the settings that are set (by environment variable name) without the secrets
+func configSummary(cfg Config) map[string]string {
+	summary := make(map[string]string)
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("envconfig")
+		if field := value.Field(i); !redactedSettings[name] && !field.IsZero() {
+			summary[name] = fmt.Sprintf("%v", field.Interface())
+		}
+	}
+	return summary
+}
+
+// configSecrets will return the values of the secret settings (redacted from the logs)
+func configSecrets(cfg Config) (secrets []string) {
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("envconfig")
+		if field := value.Field(i); redactedSettings[name] && field.Kind() == reflect.String && !field.IsZero() {
+			secrets = append(secrets, field.String())
+		}
+	}
+	for _, account := range cfg.Accounts {
+		if len(account.GithubAccessToken) > 0 {
+			secrets = append(secrets, account.GithubAccessToken)
+		}
+	}
+	return
+}
+
+// integrations will return the optional features enabled in the configuration (sorted)
+func (h *Handler) integrations() (list []string) {
+	enabled := map[string]bool{
+		"accounts":           len(h.cfg.Accounts) > 0,
+		"assume-role":        len(h.cfg.AssumeRoleARN) > 0,
+		"attention":          len(h.cfg.AttentionTable) > 0,
+		"azure-devops":       len(h.cfg.AzureDevOpsToken) > 0,
+		"bitbucket":          len(h.cfg.BitbucketAccessToken) > 0 || len(h.cfg.BitbucketAppPassword) > 0,
+		"budget":             len(h.cfg.BudgetTable) > 0,
+		"catalog":            len(h.cfg.CatalogBucket) > 0,
+		"cdevents":           len(h.cfg.CDEventsBus) > 0 || len(h.cfg.CDEventsTopicARN) > 0,
+		"changelog":          len(h.cfg.EnvironmentTable) > 0,
+		"checks-api":         h.cfg.UseChecksAPI,
+		"closed-executions":  len(h.cfg.ClosedExecutions) > 0,
+		"codebuild-events":   h.cfg.CodeBuildEvents,
+		"codecommit-mirrors": len(h.cfg.CodeCommitMirrors) > 0,
+		"codedeploy-events":  h.cfg.CodeDeployEvents,
+		"context-prefix-tag": len(h.cfg.ContextPrefixTag) > 0,
+		"definition-drift":   len(h.cfg.DefinitionTable) > 0,
+		"dead-letter-queue":  len(h.cfg.DeadLetterQueueURL) > 0,
+		"dedup":              len(h.cfg.DedupTable) > 0,
+		"deployment-stages":  len(h.cfg.DeploymentStagePattern) > 0,
+		"event-order":        len(h.cfg.EventOrderTable) > 0,
+		"execution-duration": len(h.cfg.ExecutionTable) > 0,
+		"failure-comment":    h.cfg.FailureComment,
+		"failure-details":    h.cfg.FailureDetails,
+		"failure-pr-comment": h.cfg.FailurePullRequestComment,
+		"flaky-failures":     len(h.cfg.FlakyFailureTable) > 0,
+		"freeze":             len(h.cfg.FreezeWindows) > 0 || len(h.cfg.FreezeCalendarURL) > 0,
+		"github-app":         len(h.cfg.GithubAppSecretARN) > 0,
+		"gitea":              len(h.cfg.GiteaURL) > 0,
+		"gitlab":             len(h.cfg.GitlabAccessToken) > 0,
+		"honeycomb":          len(h.cfg.HoneycombAPIKey) > 0 || len(h.cfg.HoneycombUIURL) > 0,
+		"initiator-lookup":   h.cfg.InitiatorLookup,
+		"link-build-logs":    h.cfg.LinkBuildLogs,
+		"mute":               len(h.cfg.MuteTable) > 0,
+		"opsgenie":           len(h.cfg.OpsgenieAPIKey) > 0,
+		"orphaned-commits":   len(h.cfg.OrphanedCommits) > 0,
+		"pipeline-config":    len(h.cfg.PipelineConfig) > 0,
+		"pipeline-regions":   len(h.cfg.PipelineRegions) > 0,
+		"pipeline-triggers":  h.hasTriggers(),
+		"prometheus":         len(h.cfg.PrometheusPushgatewayURL) > 0,
+		"rate-limit":         len(h.cfg.RateLimitTable) > 0,
+		"release-trains":     len(h.cfg.ReleaseTrainTable) > 0,
+		"severities":         len(h.cfg.PipelineSeverities) > 0,
+		"shadow":             len(h.cfg.ShadowMode) > 0,
+		"slack":              len(h.cfg.SlackWebhookURL) > 0,
+		"stage-durations":    len(h.cfg.StageDurationTable) > 0,
+		"status-events":      len(h.cfg.StatusTopicARN) > 0,
+		"teams":              len(h.cfg.TeamsWebhookURL) > 0 || len(h.cfg.TeamsWebhooks) > 0,
+		"templates":          len(h.cfg.DescriptionTemplate) > 0 || len(h.cfg.TargetURLTemplate) > 0,
+		"timeline":           len(h.cfg.TimelineTable) > 0,
+		"transitions":        len(h.cfg.TransitionTable) > 0,
+		"usage":              len(h.cfg.UsageTable) > 0,
+		"verified-commits":   h.cfg.RequireVerifiedCommits,
+	}
+	list = []string{}
+	for name, on := range enabled {
+		if on {
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	return
+}
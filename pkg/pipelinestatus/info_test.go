@@ -0,0 +1,74 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestConfigSummary will test configSummary()
+func TestConfigSummary(t *testing.T) {
+	summary := configSummary(Config{
+		Accounts:          accountMap{"123": {GithubAccessToken: "secret"}},
+		AWSRegion:         "us-east-1",
+		GithubAccessToken: "secret",
+		SlackWebhookURL:   "https://hooks.slack.com/secret",
+		Stage:             stageProduction,
+	})
+	if len(summary) != 2 {
+		t.Fatal("summary was not as expected", summary)
+	} else if summary["AWS_REGION"] != "us-east-1" || summary["APPLICATION_STAGE_NAME"] != stageProduction {
+		t.Fatal("summary was not as expected", summary)
+	}
+}
+
+// TestHandlerInfo will test info()
+func TestHandlerInfo(t *testing.T) {
+	h := newTestHandler(Config{Stage: stageTesting, TimelineTable: "timeline", SlackWebhookURL: "https://hooks.slack.com/secret"})
+
+	countError(&artifactError{Reason: artifactBadSHA})
+	countError(&githubError{Code: 403})
+	countError(errors.New("something else"))
+
+	info := h.info(context.Background())
+	if info.Version != version {
+		t.Fatal("version was not as expected", info.Version)
+	} else if len(info.Integrations) != 2 || info.Integrations[0] != "slack" || info.Integrations[1] != "timeline" {
+		t.Fatal("integrations were not as expected", info.Integrations)
+	} else if info.ErrorCounts["artifact:BadSHA"] < 1 || info.ErrorCounts["github:403"] < 1 || info.ErrorCounts["other"] < 1 {
+		t.Fatal("error counts were not as expected", info.ErrorCounts)
+	} else if last := info.RecentErrors[len(info.RecentErrors)-1]; last.Kind != "other" || last.Message != "something else" {
+		t.Fatal("recent errors were not as expected", info.RecentErrors)
+	} else if _, ok := info.Config["SLACK_WEBHOOK_URL"]; ok {
+		t.Fatal("secrets should not be included", info.Config)
+	}
+}
+
+// TestHandleRequest will test HandleRequest()
+func TestHandleRequest(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", stageTesting)
+	defer os.Clearenv()
+	setupSession()
+
+	if info, err := HandleRequest(context.Background(), Event{Action: actionInfo}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if info == nil || info.Config["AWS_REGION"] != "us-east-1" {
+		t.Fatal("info was not as expected", info)
+	}
+
+	// Unknown action
+	if _, err := HandleRequest(context.Background(), Event{Action: "restart"}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Pipeline event
+	if info, err := HandleRequest(context.Background(), Event{}); err == nil {
+		t.Fatal("error should have occurred")
+	} else if info != nil {
+		t.Fatal("info should be nil", info)
+	}
+}
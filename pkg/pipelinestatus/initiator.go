@@ -0,0 +1,105 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+)
+
+// Initiator defaults
+const (
+	initiatorEventName = "StartPipelineExecution"
+	initiatorLookback  = 24 * time.Hour
+)
+
+// trailEvent is the part of a CloudTrail event record needed to find the initiator
+type trailEvent struct {
+	ResponseElements struct {
+		PipelineExecutionID string `json:"pipelineExecutionId"`
+	} `json:"responseElements"`
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+}
+
+// getInitiator will return who manually started an execution (empty if it was not started by a person), the ARN of
+// the pipeline is looked up if the event did not carry it
+func (h *Handler) getInitiator(ctx context.Context, pipelineARN string,
+	executionOutput *codepipeline.GetPipelineExecutionOutput) (initiator string, err error) {
+
+	// Only manual executions have an initiator
+	trigger := executionOutput.PipelineExecution.Trigger
+	if trigger == nil || trigger.TriggerType != types.TriggerTypeStartPipelineExecution {
+		return
+	}
+
+	// Fall back to CloudTrail if the trigger does not have the identity
+	identity := aws.StringValue(trigger.TriggerDetail)
+	if len(identity) == 0 && h.cfg.InitiatorLookup {
+		if len(pipelineARN) == 0 {
+			if pipelineARN, err = getPipelineARN(
+				ctx, aws.StringValue(executionOutput.PipelineExecution.PipelineName), h.deps.CodePipeline,
+			); err != nil {
+				return
+			}
+		}
+		if identity, err = lookupInitiator(
+			ctx, h.deps.CloudTrail, pipelineARN, aws.StringValue(executionOutput.PipelineExecution.PipelineExecutionId),
+		); err != nil {
+			return
+		}
+	}
+
+	return h.initiatorHandle(identity), nil
+}
+
+// lookupInitiator will search CloudTrail for the identity that started the execution, the events of the pipeline
+// are looked up (only one attribute is allowed) and the ones that did not start an execution are skipped
+func lookupInitiator(ctx context.Context, trailSvc cloudtrailiface.CloudTrailAPI, pipelineARN,
+	executionID string) (identity string, err error) {
+	now := time.Now()
+	err = trailSvc.LookupEventsPagesWithContext(ctx, &cloudtrail.LookupEventsInput{
+		EndTime: aws.Time(now),
+		LookupAttributes: []*cloudtrail.LookupAttribute{{
+			AttributeKey:   aws.String(cloudtrail.LookupAttributeKeyResourceName),
+			AttributeValue: aws.String(pipelineARN),
+		}},
+		StartTime: aws.Time(now.Add(-initiatorLookback)),
+	}, func(page *cloudtrail.LookupEventsOutput, lastPage bool) bool {
+		for _, event := range page.Events {
+			var record trailEvent
+			if aws.StringValue(event.EventName) != initiatorEventName {
+				continue
+			} else if json.Unmarshal([]byte(aws.StringValue(event.CloudTrailEvent)), &record) != nil {
+				continue
+			}
+			if record.ResponseElements.PipelineExecutionID == executionID {
+				identity = record.UserIdentity.ARN
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
+// initiatorHandle will map an IAM identity to a configured handle, or return the user/session name
+func (h *Handler) initiatorHandle(identity string) string {
+	if len(identity) == 0 {
+		return ""
+	}
+	name := identity[strings.LastIndex(identity, "/")+1:]
+	for _, key := range []string{identity, name} {
+		if handle, ok := h.cfg.InitiatorHandles[key]; ok && len(handle) > 0 {
+			return "@" + strings.TrimPrefix(handle, "@")
+		}
+	}
+	return name
+}
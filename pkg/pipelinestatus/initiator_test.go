@@ -0,0 +1,98 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+)
+
+// Mocking cloudtrail client
+type mockCloudTrailClient struct {
+	cloudtrailiface.CloudTrailAPI
+}
+
+// LookupEventsPagesWithContext is a mock request for cloudtrail
+func (m *mockCloudTrailClient) LookupEventsPagesWithContext(_ aws.Context, input *cloudtrail.LookupEventsInput,
+	fn func(*cloudtrail.LookupEventsOutput, bool) bool, _ ...request.Option) error {
+	if len(input.LookupAttributes) != 1 ||
+		aws.StringValue(input.LookupAttributes[0].AttributeKey) != cloudtrail.LookupAttributeKeyResourceName ||
+		aws.StringValue(input.LookupAttributes[0].AttributeValue) != "arn:aws:codepipeline:us-east-1:123:my-pipeline" {
+		return fmt.Errorf("unexpected lookup attributes: %v", input.LookupAttributes)
+	}
+	if !fn(&cloudtrail.LookupEventsOutput{Events: []*cloudtrail.Event{
+		{CloudTrailEvent: aws.String("not json"), EventName: aws.String(initiatorEventName)},
+		{CloudTrailEvent: aws.String(`{"userIdentity":{"arn":"arn:aws:iam::123:user/john"},"responseElements":{"pipelineExecutionId":"other"}}`), EventName: aws.String(initiatorEventName)},
+		{CloudTrailEvent: aws.String(`{"userIdentity":{"arn":"arn:aws:iam::123:user/john"},"responseElements":{"pipelineExecutionId":"12345"}}`), EventName: aws.String("StopPipelineExecution")},
+		{CloudTrailEvent: aws.String(`{"userIdentity":{"arn":"arn:aws:sts::123:assumed-role/Admin/jane"},"responseElements":{"pipelineExecutionId":"12345"}}`), EventName: aws.String(initiatorEventName)},
+	}}, false) {
+		return nil
+	}
+	fn(&cloudtrail.LookupEventsOutput{Events: []*cloudtrail.Event{
+		{CloudTrailEvent: aws.String(`{"userIdentity":{"arn":"arn:aws:iam::123:user/john"},"responseElements":{"pipelineExecutionId":"12345"}}`), EventName: aws.String(initiatorEventName)},
+	}}, true)
+	return nil
+}
+
+// newTriggeredExecution will return an execution output with a trigger
+func newTriggeredExecution(triggerType types.TriggerType, triggerDetail string) *codepipeline.GetPipelineExecutionOutput {
+	return &codepipeline.GetPipelineExecutionOutput{
+		PipelineExecution: &types.PipelineExecution{
+			PipelineExecutionId: aws.String("12345"),
+			PipelineName:        aws.String("my-pipeline"),
+			Trigger: &types.ExecutionTrigger{
+				TriggerDetail: aws.String(triggerDetail),
+				TriggerType:   triggerType,
+			},
+		},
+	}
+}
+
+// TestGetInitiator will test getInitiator()
+func TestGetInitiator(t *testing.T) {
+	h := newTestHandler(Config{InitiatorHandles: stringMap{"jane": "jane-doe"}})
+
+	var tests = []struct {
+		triggerType   types.TriggerType
+		triggerDetail string
+		lookup        bool
+		expected      string
+	}{
+		{types.TriggerTypeWebhook, "arn:aws:codepipeline:us-east-1:123:webhook", false, ""},
+		{types.TriggerTypeStartPipelineExecution, "arn:aws:iam::123:user/john", false, "john"},
+		{types.TriggerTypeStartPipelineExecution, "arn:aws:sts::123:assumed-role/Admin/jane", false, "@jane-doe"},
+		{types.TriggerTypeStartPipelineExecution, "", false, ""},
+		{types.TriggerTypeStartPipelineExecution, "", true, "@jane-doe"},
+	}
+
+	for _, test := range tests {
+		h.cfg.InitiatorLookup = test.lookup
+		initiator, err := h.getInitiator(context.Background(), "arn:aws:codepipeline:us-east-1:123:my-pipeline", newTriggeredExecution(test.triggerType, test.triggerDetail))
+		if err != nil {
+			t.Errorf("%s Failed: trigger [%s] detail [%s], error occurred [%s]", t.Name(), test.triggerType, test.triggerDetail, err.Error())
+		} else if initiator != test.expected {
+			t.Errorf("%s Failed: trigger [%s] detail [%s], expected [%s] but got [%s]", t.Name(), test.triggerType, test.triggerDetail, test.expected, initiator)
+		}
+	}
+
+	// The ARN of the pipeline is looked up if the event did not carry it
+	h.cfg.InitiatorLookup = true
+	if initiator, err := h.getInitiator(context.Background(), "", newTriggeredExecution(
+		types.TriggerTypeStartPipelineExecution, "",
+	)); err != nil || initiator != "@jane-doe" {
+		t.Fatal("initiator should be @jane-doe", initiator, err)
+	}
+
+	// Missing trigger
+	if initiator, err := h.getInitiator(context.Background(), "", &codepipeline.GetPipelineExecutionOutput{
+		PipelineExecution: &types.PipelineExecution{},
+	}); err != nil || len(initiator) > 0 {
+		t.Fatal("initiator should be empty", initiator, err)
+	}
+}
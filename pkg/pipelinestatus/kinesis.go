@@ -0,0 +1,88 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Ingestion modes: pipeline events from EventBridge (default) or from a Kinesis stream (INGESTION_MODE=kinesis)
+const (
+	ingestionModeEventBridge = "eventbridge"
+	ingestionModeKinesis     = "kinesis"
+)
+
+// kinesisBatchResponse reports the failed records of a batch, Lambda checkpoints the shard before the
+// first failure and retries from there (requires ReportBatchItemFailures on the event source mapping)
+type kinesisBatchResponse struct {
+	BatchItemFailures []kinesisBatchItemFailure `json:"batchItemFailures"`
+}
+
+// kinesisBatchItemFailure is a record that needs to be retried
+type kinesisBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// permanentError is an event that fails the same way however often it is retried (IE: an account or a region
+// that is not configured)
+type permanentError struct {
+	err error
+}
+
+// Error will return the error of the event
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// isPermanent will return true if retrying the event cannot succeed (the error is permanent, or the source
+// artifact of the execution cannot be resolved to a commit)
+func isPermanent(err error) bool {
+	switch err.(type) {
+	case *permanentError, *artifactError:
+		return true
+	}
+	return false
+}
+
+// ProcessKinesisEvent is triggered by a Kinesis stream of pipeline events (partitioned by execution id)
+func ProcessKinesisEvent(ctx context.Context, kinesisEvent events.KinesisEvent) (kinesisBatchResponse, error) {
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return kinesisBatchResponse{}, err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessKinesisEvent(ctx, kinesisEvent), nil
+}
+
+// ProcessKinesisEvent will process the records of a shard in order, processing stops at the first failure
+// so later events of the same execution are never posted before an earlier one (invalid records and records
+// failing with a permanent error are skipped)
+func (h *Handler) ProcessKinesisEvent(ctx context.Context, kinesisEvent events.KinesisEvent) (response kinesisBatchResponse) {
+	ctx = h.withSettings(ctx)
+	response.BatchItemFailures = []kinesisBatchItemFailure{}
+	for _, record := range kinesisEvent.Records {
+
+		// Skip records that can never be processed instead of blocking the shard
+		var ev Event
+		if err := json.Unmarshal(record.Kinesis.Data, &ev); err != nil {
+			logf(ctx, "skipping invalid record %s: %s", record.Kinesis.SequenceNumber, err.Error())
+			continue
+		} else if err = validateEvent(ev); err != nil {
+			logf(ctx, "skipping invalid record %s: %s", record.Kinesis.SequenceNumber, err.Error())
+			continue
+		}
+
+		// Retry from the first failed record
+		if err := h.ProcessEventWithContext(ctx, ev); isPermanent(err) {
+			logWarnf(ctx, "skipping record %s: %s", record.Kinesis.SequenceNumber, err.Error())
+		} else if err != nil {
+			logErrorf(ctx, "unable to process record %s: %s", record.Kinesis.SequenceNumber, err.Error())
+			response.BatchItemFailures = append(response.BatchItemFailures, kinesisBatchItemFailure{
+				ItemIdentifier: record.Kinesis.SequenceNumber,
+			})
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// newKinesisRecord will create a Kinesis record holding the event
+func newKinesisRecord(t *testing.T, sequenceNumber string, ev interface{}) events.KinesisEventRecord {
+	var data []byte
+	if raw, ok := ev.(string); ok {
+		data = []byte(raw)
+	} else {
+		var err error
+		if data, err = json.Marshal(ev); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+	}
+	return events.KinesisEventRecord{Kinesis: events.KinesisRecord{Data: data, SequenceNumber: sequenceNumber}}
+}
+
+// TestHandlerProcessKinesisEvent will test ProcessKinesisEvent()
+func TestHandlerProcessKinesisEvent(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+
+	var posted []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var received payload
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		posted = append(posted, received.State)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Invalid records are skipped, processing stops at the first failure
+	response := h.ProcessKinesisEvent(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		newKinesisRecord(t, "1", "not-json"),
+		newKinesisRecord(t, "2", Event{}),
+		newKinesisRecord(t, "3", Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+		newKinesisRecord(t, "4", Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "nil", State: "FAILED"}}),
+		newKinesisRecord(t, "5", Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+	}})
+	if len(posted) != 1 || posted[0] != githubStateSuccess {
+		t.Fatal("posted statuses were not as expected", posted)
+	} else if len(response.BatchItemFailures) != 1 {
+		t.Fatal("failures were not as expected", response.BatchItemFailures)
+	} else if response.BatchItemFailures[0].ItemIdentifier != "4" {
+		t.Fatal("failed record was not as expected", response.BatchItemFailures[0].ItemIdentifier)
+	}
+
+	// All records processed
+	posted = nil
+	response = h.ProcessKinesisEvent(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		newKinesisRecord(t, "5", Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+	}})
+	if len(posted) != 1 {
+		t.Fatal("posted statuses were not as expected", posted)
+	} else if len(response.BatchItemFailures) != 0 {
+		t.Fatal("failures were not as expected", response.BatchItemFailures)
+	}
+}
+
+// TestHandlerProcessKinesisEventPermanent will test ProcessKinesisEvent() skipping the records that fail with a
+// permanent error (the next record of the batch is processed)
+func TestHandlerProcessKinesisEventPermanent(t *testing.T) {
+	h := newTestHandler(Config{
+		Accounts:             accountMap{"111111111111": {}},
+		AWSRegion:            "us-east-1",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		PipelineRegions:      []string{"us-east-1"},
+		Stage:                stageTesting,
+	})
+
+	var posted []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var received payload
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		posted = append(posted, received.State)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Unknown account, unknown region and a source artifact that cannot be resolved
+	response := h.ProcessKinesisEvent(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		newKinesisRecord(t, "1", Event{Account: "333333333333", Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+		newKinesisRecord(t, "2", Event{Account: "111111111111", Region: "ap-southeast-2", Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+		newKinesisRecord(t, "3", Event{Account: "111111111111", Detail: &Detail{ExecutionID: "12345678", Pipeline: "bad-artifact-name", State: "SUCCEEDED"}}),
+		newKinesisRecord(t, "4", Event{Account: "111111111111", Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}),
+	}})
+	if len(response.BatchItemFailures) != 0 {
+		t.Fatal("failures were not as expected", response.BatchItemFailures)
+	} else if len(posted) != 1 || posted[0] != githubStateSuccess {
+		t.Fatal("posted statuses were not as expected", posted)
+	}
+}
@@ -0,0 +1,63 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Latency metrics (from the time of the pipeline event)
+const (
+	metricStatusVisibleLatency = "StatusVisibleLatency"
+	metricStatusWriteLatency   = "StatusWriteLatency"
+	statusVisibilityAttempts   = 5
+)
+
+// statusVisibilityInterval is the wait between the reads of the status after the write
+var statusVisibilityInterval = 250 * time.Millisecond
+
+// commitStatus is a status of a commit returned by the GitHub API
+type commitStatus struct {
+	Context     string    `json:"context"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	State       string    `json:"state"`
+	TargetURL   string    `json:"target_url"`
+}
+
+// recordLatency will emit the time between the pipeline event and a GitHub milestone (in milliseconds,
+// events without a time are skipped)
+func recordLatency(metric, pipelineName string, eventTime, now time.Time) {
+	if eventTime.IsZero() {
+		return
+	}
+	printMetric(metric, float64(now.Sub(eventTime)/time.Millisecond), "Milliseconds",
+		map[string]string{"Pipeline": pipelineName}, now)
+}
+
+// statusVisible will read the statuses of the commit until the status of the context is returned
+// with the state (GitHub can serve a stale read right after the write)
+func (h *Handler) statusVisible(ctx context.Context, owner, repo, commit, statusCtx, state string) (visibleAt time.Time, err error) {
+	for attempt := 0; attempt < statusVisibilityAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(statusVisibilityInterval)
+		}
+		var statuses []commitStatus
+		if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/statuses?per_page=100", owner, repo, commit), &statuses); err != nil {
+			return
+		}
+
+		// Newest first, only the latest status of the context counts
+		for _, status := range statuses {
+			if status.Context != statusCtx {
+				continue
+			}
+			if status.State == state {
+				return time.Now(), nil
+			}
+			break
+		}
+	}
+	err = fmt.Errorf("status is not visible after %d reads: %s", statusVisibilityAttempts, statusCtx)
+	return
+}
@@ -0,0 +1,70 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestStatusVisible will test Handler.statusVisible() reading the status after the write
+func TestStatusVisible(t *testing.T) {
+	statusVisibilityInterval = time.Millisecond
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+
+	var reads int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		reads++
+
+		// Stale read first, then the new status
+		if reads == 1 {
+			_, _ = w.Write([]byte(`[{"context":"ci/some-pipeline","state":"pending"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"context":"ci/some-pipeline","state":"success"},{"context":"ci/some-pipeline","state":"pending"}]`))
+	})
+
+	if visibleAt, err := h.statusVisible(context.Background(), "mrz1836", "codepipeline-to-github", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08",
+		"ci/some-pipeline", githubStateSuccess); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if visibleAt.IsZero() || reads != 2 {
+		t.Fatal("status should have been visible on the second read", reads)
+	}
+
+	// Never visible
+	reads = 0
+	if _, err := h.statusVisible(context.Background(), "mrz1836", "codepipeline-to-github", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08",
+		"ci/other-pipeline", githubStateSuccess); err == nil {
+		t.Fatal("error should have occurred")
+	} else if reads != statusVisibilityAttempts {
+		t.Fatal("reads were not as expected", reads)
+	}
+}
+
+// TestHandlerProcessEventVerifyVisibility will test ProcessEvent() reading the status after the write
+func TestHandlerProcessEventVerifyVisibility(t *testing.T) {
+	statusVisibilityInterval = time.Millisecond
+	h := newTestHandler(Config{
+		GithubAccessToken:      "1234567",
+		GithubMaxConcurrency:   1,
+		Stage:                  stageTesting,
+		VerifyStatusVisibility: true,
+	})
+
+	var reads int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			reads++
+			_, _ = w.Write([]byte(`[{"context":"` + defaultStatusContext + `","state":"success"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: time.Now().Add(-2 * time.Second)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if reads != 1 {
+		t.Fatal("status should have been read once", reads)
+	}
+}
@@ -0,0 +1,164 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Rate limiter defaults
+const (
+	rateLimitKey     = "github"
+	rateLimitMaxWait = 3 * time.Second
+)
+
+// acquireGithubWrite will block until a GitHub write is allowed by the per-container
+// semaphore and (if RATE_LIMIT_TABLE is set) the global token bucket in DynamoDB
+func (h *Handler) acquireGithubWrite(ctx context.Context) (release func(), err error) {
+
+	// Create the semaphore once per container
+	cache := h.deps.cache
+	cache.writeSlotsOnce.Do(func() {
+		size := h.cfg.GithubMaxConcurrency
+		if size <= 0 {
+			size = 1
+		}
+		cache.writeSlots = make(chan struct{}, size)
+	})
+
+	// Wait for a free slot in this container
+	select {
+	case cache.writeSlots <- struct{}{}:
+	case <-time.After(rateLimitMaxWait):
+		return nil, fmt.Errorf("unable to acquire a github write slot within %s", rateLimitMaxWait)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() {
+		<-cache.writeSlots
+	}
+
+	// No global limiter configured
+	if len(h.cfg.RateLimitTable) == 0 {
+		return
+	}
+
+	// Take a token from the global bucket or give back the slot
+	if err = takeToken(ctx,
+		h.deps.DynamoDB, h.cfg.RateLimitTable, h.cfg.RateLimitPerSecond, h.cfg.RateLimitBurst, time.Now().Add(rateLimitMaxWait),
+	); err != nil {
+		release()
+		release = nil
+	}
+	return
+}
+
+// takeToken will remove a single token from the global token bucket, waiting for
+// the bucket to refill until the deadline is reached
+func takeToken(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, perSecond float64, burst int, deadline time.Time) error {
+	for {
+
+		// Get the current state of the bucket
+		now := time.Now()
+		tokens, updatedAt, exists, err := getBucket(ctx, dynamoSvc, table)
+		if err != nil {
+			return err
+		}
+
+		// Refill the bucket based on the time elapsed (a new bucket starts full)
+		if !exists {
+			tokens = float64(burst)
+		} else {
+			tokens = math.Min(float64(burst), tokens+now.Sub(updatedAt).Seconds()*perSecond)
+		}
+
+		// Take the token (another container may have changed the bucket, if so try again)
+		if tokens >= 1 {
+			if err = putBucket(ctx, dynamoSvc, table, tokens-1, now, updatedAt, exists); err == nil {
+				return nil
+			} else if !isConditionalCheckFailed(err) {
+				return err
+			}
+			continue
+		}
+
+		// Wait for the next token if there is time left
+		if perSecond <= 0 {
+			return fmt.Errorf("github rate limit exceeded, bucket is empty and the refill rate is %v", perSecond)
+		}
+		wait := time.Duration((1 - tokens) / perSecond * float64(time.Second))
+		if now.Add(wait).After(deadline) {
+			return fmt.Errorf("github rate limit exceeded, unable to acquire a token within %s", rateLimitMaxWait)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// getBucket will return the stored tokens and last update time of the bucket
+func getBucket(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string) (tokens float64, updatedAt time.Time, exists bool, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"limiter": {S: aws.String(rateLimitKey)},
+		},
+		TableName: aws.String(table),
+	}); err != nil || output == nil || len(output.Item) == 0 {
+		return
+	}
+
+	// Parse the stored values (an item without them was not written by the limiter)
+	for _, attribute := range []string{"tokens", "updated_at"} {
+		if output.Item[attribute] == nil || output.Item[attribute].N == nil {
+			err = fmt.Errorf("invalid rate limit bucket in table %s: missing %s", table, attribute)
+			return
+		}
+	}
+	if tokens, err = strconv.ParseFloat(aws.StringValue(output.Item["tokens"].N), 64); err != nil {
+		return
+	}
+	var nanos int64
+	if nanos, err = strconv.ParseInt(aws.StringValue(output.Item["updated_at"].N), 10, 64); err != nil {
+		return
+	}
+	updatedAt = time.Unix(0, nanos)
+	exists = true
+	return
+}
+
+// putBucket will store the bucket only if nobody else has updated it since it was read
+func putBucket(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, tokens float64, now, previous time.Time, exists bool) (err error) {
+	input := &dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"limiter":    {S: aws.String(rateLimitKey)},
+			"tokens":     {N: aws.String(strconv.FormatFloat(tokens, 'f', -1, 64))},
+			"updated_at": {N: aws.String(strconv.FormatInt(now.UnixNano(), 10))},
+		},
+		TableName: aws.String(table),
+	}
+	if exists {
+		input.ConditionExpression = aws.String("updated_at = :previous")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":previous": {N: aws.String(strconv.FormatInt(previous.UnixNano(), 10))},
+		}
+	} else {
+		input.ConditionExpression = aws.String("attribute_not_exists(limiter)")
+	}
+	_, err = dynamoSvc.PutItemWithContext(ctx, input)
+	return
+}
+
+// isConditionalCheckFailed will return true if the error is a failed DynamoDB condition
+func isConditionalCheckFailed(err error) bool {
+	if aErr, ok := err.(awserr.Error); ok {
+		return aErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+	}
+	return false
+}
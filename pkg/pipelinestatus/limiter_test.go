@@ -0,0 +1,181 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Mocking dynamodb client (single item store and counters)
+type mockDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	conflicts int
+	counts    map[string]int64
+	item      map[string]*dynamodb.AttributeValue
+}
+
+// GetItemWithContext is a mock request for dynamodb
+func (m *mockDynamoClient) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if len(aws.StringValue(input.TableName)) == 0 {
+		return nil, fmt.Errorf("missing table name")
+	}
+
+	// Counters are read by their key
+	if key := input.Key["fingerprint"]; key != nil {
+		if count, ok := m.counts[aws.StringValue(key.S)]; ok {
+			return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+				"count": {N: aws.String(fmt.Sprintf("%d", count))},
+			}}, nil
+		}
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: m.item}, nil
+}
+
+// PutItemWithContext is a mock request for dynamodb
+func (m *mockDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+
+	// Simulate another container updating the bucket first
+	if m.conflicts > 0 {
+		m.conflicts--
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "the conditional request failed", nil)
+	}
+	m.item = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// UpdateItemWithContext is a mock request for dynamodb (increments a counter per key or replaces the item)
+func (m *mockDynamoClient) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if len(aws.StringValue(input.TableName)) == 0 {
+		return nil, fmt.Errorf("missing table name")
+	}
+	if m.counts == nil {
+		m.counts = make(map[string]int64)
+	}
+	var key string
+	for _, value := range input.Key {
+		key += aws.StringValue(value.S)
+	}
+
+	// Set the attributes and return the whole item
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllNew {
+		if m.item == nil {
+			m.item = make(map[string]*dynamodb.AttributeValue)
+		}
+		for placeholder, name := range input.ExpressionAttributeNames {
+			m.item[aws.StringValue(name)] = input.ExpressionAttributeValues[":"+strings.TrimPrefix(placeholder, "#")]
+		}
+		return &dynamodb.UpdateItemOutput{Attributes: m.item}, nil
+	}
+
+	// Replace the stored item and return the old one
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		previous := m.item
+		m.item = make(map[string]*dynamodb.AttributeValue)
+		for name, value := range input.ExpressionAttributeValues {
+			m.item[strings.TrimPrefix(name, ":")] = value
+		}
+		return &dynamodb.UpdateItemOutput{Attributes: previous}, nil
+	}
+	m.counts[key]++
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+		"count": {N: aws.String(fmt.Sprintf("%d", m.counts[key]))},
+	}}, nil
+}
+
+// QueryPagesWithContext is a mock request for dynamodb (returns the stored item)
+func (m *mockDynamoClient) QueryPagesWithContext(_ aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool,
+	_ ...request.Option) error {
+	if len(aws.StringValue(input.TableName)) == 0 {
+		return fmt.Errorf("missing table name")
+	}
+	var items []map[string]*dynamodb.AttributeValue
+	if m.item != nil {
+		items = append(items, m.item)
+	}
+	fn(&dynamodb.QueryOutput{Items: items}, true)
+	return nil
+}
+
+// TestTakeToken will test takeToken()
+func TestTakeToken(t *testing.T) {
+	t.Parallel()
+
+	t.Run("new bucket starts full", func(t *testing.T) {
+		mockDynamo := &mockDynamoClient{}
+		for i := 0; i < 3; i++ {
+			if err := takeToken(context.Background(), mockDynamo, "limits", 0.001, 3, time.Now()); err != nil {
+				t.Fatal("error occurred taking token", i, err.Error())
+			}
+		}
+		if err := takeToken(context.Background(), mockDynamo, "limits", 0.001, 3, time.Now()); err == nil {
+			t.Fatal("error should have occurred, bucket is empty")
+		}
+	})
+
+	t.Run("retry on conflict", func(t *testing.T) {
+		mockDynamo := &mockDynamoClient{conflicts: 2}
+		if err := takeToken(context.Background(), mockDynamo, "limits", 1, 5, time.Now()); err != nil {
+			t.Fatal("error occurred", err.Error())
+		} else if aws.StringValue(mockDynamo.item["tokens"].N) != "4" {
+			t.Fatal("tokens value was not as expected", aws.StringValue(mockDynamo.item["tokens"].N))
+		}
+	})
+
+	t.Run("waits for refill", func(t *testing.T) {
+		mockDynamo := &mockDynamoClient{}
+		if err := takeToken(context.Background(), mockDynamo, "limits", 50, 1, time.Now()); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+		if err := takeToken(context.Background(), mockDynamo, "limits", 50, 1, time.Now().Add(time.Second)); err != nil {
+			t.Fatal("error occurred waiting for refill", err.Error())
+		}
+	})
+
+	t.Run("no refill rate", func(t *testing.T) {
+		mockDynamo := &mockDynamoClient{}
+		_ = takeToken(context.Background(), mockDynamo, "limits", 0, 1, time.Now())
+		if err := takeToken(context.Background(), mockDynamo, "limits", 0, 1, time.Now().Add(time.Second)); err == nil {
+			t.Fatal("error should have occurred")
+		}
+	})
+
+	t.Run("invalid bucket", func(t *testing.T) {
+		for _, item := range []map[string]*dynamodb.AttributeValue{
+			{"limiter": {S: aws.String(rateLimitKey)}, "updated_at": {N: aws.String("1")}},
+			{"limiter": {S: aws.String(rateLimitKey)}, "tokens": {N: aws.String("1")}},
+			{"limiter": {S: aws.String(rateLimitKey)}, "tokens": {S: aws.String("1")}, "updated_at": {N: aws.String("1")}},
+		} {
+			if err := takeToken(context.Background(), &mockDynamoClient{item: item}, "limits", 1, 1, time.Now()); err == nil {
+				t.Fatal("error should have occurred", item)
+			} else if !strings.Contains(err.Error(), "missing") {
+				t.Fatal("error was not as expected", err.Error())
+			}
+		}
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		if err := takeToken(context.Background(), &mockDynamoClient{}, "", 1, 1, time.Now()); err == nil {
+			t.Fatal("error should have occurred")
+		}
+	})
+}
+
+// TestIsConditionalCheckFailed will test isConditionalCheckFailed()
+func TestIsConditionalCheckFailed(t *testing.T) {
+	t.Parallel()
+
+	if !isConditionalCheckFailed(awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "failed", nil)) {
+		t.Fatal("expected a conditional check failure")
+	} else if isConditionalCheckFailed(fmt.Errorf("some other error")) {
+		t.Fatal("did not expect a conditional check failure")
+	}
+}
@@ -0,0 +1,327 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Log levels (LOG_LEVEL), lines below the level are not written
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+)
+
+// logLevels are the severities of the log levels
+var logLevels = map[string]int{
+	logLevelDebug: 0,
+	logLevelInfo:  1,
+	logLevelWarn:  2,
+	logLevelError: 3,
+}
+
+// logSampleBuffer is the most info and debug lines held for an event left out of the sample (LOG_SAMPLE_RATE)
+const logSampleBuffer = 200
+
+// logSampleRandom picks the events kept in the sample (replaced in tests)
+var logSampleRandom = rand.Float64
+
+// redactedText is written in place of the tokens in the log lines
+const redactedText = "[REDACTED]"
+
+// logTokens matches the GitHub tokens and the credentials of authorization headers
+var logTokens = regexp.MustCompile(`\b(gh[opsru]_[A-Za-z0-9]{16,}|github_pat_[A-Za-z0-9_]{16,})|((?i:bearer|token|basic)\s+)[A-Za-z0-9._~+/=-]{8,}`)
+
+// Per-container log output (replaced in tests)
+var (
+	logMu     sync.Mutex
+	logOutput io.Writer = os.Stdout
+)
+
+// logSettings are the log settings of a handler: the level of its configuration (LOG_LEVEL) and the secrets of its
+// configuration to redact, carried by the context of its events (handlers with different configurations can share
+// the container)
+type logSettings struct {
+	level    string
+	replacer *strings.Replacer
+}
+
+// logSettingsKey is the context key of the log settings
+type logSettingsKey struct{}
+
+// defaultLogSettings are used outside the events of a handler
+var defaultLogSettings = &logSettings{level: logLevelInfo}
+
+// logFields are the fields shared by every log line of an event, so the lines of an execution can be
+// queried together (IE: filter execution_id = "..." in CloudWatch Logs Insights)
+type logFields struct {
+	Commit      string
+	ExecutionID string
+	Pipeline    string
+	Repo        string
+	RequestID   string
+}
+
+// logFieldsKey is the context key of the log fields
+type logFieldsKey struct{}
+
+// logSample holds the info and debug lines of an event left out of the sample (LOG_SAMPLE_RATE), they are
+// only written if the event logs a warning or an error (the rest of the event is then written in full)
+type logSample struct {
+	lines   [][]byte
+	mu      sync.Mutex
+	sampled bool
+}
+
+// logSampleKey is the context key of the log sample of an event
+type logSampleKey struct{}
+
+// String will return the non-empty fields as key=value pairs
+func (f logFields) String() string {
+	var pairs []string
+	for _, field := range f.pairs() {
+		pairs = append(pairs, field[0]+"="+field[1])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// pairs will return the non-empty fields by name
+func (f logFields) pairs() (pairs [][2]string) {
+	for _, field := range [][2]string{
+		{"execution_id", f.ExecutionID},
+		{"pipeline", f.Pipeline},
+		{"commit", f.Commit},
+		{"repo", f.Repo},
+		{"request_id", f.RequestID},
+	} {
+		if len(field[1]) > 0 {
+			pairs = append(pairs, field)
+		}
+	}
+	return
+}
+
+// eventLogFields will return the log fields of an event (the execution is the build or the deployment of
+// CodeBuild and CodeDeploy events)
+func eventLogFields(ctx context.Context, ev Event) logFields {
+	fields := logFieldsFromContext(ctx)
+	if ev.Detail == nil {
+		return fields
+	}
+	fields.ExecutionID, fields.Pipeline = ev.Detail.ExecutionID, ev.Detail.Pipeline
+	if len(ev.Detail.BuildID) > 0 {
+		fields.ExecutionID = ev.Detail.BuildID
+	} else if len(ev.Detail.DeploymentID) > 0 {
+		fields.ExecutionID = ev.Detail.DeploymentID
+	} else if ev.Detail.RequestParameters != nil {
+		fields.Pipeline = ev.Detail.RequestParameters.PipelineName
+	}
+	return fields
+}
+
+// withLogFields will return a context carrying the log fields
+func withLogFields(ctx context.Context, fields logFields) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// withLogCommit will return a context carrying the log fields with the commit and the repository once resolved
+func withLogCommit(ctx context.Context, owner, repo, commit string) context.Context {
+	fields := logFieldsFromContext(ctx)
+	fields.Commit, fields.Repo = commit, owner+"/"+repo
+	return withLogFields(ctx, fields)
+}
+
+// logFieldsFromContext will return the log fields of the context (only the request id of the Lambda
+// invocation if the event is not known yet)
+func logFieldsFromContext(ctx context.Context) logFields {
+	if ctx == nil {
+		return logFields{}
+	} else if fields, ok := ctx.Value(logFieldsKey{}).(logFields); ok {
+		return fields
+	}
+	var fields logFields
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		fields.RequestID = lc.AwsRequestID
+	}
+	return fields
+}
+
+// withLogSample will return a context sampling the info and debug lines of an event at the rate
+// (1 writes every line, 0 only the lines of the events with a warning or an error)
+func withLogSample(ctx context.Context, rate float64) context.Context {
+	if rate >= 1 {
+		return ctx
+	}
+	return context.WithValue(ctx, logSampleKey{}, &logSample{sampled: logSampleRandom() < rate})
+}
+
+// hold will return the lines to write: the line of a sampled event, nothing while an event out of the sample
+// only logs info and debug lines (held), or the held lines and the line once the event logs a warning or an error
+func (s *logSample) hold(level string, line []byte) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sampled {
+		return [][]byte{line}
+	} else if logLevels[level] < logLevels[logLevelWarn] {
+		if len(s.lines) < logSampleBuffer {
+			s.lines = append(s.lines, line)
+		}
+		return nil
+	}
+	s.sampled = true
+	lines := append(s.lines, line)
+	s.lines = nil
+	return lines
+}
+
+// newLogSettings will return the log settings of a configuration: the level (LOG_LEVEL, info if not set) and the
+// secrets of the configuration (IE: the tokens) to redact from the log lines
+func newLogSettings(level string, secrets ...string) (*logSettings, error) {
+	level = strings.ToLower(level)
+	if len(level) == 0 {
+		level = logLevelInfo
+	} else if _, ok := logLevels[level]; !ok {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %s (debug, info, warn or error)", level)
+	}
+	settings := &logSettings{level: level}
+
+	// The longest secrets are replaced first (a secret can contain another one), the replacer is built once
+	unique := make(map[string]bool)
+	var sorted []string
+	for _, secret := range secrets {
+		if len(secret) >= 8 && !unique[secret] {
+			unique[secret] = true
+			sorted = append(sorted, secret)
+		}
+	}
+	if len(sorted) == 0 {
+		return settings, nil
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j]) || (len(sorted[i]) == len(sorted[j]) && sorted[i] < sorted[j])
+	})
+	var pairs []string
+	for _, secret := range sorted {
+		pairs = append(pairs, secret, redactedText)
+	}
+	settings.replacer = strings.NewReplacer(pairs...)
+	return settings, nil
+}
+
+// withLogSettings will return a context carrying the log settings
+func withLogSettings(ctx context.Context, settings *logSettings) context.Context {
+	return context.WithValue(ctx, logSettingsKey{}, settings)
+}
+
+// logSettingsFromContext will return the log settings of the context (the defaults outside the events of a handler)
+func logSettingsFromContext(ctx context.Context) *logSettings {
+	if ctx != nil {
+		if settings, ok := ctx.Value(logSettingsKey{}).(*logSettings); ok && settings != nil {
+			return settings
+		}
+	}
+	return defaultLogSettings
+}
+
+// redactLog will replace the tokens and the secrets of the configuration of the context in a log message
+func redactLog(ctx context.Context, message string) string {
+	message = logTokens.ReplaceAllString(message, "$2"+redactedText)
+	if replacer := logSettingsFromContext(ctx).replacer; replacer != nil {
+		message = replacer.Replace(message)
+	}
+	return message
+}
+
+// logJSON will write a log line (JSON) with the log fields of the context and the extra fields,
+// skipped below LOG_LEVEL (and held for the events out of the sample)
+func logJSON(ctx context.Context, level, message string, extra map[string]interface{}) {
+	if logLevels[level] < logLevels[logSettingsFromContext(ctx).level] {
+		return
+	}
+
+	line := make(map[string]interface{}, len(extra)+8)
+	for key, value := range extra {
+		line[key] = value
+	}
+	for _, field := range logFieldsFromContext(ctx).pairs() {
+		line[field[0]] = field[1]
+	}
+	line["level"] = level
+	line["message"] = redactLog(ctx, strings.TrimSpace(message))
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": logLevelError, "message": "unable to encode the log line: " + err.Error()})
+	}
+
+	lines := [][]byte{b}
+	if ctx != nil {
+		if sample, ok := ctx.Value(logSampleKey{}).(*logSample); ok {
+			lines = sample.hold(level, b)
+		}
+	}
+
+	logMu.Lock()
+	for _, l := range lines {
+		_, _ = logOutput.Write(append(l, '\n'))
+	}
+	logMu.Unlock()
+}
+
+// logDebugf will write a debug line with the log fields of the context
+func logDebugf(ctx context.Context, format string, args ...interface{}) {
+	logJSON(ctx, logLevelDebug, fmt.Sprintf(format, args...), nil)
+}
+
+// logf will write an info line with the log fields of the context
+func logf(ctx context.Context, format string, args ...interface{}) {
+	logJSON(ctx, logLevelInfo, fmt.Sprintf(format, args...), nil)
+}
+
+// logWarnf will write a warning line (a failed optional step) with the log fields of the context
+func logWarnf(ctx context.Context, format string, args ...interface{}) {
+	logJSON(ctx, logLevelWarn, fmt.Sprintf(format, args...), nil)
+}
+
+// logErrorf will write an error line with the log fields of the context
+func logErrorf(ctx context.Context, format string, args ...interface{}) {
+	logJSON(ctx, logLevelError, fmt.Sprintf(format, args...), nil)
+}
+
+// logExternalCall will write the latency of a call to an external service (debug, errors are warnings)
+func logExternalCall(ctx context.Context, service, operation string, statusCode int, latency time.Duration, err error) {
+	level, extra := logLevelDebug, map[string]interface{}{
+		"latency_ms": latency.Milliseconds(),
+		"operation":  operation,
+		"service":    service,
+	}
+	if statusCode > 0 {
+		extra["status_code"] = statusCode
+	}
+	if err != nil {
+		level, extra["error"] = logLevelWarn, redactLog(ctx, err.Error())
+	}
+	logJSON(ctx, level, service+" "+operation, extra)
+}
+
+// logAWSRequest is a handler of the AWS session logging the latency of each AWS call
+func logAWSRequest(r *request.Request) {
+	var statusCode int
+	if r.HTTPResponse != nil {
+		statusCode = r.HTTPResponse.StatusCode
+	}
+	logExternalCall(r.Context(), r.ClientInfo.ServiceName, r.Operation.Name, statusCode, time.Since(r.Time), r.Error)
+}
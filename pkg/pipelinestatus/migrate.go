@@ -0,0 +1,160 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// migrationReport is the result of migrating the legacy statuses of a pipeline's repository
+type migrationReport struct {
+	Branch            string           `json:"branch"`
+	Commits           []migratedCommit `json:"commits"`
+	Context           string           `json:"context"`
+	ProtectionUpdates []string         `json:"protection_updates"`
+	Repository        string           `json:"repository"`
+}
+
+// migratedCommit is a commit with statuses under legacy contexts
+type migratedCommit struct {
+	LegacyContexts []string `json:"legacy_contexts"`
+	SHA            string   `json:"sha"`
+	State          string   `json:"state"` // replayed pipeline state (empty if the pipeline never ran the commit)
+}
+
+// combinedStatus is the combined status of a commit
+type combinedStatus struct {
+	Statuses []payload `json:"statuses"`
+}
+
+// requiredStatusChecks are the status checks required by the branch protection
+type requiredStatusChecks struct {
+	Contexts []string `json:"contexts"`
+}
+
+// executionStates will return the state of the latest execution of each commit (of the given commits)
+func (h *Handler) executionStates(ctx context.Context, pipelineName string,
+	commits map[string]bool) (states map[string]string, err error) {
+	states = make(map[string]string)
+	paginator := codepipeline.NewListPipelineExecutionsPaginator(h.deps.CodePipeline,
+		&codepipeline.ListPipelineExecutionsInput{PipelineName: aws.String(pipelineName)})
+	for paginator.HasMorePages() && len(states) < len(commits) {
+		var page *codepipeline.ListPipelineExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, summary := range page.PipelineExecutionSummaries {
+			for _, revision := range summary.SourceRevisions {
+				sha := aws.StringValue(revision.RevisionId)
+				if _, ok := states[sha]; ok || !commits[sha] {
+					continue
+				}
+				states[sha] = getStatus(&codepipeline.GetPipelineExecutionOutput{
+					PipelineExecution: &types.PipelineExecution{Status: summary.Status},
+				})
+			}
+		}
+	}
+	return
+}
+
+// migrateStatuses will find the recent commits of the pipeline's branch with statuses under the legacy
+// contexts, replay the pipeline state of those commits under the pipeline's context and report the
+// branch protection checks that still require a legacy context (nothing is posted on a dry run)
+func (h *Handler) migrateStatuses(ctx context.Context, pipelineName string, legacyContexts []string, commitCount int,
+	dryRun bool) (report migrationReport, err error) {
+
+	// Find the repository and context of the pipeline
+	var owner, repo string
+	if owner, repo, report.Branch, err = getSourceBranch(ctx, pipelineName, h.deps.CodePipeline); err != nil {
+		return
+	} else if report.Context, err = h.statusContext(ctx, pipelineName, ""); err != nil {
+		return
+	}
+	report.Repository = owner + "/" + repo
+	report.Commits = []migratedCommit{}
+	report.ProtectionUpdates = []string{}
+	legacy := make(map[string]bool)
+	for _, statusCtx := range legacyContexts {
+		legacy[statusCtx] = true
+	}
+
+	// Find the recent commits with legacy statuses
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits?sha=%s&per_page=%d", owner, repo, report.Branch, commitCount), &commits); err != nil {
+		return
+	}
+	migrating := make(map[string]bool)
+	for _, commit := range commits {
+		var status combinedStatus
+		if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/status", owner, repo, commit.SHA), &status); err != nil {
+			return
+		}
+		migrated := migratedCommit{LegacyContexts: []string{}, SHA: commit.SHA}
+		for _, s := range status.Statuses {
+			if legacy[s.Context] {
+				migrated.LegacyContexts = append(migrated.LegacyContexts, s.Context)
+			}
+		}
+		if len(migrated.LegacyContexts) > 0 {
+			sort.Strings(migrated.LegacyContexts)
+			report.Commits = append(report.Commits, migrated)
+			migrating[commit.SHA] = true
+		}
+	}
+
+	// Replay the pipeline state of the commits
+	if len(migrating) > 0 {
+		var states map[string]string
+		if states, err = h.executionStates(ctx, pipelineName, migrating); err != nil {
+			return
+		}
+		for i, commit := range report.Commits {
+			if report.Commits[i].State = states[commit.SHA]; len(report.Commits[i].State) == 0 || dryRun {
+				continue
+			}
+			if err = h.postMigratedStatus(ctx, owner, repo, commit.SHA, report.Context, report.Commits[i].State, pipelineName); err != nil {
+				return
+			}
+		}
+	}
+
+	// Branch protection checks that need to be updated (no protection if not found)
+	var checks requiredStatusChecks
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/branches/%s/protection/required_status_checks", owner, repo, report.Branch), &checks); isGithubNotFound(err) {
+		err = nil
+	} else if err != nil {
+		return
+	}
+	for _, statusCtx := range checks.Contexts {
+		if legacy[statusCtx] {
+			report.ProtectionUpdates = append(report.ProtectionUpdates, fmt.Sprintf("replace %s with %s", statusCtx, report.Context))
+		}
+	}
+	sort.Strings(report.ProtectionUpdates)
+	return
+}
+
+// postMigratedStatus will post the replayed pipeline state of a commit
+func (h *Handler) postMigratedStatus(ctx context.Context, owner, repo, commit, statusCtx, state, pipelineName string) error {
+	req, err := h.newGithubRequest(
+		ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", owner, repo, commit), &payload{
+			Context:     statusCtx,
+			Description: joinDescription("migrated from the legacy status"),
+			State:       state,
+			TargetURL: consoleURL(configPartition(h.cfg), h.cfg.AWSRegion, fmt.Sprintf(
+				"/codesuite/codepipeline/pipelines/%s/view", pipelineName)),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusCreated, nil)
+}
@@ -0,0 +1,129 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Commits of the migrated repository
+const (
+	migrateCommitBuilt  = "1111111111111111111111111111111111111111"
+	migrateCommitLegacy = "2222222222222222222222222222222222222222"
+	migrateCommitNew    = "3333333333333333333333333333333333333333"
+)
+
+// mockMigratePipelineClient is a pipeline that ran some of the migrated commits
+type mockMigratePipelineClient struct {
+	mockCodePipelineClient
+}
+
+// ListPipelineExecutions is a mock request for codepipeline (newest first)
+func (m *mockMigratePipelineClient) ListPipelineExecutions(_ context.Context, input *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{
+			SourceRevisions: []types.SourceRevision{{RevisionId: aws.String(migrateCommitBuilt)}},
+			Status:          types.PipelineExecutionStatusSucceeded,
+		},
+		{
+			SourceRevisions: []types.SourceRevision{{RevisionId: aws.String(migrateCommitBuilt)}},
+			Status:          types.PipelineExecutionStatusFailed,
+		},
+	}}, nil
+}
+
+// newMigrateServer will start a fake GitHub API with legacy statuses and return the posted statuses
+func newMigrateServer(t *testing.T, h *Handler) map[string]payload {
+	posted := make(map[string]payload)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		repoPath := "/repos/mrz1836/codepipeline-to-github"
+		switch {
+		case r.URL.Path == repoPath+"/commits":
+			_, _ = w.Write([]byte(`[{"sha":"` + migrateCommitNew + `"},{"sha":"` + migrateCommitBuilt + `"},{"sha":"` + migrateCommitLegacy + `"}]`))
+		case r.URL.Path == repoPath+"/commits/"+migrateCommitNew+"/status":
+			_, _ = w.Write([]byte(`{"statuses":[{"context":"continuous-integration/codepipeline","state":"success"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/status"):
+			_, _ = w.Write([]byte(`{"statuses":[{"context":"ci/jenkins","state":"success"},{"context":"ci/travis","state":"failure"}]}`))
+		case r.URL.Path == repoPath+"/branches/master/protection/required_status_checks":
+			_, _ = w.Write([]byte(`{"contexts":["ci/jenkins","security/scan"]}`))
+		case r.Method == http.MethodPost:
+			var status payload
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			posted[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = status
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return posted
+}
+
+// TestMigrateStatuses will test migrateStatuses()
+func TestMigrateStatuses(t *testing.T) {
+	h := newTestHandler(Config{AWSRegion: "us-east-1"})
+	h.deps.CodePipeline = &mockMigratePipelineClient{}
+	posted := newMigrateServer(t, h)
+
+	// Dry run
+	report, err := h.migrateStatuses(context.Background(), "some-pipeline", []string{"ci/jenkins", "ci/travis"}, 20, true)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(posted) != 0 {
+		t.Fatal("nothing should have been posted", posted)
+	} else if report.Repository != "mrz1836/codepipeline-to-github" || report.Branch != "master" || report.Context != defaultStatusContext {
+		t.Fatal("report was not as expected", report)
+	} else if len(report.Commits) != 2 || report.Commits[0].SHA != migrateCommitBuilt || report.Commits[1].SHA != migrateCommitLegacy {
+		t.Fatal("commits were not as expected", report.Commits)
+	} else if report.Commits[0].State != githubStateSuccess || len(report.Commits[1].State) != 0 {
+		t.Fatal("replayed states were not as expected", report.Commits)
+	} else if len(report.Commits[0].LegacyContexts) != 2 {
+		t.Fatal("legacy contexts were not as expected", report.Commits[0].LegacyContexts)
+	} else if len(report.ProtectionUpdates) != 1 || report.ProtectionUpdates[0] != "replace ci/jenkins with "+defaultStatusContext {
+		t.Fatal("protection updates were not as expected", report.ProtectionUpdates)
+	}
+
+	// Replay the statuses
+	if _, err = h.migrateStatuses(context.Background(), "some-pipeline", []string{"ci/jenkins"}, 20, false); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(posted) != 1 || posted[migrateCommitBuilt].State != githubStateSuccess {
+		t.Fatal("posted statuses were not as expected", posted)
+	} else if posted[migrateCommitBuilt].Context != defaultStatusContext {
+		t.Fatal("context was not as expected", posted[migrateCommitBuilt].Context)
+	}
+}
+
+// TestMigrateCommand will test migrateCommand()
+func TestMigrateCommand(t *testing.T) {
+	h := newTestHandler(Config{AWSRegion: "us-east-1"})
+	h.deps.CodePipeline = &mockMigratePipelineClient{}
+	newMigrateServer(t, h)
+
+	var tests = []struct {
+		args          []string
+		expectedError bool
+	}{
+		{[]string{"-legacy", "ci/jenkins"}, true},
+		{[]string{"-pipeline", "some-pipeline"}, true},
+		{[]string{"-pipeline", "some-pipeline", "-legacy", "ci/jenkins", "-commits", "500"}, true},
+		{[]string{"-pipeline", "some-pipeline", "-legacy", "ci/jenkins", "-dry-run"}, false},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := migrateCommand(test.args, &out, h); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, expected to throw an error, but no error", t.Name(), test.args)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, error occurred [%s]", t.Name(), test.args, err.Error())
+		} else if !test.expectedError && !strings.Contains(out.String(), "branch protection: replace ci/jenkins") {
+			t.Errorf("%s Failed: [%v] inputted, output was not as expected [%s]", t.Name(), test.args, out.String())
+		}
+	}
+}
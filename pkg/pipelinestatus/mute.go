@@ -0,0 +1,70 @@
+package pipelinestatus
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// muteWindow is a period during which the statuses of a pipeline are not posted
+// (muted_until is also the TTL attribute of the table)
+type muteWindow struct {
+	Pipeline string    `dynamodbav:"pipeline" json:"pipeline"`
+	Reason   string    `dynamodbav:"reason,omitempty" json:"reason,omitempty"`
+	Until    time.Time `dynamodbav:"muted_until,unixtime" json:"muted_until"`
+}
+
+// muteStatuses will store the mute window of a pipeline (a window ending now unmutes the pipeline)
+func muteStatuses(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, window muteWindow) (err error) {
+	var item map[string]*dynamodb.AttributeValue
+	if item, err = dynamodbattribute.MarshalMap(window); err != nil {
+		return
+	}
+	_, err = dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(table),
+	})
+	return
+}
+
+// getMuteWindow will return the mute window of a pipeline if it is muted at the time
+func getMuteWindow(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName string, now time.Time) (window *muteWindow, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"pipeline": {S: aws.String(pipelineName)},
+		},
+		TableName: aws.String(table),
+	}); err != nil || output == nil || len(output.Item) == 0 {
+		return
+	}
+
+	// Expired windows can remain until the TTL removes them
+	var stored muteWindow
+	if err = dynamodbattribute.UnmarshalMap(output.Item, &stored); err != nil || !stored.Until.After(now) {
+		return
+	}
+	return &stored, nil
+}
+
+// isMuted will return true if the statuses of the pipeline are muted (the mute window is logged, a failed
+// check is not muted)
+func (h *Handler) isMuted(ctx context.Context, pipelineName string) bool {
+	if len(h.cfg.MuteTable) == 0 {
+		return false
+	}
+	window, err := getMuteWindow(ctx, h.deps.DynamoDB, h.cfg.MuteTable, pipelineName, time.Now())
+	if err != nil {
+		logWarnf(ctx, "unable to check the mute window: %s", err.Error())
+		return false
+	} else if window == nil {
+		return false
+	}
+	logf(ctx, "skipping muted pipeline: %s (until %s)", pipelineName, window.Until.Format(time.RFC3339))
+	return true
+}
@@ -0,0 +1,97 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGetMuteWindow will test muteStatuses() and getMuteWindow()
+func TestGetMuteWindow(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Nothing stored
+	if window, err := getMuteWindow(context.Background(), mockDynamo, "mutes", "some-pipeline", now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if window != nil {
+		t.Fatal("pipeline should not be muted", window)
+	}
+
+	// Muted
+	if err := muteStatuses(context.Background(), mockDynamo, "mutes", muteWindow{Pipeline: "some-pipeline", Reason: "refactoring", Until: now.Add(2 * time.Hour)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	if window, err := getMuteWindow(context.Background(), mockDynamo, "mutes", "some-pipeline", now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if window == nil || window.Reason != "refactoring" || !window.Until.Equal(now.Add(2*time.Hour)) {
+		t.Fatal("window was not as expected", window)
+	}
+
+	// Expired window
+	if window, err := getMuteWindow(context.Background(), mockDynamo, "mutes", "some-pipeline", now.Add(3*time.Hour)); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if window != nil {
+		t.Fatal("pipeline should not be muted", window)
+	}
+
+	// Missing table
+	if _, err := getMuteWindow(context.Background(), mockDynamo, "", "some-pipeline", now); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventMuted will test ProcessEvent() for a muted pipeline
+func TestHandlerProcessEventMuted(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, MuteTable: "mutes", Stage: stageTesting})
+	if err := muteStatuses(context.Background(), h.deps.DynamoDB, "mutes", muteWindow{Pipeline: "status-succeed", Until: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	var posted int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posted++
+	})
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posted != 0 {
+		t.Fatal("no status should have been posted", posted)
+	}
+}
+
+// TestMuteCommand will test muteCommand()
+func TestMuteCommand(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", "testing")
+	defer os.Clearenv()
+
+	mockDynamo := &mockDynamoClient{}
+	var tests = []struct {
+		args          []string
+		muteTable     string
+		expectedError bool
+	}{
+		{[]string{"-pipeline", "some-pipeline", "-for", "2h"}, "", true},
+		{[]string{"-for", "2h"}, "mutes", true},
+		{[]string{"-pipeline", "some-pipeline", "-for", "-2h"}, "mutes", true},
+		{[]string{"-pipeline", "some-pipeline", "-for", "2h", "-reason", "refactoring"}, "mutes", false},
+	}
+
+	for _, test := range tests {
+		_ = os.Setenv("MUTE_TABLE", test.muteTable)
+		var out bytes.Buffer
+		if err := muteCommand(test.args, &out, mockDynamo); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, expected to throw an error, but no error", t.Name(), test.args)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, error occurred [%s]", t.Name(), test.args, err.Error())
+		} else if !test.expectedError && !strings.Contains(out.String(), "refactoring") {
+			t.Errorf("%s Failed: [%v] inputted, output was not as expected [%s]", t.Name(), test.args, out.String())
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Notification defaults
+const (
+	defaultNotificationTemplate = "{{.Owner}}/{{.Repo}}@{{shortSHA .Commit}}{{if .Author}} by {{.Author}}{{end}}{{if .Description}}: {{.Description}}{{end}}"
+	metricNotificationFailure   = "NotificationFailure"
+	metricNotificationSuccess   = "NotificationSuccess"
+)
+
+// notifier is an optional backend (IE: Slack) that receives messages next to the GitHub statuses
+type notifier interface {
+	Name() string
+	Notify(ctx context.Context, message notification) error
+}
+
+// notification is a message for the notifiers, pipeline notifications also have the GitHub state
+// (used for the color), a title and a link to the execution
+type notification struct {
+	Pipeline string
+	State    string
+	Text     string
+	Title    string
+	URL      string
+}
+
+// slackNotifier sends messages to a Slack incoming webhook
+type slackNotifier struct {
+	client     HTTPClient
+	webhookURL string
+}
+
+// Name will return the name of the notifier (used in logs and metrics)
+func (s *slackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify will send the message to Slack (pipeline notifications are color coded attachments)
+func (s *slackNotifier) Notify(ctx context.Context, message notification) error {
+	return postSlackMessage(ctx, s.client, s.webhookURL, newSlackMessage(message))
+}
+
+// notifiers will return the notifiers enabled in the configuration
+func (h *Handler) notifiers() (list []notifier) {
+	if len(h.cfg.ShadowMode) > 0 {
+		return // staging copies stay quiet
+	}
+	if len(h.cfg.SlackWebhookURL) > 0 {
+		list = append(list, &slackNotifier{client: h.deps.Slack, webhookURL: h.cfg.SlackWebhookURL})
+	}
+	if len(h.cfg.TeamsWebhookURL) > 0 || len(h.cfg.TeamsWebhooks) > 0 {
+		list = append(list, &teamsNotifier{client: h.deps.Teams, webhookURL: h.cfg.TeamsWebhookURL, webhooks: h.cfg.TeamsWebhooks})
+	}
+	return
+}
+
+// notify will send a text message to every notifier (IE: warnings)
+func (h *Handler) notify(text string) map[string]error {
+	return h.notifyMessage(context.Background(), notification{Text: text})
+}
+
+// notifyMessage will send the message to every notifier at the same time, each with its own timeout, so a
+// failing backend never blocks or fails the GitHub status (errors are logged and returned per notifier),
+// messages of a pipeline only go to the notifiers of its severity
+func (h *Handler) notifyMessage(ctx context.Context, message notification) map[string]error {
+	var list []notifier
+	for _, n := range h.notifiers() {
+		if len(message.Pipeline) == 0 || h.severityNotifies(message.Pipeline, n.Name()) {
+			list = append(list, n)
+		}
+	}
+	results := make(map[string]error, len(list))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, n := range list {
+		wg.Add(1)
+		go func(n notifier) {
+			defer wg.Done()
+			err := runNotifier(n, h.cfg.NotifierTimeout, message)
+
+			// Capture the result of the notifier
+			now := time.Now()
+			dimensions := map[string]string{"Notifier": n.Name()}
+			if err != nil {
+				logWarnf(ctx, "unable to notify %s: %s", n.Name(), err.Error())
+				printMetric(metricNotificationFailure, 1, "Count", dimensions, now)
+			} else {
+				printMetric(metricNotificationSuccess, 1, "Count", dimensions, now)
+			}
+			mu.Lock()
+			results[n.Name()] = err
+			mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	return results
+}
+
+// runNotifier will send the message with a timeout and recover from a panicking notifier
+func runNotifier(n notifier, timeout time.Duration, message notification) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("notifier %s panicked: %v", n.Name(), r)
+		}
+	}()
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return n.Notify(ctx, message)
+}
+
+// commitAuthor is the part of the GitHub commit response with the author (the login if linked to an account)
+type commitAuthor struct {
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Commit struct {
+		Author struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// getCommitAuthor will return the login (or the git name) of the author of the commit
+func (h *Handler) getCommitAuthor(ctx context.Context, owner, repo, commit string) (string, error) {
+	var result commitAuthor
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, commit), &result); err != nil {
+		return "", err
+	} else if result.Author != nil && len(result.Author.Login) > 0 {
+		return result.Author.Login, nil
+	}
+	return result.Commit.Author.Name, nil
+}
+
+// notifyPipeline will send the status of a pipeline to the notifiers if its state is in NOTIFY_STATES
+// (the text is rendered with NOTIFICATION_TEMPLATE), unless the pipeline turns them off (PIPELINE_CONFIG)
+func (h *Handler) notifyPipeline(ctx context.Context, data templateData) {
+	if len(h.notifiers()) == 0 || !h.pipelineNotifies(data.Pipeline) {
+		return
+	}
+	notify := false
+	for _, state := range h.cfg.NotifyStates {
+		notify = notify || state == data.State
+	}
+	if !notify {
+		return
+	}
+
+	// Find the author of the commit (on GitHub)
+	var err error
+	if data.Forge == forgeGithub {
+		if data.Author, err = h.getCommitAuthor(ctx, data.Owner, data.Repo, data.Commit); err != nil {
+			logWarnf(ctx, "unable to get the commit author: %s", err.Error())
+		}
+	}
+
+	// Render the message
+	text := h.cfg.NotificationTemplate
+	if len(text) == 0 {
+		text = defaultNotificationTemplate
+	}
+	if text, err = renderTemplate("notification", text, data, TemplateFuncs(h.cfg.TemplateEnvAllowlist)); err != nil {
+		logWarnf(ctx, "unable to render the notification: %s", err.Error())
+		return
+	}
+	h.notifyMessage(ctx, notification{
+		Pipeline: data.Pipeline,
+		State:    data.State,
+		Text:     text,
+		Title:    data.Pipeline + ": " + data.State,
+		URL:      data.TargetURL,
+	})
+}
@@ -0,0 +1,174 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mockNotifier is a notifier with a fixed behavior
+type mockNotifier struct {
+	delay time.Duration
+	err   error
+	name  string
+	panic bool
+}
+
+// Name will return the name of the mock notifier
+func (m *mockNotifier) Name() string {
+	return m.name
+}
+
+// Notify will wait for the delay (or the timeout) and return the error
+func (m *mockNotifier) Notify(ctx context.Context, message notification) error {
+	if m.panic {
+		panic("notifier is broken")
+	}
+	select {
+	case <-time.After(m.delay):
+		return m.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TestRunNotifier will test runNotifier()
+func TestRunNotifier(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		notifier      *mockNotifier
+		expectedError bool
+	}{
+		{&mockNotifier{name: "ok"}, false},
+		{&mockNotifier{name: "failing", err: errors.New("service unavailable")}, true},
+		{&mockNotifier{name: "slow", delay: time.Second}, true},
+		{&mockNotifier{name: "panic", panic: true}, true},
+	}
+
+	for _, test := range tests {
+		if err := runNotifier(test.notifier, 20*time.Millisecond, notification{Text: "hello"}); err == nil && test.expectedError {
+			t.Errorf("%s Failed: notifier [%s], expected to throw an error, but no error", t.Name(), test.notifier.name)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: notifier [%s], error occurred [%s]", t.Name(), test.notifier.name, err.Error())
+		}
+	}
+}
+
+// TestNotify will test notify()
+func TestNotify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// No notifiers configured
+	h := newTestHandler(Config{NotifierTimeout: 20 * time.Millisecond})
+	if results := h.notify("hello"); len(results) != 0 {
+		t.Fatal("no notifiers should have run", results)
+	}
+
+	// Slack times out without blocking for the full request
+	h.cfg.SlackWebhookURL = server.URL
+	start := time.Now()
+	results := h.notify("hello")
+	if results["slack"] == nil {
+		t.Fatal("slack should have timed out", results)
+	} else if time.Since(start) >= 100*time.Millisecond {
+		t.Fatal("notify should not wait for the slow notifier", time.Since(start))
+	}
+}
+
+// TestPostSlackMessage will test postSlackMessage()
+func TestPostSlackMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("invalid_token"))
+		}
+	}))
+	defer server.Close()
+
+	if err := postSlackMessage(context.Background(), http.DefaultClient, server.URL, &slackMessage{Text: "hello"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	if err := postSlackMessage(context.Background(), http.DefaultClient, server.URL+"/invalid", &slackMessage{Text: "hello"}); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "unexpected response from Slack, code: 403 body: invalid_token" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+}
+
+// TestNewSlackMessage will test newSlackMessage()
+func TestNewSlackMessage(t *testing.T) {
+	t.Parallel()
+
+	if message := newSlackMessage(notification{Text: "token expires soon"}); message.Text != "token expires soon" || len(message.Attachments) != 0 {
+		t.Fatal("warning message was not as expected", message)
+	}
+
+	message := newSlackMessage(notification{State: githubStateFailure, Text: "mrz1836/repo@25c0c3e", Title: "web: failure", URL: "https://console.aws.amazon.com"})
+	if len(message.Attachments) != 1 {
+		t.Fatal("attachments were not as expected", message.Attachments)
+	} else if attachment := message.Attachments[0]; attachment.Color != slackColors[githubStateFailure] || attachment.TitleLink != "https://console.aws.amazon.com" {
+		t.Fatal("attachment was not as expected", attachment)
+	} else if attachment.Fallback != "web: failure: mrz1836/repo@25c0c3e" {
+		t.Fatal("fallback was not as expected", attachment.Fallback)
+	}
+}
+
+// TestHandlerProcessEventSlack will test ProcessEvent() notifying Slack of finished executions
+func TestHandlerProcessEventSlack(t *testing.T) {
+	var messages []slackMessage
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer slack.Close()
+
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		NotifyStates:         []string{githubStateFailure, githubStateSuccess},
+		SlackWebhookURL:      slack.URL,
+		Stage:                stageTesting,
+	})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"author":{"login":"mrz1836"},"commit":{"author":{"name":"MrZ"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Pending statuses are not sent
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", State: "STARTED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(messages) != 0 {
+		t.Fatal("no message should have been sent", messages)
+	}
+
+	// Success with the default template
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(messages) != 1 || len(messages[0].Attachments) != 1 {
+		t.Fatal("message was not as expected", messages)
+	} else if attachment := messages[0].Attachments[0]; attachment.Text != "mrz1836/codepipeline-to-github@25c0c3e by mrz1836" {
+		t.Fatal("text was not as expected", attachment.Text)
+	} else if attachment.Title != "status-succeed: success" || attachment.Color != slackColors[githubStateSuccess] {
+		t.Fatal("attachment was not as expected", attachment)
+	}
+
+	// Failure with a custom template
+	h.cfg.NotificationTemplate = "{{.Pipeline}} failed for {{.Author}}"
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(messages) != 2 || messages[1].Attachments[0].Text != "status-fail failed for mrz1836" {
+		t.Fatal("message was not as expected", messages)
+	}
+}
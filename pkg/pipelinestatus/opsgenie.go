@@ -0,0 +1,133 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// OpsGenie alerts (one open alert per pipeline and stage)
+const (
+	defaultOpsgenieAPIURL = "https://api.opsgenie.com"
+	opsgenieAliasPrefix   = "codepipeline-to-github-"
+	opsgenieMessageLength = 130
+	opsgenieSource        = "codepipeline-to-github"
+)
+
+// opsgenieAlert is the payload that creates an alert
+type opsgenieAlert struct {
+	Alias       string              `json:"alias"`
+	Description string              `json:"description,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+	Message     string              `json:"message"`
+	Priority    string              `json:"priority,omitempty"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Source      string              `json:"source"`
+	Tags        []string            `json:"tags,omitempty"`
+}
+
+// opsgenieResponder is a team that is routed the alert
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// opsgenieClose is the payload that closes an alert
+type opsgenieClose struct {
+	Note   string `json:"note,omitempty"`
+	Source string `json:"source"`
+}
+
+// opsgenieAlias will return the deduplication key of a pipeline or a stage of the pipeline (a fingerprint,
+// so repeated failures update the open alert and the recovery closes it)
+func opsgenieAlias(pipelineName, stage string) string {
+	sum := sha256.Sum256([]byte(pipelineName + "/" + stage))
+	return opsgenieAliasPrefix + hex.EncodeToString(sum[:])
+}
+
+// alertOpsgenie will create an alert for a failed pipeline or stage (an empty stage) and close it on
+// the recovery, pending states are ignored (skipped without OPSGENIE_API_KEY, in shadow mode and for
+// informational pipelines)
+func (h *Handler) alertOpsgenie(ctx context.Context, pipelineName, stage, state, description, targetURL string) error {
+	if len(h.cfg.OpsgenieAPIKey) == 0 || len(h.cfg.ShadowMode) > 0 {
+		return nil
+	}
+	name := pipelineName
+	if len(stage) > 0 {
+		name += "/" + stage
+	}
+	alias := opsgenieAlias(pipelineName, stage)
+
+	switch state {
+	case githubStateError, githubStateFailure:
+		if !h.severityPages(pipelineName) {
+			return nil
+		}
+		alert := &opsgenieAlert{
+			Alias:       alias,
+			Description: description,
+			Details:     map[string]string{"pipeline": pipelineName, "state": state, "url": targetURL},
+			Message:     name + ": " + state,
+			Priority:    opsgeniePriorities[h.pipelineSeverity(pipelineName)],
+			Source:      opsgenieSource,
+			Tags:        []string{"codepipeline", pipelineName},
+		}
+		if len(alert.Message) > opsgenieMessageLength {
+			alert.Message = alert.Message[:opsgenieMessageLength]
+		}
+		if len(stage) > 0 {
+			alert.Details["stage"] = stage
+		}
+		if team, ok := h.cfg.OpsgenieTeams[pipelineName]; ok && len(team) > 0 {
+			alert.Responders = []opsgenieResponder{{Name: team, Type: "team"}}
+		}
+		return h.opsgenieRequest(ctx, "/v2/alerts", alert, false)
+	case githubStateSuccess:
+		return h.opsgenieRequest(ctx, "/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias",
+			&opsgenieClose{Note: name + " recovered", Source: opsgenieSource}, true)
+	}
+	return nil
+}
+
+// opsgenieRequest will send a request to the Alert API (requests are processed asynchronously and
+// accepted with a 202, a missing alert is not an error when closing)
+func (h *Handler) opsgenieRequest(ctx context.Context, path string, data interface{}, closing bool) error {
+
+	// Encode the payload
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	apiURL := h.cfg.OpsgenieAPIURL
+	if len(apiURL) == 0 {
+		apiURL = defaultOpsgenieAPIURL
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, apiURL+path, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "GenieKey "+h.cfg.OpsgenieAPIKey)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = h.deps.Opsgenie.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusAccepted && (!closing || response.StatusCode != http.StatusNotFound) {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from OpsGenie, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestOpsgenieAlias will test opsgenieAlias()
+func TestOpsgenieAlias(t *testing.T) {
+	t.Parallel()
+
+	alias := opsgenieAlias("web", "Build")
+	if !strings.HasPrefix(alias, opsgenieAliasPrefix) || len(alias) != len(opsgenieAliasPrefix)+64 {
+		t.Fatal("alias was not as expected", alias)
+	} else if alias != opsgenieAlias("web", "Build") {
+		t.Fatal("alias should be stable")
+	} else if alias == opsgenieAlias("web", "") || alias == opsgenieAlias("api", "Build") {
+		t.Fatal("alias should be unique per pipeline and stage")
+	}
+}
+
+// TestAlertOpsgenie will test Handler.alertOpsgenie() opening and closing the alerts
+func TestAlertOpsgenie(t *testing.T) {
+	var alert opsgenieAlert
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/alerts":
+			_ = json.NewDecoder(r.Body).Decode(&alert)
+		case strings.Contains(r.URL.Path, "missing"):
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}}
+	h := newTestHandler(Config{
+		OpsgenieAPIKey: "secret-key",
+		OpsgenieAPIURL: "https://api.eu.opsgenie.com",
+		OpsgenieTeams:  stringMap{"web": "web-oncall"},
+	})
+	h.deps.Opsgenie = client
+
+	// Failures open an alert routed to the team
+	if err := h.alertOpsgenie(context.Background(), "web", "Build", githubStateFailure, "Build failed", "https://console.aws.amazon.com"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || client.requests[0].Header.Get("Authorization") != "GenieKey secret-key" ||
+		client.requests[0].URL.Host != "api.eu.opsgenie.com" {
+		t.Fatal("request was not as expected", client.requests)
+	} else if alert.Alias != opsgenieAlias("web", "Build") || alert.Message != "web/Build: failure" ||
+		alert.Details["stage"] != "Build" || alert.Details["url"] != "https://console.aws.amazon.com" {
+		t.Fatal("alert was not as expected", alert)
+	} else if len(alert.Responders) != 1 || alert.Responders[0].Name != "web-oncall" || alert.Responders[0].Type != "team" {
+		t.Fatal("responders were not as expected", alert.Responders)
+	}
+
+	// Recoveries close the alert by its alias
+	if err := h.alertOpsgenie(context.Background(), "web", "Build", githubStateSuccess, "Build succeeded", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 || client.requests[1].URL.Path != "/v2/alerts/"+opsgenieAlias("web", "Build")+"/close" ||
+		client.requests[1].URL.Query().Get("identifierType") != "alias" {
+		t.Fatal("close request was not as expected", client.requests[1].URL.String())
+	}
+
+	// Pending states are ignored
+	if err := h.alertOpsgenie(context.Background(), "web", "", githubStatePending, "", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 {
+		t.Fatal("pending should not have been sent", len(client.requests))
+	}
+
+	// Disabled
+	h.cfg.OpsgenieAPIKey = ""
+	if err := h.alertOpsgenie(context.Background(), "web", "", githubStateFailure, "", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 {
+		t.Fatal("alert should not have been sent", len(client.requests))
+	}
+}
+
+// TestOpsgenieRequest will test Handler.opsgenieRequest() checking the response
+func TestOpsgenieRequest(t *testing.T) {
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/close") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"Key format is not valid!"}`))
+	}}
+	h := newTestHandler(Config{OpsgenieAPIKey: "invalid"})
+	h.deps.Opsgenie = client
+
+	if err := h.opsgenieRequest(context.Background(), "/v2/alerts", &opsgenieAlert{}, false); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != `unexpected response from OpsGenie, code: 401 body: {"message":"Key format is not valid!"}` {
+		t.Fatal("error was not as expected", err.Error())
+	} else if client.requests[0].URL.Host != "api.opsgenie.com" {
+		t.Fatal("default api url was not used", client.requests[0].URL.String())
+	}
+
+	// Closing an alert that is not open
+	if err := h.opsgenieRequest(context.Background(), "/v2/alerts/missing/close", &opsgenieClose{}, true); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+}
+
+// TestHandlerProcessEventOpsgenie will test ProcessEvent() opening the alert of a failed stage
+func TestHandlerProcessEventOpsgenie(t *testing.T) {
+	var alert opsgenieAlert
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&alert)
+		w.WriteHeader(http.StatusAccepted)
+	}}
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		OpsgenieAPIKey:       "secret-key",
+		Stage:                stageTesting,
+	})
+	h.deps.Opsgenie = client
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Build",
+		State:       "FAILED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 || alert.Alias != opsgenieAlias("status-succeed", "Build") {
+		t.Fatal("alert was not as expected", alert)
+	}
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 || alert.Alias != opsgenieAlias("status-fail", "") || alert.Message != "status-fail: failure" {
+		t.Fatal("alert was not as expected", alert)
+	}
+}
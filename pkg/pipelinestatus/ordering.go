@@ -0,0 +1,117 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Event ordering values (EVENT_ORDER_TABLE), the sequences are fixed width so they compare as strings
+const (
+	eventOrderTimeLayout = "2006-01-02T15:04:05.000000000Z"
+	eventOrderTTL        = 7 * 24 * time.Hour
+)
+
+// eventStateRanks order the states of the same stage (or pipeline) within the same second
+var eventStateRanks = map[string]int{
+	"STARTED":    0,
+	"RESUMED":    1,
+	"STOPPING":   2,
+	"CANCELED":   3,
+	"FAILED":     3,
+	"STOPPED":    3,
+	"SUCCEEDED":  3,
+	"SUPERSEDED": 3,
+}
+
+// eventSequence will return the position of an event in its execution: the time of the event, then the sequence of
+// the stage in the pipeline and the state (the event time only has a resolution of a second, very fast pipelines
+// send several events of the same second)
+func eventSequence(eventTime time.Time, stageSequence int, state string) string {
+	return fmt.Sprintf("%s#%03d#%d", eventTime.UTC().Format(eventOrderTimeLayout), stageSequence, eventStateRanks[state])
+}
+
+// stageSequence will return the sequence of the stage in the pipeline, starting at 1 (GetPipelineState), events of
+// the pipeline itself come before (started) or after (finished) all of its stages
+func (h *Handler) stageSequence(ctx context.Context, pipelineName, stage, state string) (int, error) {
+	output, err := h.deps.CodePipeline.GetPipelineState(ctx, &codepipeline.GetPipelineStateInput{
+		Name: aws.String(pipelineName),
+	})
+	if err != nil {
+		return 0, err
+	} else if output == nil {
+		return 0, fmt.Errorf("missing pipeline state: %s", pipelineName)
+	}
+	if len(stage) == 0 {
+		if finalStates[state] {
+			return len(output.StageStates) + 1, nil
+		}
+		return 0, nil
+	}
+	for i, stageState := range output.StageStates {
+		if aws.StringValue(stageState.StageName) == stage {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown stage %s of pipeline: %s", stage, pipelineName)
+}
+
+// applyEventSequence will store the sequence as the latest applied to the status context of the execution,
+// false if a later event of the context was already applied (the event was delivered out of order). The same
+// sequence applies again, so the retry of an event whose post failed is not dropped (DEDUP_TABLE skips duplicates)
+func applyEventSequence(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, executionID, statusContext, sequence string,
+	now time.Time) (bool, error) {
+	_, err := dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(#context) OR #context <= :sequence"),
+		ExpressionAttributeNames: map[string]*string{
+			"#context": aws.String(statusContext),
+			"#expires": aws.String("expires"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expires":  {N: aws.String(strconv.FormatInt(now.Add(eventOrderTTL).Unix(), 10))},
+			":sequence": {S: aws.String(sequence)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"execution_id": {S: aws.String(executionID)},
+		},
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("SET #context = :sequence, #expires = :expires"),
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// inOrder will return false if the status of the event is stale: a later event of the same status context was
+// already applied, so the status would go back in time (IE: a STARTED delivered after the SUCCEEDED of its stage).
+// Ordering is skipped without EVENT_ORDER_TABLE, and on errors the event is applied
+func (h *Handler) inOrder(ctx context.Context, ev Event, stage, statusContext string) bool {
+	if len(h.cfg.EventOrderTable) == 0 {
+		return true
+	}
+	eventTime := ev.Time
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	sequence, err := h.stageSequence(ctx, ev.Detail.Pipeline, stage, ev.Detail.State)
+	if err != nil {
+		logWarnf(ctx, "unable to order the event: %s", err.Error())
+		return true
+	}
+	var applied bool
+	if applied, err = applyEventSequence(ctx, h.deps.DynamoDB, h.cfg.EventOrderTable, ev.Detail.ExecutionID, statusContext,
+		eventSequence(eventTime, sequence, ev.Detail.State), time.Now()); err != nil {
+		logWarnf(ctx, "unable to order the event: %s", err.Error())
+		return true
+	} else if !applied {
+		logf(ctx, "skipping the %s event of %s delivered after a later event", ev.Detail.State, statusContext)
+	}
+	return applied
+}
@@ -0,0 +1,195 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// GetPipelineState is a mock request for codepipeline (Source, Build then Deploy)
+func (m *mockCodePipelineClient) GetPipelineState(_ context.Context, input *codepipeline.GetPipelineStateInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineStateOutput, error) {
+	if aws.StringValue(input.Name) == "nil" {
+		return nil, nil
+	}
+	return &codepipeline.GetPipelineStateOutput{PipelineName: input.Name, StageStates: []types.StageState{
+		{StageName: aws.String("Source")},
+		{StageName: aws.String("Build")},
+		{StageName: aws.String("Deploy")},
+	}}, nil
+}
+
+// mockEventOrderDynamoClient keeps the latest sequence of each context (an earlier sequence fails the condition)
+type mockEventOrderDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	sequences map[string]string
+}
+
+// UpdateItemWithContext is a mock request for dynamodb (attribute_not_exists(#context) OR #context <= :sequence)
+func (m *mockEventOrderDynamoClient) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	key := aws.StringValue(input.Key["execution_id"].S) + " " + aws.StringValue(input.ExpressionAttributeNames["#context"])
+	sequence := aws.StringValue(input.ExpressionAttributeValues[":sequence"].S)
+	if latest, ok := m.sequences[key]; ok && latest > sequence {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	m.sequences[key] = sequence
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// TestEventSequence will test eventSequence()
+func TestEventSequence(t *testing.T) {
+	t.Parallel()
+
+	second := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		earlier string
+		later   string
+	}{
+		{eventSequence(second, 2, "STARTED"), eventSequence(second, 2, "SUCCEEDED")},
+		{eventSequence(second, 2, "SUCCEEDED"), eventSequence(second, 3, "STARTED")},
+		{eventSequence(second, 3, "SUCCEEDED"), eventSequence(second.Add(time.Second), 1, "STARTED")},
+		{eventSequence(second.Add(time.Second), 1, "FAILED"), eventSequence(second.Add(10*time.Second), 1, "RESUMED")},
+		{eventSequence(second, 0, "STARTED"), eventSequence(second, 4, "SUCCEEDED")},
+		{eventSequence(second.Add(-time.Nanosecond), 3, "SUCCEEDED"), eventSequence(second, 0, "STARTED")},
+	}
+
+	for _, test := range tests {
+		if test.earlier >= test.later {
+			t.Errorf("%s Failed: [%s] expected before [%s]", t.Name(), test.earlier, test.later)
+		}
+	}
+}
+
+// TestStageSequence will test Handler.stageSequence()
+func TestStageSequence(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{})
+	var tests = []struct {
+		stage         string
+		state         string
+		expected      int
+		expectedError bool
+	}{
+		{"Source", "STARTED", 1, false},
+		{"Deploy", "SUCCEEDED", 3, false},
+		{"", "STARTED", 0, false},
+		{"", "SUCCEEDED", 4, false},
+		{"Test", "STARTED", 0, true},
+	}
+
+	for _, test := range tests {
+		if output, err := h.stageSequence(context.Background(), "status-succeed", test.stage, test.state); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.stage, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.stage)
+		} else if output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%d] expected, but got [%d]", t.Name(), test.stage, test.expected, output)
+		}
+	}
+
+	if _, err := h.stageSequence(context.Background(), "nil", "Build", "STARTED"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventOutOfOrder will test ProcessEvent() dropping the events delivered after a later event
+func TestHandlerProcessEventOutOfOrder(t *testing.T) {
+	h := newTestHandler(Config{
+		EventOrderTable:      "event-order",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	dynamo := &mockEventOrderDynamoClient{sequences: make(map[string]string)}
+	h.deps.DynamoDB = dynamo
+
+	var states []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var received payload
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		states = append(states, received.Context+" "+received.State)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// The events of one second delivered shuffled: the STARTED of the stage (and the pipeline) come last
+	second := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, ev := range []Event{
+		{DetailType: detailTypeStageExecution, Time: second, Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", Stage: "Build", State: "SUCCEEDED"}},
+		{DetailType: detailTypeStageExecution, Time: second, Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", Stage: "Build", State: "STARTED"}},
+		{DetailType: detailTypeStageExecution, Time: second, Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", Stage: "Deploy", State: "STARTED"}},
+		{Time: second, Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}},
+		{Time: second.Add(-time.Second), Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "STARTED"}},
+	} {
+		if err := h.ProcessEvent(ev); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+	}
+
+	expected := []string{
+		defaultStatusContext + "/build " + githubStateSuccess,
+		defaultStatusContext + "/deploy " + githubStatePending,
+		defaultStatusContext + " " + githubStateSuccess,
+	}
+	if len(states) != len(expected) {
+		t.Fatal("statuses were not as expected", states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Errorf("%s Failed: [%s] expected, but got [%s]", t.Name(), expected[i], states[i])
+		}
+	}
+}
+
+// TestHandlerProcessEventOrderRetry will test ProcessEvent() posting the retry of an event whose post failed
+func TestHandlerProcessEventOrderRetry(t *testing.T) {
+	h := newTestHandler(Config{
+		EventOrderTable:      "event-order",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	h.deps.DynamoDB = &mockEventOrderDynamoClient{sequences: make(map[string]string)}
+
+	var posts int
+	failing := true
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		posts++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	ev := Event{Time: time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+		Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}
+	if err := h.ProcessEvent(ev); err == nil {
+		t.Fatal("error should have occurred")
+	}
+	failing = false
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 1 {
+		t.Fatal("retry should have been posted", posts)
+	}
+
+	// An earlier event is still dropped
+	ev.Time = ev.Time.Add(-time.Second)
+	ev.Detail.State = "STARTED"
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if posts != 1 {
+		t.Fatal("earlier event should have been dropped", posts)
+	}
+}
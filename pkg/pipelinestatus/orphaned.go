@@ -0,0 +1,42 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Orphaned commit modes (commits that are no longer on the source branch, IE: after a force-push)
+const (
+	orphanedCommitsNeutral     = "neutral"
+	orphanedCommitsSkip        = "skip"
+	orphanedCommitsDescription = "commit superseded by force-push"
+)
+
+// Compare statuses where the commit is part of the branch
+var reachableCompareStatuses = map[string]bool{
+	"behind":    true,
+	"identical": true,
+}
+
+// isOrphaned will return true if the commit is no longer part of the pipeline's source branch
+// (or no longer exists at all)
+func (h *Handler) isOrphaned(ctx context.Context, pipelineName, owner, repo, commit string) (orphaned bool, err error) {
+
+	// Find the branch the pipeline builds
+	var branch string
+	if _, _, branch, err = getSourceBranch(ctx, pipelineName, h.deps.CodePipeline); err != nil {
+		return
+	}
+
+	// Compare the commit with the branch
+	var compare compareResult
+	if err = h.githubGet(
+		ctx, fmt.Sprintf("/repos/%s/%s/compare/%s...%s", owner, repo, url.PathEscape(branch), commit), &compare,
+	); isGithubNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return
+	}
+	return !reachableCompareStatuses[compare.Status], nil
+}
@@ -0,0 +1,99 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestIsOrphaned will test isOrphaned()
+func TestIsOrphaned(t *testing.T) {
+	h := newTestHandler(Config{OrphanedCommits: orphanedCommitsSkip})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/repos/mrz1836/codepipeline-to-github/compare/master...") {
+		case "merged":
+			_, _ = w.Write([]byte(`{"status":"behind"}`))
+		case "head":
+			_, _ = w.Write([]byte(`{"status":"identical"}`))
+		case "force-pushed":
+			_, _ = w.Write([]byte(`{"status":"diverged"}`))
+		case "unknown":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	var tests = []struct {
+		commit        string
+		expected      bool
+		expectedError bool
+	}{
+		{"merged", false, false},
+		{"head", false, false},
+		{"force-pushed", true, false},
+		{"unknown", true, false},
+		{"broken", false, true},
+	}
+
+	for _, test := range tests {
+		orphaned, err := h.isOrphaned(context.Background(), "some-pipeline", "mrz1836", "codepipeline-to-github", test.commit)
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, expected to throw an error, but no error", t.Name(), test.commit)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.commit, err.Error())
+		} else if orphaned != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%v] but got [%v]", t.Name(), test.commit, test.expected, orphaned)
+		}
+	}
+
+	// Pipeline without a GitHub source
+	if _, err := h.isOrphaned(context.Background(), "s3-pipeline", "mrz1836", "codepipeline-to-github", "merged"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventOrphaned will test ProcessEvent() posting a neutral status for an orphaned commit
+func TestHandlerProcessEventOrphaned(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		OrphanedCommits:      orphanedCommitsNeutral,
+		Stage:                stageTesting,
+	})
+
+	var status payload
+	var run checkRun
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/compare/"):
+			_, _ = w.Write([]byte(`{"status":"diverged"}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/check-runs"):
+			_, _ = w.Write([]byte(`{"check_runs":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/check-runs"):
+			_ = json.NewDecoder(r.Body).Decode(&run)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	// Commit statuses have no neutral state
+	ev := Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if status.State != githubStatePending || !strings.Contains(status.Description, orphanedCommitsDescription) {
+		t.Fatal("status was not as expected", status)
+	}
+
+	// Check runs are neutral
+	h.cfg.UseChecksAPI = true
+	if err := h.ProcessEvent(ev); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if run.Status != checkStatusCompleted || run.Conclusion != checkConclusionNeutral {
+		t.Fatal("check run state was not as expected", run.Status, run.Conclusion)
+	}
+}
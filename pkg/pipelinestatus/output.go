@@ -0,0 +1,51 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats of the commands
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+	outputYAML  = "yaml"
+)
+
+// writeOutput will write v in the format, table is used to write the human readable version
+// (YAML uses the same field names as JSON so both formats share one schema)
+func writeOutput(out io.Writer, format string, v interface{}, table func(w io.Writer)) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(out, v)
+	case outputTable:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	case outputYAML:
+		return writeYAML(out, v)
+	default:
+		return fmt.Errorf("unknown output format: %s (available: %s, %s, %s)", format, outputJSON, outputTable, outputYAML)
+	}
+}
+
+// writeYAML will write v as YAML using its JSON field names
+func writeYAML(out io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err = yaml.Unmarshal(b, &generic); err != nil {
+		return err
+	}
+	if b, err = yaml.Marshal(generic); err != nil {
+		return err
+	}
+	_, err = out.Write(b)
+	return err
+}
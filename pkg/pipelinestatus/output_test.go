@@ -0,0 +1,41 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestWriteOutput will test writeOutput()
+func TestWriteOutput(t *testing.T) {
+	t.Parallel()
+
+	report := costsReport{Period: "2020-05", Pipelines: []usageRecord{{GithubCalls: 14, Pipeline: "some-pipeline"}}}
+	table := func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tGITHUB CALLS")
+		_, _ = fmt.Fprintln(w, "some-pipeline\t14")
+	}
+
+	var tests = []struct {
+		format        string
+		expected      string
+		expectedError bool
+	}{
+		{outputJSON, "{\n  \"period\": \"2020-05\",\n  \"pipelines\": [\n    {\n      \"codebuild_seconds\": 0,\n      \"github_calls\": 14,\n      \"invocations\": 0,\n      \"period\": \"\",\n      \"pipeline\": \"some-pipeline\"\n    }\n  ]\n}\n", false},
+		{outputTable, "PIPELINE       GITHUB CALLS\nsome-pipeline  14\n", false},
+		{outputYAML, "period: 2020-05\npipelines:\n- codebuild_seconds: 0\n  github_calls: 14\n  invocations: 0\n  period: \"\"\n  pipeline: some-pipeline\n", false},
+		{"xml", "", true},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := writeOutput(&out, test.format, report, table); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, expected to throw an error, but no error", t.Name(), test.format)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.format, err.Error())
+		} else if out.String() != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.format, test.expected, out.String())
+		}
+	}
+}
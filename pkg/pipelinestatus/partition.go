@@ -0,0 +1,53 @@
+package pipelinestatus
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// consoleDomains are the AWS console domains of each partition
+var consoleDomains = map[string]string{
+	endpoints.AwsCnPartitionID:    "console.amazonaws.cn",
+	endpoints.AwsPartitionID:      "console.aws.amazon.com",
+	endpoints.AwsUsGovPartitionID: "console.amazonaws-us-gov.com",
+}
+
+// regionPartition will return the partition of a region (aws for unknown regions)
+func regionPartition(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
+// configPartition will return the partition of the configuration: AWS_PARTITION or the partition of the region
+func configPartition(cfg Config) string {
+	if len(cfg.AWSPartition) > 0 {
+		return cfg.AWSPartition
+	}
+	return regionPartition(cfg.AWSRegion)
+}
+
+// eventPartition will return the partition of the event: from the pipeline ARN, else from the configuration
+func (h *Handler) eventPartition(ev Event) string {
+	if len(ev.Resources) > 0 {
+		if resource, err := arn.Parse(ev.Resources[0]); err == nil {
+			return resource.Partition
+		}
+	}
+	return configPartition(h.cfg)
+}
+
+// consoleURL will return the link to a page of the AWS console in the partition of the region
+func consoleURL(partition, region, path string) string {
+	domain, ok := consoleDomains[partition]
+	if !ok {
+		domain = consoleDomains[endpoints.AwsPartitionID]
+	}
+	if partition == endpoints.AwsPartitionID || !ok {
+		return fmt.Sprintf("https://%s.%s%s", region, domain, path)
+	}
+	return fmt.Sprintf("https://%s%s?region=%s", domain, path, region)
+}
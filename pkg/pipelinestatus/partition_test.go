@@ -0,0 +1,67 @@
+package pipelinestatus
+
+import (
+	"testing"
+)
+
+// TestRegionPartition will test regionPartition()
+func TestRegionPartition(t *testing.T) {
+	var tests = []struct {
+		region   string
+		expected string
+	}{
+		{"us-east-1", "aws"},
+		{"us-gov-west-1", "aws-us-gov"},
+		{"cn-north-1", "aws-cn"},
+		{"", "aws"},
+	}
+
+	for _, test := range tests {
+		if partition := regionPartition(test.region); partition != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.region, test.expected, partition)
+		}
+	}
+}
+
+// TestEventPartition will test eventPartition()
+func TestEventPartition(t *testing.T) {
+	h := newTestHandler(Config{AWSRegion: "cn-north-1"})
+
+	var tests = []struct {
+		resources []string
+		partition string
+		expected  string
+	}{
+		{[]string{"arn:aws-us-gov:codepipeline:us-gov-west-1:123:some-pipeline"}, "", "aws-us-gov"},
+		{[]string{"not-an-arn"}, "", "aws-cn"},
+		{nil, "", "aws-cn"},
+		{nil, "aws", "aws"},
+	}
+
+	for _, test := range tests {
+		h.cfg.AWSPartition = test.partition
+		if partition := h.eventPartition(Event{Resources: test.resources}); partition != test.expected {
+			t.Errorf("%s Failed: [%v] [%s] inputted, expected [%s] but got [%s]", t.Name(), test.resources, test.partition, test.expected, partition)
+		}
+	}
+}
+
+// TestConsoleURL will test consoleURL()
+func TestConsoleURL(t *testing.T) {
+	var tests = []struct {
+		partition string
+		region    string
+		expected  string
+	}{
+		{"aws", "us-east-1", "https://us-east-1.console.aws.amazon.com/codesuite"},
+		{"aws-us-gov", "us-gov-west-1", "https://console.amazonaws-us-gov.com/codesuite?region=us-gov-west-1"},
+		{"aws-cn", "cn-north-1", "https://console.amazonaws.cn/codesuite?region=cn-north-1"},
+		{"unknown", "us-east-1", "https://us-east-1.console.aws.amazon.com/codesuite"},
+	}
+
+	for _, test := range tests {
+		if url := consoleURL(test.partition, test.region, "/codesuite"); url != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted, expected [%s] but got [%s]", t.Name(), test.partition, test.region, test.expected, url)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// IAM policy defaults
+const (
+	policyEffectAllow = "Allow"
+	policyVersion     = "2012-10-17"
+)
+
+// policyDocument is an IAM policy document
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+// policyStatement is a single statement of an IAM policy document
+type policyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// requiredPolicy will return the least-privilege IAM policy for the features enabled in the configuration
+// (the basic Lambda execution role for logging is not included)
+func requiredPolicy(cfg Config) policyDocument {
+
+	// Always needed: read the pipeline executions (the source branch of scheduled executions and the
+	// executions before a rollback)
+	pipelineActions := []string{
+		"codepipeline:GetPipeline", "codepipeline:GetPipelineExecution", "codepipeline:ListPipelineExecutions",
+	}
+	if len(cfg.FlakyFailureTable) > 0 || cfg.UsageCodeBuildMinutes || cfg.UseChecksAPI ||
+		len(cfg.ApprovalTimeoutState) > 0 || len(cfg.SkippedStageState) > 0 || cfg.FailureDetails || cfg.FailureComment ||
+		cfg.FailurePullRequestComment || cfg.LinkBuildLogs || len(cfg.CodeDeployPipelines) > 0 ||
+		len(cfg.StageDurationTable) > 0 || len(cfg.FreezeWindows) > 0 || len(cfg.FreezeCalendarURL) > 0 {
+		pipelineActions = append(pipelineActions, "codepipeline:ListActionExecutions")
+	}
+	if len(cfg.ContextPrefixTag) > 0 {
+		pipelineActions = append(pipelineActions, "codepipeline:ListTagsForResource")
+	}
+	if len(cfg.EventOrderTable) > 0 {
+		pipelineActions = append(pipelineActions, "codepipeline:GetPipelineState")
+	}
+	if cfg.IngestionMode == ingestionModeWebhook || cfg.IngestionMode == ingestionModeServer {
+		pipelineActions = append(pipelineActions, "codepipeline:ListPipelines")
+	}
+	partition := configPartition(cfg)
+	policy := policyDocument{
+		Version: policyVersion,
+		Statement: []policyStatement{{
+			Sid:      "ReadPipelineExecutions",
+			Effect:   policyEffectAllow,
+			Action:   pipelineActions,
+			Resource: []string{fmt.Sprintf("arn:%s:codepipeline:%s:*:*", partition, cfg.AWSRegion)},
+		}},
+	}
+
+	// Read the pipelines of the other regions (cross-region event bus)
+	for _, region := range cfg.PipelineRegions {
+		if region != cfg.AWSRegion {
+			policy.Statement[0].Resource = append(policy.Statement[0].Resource,
+				fmt.Sprintf("arn:%s:codepipeline:%s:*:*", partition, region))
+		}
+	}
+
+	// Start the pipelines from the GitHub webhooks (the triggers of PIPELINE_CONFIG)
+	if cfg.IngestionMode == ingestionModeWebhook {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "StartTriggeredPipelines",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codepipeline:StartPipelineExecution"},
+			Resource: []string{fmt.Sprintf("arn:%s:codepipeline:%s:*:*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// Stop the executions of the closed pull requests and the deleted branches
+	if len(cfg.ClosedExecutions) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "StopClosedExecutions",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codepipeline:StopPipelineExecution"},
+			Resource: []string{fmt.Sprintf("arn:%s:codepipeline:%s:*:*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// Reject the approvals waiting during a deploy freeze
+	if cfg.FreezeRejectApprovals {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "RejectFrozenApprovals",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codepipeline:GetPipelineState", "codepipeline:PutApprovalResult"},
+			Resource: []string{fmt.Sprintf("arn:%s:codepipeline:%s:*:*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// Resolve the commit of the builds started for a branch or a pull request (and the logs of failed builds)
+	if cfg.CodeBuildEvents || cfg.LinkBuildLogs {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadBuilds",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codebuild:BatchGetBuilds"},
+			Resource: []string{fmt.Sprintf("arn:%s:codebuild:%s:*:project/*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// Find the revision of the deployments
+	if cfg.CodeDeployEvents {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadDeployments",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codedeploy:GetDeployment"},
+			Resource: []string{fmt.Sprintf("arn:%s:codedeploy:%s:*:deploymentgroup:*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// Write the catalog entities of the pipelines for the developer portal
+	if len(cfg.CatalogBucket) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "WriteCatalog",
+			Effect:   policyEffectAllow,
+			Action:   []string{"s3:PutObject"},
+			Resource: []string{fmt.Sprintf("arn:%s:s3:::%s/%s*", partition, cfg.CatalogBucket, cfg.CatalogPrefix)},
+		})
+	}
+
+	// Read the settings from SSM Parameter Store (SecureString parameters use the default key or KMS below)
+	if len(cfg.ConfigSSMPrefix) > 0 {
+		parameterARN := fmt.Sprintf("arn:%s:ssm:%s:*:parameter/%s", partition, cfg.AWSRegion, strings.Trim(cfg.ConfigSSMPrefix, "/"))
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadConfigParameters",
+			Effect:   policyEffectAllow,
+			Action:   []string{"ssm:GetParametersByPath"},
+			Resource: []string{parameterARN, parameterARN + "/*"},
+		})
+	}
+
+	// Read the pipeline configuration from S3 or SSM Parameter Store
+	if strings.HasPrefix(cfg.PipelineConfig, pipelineConfigS3Prefix) {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadPipelineConfig",
+			Effect:   policyEffectAllow,
+			Action:   []string{"s3:GetObject"},
+			Resource: []string{fmt.Sprintf("arn:%s:s3:::%s", partition, strings.TrimPrefix(cfg.PipelineConfig, pipelineConfigS3Prefix))},
+		})
+	} else if strings.HasPrefix(cfg.PipelineConfig, pipelineConfigSSMPrefix) {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:    "ReadPipelineConfig",
+			Effect: policyEffectAllow,
+			Action: []string{"ssm:GetParameter"},
+			Resource: []string{fmt.Sprintf("arn:%s:ssm:%s:*:parameter/%s", partition, cfg.AWSRegion,
+				strings.TrimPrefix(strings.TrimPrefix(cfg.PipelineConfig, pipelineConfigSSMPrefix), "/"))},
+		})
+	}
+
+	// Read the token (or the key of the GitHub App) from the secret (skipped on the testing stage)
+	if cfg.Stage != stageTesting && len(cfg.GithubTokenSecretARN) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadTokenSecret",
+			Effect:   policyEffectAllow,
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: []string{cfg.GithubTokenSecretARN},
+		})
+	}
+	if cfg.Stage != stageTesting && len(cfg.GithubAppSecretARN) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReadGithubAppSecret",
+			Effect:   policyEffectAllow,
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: []string{cfg.GithubAppSecretARN},
+		})
+	}
+
+	// Decrypt the token (skipped on the testing stage, only the tokens of the accounts, the other
+	// forges, Honeycomb and OpsGenie are encrypted when the token is read from a secret or created for the app)
+	if cfg.Stage != stageTesting && ((len(cfg.GithubTokenSecretARN) == 0 && len(cfg.GithubAppSecretARN) == 0) || len(cfg.Accounts) > 0 ||
+		len(cfg.GitlabAccessToken) > 0 || len(cfg.GiteaAccessToken) > 0 || len(cfg.AzureDevOpsToken) > 0 ||
+		len(cfg.BitbucketAccessToken) > 0 || len(cfg.BitbucketAppPassword) > 0 || len(cfg.HoneycombAPIKey) > 0 ||
+		len(cfg.OpsgenieAPIKey) > 0) {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DecryptEnvironment",
+			Effect:   policyEffectAllow,
+			Action:   []string{"kms:Decrypt"},
+			Resource: []string{fmt.Sprintf("arn:%s:kms:%s:*:key/*", partition, cfg.AWSRegion)},
+		})
+	}
+
+	// DynamoDB tables
+	if len(cfg.RateLimitTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "GlobalRateLimit",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.RateLimitTable)},
+		})
+	}
+	if len(cfg.BudgetTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "GithubBudget",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.BudgetTable)},
+		})
+	}
+	if len(cfg.ShadowAuditTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ShadowAudit",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.ShadowAuditTable)},
+		})
+	}
+	if len(cfg.DedupTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DeduplicateStatuses",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:DeleteItem", "dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.DedupTable)},
+		})
+	}
+	if len(cfg.StageDurationTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "StageDurations",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:PutItem", "dynamodb:Query"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.StageDurationTable)},
+		})
+	}
+	if len(cfg.ExecutionTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ExecutionDurations",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.ExecutionTable)},
+		})
+	}
+	if len(cfg.EventOrderTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "EventOrdering",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.EventOrderTable)},
+		})
+	}
+	if len(cfg.AttentionTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "PipelinesNeedingAttention",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.AttentionTable)},
+		})
+	}
+	if len(cfg.FlakyFailureTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "FlakyFailures",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.FlakyFailureTable)},
+		})
+	}
+
+	if len(cfg.DefinitionTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DefinitionDrift",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.DefinitionTable)},
+		})
+	}
+	if len(cfg.EnvironmentTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DeployTracking",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.EnvironmentTable)},
+		})
+	}
+	if len(cfg.MuteTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "MuteWindows",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.MuteTable)},
+		})
+	}
+	if len(cfg.ReleaseTrainTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "ReleaseTrains",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.ReleaseTrainTable)},
+		})
+	}
+	if len(cfg.TimelineTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "CommitTimeline",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:PutItem", "dynamodb:Query"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.TimelineTable)},
+		})
+	}
+	if len(cfg.TransitionTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DeploymentWindows",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:GetItem", "dynamodb:PutItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.TransitionTable)},
+		})
+	}
+	if len(cfg.UsageTable) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "UsageTracking",
+			Effect:   policyEffectAllow,
+			Action:   []string{"dynamodb:Query", "dynamodb:UpdateItem"},
+			Resource: []string{tableARN(partition, cfg.AWSRegion, cfg.UsageTable)},
+		})
+	}
+
+	// Member accounts of a central event bus, and the accounts of the pipelines (the roles of a
+	// PIPELINE_CONFIG in S3 or SSM are not known until it is read)
+	unique := make(map[string]bool)
+	for _, account := range cfg.Accounts {
+		unique[account.RoleARN] = len(account.RoleARN) > 0
+	}
+	unique[cfg.AssumeRoleARN] = len(cfg.AssumeRoleARN) > 0
+	if !strings.HasPrefix(cfg.PipelineConfig, pipelineConfigS3Prefix) && !strings.HasPrefix(cfg.PipelineConfig, pipelineConfigSSMPrefix) {
+		configs, _ := parsePipelineConfigs([]byte(cfg.PipelineConfig))
+		for _, config := range configs {
+			unique[config.RoleARN] = len(config.RoleARN) > 0
+		}
+	}
+	var roles []string
+	for roleARN, ok := range unique {
+		if ok {
+			roles = append(roles, roleARN)
+		}
+	}
+	if len(roles) > 0 {
+		sort.Strings(roles)
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "AssumeAccountRoles",
+			Effect:   policyEffectAllow,
+			Action:   []string{"sts:AssumeRole"},
+			Resource: roles,
+		})
+	}
+
+	// CDEvents publishing
+	if len(cfg.CDEventsBus) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "PublishCDEventsBus",
+			Effect:   policyEffectAllow,
+			Action:   []string{"events:PutEvents"},
+			Resource: []string{fmt.Sprintf("arn:%s:events:%s:*:event-bus/%s", partition, cfg.AWSRegion, cfg.CDEventsBus)},
+		})
+	}
+	if len(cfg.CDEventsTopicARN) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "PublishCDEventsTopic",
+			Effect:   policyEffectAllow,
+			Action:   []string{"sns:Publish"},
+			Resource: []string{cfg.CDEventsTopicARN},
+		})
+	}
+
+	if len(cfg.StatusTopicARN) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "PublishStatusEvents",
+			Effect:   policyEffectAllow,
+			Action:   []string{"sns:Publish"},
+			Resource: []string{cfg.StatusTopicARN},
+		})
+	}
+
+	// Dead-letter queue of the failed events (drained by the event source mapping or the replay command)
+	if dlqARN, err := queueARN(partition, cfg.DeadLetterQueueURL); err == nil {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "DrainDeadLetterQueue",
+			Effect:   policyEffectAllow,
+			Action:   []string{"sqs:DeleteMessage", "sqs:GetQueueAttributes", "sqs:ReceiveMessage"},
+			Resource: []string{dlqARN},
+		})
+	}
+
+	// Pipeline action jobs (do not support resource-level permissions)
+	if cfg.IngestionMode == ingestionModeAction {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "PipelineActionJobs",
+			Effect:   policyEffectAllow,
+			Action:   []string{"codepipeline:GetJobDetails", "codepipeline:PutJobFailureResult", "codepipeline:PutJobSuccessResult"},
+			Resource: []string{"*"},
+		})
+	}
+
+	// Records of the Kinesis stream (read by the event source mapping with the role of the function)
+	if cfg.IngestionMode == ingestionModeKinesis {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:    "ReadKinesisStream",
+			Effect: policyEffectAllow,
+			Action: []string{
+				"kinesis:DescribeStream", "kinesis:DescribeStreamSummary", "kinesis:GetRecords", "kinesis:GetShardIterator",
+				"kinesis:ListShards", "kinesis:SubscribeToShard",
+			},
+			Resource: []string{fmt.Sprintf("arn:%s:kinesis:%s:*:stream/*", partition, cfg.AWSRegion)},
+		}, policyStatement{
+			Sid:      "ListKinesisStreams",
+			Effect:   policyEffectAllow,
+			Action:   []string{"kinesis:ListStreams"},
+			Resource: []string{"*"},
+		})
+	}
+
+	// X-Ray subsegments of the external calls (do not support resource-level permissions)
+	if !cfg.TracingDisabled {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "TraceExternalCalls",
+			Effect:   policyEffectAllow,
+			Action:   []string{"xray:PutTelemetryRecords", "xray:PutTraceSegments"},
+			Resource: []string{"*"},
+		})
+	}
+
+	// CloudTrail lookups (does not support resource-level permissions)
+	if cfg.InitiatorLookup {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid:      "LookupInitiator",
+			Effect:   policyEffectAllow,
+			Action:   []string{"cloudtrail:LookupEvents"},
+			Resource: []string{"*"},
+		})
+	}
+
+	return policy
+}
+
+// tableARN will return the ARN of a DynamoDB table in the region
+func tableARN(partition, region, table string) string {
+	return fmt.Sprintf("arn:%s:dynamodb:%s:*:table/%s", partition, region, table)
+}
+
+// writeJSON will write an indented JSON document
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,129 @@
+package pipelinestatus
+
+import (
+	"strings"
+	"testing"
+)
+
+// hasAction will return true if the policy allows the action
+func hasAction(policy policyDocument, action string) bool {
+	for _, statement := range policy.Statement {
+		for _, a := range statement.Action {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TestRequiredPolicy will test requiredPolicy()
+func TestRequiredPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Minimal configuration on the testing stage (without tracing)
+	policy := requiredPolicy(Config{AWSRegion: "us-east-1", Stage: stageTesting, TracingDisabled: true})
+	if policy.Version != policyVersion {
+		t.Fatal("policy version was not as expected", policy.Version)
+	} else if len(policy.Statement) != 1 {
+		t.Fatal("expected a single statement", policy.Statement)
+	} else if !hasAction(policy, "codepipeline:GetPipelineExecution") {
+		t.Fatal("missing codepipeline:GetPipelineExecution")
+	} else if hasAction(policy, "kms:Decrypt") {
+		t.Fatal("kms:Decrypt is not needed on the testing stage")
+	}
+
+	// All features enabled
+	policy = requiredPolicy(Config{
+		Accounts:          accountMap{"123456789012": {RoleARN: "arn:aws:iam::123456789012:role/codepipeline-status"}},
+		AWSRegion:         "us-west-2",
+		CDEventsBus:       "cdevents",
+		ContextPrefixTag:  "github-context-prefix",
+		EnvironmentTable:  "environments",
+		FlakyFailureTable: "failures",
+		IngestionMode:     ingestionModeAction,
+		InitiatorLookup:   true,
+		RateLimitTable:    "limits",
+		Stage:             stageProduction,
+		TimelineTable:     "timeline",
+		UsageTable:        "usage",
+	})
+	for _, action := range []string{
+		"codepipeline:ListActionExecutions",
+		"codepipeline:ListTagsForResource",
+		"kms:Decrypt",
+		"dynamodb:GetItem",
+		"dynamodb:PutItem",
+		"dynamodb:UpdateItem",
+		"dynamodb:Query",
+		"cloudtrail:LookupEvents",
+		"sts:AssumeRole",
+		"events:PutEvents",
+		"codepipeline:PutJobSuccessResult",
+		"xray:PutTraceSegments",
+	} {
+		if !hasAction(policy, action) {
+			t.Fatal("missing action", action)
+		}
+	}
+
+	// Token from Secrets Manager (nothing to decrypt without accounts)
+	policy = requiredPolicy(Config{AWSRegion: "us-east-1", GithubTokenSecretARN: "arn:aws:secretsmanager:us-east-1:123456789012:secret:token", Stage: stageProduction})
+	if !hasAction(policy, "secretsmanager:GetSecretValue") {
+		t.Fatal("missing action", "secretsmanager:GetSecretValue")
+	} else if hasAction(policy, "kms:Decrypt") {
+		t.Fatal("kms:Decrypt is not needed with a token secret")
+	}
+
+	// Records of a Kinesis stream, without tracing
+	policy = requiredPolicy(Config{AWSRegion: "us-east-1", IngestionMode: ingestionModeKinesis, Stage: stageTesting, TracingDisabled: true})
+	for _, action := range []string{
+		"kinesis:DescribeStream", "kinesis:DescribeStreamSummary", "kinesis:GetRecords", "kinesis:GetShardIterator",
+		"kinesis:ListShards", "kinesis:ListStreams",
+	} {
+		if !hasAction(policy, action) {
+			t.Fatal("missing action", action)
+		}
+	}
+	if hasAction(policy, "xray:PutTraceSegments") {
+		t.Fatal("xray:PutTraceSegments is not needed with TRACING_DISABLED")
+	}
+
+	// Rejected approvals during a deploy freeze
+	policy = requiredPolicy(Config{AWSRegion: "us-east-1", FreezeRejectApprovals: true, FreezeWindows: []string{"2026-12-20T00:00:00Z/2027-01-04T00:00:00Z"}})
+	if !hasAction(policy, "codepipeline:PutApprovalResult") || !hasAction(policy, "codepipeline:ListActionExecutions") {
+		t.Fatal("missing the actions of the freeze", policy.Statement)
+	}
+
+	// Roles of the accounts of the pipelines
+	policy = requiredPolicy(Config{
+		AssumeRoleARN:   "arn:aws:iam::111111111111:role/status",
+		AWSRegion:       "us-east-1",
+		PipelineConfig:  "search: {role_arn: 'arn:aws:iam::222222222222:role/status'}\npayments: {role_arn: 'arn:aws:iam::111111111111:role/status'}",
+		Stage:           stageTesting,
+		TracingDisabled: true,
+	})
+	if statement := policy.Statement[len(policy.Statement)-1]; statement.Sid != "AssumeAccountRoles" || len(statement.Resource) != 2 ||
+		statement.Resource[0] != "arn:aws:iam::111111111111:role/status" || statement.Resource[1] != "arn:aws:iam::222222222222:role/status" {
+		t.Fatal("statement was not as expected", statement)
+	}
+
+	// Pipelines of the other regions
+	policy = requiredPolicy(Config{AWSRegion: "us-east-1", PipelineRegions: []string{"us-east-1", "eu-west-1"}, Stage: stageTesting})
+	if resources := policy.Statement[0].Resource; len(resources) != 2 || resources[1] != "arn:aws:codepipeline:eu-west-1:*:*" {
+		t.Fatal("resources were not as expected", resources)
+	}
+	if tableARN("aws", "us-west-2", "limits") != "arn:aws:dynamodb:us-west-2:*:table/limits" {
+		t.Fatal("table arn was not as expected", tableARN("aws", "us-west-2", "limits"))
+	}
+
+	// Resources in the partition of the region
+	policy = requiredPolicy(Config{AWSRegion: "us-gov-west-1", IngestionMode: ingestionModeKinesis, RateLimitTable: "limits", Stage: stageTesting})
+	for _, statement := range policy.Statement {
+		for _, resource := range statement.Resource {
+			if resource != "*" && !strings.HasPrefix(resource, "arn:aws-us-gov:") {
+				t.Fatal("resource was not in the partition", resource)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/yaml.v2"
+)
+
+// Sources of the pipeline configuration (PIPELINE_CONFIG), anything else is the document itself
+const (
+	pipelineConfigS3Prefix  = "s3://"
+	pipelineConfigSSMPrefix = "ssm:"
+)
+
+// pipelineConfigs are the settings of each pipeline (YAML or JSON) for a bridge serving pipelines with different
+// conventions (IE: payments: {repository: my-org/payments-api, context: payments/deploy, notify: false})
+type pipelineConfigs map[string]pipelineConfig
+
+// pipelineConfig are the settings of a pipeline, unset settings use the global configuration
+type pipelineConfig struct {
+	Context    string            `yaml:"context" json:"context,omitempty"`       // the status context (the stages are nested under it)
+	Ignore     bool              `yaml:"ignore" json:"ignore,omitempty"`         // no status is reported for the pipeline
+	Notify     *bool             `yaml:"notify" json:"notify,omitempty"`         // false skips the notifiers (IE: Slack)
+	Repository string            `yaml:"repository" json:"repository,omitempty"` // owner/repo reported to instead of the source
+	RoleARN    string            `yaml:"role_arn" json:"role_arn,omitempty"`     // role of the account of the pipeline
+	Triggers   []pipelineTrigger `yaml:"triggers" json:"triggers,omitempty"`     // rules starting the pipeline from the GitHub webhooks
+}
+
+// Per-container cache of the loaded pipeline configuration (S3 and SSM are read again once the TTL expires)
+var (
+	pipelineConfigCache    pipelineConfigs
+	pipelineConfigCachedAt time.Time
+	pipelineConfigMu       sync.Mutex
+	pipelineConfigSource   string
+)
+
+// parsePipelineConfigs will read and validate the pipeline configuration
+func parsePipelineConfigs(b []byte) (configs pipelineConfigs, err error) {
+	if err = yaml.UnmarshalStrict(b, &configs); err != nil {
+		return nil, fmt.Errorf("invalid PIPELINE_CONFIG: %s", err.Error())
+	}
+	for name, config := range configs {
+		if parts := strings.Split(config.Repository, "/"); len(config.Repository) > 0 &&
+			(len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0) {
+			return nil, fmt.Errorf("invalid PIPELINE_CONFIG: %s: repository must be owner/repo: %s", name, config.Repository)
+		}
+		for _, trigger := range config.Triggers {
+			if err = validateTrigger(trigger); err != nil {
+				return nil, fmt.Errorf("invalid PIPELINE_CONFIG: %s: %s", name, err.Error())
+			}
+		}
+	}
+	return
+}
+
+// loadPipelineConfigs will load the pipeline configuration from an S3 object (s3://bucket/key), an SSM parameter
+// (ssm:/name) or the setting itself, S3 and SSM are cached for the TTL
+func loadPipelineConfigs(ctx context.Context, deps Dependencies, source string, ttl time.Duration) (pipelineConfigs, error) {
+	if len(source) == 0 {
+		return nil, nil
+	} else if !strings.HasPrefix(source, pipelineConfigS3Prefix) && !strings.HasPrefix(source, pipelineConfigSSMPrefix) {
+		return parsePipelineConfigs([]byte(source))
+	}
+
+	pipelineConfigMu.Lock()
+	defer pipelineConfigMu.Unlock()
+	if pipelineConfigSource == source && time.Since(pipelineConfigCachedAt) < ttl {
+		return pipelineConfigCache, nil
+	}
+
+	var b []byte
+	if strings.HasPrefix(source, pipelineConfigS3Prefix) {
+		location, err := url.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PIPELINE_CONFIG: %s", err.Error())
+		}
+		var output *s3.GetObjectOutput
+		if output, err = deps.S3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(location.Host),
+			Key:    aws.String(strings.TrimPrefix(location.Path, "/")),
+		}); err != nil {
+			return nil, fmt.Errorf("unable to read the pipeline configuration: %s", err.Error())
+		}
+		defer func() {
+			_ = output.Body.Close()
+		}()
+		if b, err = ioutil.ReadAll(output.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		output, err := deps.SSM.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(strings.TrimPrefix(source, pipelineConfigSSMPrefix)),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the pipeline configuration: %s", err.Error())
+		}
+		b = []byte(aws.StringValue(output.Parameter.Value))
+	}
+
+	configs, err := parsePipelineConfigs(b)
+	if err != nil {
+		return nil, err
+	}
+	pipelineConfigCache, pipelineConfigCachedAt, pipelineConfigSource = configs, time.Now(), source
+	return configs, nil
+}
+
+// pipelineRepository will return the repository of the statuses of a pipeline: configured or the source of the revision
+func (h *Handler) pipelineRepository(pipelineName string, revisionURL *url.URL) (owner, repo string) {
+	if repository := h.pipelines[pipelineName].Repository; len(repository) > 0 {
+		parts := strings.SplitN(repository, "/", 2)
+		return parts[0], parts[1]
+	}
+	return revisionRepository(revisionURL, h.forgeHosts())
+}
+
+// pipelineNotifies will return false if the notifiers are turned off for the pipeline
+func (h *Handler) pipelineNotifies(pipelineName string) bool {
+	notify := h.pipelines[pipelineName].Notify
+	return notify == nil || *notify
+}
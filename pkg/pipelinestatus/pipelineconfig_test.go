@@ -0,0 +1,175 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// GetObjectWithContext is a mock request for s3 (reads the objects by bucket/key)
+func (m *mockS3Client) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	object, ok := m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey: The specified key does not exist")
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(object))}, nil
+}
+
+// GetParameterWithContext is a mock request for ssm (counts the requests)
+func (m *mockSSMClient) GetParameterWithContext(_ aws.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+	m.calls++
+	value, ok := m.parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, errors.New("ParameterNotFound")
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name, Value: aws.String(value)}}, nil
+}
+
+// TestParsePipelineConfigs will test parsePipelineConfigs()
+func TestParsePipelineConfigs(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		input         string
+		expected      pipelineConfigs
+		expectedError bool
+	}{
+		{"payments: {repository: my-org/payments-api, context: payments/deploy}", pipelineConfigs{
+			"payments": {Context: "payments/deploy", Repository: "my-org/payments-api"},
+		}, false},
+		{`{"search":{"ignore":true}}`, pipelineConfigs{"search": {Ignore: true}}, false},
+		{"payments:\n  notify: false\n", pipelineConfigs{"payments": {Notify: aws.Bool(false)}}, false},
+		{"payments: {slack: false}", nil, true},
+		{"payments: {repository: payments-api}", nil, true},
+		{"payments: {repository: my-org/}", nil, true},
+		{"payments: {triggers: [{branches: [main], variables: {ENVIRONMENT: staging}}]}", pipelineConfigs{"payments": {Triggers: []pipelineTrigger{
+			{Branches: []string{"main"}, Variables: map[string]string{"ENVIRONMENT": "staging"}},
+		}}}, false},
+		{"payments: {triggers: [{events: [release]}]}", nil, true},
+		{"payments: {triggers: [{paths: ['services/[']}]}", nil, true},
+		{"payments: [", nil, true},
+	}
+
+	for _, test := range tests {
+		if output, err := parsePipelineConfigs([]byte(test.input)); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.input)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, received error: %s", t.Name(), test.input, err.Error())
+		} else if expected, _ := json.Marshal(test.expected); string(expected) != mustJSON(output) {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.input, expected, mustJSON(output))
+		}
+	}
+}
+
+// mustJSON will encode a value as JSON for comparisons
+func mustJSON(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// TestLoadPipelineConfigs will test loadPipelineConfigs() reading the setting, S3 and SSM
+func TestLoadPipelineConfigs(t *testing.T) {
+	defer func() {
+		pipelineConfigSource = ""
+	}()
+	s3Svc := &mockS3Client{objects: map[string]string{"config-bucket/pipelines.yaml": "payments: {context: payments/deploy}"}}
+	ssmSvc := &mockSSMClient{parameters: map[string]string{"/bridge/pipelines": `{"search":{"ignore":true}}`}}
+	deps := Dependencies{S3: s3Svc, SSM: ssmSvc}
+
+	if configs, err := loadPipelineConfigs(context.Background(), deps, "", time.Minute); err != nil || configs != nil {
+		t.Fatal("configuration was not as expected", configs, err)
+	}
+	if configs, err := loadPipelineConfigs(context.Background(), deps, "payments: {ignore: true}", time.Minute); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !configs["payments"].Ignore {
+		t.Fatal("configuration was not as expected", configs)
+	}
+	if configs, err := loadPipelineConfigs(context.Background(), deps, "s3://config-bucket/pipelines.yaml", time.Minute); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if configs["payments"].Context != "payments/deploy" {
+		t.Fatal("configuration was not as expected", configs)
+	}
+
+	// SSM is cached for the TTL
+	for i := 0; i < 2; i++ {
+		if configs, err := loadPipelineConfigs(context.Background(), deps, "ssm:/bridge/pipelines", time.Minute); err != nil {
+			t.Fatal("error occurred", err.Error())
+		} else if !configs["search"].Ignore {
+			t.Fatal("configuration was not as expected", configs)
+		}
+	}
+	if ssmSvc.calls != 1 {
+		t.Fatal("parameter should have been read once", ssmSvc.calls)
+	}
+
+	if _, err := loadPipelineConfigs(context.Background(), deps, "s3://config-bucket/missing.yaml", time.Minute); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventPipelineConfig will test ProcessEvent() with the settings of the pipeline
+func TestHandlerProcessEventPipelineConfig(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+	h.pipelines = pipelineConfigs{
+		"status-fail":    {Ignore: true},
+		"status-succeed": {Context: "payments/deploy", Repository: "my-org/payments-api"},
+	}
+
+	var received payload
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(paths) != 1 || paths[0] != "/repos/my-org/payments-api/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("paths were not as expected", paths)
+	} else if received.Context != "payments/deploy" {
+		t.Fatal("context was not as expected", received.Context)
+	}
+
+	// Stages are nested under the context of the pipeline
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678", Pipeline: "status-succeed", Stage: "Build", State: "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.Context != "payments/deploy/build" {
+		t.Fatal("context was not as expected", received.Context)
+	}
+
+	// Ignored pipeline
+	paths = nil
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-fail", State: "FAILED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(paths) != 0 {
+		t.Fatal("status should not have been posted", paths)
+	}
+}
+
+// TestPipelineNotifies will test Handler.pipelineNotifies()
+func TestPipelineNotifies(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{})
+	h.pipelines = pipelineConfigs{"payments": {Notify: aws.Bool(false)}, "search": {Notify: aws.Bool(true)}}
+	if h.pipelineNotifies("payments") || !h.pipelineNotifies("search") || !h.pipelineNotifies("web") {
+		t.Fatal("notifications were not as expected")
+	}
+}
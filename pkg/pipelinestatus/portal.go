@@ -0,0 +1,45 @@
+package pipelinestatus
+
+import "strings"
+
+// Defaults of a portal entity reference (Backstage: [kind:][namespace/]name)
+const (
+	defaultPortalKind      = "component"
+	defaultPortalNamespace = "default"
+)
+
+// portalEntity is the entity of a pipeline in the developer portal, used to link statuses to the portal
+// instead of the AWS console (IE: TARGET_URL_TEMPLATE="https://backstage.example.com/catalog/{{.Entity.Namespace}}/{{.Entity.Kind}}/{{.Entity.Name}}/ci-cd")
+type portalEntity struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// String will return the entity reference (IE: component:default/payments-api)
+func (e portalEntity) String() string {
+	return e.Kind + ":" + e.Namespace + "/" + e.Name
+}
+
+// parsePortalEntity will parse an entity reference, the kind and the namespace are optional
+func parsePortalEntity(ref string) portalEntity {
+	entity := portalEntity{Kind: defaultPortalKind, Name: ref, Namespace: defaultPortalNamespace}
+	if i := strings.Index(entity.Name, ":"); i >= 0 {
+		entity.Kind, entity.Name = entity.Name[:i], entity.Name[i+1:]
+	}
+	if i := strings.Index(entity.Name, "/"); i >= 0 {
+		entity.Namespace, entity.Name = entity.Name[:i], entity.Name[i+1:]
+	}
+	return entity
+}
+
+// portalEntity will return the portal entity of the pipeline (PORTAL_ENTITIES by pipeline, then by owner/repo),
+// the component named after the repository if there is none
+func (h *Handler) portalEntity(pipelineName, owner, repo string) portalEntity {
+	if ref, ok := h.cfg.PortalEntities[pipelineName]; ok {
+		return parsePortalEntity(ref)
+	} else if ref, ok = h.cfg.PortalEntities[owner+"/"+repo]; ok {
+		return parsePortalEntity(ref)
+	}
+	return parsePortalEntity(repo)
+}
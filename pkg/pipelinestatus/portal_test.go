@@ -0,0 +1,77 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestParsePortalEntity will test parsePortalEntity()
+func TestParsePortalEntity(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		ref      string
+		expected portalEntity
+	}{
+		{"payments-api", portalEntity{Kind: "component", Name: "payments-api", Namespace: "default"}},
+		{"payments/payments-api", portalEntity{Kind: "component", Name: "payments-api", Namespace: "payments"}},
+		{"system:payments", portalEntity{Kind: "system", Name: "payments", Namespace: "default"}},
+		{"resource:payments/ledger-db", portalEntity{Kind: "resource", Name: "ledger-db", Namespace: "payments"}},
+	}
+
+	for _, test := range tests {
+		if entity := parsePortalEntity(test.ref); entity != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%+v] but got [%+v]", t.Name(), test.ref, test.expected, entity)
+		}
+	}
+}
+
+// TestHandlerPortalEntity will test Handler.portalEntity() looking up the pipeline, then the repository
+func TestHandlerPortalEntity(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{PortalEntities: stringMap{
+		"payments":                  "component:payments/payments-api",
+		"mrz1836/payments-frontend": "payments/payments-web",
+	}})
+
+	var tests = []struct {
+		pipeline string
+		repo     string
+		expected string
+	}{
+		{"payments", "payments", "component:payments/payments-api"},
+		{"web", "payments-frontend", "component:payments/payments-web"},
+		{"search", "search-api", "component:default/search-api"},
+	}
+
+	for _, test := range tests {
+		if entity := h.portalEntity(test.pipeline, "mrz1836", test.repo); entity.String() != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.pipeline, test.expected, entity)
+		}
+	}
+}
+
+// TestHandlerProcessEventPortalTargetURL will test ProcessEvent() linking the status to the developer portal
+func TestHandlerProcessEventPortalTargetURL(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		PortalEntities:       stringMap{"status-succeed": "payments/payments-api"},
+		Stage:                stageTesting,
+		TargetURLTemplate:    "https://backstage.example.com/catalog/{{.Entity.Namespace}}/{{.Entity.Kind}}/{{.Entity.Name}}/ci-cd?execution={{.ExecutionID}}",
+	})
+
+	var received payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.TargetURL != "https://backstage.example.com/catalog/payments/component/payments-api/ci-cd?execution=12345678" {
+		t.Fatal("target url was not as expected", received.TargetURL)
+	}
+}
@@ -0,0 +1,164 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Prometheus Pushgateway (PROMETHEUS_PUSHGATEWAY_URL), the metrics of an invocation are pushed at its end
+const (
+	prometheusContentType  = "text/plain; version=0.0.4"
+	prometheusMaxSamples   = 1000
+	prometheusMetricPrefix = "codepipeline_to_github_"
+)
+
+// prometheusUnits are the suffixes of the metric units (IE: status_write_latency_milliseconds)
+var prometheusUnits = map[string]string{
+	"Milliseconds": "_milliseconds",
+	"Percent":      "_percent",
+}
+
+// prometheusSample is a metric emitted during the invocation
+type prometheusSample struct {
+	Labels map[string]string
+	Name   string
+	Unit   string
+	Value  float64
+}
+
+// Per-container buffer of the metrics since the last push (samples past the limit are dropped)
+var (
+	prometheusSamples   []prometheusSample
+	prometheusSamplesMu sync.Mutex
+)
+
+// collectMetric will keep a metric until the end of the invocation
+func collectMetric(name string, value float64, unit string, dimensions map[string]string) {
+	prometheusSamplesMu.Lock()
+	defer prometheusSamplesMu.Unlock()
+	if len(prometheusSamples) < prometheusMaxSamples {
+		prometheusSamples = append(prometheusSamples, prometheusSample{Labels: dimensions, Name: name, Unit: unit, Value: value})
+	}
+}
+
+// drainMetrics will return the metrics since the last push and empty the buffer
+func drainMetrics() (samples []prometheusSample) {
+	prometheusSamplesMu.Lock()
+	defer prometheusSamplesMu.Unlock()
+	samples, prometheusSamples = prometheusSamples, nil
+	return
+}
+
+// prometheusName will return the Prometheus name of a metric with the suffix of its unit (IE: StatusWriteLatency
+// in Milliseconds is codepipeline_to_github_status_write_latency_milliseconds)
+func prometheusName(name, unit string) string {
+	var b strings.Builder
+	b.WriteString(prometheusMetricPrefix)
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	if suffix := prometheusUnits[unit]; !strings.HasSuffix(b.String(), suffix) {
+		b.WriteString(suffix)
+	}
+	return b.String()
+}
+
+// prometheusLabels will return the sorted labels of a sample (IE: {Pipeline="payments",Stage="Deploy"})
+func prometheusLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escape.Replace(labels[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatPrometheusMetrics will render the samples in the text exposition format as gauges (counts of the same
+// series are added up, the last value wins for the others)
+func formatPrometheusMetrics(samples []prometheusSample) string {
+	values := make(map[string]map[string]float64)
+	for _, sample := range samples {
+		name := prometheusName(sample.Name, sample.Unit)
+		if values[name] == nil {
+			values[name] = make(map[string]float64)
+		}
+		labels := prometheusLabels(sample.Labels)
+		if sample.Unit == "Count" {
+			values[name][labels] += sample.Value
+		} else {
+			values[name][labels] = sample.Value
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		_, _ = fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		series := make([]string, 0, len(values[name]))
+		for labels := range values[name] {
+			series = append(series, labels)
+		}
+		sort.Strings(series)
+		for _, labels := range series {
+			_, _ = fmt.Fprintf(&b, "%s%s %v\n", name, labels, values[name][labels])
+		}
+	}
+	return b.String()
+}
+
+// pushMetrics will push the metrics of the invocation to the Pushgateway under the job (PROMETHEUS_JOB), the
+// metrics are dropped without PROMETHEUS_PUSHGATEWAY_URL and a failed push is only logged
+func (h *Handler) pushMetrics(ctx context.Context) {
+	samples := drainMetrics()
+	if len(h.cfg.PrometheusPushgatewayURL) == 0 || len(samples) == 0 {
+		return
+	}
+
+	// The push must not hold the invocation past its deadline
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/metrics/job/%s",
+		strings.TrimSuffix(h.cfg.PrometheusPushgatewayURL, "/"), url.PathEscape(h.cfg.PrometheusJob)),
+		strings.NewReader(formatPrometheusMetrics(samples)))
+	if err != nil {
+		logWarnf(ctx, "unable to push the metrics: %s", err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", prometheusContentType)
+
+	var response *http.Response
+	if response, err = h.deps.Prometheus.Do(req); err != nil {
+		logWarnf(ctx, "unable to push the metrics: %s", err.Error())
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusAccepted {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		logWarnf(ctx, "unable to push the metrics, code: %d body: %s", response.StatusCode, strings.TrimSpace(string(resBody)))
+	}
+}
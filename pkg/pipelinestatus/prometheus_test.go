@@ -0,0 +1,85 @@
+package pipelinestatus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPrometheusName will test prometheusName()
+func TestPrometheusName(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name     string
+		unit     string
+		expected string
+	}{
+		{metricStatusWriteLatency, "Milliseconds", "codepipeline_to_github_status_write_latency_milliseconds"},
+		{metricGithubBudgetPct, "Percent", "codepipeline_to_github_github_api_budget_used_percent"},
+		{metricNotificationSuccess, "Count", "codepipeline_to_github_notification_success"},
+	}
+	for _, test := range tests {
+		if output := prometheusName(test.name, test.unit); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.name, test.unit, test.expected, output)
+		}
+	}
+}
+
+// TestFormatPrometheusMetrics will test formatPrometheusMetrics()
+func TestFormatPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	output := formatPrometheusMetrics([]prometheusSample{
+		{Labels: map[string]string{"Pipeline": "payments"}, Name: "StatusWriteLatency", Unit: "Milliseconds", Value: 120},
+		{Labels: map[string]string{"Pipeline": "payments"}, Name: "StatusWriteLatency", Unit: "Milliseconds", Value: 80},
+		{Labels: map[string]string{"Notifier": "slack", "Pipeline": `say "hi"`}, Name: "NotificationSuccess", Unit: "Count", Value: 1},
+		{Labels: map[string]string{"Notifier": "slack", "Pipeline": `say "hi"`}, Name: "NotificationSuccess", Unit: "Count", Value: 1},
+		{Name: "TokenExpiry", Unit: "Count", Value: 30},
+	})
+	expected := `# TYPE codepipeline_to_github_notification_success gauge
+codepipeline_to_github_notification_success{Notifier="slack",Pipeline="say \"hi\""} 2
+# TYPE codepipeline_to_github_status_write_latency_milliseconds gauge
+codepipeline_to_github_status_write_latency_milliseconds{Pipeline="payments"} 80
+# TYPE codepipeline_to_github_token_expiry gauge
+codepipeline_to_github_token_expiry 30
+`
+	if output != expected {
+		t.Fatal("metrics were not as expected", output)
+	}
+}
+
+// TestPushMetrics will test Handler.pushMetrics() pushing the metrics of the invocation to the Pushgateway
+func TestPushMetrics(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(b)
+	}))
+	defer server.Close()
+
+	// Dropped without a Pushgateway
+	h := newTestHandler(Config{PrometheusJob: "codepipeline-to-github"})
+	printMetric(metricTokenExpiry, 30, "Count", nil, time.Now())
+	h.pushMetrics(context.Background())
+	if len(path) > 0 || len(drainMetrics()) > 0 {
+		t.Fatal("metrics should have been dropped", path)
+	}
+
+	// Pushed once
+	h.cfg.PrometheusPushgatewayURL = server.URL + "/"
+	h.deps.Prometheus = server.Client()
+	printMetric(metricTokenExpiry, 30, "Count", nil, time.Now())
+	h.pushMetrics(context.Background())
+	if path != "POST /metrics/job/codepipeline-to-github" || body != "# TYPE codepipeline_to_github_github_token_days_until_expiry gauge\ncodepipeline_to_github_github_token_days_until_expiry 30\n" {
+		t.Fatal("push was not as expected", path, body)
+	}
+	path = ""
+	h.pushMetrics(context.Background())
+	if len(path) > 0 {
+		t.Fatal("metrics should not have been pushed again", path)
+	}
+}
@@ -0,0 +1,75 @@
+package pipelinestatus
+
+import (
+	"fmt"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+)
+
+// regionalDependencies will return the CodePipeline and CloudTrail services of a region (with the role of the
+// account of the pipeline, if any), cached per container by region and role
+func (c *containerCache) regionalDependencies(awsSession *session.Session, awsConfig awsv2.Config, region, roleARN string) Dependencies {
+	c.regionsMu.Lock()
+	defer c.regionsMu.Unlock()
+	key := region + " " + roleARN
+	if deps, ok := c.regions[key]; ok {
+		return deps
+	}
+	config := &aws.Config{Region: aws.String(region)}
+	regional := awsConfig.Copy()
+	regional.Region = region
+	if len(roleARN) > 0 {
+		config.Credentials = stscreds.NewCredentials(awsSession, roleARN)
+		regional = assumeRoleConfig(regional, roleARN)
+	}
+	deps := Dependencies{
+		CloudTrail:   cloudtrail.New(awsSession, config),
+		CodePipeline: codepipeline.NewFromConfig(regional),
+	}
+	c.regions[key] = deps
+	return deps
+}
+
+// forRegion will return a handler reading the pipeline in the region of the event (events of other regions arrive
+// on a cross-region event bus), the links and templates use the region of the pipeline (events of regions missing
+// from PIPELINE_REGIONS are rejected once it is set)
+func (h *Handler) forRegion(accountID, region, pipelineName string) (*Handler, error) {
+	if len(region) == 0 || region == h.cfg.AWSRegion {
+		return h, nil
+	}
+	configured := len(h.cfg.PipelineRegions) == 0
+	for _, pipelineRegion := range h.cfg.PipelineRegions {
+		configured = configured || pipelineRegion == region
+	}
+	if !configured {
+		return nil, &permanentError{err: fmt.Errorf("region %s is not configured", region)}
+	} else if h.deps.ForRegion == nil {
+		return nil, fmt.Errorf("unable to read pipeline %s in region %s: missing dependency: ForRegion", pipelineName, region)
+	}
+
+	// The role of the pipeline (or of its account) is assumed in the region
+	roleARN := h.pipelineRoleARN(accountID, pipelineName)
+	if len(roleARN) == 0 {
+		roleARN = h.cfg.Accounts[accountID].RoleARN
+	}
+
+	// New handler so the defaults are untouched
+	regionHandler := Handler{
+		accountContextPrefix: h.accountContextPrefix,
+		cfg:                  h.cfg,
+		deps:                 h.deps,
+		githubURL:            h.githubURL,
+		logSettings:          h.logSettings,
+		pipelines:            h.pipelines,
+	}
+	regionHandler.cfg.AWSRegion = region
+	regional := h.deps.ForRegion(region, roleARN)
+	regionHandler.deps.CloudTrail = regional.CloudTrail
+	regionHandler.deps.CodePipeline = regional.CodePipeline
+	return &regionHandler, nil
+}
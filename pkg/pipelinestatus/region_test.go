@@ -0,0 +1,82 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestForRegion will test forRegion() reading the pipeline in the region of the event
+func TestForRegion(t *testing.T) {
+	regionalPipeline := &mockCodePipelineClient{}
+	var created []string
+
+	h := newTestHandler(Config{AWSRegion: "us-east-1", GithubAccessToken: "1234567", Stage: stageTesting})
+	h.deps.ForRegion = func(region, roleARN string) Dependencies {
+		created = append(created, region+" "+roleARN)
+		return Dependencies{CloudTrail: &mockCloudTrailClient{}, CodePipeline: regionalPipeline}
+	}
+
+	// Events of the region of the function (or without a region)
+	for _, region := range []string{"", "us-east-1"} {
+		if regionHandler, err := h.forRegion("123456789012", region, "payments"); err != nil {
+			t.Fatal("error occurred", err.Error())
+		} else if regionHandler != h || len(created) > 0 {
+			t.Fatal("handler should not have changed", region, created)
+		}
+	}
+
+	// Events of another region (with the role of the account)
+	h.cfg.Accounts = accountMap{"222222222222": {RoleARN: "arn:aws:iam::222222222222:role/status"}}
+	regionHandler, err := h.forRegion("222222222222", "eu-west-1", "payments")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if regionHandler.deps.CodePipeline != regionalPipeline || h.deps.CodePipeline == regionalPipeline {
+		t.Fatal("pipeline service should be from the region of the event")
+	} else if regionHandler.cfg.AWSRegion != "eu-west-1" || h.cfg.AWSRegion != "us-east-1" {
+		t.Fatal("region was not as expected", regionHandler.cfg.AWSRegion, h.cfg.AWSRegion)
+	} else if len(created) != 1 || created[0] != "eu-west-1 arn:aws:iam::222222222222:role/status" {
+		t.Fatal("services were not as expected", created)
+	}
+
+	// Regions missing from PIPELINE_REGIONS
+	h.cfg.PipelineRegions = []string{"eu-west-1"}
+	if _, err = h.forRegion("123456789012", "ap-southeast-2", "payments"); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "region ap-southeast-2 is not configured" {
+		t.Fatal("error was not as expected", err.Error())
+	} else if !isPermanent(err) {
+		t.Fatal("error should be permanent", err.Error())
+	}
+
+	// Missing dependency
+	h.deps.ForRegion = nil
+	if _, err = h.forRegion("123456789012", "eu-west-1", "payments"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventRegion will test ProcessEvent() linking to the execution in the region of the event
+func TestHandlerProcessEventRegion(t *testing.T) {
+	h := newTestHandler(Config{AWSRegion: "us-east-1", GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	h.deps.ForRegion = func(region, roleARN string) Dependencies {
+		return Dependencies{CloudTrail: &mockCloudTrailClient{}, CodePipeline: &mockCodePipelineClient{}}
+	}
+
+	var targetURL string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status struct {
+			TargetURL string `json:"target_url"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		targetURL = status.TargetURL
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", State: "STARTED"},
+		Region: "eu-west-1"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if targetURL != "https://eu-west-1.console.aws.amazon.com/codesuite/codepipeline/pipelines/some-pipeline/executions/12345678" {
+		t.Fatal("target url was not as expected", targetURL)
+	}
+}
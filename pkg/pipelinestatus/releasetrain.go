@@ -0,0 +1,113 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Release train defaults
+const (
+	releaseTrainContextPrefix = "release-train/"
+	releaseTrainMemberPrefix  = "pipeline:"
+	releaseTrainTTL           = 30 * 24 * time.Hour
+	releaseTrainWaiting       = "waiting"
+)
+
+// recordTrainMember will store the status of a member pipeline for the release (train and tag commit) and
+// return the statuses of every member that reported so far
+func recordTrainMember(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, train, commit, pipelineName, status string,
+	now time.Time) (statuses map[string]string, err error) {
+
+	var output *dynamodb.UpdateItemOutput
+	if output, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#expires": aws.String("expires_at"),
+			"#member":  aws.String(releaseTrainMemberPrefix + pipelineName),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expires": {N: aws.String(strconv.FormatInt(now.Add(releaseTrainTTL).Unix(), 10))},
+			":member":  {S: aws.String(status)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"release": {S: aws.String(train + "#" + commit)},
+		},
+		ReturnValues:     aws.String(dynamodb.ReturnValueAllNew),
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("SET #member = :member, #expires = :expires"),
+	}); err != nil {
+		return
+	}
+
+	statuses = make(map[string]string)
+	for name, value := range output.Attributes {
+		if strings.HasPrefix(name, releaseTrainMemberPrefix) {
+			statuses[strings.TrimPrefix(name, releaseTrainMemberPrefix)] = aws.StringValue(value.S)
+		}
+	}
+	return
+}
+
+// trainMembers will return the pipelines of a release train (sorted)
+func trainMembers(trains stringMap, train string) (members []string) {
+	for pipelineName, name := range trains {
+		if name == train {
+			members = append(members, pipelineName)
+		}
+	}
+	sort.Strings(members)
+	return
+}
+
+// trainStatus will aggregate the statuses of the members: failed if any member failed, successful once
+// every member succeeded, pending otherwise (the description lists the result of each member)
+func trainStatus(members []string, statuses map[string]string) (state, description string) {
+	state = githubStateSuccess
+	results := make([]string, 0, len(members))
+	for _, member := range members {
+		status, ok := statuses[member]
+		if !ok {
+			status = releaseTrainWaiting
+		}
+		results = append(results, member+": "+status)
+
+		switch {
+		case status == githubStateError || status == githubStateFailure:
+			state = githubStateFailure
+		case status != githubStateSuccess && state == githubStateSuccess:
+			state = githubStatePending
+		}
+	}
+	return state, strings.Join(results, ", ")
+}
+
+// postReleaseTrainStatus will record the status of the pipeline and post the aggregated status of its release
+// train on the tag commit (called while holding the GitHub write slot of the pipeline status)
+func (h *Handler) postReleaseTrainStatus(ctx context.Context, train, pipelineName, owner, repo, commit, status, targetURL string) error {
+	statuses, err := recordTrainMember(ctx, h.deps.DynamoDB, h.cfg.ReleaseTrainTable, train, commit, pipelineName, status, time.Now())
+	if err != nil {
+		return err
+	}
+	state, description := trainStatus(trainMembers(h.cfg.ReleaseTrains, train), statuses)
+
+	var req *http.Request
+	if req, err = h.newGithubRequest(
+		ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", owner, repo, commit), &payload{
+			Context:     releaseTrainContextPrefix + train,
+			Description: joinDescription(description),
+			State:       state,
+			TargetURL:   targetURL,
+		},
+	); err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusCreated, nil)
+}
@@ -0,0 +1,95 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestTrainStatus will test trainStatus()
+func TestTrainStatus(t *testing.T) {
+	members := []string{"api", "web", "worker"}
+
+	var tests = []struct {
+		statuses            map[string]string
+		expectedState       string
+		expectedDescription string
+	}{
+		{map[string]string{"api": githubStateSuccess}, githubStatePending, "api: success, web: waiting, worker: waiting"},
+		{map[string]string{"api": githubStateSuccess, "web": githubStateSuccess, "worker": githubStateSuccess}, githubStateSuccess, "api: success, web: success, worker: success"},
+		{map[string]string{"api": githubStateSuccess, "web": githubStateFailure}, githubStateFailure, "api: success, web: failure, worker: waiting"},
+		{map[string]string{"api": githubStateError, "web": githubStatePending}, githubStateFailure, "api: error, web: pending, worker: waiting"},
+	}
+
+	for _, test := range tests {
+		if state, description := trainStatus(members, test.statuses); state != test.expectedState {
+			t.Errorf("%s Failed: [%v] inputted, expected state [%s] but got [%s]", t.Name(), test.statuses, test.expectedState, state)
+		} else if description != test.expectedDescription {
+			t.Errorf("%s Failed: [%v] inputted, expected [%s] but got [%s]", t.Name(), test.statuses, test.expectedDescription, description)
+		}
+	}
+}
+
+// TestTrainMembers will test trainMembers()
+func TestTrainMembers(t *testing.T) {
+	members := trainMembers(stringMap{"web": "platform", "api": "platform", "search": "search"}, "platform")
+	if len(members) != 2 || members[0] != "api" || members[1] != "web" {
+		t.Fatal("members were not as expected", members)
+	}
+}
+
+// TestRecordTrainMember will test recordTrainMember()
+func TestRecordTrainMember(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	now := time.Now()
+
+	if _, err := recordTrainMember(context.Background(), mockDynamo, "trains", "platform", "abc", "api", githubStateSuccess, now); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	statuses, err := recordTrainMember(context.Background(), mockDynamo, "trains", "platform", "abc", "web", githubStatePending, now)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(statuses) != 2 || statuses["api"] != githubStateSuccess || statuses["web"] != githubStatePending {
+		t.Fatal("statuses were not as expected", statuses)
+	}
+
+	// Missing table
+	if _, err = recordTrainMember(context.Background(), mockDynamo, "", "platform", "abc", "web", githubStatePending, now); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventReleaseTrain will test ProcessEvent() for a member of a release train
+func TestHandlerProcessEventReleaseTrain(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		ReleaseTrains:        stringMap{"status-succeed": "platform", "web": "platform"},
+		ReleaseTrainTable:    "trains",
+		Stage:                stageTesting,
+	})
+
+	received := make(map[string]payload)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status payload
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		received[status.Context] = status
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if train := received["release-train/platform"]; train.State != githubStatePending {
+		t.Fatal("train state was not as expected", train.State)
+	} else if train.Description != "status-succeed: success, web: waiting" {
+		t.Fatal("train description was not as expected", train.Description)
+	} else if received[defaultStatusContext].State != githubStateSuccess {
+		t.Fatal("pipeline status was not as expected", received[defaultStatusContext])
+	}
+}
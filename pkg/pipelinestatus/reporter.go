@@ -0,0 +1,101 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StatusUpdate is a commit status posted by a StatusReporter (the state uses the GitHub names,
+// IE: pending, success)
+type StatusUpdate struct {
+	Commit      string
+	Context     string
+	Description string
+	Owner       string // GitLab namespace (IE: group/subgroup)
+	Repo        string
+	State       string
+	TargetURL   string
+}
+
+// StatusReporter posts the commit statuses to the forge hosting the code (IE: GitHub, GitLab, Bitbucket, Gitea
+// or Azure DevOps)
+type StatusReporter interface {
+	Name() string
+	PostStatus(ctx context.Context, status StatusUpdate) error
+}
+
+// githubReporter posts the commit statuses with the GitHub API of the handler
+type githubReporter struct {
+	h *Handler
+}
+
+// Name will return the name of the forge
+func (r *githubReporter) Name() string {
+	return forgeGithub
+}
+
+// PostStatus will create the commit status
+func (r *githubReporter) PostStatus(ctx context.Context, status StatusUpdate) error {
+	req, err := r.h.newGithubRequest(
+		ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", status.Owner, status.Repo, status.Commit), &payload{
+			Context:     status.Context,
+			Description: status.Description,
+			State:       status.State,
+			TargetURL:   status.TargetURL,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return r.h.doGithubRequest(req, http.StatusCreated, nil)
+}
+
+// forgeHosts will return the forges of the self-hosted hosts (IE: the hosts of GITHUB_API_BASE_URL and GITEA_URL)
+func (h *Handler) forgeHosts() map[string]string {
+	hosts := map[string]string{h.githubWebHost(): forgeGithub}
+	if giteaURL, err := url.Parse(h.cfg.GiteaURL); err == nil && len(giteaURL.Host) > 0 {
+		hosts[giteaURL.Host] = forgeGitea
+	}
+	return hosts
+}
+
+// statusReporter will return the reporter of the forge hosting the revision url (the pipelines in
+// GITEA_PIPELINES and AZURE_DEVOPS_REPOSITORIES always report there, IE: mirrors of GitHub repositories)
+func (h *Handler) statusReporter(pipelineName string, revisionURL *url.URL) StatusReporter {
+	forge := revisionForge(revisionURL, h.forgeHosts())
+	for _, name := range h.cfg.GiteaPipelines {
+		if name == pipelineName {
+			forge = forgeGitea
+		}
+	}
+	if _, ok := h.cfg.AzureDevOpsRepositories[pipelineName]; ok {
+		forge = forgeAzureDevOps
+	}
+	switch forge {
+	case forgeBitbucket:
+		return &bitbucketReporter{accessToken: h.cfg.BitbucketAccessToken, apiURL: bitbucketAPIURL,
+			appPassword: h.cfg.BitbucketAppPassword, client: h.deps.Bitbucket, username: h.cfg.BitbucketUsername}
+	case forgeAzureDevOps:
+		return &azureDevOpsReporter{apiURL: azureAPIURL, client: h.deps.AzureDevOps,
+			repository: h.cfg.AzureDevOpsRepositories[pipelineName], token: h.cfg.AzureDevOpsToken}
+	case forgeGitea:
+		return &giteaReporter{apiURL: strings.TrimSuffix(h.cfg.GiteaURL, "/") + giteaAPIPath, client: h.deps.Gitea,
+			token: h.cfg.GiteaAccessToken}
+	case forgeGitlab:
+		return &gitlabReporter{apiURL: gitlabAPIURL(revisionURL), client: h.deps.GitLab, token: h.cfg.GitlabAccessToken}
+	}
+	return &githubReporter{h: h}
+}
+
+// postStatus will post the status with the reporter of the pipeline once it is our turn to write
+func (h *Handler) postStatus(ctx context.Context, pipelineName string, revisionURL *url.URL, status StatusUpdate) error {
+	release, err := h.acquireGithubWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return h.statusReporter(pipelineName, revisionURL).PostStatus(ctx, status)
+}
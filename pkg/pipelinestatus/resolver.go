@@ -0,0 +1,57 @@
+package pipelinestatus
+
+import (
+	"fmt"
+)
+
+// ResolvedStatus is the commit status posted to GitHub for an execution
+type ResolvedStatus struct {
+	Context     string
+	Description string
+	State       string
+	TargetURL   string
+}
+
+// ResolverInput is the execution and artifact data given to a Resolver, Status is the built-in status
+type ResolverInput struct {
+	Commit      string
+	ExecutionID string
+	Owner       string
+	Pipeline    string
+	Region      string
+	Repo        string
+	State       string
+	Status      ResolvedStatus
+	Variables   map[string]string
+}
+
+// Resolver overrides the built-in status of an execution, the empty fields of the result keep
+// the built-in value (set it with the Resolver dependency)
+type Resolver func(input ResolverInput) (ResolvedStatus, error)
+
+// resolveStatus will apply the resolver to the built-in status and check the resolved state
+func resolveStatus(resolver Resolver, input ResolverInput) (ResolvedStatus, error) {
+	status := input.Status
+	resolved, err := resolver(input)
+	if err != nil {
+		return status, fmt.Errorf("unable to resolve the status: %s", err.Error())
+	}
+	if len(resolved.Context) > 0 {
+		status.Context = resolved.Context
+	}
+	if len(resolved.Description) > 0 {
+		status.Description = joinDescription(resolved.Description)
+	}
+	if len(resolved.TargetURL) > 0 {
+		status.TargetURL = resolved.TargetURL
+	}
+	switch resolved.State {
+	case "":
+	case githubStateError, githubStateFailure, githubStatePending, githubStateSuccess:
+		status.State = resolved.State
+	default:
+		return status, fmt.Errorf("invalid resolved state: %s (available: %s, %s, %s, %s)", resolved.State,
+			githubStateError, githubStateFailure, githubStatePending, githubStateSuccess)
+	}
+	return status, nil
+}
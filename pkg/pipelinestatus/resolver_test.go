@@ -0,0 +1,85 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// TestResolveStatus will test resolveStatus()
+func TestResolveStatus(t *testing.T) {
+	builtIn := ResolvedStatus{
+		Context:     defaultStatusContext,
+		Description: "started by jane",
+		State:       githubStateSuccess,
+		TargetURL:   "https://console.aws.amazon.com",
+	}
+
+	var tests = []struct {
+		resolved      ResolvedStatus
+		expected      ResolvedStatus
+		expectedError bool
+	}{
+		{ResolvedStatus{}, builtIn, false},
+		{ResolvedStatus{Context: "deploy/api"}, ResolvedStatus{"deploy/api", "started by jane", githubStateSuccess, "https://console.aws.amazon.com"}, false},
+		{ResolvedStatus{State: githubStateFailure, TargetURL: "https://ci.example.com"}, ResolvedStatus{defaultStatusContext, "started by jane", githubStateFailure, "https://ci.example.com"}, false},
+		{ResolvedStatus{Description: "deployed to staging"}, ResolvedStatus{defaultStatusContext, "deployed to staging", githubStateSuccess, "https://console.aws.amazon.com"}, false},
+		{ResolvedStatus{State: "neutral"}, builtIn, true},
+	}
+
+	for _, test := range tests {
+		resolver := func(input ResolverInput) (ResolvedStatus, error) { return test.resolved, nil }
+		if status, err := resolveStatus(resolver, ResolverInput{Status: builtIn}); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error not expected but got: %s", t.Name(), test.resolved, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error was expected", t.Name(), test.resolved)
+		} else if err == nil && status != test.expected {
+			t.Errorf("%s Failed: [%v] inputted, expected [%v] but got [%v]", t.Name(), test.resolved, test.expected, status)
+		}
+	}
+
+	// Resolver error
+	if _, err := resolveStatus(func(input ResolverInput) (ResolvedStatus, error) {
+		return ResolvedStatus{}, errors.New("unknown team")
+	}, ResolverInput{Status: builtIn}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventResolver will test ProcessEvent() with a custom resolver
+func TestHandlerProcessEventResolver(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+
+	var input ResolverInput
+	h.deps.Resolver = func(in ResolverInput) (ResolvedStatus, error) {
+		input = in
+		return ResolvedStatus{Context: "deploy/" + in.Pipeline, TargetURL: "https://ci.example.com/" + in.ExecutionID}, nil
+	}
+
+	var received payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if input.Commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" || input.Status.Context != defaultStatusContext {
+		t.Fatal("resolver input was not as expected", input)
+	} else if received.Context != "deploy/status-succeed" {
+		t.Fatal("context was not as expected", received.Context)
+	} else if received.TargetURL != "https://ci.example.com/12345678" {
+		t.Fatal("target url was not as expected", received.TargetURL)
+	} else if received.State != githubStateSuccess {
+		t.Fatal("state was not as expected", received.State)
+	}
+}
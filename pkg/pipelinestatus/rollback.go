@@ -0,0 +1,89 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// rollbackTriggers are the trigger types of executions that redeploy the source revisions of an earlier execution
+var rollbackTriggers = map[types.TriggerType]bool{
+	types.TriggerTypeAutomatedRollback: true,
+	types.TriggerTypeManualRollback:    true,
+}
+
+// isRollback will return true if the execution is a (manual or automated) rollback
+func isRollback(executionOutput *codepipeline.GetPipelineExecutionOutput) bool {
+	trigger := executionOutput.PipelineExecution.Trigger
+	return trigger != nil && rollbackTriggers[trigger.TriggerType]
+}
+
+// getRolledBackCommit will return the commit that was deployed when the rollback started, the commit
+// of the last successful execution before the rollback (empty if there is none)
+func (h *Handler) getRolledBackCommit(ctx context.Context, pipelineName, executionID string) (commit string, revisionURL *url.URL, err error) {
+
+	// Executions are listed newest first
+	var previousID string
+	var passedRollback bool
+	paginator := codepipeline.NewListPipelineExecutionsPaginator(h.deps.CodePipeline,
+		&codepipeline.ListPipelineExecutionsInput{PipelineName: aws.String(pipelineName)})
+pages:
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListPipelineExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, summary := range page.PipelineExecutionSummaries {
+			if aws.StringValue(summary.PipelineExecutionId) == executionID {
+				passedRollback = true
+			} else if passedRollback && summary.Status == types.PipelineExecutionStatusSucceeded {
+				previousID = aws.StringValue(summary.PipelineExecutionId)
+				break pages
+			}
+		}
+	}
+	if len(previousID) == 0 {
+		return
+	}
+
+	commit, _, revisionURL, err = getCommit(ctx, pipelineName, previousID, h.primaryArtifact(pipelineName), h.forgeHosts(),
+		h.cfg.CodeCommitMirrors, h.deps.CodePipeline)
+	return
+}
+
+// rolledBackStatus will return the status of the rolled back commit for the status of the rollback
+// (the commit stays deployed if the rollback fails)
+func rolledBackStatus(rollbackStatus, restored string) (state, description string) {
+	switch rollbackStatus {
+	case githubStatePending:
+		return githubStatePending, "rolling back to " + shortSHA(restored)
+	case githubStateSuccess:
+		return githubStateFailure, "rolled back to " + shortSHA(restored)
+	default:
+		return githubStateSuccess, "rollback to " + shortSHA(restored) + " failed"
+	}
+}
+
+// postRolledBackStatus will post the status of the rollback on the commit being rolled back
+// (called while holding the GitHub write slot of the rollback status)
+func (h *Handler) postRolledBackStatus(ctx context.Context, pipelineName string, revisionURL *url.URL, commit, restored,
+	rollbackStatus, statusCtx, targetURL string) error {
+	owner, repo := h.pipelineRepository(pipelineName, revisionURL)
+	if len(owner) == 0 || len(repo) == 0 {
+		return fmt.Errorf("unable to parse the revision url: %s", revisionURL.String())
+	}
+	state, description := rolledBackStatus(rollbackStatus, restored)
+	return h.statusReporter(pipelineName, revisionURL).PostStatus(ctx, StatusUpdate{
+		Commit:      commit,
+		Context:     statusCtx,
+		Description: joinDescription(description),
+		Owner:       owner,
+		Repo:        repo,
+		State:       state,
+		TargetURL:   targetURL,
+	})
+}
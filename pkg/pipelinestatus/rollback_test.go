@@ -0,0 +1,160 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockRollbackPipelineClient is a pipeline with a deploy of "bad" rolled back to "good"
+type mockRollbackPipelineClient struct {
+	mockCodePipelineClient
+	rollbackStatus types.PipelineExecutionStatus
+}
+
+// rollbackCommits are the commits of the executions of the rollback pipeline
+var rollbackCommits = map[string]string{
+	"bad":      "badbad0000000000000000000000000000000000",
+	"broken":   "b0000000000000000000000000000000000000e0",
+	"good":     "900d000000000000000000000000000000000000",
+	"newer":    "0e00000000000000000000000000000000000000",
+	"rollback": "900d000000000000000000000000000000000000",
+}
+
+// GetPipelineExecution is a mock request for codepipeline
+func (m *mockRollbackPipelineClient) GetPipelineExecution(ctx context.Context, input *codepipeline.GetPipelineExecutionInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineExecutionOutput, error) {
+	executionID := aws.StringValue(input.PipelineExecutionId)
+	execution := &types.PipelineExecution{
+		ArtifactRevisions: []types.ArtifactRevision{{
+			Name:        aws.String(sourceArtifactName),
+			RevisionId:  aws.String(rollbackCommits[executionID]),
+			RevisionUrl: aws.String("https://github.com/mrz1836/codepipeline-to-github/commit/" + rollbackCommits[executionID]),
+		}},
+		PipelineExecutionId: input.PipelineExecutionId,
+		PipelineName:        input.PipelineName,
+		Status:              types.PipelineExecutionStatusSucceeded,
+	}
+	if executionID == "rollback" {
+		execution.Status = m.rollbackStatus
+		execution.Trigger = &types.ExecutionTrigger{TriggerType: types.TriggerTypeAutomatedRollback}
+	}
+	return &codepipeline.GetPipelineExecutionOutput{PipelineExecution: execution}, nil
+}
+
+// ListPipelineExecutions is a mock request for codepipeline (newest first)
+func (m *mockRollbackPipelineClient) ListPipelineExecutions(_ context.Context, input *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	if input.NextToken != nil {
+		return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+			{PipelineExecutionId: aws.String("bad"), Status: types.PipelineExecutionStatusSucceeded},
+			{PipelineExecutionId: aws.String("good"), Status: types.PipelineExecutionStatusSucceeded},
+		}}, nil
+	}
+	return &codepipeline.ListPipelineExecutionsOutput{NextToken: aws.String("2"), PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{PipelineExecutionId: aws.String("newer"), Status: types.PipelineExecutionStatusSucceeded},
+		{PipelineExecutionId: aws.String("rollback"), Status: m.rollbackStatus},
+		{PipelineExecutionId: aws.String("broken"), Status: types.PipelineExecutionStatusFailed},
+	}}, nil
+}
+
+// TestIsRollback will test isRollback()
+func TestIsRollback(t *testing.T) {
+	var tests = []struct {
+		triggerType types.TriggerType
+		expected    bool
+	}{
+		{types.TriggerTypeAutomatedRollback, true},
+		{types.TriggerTypeManualRollback, true},
+		{types.TriggerTypeWebhook, false},
+	}
+
+	for _, test := range tests {
+		if rollback := isRollback(newTriggeredExecution(test.triggerType, "")); rollback != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%v] but got [%v]", t.Name(), test.triggerType, test.expected, rollback)
+		}
+	}
+	if isRollback(&codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{}}) {
+		t.Fatal("execution without a trigger is not a rollback")
+	}
+}
+
+// TestRolledBackStatus will test rolledBackStatus()
+func TestRolledBackStatus(t *testing.T) {
+	var tests = []struct {
+		rollbackStatus      string
+		expectedState       string
+		expectedDescription string
+	}{
+		{githubStatePending, githubStatePending, "rolling back to 25c0c3e"},
+		{githubStateSuccess, githubStateFailure, "rolled back to 25c0c3e"},
+		{githubStateFailure, githubStateSuccess, "rollback to 25c0c3e failed"},
+	}
+
+	for _, test := range tests {
+		if state, description := rolledBackStatus(test.rollbackStatus, "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"); state != test.expectedState {
+			t.Errorf("%s Failed: [%s] inputted, expected state [%s] but got [%s]", t.Name(), test.rollbackStatus, test.expectedState, state)
+		} else if description != test.expectedDescription {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.rollbackStatus, test.expectedDescription, description)
+		}
+	}
+}
+
+// TestGetRolledBackCommit will test getRolledBackCommit()
+func TestGetRolledBackCommit(t *testing.T) {
+	h := newTestHandler(Config{})
+	h.deps.CodePipeline = &mockRollbackPipelineClient{rollbackStatus: types.PipelineExecutionStatusInProgress}
+
+	if commit, revisionURL, err := h.getRolledBackCommit(context.Background(), "some-pipeline", "rollback"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commit != rollbackCommits["bad"] {
+		t.Fatal("commit was not as expected", commit)
+	} else if !strings.HasSuffix(revisionURL.Path, "/commit/"+rollbackCommits["bad"]) {
+		t.Fatal("revision url was not as expected", revisionURL)
+	}
+
+	// Unknown execution
+	if commit, _, err := h.getRolledBackCommit(context.Background(), "some-pipeline", "missing"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(commit) > 0 {
+		t.Fatal("commit should be empty", commit)
+	}
+}
+
+// TestHandlerProcessEventRollback will test ProcessEvent() for a rollback execution
+func TestHandlerProcessEventRollback(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	h.deps.CodePipeline = &mockRollbackPipelineClient{rollbackStatus: types.PipelineExecutionStatusSucceeded}
+
+	received := make(map[string]payload)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status payload
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		received[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = status
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "rollback",
+		Pipeline:    "some-pipeline",
+		State:       "SUCCEEDED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if restored := received[rollbackCommits["good"]]; restored.State != githubStateSuccess {
+		t.Fatal("restored state was not as expected", restored.State)
+	} else if restored.Description != "rollback of badbad0" {
+		t.Fatal("restored description was not as expected", restored.Description)
+	} else if rolledBack := received[rollbackCommits["bad"]]; rolledBack.State != githubStateFailure {
+		t.Fatal("rolled back state was not as expected", rolledBack.State)
+	} else if rolledBack.Description != "rolled back to 900d000" {
+		t.Fatal("rolled back description was not as expected", rolledBack.Description)
+	} else if rolledBack.Context != restored.Context || rolledBack.TargetURL != restored.TargetURL {
+		t.Fatal("rolled back status should link the rollback", rolledBack)
+	}
+}
@@ -0,0 +1,137 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rollupMarker identifies the rollup comment of the bridge on a pull request
+const rollupMarker = "<!-- codepipeline-to-github:rollup -->"
+
+// rollupRow is the latest status of one context of the commit
+type rollupRow struct {
+	Context     string
+	Description string
+	Duration    time.Duration // from the first status of the context (zero while pending)
+	State       string
+	TargetURL   string
+	started     time.Time
+	updated     time.Time
+}
+
+// rollupRows will return the latest status of each context (statuses are newest first), sorted by context
+func rollupRows(statuses []commitStatus) (rows []rollupRow) {
+	index := make(map[string]int)
+	for _, status := range statuses {
+		i, ok := index[status.Context]
+		if !ok {
+			i = len(rows)
+			index[status.Context] = i
+			rows = append(rows, rollupRow{
+				Context:     status.Context,
+				Description: status.Description,
+				State:       status.State,
+				TargetURL:   status.TargetURL,
+				updated:     status.CreatedAt,
+			})
+		}
+		rows[i].started = status.CreatedAt
+	}
+
+	// Finished contexts took from their first to their latest status
+	for i := range rows {
+		if rows[i].State != githubStatePending && !rows[i].started.IsZero() {
+			rows[i].Duration = rows[i].updated.Sub(rows[i].started)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Context < rows[j].Context
+	})
+	return
+}
+
+// formatRollup will create the markdown table of the statuses of the commit
+func formatRollup(commit string, rows []rollupRow) string {
+	var b strings.Builder
+	b.WriteString(rollupMarker + "\n")
+	_, _ = fmt.Fprintf(&b, "### Pipelines for %s\n\n", shortSHA(commit))
+	b.WriteString("| Pipeline | Status | Duration | Details |\n| --- | --- | --- | --- |\n")
+	for _, row := range rows {
+		duration := "-"
+		if row.Duration > 0 {
+			duration = row.Duration.Round(time.Second).String()
+		}
+		details := strings.ReplaceAll(row.Description, "|", "\\|")
+		if len(row.TargetURL) > 0 {
+			if len(details) == 0 {
+				details = "Details"
+			}
+			details = fmt.Sprintf("[%s](%s)", details, row.TargetURL)
+		}
+		_, _ = fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", row.Context, row.State, duration, details)
+	}
+	return b.String()
+}
+
+// postRollupComment will create (or update) the rollup comment of the statuses of the commit on each
+// open pull request with the commit as head
+func (h *Handler) postRollupComment(ctx context.Context, owner, repo, commit string) error {
+
+	// Pull requests of the head commit
+	var pulls []pullRequest
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/pulls", owner, repo, commit), &pulls); err != nil {
+		return err
+	}
+	var numbers []int
+	for _, pull := range pulls {
+		if pull.State == "open" && pull.Head.SHA == commit {
+			numbers = append(numbers, pull.Number)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	// All the statuses of the commit
+	var statuses []commitStatus
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s/statuses?per_page=100", owner, repo, commit), &statuses); err != nil {
+		return err
+	}
+	body := formatRollup(commit, rollupRows(statuses))
+
+	for _, number := range numbers {
+		if err := h.upsertRollupComment(ctx, owner, repo, number, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertRollupComment will update the rollup comment of a pull request (created if missing)
+func (h *Handler) upsertRollupComment(ctx context.Context, owner, repo string, number int, body string) error {
+	var comments []issueComment
+	if err := h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/issues/%d/comments?per_page=100", owner, repo, number), &comments); err != nil {
+		return err
+	}
+
+	method, path, expectedCode := http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number), http.StatusCreated
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.Body, rollupMarker) {
+			if comment.Body == body {
+				return nil
+			}
+			method, path, expectedCode = http.MethodPatch, fmt.Sprintf("/repos/%s/%s/issues/comments/%d", owner, repo, comment.ID), http.StatusOK
+			break
+		}
+	}
+
+	req, err := h.newGithubRequest(ctx, method, path, &issueComment{Body: body})
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, expectedCode, nil)
+}
@@ -0,0 +1,96 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRollupRows will test rollupRows()
+func TestRollupRows(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	rows := rollupRows([]commitStatus{
+		{Context: "ci/web", CreatedAt: started.Add(4 * time.Minute), State: githubStateSuccess, TargetURL: "https://web"},
+		{Context: "ci/api", CreatedAt: started.Add(2 * time.Minute), State: githubStatePending},
+		{Context: "ci/web", CreatedAt: started.Add(time.Minute), State: githubStatePending},
+		{Context: "ci/web", CreatedAt: started, State: githubStatePending},
+	})
+	if len(rows) != 2 {
+		t.Fatal("rows were not as expected", rows)
+	} else if rows[0].Context != "ci/api" || rows[0].State != githubStatePending || rows[0].Duration != 0 {
+		t.Fatal("api row was not as expected", rows[0])
+	} else if rows[1].Context != "ci/web" || rows[1].State != githubStateSuccess || rows[1].Duration != 4*time.Minute {
+		t.Fatal("web row was not as expected", rows[1])
+	}
+}
+
+// TestFormatRollup will test formatRollup()
+func TestFormatRollup(t *testing.T) {
+	t.Parallel()
+
+	rollup := formatRollup("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", []rollupRow{
+		{Context: "ci/api", Description: "Deploy | Build", State: githubStatePending},
+		{Context: "ci/web", Duration: 252 * time.Second, State: githubStateSuccess, TargetURL: "https://web"},
+	})
+	if !strings.HasPrefix(rollup, rollupMarker+"\n### Pipelines for 25c0c3e\n") {
+		t.Fatal("header was not as expected", rollup)
+	} else if !strings.Contains(rollup, "| `ci/api` | pending | - | Deploy \\| Build |\n") {
+		t.Fatal("api row was not as expected", rollup)
+	} else if !strings.Contains(rollup, "| `ci/web` | success | 4m12s | [Details](https://web) |\n") {
+		t.Fatal("web row was not as expected", rollup)
+	}
+}
+
+// TestPostRollupComment will test postRollupComment() creating and then updating the comment
+func TestPostRollupComment(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+	commit := "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"
+
+	var comments []issueComment
+	var writes []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pulls"):
+			_, _ = w.Write([]byte(`[{"number":7,"state":"open","head":{"sha":"` + commit + `"}},` +
+				`{"number":8,"state":"open","head":{"sha":"1111111111111111111111111111111111111111"}}]`))
+		case strings.HasSuffix(r.URL.Path, "/statuses"):
+			_, _ = w.Write([]byte(`[{"context":"ci/web","state":"success","target_url":"https://web"}]`))
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(comments)
+		default:
+			writes = append(writes, r.Method+" "+r.URL.Path)
+			var comment issueComment
+			_ = json.NewDecoder(r.Body).Decode(&comment)
+			comment.ID = 99
+			comments = []issueComment{{Body: "Looks good"}, comment}
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+			}
+		}
+	})
+
+	// Created on the first status
+	if err := h.postRollupComment(context.Background(), "mrz1836", "codepipeline-to-github", commit); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(writes) != 1 || writes[0] != "POST /repos/mrz1836/codepipeline-to-github/issues/7/comments" {
+		t.Fatal("writes were not as expected", writes)
+	}
+
+	// Unchanged rollup is not written again, a changed one is updated in place
+	if err := h.postRollupComment(context.Background(), "mrz1836", "codepipeline-to-github", commit); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(writes) != 1 {
+		t.Fatal("unchanged rollup should not have been written", writes)
+	}
+	comments[1].Body = rollupMarker + "\nstale"
+	if err := h.postRollupComment(context.Background(), "mrz1836", "codepipeline-to-github", commit); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(writes) != 2 || writes[1] != "PATCH /repos/mrz1836/codepipeline-to-github/issues/comments/99" {
+		t.Fatal("writes were not as expected", writes)
+	}
+}
@@ -0,0 +1,96 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Source action defaults
+const (
+	sourceProviderGithub   = "GitHub"
+	sourceProviderCodeStar = "CodeStarSourceConnection"
+)
+
+// branchCommit is the part of the GitHub commit response needed for the branch head
+type branchCommit struct {
+	SHA string `json:"sha"`
+}
+
+// isScheduled will return true if the execution was started by a schedule (no push)
+func isScheduled(executionOutput *codepipeline.GetPipelineExecutionOutput) bool {
+	trigger := executionOutput.PipelineExecution.Trigger
+	return trigger != nil && trigger.TriggerType == types.TriggerTypeCloudWatchEvent
+}
+
+// getBranchHead will resolve the current head commit of the branch the pipeline builds (or the commit of the
+// tag if the source action builds a tag, IE: refs/tags/v1.2.3)
+func (h *Handler) getBranchHead(ctx context.Context, pipelineName string) (commit string, revisionURL *url.URL, tag string, err error) {
+
+	// Find the repository and branch from the source action
+	var owner, repo, branch string
+	if owner, repo, branch, err = getSourceBranch(ctx, pipelineName, h.deps.CodePipeline); err != nil {
+		return
+	} else if strings.HasPrefix(branch, tagRefPrefix) {
+		tag = strings.TrimPrefix(branch, tagRefPrefix)
+		branch = tag
+	}
+
+	// Get the head of the branch
+	var head branchCommit
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, url.PathEscape(branch)), &head); err != nil {
+		return
+	} else if len(head.SHA) == 0 {
+		err = fmt.Errorf("missing head commit for branch: %s/%s:%s", owner, repo, branch)
+		return
+	}
+
+	commit = head.SHA
+	revisionURL, err = url.Parse(fmt.Sprintf("https://%s/%s/%s/commit/%s", h.githubWebHost(), owner, repo, commit))
+	return
+}
+
+// getSourceBranch will return the GitHub repository and branch of the pipeline's source action
+func getSourceBranch(ctx context.Context, pipelineName string,
+	pipeline CodePipelineAPI) (owner, repo, branch string, err error) {
+	owner, repo, branch, _, err = getSourceAction(ctx, pipelineName, pipeline)
+	return
+}
+
+// getSourceAction will return the GitHub repository and branch of the pipeline's source action with the action
+func getSourceAction(ctx context.Context, pipelineName string,
+	pipeline CodePipelineAPI) (owner, repo, branch string, action types.ActionDeclaration, err error) {
+	var output *codepipeline.GetPipelineOutput
+	if output, err = pipeline.GetPipeline(ctx, &codepipeline.GetPipelineInput{
+		Name: aws.String(pipelineName),
+	}); err != nil {
+		return
+	} else if output == nil || output.Pipeline == nil {
+		err = fmt.Errorf("missing pipeline: %s", pipelineName)
+		return
+	}
+
+	for _, stage := range output.Pipeline.Stages {
+		for _, action = range stage.Actions {
+			if action.ActionTypeId == nil || action.ActionTypeId.Category != types.ActionCategorySource {
+				continue
+			}
+			cfg := action.Configuration
+			switch aws.StringValue(action.ActionTypeId.Provider) {
+			case sourceProviderGithub:
+				return cfg["Owner"], cfg["Repo"], cfg["Branch"], action, nil
+			case sourceProviderCodeStar:
+				if parts := strings.SplitN(cfg["FullRepositoryId"], "/", 2); len(parts) == 2 {
+					return parts[0], parts[1], cfg["BranchName"], action, nil
+				}
+			}
+		}
+	}
+
+	return "", "", "", types.ActionDeclaration{}, fmt.Errorf("no GitHub source action found in pipeline: %s", pipelineName)
+}
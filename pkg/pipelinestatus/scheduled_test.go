@@ -0,0 +1,119 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// GetPipeline is a mock request for codepipeline
+func (m *mockCodePipelineClient) GetPipeline(_ context.Context, input *codepipeline.GetPipelineInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineOutput, error) {
+	source := &types.ActionDeclaration{
+		Name: aws.String("Source"),
+		ActionTypeId: &types.ActionTypeId{
+			Category: types.ActionCategorySource,
+			Provider: aws.String(sourceProviderGithub),
+		},
+		Configuration: map[string]string{
+			"Owner":  "mrz1836",
+			"Repo":   "codepipeline-to-github",
+			"Branch": "master",
+		},
+	}
+	switch aws.StringValue(input.Name) {
+	case "nil":
+		return nil, nil
+	case "codestar-pipeline":
+		source.ActionTypeId.Provider = aws.String(sourceProviderCodeStar)
+		source.Configuration = map[string]string{
+			"FullRepositoryId": "mrz1836/codepipeline-to-github",
+			"BranchName":       "development",
+		}
+	case "s3-pipeline":
+		source.ActionTypeId.Provider = aws.String("S3")
+	}
+	return &codepipeline.GetPipelineOutput{Metadata: &types.PipelineMetadata{
+		PipelineArn: aws.String("arn:aws:codepipeline:us-east-1:123:" + aws.StringValue(input.Name)),
+	}, Pipeline: &types.PipelineDeclaration{
+		Name: input.Name,
+		Stages: []types.StageDeclaration{
+			{Name: aws.String("Source"), Actions: []types.ActionDeclaration{*source}},
+		},
+	}}, nil
+}
+
+// TestIsScheduled will test isScheduled()
+func TestIsScheduled(t *testing.T) {
+	t.Parallel()
+
+	if !isScheduled(newTriggeredExecution(types.TriggerTypeCloudWatchEvent, "arn:aws:events:us-east-1:123:rule/nightly")) {
+		t.Fatal("execution should be scheduled")
+	} else if isScheduled(newTriggeredExecution(types.TriggerTypeWebhook, "")) {
+		t.Fatal("execution should not be scheduled")
+	} else if isScheduled(&codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{}}) {
+		t.Fatal("execution without a trigger should not be scheduled")
+	}
+}
+
+// TestGetSourceBranch will test getSourceBranch()
+func TestGetSourceBranch(t *testing.T) {
+	t.Parallel()
+
+	mockPipeline := &mockCodePipelineClient{}
+
+	var tests = []struct {
+		pipelineName   string
+		expectedOwner  string
+		expectedRepo   string
+		expectedBranch string
+		expectedError  bool
+	}{
+		{"some-pipeline", "mrz1836", "codepipeline-to-github", "master", false},
+		{"codestar-pipeline", "mrz1836", "codepipeline-to-github", "development", false},
+		{"s3-pipeline", "", "", "", true},
+		{"nil", "", "", "", true},
+	}
+
+	for _, test := range tests {
+		owner, repo, branch, err := getSourceBranch(context.Background(), test.pipelineName, mockPipeline)
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: pipeline [%s], expected to throw an error, but no error", t.Name(), test.pipelineName)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: pipeline [%s], error occurred [%s]", t.Name(), test.pipelineName, err.Error())
+		} else if owner != test.expectedOwner || repo != test.expectedRepo || branch != test.expectedBranch {
+			t.Errorf("%s Failed: pipeline [%s], expected [%s/%s:%s] but got [%s/%s:%s]", t.Name(), test.pipelineName,
+				test.expectedOwner, test.expectedRepo, test.expectedBranch, owner, repo, branch)
+		}
+	}
+}
+
+// TestGetBranchHead will test getBranchHead()
+func TestGetBranchHead(t *testing.T) {
+	h := newTestHandler(Config{})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/mrz1836/codepipeline-to-github/commits/master" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sha":"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"}`))
+	})
+
+	commit, revisionURL, _, err := h.getBranchHead(context.Background(), "some-pipeline")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("commit was not as expected", commit)
+	} else if revisionURL.String() != "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("revisionURL was not as expected", revisionURL.String())
+	}
+
+	// Branch without a head
+	if _, _, _, err = h.getBranchHead(context.Background(), "codestar-pipeline"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
@@ -0,0 +1,123 @@
+package pipelinestatus
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHub secondary rate limits (abuse detection): a 403 or 429 whose body mentions the secondary rate limit,
+// GitHub asks to wait for the Retry-After (or a minute without it) before the next request
+const (
+	abuseDetectionMessage            = "abuse detection"
+	defaultSecondaryRateLimitWait    = time.Minute
+	enterpriseCloudHostSuffix        = ".ghe.com" // GitHub Enterprise Cloud with data residency has the limits of github.com
+	enterpriseSecondaryRateLimitWait = 2 * time.Minute
+	metricGithubSecondaryRateLimit   = "GithubSecondaryRateLimit"
+	secondaryRateLimitMessage        = "secondary rate limit"
+)
+
+// githubLimitProfile is how the rate limits of a GitHub server are recognized and waited for
+type githubLimitProfile struct {
+	messages []string      // parts of the bodies of the limited responses (lowercase)
+	name     string        // logged with the pause
+	primary  bool          // a response with no remaining requests pauses until X-RateLimit-Reset
+	wait     time.Duration // wait without a Retry-After
+}
+
+// GitHub Enterprise Server limits are set by its admins (often stricter than github.com once enabled): older
+// versions answer with the "abuse detection mechanism" message, the Retry-After is rarely sent and the exhausted
+// primary limit is waited for (requests before the reset are refused and logged by the server)
+var (
+	githubDotComLimits = githubLimitProfile{
+		messages: []string{secondaryRateLimitMessage},
+		name:     "github.com",
+		wait:     defaultSecondaryRateLimitWait,
+	}
+	githubEnterpriseLimits = githubLimitProfile{
+		messages: []string{secondaryRateLimitMessage, abuseDetectionMessage},
+		name:     "enterprise server",
+		primary:  true,
+		wait:     enterpriseSecondaryRateLimitWait,
+	}
+)
+
+// Per-container cool-down of the GitHub requests after a secondary rate limit (requests fail fast until it ends,
+// more requests during the cool-down only extend the penalty)
+var (
+	githubCooldownMu    sync.Mutex
+	githubCooldownUntil time.Time
+)
+
+// secondaryRateLimitError is a request refused by the secondary rate limit (or skipped during the cool-down)
+type secondaryRateLimitError struct {
+	RetryAt time.Time
+}
+
+// Error will return when the requests are allowed again
+func (e *secondaryRateLimitError) Error() string {
+	return fmt.Sprintf("github secondary rate limit, requests are paused until %s", e.RetryAt.UTC().Format(time.RFC3339))
+}
+
+// githubLimits will return the limit profile of the GitHub server: GitHub Enterprise Server when GITHUB_API_BASE_URL
+// is set (unless it is a GitHub Enterprise Cloud host)
+func githubLimits(cfg Config) githubLimitProfile {
+	if len(cfg.GithubAPIBaseURL) == 0 {
+		return githubDotComLimits
+	} else if apiURL, err := url.Parse(cfg.GithubAPIBaseURL); err == nil && strings.HasSuffix(apiURL.Hostname(), enterpriseCloudHostSuffix) {
+		return githubDotComLimits
+	}
+	return githubEnterpriseLimits
+}
+
+// isLimited will return true if the response is a rate limit of the profile: a secondary rate limit (the primary
+// limit is a 403 with no remaining requests and a different message), or the exhausted primary limit
+func (p githubLimitProfile) isLimited(code int, header http.Header, body string) bool {
+	if code != http.StatusForbidden && code != http.StatusTooManyRequests {
+		return false
+	} else if p.primary && header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	body = strings.ToLower(body)
+	for _, message := range p.messages {
+		if strings.Contains(body, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAt will return when the requests are allowed again: the Retry-After (in seconds), the X-RateLimit-Reset of
+// an exhausted primary limit or the wait of the profile
+func (p githubLimitProfile) retryAt(header http.Header, now time.Time) time.Time {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return now.Add(time.Duration(seconds) * time.Second)
+	} else if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil && p.primary &&
+		header.Get("X-RateLimit-Remaining") == "0" && time.Unix(reset, 0).After(now) {
+		return time.Unix(reset, 0)
+	}
+	return now.Add(p.wait)
+}
+
+// startGithubCooldown will pause the GitHub requests of the container until the time (a later cool-down wins)
+func startGithubCooldown(until time.Time) {
+	githubCooldownMu.Lock()
+	defer githubCooldownMu.Unlock()
+	if until.After(githubCooldownUntil) {
+		githubCooldownUntil = until
+	}
+}
+
+// githubCooldown will return the end of the cool-down, zero if the requests are allowed
+func githubCooldown(now time.Time) time.Time {
+	githubCooldownMu.Lock()
+	defer githubCooldownMu.Unlock()
+	if now.Before(githubCooldownUntil) {
+		return githubCooldownUntil
+	}
+	return time.Time{}
+}
@@ -0,0 +1,149 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// resetGithubCooldown will allow the GitHub requests again
+func resetGithubCooldown() {
+	githubCooldownMu.Lock()
+	githubCooldownUntil = time.Time{}
+	githubCooldownMu.Unlock()
+}
+
+// TestGithubLimits will test githubLimits()
+func TestGithubLimits(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		apiBaseURL string
+		expected   string
+	}{
+		{"", githubDotComLimits.name},
+		{"https://github.mycorp.com/api/v3", githubEnterpriseLimits.name},
+		{"https://api.mycorp.ghe.com", githubDotComLimits.name},
+	}
+	for _, test := range tests {
+		if output := githubLimits(Config{GithubAPIBaseURL: test.apiBaseURL}).name; output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.apiBaseURL, test.expected, output)
+		}
+	}
+}
+
+// TestGithubLimitProfileIsLimited will test githubLimitProfile.isLimited()
+func TestGithubLimitProfileIsLimited(t *testing.T) {
+	t.Parallel()
+
+	exhausted := http.Header{"X-Ratelimit-Remaining": []string{"0"}}
+	var tests = []struct {
+		profile  githubLimitProfile
+		code     int
+		header   http.Header
+		body     string
+		expected bool
+	}{
+		{githubDotComLimits, http.StatusForbidden, http.Header{}, `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`, true},
+		{githubDotComLimits, http.StatusTooManyRequests, http.Header{}, `{"message":"You have exceeded a secondary rate limit."}`, true},
+		{githubDotComLimits, http.StatusForbidden, exhausted, `{"message":"API rate limit exceeded for user ID 1."}`, false},
+		{githubDotComLimits, http.StatusForbidden, http.Header{}, `{"message":"You have triggered an abuse detection mechanism."}`, false},
+		{githubDotComLimits, http.StatusForbidden, http.Header{}, `{"message":"Repository was archived so is read-only."}`, false},
+		{githubDotComLimits, http.StatusInternalServerError, http.Header{}, `secondary rate limit`, false},
+		{githubEnterpriseLimits, http.StatusForbidden, http.Header{}, `{"message":"You have triggered an abuse detection mechanism."}`, true},
+		{githubEnterpriseLimits, http.StatusForbidden, exhausted, `{"message":"API rate limit exceeded for user ID 1."}`, true},
+		{githubEnterpriseLimits, http.StatusForbidden, http.Header{}, `{"message":"Repository was archived so is read-only."}`, false},
+		{githubEnterpriseLimits, http.StatusInternalServerError, exhausted, ``, false},
+	}
+	for _, test := range tests {
+		if output := test.profile.isLimited(test.code, test.header, test.body); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%d] [%s] inputted and [%v] expected, received: [%v]", t.Name(), test.profile.name,
+				test.code, test.body, test.expected, output)
+		}
+	}
+}
+
+// TestGithubLimitProfileRetryAt will test githubLimitProfile.retryAt()
+func TestGithubLimitProfileRetryAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	reset := http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10)}}
+	var tests = []struct {
+		profile  githubLimitProfile
+		header   http.Header
+		expected time.Time
+	}{
+		{githubDotComLimits, http.Header{"Retry-After": []string{"30"}}, now.Add(30 * time.Second)},
+		{githubDotComLimits, http.Header{}, now.Add(defaultSecondaryRateLimitWait)},
+		{githubDotComLimits, reset, now.Add(defaultSecondaryRateLimitWait)},
+		{githubEnterpriseLimits, http.Header{"Retry-After": []string{"30"}}, now.Add(30 * time.Second)},
+		{githubEnterpriseLimits, http.Header{}, now.Add(enterpriseSecondaryRateLimitWait)},
+		{githubEnterpriseLimits, reset, now.Add(10 * time.Minute)},
+	}
+	for _, test := range tests {
+		if output := test.profile.retryAt(test.header, now); !output.Equal(test.expected) {
+			t.Errorf("%s Failed: [%s] [%v] inputted and [%s] expected, received: [%s]", t.Name(), test.profile.name,
+				test.header, test.expected, output)
+		}
+	}
+}
+
+// TestDoGithubRequestSecondaryRateLimit will test doGithubRequest() pausing the requests after a secondary rate limit
+func TestDoGithubRequestSecondaryRateLimit(t *testing.T) {
+	resetGithubCooldown()
+	defer resetGithubCooldown()
+
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+	var requests int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`))
+	})
+
+	// Refused, then paused without a request
+	started := time.Now()
+	for i := 0; i < 2; i++ {
+		err := h.githubGet(context.Background(), "/repos/mrz1836/codepipeline-to-github/commits/master", nil)
+		if limitErr, ok := err.(*secondaryRateLimitError); !ok {
+			t.Fatal("error was not as expected", err)
+		} else if limitErr.RetryAt.Before(started.Add(119*time.Second)) || limitErr.RetryAt.After(time.Now().Add(120*time.Second)) {
+			t.Fatal("retry time was not as expected", limitErr.RetryAt)
+		}
+	}
+	if requests != 1 {
+		t.Fatal("requests should have been paused", requests)
+	}
+
+	// Requests are allowed once the cool-down is over
+	if until := githubCooldown(time.Now().Add(121 * time.Second)); !until.IsZero() {
+		t.Fatal("cool-down should have ended", until)
+	}
+}
+
+// TestDoGithubRequestEnterpriseRateLimit will test doGithubRequest() pausing the requests of GitHub Enterprise
+// Server until the reset of its exhausted primary limit
+func TestDoGithubRequestEnterpriseRateLimit(t *testing.T) {
+	resetGithubCooldown()
+	defer resetGithubCooldown()
+
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubAPIBaseURL: "https://github.mycorp.com/api/v3"})
+	reset := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"API rate limit exceeded for user ID 1."}`))
+	})
+
+	err := h.githubGet(context.Background(), "/repos/mrz1836/codepipeline-to-github/commits/master", nil)
+	if limitErr, ok := err.(*secondaryRateLimitError); !ok {
+		t.Fatal("error was not as expected", err)
+	} else if !limitErr.RetryAt.Equal(reset) {
+		t.Fatal("retry time was not as expected", limitErr.RetryAt)
+	}
+}
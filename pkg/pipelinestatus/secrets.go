@@ -0,0 +1,77 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// cachedSecret is a GitHub token fetched from Secrets Manager
+type cachedSecret struct {
+	fetchedAt time.Time
+	token     string
+}
+
+// getSecretToken will return the GitHub token stored in the secret, the secret is either the plain
+// token or a JSON object with the token under the key (cached per container by secret ARN and refreshed
+// after GITHUB_TOKEN_SECRET_TTL, or sooner if GitHub rejects the token after a rotation). The secret is
+// fetched without holding the cache, the events of other secrets are not blocked on Secrets Manager
+func (c *containerCache) getSecretToken(ctx context.Context, secrets secretsmanageriface.SecretsManagerAPI,
+	secretARN, key string, ttl time.Duration, now time.Time) (string, error) {
+	c.secretsMu.Lock()
+	cached, ok := c.secrets[secretARN]
+	c.secretsMu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < ttl {
+		return cached.token, nil
+	}
+
+	// Get the current version of the secret (rotations move the AWSCURRENT stage)
+	output, err := secrets.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	})
+	if err != nil {
+		return "", err
+	}
+	token, err := parseSecretToken(aws.StringValue(output.SecretString), key)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %s", secretARN, err.Error())
+	}
+	c.secretsMu.Lock()
+	c.secrets[secretARN] = cachedSecret{fetchedAt: now, token: token}
+	c.secretsMu.Unlock()
+	return token, nil
+}
+
+// parseSecretToken will return the token of a plain or JSON secret
+func parseSecretToken(secret, key string) (string, error) {
+	secret = strings.TrimSpace(secret)
+	if !strings.HasPrefix(secret, "{") {
+		if len(secret) == 0 {
+			return "", errors.New("empty secret")
+		}
+		return secret, nil
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", err
+	}
+	token, _ := values[key].(string)
+	if len(token) == 0 {
+		return "", fmt.Errorf("missing key %s in the secret", key)
+	}
+	return token, nil
+}
+
+// forgetSecret will drop the cached token of the secret so the next handler fetches the rotated token
+func (c *containerCache) forgetSecret(secretARN string) {
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	delete(c.secrets, secretARN)
+}
@@ -0,0 +1,115 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// mockSecretsManagerClient returns the secret and counts the requests
+type mockSecretsManagerClient struct {
+	secretsmanageriface.SecretsManagerAPI
+	calls  int
+	secret string
+}
+
+// GetSecretValueWithContext is used for mocking the current version of a secret
+func (m *mockSecretsManagerClient) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput,
+	opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	m.calls++
+	if len(m.secret) == 0 {
+		return nil, errors.New("ResourceNotFoundException: secret not found")
+	}
+	return &secretsmanager.GetSecretValueOutput{ARN: input.SecretId, SecretString: aws.String(m.secret)}, nil
+}
+
+// TestParseSecretToken will test parseSecretToken()
+func TestParseSecretToken(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		secret        string
+		expected      string
+		expectedError bool
+	}{
+		{"ghp_1234567", "ghp_1234567", false},
+		{" ghp_1234567\n", "ghp_1234567", false},
+		{`{"github_access_token":"github_pat_1234567","other":"value"}`, "github_pat_1234567", false},
+		{`{"token":"ghp_1234567"}`, "", true},
+		{`{"github_access_token":`, "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		if token, err := parseSecretToken(test.secret, "github_access_token"); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.secret)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.secret, err.Error())
+		} else if token != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.secret, test.expected, token)
+		}
+	}
+}
+
+// TestGetSecretToken will test containerCache.getSecretToken() caching and refreshing the token
+func TestGetSecretToken(t *testing.T) {
+	secretARN := "arn:aws:secretsmanager:us-east-1:123456789012:secret:get-secret-token"
+	mockSecrets := &mockSecretsManagerClient{secret: `{"github_access_token":"ghp_first"}`}
+	now := time.Now()
+	cache := newContainerCache()
+
+	// Fetched once, then cached
+	for i := 0; i < 2; i++ {
+		if token, err := cache.getSecretToken(context.Background(), mockSecrets, secretARN, "github_access_token", time.Minute, now); err != nil {
+			t.Fatal("error occurred", err.Error())
+		} else if token != "ghp_first" {
+			t.Fatal("token was not as expected", token)
+		}
+	}
+	if mockSecrets.calls != 1 {
+		t.Fatal("secret should have been fetched once", mockSecrets.calls)
+	}
+
+	// Rotated token after the cache expires
+	mockSecrets.secret = `{"github_access_token":"ghp_second"}`
+	if token, err := cache.getSecretToken(context.Background(), mockSecrets, secretARN, "github_access_token", time.Minute, now.Add(2*time.Minute)); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if token != "ghp_second" {
+		t.Fatal("token was not as expected", token)
+	}
+
+	// Missing secret
+	if _, err := cache.getSecretToken(context.Background(), &mockSecretsManagerClient{}, secretARN+"-missing", "github_access_token", time.Minute, now); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestForgetSecretOnUnauthorized will test doGithubRequest() dropping the cached token when it is rejected
+func TestForgetSecretOnUnauthorized(t *testing.T) {
+	secretARN := "arn:aws:secretsmanager:us-east-1:123456789012:secret:forget-secret"
+	mockSecrets := &mockSecretsManagerClient{secret: "ghp_rotated"}
+	h := newTestHandler(Config{GithubAccessToken: "ghp_rotated", GithubTokenSecretARN: secretARN})
+	if _, err := h.deps.cache.getSecretToken(context.Background(), mockSecrets, secretARN, "github_access_token", time.Hour, time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if err := h.githubGet(context.Background(), "/user", nil); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	if _, err := h.deps.cache.getSecretToken(context.Background(), mockSecrets, secretARN, "github_access_token", time.Hour, time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if mockSecrets.calls != 2 {
+		t.Fatal("secret should have been fetched again", mockSecrets.calls)
+	}
+}
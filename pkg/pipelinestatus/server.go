@@ -0,0 +1,215 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Server mode (INGESTION_MODE), the bridge runs as a long-lived HTTP server in a container (Kubernetes or ECS)
+// and receives the events from an EventBridge API destination (its connection sends SERVER_SECRET as an API key)
+const (
+	ingestionModeServer   = "server"
+	serverHeaderSecret    = "X-Api-Key"
+	serverMaxEventBytes   = 256 << 10
+	serverPathEvents      = "/events"
+	serverPathHealth      = "/healthz"
+	serverPathInfo        = "/info"
+	serverPathReady       = "/readyz"
+	serverPathTimeline    = "/timeline"
+	serverReadTimeout     = 10 * time.Second
+	serverReadyTimeout    = 5 * time.Second
+	serverShutdownTimeout = 25 * time.Second
+)
+
+// serverHandler is the handler served by the server mode (a Handler created at startup)
+type serverHandler interface {
+	environmentState(ctx context.Context, pipelineName string) (environmentState, error)
+	info(ctx context.Context) deploymentInfo
+	processServerEvent(ctx context.Context, ev Event) error
+	ready(ctx context.Context) error
+	syncStatus(ctx context.Context, pipelineName, executionID string, dryRun bool) (syncReport, error)
+	timeline(ctx context.Context, commit string) ([]timelineEntry, error)
+}
+
+// ready will check the dependencies of the handler: the token was decrypted (or fetched) when the configuration
+// was loaded, and AWS answers a request
+func (h *Handler) ready(ctx context.Context) error {
+	if len(h.cfg.GithubAccessToken) == 0 {
+		return fmt.Errorf("the GitHub token is not set")
+	}
+	if _, err := h.deps.CodePipeline.ListPipelines(ctx, &codepipeline.ListPipelinesInput{MaxResults: aws.Int32(1)}); err != nil {
+		return fmt.Errorf("AWS is not reachable: %s", err.Error())
+	}
+	return nil
+}
+
+// processServerEvent will process an event posted to the server like ProcessEvent, with the handler of the server
+func (h *Handler) processServerEvent(ctx context.Context, ev Event) error {
+	if err := validateEvent(ev); err != nil {
+		return err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessEventWithContext(ctx, ev)
+}
+
+// timeline will return the state transitions of a commit (TIMELINE_TABLE)
+func (h *Handler) timeline(ctx context.Context, commit string) ([]timelineEntry, error) {
+	if len(h.cfg.TimelineTable) == 0 {
+		return nil, errors.New("missing TIMELINE_TABLE, the timeline is not being recorded")
+	}
+	entries, err := getTimeline(ctx, h.deps.DynamoDB, h.cfg.TimelineTable, commit)
+	if err == nil && entries == nil {
+		entries = []timelineEntry{}
+	}
+	return entries, err
+}
+
+// authorized will check the secret of a request to the server
+func authorized(r *http.Request, secret string) bool {
+	return len(secret) > 0 && subtle.ConstantTimeCompare([]byte(r.Header.Get(serverHeaderSecret)), []byte(secret)) == 1
+}
+
+// writeResponse will write the JSON response of an endpoint
+func writeResponse(ctx context.Context, w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := writeJSON(w, v); err != nil {
+		logWarnf(ctx, "unable to write the response: %s", err.Error())
+	}
+}
+
+// newServerMux will route the endpoints of the server mode: the liveness probe only answers while the process is
+// serving (a restart does not fix a dependency), the readiness probe checks the dependencies (the container gets
+// no traffic until they are back), the events are processed like the events of the Lambda and the info of the
+// deployment and the timeline of a commit are returned, and the methods of the Connect API are called (only with the
+// secret)
+func newServerMux(secret string, h serverHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(serverPathHealth, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc(serverPathReady, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), serverReadyTimeout)
+		defer cancel()
+		if err := h.ready(ctx); err != nil {
+			logWarnf(ctx, "not ready: %s", err.Error())
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("not ready"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ready"))
+	})
+	mux.HandleFunc(serverPathEvents, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		} else if !authorized(r, secret) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized"))
+			return
+		}
+		var ev Event
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, serverMaxEventBytes)).Decode(&ev); err != nil {
+			if _, ok := err.(*http.MaxBytesError); ok {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				_, _ = w.Write([]byte("event too large"))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid event"))
+			return
+		}
+		if err := h.processServerEvent(r.Context(), ev); err != nil {
+			logErrorf(r.Context(), "unable to process the event: %s", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc(serverPathInfo, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		} else if !authorized(r, secret) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized"))
+			return
+		}
+		writeResponse(r.Context(), w, h.info(r.Context()))
+	})
+	mux.HandleFunc(serverPathTimeline, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		} else if !authorized(r, secret) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized"))
+			return
+		}
+		commit := r.URL.Query().Get("commit")
+		if len(commit) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("missing commit"))
+			return
+		}
+		entries, err := h.timeline(r.Context(), commit)
+		if err != nil {
+			logErrorf(r.Context(), "unable to get the timeline of %s: %s", commit, err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeResponse(r.Context(), w, entries)
+	})
+	handleConnect(mux, secret, h)
+	return mux
+}
+
+// runServer will serve the endpoints of the server mode on SERVER_ADDRESS until the container is stopped, the
+// handler created at startup serves every request (the token is decrypted once)
+func runServer() error {
+	h, err := handlerFromEnvironment(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to load the configuration: %s", err.Error())
+	}
+	server := &http.Server{
+		Addr:              h.cfg.ServerAddress,
+		Handler:           newServerMux(h.cfg.ServerSecret, h),
+		ReadHeaderTimeout: serverReadTimeout,
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(stop)
+	return serveUntilStopped(server, stop)
+}
+
+// serveUntilStopped will serve until the server fails or a signal arrives, then shut down gracefully: the events
+// being processed finish (within serverShutdownTimeout) before the container stops
+func serveUntilStopped(server *http.Server, stop <-chan os.Signal) error {
+	served := make(chan error, 1)
+	go func() {
+		logf(context.Background(), "serving on %s", server.Addr)
+		served <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-served:
+		return err
+	case sig := <-stop:
+		logf(context.Background(), "shutting down on %s", sig.String())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
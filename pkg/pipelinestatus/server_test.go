@@ -0,0 +1,198 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+)
+
+// mockServerCodePipelineClient fails to list the pipelines (AWS is not reachable)
+type mockServerCodePipelineClient struct {
+	mockCodePipelineClient
+	err error
+}
+
+// ListPipelines is a mock request for codepipeline
+func (m *mockServerCodePipelineClient) ListPipelines(_ context.Context, input *codepipeline.ListPipelinesInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelinesOutput, error) {
+	return &codepipeline.ListPipelinesOutput{}, m.err
+}
+
+// mockServerHandler records the events posted to the server
+type mockServerHandler struct {
+	processErr error
+	processed  []Event
+	readyErr   error
+}
+
+// environmentState will return the deploy of the pipeline
+func (m *mockServerHandler) environmentState(_ context.Context, pipelineName string) (environmentState, error) {
+	if pipelineName == "unknown" {
+		return environmentState{}, &connectError{Code: connectCodeNotFound, Message: "no deploy recorded for pipeline unknown"}
+	}
+	return environmentState{Commit: "25c0c3e", Pipeline: pipelineName, UpdatedAt: time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)}, nil
+}
+
+// info will return the info of a deployment
+func (m *mockServerHandler) info(_ context.Context) deploymentInfo {
+	return deploymentInfo{Version: "v1.2.3"}
+}
+
+// processServerEvent will record the event
+func (m *mockServerHandler) processServerEvent(_ context.Context, ev Event) error {
+	m.processed = append(m.processed, ev)
+	return m.processErr
+}
+
+// ready will return the error of the dependencies
+func (m *mockServerHandler) ready(_ context.Context) error {
+	return m.readyErr
+}
+
+// syncStatus will return the synced execution (or fail to get the execution)
+func (m *mockServerHandler) syncStatus(_ context.Context, pipelineName, executionID string, dryRun bool) (syncReport, error) {
+	if executionID == "missing" {
+		return syncReport{}, errors.New("execution not found")
+	}
+	result := syncResultSynced
+	if dryRun {
+		result = syncResultReady
+	}
+	return syncReport{Pipeline: pipelineName, Executions: []syncedExecution{{ExecutionID: executionID, Result: result, State: "SUCCEEDED"}}}, nil
+}
+
+// timeline will return a transition of the commit
+func (m *mockServerHandler) timeline(_ context.Context, commit string) ([]timelineEntry, error) {
+	if commit == "unknown" {
+		return nil, errors.New("missing TIMELINE_TABLE, the timeline is not being recorded")
+	}
+	return []timelineEntry{{Commit: commit, Pipeline: "web", State: "SUCCEEDED"}}, nil
+}
+
+// TestHandlerReady will test Handler.ready()
+func TestHandlerReady(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+	codePipeline := &mockServerCodePipelineClient{}
+	h.deps.CodePipeline = codePipeline
+	if err := h.ready(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	codePipeline.err = errors.New("dial tcp: i/o timeout")
+	if err := h.ready(context.Background()); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "AWS is not reachable: dial tcp: i/o timeout" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	h.cfg.GithubAccessToken = ""
+	if err := h.ready(context.Background()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestServerMux will test the endpoints of newServerMux()
+func TestServerMux(t *testing.T) {
+	t.Parallel()
+
+	h := &mockServerHandler{}
+	server := httptest.NewServer(newServerMux("s3cr3t", h))
+	defer server.Close()
+
+	request := func(method, path, secret, body string) (int, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+		req.Header.Set(serverHeaderSecret, secret)
+		var response *http.Response
+		if response, err = http.DefaultClient.Do(req); err != nil {
+			t.Fatal("error occurred", err.Error())
+		}
+		defer func() {
+			_ = response.Body.Close()
+		}()
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return response.StatusCode, string(resBody)
+	}
+
+	var tests = []struct {
+		name         string
+		method       string
+		path         string
+		secret       string
+		body         string
+		readyErr     error
+		processErr   error
+		expectedCode int
+		expectedBody string
+	}{
+		{"liveness", http.MethodGet, serverPathHealth, "", "", nil, nil, http.StatusOK, "ok"},
+		{"liveness without dependencies", http.MethodGet, serverPathHealth, "", "", errors.New("KMS is down"), nil, http.StatusOK, "ok"},
+		{"ready", http.MethodGet, serverPathReady, "", "", nil, nil, http.StatusOK, "ready"},
+		{"not ready", http.MethodGet, serverPathReady, "", "", errors.New("KMS is down"), nil, http.StatusServiceUnavailable, "not ready"},
+		{"event", http.MethodPost, serverPathEvents, "s3cr3t", `{"detail":{"execution-id":"1","pipeline":"web","state":"STARTED"}}`, nil, nil, http.StatusNoContent, ""},
+		{"missing secret", http.MethodPost, serverPathEvents, "", `{"detail":{"execution-id":"2","pipeline":"web","state":"STARTED"}}`, nil, nil, http.StatusUnauthorized, "unauthorized"},
+		{"wrong secret", http.MethodPost, serverPathEvents, "secret", `{"detail":{"execution-id":"2","pipeline":"web","state":"STARTED"}}`, nil, nil, http.StatusUnauthorized, "unauthorized"},
+		{"failed event", http.MethodPost, serverPathEvents, "s3cr3t", `{"detail":{}}`, nil, errors.New("failed"), http.StatusInternalServerError, ""},
+		{"invalid event", http.MethodPost, serverPathEvents, "s3cr3t", `{`, nil, nil, http.StatusBadRequest, "invalid event"},
+		{"event too large", http.MethodPost, serverPathEvents, "s3cr3t", `{"detail":{"pipeline":"` + strings.Repeat("a", serverMaxEventBytes) + `"}}`, nil, nil, http.StatusRequestEntityTooLarge, "event too large"},
+		{"wrong method", http.MethodGet, serverPathEvents, "s3cr3t", "", nil, nil, http.StatusMethodNotAllowed, ""},
+		{"info", http.MethodGet, serverPathInfo, "s3cr3t", "", nil, nil, http.StatusOK, "{\n  \"config\": null,\n  \"error_counts\": null,\n  \"errors_since\": \"0001-01-01T00:00:00Z\",\n  \"integrations\": null,\n  \"recent_errors\": null,\n  \"version\": \"v1.2.3\"\n}\n"},
+		{"info without secret", http.MethodGet, serverPathInfo, "", "", nil, nil, http.StatusUnauthorized, "unauthorized"},
+		{"timeline", http.MethodGet, serverPathTimeline + "?commit=25c0c3e", "s3cr3t", "", nil, nil, http.StatusOK, "[\n  {\n    \"commit\": \"25c0c3e\",\n    \"execution_id\": \"\",\n    \"pipeline\": \"web\",\n    \"state\": \"SUCCEEDED\",\n    \"time\": \"0001-01-01T00:00:00Z\"\n  }\n]\n"},
+		{"timeline without commit", http.MethodGet, serverPathTimeline, "s3cr3t", "", nil, nil, http.StatusBadRequest, "missing commit"},
+		{"timeline without table", http.MethodGet, serverPathTimeline + "?commit=unknown", "s3cr3t", "", nil, nil, http.StatusInternalServerError, ""},
+		{"timeline without secret", http.MethodGet, serverPathTimeline + "?commit=25c0c3e", "", "", nil, nil, http.StatusUnauthorized, "unauthorized"},
+		{"unknown path", http.MethodGet, "/unknown", "", "", nil, nil, http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, test := range tests {
+		h.readyErr, h.processErr = test.readyErr, test.processErr
+		if code, body := request(test.method, test.path, test.secret, test.body); code != test.expectedCode || body != test.expectedBody {
+			t.Errorf("%s Failed: [%s] inputted and [%d %q] expected, but got [%d %q]", t.Name(), test.name,
+				test.expectedCode, test.expectedBody, code, body)
+		}
+	}
+
+	if len(h.processed) != 2 || h.processed[0].Detail.ExecutionID != "1" || h.processed[0].Detail.State != "STARTED" {
+		t.Fatal("events were not as expected", h.processed)
+	}
+}
+
+// TestHandlerTimeline will test Handler.timeline() without TIMELINE_TABLE
+func TestHandlerTimeline(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{})
+	if _, err := h.timeline(context.Background(), "25c0c3e"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestServeUntilStopped will test serveUntilStopped() shutting down the server on a signal
+func TestServeUntilStopped(t *testing.T) {
+	t.Parallel()
+
+	stop := make(chan os.Signal, 1)
+	stop <- syscall.SIGTERM
+	if err := serveUntilStopped(&http.Server{Addr: "127.0.0.1:0", Handler: http.NewServeMux()}, stop); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	// The server fails to listen
+	if err := serveUntilStopped(&http.Server{Addr: "127.0.0.1:-1"}, make(chan os.Signal)); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
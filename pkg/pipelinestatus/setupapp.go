@@ -0,0 +1,360 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// GitHub App manifest flow of the setup-app command
+const (
+	commandSetupApp      = "setup-app"
+	defaultAppSecretName = "codepipeline-to-github/github-app"
+	defaultSetupListen   = "localhost:8976"
+	setupPathCallback    = "/callback"
+	setupPathInstalled   = "/installed"
+)
+
+// setupAppPage submits the manifest to GitHub (the manifest flow starts with a POST from the browser)
+var setupAppPage = template.Must(template.New("setup-app").Parse(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form action="{{.Action}}" method="post">
+<input type="hidden" name="manifest" value="{{.Manifest}}">
+<input type="submit" value="Create the GitHub App">
+</form>
+</body></html>`))
+
+// githubAppManifest is the manifest of the GitHub App (the permissions of the enabled features)
+type githubAppManifest struct {
+	DefaultEvents      []string          `json:"default_events,omitempty"`
+	DefaultPermissions map[string]string `json:"default_permissions"`
+	HookAttributes     *githubAppHook    `json:"hook_attributes,omitempty"`
+	Name               string            `json:"name"`
+	Public             bool              `json:"public"`
+	RedirectURL        string            `json:"redirect_url"`
+	SetupURL           string            `json:"setup_url"`
+	URL                string            `json:"url"`
+}
+
+// githubAppHook is the webhook of the GitHub App (INGESTION_MODE webhook)
+type githubAppHook struct {
+	Active bool   `json:"active"`
+	URL    string `json:"url"`
+}
+
+// githubAppConversion is the GitHub App created from the manifest (the private key is only returned once)
+type githubAppConversion struct {
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	HTMLURL       string `json:"html_url"`
+	ID            int64  `json:"id"`
+	PEM           string `json:"pem"`
+	Slug          string `json:"slug"`
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// githubAppSetup is the result of the setup: the app, its installation and the settings of the bridge
+type githubAppSetup struct {
+	AppID          int64             `json:"app_id"`
+	InstallationID int64             `json:"installation_id"`
+	SecretARN      string            `json:"secret_arn"`
+	Settings       map[string]string `json:"settings"`
+	Slug           string            `json:"slug"`
+	SSMPrefix      string            `json:"ssm_prefix,omitempty"`
+}
+
+// newGithubAppManifest will create the manifest of the app: contents are read for the commits and the enabled
+// features add their permissions, the webhook receives the new branch protection rules
+func newGithubAppManifest(cfg Config, name, baseURL, webhookURL string) githubAppManifest {
+	manifest := githubAppManifest{
+		DefaultPermissions: map[string]string{"contents": "read"},
+		Name:               name,
+		RedirectURL:        baseURL + setupPathCallback,
+		SetupURL:           baseURL + setupPathInstalled,
+		URL:                "https://github.com/mrz1836/codepipeline-to-github",
+	}
+	for _, permission := range requiredGithubPermissions(cfg) {
+		parts := strings.SplitN(permission, ":", 2)
+		manifest.DefaultPermissions[parts[0]] = parts[1]
+	}
+	if len(webhookURL) > 0 {
+		manifest.DefaultEvents = []string{webhookEventBranchProtectionRule}
+		manifest.DefaultPermissions["administration"] = "read"
+		manifest.DefaultPermissions["pull_requests"] = "read"
+		manifest.HookAttributes = &githubAppHook{Active: true, URL: webhookURL}
+	}
+	return manifest
+}
+
+// githubAppCreateURL will return the page creating an app from a manifest (of the organization, or the user)
+func githubAppCreateURL(cfg Config, org string) string {
+	host := githubHost
+	if apiURL, err := url.Parse(cfg.GithubAPIBaseURL); err == nil && len(apiURL.Host) > 0 {
+		host = apiURL.Host
+	}
+	if len(org) > 0 {
+		return fmt.Sprintf("https://%s/organizations/%s/settings/apps/new", host, url.PathEscape(org))
+	}
+	return "https://" + host + "/settings/apps/new"
+}
+
+// convertGithubAppManifest will exchange the code of the callback for the app (with its private key)
+func convertGithubAppManifest(ctx context.Context, client HTTPClient, apiURL, code string) (app githubAppConversion, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/app-manifests/%s/conversions", apiURL, url.PathEscape(code)), nil); err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	var response *http.Response
+	if response, err = client.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("unable to create the GitHub App from the manifest, code: %d", response.StatusCode)
+		return
+	}
+	err = json.NewDecoder(response.Body).Decode(&app)
+	return
+}
+
+// storeGithubAppSecret will store the private key and the secrets of the app in Secrets Manager (a new version of
+// an existing secret) and return the ARN of the secret
+func storeGithubAppSecret(ctx context.Context, secrets secretsmanageriface.SecretsManagerAPI, name string,
+	app githubAppConversion) (string, error) {
+	b, err := json.Marshal(githubAppSecret{
+		AppID:         app.ID,
+		ClientID:      app.ClientID,
+		ClientSecret:  app.ClientSecret,
+		PrivateKey:    app.PEM,
+		WebhookSecret: app.WebhookSecret,
+	})
+	if err != nil {
+		return "", err
+	}
+	var created *secretsmanager.CreateSecretOutput
+	if created, err = secrets.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+		Description:  aws.String("GitHub App " + app.Slug + " of codepipeline-to-github"),
+		Name:         aws.String(name),
+		SecretString: aws.String(string(b)),
+	}); err == nil {
+		return aws.StringValue(created.ARN), nil
+	} else if aErr, ok := err.(awserr.Error); !ok || aErr.Code() != secretsmanager.ErrCodeResourceExistsException {
+		return "", err
+	}
+	var updated *secretsmanager.PutSecretValueOutput
+	if updated, err = secrets.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(b)),
+	}); err != nil {
+		return "", err
+	}
+	return aws.StringValue(updated.ARN), nil
+}
+
+// writeGithubAppSettings will write the settings of the app under the prefix of SSM Parameter Store (CONFIG_SSM_PREFIX)
+func writeGithubAppSettings(ctx context.Context, ssmSvc ssmiface.SSMAPI, prefix string, settings map[string]string) error {
+	for name, value := range settings {
+		if _, err := ssmSvc.PutParameterWithContext(ctx, &ssm.PutParameterInput{
+			Name:      aws.String("/" + strings.Trim(prefix, "/") + "/" + name),
+			Overwrite: aws.Bool(true),
+			Type:      aws.String(ssm.ParameterTypeString),
+			Value:     aws.String(value),
+		}); err != nil {
+			return fmt.Errorf("unable to write %s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// githubAppSetupServer serves the manifest flow in the browser: the page submitting the manifest, the callback
+// with the code of the created app and the redirect after the app is installed
+type githubAppSetupServer struct {
+	apiURL     string
+	client     HTTPClient
+	createURL  string
+	done       chan error
+	manifest   githubAppManifest
+	mu         sync.Mutex
+	result     githubAppSetup
+	secretName string
+	secrets    secretsmanageriface.SecretsManagerAPI
+	state      string
+}
+
+// finish will end the setup (only the first result counts)
+func (s *githubAppSetupServer) finish(err error) {
+	select {
+	case s.done <- err:
+	default:
+	}
+}
+
+// ServeHTTP will serve a step of the manifest flow
+func (s *githubAppSetupServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		manifest, _ := json.Marshal(s.manifest)
+		_ = setupAppPage.Execute(w, map[string]string{
+			"Action":   s.createURL + "?state=" + url.QueryEscape(s.state),
+			"Manifest": string(manifest),
+		})
+	case setupPathCallback:
+		if r.URL.Query().Get("state") != s.state || len(r.URL.Query().Get("code")) == 0 {
+			http.Error(w, "invalid callback", http.StatusBadRequest)
+			return
+		}
+		app, err := convertGithubAppManifest(r.Context(), s.client, s.apiURL, r.URL.Query().Get("code"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			s.finish(err)
+			return
+		}
+		var secretARN string
+		if secretARN, err = storeGithubAppSecret(r.Context(), s.secrets, s.secretName, app); err != nil {
+			http.Error(w, "unable to store the private key of the app", http.StatusInternalServerError)
+			s.finish(fmt.Errorf("unable to store the private key of app %s: %s", app.Slug, err.Error()))
+			return
+		}
+		s.mu.Lock()
+		s.result.AppID, s.result.SecretARN, s.result.Slug = app.ID, secretARN, app.Slug
+		s.mu.Unlock()
+		http.Redirect(w, r, app.HTMLURL+"/installations/new", http.StatusFound)
+	case setupPathInstalled:
+		installationID, err := strconv.ParseInt(r.URL.Query().Get("installation_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid installation", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.result.InstallationID = installationID
+		s.mu.Unlock()
+		_, _ = io.WriteString(w, "The GitHub App is installed, you can close this window.")
+		s.finish(nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// setupAppCommand will create the GitHub App of the bridge with the manifest flow: the app is created and installed
+// in the browser, its private key is stored in Secrets Manager and the settings are written under CONFIG_SSM_PREFIX
+// (IE: status setup-app -name my-org-codepipeline -org my-org)
+func setupAppCommand(args []string, out, prompt io.Writer, secrets secretsmanageriface.SecretsManagerAPI,
+	ssmSvc ssmiface.SSMAPI) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandSetupApp, flag.ContinueOnError)
+	listen := flags.String("listen", defaultSetupListen, "local address receiving the redirects of GitHub")
+	name := flags.String("name", "", "name of the GitHub App")
+	org := flags.String("org", "", "organization owning the app (default: the user)")
+	output := outputFlag(flags, outputTable)
+	secretName := flags.String("secret-name", defaultAppSecretName, "name of the secret of the private key")
+	ssmPrefix := flags.String("ssm-prefix", os.Getenv(configSSMPrefixEnv), "prefix of the settings (default: CONFIG_SSM_PREFIX)")
+	timeout := flags.Duration("timeout", 15*time.Minute, "time to create and install the app")
+	webhookURL := flags.String("webhook-url", "", "url of the webhook (INGESTION_MODE webhook)")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*name) == 0 {
+		return errors.New("missing flag -name")
+	}
+
+	// Load the configuration (the permissions of the enabled features)
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	}
+	var apiURL string
+	if apiURL, err = githubAPIURL(cfg); err != nil {
+		return
+	}
+
+	// Serve the flow until the app is installed
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", *listen); err != nil {
+		return
+	}
+	state := make([]byte, 16)
+	if _, err = rand.Read(state); err != nil {
+		return
+	}
+	baseURL := "http://" + listener.Addr().String()
+	setup := &githubAppSetupServer{
+		apiURL:     apiURL,
+		client:     http.DefaultClient,
+		createURL:  githubAppCreateURL(cfg, *org),
+		done:       make(chan error, 1),
+		manifest:   newGithubAppManifest(cfg, *name, baseURL, *webhookURL),
+		secretName: *secretName,
+		secrets:    secrets,
+		state:      hex.EncodeToString(state),
+	}
+	server := &http.Server{Handler: setup, ReadHeaderTimeout: serverReadTimeout}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+	_, _ = fmt.Fprintf(prompt, "Open %s/ in a browser to create and install the GitHub App %s\n", baseURL, *name)
+	select {
+	case err = <-setup.done:
+		if err != nil {
+			return
+		}
+	case <-time.After(*timeout):
+		return fmt.Errorf("the GitHub App was not installed within %s", *timeout)
+	}
+
+	// Write the settings of the bridge
+	setup.mu.Lock()
+	result := setup.result
+	setup.mu.Unlock()
+	result.Settings = map[string]string{
+		"GITHUB_APP_INSTALLATION_ID": strconv.FormatInt(result.InstallationID, 10),
+		"GITHUB_APP_SECRET_ARN":      result.SecretARN,
+	}
+	if len(*ssmPrefix) > 0 {
+		if err = writeGithubAppSettings(context.Background(), ssmSvc, *ssmPrefix, result.Settings); err != nil {
+			return
+		}
+		result.SSMPrefix = *ssmPrefix
+	}
+	return writeOutput(out, *output, result, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "APP\t%s (%d)\n", result.Slug, result.AppID)
+		_, _ = fmt.Fprintf(w, "INSTALLATION\t%d\n", result.InstallationID)
+		_, _ = fmt.Fprintf(w, "SECRET\t%s\n", result.SecretARN)
+		names := make([]string, 0, len(result.Settings))
+		for setting := range result.Settings {
+			names = append(names, setting)
+		}
+		sort.Strings(names)
+		for _, setting := range names {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", setting, result.Settings[setting])
+		}
+	})
+}
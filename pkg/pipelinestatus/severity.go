@@ -0,0 +1,69 @@
+package pipelinestatus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities of the pipelines (PIPELINE_SEVERITIES), pipelines are standard unless configured
+const (
+	severityCritical      = "critical"
+	severityInformational = "informational"
+	severityStandard      = "standard"
+)
+
+// opsgeniePriorities are the priorities of the alerts of the severities that page (OpsGenie defaults to P3)
+var opsgeniePriorities = map[string]string{
+	severityCritical: "P1",
+	severityStandard: "P3",
+}
+
+// validateSeverities will check the severities of the pipelines and the notifiers of the severities
+func validateSeverities(cfg Config) error {
+	for pipelineName, severity := range cfg.PipelineSeverities {
+		switch severity {
+		case severityCritical, severityInformational, severityStandard:
+		default:
+			return fmt.Errorf("invalid PIPELINE_SEVERITIES severity of %s: %s (available: %s, %s, %s)", pipelineName, severity,
+				severityCritical, severityInformational, severityStandard)
+		}
+	}
+	for severity := range cfg.SeverityNotifiers {
+		switch severity {
+		case severityCritical, severityInformational, severityStandard:
+		default:
+			return fmt.Errorf("invalid SEVERITY_NOTIFIERS severity: %s (available: %s, %s, %s)", severity,
+				severityCritical, severityInformational, severityStandard)
+		}
+	}
+	return nil
+}
+
+// pipelineSeverity will return the severity of the pipeline (standard unless configured)
+func (h *Handler) pipelineSeverity(pipelineName string) string {
+	if severity, ok := h.cfg.PipelineSeverities[pipelineName]; ok && len(severity) > 0 {
+		return severity
+	}
+	return severityStandard
+}
+
+// severityNotifies will return true if the notifier fires for the severity of the pipeline
+// (SEVERITY_NOTIFIERS, IE: {"informational":"slack"}), severities that are not configured fire every notifier
+func (h *Handler) severityNotifies(pipelineName, notifierName string) bool {
+	names, ok := h.cfg.SeverityNotifiers[h.pipelineSeverity(pipelineName)]
+	if !ok {
+		return true
+	}
+	for _, name := range strings.Split(names, ",") {
+		if strings.TrimSpace(name) == notifierName {
+			return true
+		}
+	}
+	return false
+}
+
+// severityPages will return true if the failures of the pipeline page (informational pipelines never do)
+func (h *Handler) severityPages(pipelineName string) bool {
+	_, ok := opsgeniePriorities[h.pipelineSeverity(pipelineName)]
+	return ok
+}
@@ -0,0 +1,125 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestValidateSeverities will test validateSeverities()
+func TestValidateSeverities(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		cfg           Config
+		expectedError bool
+	}{
+		{Config{}, false},
+		{Config{PipelineSeverities: stringMap{"web": severityCritical, "docs": severityInformational}}, false},
+		{Config{PipelineSeverities: stringMap{"docs": "low"}}, true},
+		{Config{SeverityNotifiers: stringMap{severityInformational: "slack"}}, false},
+		{Config{SeverityNotifiers: stringMap{"urgent": "slack,teams"}}, true},
+	}
+
+	for _, test := range tests {
+		if err := validateSeverities(test.cfg); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error not expected but got: %s", t.Name(), test.cfg.PipelineSeverities, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error was expected", t.Name(), test.cfg.PipelineSeverities)
+		}
+	}
+}
+
+// TestSeverityNotifies will test Handler.severityNotifies() and Handler.severityPages()
+func TestSeverityNotifies(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(Config{
+		PipelineSeverities: stringMap{"web": severityCritical, "docs": severityInformational},
+		SeverityNotifiers:  stringMap{severityInformational: "slack", severityStandard: ""},
+	})
+	var tests = []struct {
+		pipeline      string
+		notifier      string
+		expected      bool
+		expectedPages bool
+	}{
+		{"web", "slack", true, true},
+		{"web", "teams", true, true},
+		{"docs", "slack", true, false},
+		{"docs", "teams", false, false},
+		{"api", "slack", false, true},
+	}
+
+	for _, test := range tests {
+		if output := h.severityNotifies(test.pipeline, test.notifier); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%t] expected, but got [%t]", t.Name(), test.pipeline, test.notifier, test.expected, output)
+		} else if pages := h.severityPages(test.pipeline); pages != test.expectedPages {
+			t.Errorf("%s Failed: [%s] inputted and pages [%t] expected, but got [%t]", t.Name(), test.pipeline, test.expectedPages, pages)
+		}
+	}
+}
+
+// TestNotifyMessageSeverity will test Handler.notifyMessage() only firing the notifiers of the severity
+func TestNotifyMessageSeverity(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	h := newTestHandler(Config{
+		PipelineSeverities: stringMap{"docs": severityInformational},
+		SeverityNotifiers:  stringMap{severityInformational: "teams"},
+		SlackWebhookURL:    server.URL + "/slack",
+		TeamsWebhookURL:    server.URL + "/teams",
+	})
+
+	if results := h.notifyMessage(context.Background(), notification{Pipeline: "docs", State: githubStateFailure, Text: "failed"}); len(results) != 1 {
+		t.Fatal("only teams should have been notified", results)
+	} else if len(paths) != 1 || paths[0] != "/teams" {
+		t.Fatal("requests were not as expected", paths)
+	}
+
+	// Warnings (no pipeline) go to every notifier
+	if results := h.notify("token expires soon"); len(results) != 2 {
+		t.Fatal("every notifier should have been notified", results)
+	}
+}
+
+// TestAlertOpsgenieSeverity will test Handler.alertOpsgenie() paging by the severity of the pipeline
+func TestAlertOpsgenieSeverity(t *testing.T) {
+	var alert opsgenieAlert
+	client := &mockForgeClient{handler: func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&alert)
+		w.WriteHeader(http.StatusAccepted)
+	}}
+	h := newTestHandler(Config{
+		OpsgenieAPIKey:     "secret-key",
+		PipelineSeverities: stringMap{"web": severityCritical, "docs": severityInformational},
+	})
+	h.deps.Opsgenie = client
+
+	if err := h.alertOpsgenie(context.Background(), "web", "", githubStateFailure, "failed", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if alert.Priority != "P1" {
+		t.Fatal("priority was not as expected", alert.Priority)
+	}
+
+	// Informational failures do not page
+	if err := h.alertOpsgenie(context.Background(), "docs", "Build", githubStateFailure, "failed", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 1 {
+		t.Fatal("informational failure should not have paged", len(client.requests))
+	}
+
+	// Standard pipelines page with the default priority
+	if err := h.alertOpsgenie(context.Background(), "api", "", githubStateFailure, "failed", ""); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(client.requests) != 2 || alert.Priority != "P3" || !strings.HasPrefix(alert.Message, "api") {
+		t.Fatal("alert was not as expected", alert)
+	}
+}
@@ -0,0 +1,73 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Shadow modes of a staging copy of the bridge (SHADOW_MODE), events are processed in full but GitHub
+// writes are rerouted to a mirror repository or only recorded in the audit table
+const (
+	shadowModeAudit      = "audit"
+	shadowModeRepository = "repository"
+)
+
+// shadowRepositoryPath will replace the repository of a GitHub API path (/repos/owner/repo/...) with the shadow repository
+func shadowRepositoryPath(path, shadowRepository string) string {
+	parts := strings.SplitN(path, "/", 5)
+	if len(parts) < 4 || parts[1] != "repos" {
+		return path
+	}
+	rewritten := "/repos/" + shadowRepository
+	if len(parts) == 5 {
+		rewritten += "/" + parts[4]
+	}
+	return rewritten
+}
+
+// recordShadowWrite will add a GitHub write to the audit table
+func (h *Handler) recordShadowWrite(ctx context.Context, method, path, body string, now time.Time) error {
+	_, err := h.deps.DynamoDB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"body":    {S: aws.String(body)},
+			"id":      {S: aws.String(strconv.FormatInt(now.UnixNano(), 10) + " " + method + " " + path)},
+			"method":  {S: aws.String(method)},
+			"path":    {S: aws.String(path)},
+			"time":    {S: aws.String(now.UTC().Format(time.RFC3339Nano))},
+			"version": {S: aws.String(version)},
+		},
+		TableName: aws.String(h.cfg.ShadowAuditTable),
+	})
+	return err
+}
+
+// shadowWrite will audit a GitHub write and reroute it to the shadow repository, writes are not sent at all
+// in the audit mode (handled is true and the write counts as a success)
+func (h *Handler) shadowWrite(req *http.Request) (handled bool, err error) {
+	if len(h.cfg.ShadowAuditTable) > 0 {
+		var body []byte
+		if req.Body != nil {
+			if body, err = ioutil.ReadAll(req.Body); err != nil {
+				return true, err
+			}
+			req.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		}
+		if err = h.recordShadowWrite(req.Context(), req.Method, req.URL.Path, string(body), time.Now()); err != nil {
+			return true, fmt.Errorf("unable to audit the shadow write: %s", err.Error())
+		}
+	}
+	if h.cfg.ShadowMode == shadowModeAudit {
+		logf(req.Context(), "shadow mode: skipped %s %s", req.Method, req.URL.Path)
+		return true, nil
+	}
+	req.URL.Path = shadowRepositoryPath(req.URL.Path, h.cfg.ShadowRepository)
+	return false, nil
+}
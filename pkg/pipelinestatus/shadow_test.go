@@ -0,0 +1,111 @@
+package pipelinestatus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockAuditDynamoClient keeps the audited writes
+type mockAuditDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items []map[string]*dynamodb.AttributeValue
+}
+
+// PutItemWithContext is a mock request for dynamodb (keeps the item)
+func (m *mockAuditDynamoClient) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.items = append(m.items, input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// TestShadowRepositoryPath will test shadowRepositoryPath()
+func TestShadowRepositoryPath(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e", "/repos/staging/mirror/statuses/25c0c3e"},
+		{"/repos/mrz1836/codepipeline-to-github/check-runs", "/repos/staging/mirror/check-runs"},
+		{"/repos/mrz1836/codepipeline-to-github", "/repos/staging/mirror"},
+		{"/user", "/user"},
+		{"/graphql", "/graphql"},
+	}
+
+	for _, test := range tests {
+		if output := shadowRepositoryPath(test.path, "staging/mirror"); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.path, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessEventShadowAudit will test ProcessEvent() only auditing the writes
+func TestHandlerProcessEventShadowAudit(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		ShadowAuditTable:     "audit",
+		ShadowMode:           shadowModeAudit,
+		Stage:                stageTesting,
+	})
+	mockDynamo := &mockAuditDynamoClient{}
+	h.deps.DynamoDB = mockDynamo
+
+	var writes int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: time.Now()}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if writes != 0 {
+		t.Fatal("writes should not have been sent", writes)
+	} else if len(mockDynamo.items) != 1 {
+		t.Fatal("write should have been audited", mockDynamo.items)
+	} else if path := aws.StringValue(mockDynamo.items[0]["path"].S); path !=
+		"/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("path was not as expected", path)
+	} else if body := aws.StringValue(mockDynamo.items[0]["body"].S); len(body) == 0 {
+		t.Fatal("body should have been audited")
+	}
+}
+
+// TestHandlerProcessEventShadowRepository will test ProcessEvent() writing to the shadow repository
+func TestHandlerProcessEventShadowRepository(t *testing.T) {
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		ShadowMode:           shadowModeRepository,
+		ShadowRepository:     "staging/mirror",
+		Stage:                stageTesting,
+	})
+
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			body, _ := ioutil.ReadAll(r.Body)
+			if len(body) == 0 {
+				t.Error("body should have been sent")
+			}
+			paths = append(paths, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(paths) != 1 || paths[0] != "/repos/staging/mirror/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("paths were not as expected", paths)
+	}
+}
@@ -0,0 +1,101 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Lambda shutdown: the runtime only gets a SIGTERM before the sandbox is shut down once an extension is registered,
+// an internal extension without events is registered (Extensions API) so the buffers are flushed in time
+const (
+	extensionAPIVersion  = "2020-01-01"
+	extensionHeaderID    = "Lambda-Extension-Identifier"
+	extensionHeaderName  = "Lambda-Extension-Name"
+	extensionName        = "codepipeline-to-github-shutdown"
+	shutdownFlushTimeout = 400 * time.Millisecond // the runtime has 500 ms between the SIGTERM and the SIGKILL
+)
+
+// Handler of the last invocation of the container, its settings flush the buffers on shutdown
+var (
+	shutdownHandler   *Handler
+	shutdownHandlerMu sync.Mutex
+)
+
+// setShutdownHandler will keep the handler whose settings flush the buffers on shutdown
+func setShutdownHandler(h *Handler) {
+	shutdownHandlerMu.Lock()
+	defer shutdownHandlerMu.Unlock()
+	shutdownHandler = h
+}
+
+// enableShutdownFlush will flush the buffers on the SIGTERM of the Lambda shutdown (nothing is registered outside
+// of Lambda)
+func enableShutdownFlush() {
+	runtimeAPI := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if len(runtimeAPI) == 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	go func() {
+		<-signals
+		flushOnShutdown()
+	}()
+	if err := registerShutdownExtension(context.Background(), http.DefaultClient, runtimeAPI); err != nil {
+		logWarnf(context.Background(), "unable to register the shutdown extension, the buffers are not flushed on shutdown: %s", err.Error())
+	}
+}
+
+// registerShutdownExtension will register the internal extension (without events) that enables the SIGTERM, the
+// registration is done once the extension asks for its next event (which never comes)
+func registerShutdownExtension(ctx context.Context, client HTTPClient, runtimeAPI string) error {
+	baseURL := fmt.Sprintf("http://%s/%s/extension", runtimeAPI, extensionAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/register", bytes.NewBufferString(`{"events":[]}`))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(extensionHeaderName, extensionName)
+	var response *http.Response
+	if response, err = client.Do(req); err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	extensionID := response.Header.Get(extensionHeaderID)
+	if response.StatusCode != http.StatusOK || len(extensionID) == 0 {
+		return fmt.Errorf("unexpected response from the Extensions API, code: %d", response.StatusCode)
+	}
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/event/next", nil); err != nil {
+		return err
+	}
+	req.Header.Set(extensionHeaderID, extensionID)
+	go func() {
+		if next, nextErr := client.Do(req); nextErr == nil {
+			_ = next.Body.Close()
+		}
+	}()
+	return nil
+}
+
+// flushOnShutdown will push the metrics still buffered (IE: of an invocation that timed out before its push) with
+// the settings of the last handler, the coalesced status updates are skipped rather than buffered and the traces
+// and markers are sent right away, so the metrics are the only buffer
+func flushOnShutdown() {
+	shutdownHandlerMu.Lock()
+	h := shutdownHandler
+	shutdownHandlerMu.Unlock()
+	if h == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+	defer cancel()
+	h.pushMetrics(ctx)
+	logf(ctx, "shutting down, the buffered metrics were flushed")
+}
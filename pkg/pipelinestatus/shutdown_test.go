@@ -0,0 +1,80 @@
+package pipelinestatus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRegisterShutdownExtension will test registerShutdownExtension()
+func TestRegisterShutdownExtension(t *testing.T) {
+	t.Parallel()
+
+	var name, body string
+	next := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /2020-01-01/extension/register":
+			b, _ := ioutil.ReadAll(r.Body)
+			name, body = r.Header.Get(extensionHeaderName), string(b)
+			w.Header().Set(extensionHeaderID, "ext-123")
+		case "GET /2020-01-01/extension/event/next":
+			next <- r.Header.Get(extensionHeaderID)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	runtimeAPI := strings.TrimPrefix(server.URL, "http://")
+
+	// Registered without events, then waiting for the next event
+	if err := registerShutdownExtension(context.Background(), server.Client(), runtimeAPI); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if name != extensionName || body != `{"events":[]}` {
+		t.Fatal("registration was not as expected", name, body)
+	}
+	select {
+	case extensionID := <-next:
+		if extensionID != "ext-123" {
+			t.Fatal("extension id was not as expected", extensionID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("next event was not requested")
+	}
+
+	// Refused registration
+	if err := registerShutdownExtension(context.Background(), server.Client(), runtimeAPI+"/refused"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestFlushOnShutdown will test flushOnShutdown() pushing the buffered metrics with the settings of the last handler
+func TestFlushOnShutdown(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+	defer setShutdownHandler(nil)
+
+	// Nothing to flush without a handler
+	setShutdownHandler(nil)
+	flushOnShutdown()
+
+	// The metrics of an invocation that timed out before its push
+	h := newTestHandler(Config{PrometheusJob: "codepipeline-to-github", PrometheusPushgatewayURL: server.URL})
+	h.deps.Prometheus = server.Client()
+	setShutdownHandler(h)
+	printMetric(metricTokenExpiry, 30, "Count", nil, time.Now())
+	flushOnShutdown()
+	if !strings.Contains(body, "codepipeline_to_github_github_token_days_until_expiry 30") {
+		t.Fatal("metrics were not flushed", body)
+	} else if len(drainMetrics()) > 0 {
+		t.Fatal("buffer should be empty")
+	}
+}
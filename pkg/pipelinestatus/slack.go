@@ -0,0 +1,79 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// slackColors are the attachment colors of the GitHub states
+var slackColors = map[string]string{
+	githubStateError:   "#cb2431",
+	githubStateFailure: "#cb2431",
+	githubStatePending: "#dbab09",
+	githubStateSuccess: "#28a745",
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+	Text        string            `json:"text,omitempty"`
+}
+
+// slackAttachment is a color coded block of a Slack message
+type slackAttachment struct {
+	Color     string `json:"color,omitempty"`
+	Fallback  string `json:"fallback"`
+	Text      string `json:"text"`
+	Title     string `json:"title,omitempty"`
+	TitleLink string `json:"title_link,omitempty"`
+}
+
+// newSlackMessage will create the Slack message of a notification (plain text without a state)
+func newSlackMessage(message notification) *slackMessage {
+	if len(message.State) == 0 {
+		return &slackMessage{Text: message.Text}
+	}
+	return &slackMessage{Attachments: []slackAttachment{{
+		Color:     slackColors[message.State],
+		Fallback:  strings.TrimSpace(message.Title + ": " + message.Text),
+		Text:      message.Text,
+		Title:     message.Title,
+		TitleLink: message.URL,
+	}}}
+}
+
+// postSlackMessage will send a message to a Slack incoming webhook
+func postSlackMessage(ctx context.Context, client HTTPClient, webhookURL string, message *slackMessage) error {
+
+	// Encode the message
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	// Fire the request and check for success
+	var response *http.Response
+	if response, err = client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Slack, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
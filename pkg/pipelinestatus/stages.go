@@ -0,0 +1,271 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Stage events and outcomes
+const (
+	detailTypeStageExecution = "CodePipeline Stage Execution State Change"
+)
+
+// stageStates are the GitHub statuses of the stage states (and of the listed executions, see executionEventStates)
+var stageStates = map[string]string{
+	"CANCELED":  githubStateError,
+	"FAILED":    githubStateFailure,
+	"RESUMED":   githubStatePending,
+	"STARTED":   githubStatePending,
+	"STOPPED":   githubStateError,
+	"STOPPING":  githubStatePending,
+	"SUCCEEDED": githubStateSuccess,
+}
+
+// isStageEvent will return true if the event is a state change of a stage
+func isStageEvent(ev Event) bool {
+	return ev.Detail != nil && ev.DetailType == detailTypeStageExecution
+}
+
+// validateStageEvent will check the stage event for the required parameters
+func validateStageEvent(ev Event) error {
+	if err := validateEvent(ev); err != nil {
+		return err
+	} else if len(ev.Detail.Stage) == 0 {
+		return errors.New("missing event param stage")
+	} else if _, ok := stageStates[ev.Detail.State]; !ok {
+		return fmt.Errorf("unknown stage state: %s", ev.Detail.State)
+	}
+	return nil
+}
+
+// stageContext will return the context of a stage, nested under the context of the pipeline
+// (IE: ci/pipeline/build)
+func stageContext(pipelineContext, stage string) string {
+	return pipelineContext + "/" + strings.ToLower(stage)
+}
+
+// stageStatusContext will return the context of a stage: rendered with STATUS_CONTEXT_TEMPLATE or nested under the
+// context of the pipeline (always for the scheduled context and the contexts of PIPELINE_CONFIG)
+func (h *Handler) stageStatusContext(pipelineContext, pipelineName, stage string, scheduled bool) (string, error) {
+	if len(h.cfg.StatusContextTemplate) == 0 || scheduled || len(h.pipelines[pipelineName].Context) > 0 {
+		return stageContext(pipelineContext, stage), nil
+	}
+	return h.renderStatusContext(pipelineName, stage)
+}
+
+// processStageEvent will post a separate status for a stage of the execution in the event
+// (the pipeline status is left alone)
+func (h *Handler) processStageEvent(ctx context.Context, ev Event) error {
+	if err := validateStageEvent(ev); err != nil {
+		return err
+	}
+	logf(ctx, "Incoming Stage Details: %+v", ev.Detail)
+
+	// Use the configuration of the account that sent the event (and the role of the account and the region
+	// of the pipeline)
+	scoped, err := h.forExecution(ctx, ev.Account, ev.Region, ev.Detail.Pipeline)
+	if err != nil {
+		return err
+	}
+	return scoped.postStageStatus(ctx, ev)
+}
+
+// postStageStatus will post the status of a stage with the configuration of its account, region and pipeline
+func (h *Handler) postStageStatus(ctx context.Context, ev Event) error {
+
+	// Muted pipelines are acknowledged without posting a status
+	if h.isMuted(ctx, ev.Detail.Pipeline) {
+		return nil
+	}
+
+	// Pending stage updates are low priority near the daily budget
+	if stageStates[ev.Detail.State] == githubStatePending && h.coalescing() {
+		logf(ctx, "skipping stage %s update of %s near the github budget", ev.Detail.Stage, ev.Detail.Pipeline)
+		return nil
+	}
+	ctx, githubCalls := withGithubCalls(ctx)
+	defer h.recordBudget(ctx, githubCalls)
+
+	// Get the commit of the execution
+	executionOutput, err := getExecutionOutput(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, h.deps.CodePipeline)
+	if err != nil {
+		return err
+	}
+	commit, _, revisionURL, err := getCommitFromExecution(ctx, executionOutput, h.primaryArtifact(ev.Detail.Pipeline), h.forgeHosts(),
+		h.cfg.CodeCommitMirrors)
+	if tag := executionTag(executionOutput, h.primaryArtifact(ev.Detail.Pipeline)); len(tag) > 0 && (err != nil || revisionURL == nil) {
+		commit, revisionURL, err = h.getTagCommit(ctx, ev.Detail.Pipeline, tag)
+	}
+	if err == nil && revisionURL == nil {
+		err = missingArtifactError(executionOutput, h.primaryArtifact(ev.Detail.Pipeline))
+	}
+	if err != nil {
+		reportArtifactError(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, err)
+		return err
+	}
+	owner, repo := h.pipelineRepository(ev.Detail.Pipeline, revisionURL)
+	ctx = withLogCommit(ctx, owner, repo, commit)
+
+	// Get the status context for the stage
+	var pipelineARN, statusCtx string
+	if len(ev.Resources) > 0 {
+		pipelineARN = ev.Resources[0]
+	}
+	scheduled := isScheduled(executionOutput)
+	if scheduled {
+		statusCtx = h.cfg.ScheduledContext
+	} else if statusCtx, err = h.statusContext(ctx, ev.Detail.Pipeline, pipelineARN); err != nil {
+		return err
+	}
+	if statusCtx, err = h.stageStatusContext(statusCtx, ev.Detail.Pipeline, ev.Detail.Stage, scheduled); err != nil {
+		return err
+	}
+
+	// Expired approvals get their own state instead of a failure
+	state := stageStates[ev.Detail.State]
+	description := ev.Detail.Stage + " " + strings.ToLower(ev.Detail.State)
+	if len(h.cfg.ApprovalTimeoutState) > 0 && state == githubStateFailure {
+		var timedOut bool
+		if timedOut, err = h.approvalTimedOut(ctx, ev.Detail.Pipeline, ev.Detail.ExecutionID, ev.Detail.Stage); err != nil {
+			logWarnf(ctx, "unable to check the approval of the stage: %s", err.Error())
+		} else if timedOut {
+			state, description = h.cfg.ApprovalTimeoutState, "approval of "+ev.Detail.Stage+" timed out"
+		}
+	}
+
+	// The approvals of the production stages are held during a deploy freeze
+	if held := h.holdForFreeze(ctx, ev, state); len(held) > 0 {
+		state, description = githubStatePending, held
+	}
+
+	// Drop the events delivered after a later event of the stage
+	if !h.inOrder(ctx, ev, ev.Detail.Stage, statusCtx) {
+		return nil
+	}
+
+	// Skip the statuses that were already reported (duplicate deliveries and retries)
+	first, forget := h.firstReport(ctx, ev, statusCtx)
+	if !first {
+		return nil
+	}
+
+	// Post the status of the stage
+	targetURL := consoleURL(h.eventPartition(ev), h.cfg.AWSRegion, fmt.Sprintf(
+		"/codesuite/codepipeline/pipelines/%s/executions/%s", ev.Detail.Pipeline, ev.Detail.ExecutionID))
+	if err = h.postStatus(ctx, ev.Detail.Pipeline, revisionURL, StatusUpdate{
+		Commit:      commit,
+		Context:     statusCtx,
+		Description: joinDescription(description),
+		Owner:       owner,
+		Repo:        repo,
+		State:       state,
+		TargetURL:   targetURL,
+	}); err != nil {
+		forget()
+		return err
+	}
+
+	// Track the deploy stages in the environment of the stage (GitHub deployments)
+	if h.isDeploymentStage(ev.Detail.Stage) && h.statusReporter(ev.Detail.Pipeline, revisionURL).Name() == forgeGithub {
+		var release func()
+		if release, err = h.acquireGithubWrite(ctx); err != nil {
+			return err
+		}
+		err = h.postStageDeployment(ctx, ev, owner, repo, commit, state, targetURL)
+		release()
+		if err != nil {
+			logWarnf(ctx, "unable to post the deployment of the stage: %s", err.Error())
+		}
+	}
+
+	// Open (or close) the OpsGenie alert of the stage
+	if err = h.alertOpsgenie(ctx, ev.Detail.Pipeline, ev.Detail.Stage, state, description, targetURL); err != nil {
+		logWarnf(ctx, "unable to alert OpsGenie: %s", err.Error())
+	}
+	return nil
+}
+
+// approvalTimedOut will return true if the stage failed because a manual approval expired
+// (a rejected approval is updated by the reviewer, an expired one is not)
+func (h *Handler) approvalTimedOut(ctx context.Context, pipelineName, executionID, stage string) (bool, error) {
+	actions, err := getActionExecutions(ctx, pipelineName, executionID, h.deps.CodePipeline)
+	if err != nil {
+		return false, err
+	}
+	for _, action := range actions {
+		if aws.StringValue(action.StageName) != stage || action.Status != types.ActionExecutionStatusFailed ||
+			action.Input == nil || action.Input.ActionTypeId == nil {
+			continue
+		}
+		if action.Input.ActionTypeId.Category == types.ActionCategoryApproval && len(aws.StringValue(action.UpdatedBy)) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// skippedStages will return the stages of the pipeline that did not run in the execution
+// (disabled transitions, unmet conditions or an earlier failure)
+func (h *Handler) skippedStages(ctx context.Context, pipelineName, executionID string) (skipped []string, err error) {
+	var output *codepipeline.GetPipelineOutput
+	if output, err = h.deps.CodePipeline.GetPipeline(ctx, &codepipeline.GetPipelineInput{
+		Name: aws.String(pipelineName),
+	}); err != nil {
+		return
+	} else if output == nil || output.Pipeline == nil {
+		err = fmt.Errorf("missing pipeline: %s", pipelineName)
+		return
+	}
+
+	var actions []*types.ActionExecutionDetail
+	if actions, err = getActionExecutions(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil {
+		return
+	}
+	ran := make(map[string]bool)
+	for _, action := range actions {
+		ran[aws.StringValue(action.StageName)] = true
+	}
+	for _, stage := range output.Pipeline.Stages {
+		if name := aws.StringValue(stage.Name); !ran[name] {
+			skipped = append(skipped, name)
+		}
+	}
+	return
+}
+
+// postSkippedStages will post the skipped state on the stages that did not run in the finished execution,
+// so their contexts are not left pending
+func (h *Handler) postSkippedStages(ctx context.Context, pipelineName, executionID, owner, repo, commit, statusCtx, targetURL string,
+	scheduled bool) error {
+	skipped, err := h.skippedStages(ctx, pipelineName, executionID)
+	if err != nil {
+		return err
+	}
+	for _, stage := range skipped {
+		var skippedContext string
+		if skippedContext, err = h.stageStatusContext(statusCtx, pipelineName, stage, scheduled); err != nil {
+			return err
+		}
+		var req *http.Request
+		if req, err = h.newGithubRequest(
+			ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", owner, repo, commit), &payload{
+				Context:     skippedContext,
+				Description: joinDescription(stage + " skipped"),
+				State:       h.cfg.SkippedStageState,
+				TargetURL:   targetURL,
+			},
+		); err != nil {
+			return err
+		} else if err = h.doGithubRequest(req, http.StatusCreated, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockStagesPipelineClient is a pipeline whose execution ran the build and expired at the approval
+type mockStagesPipelineClient struct {
+	mockCodePipelineClient
+}
+
+// GetPipeline is a mock request for codepipeline
+func (m *mockStagesPipelineClient) GetPipeline(_ context.Context, input *codepipeline.GetPipelineInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineOutput, error) {
+	return &codepipeline.GetPipelineOutput{Pipeline: &types.PipelineDeclaration{
+		Name: input.Name,
+		Stages: []types.StageDeclaration{
+			{Name: aws.String("Build")}, {Name: aws.String("Approve")}, {Name: aws.String("Deploy")},
+		},
+	}}, nil
+}
+
+// ListActionExecutions is a mock request for codepipeline
+func (m *mockStagesPipelineClient) ListActionExecutions(_ context.Context, input *codepipeline.ListActionExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+	return &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: []types.ActionExecutionDetail{
+		{
+			ActionName: aws.String("Build"),
+			StageName:  aws.String("Build"),
+			Status:     types.ActionExecutionStatusSucceeded,
+		},
+		{
+			ActionName: aws.String("Review"),
+			Input: &types.ActionExecutionInput{
+				ActionTypeId: &types.ActionTypeId{Category: types.ActionCategoryApproval},
+			},
+			StageName: aws.String("Approve"),
+			Status:    types.ActionExecutionStatusFailed,
+		},
+	}}, nil
+}
+
+// TestStageContext will test stageContext()
+func TestStageContext(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		context  string
+		stage    string
+		expected string
+	}{
+		{"ci/pipeline", "Build", "ci/pipeline/build"},
+		{defaultStatusContext, "Deploy-Prod", defaultStatusContext + "/deploy-prod"},
+	}
+
+	for _, test := range tests {
+		if output := stageContext(test.context, test.stage); output != test.expected {
+			t.Errorf("%s Failed: [%s/%s] inputted and [%s] expected, but got: %s", t.Name(), test.context, test.stage, test.expected, output)
+		}
+	}
+}
+
+// TestValidateStageEvent will test validateStageEvent()
+func TestValidateStageEvent(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		detail        *Detail
+		expectedError bool
+	}{
+		{nil, true},
+		{&Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", State: "STARTED"}, true},
+		{&Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", Stage: "Build", State: "UNKNOWN"}, true},
+		{&Detail{ExecutionID: "12345678", Pipeline: "some-pipeline", Stage: "Build", State: "STARTED"}, false},
+	}
+
+	for _, test := range tests {
+		if err := validateStageEvent(Event{Detail: test.detail, DetailType: detailTypeStageExecution}); err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error not expected but got: %s", t.Name(), test.detail, err.Error())
+		} else if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted and error was expected", t.Name(), test.detail)
+		}
+	}
+}
+
+// TestHandlerProcessEventStage will test ProcessEvent() for a stage event
+func TestHandlerProcessEventStage(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:            "us-east-1",
+		ContextPrefixes:      stringMap{"status-succeed": "ci"},
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+	})
+
+	var received payload
+	var path string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		Stage:       "Build",
+		State:       "FAILED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path != "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("path was not as expected", path)
+	} else if received.Context != "ci/status-succeed/build" {
+		t.Fatal("context was not as expected", received.Context)
+	} else if received.State != githubStateFailure || received.Description != "Build failed" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Missing stage
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-succeed",
+		State:       "FAILED",
+	}}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestApprovalTimedOut will test Handler.approvalTimedOut()
+func TestApprovalTimedOut(t *testing.T) {
+	h := newTestHandler(Config{})
+	h.deps.CodePipeline = &mockStagesPipelineClient{}
+
+	var tests = []struct {
+		stage    string
+		expected bool
+	}{
+		{"Approve", true},
+		{"Build", false},
+		{"Deploy", false},
+	}
+
+	for _, test := range tests {
+		if output, err := h.approvalTimedOut(context.Background(), "some-pipeline", "12345678", test.stage); err != nil {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.stage, err.Error())
+		} else if output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%t] expected, but got: %t", t.Name(), test.stage, test.expected, output)
+		}
+	}
+}
+
+// TestHandlerProcessEventSkippedStages will test the skipped and approval timeout statuses
+func TestHandlerProcessEventSkippedStages(t *testing.T) {
+	h := newTestHandler(Config{
+		ApprovalTimeoutState: githubStateError,
+		ContextPrefixes:      stringMap{"status-fail": "ci"},
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		SkippedStageState:    githubStateSuccess,
+		Stage:                stageTesting,
+	})
+	mockPipeline := &mockStagesPipelineClient{}
+	h.deps.CodePipeline = mockPipeline
+
+	received := make(map[string]payload)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status payload
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		received[status.Context] = status
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// The approval expired
+	if err := h.ProcessEvent(Event{DetailType: detailTypeStageExecution, Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-fail",
+		Stage:       "Approve",
+		State:       "FAILED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if status := received["ci/status-fail/approve"]; status.State != githubStateError || status.Description != "approval of Approve timed out" {
+		t.Fatal("approval status was not as expected", status)
+	}
+
+	// The deploy stage never ran
+	if err := h.ProcessEvent(Event{Detail: &Detail{
+		ExecutionID: "12345678",
+		Pipeline:    "status-fail",
+		State:       "FAILED",
+	}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if status := received["ci/status-fail/deploy"]; status.State != githubStateSuccess || !strings.HasSuffix(status.Description, "skipped") {
+		t.Fatal("skipped status was not as expected", status)
+	} else if _, ok := received["ci/status-fail/build"]; ok {
+		t.Fatal("stages that ran should not be skipped")
+	}
+}
@@ -0,0 +1,601 @@
+/*
+Package pipelinestatus is the CodePipeline status event receiver, embed it in another function with NewHandler
+and Handler.ProcessEventWithContext or run it as the function with Main
+
+More information: https://github.com/mrz1836/codepipeline-to-github
+*/
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// Application defaults
+const (
+	githubDescriptionLimit = 140
+	githubStateError       = "error"
+	githubStateFailure     = "failure"
+	githubStatePending     = "pending"
+	githubStateSuccess     = "success"
+	sourceArtifactName     = "SourceCode"
+	stageTesting           = "testing"
+	stageProduction        = "production"
+)
+
+// Event is what is emitted by CloudWatch (or an action like {"action":"info"})
+type Event struct {
+	Account    string    `json:"account"`
+	Action     string    `json:"action"`
+	Detail     *Detail   `json:"detail"`
+	DetailType string    `json:"detail-type"`
+	Region     string    `json:"region"`
+	Resources  []string  `json:"resources"`
+	Time       time.Time `json:"time"`
+}
+
+// Detail is the custom event information (of an execution, a stage, a build or a deployment, or the API call of a stage
+// transition change)
+type Detail struct {
+	ExecutionID       string                `json:"execution-id"`
+	State             string                `json:"state"`
+	Pipeline          string                `json:"pipeline"`
+	Stage             string                `json:"stage"`
+	EventName         string                `json:"eventName"`
+	RequestParameters *transitionParameters `json:"requestParameters"`
+	BuildID           string                `json:"build-id"`
+	BuildInformation  *buildInformation     `json:"additional-information"`
+	BuildStatus       string                `json:"build-status"`
+	ProjectName       string                `json:"project-name"`
+	Application       string                `json:"application"`
+	DeploymentGroup   string                `json:"deploymentGroup"`
+	DeploymentID      string                `json:"deploymentId"`
+
+	// githubStatus is the status reported by a pipeline action (the execution is still running)
+	githubStatus string
+}
+
+// payload is the data payload to send Github
+type payload struct {
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+}
+
+// Config is for the application's configuration settings (loaded from environment variables)
+type Config struct {
+	Accounts                   accountMap    `split_words:"true" envconfig:"ACCOUNTS"`
+	AnnotateSecondaryRevisions bool          `split_words:"true" envconfig:"ANNOTATE_SECONDARY_REVISIONS"`
+	ApprovalTimeoutState       string        `split_words:"true" envconfig:"APPROVAL_TIMEOUT_STATE"`
+	AssumeRoleARN              string        `split_words:"true" envconfig:"ASSUME_ROLE_ARN"`
+	AttentionTable             string        `split_words:"true" envconfig:"ATTENTION_TABLE"`
+	AWSPartition               string        `split_words:"true" envconfig:"AWS_PARTITION"`
+	AWSRegion                  string        `split_words:"true" envconfig:"AWS_REGION"`
+	AzureDevOpsRepositories    stringMap     `split_words:"true" envconfig:"AZURE_DEVOPS_REPOSITORIES"`
+	AzureDevOpsToken           string        `split_words:"true" envconfig:"AZURE_DEVOPS_TOKEN"`
+	BitbucketAccessToken       string        `split_words:"true" envconfig:"BITBUCKET_ACCESS_TOKEN"`
+	BitbucketAppPassword       string        `split_words:"true" envconfig:"BITBUCKET_APP_PASSWORD"`
+	BitbucketUsername          string        `split_words:"true" envconfig:"BITBUCKET_USERNAME"`
+	BudgetTable                string        `split_words:"true" envconfig:"BUDGET_TABLE"`
+	CatalogBucket              string        `split_words:"true" envconfig:"CATALOG_BUCKET"`
+	CatalogPrefix              string        `split_words:"true" envconfig:"CATALOG_PREFIX" default:"catalog/"`
+	CDEventsBus                string        `split_words:"true" envconfig:"CDEVENTS_EVENT_BUS"`
+	CDEventsEnvironment        string        `split_words:"true" envconfig:"CDEVENTS_ENVIRONMENT"`
+	CDEventsTopicARN           string        `split_words:"true" envconfig:"CDEVENTS_TOPIC_ARN"`
+	CodeBuildEvents            bool          `split_words:"true" envconfig:"CODEBUILD_EVENTS"`
+	CodeCommitMirrors          stringMap     `split_words:"true" envconfig:"CODECOMMIT_MIRRORS"`
+	CodeDeployEvents           bool          `split_words:"true" envconfig:"CODEDEPLOY_EVENTS"`
+	ClosedExecutions           string        `split_words:"true" envconfig:"CLOSED_EXECUTIONS"`
+	CodeDeployPipelines        stringMap     `split_words:"true" envconfig:"CODEDEPLOY_PIPELINES"`
+	ConfigSSMPrefix            string        `split_words:"true" envconfig:"CONFIG_SSM_PREFIX"`
+	ConfigSSMTTL               time.Duration `default:"5m" split_words:"true" envconfig:"CONFIG_SSM_TTL"`
+	ContextPrefixes            stringMap     `split_words:"true" envconfig:"CONTEXT_PREFIXES"`
+	ContextPrefixTag           string        `split_words:"true" envconfig:"CONTEXT_PREFIX_TAG"`
+	DeadLetterQueueURL         string        `split_words:"true" envconfig:"DEAD_LETTER_QUEUE_URL"`
+	DedupTable                 string        `split_words:"true" envconfig:"DEDUP_TABLE"`
+	DefinitionTable            string        `split_words:"true" envconfig:"DEFINITION_TABLE"`
+	DeploymentStagePattern     string        `split_words:"true" envconfig:"DEPLOYMENT_STAGE_PATTERN"`
+	DescriptionTemplate        string        `split_words:"true" envconfig:"DESCRIPTION_TEMPLATE"`
+	EnvironmentTable           string        `split_words:"true" envconfig:"ENVIRONMENT_TABLE"`
+	EventOrderTable            string        `split_words:"true" envconfig:"EVENT_ORDER_TABLE"`
+	ExecutionTable             string        `split_words:"true" envconfig:"EXECUTION_TABLE"`
+	FailureComment             bool          `split_words:"true" envconfig:"FAILURE_COMMENT"`
+	FailureDetails             bool          `split_words:"true" envconfig:"FAILURE_DETAILS"`
+	FailurePullRequestComment  bool          `split_words:"true" envconfig:"FAILURE_PULL_REQUEST_COMMENT"`
+	FlakyFailureTable          string        `split_words:"true" envconfig:"FLAKY_FAILURE_TABLE"`
+	FlakyFailureThreshold      int           `default:"3" split_words:"true" envconfig:"FLAKY_FAILURE_THRESHOLD"`
+	FreezeCalendarURL          string        `split_words:"true" envconfig:"FREEZE_CALENDAR_URL"`
+	FreezeFailOpen             bool          `split_words:"true" envconfig:"FREEZE_FAIL_OPEN"`
+	FreezeRejectApprovals      bool          `split_words:"true" envconfig:"FREEZE_REJECT_APPROVALS"`
+	FreezeStagePattern         string        `default:"Prod*" split_words:"true" envconfig:"FREEZE_STAGE_PATTERN"`
+	FreezeWindows              []string      `split_words:"true" envconfig:"FREEZE_WINDOWS"`
+	GiteaAccessToken           string        `split_words:"true" envconfig:"GITEA_ACCESS_TOKEN"`
+	GiteaPipelines             []string      `split_words:"true" envconfig:"GITEA_PIPELINES"`
+	GiteaURL                   string        `split_words:"true" envconfig:"GITEA_URL"`
+	GithubAccessToken          string        `split_words:"true" envconfig:"GITHUB_ACCESS_TOKEN"`
+	GithubAPIBaseURL           string        `split_words:"true" envconfig:"GITHUB_API_BASE_URL"`
+	GithubAPIVersion           string        `default:"2022-11-28" split_words:"true" envconfig:"GITHUB_API_VERSION"`
+	GithubAppInstallationID    int64         `split_words:"true" envconfig:"GITHUB_APP_INSTALLATION_ID"`
+	GithubAppSecretARN         string        `split_words:"true" envconfig:"GITHUB_APP_SECRET_ARN"`
+	GithubBudgetCoalesceAt     float64       `default:"0.8" split_words:"true" envconfig:"GITHUB_BUDGET_COALESCE_AT"`
+	GithubCABundle             string        `split_words:"true" envconfig:"GITHUB_CA_BUNDLE"`
+	GithubDailyBudget          int64         `split_words:"true" envconfig:"GITHUB_DAILY_BUDGET"`
+	GithubInsecureSkipVerify   bool          `split_words:"true" envconfig:"GITHUB_INSECURE_SKIP_VERIFY"`
+	GithubMaxConcurrency       int           `default:"5" split_words:"true" envconfig:"GITHUB_MAX_CONCURRENCY"`
+	GithubPreviews             []string      `split_words:"true" envconfig:"GITHUB_PREVIEWS"`
+	GithubTokenSecretARN       string        `split_words:"true" envconfig:"GITHUB_TOKEN_SECRET_ARN"`
+	GithubTokenSecretKey       string        `default:"github_access_token" split_words:"true" envconfig:"GITHUB_TOKEN_SECRET_KEY"`
+	GithubTokenSecretTTL       time.Duration `default:"5m" split_words:"true" envconfig:"GITHUB_TOKEN_SECRET_TTL"`
+	GithubWebhookSecret        string        `split_words:"true" envconfig:"GITHUB_WEBHOOK_SECRET"`
+	GitlabAccessToken          string        `split_words:"true" envconfig:"GITLAB_ACCESS_TOKEN"`
+	HoneycombAPIKey            string        `split_words:"true" envconfig:"HONEYCOMB_API_KEY"`
+	HoneycombAPIURL            string        `default:"https://api.honeycomb.io" split_words:"true" envconfig:"HONEYCOMB_API_URL"`
+	HoneycombDataset           string        `split_words:"true" envconfig:"HONEYCOMB_DATASET"`
+	HoneycombUIURL             string        `split_words:"true" envconfig:"HONEYCOMB_UI_URL"`
+	IngestionMode              string        `default:"eventbridge" split_words:"true" envconfig:"INGESTION_MODE"`
+	InitiatorHandles           stringMap     `split_words:"true" envconfig:"INITIATOR_HANDLES"`
+	InitiatorLookup            bool          `split_words:"true" envconfig:"INITIATOR_LOOKUP"`
+	LinkBuildLogs              bool          `split_words:"true" envconfig:"LINK_BUILD_LOGS"`
+	LogLevel                   string        `split_words:"true" envconfig:"LOG_LEVEL" default:"info"`
+	LogSampleRate              float64       `default:"1" split_words:"true" envconfig:"LOG_SAMPLE_RATE"`
+	MuteTable                  string        `split_words:"true" envconfig:"MUTE_TABLE"`
+	NotificationTemplate       string        `split_words:"true" envconfig:"NOTIFICATION_TEMPLATE"`
+	NotifierTimeout            time.Duration `default:"2s" split_words:"true" envconfig:"NOTIFIER_TIMEOUT"`
+	NotifyStates               []string      `default:"error,failure,success" split_words:"true" envconfig:"NOTIFY_STATES"`
+	OpsgenieAPIKey             string        `split_words:"true" envconfig:"OPSGENIE_API_KEY"`
+	OpsgenieAPIURL             string        `default:"https://api.opsgenie.com" split_words:"true" envconfig:"OPSGENIE_API_URL"`
+	OpsgenieTeams              stringMap     `split_words:"true" envconfig:"OPSGENIE_TEAMS"`
+	OrphanedCommits            string        `split_words:"true" envconfig:"ORPHANED_COMMITS"`
+	PipelineConfig             string        `split_words:"true" envconfig:"PIPELINE_CONFIG"`
+	PipelineRegions            []string      `split_words:"true" envconfig:"PIPELINE_REGIONS"`
+	PipelineRenames            stringMap     `split_words:"true" envconfig:"PIPELINE_RENAMES"`
+	PipelineSeverities         stringMap     `split_words:"true" envconfig:"PIPELINE_SEVERITIES"`
+	PortalEntities             stringMap     `split_words:"true" envconfig:"PORTAL_ENTITIES"`
+	PrimaryArtifacts           stringMap     `split_words:"true" envconfig:"PRIMARY_ARTIFACTS"`
+	PrometheusJob              string        `default:"codepipeline-to-github" split_words:"true" envconfig:"PROMETHEUS_JOB"`
+	PrometheusPushgatewayURL   string        `split_words:"true" envconfig:"PROMETHEUS_PUSHGATEWAY_URL"`
+	RateLimitBurst             int           `default:"10" split_words:"true" envconfig:"RATE_LIMIT_BURST"`
+	RateLimitPerSecond         float64       `default:"1" split_words:"true" envconfig:"RATE_LIMIT_PER_SECOND"`
+	RateLimitTable             string        `split_words:"true" envconfig:"RATE_LIMIT_TABLE"`
+	ReleaseTrains              stringMap     `split_words:"true" envconfig:"RELEASE_TRAINS"`
+	ReleaseTrainTable          string        `split_words:"true" envconfig:"RELEASE_TRAIN_TABLE"`
+	RequireVerifiedCommits     bool          `split_words:"true" envconfig:"REQUIRE_VERIFIED_COMMITS"`
+	RollupComment              bool          `split_words:"true" envconfig:"ROLLUP_COMMENT"`
+	ScheduledContext           string        `default:"nightly/codepipeline" split_words:"true" envconfig:"SCHEDULED_CONTEXT"`
+	ServerAddress              string        `default:":8080" split_words:"true" envconfig:"SERVER_ADDRESS"`
+	ServerSecret               string        `split_words:"true" envconfig:"SERVER_SECRET"`
+	SeverityNotifiers          stringMap     `split_words:"true" envconfig:"SEVERITY_NOTIFIERS"`
+	ShadowAuditTable           string        `split_words:"true" envconfig:"SHADOW_AUDIT_TABLE"`
+	ShadowMode                 string        `split_words:"true" envconfig:"SHADOW_MODE"`
+	ShadowRepository           string        `split_words:"true" envconfig:"SHADOW_REPOSITORY"`
+	SkippedStageState          string        `split_words:"true" envconfig:"SKIPPED_STAGE_STATE"`
+	SlackWebhookURL            string        `split_words:"true" envconfig:"SLACK_WEBHOOK_URL"`
+	Stage                      string        `split_words:"true" envconfig:"APPLICATION_STAGE_NAME"`
+	StageDurationTable         string        `split_words:"true" envconfig:"STAGE_DURATION_TABLE"`
+	StageDurationThreshold     float64       `default:"1.5" split_words:"true" envconfig:"STAGE_DURATION_THRESHOLD"`
+	StatusContextTemplate      string        `split_words:"true" envconfig:"STATUS_CONTEXT_TEMPLATE"`
+	StatusTopicARN             string        `split_words:"true" envconfig:"STATUS_TOPIC_ARN"`
+	TargetURLTemplate          string        `split_words:"true" envconfig:"TARGET_URL_TEMPLATE"`
+	TeamsWebhooks              stringMap     `split_words:"true" envconfig:"TEAMS_WEBHOOKS"`
+	TeamsWebhookURL            string        `split_words:"true" envconfig:"TEAMS_WEBHOOK_URL"`
+	TemplateEnvAllowlist       []string      `split_words:"true" envconfig:"TEMPLATE_ENV_ALLOWLIST"`
+	TimelineTable              string        `split_words:"true" envconfig:"TIMELINE_TABLE"`
+	TokenExpiryWarningDays     int           `default:"14" split_words:"true" envconfig:"TOKEN_EXPIRY_WARNING_DAYS"`
+	TracingDisabled            bool          `split_words:"true" envconfig:"TRACING_DISABLED"`
+	TransitionTable            string        `split_words:"true" envconfig:"TRANSITION_TABLE"`
+	UsageCodeBuildMinutes      bool          `split_words:"true" envconfig:"USAGE_CODEBUILD_MINUTES"`
+	UsageTable                 string        `split_words:"true" envconfig:"USAGE_TABLE"`
+	UseChecksAPI               bool          `split_words:"true" envconfig:"USE_CHECKS_API"`
+	VerifyStatusVisibility     bool          `split_words:"true" envconfig:"VERIFY_STATUS_VISIBILITY"`
+	WarmUp                     bool          `split_words:"true" envconfig:"WARM_UP"`
+}
+
+// stringMap is a map configured as a JSON object (IE: {"key":"value"})
+type stringMap map[string]string
+
+// Decode will parse the map from a JSON object
+func (m *stringMap) Decode(value string) error {
+	return json.Unmarshal([]byte(value), (*map[string]string)(m))
+}
+
+// awsSession is the shared AWS session for the container
+var awsSession *session.Session
+
+// containerDependencies are the services of the container, shared by the handlers of its events (with the caches)
+var containerDependencies Dependencies
+
+// ProcessEvent is triggered by a CloudWatch event rule, the configuration is loaded from
+// the environment and the AWS services are created from the shared session (the AWS requests
+// are cancelled at the deadline of the invocation)
+func ProcessEvent(ctx context.Context, ev Event) error {
+
+	// Check for required parameters
+	if err := validateEvent(ev); err != nil {
+		return err
+	}
+
+	// Create the handler and process the event
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessEventWithContext(ctx, ev)
+}
+
+// handlerFromEnvironment will create a handler using the configuration from the environment
+// and the AWS services from the shared session (kept to flush the buffers on shutdown)
+func handlerFromEnvironment(ctx context.Context) (*Handler, error) {
+	h, err := NewHandlerFromEnvironment(ctx, containerDependencies)
+	if err != nil {
+		return nil, err
+	}
+	setShutdownHandler(h)
+	return h, nil
+}
+
+// NewHandlerFromEnvironment will create a handler with the configuration of the function (the environment and
+// its providers, decrypted) and the dependencies, IE: to embed the handler in another function
+func NewHandlerFromEnvironment(ctx context.Context, deps Dependencies) (*Handler, error) {
+
+	// Load the configuration
+	if deps.cache == nil {
+		deps.cache = newContainerCache()
+	}
+	cfg, err := loadConfiguration(ctx, deps.cache, deps.KMS, deps.SecretsManager, environmentProviders(deps)...)
+	if err != nil {
+		return nil, err
+	}
+	var h *Handler
+	if h, err = newHandler(cfg, deps); err != nil {
+		return nil, err
+	} else if h.pipelines, err = loadPipelineConfigs(ctx, deps, cfg.PipelineConfig, cfg.ConfigSSMTTL); err != nil {
+		return nil, err
+	}
+
+	// Fail fast if the GitHub token cannot post for the enabled features
+	if cfg.Stage != stageTesting {
+		if err = h.verifyTokenPermissions(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// joinDescription will combine the non-empty parts of a status description within GitHub's length limit
+// (truncated on a character boundary)
+func joinDescription(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if len(part) > 0 {
+			kept = append(kept, part)
+		}
+	}
+	description := strings.Join(kept, "; ")
+	if len(description) > githubDescriptionLimit {
+		cut := githubDescriptionLimit - 3
+		for cut > 0 && !utf8.RuneStart(description[cut]) {
+			cut--
+		}
+		description = description[:cut] + "..."
+	}
+	return description
+}
+
+// loadConfiguration will load the configuration from the environment and the providers (IE: SSM) and
+// decrypt any encrypted variables, the GitHub token is fetched from Secrets Manager instead if
+// GITHUB_TOKEN_SECRET_ARN is set (or created for the installation of the GitHub App of GITHUB_APP_SECRET_ARN)
+func loadConfiguration(ctx context.Context, cache *containerCache, kmsSvc KMSAPI,
+	secrets secretsmanageriface.SecretsManagerAPI, providers ...ConfigProvider) (cfg Config, err error) {
+
+	// Get configuration set using environment variables, then the settings of the providers
+	var settings map[string]string
+	var provided map[string]bool
+	if settings, err = providerSettings(ctx, providers); err != nil {
+		return
+	} else if provided, err = processConfiguration(&cfg, settings); err != nil {
+		return
+	} else if len(cfg.GithubAccessToken) == 0 && len(cfg.GithubTokenSecretARN) == 0 && len(cfg.GithubAppSecretARN) == 0 {
+		err = errors.New("required key GITHUB_ACCESS_TOKEN, GITHUB_TOKEN_SECRET_ARN or GITHUB_APP_SECRET_ARN missing value")
+		return
+	} else if len(cfg.GithubAppSecretARN) > 0 && cfg.GithubAppInstallationID == 0 {
+		err = errors.New("GITHUB_APP_SECRET_ARN requires GITHUB_APP_INSTALLATION_ID")
+		return
+	}
+
+	// Skip KMS and Secrets Manager on testing stage
+	if cfg.Stage == stageTesting {
+		return
+	}
+
+	// Decrypt the webhooks (plain webhook urls are used as they are)
+	if cfg.SlackWebhookURL, err = cache.decryptWebhook(ctx, kmsSvc, cfg.SlackWebhookURL, provided["SLACK_WEBHOOK_URL"]); err != nil {
+		return
+	} else if cfg.TeamsWebhookURL, err = cache.decryptWebhook(ctx, kmsSvc, cfg.TeamsWebhookURL, provided["TEAMS_WEBHOOK_URL"]); err != nil {
+		return
+	}
+
+	// Decrypt the tokens of the other forges (mirrored repositories), Honeycomb, OpsGenie and the webhook and
+	// server secrets
+	for name, token := range map[string]*string{
+		"AZURE_DEVOPS_TOKEN":     &cfg.AzureDevOpsToken,
+		"BITBUCKET_ACCESS_TOKEN": &cfg.BitbucketAccessToken,
+		"BITBUCKET_APP_PASSWORD": &cfg.BitbucketAppPassword,
+		"GITEA_ACCESS_TOKEN":     &cfg.GiteaAccessToken,
+		"GITHUB_WEBHOOK_SECRET":  &cfg.GithubWebhookSecret,
+		"GITLAB_ACCESS_TOKEN":    &cfg.GitlabAccessToken,
+		"HONEYCOMB_API_KEY":      &cfg.HoneycombAPIKey,
+		"OPSGENIE_API_KEY":       &cfg.OpsgenieAPIKey,
+		"SERVER_SECRET":          &cfg.ServerSecret,
+	} {
+		if len(*token) > 0 && !provided[name] {
+			if *token, err = cache.decryptString(ctx, kmsSvc, *token); err != nil {
+				return
+			}
+		}
+	}
+
+	// Decrypt the tokens of the accounts
+	if !provided["ACCOUNTS"] {
+		if cfg.Accounts, err = cache.decryptAccounts(ctx, kmsSvc, cfg.Accounts); err != nil {
+			return
+		}
+	}
+
+	// Create a token of the installation of the GitHub App (cached per container until it expires)
+	if len(cfg.GithubAppSecretARN) > 0 {
+		if secrets == nil {
+			err = errors.New("missing dependency: SecretsManager")
+			return
+		}
+		var apiURL string
+		if apiURL, err = githubAPIURL(cfg); err != nil {
+			return
+		}
+		var token installationToken
+		if token, err = cache.getInstallationToken(ctx, secrets, apiURL, cfg.GithubAppSecretARN, cfg.GithubAppInstallationID,
+			time.Now()); err != nil {
+			return
+		}
+		cfg.GithubAccessToken = token.Token
+		return
+	}
+
+	// Get the Token from the secret (cached per container)
+	if len(cfg.GithubTokenSecretARN) > 0 {
+		if secrets == nil {
+			err = errors.New("missing dependency: SecretsManager")
+			return
+		}
+		cfg.GithubAccessToken, err = cache.getSecretToken(ctx, secrets, cfg.GithubTokenSecretARN,
+			cfg.GithubTokenSecretKey, cfg.GithubTokenSecretTTL, time.Now())
+		return
+	}
+
+	// Providers return the Token decrypted
+	if provided["GITHUB_ACCESS_TOKEN"] {
+		return
+	}
+
+	// Update the Token with the decoded value or fail
+	cfg.GithubAccessToken, err = cache.decryptString(ctx, kmsSvc, cfg.GithubAccessToken)
+	return
+}
+
+// decryptWebhook will decrypt an encrypted webhook url (plain urls and the settings of the providers are returned as they are)
+func (c *containerCache) decryptWebhook(ctx context.Context, kmsSvc KMSAPI, webhookURL string, provided bool) (string, error) {
+	if len(webhookURL) == 0 || provided || strings.HasPrefix(webhookURL, "https://") {
+		return webhookURL, nil
+	}
+	return c.decryptString(ctx, kmsSvc, webhookURL)
+}
+
+// getCommit will get the Github commit and revision url from an execution
+func getCommit(ctx context.Context, pipelineName, executionID, artifactName string, forgeHosts, mirrors map[string]string,
+	pipeline CodePipelineAPI) (commit, status string, revisionURL *url.URL, err error) {
+
+	// Get the execution details
+	var executionOutput *codepipeline.GetPipelineExecutionOutput
+	if executionOutput, err = getExecutionOutput(ctx, pipelineName, executionID, pipeline); err != nil {
+		return
+	}
+
+	return getCommitFromExecution(ctx, executionOutput, artifactName, forgeHosts, mirrors)
+}
+
+// getCommitFromExecution will get the Github commit and revision url of the source artifact from the execution details
+func getCommitFromExecution(ctx context.Context, executionOutput *codepipeline.GetPipelineExecutionOutput, artifactName string,
+	forgeHosts, mirrors map[string]string) (commit, status string,
+	revisionURL *url.URL, err error) {
+
+	// Find the source artifacts
+	sourceArtifact := getArtifact(executionOutput, artifactName)
+
+	// No artifact to work with (this occurs if a "Release Change" event is fired)
+	if sourceArtifact == nil {
+		logWarnf(ctx, "no %s found in execution: %s for pipeline: %s",
+			artifactName, *executionOutput.PipelineExecution.PipelineExecutionId, *executionOutput.PipelineExecution.PipelineName)
+		return
+	}
+
+	// Set the commit
+	commit = aws.StringValue(sourceArtifact.RevisionId)
+
+	// Parse the revision URL
+	if revisionURL, err = url.Parse(aws.StringValue(sourceArtifact.RevisionUrl)); err != nil {
+		return
+	} else if revisionURL == nil {
+		err = fmt.Errorf("missing %s: %s", artifactName, "RevisionUrl")
+		return
+	} else if revisionURL, err = mirrorRevisionURL(revisionURL, commit, forgeHosts, mirrors); err != nil {
+		if artifactErr, ok := err.(*artifactError); ok {
+			artifactErr.Artifact = artifactName
+		}
+		return
+	} else if err = validateArtifact(commit, revisionURL, forgeHosts); err != nil {
+		if artifactErr, ok := err.(*artifactError); ok {
+			artifactErr.Artifact = artifactName
+		}
+		revisionURL = nil
+		return
+	}
+
+	// Set the status based on the pipeline status
+	status = getStatus(executionOutput)
+
+	return
+}
+
+// getStatus will return the Github status for the execution status
+func getStatus(executionOutput *codepipeline.GetPipelineExecutionOutput) string {
+	return executionStatusState(string(executionOutput.PipelineExecution.Status))
+}
+
+// executionStatusState will return the Github status of an execution status (IE: InProgress)
+func executionStatusState(status string) string {
+	switch status {
+	case "InProgress":
+		return githubStatePending
+	case "Succeeded":
+		return githubStateSuccess
+	default:
+		return githubStateFailure
+	}
+}
+
+// decryptString uses AWS Key Management Service (AWS KMS) to decrypt environment variables.
+// In order for this method to work, the function needs access to the kms:Decrypt capability.
+// The values are decrypted once per container (a ciphertext always decrypts to the same value).
+func (c *containerCache) decryptString(ctx context.Context, kmsSvc KMSAPI, encryptedText string) (string, error) {
+	c.decryptedMu.Lock()
+	defer c.decryptedMu.Unlock()
+	if decrypted, ok := c.decrypted[encryptedText]; ok {
+		return decrypted, nil
+	}
+
+	// Decode the encryptedText
+	sDec, err := base64.StdEncoding.DecodeString(encryptedText)
+	if err != nil {
+		return "", err
+	}
+
+	// Decrypt the decoded text
+	var out *kms.DecryptOutput
+	if out, err = kmsSvc.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: sDec,
+	}); err != nil {
+		return "", err
+	}
+
+	// Return a string with no leading or trailing spaces or carriage returns
+	decrypted := strings.TrimSpace(strings.TrimSuffix(string(out.Plaintext), "\n"))
+	c.decrypted[encryptedText] = decrypted
+	return decrypted, nil
+}
+
+// getExecutionOutput will return the output details of the pipeline execution
+func getExecutionOutput(ctx context.Context, pipelineName, executionID string,
+	pipeline CodePipelineAPI) (response *codepipeline.GetPipelineExecutionOutput, err error) {
+	if response, err = pipeline.GetPipelineExecution(ctx, &codepipeline.GetPipelineExecutionInput{
+		PipelineExecutionId: aws.String(executionID),
+		PipelineName:        aws.String(pipelineName),
+	}); err != nil {
+		return
+	} else if response == nil {
+		err = fmt.Errorf("missing pipeline execution")
+	}
+	return
+}
+
+// getArtifact will get the artifact by name from a given output
+func getArtifact(executionOutput *codepipeline.GetPipelineExecutionOutput, artifactName string) (sourceArtifact *types.ArtifactRevision) {
+	for i, artifact := range executionOutput.PipelineExecution.ArtifactRevisions {
+		if aws.StringValue(artifact.Name) == artifactName {
+			sourceArtifact = &executionOutput.PipelineExecution.ArtifactRevisions[i]
+			break
+		}
+	}
+	return
+}
+
+// setupSession will create the shared AWS session and the configuration of the aws-sdk-go-v2 clients (in the
+// region of AWS_REGION)
+func setupSession() {
+	if awsSession == nil {
+		// The shared config is read next to the environment (IE: the profile and its region on a developer laptop,
+		// an empty AWS_REGION does not replace the region of the profile)
+		awsSession = session.Must(session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		}))
+		awsSession.Handlers.Complete.PushBack(logAWSRequest)
+		awsSession.Handlers.Complete.PushBack(traceAWSRequest)
+
+		var err error
+		if awsConfig, err = loadAWSConfig(context.Background()); err != nil {
+			panic(err)
+		}
+		containerDependencies = NewDependencies(awsSession, awsConfig)
+	}
+}
+
+// Main will start the lambda event handler (or run a command: status permissions) from the main package
+func Main() {
+	setupSession()
+
+	// Run a command instead of the handler
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:], os.Stdout); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Prepare the container before the first event
+	if warm, _ := strconv.ParseBool(os.Getenv("WARM_UP")); warm {
+		if err := warmUp(context.Background()); err != nil {
+			logWarnf(context.Background(), "unable to warm up: %s", err.Error())
+		}
+	}
+
+	// Start lambda (jobs of a pipeline action, events from a Kinesis stream or the dead-letter queue, GitHub webhooks
+	// or directly from EventBridge), or the HTTP server of a container
+	if os.Getenv("INGESTION_MODE") != ingestionModeServer {
+		enableShutdownFlush() // the server flushes after each event and shuts down on its own SIGTERM
+	}
+	switch os.Getenv("INGESTION_MODE") {
+	case ingestionModeServer:
+		if err := runServer(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	case ingestionModeAction:
+		lambda.Start(ProcessJobEvent)
+	case ingestionModeKinesis:
+		lambda.Start(ProcessKinesisEvent)
+	case ingestionModeDLQ:
+		lambda.Start(ProcessDeadLetterEvent)
+	case ingestionModeWebhook:
+		lambda.Start(ProcessWebhookEvent)
+	default:
+		lambda.Start(HandleRequest)
+	}
+}
@@ -1,25 +1,27 @@
-package main
+package pipelinestatus
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/codepipeline"
-	"github.com/aws/aws-sdk-go/service/codepipeline/codepipelineiface"
-	"github.com/aws/aws-sdk-go/service/kms"
-	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 )
 
 // Mocking kms client
 type mockKmsClient struct {
-	kmsiface.KMSAPI
+	KMSAPI
 }
 
 // Decrypt is used for mocking a decryption of a KMS key
-func (m *mockKmsClient) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+func (m *mockKmsClient) Decrypt(ctx context.Context, input *kms.DecryptInput,
+	_ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
 
 	// Invalid input
 	if len(input.CiphertextBlob) == 0 {
@@ -34,11 +36,17 @@ func (m *mockKmsClient) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, er
 
 // Mocking pipeline client
 type mockCodePipelineClient struct {
-	codepipelineiface.CodePipelineAPI
+	CodePipelineAPI
 }
 
 // GetPipelineExecution is a mock request for codepipeline
-func (m *mockCodePipelineClient) GetPipelineExecution(input *codepipeline.GetPipelineExecutionInput) (*codepipeline.GetPipelineExecutionOutput, error) {
+func (m *mockCodePipelineClient) GetPipelineExecution(ctx context.Context, input *codepipeline.GetPipelineExecutionInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.GetPipelineExecutionOutput, error) {
+
+	// Cancelled request (IE: the deadline of the invocation passed)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Missing pipeline name
 	if len(aws.StringValue(input.PipelineName)) == 0 {
@@ -56,24 +64,52 @@ func (m *mockCodePipelineClient) GetPipelineExecution(input *codepipeline.GetPip
 	}
 
 	// Create a valid artifact
-	var artifacts []*codepipeline.ArtifactRevision
+	var artifacts []types.ArtifactRevision
 
 	if aws.StringValue(input.PipelineName) == "bad-artifact-name" {
-		artifacts = append(artifacts, &codepipeline.ArtifactRevision{
+		artifacts = append(artifacts, types.ArtifactRevision{
 			Name:            aws.String("InvalidArtifactName"),
 			RevisionId:      aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
 			RevisionSummary: aws.String("Some commit message"),
 			RevisionUrl:     aws.String("https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
 		})
 	} else if aws.StringValue(input.PipelineName) == "bad-artifact-url" {
-		artifacts = append(artifacts, &codepipeline.ArtifactRevision{
+		artifacts = append(artifacts, types.ArtifactRevision{
 			Name:            aws.String("InvalidArtifactName"),
 			RevisionId:      aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
 			RevisionSummary: aws.String("Some commit message"),
 			RevisionUrl:     aws.String("not a url"),
 		})
+	} else if aws.StringValue(input.PipelineName) == "bitbucket" {
+		artifacts = append(artifacts, types.ArtifactRevision{
+			Name:        aws.String("SourceCode"),
+			RevisionId:  aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+			RevisionUrl: aws.String("https://bitbucket.org/mrz1836/codepipeline-to-github/commits/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+		})
+	} else if aws.StringValue(input.PipelineName) == "gitlab-mirror" {
+		artifacts = append(artifacts, types.ArtifactRevision{
+			Name:        aws.String("SourceCode"),
+			RevisionId:  aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+			RevisionUrl: aws.String("https://gitlab.example.com/group/sub/project/-/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+		})
+	} else if aws.StringValue(input.PipelineName) == "release-tag" {
+		artifacts = append(artifacts, types.ArtifactRevision{
+			Name:        aws.String("SourceCode"),
+			RevisionId:  aws.String("refs/tags/v1.2.3"),
+			RevisionUrl: aws.String("https://github.com/mrz1836/codepipeline-to-github/releases/tag/v1.2.3"),
+		})
+	} else if aws.StringValue(input.PipelineName) == "multi-branch" {
+		artifacts = append(artifacts, types.ArtifactRevision{
+			Name:        aws.String("Overlay"),
+			RevisionId:  aws.String("1111111111111111111111111111111111111111"),
+			RevisionUrl: aws.String("https://github.com/mrz1836/codepipeline-to-github/commit/1111111111111111111111111111111111111111"),
+		}, types.ArtifactRevision{
+			Name:        aws.String("Trunk"),
+			RevisionId:  aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+			RevisionUrl: aws.String("https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
+		})
 	} else {
-		artifacts = append(artifacts, &codepipeline.ArtifactRevision{
+		artifacts = append(artifacts, types.ArtifactRevision{
 			Name:            aws.String("SourceCode"),
 			RevisionId:      aws.String("25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"),
 			RevisionSummary: aws.String("Some commit message"),
@@ -81,18 +117,18 @@ func (m *mockCodePipelineClient) GetPipelineExecution(input *codepipeline.GetPip
 		})
 	}
 
-	defaultStatus := aws.String("InProgress")
+	defaultStatus := types.PipelineExecutionStatusInProgress
 
 	// Change the status
 	if aws.StringValue(input.PipelineName) == "status-succeed" {
-		defaultStatus = aws.String("Succeeded")
+		defaultStatus = types.PipelineExecutionStatusSucceeded
 	} else if aws.StringValue(input.PipelineName) == "status-fail" {
-		defaultStatus = aws.String("Failure")
+		defaultStatus = types.PipelineExecutionStatus("Failure")
 	}
 
 	// Create a valid execution output
 	output := &codepipeline.GetPipelineExecutionOutput{
-		PipelineExecution: &codepipeline.PipelineExecution{
+		PipelineExecution: &types.PipelineExecution{
 			ArtifactRevisions:   artifacts,
 			PipelineExecutionId: input.PipelineExecutionId,
 			PipelineName:        input.PipelineName,
@@ -106,61 +142,57 @@ func (m *mockCodePipelineClient) GetPipelineExecution(input *codepipeline.GetPip
 // TestProcessEvent will test the ProcessEvent() method
 func TestProcessEvent(t *testing.T) {
 
+	// Create the shared AWS session
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	setupSession()
 	os.Clearenv()
 
-	// Create a new AWS session
-	if awsSession == nil {
-		awsSession = session.Must(session.NewSession(&aws.Config{
-			Region: aws.String("us-east-1"),
-		}))
-	}
-
 	t.Run("missing event detail", func(t *testing.T) {
-		if err := ProcessEvent(event{}); err == nil {
+		if err := ProcessEvent(context.Background(), Event{}); err == nil {
 			t.Fatal("error failed to trigger with an invalid request")
 		}
 	})
 
 	t.Run("missing param execution-id", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "",
 			}}
-		if err := ProcessEvent(ev); err == nil {
+		if err := ProcessEvent(context.Background(), ev); err == nil {
 			t.Fatal("error failed to trigger with an invalid request")
 		}
 	})
 
 	t.Run("missing param pipeline", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "12345678",
 			}}
-		if err := ProcessEvent(ev); err == nil {
+		if err := ProcessEvent(context.Background(), ev); err == nil {
 			t.Fatal("error failed to trigger with an invalid request")
 		}
 	})
 
 	t.Run("missing pipeline execution", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "12345678",
 				Pipeline:    "12345678",
 			}}
 		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
-		if err := ProcessEvent(ev); err == nil {
+		if err := ProcessEvent(context.Background(), ev); err == nil {
 			t.Fatal("error failed to trigger with an invalid request")
 		}
 	})
 
 	t.Run("required key AWS_REGION missing value", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "12345678",
 				Pipeline:    "12345678",
 			}}
 		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
-		err := ProcessEvent(ev)
+		err := ProcessEvent(context.Background(), ev)
 		if err == nil {
 			t.Fatal("expected error")
 		} else if err.Error() != "required key AWS_REGION missing value" {
@@ -169,14 +201,14 @@ func TestProcessEvent(t *testing.T) {
 	})
 
 	t.Run("required key APPLICATION_STAGE_NAME missing value", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "12345678",
 				Pipeline:    "12345678",
 			}}
 		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
 		_ = os.Setenv("AWS_REGION", "us-east-1")
-		err := ProcessEvent(ev)
+		err := ProcessEvent(context.Background(), ev)
 		if err == nil {
 			t.Fatal("expected error")
 		} else if err.Error() != "required key APPLICATION_STAGE_NAME missing value" {
@@ -185,15 +217,15 @@ func TestProcessEvent(t *testing.T) {
 	})
 
 	t.Run("ValidationException: ExecutionID", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "12345678",
 				Pipeline:    "12345678",
 			}}
 		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
 		_ = os.Setenv("AWS_REGION", "us-east-1")
 		_ = os.Setenv("APPLICATION_STAGE_NAME", "testing")
-		err := ProcessEvent(ev)
+		err := ProcessEvent(context.Background(), ev)
 		if err == nil {
 			t.Fatal("error was expected")
 		} /*else if !strings.Contains(err.Error(), "ValidationException: 1 validation error detected") {
@@ -202,15 +234,15 @@ func TestProcessEvent(t *testing.T) {
 	})
 
 	t.Run("PipelineNotFoundException: The account with id", func(t *testing.T) {
-		ev := event{
-			Detail: &detail{
+		ev := Event{
+			Detail: &Detail{
 				ExecutionID: "a5ef215c-43b4-4513-b97f-1829f642e0b1",
 				Pipeline:    "12345678",
 			}}
 		_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
 		_ = os.Setenv("AWS_REGION", "us-east-1")
 		_ = os.Setenv("APPLICATION_STAGE_NAME", "testing")
-		err := ProcessEvent(ev)
+		err := ProcessEvent(context.Background(), ev)
 		if err == nil {
 			t.Fatal("error was expected")
 		} /* else if !strings.Contains(err.Error(), "PipelineNotFoundException: The account with id") {
@@ -245,7 +277,7 @@ func TestGetExecutionOutput(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		response, err := getExecutionOutput(test.pipelineName, test.executionID, mockPipeline)
+		response, err := getExecutionOutput(context.Background(), test.pipelineName, test.executionID, mockPipeline)
 		if err == nil && test.expectedError {
 			t.Errorf("%s Failed: codepipeline [%s] executionID [%s], expected to throw an error, but no error", t.Name(), test.pipelineName, test.executionID)
 		} else if err != nil && !test.expectedError {
@@ -258,10 +290,17 @@ func TestGetExecutionOutput(t *testing.T) {
 			t.Errorf("%s Failed: codepipeline [%s] executionID [%s], expected [%s]", t.Name(), test.pipelineName, test.executionID, test.expectedPipelineName)
 		} else if response != nil && aws.StringValue(response.PipelineExecution.PipelineExecutionId) != test.expectedExecutionID && !test.expectedError {
 			t.Errorf("%s Failed: codepipeline [%s] executionID [%s], expected [%s]", t.Name(), test.pipelineName, test.executionID, test.executionID)
-		} else if response != nil && aws.StringValue(response.PipelineExecution.Status) != test.expectedStatus && !test.expectedError {
+		} else if response != nil && string(response.PipelineExecution.Status) != test.expectedStatus && !test.expectedError {
 			t.Errorf("%s Failed: codepipeline [%s] executionID [%s], expected [%s]", t.Name(), test.pipelineName, test.executionID, test.expectedStatus)
 		}
 	}
+
+	// Cancelled context (the deadline of the invocation)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := getExecutionOutput(ctx, "some-pipeline", "12345", mockPipeline); err == nil {
+		t.Fatal("error should have occurred")
+	}
 }
 
 // TestGetArtifact will test getting an artifact from an execution output
@@ -271,22 +310,22 @@ func TestGetArtifact(t *testing.T) {
 	mockPipeline := &mockCodePipelineClient{}
 
 	// Test a valid pipeline response
-	response, err := getExecutionOutput("some-pipeline", "12345", mockPipeline)
+	response, err := getExecutionOutput(context.Background(), "some-pipeline", "12345", mockPipeline)
 	if err != nil {
 		t.Fatal("error should not have occurred", err.Error())
 	} else if response == nil {
 		t.Fatal("response is nil and was expected to be a pointer")
 	}
 
-	artifact := getArtifact(response)
+	artifact := getArtifact(response, sourceArtifactName)
 	if artifact == nil {
 		t.Fatal("artifact was nil, expected a pointer")
 	}
 
 	// Test an invalid artifact name
-	response, err = getExecutionOutput("bad-artifact-name", "12345", mockPipeline)
+	response, err = getExecutionOutput(context.Background(), "bad-artifact-name", "12345", mockPipeline)
 
-	artifact = getArtifact(response)
+	artifact = getArtifact(response, sourceArtifactName)
 	if artifact != nil {
 		t.Fatal("artifact was not nil, expected artifact to be nil")
 	}
@@ -300,7 +339,7 @@ func TestGetCommit(t *testing.T) {
 	mockPipeline := &mockCodePipelineClient{}
 
 	// Test a valid pipeline response
-	response, err := getExecutionOutput("some-pipeline", "12345", mockPipeline)
+	response, err := getExecutionOutput(context.Background(), "some-pipeline", "12345", mockPipeline)
 	if err != nil {
 		t.Fatal("error should not have occurred", err.Error())
 	} else if response == nil {
@@ -308,7 +347,7 @@ func TestGetCommit(t *testing.T) {
 	}
 
 	// Valid commit artifact
-	commit, status, revisionURL, commitErr := getCommit("some-pipeline", "12345", mockPipeline)
+	commit, status, revisionURL, commitErr := getCommit(context.Background(), "some-pipeline", "12345", sourceArtifactName, nil, nil, mockPipeline)
 	if commitErr != nil {
 		t.Fatal("error occurred in getCommit", commitErr.Error())
 	} else if commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
@@ -322,7 +361,7 @@ func TestGetCommit(t *testing.T) {
 	}
 
 	// Invalid commit url
-	_, _, revisionURL, commitErr = getCommit("bad-artifact-url", "12345", mockPipeline)
+	_, _, revisionURL, commitErr = getCommit(context.Background(), "bad-artifact-url", "12345", sourceArtifactName, nil, nil, mockPipeline)
 	if revisionURL != nil {
 		t.Fatal("revisionURL should have been nil")
 	} else if commitErr != nil {
@@ -330,28 +369,36 @@ func TestGetCommit(t *testing.T) {
 	}
 }
 
-// TestDecryptString will test decryptString()
+// TestDecryptString will test containerCache.decryptString()
 func TestDecryptString(t *testing.T) {
 	t.Parallel()
 
 	mockKms := &mockKmsClient{}
+	cache := newContainerCache()
 
 	// Valid decryption
-	decrypted, err := decryptString(mockKms, "dGhpcyBpcyBzYW5mb3VuZHJ5IGxpbnV4IHR1dG9yaWFsCg==")
+	decrypted, err := cache.decryptString(context.Background(), mockKms, "dGhpcyBpcyBzYW5mb3VuZHJ5IGxpbnV4IHR1dG9yaWFsCg==")
 	if err != nil {
 		t.Fatal("error occurred", err.Error())
 	} else if decrypted != "some-encrypted-text" {
 		t.Fatal("value expected was wrong", decrypted)
 	}
 
+	// Decrypted once per container
+	if decrypted, err = cache.decryptString(context.Background(), nil, "dGhpcyBpcyBzYW5mb3VuZHJ5IGxpbnV4IHR1dG9yaWFsCg=="); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if decrypted != "some-encrypted-text" {
+		t.Fatal("value expected was wrong", decrypted)
+	}
+
 	// Invalid base64
-	_, err = decryptString(mockKms, "invalid-base-64")
+	_, err = cache.decryptString(context.Background(), mockKms, "invalid-base-64")
 	if err == nil {
 		t.Fatal("error should have occurred")
 	}
 
 	// Invalid value
-	_, err = decryptString(mockKms, "")
+	_, err = cache.decryptString(context.Background(), mockKms, "")
 	if err == nil {
 		t.Fatal("error should have occurred")
 	}
@@ -364,34 +411,34 @@ func TestLoadConfiguration(t *testing.T) {
 	os.Clearenv()
 
 	// Invalid - missing region
-	err := loadConfiguration(mockKms)
+	_, err := loadConfiguration(context.Background(), newContainerCache(), mockKms, nil)
 	if err == nil {
 		t.Fatal("error should have occurred")
 	} else if err.Error() != "required key AWS_REGION missing value" {
 		t.Error("error returned was not as expected", err.Error())
 	}
 
-	// Invalid - missing github token
+	// Invalid - missing application stage
 	_ = os.Setenv("AWS_REGION", "us-east-1")
-	err = loadConfiguration(mockKms)
+	_, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil)
 	if err == nil {
 		t.Fatal("error should have occurred")
-	} else if err.Error() != "required key GITHUB_ACCESS_TOKEN missing value" {
+	} else if err.Error() != "required key APPLICATION_STAGE_NAME missing value" {
 		t.Error("error returned was not as expected", err.Error())
 	}
 
-	// Invalid - missing application stage
-	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
-	err = loadConfiguration(mockKms)
+	// Invalid - missing github token
+	_ = os.Setenv("APPLICATION_STAGE_NAME", "development")
+	_, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil)
 	if err == nil {
 		t.Fatal("error should have occurred")
-	} else if err.Error() != "required key APPLICATION_STAGE_NAME missing value" {
+	} else if err.Error() != "required key GITHUB_ACCESS_TOKEN, GITHUB_TOKEN_SECRET_ARN or GITHUB_APP_SECRET_ARN missing value" {
 		t.Error("error returned was not as expected", err.Error())
 	}
 
 	// Invalid - token is not base64
-	_ = os.Setenv("APPLICATION_STAGE_NAME", "development")
-	err = loadConfiguration(mockKms)
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil)
 	if err == nil {
 		t.Fatal("error should have occurred")
 	} else if err.Error() != "illegal base64 data at input byte 4" {
@@ -400,12 +447,70 @@ func TestLoadConfiguration(t *testing.T) {
 
 	// Valid base64 value
 	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "dGVzdC10b2tlbi12YWx1ZQ==")
-	err = loadConfiguration(mockKms)
+	var cfg Config
+	cfg, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil)
 	if err != nil {
 		t.Fatal("error occurred", err.Error())
-	} else if len(config.GithubAccessToken) == 0 {
+	} else if len(cfg.GithubAccessToken) == 0 {
 		t.Fatal("missing token value")
-	} else if config.GithubAccessToken != "some-encrypted-text" {
-		t.Fatal("invalid token value", config.GithubAccessToken)
+	} else if cfg.GithubAccessToken != "some-encrypted-text" {
+		t.Fatal("invalid token value", cfg.GithubAccessToken)
+	}
+
+	// Encrypted Slack webhook (plain webhook urls are kept)
+	_ = os.Setenv("SLACK_WEBHOOK_URL", "dGVzdC13ZWJob29r")
+	if cfg, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if cfg.SlackWebhookURL != "some-encrypted-text" {
+		t.Fatal("invalid webhook value", cfg.SlackWebhookURL)
+	}
+	_ = os.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T000/B000/XXXX")
+	if cfg, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if cfg.SlackWebhookURL != "https://hooks.slack.com/services/T000/B000/XXXX" {
+		t.Fatal("invalid webhook value", cfg.SlackWebhookURL)
+	}
+
+	// Token from Secrets Manager (not decrypted)
+	_ = os.Setenv("GITHUB_TOKEN_SECRET_ARN", "arn:aws:secretsmanager:us-east-1:123456789012:secret:load-configuration")
+	if _, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, nil); err == nil {
+		t.Fatal("error should have occurred")
+	}
+	if cfg, err = loadConfiguration(context.Background(), newContainerCache(), mockKms, &mockSecretsManagerClient{secret: "ghp_from-secret"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if cfg.GithubAccessToken != "ghp_from-secret" {
+		t.Fatal("invalid token value", cfg.GithubAccessToken)
+	}
+}
+
+// TestJoinDescription will test joinDescription()
+func TestJoinDescription(t *testing.T) {
+	t.Parallel()
+
+	if description := joinDescription("", "first", "", "second"); description != "first; second" {
+		t.Fatal("description was not as expected", description)
+	} else if description = joinDescription(); description != "" {
+		t.Fatal("description should be empty", description)
+	} else if description = joinDescription(strings.Repeat("a", 200)); len(description) != githubDescriptionLimit {
+		t.Fatal("description was not truncated", len(description))
+	} else if description = joinDescription(strings.Repeat("é", 100)); !utf8.ValidString(description) ||
+		len(description) > githubDescriptionLimit || !strings.HasSuffix(description, "é...") {
+		t.Fatal("description was not truncated on a character boundary", description)
+	}
+}
+
+// TestStringMapDecode will test stringMap.Decode()
+func TestStringMapDecode(t *testing.T) {
+	t.Parallel()
+
+	var h stringMap
+	if err := h.Decode(`{"jane":"@jane-doe","arn:aws:iam::123:user/john":"johnny"}`); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if h["arn:aws:iam::123:user/john"] != "johnny" {
+		t.Fatal("handle was not as expected", h)
+	}
+
+	if err := h.Decode("jane:@jane-doe"); err == nil {
+		t.Fatal("error should have occurred")
 	}
 }
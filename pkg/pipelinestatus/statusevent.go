@@ -0,0 +1,50 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// statusEventVersion is the schema version of the status event (bumped on breaking changes)
+const statusEventVersion = "1"
+
+// statusEvent is the normalized status of a pipeline execution published to STATUS_TOPIC_ARN after the
+// GitHub update, so subscribers do not have to parse the CodePipeline events themselves
+type statusEvent struct {
+	Commit         string    `json:"commit"`
+	Context        string    `json:"context"`
+	Description    string    `json:"description"`
+	EventTime      time.Time `json:"event_time"` // time of the pipeline event
+	ExecutionID    string    `json:"execution_id"`
+	ExecutionState string    `json:"execution_state"` // IE: SUCCEEDED
+	Owner          string    `json:"owner"`
+	Pipeline       string    `json:"pipeline"`
+	Repo           string    `json:"repo"`
+	SchemaVersion  string    `json:"schema_version"`
+	State          string    `json:"state"` // GitHub state (IE: success)
+	TargetURL      string    `json:"target_url"`
+	UpdatedTime    time.Time `json:"updated_time"` // time of the GitHub update
+}
+
+// publishStatusEvent will send the status event to the SNS topic (pipeline and state are message
+// attributes for subscription filter policies)
+func (h *Handler) publishStatusEvent(ctx context.Context, event statusEvent) error {
+	event.SchemaVersion = statusEventVersion
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = h.deps.SNS.PublishWithContext(ctx, &sns.PublishInput{
+		Message: aws.String(string(b)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"pipeline": {DataType: aws.String("String"), StringValue: aws.String(event.Pipeline)},
+			"state":    {DataType: aws.String("String"), StringValue: aws.String(event.State)},
+		},
+		TopicArn: aws.String(h.cfg.StatusTopicARN),
+	})
+	return err
+}
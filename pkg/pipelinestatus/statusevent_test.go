@@ -0,0 +1,67 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestPublishStatusEvent will test publishStatusEvent()
+func TestPublishStatusEvent(t *testing.T) {
+	mockTopic := &mockSNSClient{}
+	h := newTestHandler(Config{StatusTopicARN: "arn:aws:sns:us-east-1:123456789012:status"})
+	h.deps.SNS = mockTopic
+
+	if err := h.publishStatusEvent(context.Background(), statusEvent{Pipeline: "web", State: githubStateSuccess}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(mockTopic.messages) != 1 {
+		t.Fatal("message was not published", mockTopic.messages)
+	}
+	var event statusEvent
+	if err := json.Unmarshal([]byte(mockTopic.messages[0]), &event); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if event.SchemaVersion != statusEventVersion || event.Pipeline != "web" {
+		t.Fatal("event was not as expected", event)
+	}
+
+	// Missing topic
+	h.cfg.StatusTopicARN = ""
+	if err := h.publishStatusEvent(context.Background(), statusEvent{Pipeline: "web"}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventStatusEvent will test ProcessEvent() publishing the status event after the GitHub update
+func TestHandlerProcessEventStatusEvent(t *testing.T) {
+	mockTopic := &mockSNSClient{}
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		Stage:                stageTesting,
+		StatusTopicARN:       "arn:aws:sns:us-east-1:123456789012:status",
+	})
+	h.deps.SNS = mockTopic
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	eventTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"},
+		Time: eventTime}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(mockTopic.messages) != 1 {
+		t.Fatal("message was not published", mockTopic.messages)
+	}
+
+	var published statusEvent
+	if err := json.Unmarshal([]byte(mockTopic.messages[0]), &published); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if published.Commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" || published.ExecutionID != "12345678" ||
+		published.State != githubStateSuccess || published.ExecutionState != "SUCCEEDED" || published.Context != defaultStatusContext {
+		t.Fatal("event was not as expected", published)
+	} else if !published.EventTime.Equal(eventTime) || published.UpdatedTime.IsZero() {
+		t.Fatal("timestamps were not as expected", published)
+	}
+}
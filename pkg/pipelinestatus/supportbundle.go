@@ -0,0 +1,265 @@
+package pipelinestatus
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// supportBundleServices are the AWS services the support bundle is collected from
+type supportBundleServices struct {
+	DynamoDB dynamodbiface.DynamoDBAPI
+	IAM      iamiface.IAMAPI
+	Lambda   lambdaiface.LambdaAPI
+}
+
+// supportBundleFile is a file of the support bundle (collection errors are noted in the manifest)
+type supportBundleFile struct {
+	Error string `json:"error,omitempty"`
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+}
+
+// supportBundleVersion is the version of the command and of the deployed function
+type supportBundleVersion struct {
+	Deployed  string `json:"deployed,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Version   string `json:"version"`
+}
+
+// simulationResult is the decision of the IAM policy simulator for an action of the required policy
+type simulationResult struct {
+	Action   string `json:"action"`
+	Decision string `json:"decision"`
+	Resource string `json:"resource"`
+	Sid      string `json:"sid"`
+}
+
+// auditRecords will return the most recent GitHub writes of the audit table (SHADOW_AUDIT_TABLE), newest first
+func auditRecords(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, limit int) (records []map[string]string, err error) {
+	var items []map[string]*dynamodb.AttributeValue
+	if err = dynamoSvc.ScanPagesWithContext(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	}); err != nil {
+		return
+	}
+
+	// The ids start with the time of the write
+	sort.Slice(items, func(i, j int) bool {
+		return aws.StringValue(items[i]["id"].S) > aws.StringValue(items[j]["id"].S)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	records = []map[string]string{}
+	for _, item := range items {
+		record := make(map[string]string, len(item))
+		for name, value := range item {
+			record[name] = aws.StringValue(value.S)
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+// deployedInfo will return the info of the deployed function (its configuration, error counts and recent errors)
+func deployedInfo(ctx context.Context, lambdaSvc lambdaiface.LambdaAPI, functionName string) (info deploymentInfo, err error) {
+	var output *lambda.InvokeOutput
+	if output, err = lambdaSvc.InvokeWithContext(ctx, &lambda.InvokeInput{
+		FunctionName: aws.String(functionName),
+		Payload:      []byte(`{"action":"` + actionInfo + `"}`),
+	}); err != nil {
+		return
+	} else if len(aws.StringValue(output.FunctionError)) > 0 {
+		err = fmt.Errorf("function error: %s", string(output.Payload))
+		return
+	}
+	err = json.Unmarshal(output.Payload, &info)
+	return
+}
+
+// simulatePolicy will simulate the actions of the required policy with the policies of the role of the function
+func simulatePolicy(ctx context.Context, iamSvc iamiface.IAMAPI, roleARN string, policy policyDocument) (results []simulationResult, err error) {
+	results = []simulationResult{}
+	for _, statement := range policy.Statement {
+		if err = iamSvc.SimulatePrincipalPolicyPagesWithContext(ctx, &iam.SimulatePrincipalPolicyInput{
+			ActionNames:     aws.StringSlice(statement.Action),
+			PolicySourceArn: aws.String(roleARN),
+			ResourceArns:    aws.StringSlice(statement.Resource),
+		}, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+			for _, evaluation := range page.EvaluationResults {
+				results = append(results, simulationResult{
+					Action:   aws.StringValue(evaluation.EvalActionName),
+					Decision: aws.StringValue(evaluation.EvalDecision),
+					Resource: aws.StringValue(evaluation.EvalResourceName),
+					Sid:      statement.Sid,
+				})
+			}
+			return true
+		}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// writeSupportBundle will write the files (JSON) to a gzipped tar archive, in the order of the manifest
+func writeSupportBundle(w io.Writer, manifest []supportBundleFile, contents map[string][]byte, now time.Time) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	for _, file := range manifest {
+		if err := tw.WriteHeader(&tar.Header{
+			ModTime: now, Mode: 0644, Name: file.Name, Size: int64(len(contents[file.Name])), Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		} else if _, err = tw.Write(contents[file.Name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// supportBundleCommand will collect the redacted configuration, the recent audit records, the recent errors and
+// the version of the deployed function and the IAM simulation of its role into one archive to attach to an issue
+// (IE: status support-bundle -function codepipeline-to-github -file bundle.tar.gz)
+func supportBundleCommand(args []string, out io.Writer, services supportBundleServices) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandSupportBundle, flag.ContinueOnError)
+	auditLimit := flags.Int("audit-records", 50, "number of recent audit records (SHADOW_AUDIT_TABLE)")
+	file := flags.String("file", "support-bundle.tar.gz", "path of the archive")
+	functionName := flags.String("function", "", "name of the deployed function (its info and role)")
+	output := outputFlag(flags, outputTable)
+	roleARN := flags.String("role", "", "role to simulate the required policy with (the role of -function if empty)")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if *auditLimit < 0 {
+		return errors.New("flag -audit-records cannot be negative")
+	}
+
+	// Load the configuration
+	var cfg Config
+	if _, err = processConfiguration(&cfg, nil); err != nil {
+		return
+	}
+
+	// Collect the files, a part that cannot be collected is noted in the manifest
+	ctx := context.Background()
+	var manifest []supportBundleFile
+	contents := make(map[string][]byte)
+	add := func(name string, collect func() (interface{}, error)) {
+		entry := supportBundleFile{Name: name}
+		v, collectErr := collect()
+		if collectErr != nil {
+			entry.Error = collectErr.Error()
+			v = map[string]string{"error": entry.Error}
+		}
+		contents[name], _ = json.MarshalIndent(v, "", "  ")
+		entry.Size = len(contents[name])
+		manifest = append(manifest, entry)
+	}
+	var deployed *deploymentInfo
+	add("config.json", func() (interface{}, error) {
+		return configSummary(cfg), nil
+	})
+	add("deployment.json", func() (interface{}, error) {
+		if len(*functionName) == 0 {
+			return nil, errors.New("missing flag -function")
+		}
+		info, infoErr := deployedInfo(ctx, services.Lambda, *functionName)
+		if infoErr != nil {
+			return nil, infoErr
+		}
+		deployed = &info
+		return info, nil
+	})
+	add("errors.json", func() (interface{}, error) {
+		if deployed == nil {
+			return nil, errors.New("the info of the deployed function is not available")
+		}
+		return map[string]interface{}{
+			"counts": deployed.ErrorCounts, "recent": deployed.RecentErrors, "since": deployed.ErrorsSince,
+		}, nil
+	})
+	add("audit.json", func() (interface{}, error) {
+		if len(cfg.ShadowAuditTable) == 0 {
+			return nil, errors.New("missing SHADOW_AUDIT_TABLE, GitHub writes are not being recorded")
+		}
+		return auditRecords(ctx, services.DynamoDB, cfg.ShadowAuditTable, *auditLimit)
+	})
+	add("permissions.json", func() (interface{}, error) {
+		role := *roleARN
+		if len(role) == 0 && len(*functionName) > 0 {
+			function, getErr := services.Lambda.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+				FunctionName: aws.String(*functionName),
+			})
+			if getErr != nil {
+				return nil, getErr
+			}
+			role = aws.StringValue(function.Role)
+		}
+		if len(role) == 0 {
+			return nil, errors.New("missing flag -role or -function")
+		}
+		return simulatePolicy(ctx, services.IAM, role, requiredPolicy(cfg))
+	})
+	add("version.json", func() (interface{}, error) {
+		v := supportBundleVersion{GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH, Version: version}
+		if deployed != nil {
+			v.Deployed = deployed.Version
+		}
+		return v, nil
+	})
+	add("manifest.json", func() (interface{}, error) {
+		return manifest, nil
+	})
+
+	// Write the archive
+	var path string
+	if path, err = expandPath(*file); err != nil {
+		return
+	}
+	var f *os.File
+	if f, err = os.Create(path); err != nil {
+		return
+	}
+	if err = writeSupportBundle(f, manifest, contents, time.Now()); err != nil {
+		_ = f.Close()
+		return
+	} else if err = f.Close(); err != nil {
+		return
+	}
+
+	// Write the manifest
+	return writeOutput(out, *output, manifest, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "%s\n", path)
+		_, _ = fmt.Fprintln(w, "FILE\tSIZE\tERROR")
+		for _, entry := range manifest {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", entry.Name, entry.Size, entry.Error)
+		}
+	})
+}
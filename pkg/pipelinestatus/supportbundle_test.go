@@ -0,0 +1,193 @@
+package pipelinestatus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// mockScanDynamoClient returns the audit records of the table
+type mockScanDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items []map[string]*dynamodb.AttributeValue
+}
+
+// ScanPagesWithContext is a mock request for dynamodb (a page per item)
+func (m *mockScanDynamoClient) ScanPagesWithContext(_ aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool,
+	_ ...request.Option) error {
+	for i, item := range m.items {
+		if !fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}}, i == len(m.items)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// mockIAMClient allows every action except the ones of the denied role
+type mockIAMClient struct {
+	iamiface.IAMAPI
+}
+
+// SimulatePrincipalPolicyPagesWithContext is a mock request for iam
+func (m *mockIAMClient) SimulatePrincipalPolicyPagesWithContext(_ aws.Context, input *iam.SimulatePrincipalPolicyInput,
+	fn func(*iam.SimulatePolicyResponse, bool) bool, _ ...request.Option) error {
+	decision := iam.PolicyEvaluationDecisionTypeAllowed
+	if aws.StringValue(input.PolicySourceArn) == "arn:aws:iam::123456789012:role/denied" {
+		decision = iam.PolicyEvaluationDecisionTypeImplicitDeny
+	}
+	var page iam.SimulatePolicyResponse
+	for _, action := range input.ActionNames {
+		page.EvaluationResults = append(page.EvaluationResults, &iam.EvaluationResult{
+			EvalActionName: action, EvalDecision: aws.String(decision), EvalResourceName: aws.String("*"),
+		})
+	}
+	fn(&page, true)
+	return nil
+}
+
+// mockLambdaClient returns the info of the deployed function (unknown functions fail)
+type mockLambdaClient struct {
+	lambdaiface.LambdaAPI
+}
+
+// InvokeWithContext is a mock request for lambda
+func (m *mockLambdaClient) InvokeWithContext(_ aws.Context, input *lambda.InvokeInput, _ ...request.Option) (*lambda.InvokeOutput, error) {
+	if aws.StringValue(input.FunctionName) != "codepipeline-to-github" {
+		return nil, errors.New("function not found")
+	}
+	return &lambda.InvokeOutput{Payload: []byte(`{"version":"v1.2.0","error_counts":{"github":2},` +
+		`"recent_errors":[{"kind":"github","message":"unexpected response from GitHub, code: 502"}]}`)}, nil
+}
+
+// GetFunctionConfigurationWithContext is a mock request for lambda
+func (m *mockLambdaClient) GetFunctionConfigurationWithContext(_ aws.Context, input *lambda.GetFunctionConfigurationInput,
+	_ ...request.Option) (*lambda.FunctionConfiguration, error) {
+	return &lambda.FunctionConfiguration{Role: aws.String("arn:aws:iam::123456789012:role/codepipeline-to-github")}, nil
+}
+
+// readSupportBundle will return the files of a support bundle
+func readSupportBundle(t *testing.T, path string) map[string][]byte {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		header, nextErr := tr.Next()
+		if nextErr != nil {
+			break
+		}
+		files[header.Name], _ = ioutil.ReadAll(tr)
+	}
+	return files
+}
+
+// TestAuditRecords will test auditRecords() returning the newest records first
+func TestAuditRecords(t *testing.T) {
+	t.Parallel()
+
+	svc := &mockScanDynamoClient{}
+	for _, id := range []string{"1000 POST /a", "3000 POST /c", "2000 POST /b"} {
+		svc.items = append(svc.items, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+	}
+	records, err := auditRecords(context.Background(), svc, "audit", 2)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(records) != 2 || records[0]["id"] != "3000 POST /c" || records[1]["id"] != "2000 POST /b" {
+		t.Fatal("records were not as expected", records)
+	}
+}
+
+// TestSimulatePolicy will test simulatePolicy()
+func TestSimulatePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := policyDocument{Statement: []policyStatement{{Action: []string{"dynamodb:PutItem"}, Resource: []string{"*"}, Sid: "RateLimits"}}}
+	if results, err := simulatePolicy(context.Background(), &mockIAMClient{}, "arn:aws:iam::123456789012:role/denied", policy); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(results) != 1 || results[0].Decision != iam.PolicyEvaluationDecisionTypeImplicitDeny || results[0].Sid != "RateLimits" {
+		t.Fatal("results were not as expected", results)
+	}
+}
+
+// TestSupportBundleCommand will test supportBundleCommand()
+func TestSupportBundleCommand(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", "production")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("SHADOW_AUDIT_TABLE", "audit")
+	defer os.Clearenv()
+
+	services := supportBundleServices{
+		DynamoDB: &mockScanDynamoClient{items: []map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("1000 POST /a")}}}},
+		IAM:      &mockIAMClient{},
+		Lambda:   &mockLambdaClient{},
+	}
+	file := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	var out bytes.Buffer
+	if err := supportBundleCommand([]string{"-file", file, "-function", "codepipeline-to-github", "-output", outputJSON},
+		&out, services); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	var manifest []supportBundleFile
+	if err := json.Unmarshal(out.Bytes(), &manifest); err != nil {
+		t.Fatal("output was not valid json", err.Error())
+	}
+	for _, entry := range manifest {
+		if len(entry.Error) > 0 {
+			t.Fatal("file should have been collected", entry)
+		}
+	}
+
+	files := readSupportBundle(t, file)
+	if len(files) != len(manifest) {
+		t.Fatal("files were not as expected", len(files))
+	} else if bytes.Contains(files["config.json"], []byte("1234567")) {
+		t.Fatal("token should have been redacted", string(files["config.json"]))
+	} else if !bytes.Contains(files["errors.json"], []byte("code: 502")) {
+		t.Fatal("recent errors were not as expected", string(files["errors.json"]))
+	} else if !bytes.Contains(files["audit.json"], []byte("1000 POST /a")) {
+		t.Fatal("audit records were not as expected", string(files["audit.json"]))
+	} else if !bytes.Contains(files["version.json"], []byte(`"deployed": "v1.2.0"`)) {
+		t.Fatal("version was not as expected", string(files["version.json"]))
+	} else if !bytes.Contains(files["permissions.json"], []byte(iam.PolicyEvaluationDecisionTypeAllowed)) {
+		t.Fatal("permissions were not as expected", string(files["permissions.json"]))
+	}
+
+	// Parts that cannot be collected are noted in the manifest
+	out.Reset()
+	if err := supportBundleCommand([]string{"-file", file, "-function", "unknown", "-output", outputJSON},
+		&out, services); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = json.Unmarshal(out.Bytes(), &manifest); err != nil {
+		t.Fatal("output was not valid json", err.Error())
+	} else if manifest[1].Name != "deployment.json" || manifest[1].Error != "function not found" {
+		t.Fatal("manifest was not as expected", manifest)
+	}
+}
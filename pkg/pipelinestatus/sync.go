@@ -0,0 +1,181 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Results of the synced executions
+const (
+	syncResultFailed = "failed"
+	syncResultReady  = "ready"
+	syncResultSynced = "synced"
+)
+
+// executionEventStates are the event states of the execution statuses (IE: the status of a listed execution)
+var executionEventStates = map[types.PipelineExecutionStatus]string{
+	types.PipelineExecutionStatusCancelled:  "CANCELED",
+	types.PipelineExecutionStatusFailed:     "FAILED",
+	types.PipelineExecutionStatusInProgress: "STARTED",
+	types.PipelineExecutionStatusStopped:    "STOPPED",
+	types.PipelineExecutionStatusStopping:   "STOPPING",
+	types.PipelineExecutionStatusSucceeded:  "SUCCEEDED",
+	types.PipelineExecutionStatusSuperseded: "SUPERSEDED",
+}
+
+// syncReport is the result of syncing the statuses of the executions of a pipeline
+type syncReport struct {
+	Executions []syncedExecution `json:"executions"`
+	Pipeline   string            `json:"pipeline"`
+}
+
+// syncedExecution is the result of one execution
+type syncedExecution struct {
+	Error       string     `json:"error,omitempty"`
+	ExecutionID string     `json:"execution_id"`
+	Result      string     `json:"result"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	State       string     `json:"state"`
+}
+
+// syncExecution will post the status of an execution again as the event of its current status
+// (nothing is posted on a dry run)
+func (h *Handler) syncExecution(ctx context.Context, pipelineName, executionID string, status types.PipelineExecutionStatus,
+	dryRun bool) (result syncedExecution) {
+	result = syncedExecution{ExecutionID: executionID, State: executionEventStates[status]}
+	if len(result.State) == 0 {
+		result.Result, result.Error = syncResultFailed, "unknown execution status: "+string(status)
+		return
+	} else if dryRun {
+		result.Result = syncResultReady
+		return
+	}
+	if err := h.ProcessEventWithContext(ctx, Event{Detail: &Detail{
+		ExecutionID: executionID,
+		Pipeline:    pipelineName,
+		State:       result.State,
+	}}); err != nil {
+		result.Result, result.Error = syncResultFailed, err.Error()
+		return
+	}
+	result.Result = syncResultSynced
+	return
+}
+
+// syncStatus will post the status of an execution of the pipeline again
+func (h *Handler) syncStatus(ctx context.Context, pipelineName, executionID string,
+	dryRun bool) (report syncReport, err error) {
+	var executionOutput *codepipeline.GetPipelineExecutionOutput
+	if executionOutput, err = getExecutionOutput(ctx, pipelineName, executionID, h.deps.CodePipeline); err != nil {
+		return
+	}
+	report = syncReport{Pipeline: pipelineName, Executions: []syncedExecution{
+		h.syncExecution(ctx, pipelineName, executionID, executionOutput.PipelineExecution.Status, dryRun),
+	}}
+	return
+}
+
+// backfillStatuses will post the statuses of the executions of the pipeline started since the time again, oldest first
+// so the latest execution of a commit wins (the statuses already reported are skipped with DEDUP_TABLE)
+func (h *Handler) backfillStatuses(ctx context.Context, pipelineName string, since time.Time,
+	dryRun bool) (report syncReport, err error) {
+	var summaries []types.PipelineExecutionSummary
+	paginator := codepipeline.NewListPipelineExecutionsPaginator(h.deps.CodePipeline,
+		&codepipeline.ListPipelineExecutionsInput{PipelineName: aws.String(pipelineName)})
+pages:
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListPipelineExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, summary := range page.PipelineExecutionSummaries {
+			if aws.TimeValue(summary.StartTime).Before(since) {
+				break pages
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+
+	report = syncReport{Pipeline: pipelineName, Executions: []syncedExecution{}}
+	for i := len(summaries) - 1; i >= 0; i-- {
+		result := h.syncExecution(ctx, pipelineName, aws.StringValue(summaries[i].PipelineExecutionId),
+			summaries[i].Status, dryRun)
+		result.StartTime = summaries[i].StartTime
+		report.Executions = append(report.Executions, result)
+	}
+	return
+}
+
+// writeSyncReport will write the result of the synced executions
+func writeSyncReport(out io.Writer, format string, report syncReport) error {
+	return writeOutput(out, format, report, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "PIPELINE\tEXECUTION\tSTATE\tRESULT\tERROR")
+		for _, execution := range report.Executions {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", report.Pipeline, execution.ExecutionID, execution.State,
+				execution.Result, execution.Error)
+		}
+	})
+}
+
+// syncCommand will post the status of an execution again (IE: statusctl sync -pipeline X -execution-id Y)
+func syncCommand(args []string, out io.Writer, h *Handler) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandSync, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report without posting the status")
+	executionID := flags.String("execution-id", "", "id of the execution")
+	output := outputFlag(flags, outputTable)
+	pipelineName := flags.String("pipeline", "", "name of the pipeline")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*pipelineName) == 0 {
+		return errors.New("missing flag -pipeline")
+	} else if len(*executionID) == 0 {
+		return errors.New("missing flag -execution-id")
+	}
+
+	// Sync the status
+	var report syncReport
+	if report, err = h.syncStatus(context.Background(), *pipelineName, *executionID, *dryRun); err != nil {
+		return
+	} else if err = writeSyncReport(out, *output, report); err != nil {
+		return
+	} else if report.Executions[0].Result == syncResultFailed {
+		return fmt.Errorf("unable to sync execution %s: %s", *executionID, report.Executions[0].Error)
+	}
+	return
+}
+
+// backfillCommand will post the statuses of the recent executions of a pipeline again after an outage
+// (IE: statusctl backfill -pipeline X -since 24h)
+func backfillCommand(args []string, out io.Writer, h *Handler) (err error) {
+
+	// Parse the flags
+	flags := flag.NewFlagSet(commandBackfill, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "list the executions without posting their statuses")
+	output := outputFlag(flags, outputTable)
+	pipelineName := flags.String("pipeline", "", "name of the pipeline")
+	since := flags.Duration("since", 24*time.Hour, "how far back to backfill the executions (IE: 24h)")
+	if err = flags.Parse(args); err != nil {
+		return
+	} else if len(*pipelineName) == 0 {
+		return errors.New("missing flag -pipeline")
+	} else if *since <= 0 {
+		return errors.New("flag -since must be positive")
+	}
+
+	// Backfill the statuses
+	var report syncReport
+	if report, err = h.backfillStatuses(context.Background(), *pipelineName, time.Now().Add(-*since), *dryRun); err != nil {
+		return
+	}
+	return writeSyncReport(out, *output, report)
+}
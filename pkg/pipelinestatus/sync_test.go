@@ -0,0 +1,120 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockBackfillPipelineClient is a pipeline with executions of the last hours and an older one
+type mockBackfillPipelineClient struct {
+	mockCodePipelineClient
+}
+
+// ListPipelineExecutions is a mock request for codepipeline (newest first)
+func (m *mockBackfillPipelineClient) ListPipelineExecutions(_ context.Context, input *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	now := time.Now()
+	if input.NextToken != nil {
+		return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+			{PipelineExecutionId: aws.String("stale"), StartTime: aws.Time(now.Add(-48 * time.Hour)),
+				Status: types.PipelineExecutionStatusSucceeded},
+		}}, nil
+	}
+	return &codepipeline.ListPipelineExecutionsOutput{NextToken: aws.String("2"), PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{PipelineExecutionId: aws.String("recent"), StartTime: aws.Time(now.Add(-time.Hour)),
+			Status: types.PipelineExecutionStatusSucceeded},
+		{PipelineExecutionId: aws.String("older"), StartTime: aws.Time(now.Add(-2 * time.Hour)),
+			Status: types.PipelineExecutionStatusFailed},
+	}}, nil
+}
+
+// TestHandlerSyncStatus will test Handler.syncStatus() posting the status of an execution again
+func TestHandlerSyncStatus(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+
+	var received []payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		var status payload
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		received = append(received, status)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	report, err := h.syncStatus(context.Background(), "status-succeed", "12345678", false)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(report.Executions) != 1 || report.Executions[0].State != "SUCCEEDED" || report.Executions[0].Result != syncResultSynced {
+		t.Fatal("report was not as expected", report)
+	} else if len(received) != 1 || received[0].State != githubStateSuccess {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Nothing is posted on a dry run
+	received = nil
+	if report, err = h.syncStatus(context.Background(), "status-succeed", "12345678", true); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if report.Executions[0].Result != syncResultReady || len(received) != 0 {
+		t.Fatal("dry run should not post", report, received)
+	}
+}
+
+// TestHandlerBackfillStatuses will test Handler.backfillStatuses() posting the recent executions oldest first
+func TestHandlerBackfillStatuses(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	h.deps.CodePipeline = &mockBackfillPipelineClient{}
+
+	var posts int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	report, err := h.backfillStatuses(context.Background(), "some-pipeline", time.Now().Add(-24*time.Hour), false)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(report.Executions) != 2 || report.Executions[0].ExecutionID != "older" || report.Executions[1].ExecutionID != "recent" {
+		t.Fatal("executions were not as expected", report.Executions)
+	} else if report.Executions[0].State != "FAILED" || report.Executions[1].Result != syncResultSynced || posts != 2 {
+		t.Fatal("results were not as expected", report.Executions, posts)
+	}
+}
+
+// TestSyncCommands will test syncCommand() and backfillCommand()
+func TestSyncCommands(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+	h.deps.CodePipeline = &mockBackfillPipelineClient{}
+
+	var tests = []struct {
+		command       func(args []string, out *bytes.Buffer) error
+		args          []string
+		expectedError string
+	}{
+		{func(args []string, out *bytes.Buffer) error { return syncCommand(args, out, h) }, []string{"--execution-id", "12345678"}, "missing flag -pipeline"},
+		{func(args []string, out *bytes.Buffer) error { return syncCommand(args, out, h) }, []string{"--pipeline", "web"}, "missing flag -execution-id"},
+		{func(args []string, out *bytes.Buffer) error { return backfillCommand(args, out, h) }, []string{}, "missing flag -pipeline"},
+		{func(args []string, out *bytes.Buffer) error { return backfillCommand(args, out, h) }, []string{"--pipeline", "web", "--since", "-1h"}, "flag -since must be positive"},
+	}
+	for _, test := range tests {
+		if err := test.command(test.args, &bytes.Buffer{}); err == nil || err.Error() != test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, expected [%s] but got [%v]", t.Name(), test.args, test.expectedError, err)
+		}
+	}
+
+	// Dry run of the backfill
+	out := &bytes.Buffer{}
+	if err := backfillCommand([]string{"--pipeline", "web", "--since", "24h", "--dry-run"}, out, h); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 ||
+		strings.Join(strings.Fields(lines[1]), " ") != "web older FAILED ready" {
+		t.Fatal("output was not as expected", out.String())
+	}
+}
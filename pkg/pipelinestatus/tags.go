@@ -0,0 +1,61 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Git tags built by release pipelines
+const (
+	tagPagePath  = "/releases/tag/"
+	tagRefPrefix = "refs/tags/"
+)
+
+// executionTag will return the git tag an execution builds: the revision of the source artifact is a tag ref
+// (refs/tags/v1.2.3) or its url is the page of the tag (/owner/repo/releases/tag/v1.2.3), empty for branches
+func executionTag(executionOutput *codepipeline.GetPipelineExecutionOutput, artifactName string) string {
+	sourceArtifact := getArtifact(executionOutput, artifactName)
+	if sourceArtifact == nil {
+		return ""
+	} else if revision := aws.StringValue(sourceArtifact.RevisionId); strings.HasPrefix(revision, tagRefPrefix) {
+		return strings.TrimPrefix(revision, tagRefPrefix)
+	}
+	if revisionURL, err := url.Parse(aws.StringValue(sourceArtifact.RevisionUrl)); err == nil {
+		if i := strings.Index(revisionURL.Path, tagPagePath); i > 0 {
+			return strings.Trim(revisionURL.Path[i+len(tagPagePath):], "/")
+		}
+	}
+	return ""
+}
+
+// getTagCommit will resolve the commit of a tag of the repository the pipeline builds (the configured
+// repository, or the repository of the source action), annotated tags resolve to the commit they point to
+func (h *Handler) getTagCommit(ctx context.Context, pipelineName, tag string) (commit string, revisionURL *url.URL, err error) {
+
+	// Find the repository
+	var owner, repo string
+	if repository := h.pipelines[pipelineName].Repository; len(repository) > 0 {
+		parts := strings.SplitN(repository, "/", 2)
+		owner, repo = parts[0], parts[1]
+	} else if owner, repo, _, err = getSourceBranch(ctx, pipelineName, h.deps.CodePipeline); err != nil {
+		return
+	}
+
+	// Get the commit of the tag
+	var tagged branchCommit
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, url.PathEscape(tag)), &tagged); err != nil {
+		return
+	} else if len(tagged.SHA) == 0 {
+		err = fmt.Errorf("missing commit for tag: %s/%s:%s", owner, repo, tag)
+		return
+	}
+
+	commit = tagged.SHA
+	revisionURL, err = url.Parse(fmt.Sprintf("https://%s/%s/%s/commit/%s", h.githubWebHost(), owner, repo, commit))
+	return
+}
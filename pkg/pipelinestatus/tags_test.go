@@ -0,0 +1,103 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestExecutionTag will test executionTag()
+func TestExecutionTag(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		revisionID  string
+		revisionURL string
+		expected    string
+	}{
+		{"refs/tags/v1.2.3", "", "v1.2.3"},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://github.com/mrz1836/codepipeline-to-github/releases/tag/v2.0.0", "v2.0.0"},
+		{"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08", ""},
+		{"refs/heads/master", "", ""},
+	}
+	for _, test := range tests {
+		executionOutput := &codepipeline.GetPipelineExecutionOutput{PipelineExecution: &types.PipelineExecution{
+			ArtifactRevisions: []types.ArtifactRevision{{
+				Name: aws.String("SourceCode"), RevisionId: aws.String(test.revisionID), RevisionUrl: aws.String(test.revisionURL),
+			}},
+		}}
+		if output := executionTag(executionOutput, "SourceCode"); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%s] expected, received: [%s]", t.Name(), test.revisionID, test.revisionURL, test.expected, output)
+		} else if output = executionTag(executionOutput, "OtherArtifact"); output != "" {
+			t.Errorf("%s Failed: [%s] expected no tag for another artifact, received: [%s]", t.Name(), test.revisionID, output)
+		}
+	}
+}
+
+// TestGetTagCommit will test getTagCommit()
+func TestGetTagCommit(t *testing.T) {
+	h := newTestHandler(Config{})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/mrz1836/codepipeline-to-github/commits/v1.2.3", "/repos/my-org/payments/commits/v1.2.3":
+			_, _ = w.Write([]byte(`{"sha":"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+
+	commit, revisionURL, err := h.getTagCommit(context.Background(), "some-pipeline", "v1.2.3")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if commit != "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("commit was not as expected", commit)
+	} else if revisionURL.String() != "https://github.com/mrz1836/codepipeline-to-github/commit/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("revisionURL was not as expected", revisionURL.String())
+	}
+
+	// Configured repository
+	h.pipelines = pipelineConfigs{"payments": {Repository: "my-org/payments"}}
+	if _, revisionURL, err = h.getTagCommit(context.Background(), "payments", "v1.2.3"); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !strings.HasPrefix(revisionURL.String(), "https://github.com/my-org/payments/commit/") {
+		t.Fatal("revisionURL was not as expected", revisionURL.String())
+	}
+
+	// Unknown tag
+	if _, _, err = h.getTagCommit(context.Background(), "some-pipeline", "v9.9.9"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestHandlerProcessEventTag will test ProcessEvent() posting the status of a tag on its commit
+func TestHandlerProcessEventTag(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting})
+
+	var posted []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"sha":"25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"}`))
+			return
+		}
+		var status struct {
+			Description string `json:"description"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&status)
+		posted = append(posted, r.URL.Path+" "+status.Description)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "release-tag", State: "STARTED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(posted) != 1 || !strings.HasPrefix(posted[0], "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08 ") {
+		t.Fatal("status was not as expected", posted)
+	} else if !strings.Contains(posted[0], "tag v1.2.3") {
+		t.Fatal("description should name the tag", posted[0])
+	}
+}
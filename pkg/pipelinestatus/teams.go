@@ -0,0 +1,136 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Adaptive Card values of a Teams message
+const (
+	teamsCardContentType = "application/vnd.microsoft.card.adaptive"
+	teamsCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	teamsCardVersion     = "1.4"
+)
+
+// teamsColors are the text colors of the GitHub states (Adaptive Card color names)
+var teamsColors = map[string]string{
+	githubStateError:   "Attention",
+	githubStateFailure: "Attention",
+	githubStatePending: "Warning",
+	githubStateSuccess: "Good",
+}
+
+// teamsMessage is the payload of a Teams incoming webhook (a single Adaptive Card)
+type teamsMessage struct {
+	Attachments []teamsAttachment `json:"attachments"`
+	Type        string            `json:"type"`
+}
+
+// teamsAttachment is the attachment holding the card
+type teamsAttachment struct {
+	Content     teamsCard `json:"content"`
+	ContentType string    `json:"contentType"`
+}
+
+// teamsCard is an Adaptive Card
+type teamsCard struct {
+	Actions []teamsAction    `json:"actions,omitempty"`
+	Body    []teamsTextBlock `json:"body"`
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+}
+
+// teamsTextBlock is a text element of a card
+type teamsTextBlock struct {
+	Color  string `json:"color,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Text   string `json:"text"`
+	Type   string `json:"type"`
+	Weight string `json:"weight,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+// teamsAction is a button of a card
+type teamsAction struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+}
+
+// newTeamsMessage will create the Adaptive Card of a notification (the title is colored by the state)
+func newTeamsMessage(message notification) *teamsMessage {
+	card := teamsCard{Schema: teamsCardSchema, Type: "AdaptiveCard", Version: teamsCardVersion}
+	if len(message.Title) > 0 {
+		card.Body = append(card.Body, teamsTextBlock{
+			Color: teamsColors[message.State], Size: "Medium", Text: message.Title, Type: "TextBlock", Weight: "Bolder", Wrap: true,
+		})
+	}
+	card.Body = append(card.Body, teamsTextBlock{Text: message.Text, Type: "TextBlock", Wrap: true})
+	if len(message.URL) > 0 {
+		card.Actions = []teamsAction{{Title: "View execution", Type: "Action.OpenUrl", URL: message.URL}}
+	}
+	return &teamsMessage{
+		Attachments: []teamsAttachment{{Content: card, ContentType: teamsCardContentType}},
+		Type:        "message",
+	}
+}
+
+// teamsNotifier sends messages to the Teams incoming webhook of the pipeline (or the default webhook)
+type teamsNotifier struct {
+	client     HTTPClient
+	webhookURL string
+	webhooks   map[string]string
+}
+
+// Name will return the name of the notifier (used in logs and metrics)
+func (n *teamsNotifier) Name() string {
+	return "teams"
+}
+
+// Notify will send the message to the webhook of the pipeline (skipped if there is none)
+func (n *teamsNotifier) Notify(ctx context.Context, message notification) error {
+	webhookURL := n.webhookURL
+	if pipelineURL, ok := n.webhooks[message.Pipeline]; ok && len(message.Pipeline) > 0 {
+		webhookURL = pipelineURL
+	}
+	if len(webhookURL) == 0 {
+		return nil
+	}
+	return postTeamsMessage(ctx, n.client, webhookURL, newTeamsMessage(message))
+}
+
+// postTeamsMessage will send a message to a Teams incoming webhook
+func postTeamsMessage(ctx context.Context, client HTTPClient, webhookURL string, message *teamsMessage) error {
+
+	// Encode the message
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	// Create the request
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	// Fire the request and check for success (workflow webhooks accept with a 202)
+	var response *http.Response
+	if response, err = client.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusAccepted {
+		resBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected response from Teams, code: %d body: %s", response.StatusCode, string(resBody))
+	}
+	return nil
+}
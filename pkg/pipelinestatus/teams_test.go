@@ -0,0 +1,106 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestNewTeamsMessage will test newTeamsMessage()
+func TestNewTeamsMessage(t *testing.T) {
+	t.Parallel()
+
+	message := newTeamsMessage(notification{State: githubStateFailure, Text: "mrz1836/repo@25c0c3e", Title: "web: failure", URL: "https://console.aws.amazon.com"})
+	if message.Type != "message" || len(message.Attachments) != 1 || message.Attachments[0].ContentType != teamsCardContentType {
+		t.Fatal("message was not as expected", message)
+	}
+	card := message.Attachments[0].Content
+	if len(card.Body) != 2 || card.Body[0].Text != "web: failure" || card.Body[0].Color != "Attention" {
+		t.Fatal("card body was not as expected", card.Body)
+	} else if len(card.Actions) != 1 || card.Actions[0].URL != "https://console.aws.amazon.com" {
+		t.Fatal("card actions were not as expected", card.Actions)
+	}
+
+	// Warnings are a single text block
+	if message = newTeamsMessage(notification{Text: "token expires soon"}); len(message.Attachments[0].Content.Body) != 1 ||
+		len(message.Attachments[0].Content.Actions) != 0 {
+		t.Fatal("warning was not as expected", message)
+	}
+}
+
+// TestTeamsNotifier will test teamsNotifier.Notify() picking the webhook of the pipeline
+func TestTeamsNotifier(t *testing.T) {
+	received := make(map[string]teamsMessage)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Webhook message delivery failed"))
+			return
+		}
+		var message teamsMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		received[r.URL.Path] = message
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	n := &teamsNotifier{client: http.DefaultClient, webhookURL: server.URL + "/default", webhooks: map[string]string{
+		"payments": server.URL + "/payments",
+		"broken":   server.URL + "/invalid",
+	}}
+	if err := n.Notify(context.Background(), notification{Pipeline: "payments", State: githubStateSuccess, Text: "deployed"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = n.Notify(context.Background(), notification{Pipeline: "search", Text: "deployed"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(received) != 2 || len(received["/payments"].Attachments) != 1 || len(received["/default"].Attachments) != 1 {
+		t.Fatal("messages were not as expected", received)
+	}
+
+	if err := n.Notify(context.Background(), notification{Pipeline: "broken", Text: "deployed"}); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err.Error() != "unexpected response from Teams, code: 400 body: Webhook message delivery failed" {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Pipelines without a webhook are skipped
+	n.webhookURL = ""
+	if err := n.Notify(context.Background(), notification{Pipeline: "search", Text: "deployed"}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+}
+
+// TestHandlerProcessEventTeams will test ProcessEvent() notifying the Teams webhook of the pipeline
+func TestHandlerProcessEventTeams(t *testing.T) {
+	var messages []teamsMessage
+	teams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message teamsMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer teams.Close()
+
+	h := newTestHandler(Config{
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		NotifyStates:         []string{githubStateSuccess},
+		Stage:                stageTesting,
+		TeamsWebhooks:        stringMap{"status-succeed": teams.URL},
+	})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"commit":{"author":{"name":"MrZ"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := h.ProcessEvent(Event{Detail: &Detail{ExecutionID: "12345678", Pipeline: "status-succeed", State: "SUCCEEDED"}}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(messages) != 1 {
+		t.Fatal("message was not sent", messages)
+	} else if body := messages[0].Attachments[0].Content.Body; body[1].Text != "mrz1836/codepipeline-to-github@25c0c3e by MrZ" {
+		t.Fatal("text was not as expected", body[1].Text)
+	}
+}
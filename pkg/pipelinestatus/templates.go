@@ -0,0 +1,136 @@
+package pipelinestatus
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// templateData is the data available to the description and target URL templates
+// (IE: DESCRIPTION_TEMPLATE="Deployed {{.Variables.IMAGE_TAG}} to {{.Variables.ENVIRONMENT}}")
+type templateData struct {
+	Author      string // notifications only (NOTIFICATION_TEMPLATE)
+	Commit      string
+	Description string
+	Duration    string       // total duration of a finished execution, IE: 7m 32s (EXECUTION_TABLE)
+	Entity      portalEntity // the pipeline in the developer portal (PORTAL_ENTITIES)
+	ExecutionID string
+	Forge       string // IE: github, gitlab, bitbucket
+	Owner       string
+	Pipeline    string
+	Region      string
+	Repo        string
+	State       string // the GitHub state, IE: success
+	Status      string // the state of the event, IE: Succeeded
+	Tag         string // the git tag of a release pipeline, IE: v1.2.3
+	TargetURL   string
+	Variables   map[string]string
+}
+
+// contextTemplateData is the data available to the status context template (STATUS_CONTEXT_TEMPLATE)
+// (IE: "ci/{{.Pipeline}}/{{.Stage}}", the stage is empty for the status of the pipeline)
+type contextTemplateData struct {
+	Pipeline string
+	Region   string
+	Stage    string
+}
+
+// TemplateFuncs will return the helper functions available to the description and target URL templates,
+// only the environment variables in allowedEnv can be read with env
+// (IE: {{.Description | truncate 40}}, {{shortSHA .Commit}}, {{humanDuration "754s"}}, {{env "TEAM"}})
+func TemplateFuncs(allowedEnv []string) template.FuncMap {
+	allowed := make(map[string]bool, len(allowedEnv))
+	for _, name := range allowedEnv {
+		allowed[name] = true
+	}
+	return template.FuncMap{
+		"env": func(name string) (string, error) {
+			if !allowed[name] {
+				return "", fmt.Errorf("environment variable %s is not in TEMPLATE_ENV_ALLOWLIST", name)
+			}
+			return os.Getenv(name), nil
+		},
+		"humanDuration": humanDuration,
+		"lower":         strings.ToLower,
+		"shortSHA":      shortSHA,
+		"truncate": func(length int, value string) string {
+			if length < 0 || len(value) <= length {
+				return value
+			} else if length <= 3 {
+				return value[:length]
+			}
+			return value[:length-3] + "..."
+		},
+		"upper": strings.ToUpper,
+	}
+}
+
+// humanDuration will format a duration (time.Duration, seconds or a duration string) as IE: 12m34s
+func humanDuration(value interface{}) (string, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	case int:
+		d = time.Duration(v) * time.Second
+	case int64:
+		d = time.Duration(v) * time.Second
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			d = time.Duration(seconds * float64(time.Second))
+		} else if d, err = time.ParseDuration(v); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported duration: %v", value)
+	}
+	return d.Round(time.Second).String(), nil
+}
+
+// renderTemplate will execute a template with the given data and helper functions (missing variables render as empty)
+func renderTemplate(name, text string, data templateData, funcs template.FuncMap) (string, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err = tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// renderStatusContext will render the status context of a pipeline (or of its stage) with STATUS_CONTEXT_TEMPLATE,
+// the slashes left around an empty stage are removed (IE: ci/web/ is ci/web)
+func (h *Handler) renderStatusContext(pipelineName, stage string) (string, error) {
+	var b strings.Builder
+	tmpl, err := template.New("context").Funcs(TemplateFuncs(h.cfg.TemplateEnvAllowlist)).Option("missingkey=zero").
+		Parse(h.cfg.StatusContextTemplate)
+	if err != nil {
+		return "", err
+	} else if err = tmpl.Execute(&b, contextTemplateData{Pipeline: pipelineName, Region: h.cfg.AWSRegion, Stage: stage}); err != nil {
+		return "", err
+	}
+	statusCtx := strings.Trim(strings.Replace(b.String(), "//", "/", -1), "/ ")
+	if len(statusCtx) == 0 {
+		return "", fmt.Errorf("STATUS_CONTEXT_TEMPLATE rendered an empty context for %s", pipelineName)
+	}
+	return statusCtx, nil
+}
+
+// executionVariables will return the resolved pipeline variables of an execution (V2 pipelines)
+func executionVariables(executionOutput *codepipeline.GetPipelineExecutionOutput) map[string]string {
+	variables := make(map[string]string)
+	for _, variable := range executionOutput.PipelineExecution.Variables {
+		variables[aws.StringValue(variable.Name)] = aws.StringValue(variable.ResolvedValue)
+	}
+	return variables
+}
@@ -0,0 +1,123 @@
+package pipelinestatus
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TestRenderTemplate will test renderTemplate()
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	data := templateData{
+		Pipeline:  "some-pipeline",
+		State:     githubStateSuccess,
+		Variables: map[string]string{"IMAGE_TAG": "v1.2.3"},
+	}
+
+	var tests = []struct {
+		text          string
+		expected      string
+		expectedError bool
+	}{
+		{"{{.State}}: {{.Pipeline}} built {{.Variables.IMAGE_TAG}}", "success: some-pipeline built v1.2.3", false},
+		{"env: {{.Variables.MISSING}}", "env:", false},
+		{"{{.Unknown}}", "", true},
+		{"{{.State", "", true},
+		{"{{.Pipeline | upper}} {{lower .State}}", "SOME-PIPELINE success", false},
+		{"{{shortSHA .Commit}}", "25c0c3e", false},
+		{"{{.Pipeline | truncate 8}}", "some-...", false},
+		{"{{humanDuration .Variables.DURATION}}", "12m34s", false},
+		{"{{env \"HOME\"}}", "", true},
+	}
+	data.Commit = "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"
+	data.Variables["DURATION"] = "754"
+
+	for _, test := range tests {
+		output, err := renderTemplate("test", test.text, data, TemplateFuncs(nil))
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: template [%s], expected to throw an error, but no error", t.Name(), test.text)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: template [%s], error occurred [%s]", t.Name(), test.text, err.Error())
+		} else if output != test.expected {
+			t.Errorf("%s Failed: template [%s], expected [%s] but got [%s]", t.Name(), test.text, test.expected, output)
+		}
+	}
+}
+
+// TestTemplateFuncs will test TemplateFuncs()
+func TestTemplateFuncs(t *testing.T) {
+	_ = os.Setenv("TEMPLATE_TEAM", "payments")
+	defer func() {
+		_ = os.Unsetenv("TEMPLATE_TEAM")
+	}()
+
+	output, err := renderTemplate("test", `{{env "TEMPLATE_TEAM"}}`, templateData{}, TemplateFuncs([]string{"TEMPLATE_TEAM"}))
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if output != "payments" {
+		t.Fatal("output was not as expected", output)
+	}
+}
+
+// TestHumanDuration will test humanDuration()
+func TestHumanDuration(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		input         interface{}
+		expected      string
+		expectedError bool
+	}{
+		{90 * time.Second, "1m30s", false},
+		{90, "1m30s", false},
+		{int64(3600), "1h0m0s", false},
+		{90.4, "1m30s", false},
+		{"2m5s", "2m5s", false},
+		{"754", "12m34s", false},
+		{"soon", "", true},
+		{true, "", true},
+	}
+
+	for _, test := range tests {
+		output, err := humanDuration(test.input)
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, expected to throw an error, but no error", t.Name(), test.input)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%v] inputted, error occurred [%s]", t.Name(), test.input, err.Error())
+		} else if output != test.expected {
+			t.Errorf("%s Failed: [%v] inputted, expected [%s] but got [%s]", t.Name(), test.input, test.expected, output)
+		}
+	}
+}
+
+// TestExecutionVariables will test executionVariables()
+func TestExecutionVariables(t *testing.T) {
+	t.Parallel()
+
+	variables := executionVariables(&codepipeline.GetPipelineExecutionOutput{
+		PipelineExecution: &types.PipelineExecution{
+			Variables: []types.ResolvedPipelineVariable{
+				{Name: aws.String("IMAGE_TAG"), ResolvedValue: aws.String("v1.2.3")},
+				{Name: aws.String("ENVIRONMENT"), ResolvedValue: aws.String("staging")},
+			},
+		},
+	})
+	if len(variables) != 2 {
+		t.Fatal("expected 2 variables", variables)
+	} else if variables["ENVIRONMENT"] != "staging" {
+		t.Fatal("variable was not as expected", variables["ENVIRONMENT"])
+	}
+
+	// V1 pipelines have no variables
+	if variables = executionVariables(&codepipeline.GetPipelineExecutionOutput{
+		PipelineExecution: &types.PipelineExecution{},
+	}); len(variables) != 0 {
+		t.Fatal("expected no variables", variables)
+	}
+}
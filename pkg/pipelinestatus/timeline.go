@@ -0,0 +1,62 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// timelineEntry is a single state transition of a pipeline execution for a commit
+type timelineEntry struct {
+	Commit      string    `dynamodbav:"commit" json:"commit"`
+	ExecutionID string    `dynamodbav:"execution_id" json:"execution_id"`
+	Pipeline    string    `dynamodbav:"pipeline" json:"pipeline"`
+	Sort        string    `dynamodbav:"sort" json:"-"`
+	State       string    `dynamodbav:"state" json:"state"`
+	Time        time.Time `dynamodbav:"time" json:"time"`
+}
+
+// recordTransition will store a state transition in the timeline of a commit
+// (the sort key orders the transitions by time and keeps each one unique)
+func recordTransition(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, entry timelineEntry) (err error) {
+	entry.Time = entry.Time.UTC()
+	entry.Sort = fmt.Sprintf("%s#%s#%s#%s", entry.Time.Format(time.RFC3339Nano), entry.Pipeline, entry.ExecutionID, entry.State)
+
+	var item map[string]*dynamodb.AttributeValue
+	if item, err = dynamodbattribute.MarshalMap(entry); err != nil {
+		return
+	}
+	_, err = dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(table),
+	})
+	return
+}
+
+// getTimeline will return the state transitions of a commit in the order they happened
+func getTimeline(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, commit string) (entries []timelineEntry, err error) {
+	var items []map[string]*dynamodb.AttributeValue
+	if err = dynamoSvc.QueryPagesWithContext(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeNames: map[string]*string{
+			"#commit": aws.String("commit"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":commit": {S: aws.String(commit)},
+		},
+		KeyConditionExpression: aws.String("#commit = :commit"),
+		ScanIndexForward:       aws.Bool(true),
+		TableName:              aws.String(table),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	}); err != nil {
+		return
+	}
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &entries)
+	return
+}
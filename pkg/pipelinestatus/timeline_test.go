@@ -0,0 +1,99 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRecordTransition will test recordTransition() and getTimeline()
+func TestRecordTransition(t *testing.T) {
+	mockDynamo := &mockDynamoClient{}
+	transitionTime := time.Date(2020, 4, 30, 3, 31, 47, 0, time.UTC)
+
+	if err := recordTransition(context.Background(), mockDynamo, "timeline", timelineEntry{
+		Commit:      "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08",
+		ExecutionID: "12345678",
+		Pipeline:    "some-pipeline",
+		State:       "STARTED",
+		Time:        transitionTime,
+	}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+
+	entries, err := getTimeline(context.Background(), mockDynamo, "timeline", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(entries) != 1 {
+		t.Fatal("expected one entry", entries)
+	} else if entries[0].Sort != "2020-04-30T03:31:47Z#some-pipeline#12345678#STARTED" {
+		t.Fatal("sort key was not as expected", entries[0].Sort)
+	} else if !entries[0].Time.Equal(transitionTime) || entries[0].State != "STARTED" {
+		t.Fatal("entry was not as expected", entries[0])
+	}
+
+	// Missing table
+	if _, err = getTimeline(context.Background(), mockDynamo, "", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestTimelineCommand will test timelineCommand()
+func TestTimelineCommand(t *testing.T) {
+
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", "testing")
+	defer os.Clearenv()
+
+	mockDynamo := &mockDynamoClient{}
+	_ = recordTransition(context.Background(), mockDynamo, "timeline", timelineEntry{
+		Commit:      "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08",
+		ExecutionID: "12345678",
+		Pipeline:    "some-pipeline",
+		State:       "SUCCEEDED",
+		Time:        time.Date(2020, 4, 30, 3, 31, 47, 0, time.UTC),
+	})
+
+	// Missing commit
+	if err := timelineCommand(nil, &bytes.Buffer{}, mockDynamo); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Missing table
+	args := []string{"-commit", "25c0c3e61c4db2c2cde8b163b3ad096875c1ce08"}
+	if err := timelineCommand(args, &bytes.Buffer{}, mockDynamo); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Valid timeline
+	_ = os.Setenv("TIMELINE_TABLE", "timeline")
+	var out bytes.Buffer
+	if err := timelineCommand(args, &out, mockDynamo); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected a header and one row", out.String())
+	} else if strings.Join(strings.Fields(lines[1]), " ") != "2020-04-30T03:31:47Z some-pipeline 12345678 SUCCEEDED" {
+		t.Fatal("row was not as expected", lines[1])
+	}
+
+	// JSON timeline
+	out.Reset()
+	var entries []map[string]interface{}
+	if err := timelineCommand(append(args, "-output", outputJSON), &out, mockDynamo); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatal("output was not valid json", err.Error())
+	} else if len(entries) != 1 || entries[0]["state"] != "SUCCEEDED" {
+		t.Fatal("timeline was not as expected", out.String())
+	} else if _, ok := entries[0]["sort"]; ok {
+		t.Fatal("sort key should not be in the output", out.String())
+	}
+}
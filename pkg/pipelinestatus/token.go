@@ -0,0 +1,128 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Token health defaults
+const (
+	metricNamespace         = "CodePipelineToGithub"
+	metricTokenExpiry       = "GithubTokenDaysUntilExpiry"
+	tokenExpirationHeader   = "GitHub-Authentication-Token-Expiration"
+	tokenExpiryWarningEvery = 24 * time.Hour
+)
+
+// tokenExpirationLayouts are the formats GitHub uses for the token expiration header
+var tokenExpirationLayouts = []string{
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// Per-container throttle for the token expiry warning
+var (
+	lastTokenWarning   time.Time
+	lastTokenWarningMu sync.Mutex
+)
+
+// tokenExpiryMu guards the token expiry of the handlers (set by concurrent events)
+var tokenExpiryMu sync.Mutex
+
+// setTokenExpiry will keep track of when the token of the handler expires
+func (h *Handler) setTokenExpiry(expiresAt time.Time) {
+	tokenExpiryMu.Lock()
+	defer tokenExpiryMu.Unlock()
+	h.tokenExpiresAt = expiresAt
+}
+
+// tokenExpiry will return when the token of the handler expires (zero if unknown)
+func (h *Handler) tokenExpiry() time.Time {
+	tokenExpiryMu.Lock()
+	defer tokenExpiryMu.Unlock()
+	return h.tokenExpiresAt
+}
+
+// parseTokenExpiration will return the expiration of the token used for a GitHub response
+// (tokens without an expiration do not send the header)
+func parseTokenExpiration(header http.Header) (expiresAt time.Time, ok bool) {
+	value := header.Get(tokenExpirationHeader)
+	if len(value) == 0 {
+		return
+	}
+	for _, layout := range tokenExpirationLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return
+}
+
+// daysUntil will return the whole days left until a time (negative once it has passed)
+func daysUntil(expiresAt, now time.Time) int {
+	return int(math.Floor(expiresAt.Sub(now).Hours() / 24))
+}
+
+// checkTokenExpiry will emit the days-until-expiry metric of the GitHub token and warn the
+// notifiers (at most once a day per container) when the token is about to expire
+func (h *Handler) checkTokenExpiry(now time.Time) {
+
+	// Only tokens with an expiration are checked
+	expiresAt := h.tokenExpiry()
+	if expiresAt.IsZero() {
+		return
+	}
+	days := daysUntil(expiresAt, now)
+	printMetric(metricTokenExpiry, float64(days), "Count", nil, now)
+
+	// Warn if the token expires soon
+	if days > h.cfg.TokenExpiryWarningDays {
+		return
+	}
+	lastTokenWarningMu.Lock()
+	defer lastTokenWarningMu.Unlock()
+	if now.Sub(lastTokenWarning) < tokenExpiryWarningEvery {
+		return
+	}
+	for _, err := range h.notify(fmt.Sprintf(
+		"The GitHub token used for CodePipeline statuses (%s) expires in %d day(s) on %s, commit statuses will stop when it lapses",
+		h.cfg.Stage, days, expiresAt.UTC().Format(time.RFC1123),
+	)) {
+		if err == nil {
+			lastTokenWarning = now
+		}
+	}
+}
+
+// printMetric will log a metric in the CloudWatch embedded metric format (extracted from the Lambda logs)
+func printMetric(name string, value float64, unit string, dimensions map[string]string, now time.Time) {
+	keys := make([]string, 0, len(dimensions))
+	for key := range dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	record := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"CloudWatchMetrics": []interface{}{map[string]interface{}{
+				"Dimensions": [][]string{keys},
+				"Metrics":    []interface{}{map[string]string{"Name": name, "Unit": unit}},
+				"Namespace":  metricNamespace,
+			}},
+			"Timestamp": now.UnixNano() / int64(time.Millisecond),
+		},
+		name: value,
+	}
+	for key, dimension := range dimensions {
+		record[key] = dimension
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(b))
+	collectMetric(name, value, unit, dimensions)
+}
@@ -0,0 +1,88 @@
+package pipelinestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestParseTokenExpiration will test parseTokenExpiration()
+func TestParseTokenExpiration(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected time.Time
+		ok       bool
+	}{
+		{"2020-06-01 12:00:00 UTC", time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), true},
+		{"2020-06-01 12:00:00 -0700", time.Date(2020, 6, 1, 19, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"next week", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		if len(test.value) > 0 {
+			header.Set(tokenExpirationHeader, test.value)
+		}
+		if expiresAt, ok := parseTokenExpiration(header); ok != test.ok {
+			t.Errorf("%s Failed: [%s] inputted, expected ok [%v] but got [%v]", t.Name(), test.value, test.ok, ok)
+		} else if !expiresAt.Equal(test.expected) {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.value, test.expected, expiresAt)
+		}
+	}
+}
+
+// TestDaysUntil will test daysUntil()
+func TestDaysUntil(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	if days := daysUntil(now.Add(36*time.Hour), now); days != 1 {
+		t.Fatal("days was not as expected", days)
+	} else if days = daysUntil(now.Add(-time.Hour), now); days != -1 {
+		t.Fatal("days was not as expected", days)
+	}
+}
+
+// TestCheckTokenExpiry will test checkTokenExpiry()
+func TestCheckTokenExpiry(t *testing.T) {
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	defer func() {
+		lastTokenWarning = time.Time{}
+	}()
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	h := newTestHandler(Config{SlackWebhookURL: server.URL, Stage: stageProduction, TokenExpiryWarningDays: 14})
+
+	// No expiration known
+	h.checkTokenExpiry(now)
+	if len(messages) != 0 {
+		t.Fatal("no warning should have been sent", messages)
+	}
+
+	// Expires later than the warning window
+	h.tokenExpiresAt = now.Add(30 * 24 * time.Hour)
+	h.checkTokenExpiry(now)
+	if len(messages) != 0 {
+		t.Fatal("no warning should have been sent", messages)
+	}
+
+	// Expires soon (warned once a day)
+	h.tokenExpiresAt = now.Add(3 * 24 * time.Hour)
+	h.checkTokenExpiry(now)
+	h.checkTokenExpiry(now.Add(time.Hour))
+	if len(messages) != 1 {
+		t.Fatal("one warning should have been sent", messages)
+	}
+	h.checkTokenExpiry(now.Add(25 * time.Hour))
+	if len(messages) != 2 {
+		t.Fatal("a second warning should have been sent", messages)
+	}
+}
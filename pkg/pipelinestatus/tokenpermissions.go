@@ -0,0 +1,177 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GitHub token kinds and permissions
+const (
+	fineGrainedTokenPrefix      = "github_pat_"
+	githubPermissionChecks      = "checks:write"
+	githubPermissionDeployments = "deployments:write"
+	githubPermissionStatuses    = "statuses:write"
+	githubTokenClassic          = "classic"
+	githubTokenFineGrained      = "fine-grained"
+	oauthScopesHeader           = "X-OAuth-Scopes"
+	permissionProbeCommit       = "0000000000000000000000000000000000000000"
+	permissionProbeNotAccessed  = "Resource not accessible by personal access token"
+)
+
+// githubPermission is how a permission is granted to a classic token (scopes) and how it is probed
+// on a repository for a fine-grained token (an empty POST is rejected with a 403 without the permission
+// and a 422 with it, nothing is created)
+type githubPermission struct {
+	probe  string
+	scopes []string
+}
+
+// githubPermissions are the permissions the features need
+var githubPermissions = map[string]githubPermission{
+	githubPermissionChecks:      {probe: "/repos/%s/check-runs", scopes: []string{"repo"}},
+	githubPermissionDeployments: {probe: "/repos/%s/deployments", scopes: []string{"repo", "repo_deployment"}},
+	githubPermissionStatuses:    {probe: "/repos/%s/statuses/" + permissionProbeCommit, scopes: []string{"repo", "repo:status"}},
+}
+
+// githubRepository is a repository the token can access
+type githubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// tokenKind will return if the GitHub token is a classic or a fine-grained personal access token, or a token
+// of a GitHub App installation
+func tokenKind(token string) string {
+	if strings.HasPrefix(token, fineGrainedTokenPrefix) {
+		return githubTokenFineGrained
+	} else if strings.HasPrefix(token, installationTokenPrefix) {
+		return githubTokenInstallation
+	}
+	return githubTokenClassic
+}
+
+// requiredGithubPermissions will return the permissions needed by the enabled features (sorted)
+func requiredGithubPermissions(cfg Config) (permissions []string) {
+	if cfg.UseChecksAPI {
+		permissions = append(permissions, githubPermissionChecks)
+	}
+	if cfg.CodeDeployEvents || len(cfg.DeploymentStagePattern) > 0 {
+		permissions = append(permissions, githubPermissionDeployments)
+	}
+	if !cfg.UseChecksAPI {
+		permissions = append(permissions, githubPermissionStatuses)
+	}
+	return
+}
+
+// verifyTokenPermissions will return an error listing the permissions the GitHub token is missing
+// (classic tokens are checked with their scopes, fine-grained tokens by probing a repository they can access),
+// the check runs once per token in a container, not per event
+func (h *Handler) verifyTokenPermissions(ctx context.Context) (err error) {
+	cache := h.deps.cache
+	cache.verifiedTokenMu.Lock()
+	defer cache.verifiedTokenMu.Unlock()
+	if cache.verifiedToken == h.cfg.GithubAccessToken {
+		return nil
+	}
+
+	required := requiredGithubPermissions(h.cfg)
+	var missing []string
+	switch tokenKind(h.cfg.GithubAccessToken) {
+	case githubTokenFineGrained:
+		missing, err = h.missingFineGrainedPermissions(ctx, required)
+	case githubTokenInstallation:
+		missing = h.missingInstallationPermissions(required)
+	default:
+		missing, err = h.missingClassicPermissions(ctx, required)
+	}
+	if err != nil {
+		return
+	} else if len(missing) > 0 {
+		return fmt.Errorf("the %s GitHub token is missing permissions: %s", tokenKind(h.cfg.GithubAccessToken), strings.Join(missing, ", "))
+	}
+	cache.verifiedToken = h.cfg.GithubAccessToken
+	return nil
+}
+
+// missingClassicPermissions will return the required permissions not granted by the scopes of a classic token,
+// scopes that are not needed are logged (tokens without the scopes header, IE: app tokens, are not checked)
+func (h *Handler) missingClassicPermissions(ctx context.Context, required []string) (missing []string, err error) {
+	var req *http.Request
+	if req, err = h.newGithubRequest(ctx, http.MethodGet, "/user", nil); err != nil {
+		return
+	}
+	var response *http.Response
+	if response, err = h.deps.GitHub.Do(req); err != nil {
+		return
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = &githubError{Code: response.StatusCode}
+		return
+	} else if len(response.Header.Values(oauthScopesHeader)) == 0 {
+		return
+	}
+
+	granted := make(map[string]bool)
+	for _, scope := range strings.Split(response.Header.Get(oauthScopesHeader), ",") {
+		if scope = strings.TrimSpace(scope); len(scope) > 0 {
+			granted[scope] = true
+		}
+	}
+	needed := make(map[string]bool)
+	for _, permission := range required {
+		found := false
+		for _, scope := range githubPermissions[permission].scopes {
+			needed[scope] = true
+			found = found || granted[scope]
+		}
+		if !found {
+			missing = append(missing, permission)
+		}
+	}
+
+	var extra []string
+	for scope := range granted {
+		if !needed[scope] {
+			extra = append(extra, scope)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		logWarnf(context.Background(), "the GitHub token has scopes that are not needed: %s", strings.Join(extra, ", "))
+	}
+	return
+}
+
+// missingFineGrainedPermissions will probe the required permissions on a repository the fine-grained token
+// can access (the permissions of a fine-grained token apply to all of its repositories)
+func (h *Handler) missingFineGrainedPermissions(ctx context.Context, required []string) (missing []string, err error) {
+	var repos []githubRepository
+	if err = h.githubGet(ctx, "/user/repos?per_page=1", &repos); err != nil {
+		return
+	} else if len(repos) == 0 {
+		err = errors.New("the fine-grained GitHub token has no repository access")
+		return
+	}
+
+	for _, permission := range required {
+		var req *http.Request
+		if req, err = h.newGithubRequest(ctx, http.MethodPost, fmt.Sprintf(githubPermissions[permission].probe, repos[0].FullName), struct{}{}); err != nil {
+			return
+		}
+		if err = h.doGithubRequest(req, http.StatusUnprocessableEntity, nil); err == nil {
+			continue
+		}
+		if ghErr, ok := err.(*githubError); ok && ghErr.Code == http.StatusForbidden && strings.Contains(ghErr.Body, permissionProbeNotAccessed) {
+			missing = append(missing, permission)
+			err = nil
+			continue
+		}
+		return
+	}
+	return
+}
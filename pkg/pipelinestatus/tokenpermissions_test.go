@@ -0,0 +1,121 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestTokenKind will test tokenKind()
+func TestTokenKind(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		token    string
+		expected string
+	}{
+		{"ghp_1234567", githubTokenClassic},
+		{"1234567890abcdef1234567890abcdef12345678", githubTokenClassic},
+		{"github_pat_11AAAA_1234567", githubTokenFineGrained},
+	}
+
+	for _, test := range tests {
+		if kind := tokenKind(test.token); kind != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.token, test.expected, kind)
+		}
+	}
+}
+
+// TestRequiredGithubPermissions will test requiredGithubPermissions()
+func TestRequiredGithubPermissions(t *testing.T) {
+	t.Parallel()
+
+	if permissions := requiredGithubPermissions(Config{}); len(permissions) != 1 || permissions[0] != githubPermissionStatuses {
+		t.Fatal("permissions were not as expected", permissions)
+	} else if permissions = requiredGithubPermissions(Config{UseChecksAPI: true}); len(permissions) != 1 || permissions[0] != githubPermissionChecks {
+		t.Fatal("permissions were not as expected", permissions)
+	} else if permissions = requiredGithubPermissions(Config{CodeDeployEvents: true}); len(permissions) != 2 ||
+		permissions[0] != githubPermissionDeployments || permissions[1] != githubPermissionStatuses {
+		t.Fatal("permissions were not as expected", permissions)
+	}
+}
+
+// TestVerifyTokenPermissionsClassic will test Handler.verifyTokenPermissions() with classic tokens
+func TestVerifyTokenPermissionsClassic(t *testing.T) {
+	var tests = []struct {
+		scopes        string
+		useChecksAPI  bool
+		expectedError bool
+	}{
+		{"repo", false, false},
+		{"repo:status, read:org", false, false},
+		{"repo:status", true, true},
+		{"read:org", false, true},
+		{"", false, true},
+	}
+
+	for _, test := range tests {
+		h := newTestHandler(Config{GithubAccessToken: "ghp_1234567", UseChecksAPI: test.useChecksAPI})
+		scopes := test.scopes
+		newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(oauthScopesHeader, scopes)
+			_, _ = w.Write([]byte(`{}`))
+		})
+		if err := h.verifyTokenPermissions(context.Background()); err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error was expected", t.Name(), test.scopes)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.scopes, err.Error())
+		}
+	}
+
+	// Tokens without scopes (IE: app tokens) are not checked
+	h := newTestHandler(Config{GithubAccessToken: "ghs_1234567"})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	if err := h.verifyTokenPermissions(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+}
+
+// TestVerifyTokenPermissionsFineGrained will test Handler.verifyTokenPermissions() with fine-grained tokens
+func TestVerifyTokenPermissionsFineGrained(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "github_pat_1234567", UseChecksAPI: true})
+
+	var calls int
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/user/repos":
+			_, _ = w.Write([]byte(`[{"full_name":"mrz1836/codepipeline-to-github"}]`))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/repos/mrz1836/codepipeline-to-github/statuses/"):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/mrz1836/codepipeline-to-github/check-runs":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"` + permissionProbeNotAccessed + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// Missing checks
+	err := h.verifyTokenPermissions(context.Background())
+	if err == nil {
+		t.Fatal("error should have occurred")
+	} else if !strings.Contains(err.Error(), githubPermissionChecks) || strings.Contains(err.Error(), githubPermissionStatuses) {
+		t.Fatal("error was not as expected", err.Error())
+	}
+
+	// Statuses are granted, verified once per token
+	h.cfg.UseChecksAPI = false
+	if err = h.verifyTokenPermissions(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	calls = 0
+	if err = h.verifyTokenPermissions(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if calls != 0 {
+		t.Fatal("token should not be verified again", calls)
+	}
+}
@@ -0,0 +1,140 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// tombstoneReport is the result of retiring the context of a renamed pipeline
+type tombstoneReport struct {
+	Branch            string   `json:"branch"`
+	Commits           []string `json:"commits"` // open pull request head commits that got the tombstone status
+	Context           string   `json:"context"` // obsolete context
+	Pipeline          string   `json:"pipeline"`
+	ProtectionUpdates []string `json:"protection_updates"`
+	RenamedTo         string   `json:"renamed_to"`
+	Replacement       string   `json:"replacement"` // context of the renamed pipeline
+	Repository        string   `json:"repository"`
+}
+
+// pullRequest is an open pull request of a repository
+type pullRequest struct {
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Number int    `json:"number"`
+	State  string `json:"state"`
+}
+
+// renamedContext will return the obsolete context of a renamed pipeline, the prefix of the new name is
+// used when the old name is no longer configured (the default context is shared by both names)
+func (h *Handler) renamedContext(ctx context.Context, oldName, newContext, newName string) (string, error) {
+	if _, ok := h.cfg.ContextPrefixes[oldName]; ok {
+		return h.statusContext(ctx, oldName, "")
+	} else if strings.HasSuffix(newContext, "/"+newName) {
+		return strings.TrimSuffix(newContext, newName) + oldName, nil
+	}
+	return newContext, nil
+}
+
+// tombstoneContexts will post a terminal success status under the obsolete context of each renamed pipeline
+// (PIPELINE_RENAMES) on the head commit of the open pull requests of its branch, so the context no longer
+// blocks merges, and report the branch protection checks to update (nothing is posted on a dry run)
+func (h *Handler) tombstoneContexts(ctx context.Context, only string, dryRun bool) (reports []tombstoneReport, err error) {
+	reports = []tombstoneReport{}
+	var renamed []string
+	for oldName := range h.cfg.PipelineRenames {
+		if len(only) == 0 || only == oldName {
+			renamed = append(renamed, oldName)
+		}
+	}
+	if len(renamed) == 0 {
+		err = fmt.Errorf("no renamed pipeline in PIPELINE_RENAMES: %s", only)
+		return
+	}
+	sort.Strings(renamed)
+
+	for _, oldName := range renamed {
+		var report tombstoneReport
+		if report, err = h.tombstoneContext(ctx, oldName, h.cfg.PipelineRenames[oldName], dryRun); err != nil {
+			return
+		}
+		reports = append(reports, report)
+	}
+	return
+}
+
+// tombstoneContext will retire the obsolete context of one renamed pipeline
+func (h *Handler) tombstoneContext(ctx context.Context, oldName, newName string, dryRun bool) (report tombstoneReport, err error) {
+	report = tombstoneReport{Commits: []string{}, Pipeline: oldName, ProtectionUpdates: []string{}, RenamedTo: newName}
+
+	// Find the repository and both contexts (from the renamed pipeline)
+	var owner, repo string
+	if owner, repo, report.Branch, err = getSourceBranch(ctx, newName, h.deps.CodePipeline); err != nil {
+		return
+	} else if report.Replacement, err = h.statusContext(ctx, newName, ""); err != nil {
+		return
+	} else if report.Context, err = h.renamedContext(ctx, oldName, report.Replacement, newName); err != nil {
+		return
+	}
+	report.Repository = owner + "/" + repo
+
+	// Both names share the same context, nothing is obsolete
+	if report.Context == report.Replacement {
+		return
+	}
+
+	// Post the tombstone on the head commit of the open pull requests
+	var pulls []pullRequest
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/pulls?state=open&base=%s&per_page=100", owner, repo, report.Branch), &pulls); err != nil {
+		return
+	}
+	posted := make(map[string]bool)
+	for _, pull := range pulls {
+		if posted[pull.Head.SHA] {
+			continue
+		}
+		posted[pull.Head.SHA] = true
+		report.Commits = append(report.Commits, pull.Head.SHA)
+		if dryRun {
+			continue
+		}
+		if err = h.postTombstoneStatus(ctx, owner, repo, pull.Head.SHA, report.Context, newName); err != nil {
+			return
+		}
+	}
+
+	// Branch protection checks that need to be updated (no protection if not found)
+	var checks requiredStatusChecks
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/branches/%s/protection/required_status_checks", owner, repo, report.Branch), &checks); isGithubNotFound(err) {
+		err = nil
+	} else if err != nil {
+		return
+	}
+	for _, statusCtx := range checks.Contexts {
+		if statusCtx == report.Context {
+			report.ProtectionUpdates = append(report.ProtectionUpdates, fmt.Sprintf("replace %s with %s", statusCtx, report.Replacement))
+		}
+	}
+	return
+}
+
+// postTombstoneStatus will post the terminal status of an obsolete context
+func (h *Handler) postTombstoneStatus(ctx context.Context, owner, repo, commit, statusCtx, newName string) error {
+	req, err := h.newGithubRequest(
+		ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/statuses/%s", owner, repo, commit), &payload{
+			Context:     statusCtx,
+			Description: joinDescription("pipeline renamed to " + newName + ", this context is obsolete"),
+			State:       githubStateSuccess,
+			TargetURL: consoleURL(configPartition(h.cfg), h.cfg.AWSRegion, fmt.Sprintf(
+				"/codesuite/codepipeline/pipelines/%s/view", newName)),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return h.doGithubRequest(req, http.StatusCreated, nil)
+}
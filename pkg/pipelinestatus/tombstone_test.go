@@ -0,0 +1,126 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Head commits of the open pull requests
+const (
+	tombstoneCommitFeature = "4444444444444444444444444444444444444444"
+	tombstoneCommitFix     = "5555555555555555555555555555555555555555"
+)
+
+// newTombstoneServer will start a fake GitHub API with open pull requests and return the posted statuses
+func newTombstoneServer(t *testing.T, h *Handler) map[string]payload {
+	posted := make(map[string]payload)
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		repoPath := "/repos/mrz1836/codepipeline-to-github"
+		switch {
+		case r.URL.Path == repoPath+"/pulls":
+			_, _ = w.Write([]byte(`[{"number":1,"head":{"sha":"` + tombstoneCommitFeature + `"}},` +
+				`{"number":2,"head":{"sha":"` + tombstoneCommitFix + `"}},{"number":3,"head":{"sha":"` + tombstoneCommitFix + `"}}]`))
+		case r.URL.Path == repoPath+"/branches/master/protection/required_status_checks":
+			_, _ = w.Write([]byte(`{"contexts":["team-payments/ci/payments","security/scan"]}`))
+		case r.Method == http.MethodPost:
+			var status payload
+			_ = json.NewDecoder(r.Body).Decode(&status)
+			posted[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = status
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return posted
+}
+
+// TestRenamedContext will test Handler.renamedContext()
+func TestRenamedContext(t *testing.T) {
+	h := newTestHandler(Config{ContextPrefixes: stringMap{"billing": "team-billing/ci"}})
+
+	var tests = []struct {
+		oldName    string
+		newContext string
+		newName    string
+		expected   string
+	}{
+		{"payments", "team-payments/ci/payments-v2", "payments-v2", "team-payments/ci/payments"},
+		{"billing", "team-finance/ci/invoices", "invoices", "team-billing/ci/billing"},
+		{"payments", defaultStatusContext, "payments-v2", defaultStatusContext},
+	}
+
+	for _, test := range tests {
+		if statusCtx, err := h.renamedContext(context.Background(), test.oldName, test.newContext, test.newName); err != nil {
+			t.Errorf("%s Failed: [%s] inputted and error not expected but got: %s", t.Name(), test.oldName, err.Error())
+		} else if statusCtx != test.expected {
+			t.Errorf("%s Failed: [%s] inputted, expected [%s] but got [%s]", t.Name(), test.oldName, test.expected, statusCtx)
+		}
+	}
+}
+
+// TestTombstoneContexts will test Handler.tombstoneContexts()
+func TestTombstoneContexts(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:       "us-east-1",
+		ContextPrefixes: stringMap{"payments-v2": "team-payments/ci"},
+		PipelineRenames: stringMap{"payments": "payments-v2", "search": "search-v2"},
+	})
+	posted := newTombstoneServer(t, h)
+
+	// Dry run
+	reports, err := h.tombstoneContexts(context.Background(), "", true)
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(posted) != 0 {
+		t.Fatal("nothing should have been posted", posted)
+	} else if len(reports) != 2 || reports[0].Pipeline != "payments" || reports[1].Pipeline != "search" {
+		t.Fatal("reports were not as expected", reports)
+	} else if reports[0].Context != "team-payments/ci/payments" || reports[0].Replacement != "team-payments/ci/payments-v2" {
+		t.Fatal("contexts were not as expected", reports[0])
+	} else if len(reports[0].Commits) != 2 {
+		t.Fatal("commits were not as expected", reports[0].Commits)
+	} else if len(reports[0].ProtectionUpdates) != 1 || reports[0].ProtectionUpdates[0] != "replace team-payments/ci/payments with team-payments/ci/payments-v2" {
+		t.Fatal("protection updates were not as expected", reports[0].ProtectionUpdates)
+	} else if len(reports[1].Commits) != 0 || reports[1].Context != defaultStatusContext {
+		t.Fatal("a shared context should not be retired", reports[1])
+	}
+
+	// Post the tombstones
+	if _, err = h.tombstoneContexts(context.Background(), "payments", false); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(posted) != 2 || posted[tombstoneCommitFix].State != githubStateSuccess {
+		t.Fatal("posted statuses were not as expected", posted)
+	} else if posted[tombstoneCommitFeature].Context != "team-payments/ci/payments" {
+		t.Fatal("context was not as expected", posted[tombstoneCommitFeature].Context)
+	}
+
+	// Unknown pipeline
+	if _, err = h.tombstoneContexts(context.Background(), "billing", true); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestTombstoneCommand will test tombstoneCommand()
+func TestTombstoneCommand(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion:       "us-east-1",
+		ContextPrefixes: stringMap{"payments-v2": "team-payments/ci"},
+		PipelineRenames: stringMap{"payments": "payments-v2"},
+	})
+	newTombstoneServer(t, h)
+
+	var out bytes.Buffer
+	if err := tombstoneCommand([]string{"-dry-run"}, &out, h); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if !strings.Contains(out.String(), "team-payments/ci/payments") {
+		t.Fatal("output was not as expected", out.String())
+	}
+
+	if err := tombstoneCommand([]string{"-pipeline", "billing"}, &out, h); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
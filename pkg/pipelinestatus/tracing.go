@@ -0,0 +1,197 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// X-Ray values (the Lambda runtime creates the segment of the invocation, the calls are its subsegments)
+const (
+	defaultXrayDaemonAddress = "127.0.0.1:2000"
+	xrayContextKey           = "x-amzn-trace-id"
+	xrayDaemonHeader         = `{"format": "json", "version": 1}` + "\n"
+	xrayTraceEnv             = "_X_AMZN_TRACE_ID"
+)
+
+// Per-container connection to the X-Ray daemon, opened by the first subsegment
+var (
+	xrayConn net.Conn
+	xrayMu   sync.Mutex
+)
+
+// tracingDisabledKey is the context key of the events of a handler with TRACING_DISABLED (IE: local and test runs)
+type tracingDisabledKey struct{}
+
+// xraySubsegment is a subsegment document sent to the X-Ray daemon
+type xraySubsegment struct {
+	AWS       *xrayAWS   `json:"aws,omitempty"`
+	Cause     *xrayCause `json:"cause,omitempty"`
+	EndTime   float64    `json:"end_time"`
+	Error     bool       `json:"error,omitempty"`
+	Fault     bool       `json:"fault,omitempty"`
+	HTTP      *xrayHTTP  `json:"http,omitempty"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	ParentID  string     `json:"parent_id"`
+	StartTime float64    `json:"start_time"`
+	TraceID   string     `json:"trace_id"`
+	Type      string     `json:"type"`
+}
+
+// xrayAWS is the AWS call of a subsegment
+type xrayAWS struct {
+	Operation string `json:"operation"`
+	Region    string `json:"region,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// xrayCause is the error of a subsegment
+type xrayCause struct {
+	Exceptions []xrayException `json:"exceptions"`
+}
+
+// xrayException is an error message of a subsegment
+type xrayException struct {
+	Message string `json:"message"`
+}
+
+// xrayHTTP is the HTTP request and response of a subsegment
+type xrayHTTP struct {
+	Request  xrayHTTPRequest   `json:"request"`
+	Response *xrayHTTPResponse `json:"response,omitempty"`
+}
+
+// xrayHTTPRequest is the request of a subsegment
+type xrayHTTPRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// xrayHTTPResponse is the response of a subsegment
+type xrayHTTPResponse struct {
+	Status int `json:"status"`
+}
+
+// withTracing will return a context enabling (or disabling) the X-Ray subsegments of its calls (TRACING_DISABLED)
+func withTracing(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, tracingDisabledKey{}, !enabled)
+}
+
+// traceParent will return the trace and the parent segment of the invocation, empty if the invocation is not
+// sampled (or not traced at all, IE: local runs)
+func traceParent(ctx context.Context) (traceID, parentID string) {
+	header, _ := ctx.Value(xrayContextKey).(string)
+	if len(header) == 0 {
+		header = os.Getenv(xrayTraceEnv)
+	}
+	sampled := false
+	for _, part := range strings.Split(header, ";") {
+		if i := strings.Index(part, "="); i > 0 {
+			switch key, value := strings.TrimSpace(part[:i]), part[i+1:]; key {
+			case "Root":
+				traceID = value
+			case "Parent":
+				parentID = value
+			case "Sampled":
+				sampled = value == "1"
+			}
+		}
+	}
+	if !sampled || len(traceID) == 0 || len(parentID) == 0 {
+		return "", ""
+	}
+	return
+}
+
+// newSubsegment will create a subsegment of the invocation (nil if tracing is disabled or the invocation is not sampled)
+func newSubsegment(ctx context.Context, name, namespace string, start, end time.Time, statusCode int, err error) *xraySubsegment {
+	if disabled, _ := ctx.Value(tracingDisabledKey{}).(bool); disabled {
+		return nil
+	}
+	traceID, parentID := traceParent(ctx)
+	if len(traceID) == 0 {
+		return nil
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	segment := &xraySubsegment{
+		EndTime:   float64(end.UnixNano()) / float64(time.Second),
+		Error:     statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError,
+		Fault:     statusCode >= http.StatusInternalServerError,
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Namespace: namespace,
+		ParentID:  parentID,
+		StartTime: float64(start.UnixNano()) / float64(time.Second),
+		TraceID:   traceID,
+		Type:      "subsegment",
+	}
+	if err != nil {
+		segment.Cause = &xrayCause{Exceptions: []xrayException{{Message: redactLog(ctx, err.Error())}}}
+		segment.Fault = segment.Fault || !segment.Error
+	}
+	return segment
+}
+
+// sendSubsegment will send a subsegment to the X-Ray daemon (UDP, failures are ignored)
+func sendSubsegment(segment *xraySubsegment) {
+	if segment == nil {
+		return
+	}
+	b, err := json.Marshal(segment)
+	if err != nil {
+		return
+	}
+	xrayMu.Lock()
+	defer xrayMu.Unlock()
+	if xrayConn == nil {
+		address := os.Getenv("AWS_XRAY_DAEMON_ADDRESS")
+		if len(address) == 0 {
+			address = defaultXrayDaemonAddress
+		}
+		if xrayConn, err = net.Dial("udp", address); err != nil {
+			xrayConn = nil
+			return
+		}
+	}
+	_, _ = xrayConn.Write(append([]byte(xrayDaemonHeader), b...))
+}
+
+// traceHTTPCall will send the subsegment of an HTTP call to a remote service (IE: the GitHub API)
+func traceHTTPCall(req *http.Request, start time.Time, statusCode int, err error) {
+	segment := newSubsegment(req.Context(), req.URL.Host, "remote", start, time.Now(), statusCode, err)
+	if segment == nil {
+		return
+	}
+	segment.HTTP = &xrayHTTP{Request: xrayHTTPRequest{Method: req.Method, URL: req.URL.Scheme + "://" + req.URL.Host + req.URL.Path}}
+	if statusCode > 0 {
+		segment.HTTP.Response = &xrayHTTPResponse{Status: statusCode}
+	}
+	sendSubsegment(segment)
+}
+
+// traceAWSRequest is a handler of the AWS session sending the subsegment of each AWS call (IE: KMS Decrypt)
+func traceAWSRequest(r *request.Request) {
+	var statusCode int
+	if r.HTTPResponse != nil {
+		statusCode = r.HTTPResponse.StatusCode
+	}
+	segment := newSubsegment(r.Context(), r.ClientInfo.ServiceName, "aws", r.Time, time.Now(), statusCode, r.Error)
+	if segment == nil {
+		return
+	}
+	segment.AWS = &xrayAWS{Operation: r.Operation.Name, Region: aws.StringValue(r.Config.Region), RequestID: r.RequestID}
+	sendSubsegment(segment)
+}
@@ -0,0 +1,127 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenXray will replace the X-Ray daemon with a UDP listener until the test ends
+func listenXray(t *testing.T) <-chan xraySubsegment {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	_ = os.Setenv("AWS_XRAY_DAEMON_ADDRESS", conn.LocalAddr().String())
+	xrayMu.Lock()
+	xrayConn = nil
+	xrayMu.Unlock()
+	t.Cleanup(func() {
+		_ = os.Unsetenv("AWS_XRAY_DAEMON_ADDRESS")
+		_ = conn.Close()
+		xrayMu.Lock()
+		xrayConn = nil
+		xrayMu.Unlock()
+	})
+
+	segments := make(chan xraySubsegment, 10)
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, _, readErr := conn.ReadFrom(buf)
+			if readErr != nil {
+				return
+			}
+			parts := strings.SplitN(string(buf[:n]), "\n", 2)
+			var segment xraySubsegment
+			if len(parts) == 2 && parts[0]+"\n" == xrayDaemonHeader && json.Unmarshal([]byte(parts[1]), &segment) == nil {
+				segments <- segment
+			}
+		}
+	}()
+	return segments
+}
+
+// TestTraceParent will test traceParent()
+func TestTraceParent(t *testing.T) {
+	_ = os.Unsetenv(xrayTraceEnv)
+	defer func() {
+		_ = os.Unsetenv(xrayTraceEnv)
+	}()
+
+	var tests = []struct {
+		header         string
+		expectedTrace  string
+		expectedParent string
+	}{
+		{"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", "1-5759e988-bd862e3fe1be46a994272793", "53995c3f42cd8ad8"},
+		{"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0", "", ""},
+		{"Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", "", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		ctx := context.WithValue(context.Background(), xrayContextKey, test.header)
+		if traceID, parentID := traceParent(ctx); traceID != test.expectedTrace || parentID != test.expectedParent {
+			t.Errorf("%s Failed: [%s] inputted and [%s] [%s] expected, received: [%s] [%s]", t.Name(), test.header, test.expectedTrace, test.expectedParent, traceID, parentID)
+		}
+	}
+
+	// The runtime sets the header of the invocation in the environment
+	_ = os.Setenv(xrayTraceEnv, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	if traceID, _ := traceParent(context.Background()); traceID != "1-5759e988-bd862e3fe1be46a994272793" {
+		t.Fatal("trace was not as expected", traceID)
+	}
+}
+
+// TestTraceHTTPCall will test traceHTTPCall() sending the subsegment of a GitHub call
+func TestTraceHTTPCall(t *testing.T) {
+	segments := listenXray(t)
+	_ = os.Setenv(xrayTraceEnv, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	defer func() {
+		_ = os.Unsetenv(xrayTraceEnv)
+	}()
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e?token=1", nil)
+	traceHTTPCall(req, time.Now().Add(-time.Second), http.StatusUnprocessableEntity, nil)
+
+	select {
+	case segment := <-segments:
+		if segment.Name != "api.github.com" || segment.Namespace != "remote" || segment.TraceID != "1-5759e988-bd862e3fe1be46a994272793" ||
+			segment.ParentID != "53995c3f42cd8ad8" || len(segment.ID) != 16 || segment.Type != "subsegment" {
+			t.Fatal("subsegment was not as expected", segment)
+		} else if !segment.Error || segment.Fault || segment.EndTime-segment.StartTime < 1 {
+			t.Fatal("subsegment result was not as expected", segment)
+		} else if segment.HTTP == nil || segment.HTTP.Request.URL != "https://api.github.com/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e" ||
+			segment.HTTP.Response == nil || segment.HTTP.Response.Status != http.StatusUnprocessableEntity {
+			t.Fatal("subsegment request was not as expected", segment.HTTP)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subsegment was not sent")
+	}
+
+	// Failed calls are faults
+	traceHTTPCall(req, time.Now(), 0, errors.New("connection refused"))
+	select {
+	case segment := <-segments:
+		if !segment.Fault || segment.Cause == nil || segment.Cause.Exceptions[0].Message != "connection refused" || segment.HTTP.Response != nil {
+			t.Fatal("subsegment was not as expected", segment)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subsegment was not sent")
+	}
+
+	// Disabled (TRACING_DISABLED)
+	traceHTTPCall(req.WithContext(withTracing(req.Context(), false)), time.Now(), http.StatusOK, nil)
+	select {
+	case segment := <-segments:
+		t.Fatal("subsegment should not have been sent", segment)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,212 @@
+package pipelinestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Stage transition events (CloudTrail API calls delivered by EventBridge)
+const (
+	eventDisableStageTransition = "DisableStageTransition"
+	eventEnableStageTransition  = "EnableStageTransition"
+	transitionContextSuffix     = "/deployment-window/"
+)
+
+// transitionParameters are the request parameters of a stage transition change
+type transitionParameters struct {
+	PipelineName   string `json:"pipelineName"`
+	Reason         string `json:"reason"`
+	StageName      string `json:"stageName"`
+	TransitionType string `json:"transitionType"`
+}
+
+// transitionWindow is the commit a closed deployment window was posted on, the window is opened on the same
+// commit (later executions can start while the transition is disabled)
+type transitionWindow struct {
+	Commit      string `dynamodbav:"commit"`
+	RevisionURL string `dynamodbav:"revision_url"`
+	Transition  string `dynamodbav:"transition"` // pipeline/stage
+}
+
+// isTransitionEvent will return true if the event disables or enables a stage transition
+func isTransitionEvent(ev Event) bool {
+	return ev.Detail != nil &&
+		(ev.Detail.EventName == eventDisableStageTransition || ev.Detail.EventName == eventEnableStageTransition)
+}
+
+// validateTransitionEvent will check the transition event for the required parameters
+func validateTransitionEvent(ev Event) error {
+	if ev.Detail.RequestParameters == nil {
+		return errors.New("missing event param requestParameters")
+	} else if len(ev.Detail.RequestParameters.PipelineName) == 0 {
+		return errors.New("missing event param requestParameters.pipelineName")
+	} else if len(ev.Detail.RequestParameters.StageName) == 0 {
+		return errors.New("missing event param requestParameters.stageName")
+	}
+	return nil
+}
+
+// getLatestCommit will return the commit of the most recent execution of the pipeline
+func (h *Handler) getLatestCommit(ctx context.Context, pipelineName string) (commit string, revisionURL *url.URL, err error) {
+	var output *codepipeline.ListPipelineExecutionsOutput
+	if output, err = h.deps.CodePipeline.ListPipelineExecutions(ctx, &codepipeline.ListPipelineExecutionsInput{
+		MaxResults:   aws.Int32(1),
+		PipelineName: aws.String(pipelineName),
+	}); err != nil {
+		return
+	} else if output == nil || len(output.PipelineExecutionSummaries) == 0 {
+		err = fmt.Errorf("no executions found for pipeline: %s", pipelineName)
+		return
+	}
+
+	var executionOutput *codepipeline.GetPipelineExecutionOutput
+	if executionOutput, err = getExecutionOutput(
+		ctx, pipelineName, aws.StringValue(output.PipelineExecutionSummaries[0].PipelineExecutionId), h.deps.CodePipeline,
+	); err != nil {
+		return
+	} else if commit, _, revisionURL, err = getCommitFromExecution(ctx, executionOutput, h.primaryArtifact(pipelineName), h.forgeHosts(),
+		h.cfg.CodeCommitMirrors); err == nil && revisionURL == nil {
+		err = missingArtifactError(executionOutput, h.primaryArtifact(pipelineName))
+	}
+	return
+}
+
+// storeTransitionWindow will store the commit the deployment window of the stage was closed on
+func storeTransitionWindow(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table string, window transitionWindow) (err error) {
+	var item map[string]*dynamodb.AttributeValue
+	if item, err = dynamodbattribute.MarshalMap(window); err != nil {
+		return
+	}
+	_, err = dynamoSvc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(table),
+	})
+	return
+}
+
+// getTransitionWindow will return the commit the deployment window of the stage was closed on (nil if unknown)
+func getTransitionWindow(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, transition string) (window *transitionWindow, err error) {
+	var output *dynamodb.GetItemOutput
+	if output, err = dynamoSvc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"transition": {S: aws.String(transition)},
+		},
+		TableName: aws.String(table),
+	}); err != nil || output == nil || len(output.Item) == 0 {
+		return
+	}
+	var stored transitionWindow
+	if err = dynamodbattribute.UnmarshalMap(output.Item, &stored); err != nil || len(stored.Commit) == 0 {
+		return
+	}
+	return &stored, nil
+}
+
+// windowCommit will return the commit the status of the window is posted on: a closed window is posted on the latest
+// commit of the pipeline (and recorded in TRANSITION_TABLE), an open window on the commit it was closed on
+func (h *Handler) windowCommit(ctx context.Context, parameters *transitionParameters, enabled bool) (commit string, revisionURL *url.URL, err error) {
+	transition := parameters.PipelineName + "/" + parameters.StageName
+	if enabled && len(h.cfg.TransitionTable) > 0 {
+		var window *transitionWindow
+		if window, err = getTransitionWindow(ctx, h.deps.DynamoDB, h.cfg.TransitionTable, transition); err != nil {
+			return
+		} else if window != nil {
+			revisionURL, err = url.Parse(window.RevisionURL)
+			return window.Commit, revisionURL, err
+		}
+	}
+
+	// Find the commit that is waiting for the stage
+	if commit, revisionURL, err = h.getLatestCommit(ctx, parameters.PipelineName); err != nil || enabled ||
+		len(h.cfg.TransitionTable) == 0 {
+		return
+	}
+	err = storeTransitionWindow(ctx, h.deps.DynamoDB, h.cfg.TransitionTable, transitionWindow{
+		Commit: commit, RevisionURL: revisionURL.String(), Transition: transition,
+	})
+	return
+}
+
+// transitionStatus will return the status of a stage transition change, a closed window is only pending
+// when the commit is recorded to open it again (without TRANSITION_TABLE the window is opened on the latest
+// commit, so the closed window must not block the merges of the commit it was posted on)
+func transitionStatus(parameters *transitionParameters, enabled, recorded bool) (state, description string) {
+	if enabled {
+		return githubStateSuccess, "deployment window open: " + parameters.StageName
+	}
+	reason := parameters.Reason
+	if len(reason) == 0 {
+		reason = "transition to " + parameters.StageName + " disabled"
+	}
+	if !recorded {
+		return githubStateSuccess, "deployment window closed: " + reason
+	}
+	return githubStatePending, "deployment window closed: " + reason
+}
+
+// processTransitionEvent will reflect a disabled stage transition on the latest commit of the pipeline (and the
+// re-enabled transition on the same commit), so developers know why the commit is not progressing
+func (h *Handler) processTransitionEvent(ctx context.Context, ev Event) error {
+	if err := validateTransitionEvent(ev); err != nil {
+		return err
+	}
+	parameters := ev.Detail.RequestParameters
+	logf(ctx, "Incoming Transition Details: %+v", parameters)
+
+	// Use the configuration of the account that sent the event (and the role of the account and the region
+	// of the pipeline)
+	scoped, err := h.forExecution(ctx, ev.Account, ev.Region, parameters.PipelineName)
+	if err != nil {
+		return err
+	}
+	return scoped.postTransitionStatus(ctx, ev)
+}
+
+// postTransitionStatus will post the status of a stage transition window with the configuration of its account,
+// region and pipeline
+func (h *Handler) postTransitionStatus(ctx context.Context, ev Event) error {
+	parameters := ev.Detail.RequestParameters
+
+	// Muted pipelines are acknowledged without posting a status
+	if h.isMuted(ctx, parameters.PipelineName) {
+		return nil
+	}
+
+	// Find the commit of the window
+	enabled := ev.Detail.EventName == eventEnableStageTransition
+	commit, revisionURL, err := h.windowCommit(ctx, parameters, enabled)
+	if err != nil {
+		reportArtifactError(ctx, parameters.PipelineName, "", err)
+		return err
+	}
+	owner, repo := h.pipelineRepository(parameters.PipelineName, revisionURL)
+	ctx = withLogCommit(ctx, owner, repo, commit)
+
+	// The window has its own context per stage (the pipeline status is left alone)
+	var statusCtx string
+	if statusCtx, err = h.statusContext(ctx, parameters.PipelineName, ""); err != nil {
+		return err
+	}
+	state, description := transitionStatus(parameters, enabled, len(h.cfg.TransitionTable) > 0)
+
+	// Post the status of the window
+	return h.postStatus(ctx, parameters.PipelineName, revisionURL, StatusUpdate{
+		Commit:      commit,
+		Context:     statusCtx + transitionContextSuffix + parameters.StageName,
+		Description: joinDescription(description),
+		Owner:       owner,
+		Repo:        repo,
+		State:       state,
+		TargetURL: consoleURL(h.eventPartition(ev), h.cfg.AWSRegion, fmt.Sprintf(
+			"/codesuite/codepipeline/pipelines/%s/view", parameters.PipelineName)),
+	})
+}
@@ -0,0 +1,146 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ListPipelineExecutions is a mock request for codepipeline
+func (m *mockCodePipelineClient) ListPipelineExecutions(_ context.Context, input *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	if aws.StringValue(input.PipelineName) == "no-executions" {
+		return &codepipeline.ListPipelineExecutionsOutput{}, nil
+	}
+	return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{PipelineExecutionId: aws.String("12345678")},
+	}}, nil
+}
+
+// newTransitionEvent will create a stage transition change event
+func newTransitionEvent(eventName, pipelineName, reason string) Event {
+	return Event{Detail: &Detail{
+		EventName: eventName,
+		RequestParameters: &transitionParameters{
+			PipelineName:   pipelineName,
+			Reason:         reason,
+			StageName:      "Production",
+			TransitionType: "Inbound",
+		},
+	}}
+}
+
+// TestValidateTransitionEvent will test validateEvent() for transition events
+func TestValidateTransitionEvent(t *testing.T) {
+	if err := validateEvent(newTransitionEvent(eventDisableStageTransition, "some-pipeline", "")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = validateEvent(newTransitionEvent(eventDisableStageTransition, "", "")); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err = validateEvent(Event{Detail: &Detail{EventName: eventEnableStageTransition}}); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestTransitionStatus will test transitionStatus()
+func TestTransitionStatus(t *testing.T) {
+	var tests = []struct {
+		reason              string
+		enabled             bool
+		recorded            bool
+		expectedState       string
+		expectedDescription string
+	}{
+		{"prod freeze", false, true, githubStatePending, "deployment window closed: prod freeze"},
+		{"", false, true, githubStatePending, "deployment window closed: transition to Production disabled"},
+		{"prod freeze", false, false, githubStateSuccess, "deployment window closed: prod freeze"},
+		{"", true, true, githubStateSuccess, "deployment window open: Production"},
+		{"", true, false, githubStateSuccess, "deployment window open: Production"},
+	}
+
+	for _, test := range tests {
+		parameters := &transitionParameters{Reason: test.reason, StageName: "Production"}
+		if state, description := transitionStatus(parameters, test.enabled, test.recorded); state != test.expectedState {
+			t.Errorf("%s Failed: [%s] [%v] [%v] inputted, expected state [%s] but got [%s]", t.Name(), test.reason, test.enabled, test.recorded, test.expectedState, state)
+		} else if description != test.expectedDescription {
+			t.Errorf("%s Failed: [%s] [%v] [%v] inputted, expected [%s] but got [%s]", t.Name(), test.reason, test.enabled, test.recorded, test.expectedDescription, description)
+		}
+	}
+}
+
+// TestHandlerProcessTransitionEvent will test ProcessEvent() for transition events
+func TestHandlerProcessTransitionEvent(t *testing.T) {
+	h := newTestHandler(Config{
+		AWSRegion: "us-east-1", GithubAccessToken: "1234567", GithubMaxConcurrency: 1, Stage: stageTesting, TransitionTable: "transitions",
+	})
+
+	var received payload
+	var path string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	// Disabled
+	if err := h.ProcessEvent(newTransitionEvent(eventDisableStageTransition, "some-pipeline", "prod freeze")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path != "/repos/mrz1836/codepipeline-to-github/statuses/25c0c3e61c4db2c2cde8b163b3ad096875c1ce08" {
+		t.Fatal("path was not as expected", path)
+	} else if received.State != githubStatePending || received.Description != "deployment window closed: prod freeze" {
+		t.Fatal("status was not as expected", received)
+	} else if received.Context != defaultStatusContext+"/deployment-window/Production" {
+		t.Fatal("context was not as expected", received.Context)
+	} else if received.TargetURL != "https://us-east-1.console.aws.amazon.com/codesuite/codepipeline/pipelines/some-pipeline/view" {
+		t.Fatal("target url was not as expected", received.TargetURL)
+	}
+
+	// Enabled on the commit the window was closed on (not the latest commit)
+	if err := storeTransitionWindow(context.Background(), h.deps.DynamoDB, "transitions", transitionWindow{
+		Commit:      "1234567890123456789012345678901234567890",
+		RevisionURL: "https://github.com/mrz1836/codepipeline-to-github/commit/1234567890123456789012345678901234567890",
+		Transition:  "some-pipeline/Production",
+	}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	if err := h.ProcessEvent(newTransitionEvent(eventEnableStageTransition, "some-pipeline", "")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if path != "/repos/mrz1836/codepipeline-to-github/statuses/1234567890123456789012345678901234567890" {
+		t.Fatal("path was not as expected", path)
+	} else if received.State != githubStateSuccess {
+		t.Fatal("state was not as expected", received.State)
+	}
+
+	// Without a table the closed window does not block the merges
+	h.cfg.TransitionTable = ""
+	if err := h.ProcessEvent(newTransitionEvent(eventDisableStageTransition, "some-pipeline", "prod freeze")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if received.State != githubStateSuccess || received.Description != "deployment window closed: prod freeze" {
+		t.Fatal("status was not as expected", received)
+	}
+
+	// Muted pipelines
+	h.cfg.MuteTable = "mutes"
+	if err := muteStatuses(context.Background(), h.deps.DynamoDB, "mutes", muteWindow{Pipeline: "some-pipeline", Until: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	path = ""
+	if err := h.ProcessEvent(newTransitionEvent(eventEnableStageTransition, "some-pipeline", "")); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(path) > 0 {
+		t.Fatal("no status should have been posted", path)
+	}
+	h.cfg.MuteTable = ""
+
+	// No executions or no source artifact
+	if err := h.ProcessEvent(newTransitionEvent(eventDisableStageTransition, "no-executions", "")); err == nil {
+		t.Fatal("error should have occurred")
+	} else if err = h.ProcessEvent(newTransitionEvent(eventDisableStageTransition, "bad-artifact-name", "")); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
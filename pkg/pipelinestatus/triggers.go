@@ -0,0 +1,406 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// GitHub webhooks starting the pipelines (the triggers of PIPELINE_CONFIG, INGESTION_MODE=webhook)
+const (
+	branchRefPrefix         = "refs/heads/"
+	triggerMaxPullFilePages = 30 // the files API lists up to 3000 files of a pull request
+	triggerPullFilesPerPage = 100
+	triggerSkipped          = "skipped (no relevant changes)"
+	triggerSkippedRunID     = "skipped" // external id of the neutral check runs of the skipped pipelines
+	webhookEventPullRequest = "pull_request"
+	webhookEventPush        = "push"
+)
+
+// triggerPullRequestActions are the actions of a pull_request webhook that start the pipelines
+var triggerPullRequestActions = map[string]bool{
+	"labeled":     true,
+	"opened":      true,
+	"reopened":    true,
+	"synchronize": true,
+}
+
+// pipelineTrigger is a rule starting the pipeline from the GitHub webhooks, every set condition must match
+// (IE: {branches: [main, release/*], paths: [services/payments], variables: {ENVIRONMENT: staging}})
+type pipelineTrigger struct {
+	Branches  []string          `yaml:"branches" json:"branches,omitempty"`   // patterns of the pushed branch or the base branch of the pull request
+	Events    []string          `yaml:"events" json:"events,omitempty"`       // push or pull_request (both by default)
+	Labels    []string          `yaml:"labels" json:"labels,omitempty"`       // one of the labels of the pull request (pushes never match)
+	Paths     []string          `yaml:"paths" json:"paths,omitempty"`         // patterns of a changed file or one of its directories
+	Variables map[string]string `yaml:"variables" json:"variables,omitempty"` // V2 pipeline variables (${commit}, ${branch} and ${pull_request} are expanded)
+}
+
+// webhookRepository is the repository of a push or pull_request webhook
+type webhookRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// pushEvent is the part of a push webhook used to start the pipelines
+type pushEvent struct {
+	After   string `json:"after"`
+	Before  string `json:"before"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+	Deleted    bool              `json:"deleted"`
+	Ref        string            `json:"ref"`
+	Repository webhookRepository `json:"repository"`
+}
+
+// pullRequestEvent is the part of a pull_request webhook used to start the pipelines
+type pullRequestEvent struct {
+	Action string `json:"action"`
+	Label  struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Number      int `json:"number"`
+	PullRequest struct {
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Merged bool `json:"merged"`
+	} `json:"pull_request"`
+	Repository webhookRepository `json:"repository"`
+}
+
+// pullRequestFile is a changed file of a pull request of the GitHub API
+type pullRequestFile struct {
+	Filename string `json:"filename"`
+}
+
+// webhookChange is a push or an update of a pull request matched against the triggers of the pipelines
+type webhookChange struct {
+	Added       string   // the label added to the pull request (labeled action)
+	Branch      string   // the pushed branch or the base branch of the pull request
+	Closed      bool     // the pull request was closed without merging or the branch was deleted
+	Commit      string   // the pushed commit or the head commit of the pull request
+	Event       string   // push or pull_request
+	HeadBranch  string   // the pushed branch or the head branch of the pull request
+	Labels      []string // the labels of the pull request
+	Paths       []string // the changed files
+	PullRequest int      // the number of the pull request
+	Repository  string   // owner/repo
+}
+
+// validateTrigger will return an error if a pattern or an event of the trigger is invalid
+func validateTrigger(trigger pipelineTrigger) error {
+	for _, event := range trigger.Events {
+		if event != webhookEventPush && event != webhookEventPullRequest {
+			return fmt.Errorf("event must be %s or %s: %s", webhookEventPush, webhookEventPullRequest, event)
+		}
+	}
+	for _, pattern := range append(append([]string{}, trigger.Branches...), trigger.Paths...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s: %s", pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+// matchPattern will return true if the value matches one of the patterns
+func matchPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchChangedPath will return true if a changed file or one of its directories matches one of the patterns
+// (IE: services/payments and services/* match services/payments/main.go)
+func matchChangedPath(patterns, files []string) bool {
+	for _, file := range files {
+		for dir := strings.Trim(file, "/"); dir != "." && len(dir) > 0; dir = path.Dir(dir) {
+			if matchPattern(patterns, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchTrigger will return true if every condition of the trigger matches the change (an added label only matches
+// the triggers requiring it, so labeling a pull request does not start its other pipelines again)
+func matchTrigger(trigger pipelineTrigger, change webhookChange) bool {
+	if len(trigger.Events) > 0 && !matchPattern(trigger.Events, change.Event) {
+		return false
+	} else if len(trigger.Branches) > 0 && !matchPattern(trigger.Branches, change.Branch) {
+		return false
+	} else if len(change.Added) > 0 && !matchPattern(trigger.Labels, change.Added) {
+		return false
+	} else if len(trigger.Paths) > 0 && !matchChangedPath(trigger.Paths, change.Paths) {
+		return false
+	}
+	if len(trigger.Labels) > 0 {
+		for _, label := range change.Labels {
+			if matchPattern(trigger.Labels, label) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// skippedByPaths will return true if a trigger with paths only missed on the changed files (the pipeline would
+// have started for a change of its paths)
+func skippedByPaths(triggers []pipelineTrigger, change webhookChange) bool {
+	for _, trigger := range triggers {
+		if len(trigger.Paths) > 0 {
+			trigger.Paths = nil
+			if matchTrigger(trigger, change) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// triggerVariables will return the V2 pipeline variables of the trigger (sorted, the change is expanded in the values)
+func triggerVariables(trigger pipelineTrigger, change webhookChange) (variables []types.PipelineVariable) {
+	values := map[string]string{
+		"branch":       change.HeadBranch,
+		"commit":       change.Commit,
+		"pull_request": "",
+	}
+	if change.PullRequest > 0 {
+		values["pull_request"] = strconv.Itoa(change.PullRequest)
+	}
+	for name, value := range trigger.Variables {
+		variables = append(variables, types.PipelineVariable{
+			Name:  aws.String(name),
+			Value: aws.String(os.Expand(value, func(key string) string { return values[key] })),
+		})
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return aws.StringValue(variables[i].Name) < aws.StringValue(variables[j].Name)
+	})
+	return
+}
+
+// hasTriggers will return true if a pipeline is started from the GitHub webhooks
+func (h *Handler) hasTriggers() bool {
+	for _, config := range h.pipelines {
+		if len(config.Triggers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookChange will read the change of a push or pull_request webhook, ok is false for the deliveries that start
+// or stop nothing (tags, the other actions of the pull requests and the closures without CLOSED_EXECUTIONS)
+func (h *Handler) webhookChange(ctx context.Context, eventType string, body []byte) (change webhookChange, ok bool, err error) {
+	change.Event = eventType
+	if eventType == webhookEventPush {
+		var ev pushEvent
+		if err = json.Unmarshal(body, &ev); err != nil || !strings.HasPrefix(ev.Ref, branchRefPrefix) {
+			return
+		}
+		change.Branch = strings.TrimPrefix(ev.Ref, branchRefPrefix)
+		change.HeadBranch, change.Repository = change.Branch, ev.Repository.FullName
+		if ev.Deleted {
+			change.Closed, change.Commit = true, ev.Before
+			return change, len(h.cfg.ClosedExecutions) > 0, nil
+		}
+		change.Commit = ev.After
+		for _, commit := range ev.Commits {
+			change.Paths = append(change.Paths, commit.Added...)
+			change.Paths = append(change.Paths, commit.Modified...)
+			change.Paths = append(change.Paths, commit.Removed...)
+		}
+		return change, h.hasTriggers(), nil
+	}
+
+	var ev pullRequestEvent
+	if err = json.Unmarshal(body, &ev); err != nil {
+		return
+	}
+	change.Branch, change.Commit = ev.PullRequest.Base.Ref, ev.PullRequest.Head.SHA
+	change.HeadBranch, change.PullRequest, change.Repository = ev.PullRequest.Head.Ref, ev.Number, ev.Repository.FullName
+	if ev.Action == closedPullRequestAction {
+		change.Closed = !ev.PullRequest.Merged // the executions of a merged head commit may be deploying it
+		return change, change.Closed && len(h.cfg.ClosedExecutions) > 0, nil
+	} else if !triggerPullRequestActions[ev.Action] || !h.hasTriggers() {
+		return
+	}
+	if ev.Action == "labeled" {
+		change.Added = ev.Label.Name
+	}
+	for _, label := range ev.PullRequest.Labels {
+		change.Labels = append(change.Labels, label.Name)
+	}
+
+	// The payload of a pull request has no files
+	for page := 1; page <= triggerMaxPullFilePages; page++ {
+		var files []pullRequestFile
+		if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=%d&page=%d",
+			change.Repository, change.PullRequest, triggerPullFilesPerPage, page), &files); err != nil {
+			return
+		}
+		for _, file := range files {
+			change.Paths = append(change.Paths, file.Filename)
+		}
+		if len(files) < triggerPullFilesPerPage {
+			break
+		}
+	}
+	return change, true, nil
+}
+
+// startTriggeredPipelines will start the pipelines with a trigger matching the change (the first matching trigger
+// of a pipeline sets its variables), pipelines whose source is another repository are skipped and the sources
+// of CodeStar connections are pinned to the commit (GitHub version 1 sources start from the head of their branch).
+// Pipelines whose triggers only missed on their paths are returned as skipped
+func (h *Handler) startTriggeredPipelines(ctx context.Context, change webhookChange, delivery string) (started, skipped []string, err error) {
+	var names []string
+	for name := range h.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var trigger pipelineTrigger
+		matched := false
+		for _, trigger = range h.pipelines[name].Triggers {
+			if matched = matchTrigger(trigger, change); matched {
+				break
+			}
+		}
+		skip := !matched && skippedByPaths(h.pipelines[name].Triggers, change) && !h.pipelines[name].Ignore
+		if !matched && !skip {
+			continue
+		}
+
+		var pipelineHandler *Handler
+		if pipelineHandler, err = h.forPipeline("", name); err != nil {
+			return
+		}
+		var owner, repo string
+		var action types.ActionDeclaration
+		if owner, repo, _, action, err = getSourceAction(ctx, name, pipelineHandler.deps.CodePipeline); err != nil {
+			return
+		} else if !strings.EqualFold(owner+"/"+repo, change.Repository) {
+			logWarnf(ctx, "pipeline %s is not started, its source is another repository: %s/%s", name, owner, repo)
+			continue
+		} else if skip {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		input := &codepipeline.StartPipelineExecutionInput{
+			Name:      aws.String(name),
+			Variables: triggerVariables(trigger, change),
+		}
+		if len(delivery) > 0 {
+			token := sha256.Sum256([]byte(delivery + "/" + name)) // a redelivery does not start the pipeline again
+			input.ClientRequestToken = aws.String(hex.EncodeToString(token[:]))
+		}
+		if aws.StringValue(action.ActionTypeId.Provider) == sourceProviderCodeStar {
+			input.SourceRevisions = []types.SourceRevisionOverride{{
+				ActionName:    action.Name,
+				RevisionType:  types.SourceRevisionTypeCommitId,
+				RevisionValue: aws.String(change.Commit),
+			}}
+		}
+		var output *codepipeline.StartPipelineExecutionOutput
+		if output, err = pipelineHandler.deps.CodePipeline.StartPipelineExecution(ctx, input); err != nil {
+			return
+		} else if output != nil {
+			logf(ctx, "started execution %s of pipeline %s for %s", aws.StringValue(output.PipelineExecutionId), name, change.Commit)
+		}
+		started = append(started, name)
+	}
+	return
+}
+
+// postSkippedStatus will post a "skipped (no relevant changes)" status of the pipeline on the commit so a required
+// context does not wait for an execution that never starts (success, commit statuses have no neutral state, or a
+// neutral check run with the Checks API)
+func (h *Handler) postSkippedStatus(ctx context.Context, change webhookChange, pipelineName string) error {
+	return h.postChangeStatus(ctx, change, pipelineName, "", githubStateSuccess, checkConclusionNeutral, triggerSkipped)
+}
+
+// postChangeStatus will post a status of the pipeline on the commit of the change (or a completed check run with the
+// conclusion), linked to the execution (or to the pipeline without one)
+func (h *Handler) postChangeStatus(ctx context.Context, change webhookChange, pipelineName, executionID, state, conclusion,
+	description string) (err error) {
+	var pipelineARN, statusCtx string
+	if len(h.cfg.ContextPrefixTag) > 0 {
+		var pipelineHandler *Handler
+		if pipelineHandler, err = h.forPipeline("", pipelineName); err != nil {
+			return
+		} else if pipelineARN, err = getPipelineARN(ctx, pipelineName, pipelineHandler.deps.CodePipeline); err != nil {
+			return
+		}
+	}
+	if statusCtx, err = h.statusContext(ctx, pipelineName, pipelineARN); err != nil {
+		return
+	}
+	targetURL := consoleURL(configPartition(h.cfg), h.cfg.AWSRegion, fmt.Sprintf(
+		"/codesuite/codepipeline/pipelines/%s/view", pipelineName))
+	externalID := triggerSkippedRunID
+	if len(executionID) > 0 {
+		externalID = executionID
+		targetURL = consoleURL(configPartition(h.cfg), h.cfg.AWSRegion, fmt.Sprintf(
+			"/codesuite/codepipeline/pipelines/%s/executions/%s", pipelineName, executionID))
+	}
+	owner, repo, _ := strings.Cut(change.Repository, "/")
+
+	if h.cfg.UseChecksAPI {
+		var release func()
+		if release, err = h.acquireGithubWrite(ctx); err != nil {
+			return
+		}
+		defer release()
+		return h.postCheckRun(ctx, owner, repo, checkRun{
+			Conclusion: conclusion,
+			DetailsURL: targetURL,
+			ExternalID: externalID,
+			HeadSHA:    change.Commit,
+			Name:       statusCtx,
+			Output:     &checkRunOutput{Summary: description, Title: description},
+			Status:     checkStatusCompleted,
+		})
+	}
+	var revisionURL *url.URL
+	if revisionURL, err = url.Parse(fmt.Sprintf("https://%s/%s/commit/%s", h.githubWebHost(), change.Repository, change.Commit)); err != nil {
+		return
+	}
+	return h.postStatus(ctx, pipelineName, revisionURL, StatusUpdate{
+		Commit:      change.Commit,
+		Context:     statusCtx,
+		Description: description,
+		Owner:       owner,
+		Repo:        repo,
+		State:       state,
+		TargetURL:   targetURL,
+	})
+}
@@ -0,0 +1,206 @@
+package pipelinestatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockTriggerPipelineClient records the started executions
+type mockTriggerPipelineClient struct {
+	mockCodePipelineClient
+	started []*codepipeline.StartPipelineExecutionInput
+}
+
+// StartPipelineExecution is a mock request for codepipeline
+func (m *mockTriggerPipelineClient) StartPipelineExecution(_ context.Context, input *codepipeline.StartPipelineExecutionInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.StartPipelineExecutionOutput, error) {
+	m.started = append(m.started, input)
+	return &codepipeline.StartPipelineExecutionOutput{PipelineExecutionId: aws.String("1")}, nil
+}
+
+// TestMatchTrigger will test matchTrigger()
+func TestMatchTrigger(t *testing.T) {
+	t.Parallel()
+
+	push := webhookChange{Branch: "main", Event: webhookEventPush, Paths: []string{"services/payments/main.go", "README.md"}}
+	pull := webhookChange{Branch: "main", Event: webhookEventPullRequest, Labels: []string{"deploy"}, Paths: []string{"web/index.html"}}
+	labeled := pull
+	labeled.Added = "bug"
+
+	var tests = []struct {
+		name     string
+		trigger  pipelineTrigger
+		change   webhookChange
+		expected bool
+	}{
+		{"any change", pipelineTrigger{}, push, true},
+		{"branch", pipelineTrigger{Branches: []string{"release/*", "main"}}, push, true},
+		{"other branch", pipelineTrigger{Branches: []string{"release/*"}}, push, false},
+		{"event", pipelineTrigger{Events: []string{webhookEventPullRequest}}, pull, true},
+		{"other event", pipelineTrigger{Events: []string{webhookEventPullRequest}}, push, false},
+		{"directory", pipelineTrigger{Paths: []string{"services/payments"}}, push, true},
+		{"directory pattern", pipelineTrigger{Paths: []string{"services/*"}}, push, true},
+		{"file pattern", pipelineTrigger{Paths: []string{"*.md"}}, push, true},
+		{"other paths", pipelineTrigger{Paths: []string{"services/search"}}, push, false},
+		{"label", pipelineTrigger{Labels: []string{"deploy"}}, pull, true},
+		{"other label", pipelineTrigger{Labels: []string{"preview"}}, pull, false},
+		{"label of a push", pipelineTrigger{Labels: []string{"deploy"}}, push, false},
+		{"added label", pipelineTrigger{Labels: []string{"bug"}}, labeled, false},
+		{"unrelated added label", pipelineTrigger{Paths: []string{"web"}}, labeled, false},
+	}
+
+	for _, test := range tests {
+		if output := matchTrigger(test.trigger, test.change); output != test.expected {
+			t.Errorf("%s Failed: [%s] inputted and [%t] expected, but got [%t]", t.Name(), test.name, test.expected, output)
+		}
+	}
+}
+
+// TestTriggerVariables will test triggerVariables()
+func TestTriggerVariables(t *testing.T) {
+	t.Parallel()
+
+	variables := triggerVariables(pipelineTrigger{Variables: map[string]string{
+		"PREVIEW":     "pr-${pull_request}",
+		"ENVIRONMENT": "staging",
+		"REVISION":    "${branch}@${commit}",
+	}}, webhookChange{Commit: "25c0c3e", HeadBranch: "feature", PullRequest: 12})
+	if output := mustJSON(variables); output != `[{"Name":"ENVIRONMENT","Value":"staging"},{"Name":"PREVIEW","Value":"pr-12"},`+
+		`{"Name":"REVISION","Value":"feature@25c0c3e"}]` {
+		t.Fatal("variables were not as expected", output)
+	}
+}
+
+// TestProcessWebhookTriggers will test Handler.ProcessWebhook() starting the pipelines of the pushes and the pull requests
+func TestProcessWebhookTriggers(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567", GithubMaxConcurrency: 1, GithubWebhookSecret: "secret", Stage: stageTesting})
+	mockPipeline := &mockTriggerPipelineClient{}
+	h.deps.CodePipeline = mockPipeline
+	h.pipelines = pipelineConfigs{
+		"codestar-pipeline": {Triggers: []pipelineTrigger{
+			{Labels: []string{"deploy"}, Variables: map[string]string{"ENVIRONMENT": "pr-${pull_request}"}},
+			{Branches: []string{"main"}, Paths: []string{"services/*"}, Variables: map[string]string{"ENVIRONMENT": "staging"}},
+		}},
+		"docs":     {Triggers: []pipelineTrigger{{Events: []string{webhookEventPush}, Paths: []string{"docs"}}}},
+		"payments": {Ignore: true},
+	}
+
+	var paths []string
+	var posted []payload
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.String())
+		if r.Method == http.MethodPost {
+			var received payload
+			_ = json.NewDecoder(r.Body).Decode(&received)
+			posted = append(posted, received)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"filename":"services/payments/main.go"}]`))
+	})
+
+	send := func(eventType, delivery, body string) events.APIGatewayV2HTTPResponse {
+		return h.ProcessWebhook(context.Background(), events.APIGatewayV2HTTPRequest{Body: body, Headers: map[string]string{
+			"x-github-delivery":   delivery,
+			"x-github-event":      eventType,
+			"x-hub-signature-256": signWebhook("secret", body),
+		}})
+	}
+
+	// A push to main changing a service and the docs starts both pipelines, pinned to the commit for CodeStar sources
+	body := `{"ref":"refs/heads/main","after":"25c0c3e","repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"commits":[{"added":["docs/usage.md"]},{"modified":["services/payments/main.go"]}]}`
+	if response := send(webhookEventPush, "72d3162e", body); response.StatusCode != http.StatusOK || response.Body != "started 2 pipelines, skipped 0" {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.started) != 2 {
+		t.Fatal("started executions were not as expected", mockPipeline.started)
+	} else if started := mockPipeline.started[0]; aws.StringValue(started.Name) != "codestar-pipeline" ||
+		mustJSON(started.Variables) != `[{"Name":"ENVIRONMENT","Value":"staging"}]` || len(started.SourceRevisions) != 1 ||
+		aws.StringValue(started.SourceRevisions[0].ActionName) != "Source" || aws.StringValue(started.SourceRevisions[0].RevisionValue) != "25c0c3e" ||
+		len(aws.StringValue(started.ClientRequestToken)) != 64 {
+		t.Fatal("started execution was not as expected", mustJSON(started))
+	} else if started = mockPipeline.started[1]; aws.StringValue(started.Name) != "docs" || len(started.SourceRevisions) != 0 {
+		t.Fatal("started execution was not as expected", mustJSON(started))
+	}
+
+	// A labeled pull request starts the pipeline of the label with the files of the pull request
+	mockPipeline.started = nil
+	body = `{"action":"labeled","number":12,"label":{"name":"deploy"},"repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"pull_request":{"base":{"ref":"main"},"head":{"ref":"feature","sha":"aaa111"},"labels":[{"name":"deploy"}]}}`
+	if response := send(webhookEventPullRequest, "", body); response.StatusCode != http.StatusOK || response.Body != "started 1 pipelines, skipped 0" {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.started) != 1 || mustJSON(mockPipeline.started[0].Variables) != `[{"Name":"ENVIRONMENT","Value":"pr-12"}]` ||
+		mockPipeline.started[0].ClientRequestToken != nil {
+		t.Fatal("started executions were not as expected", mustJSON(mockPipeline.started))
+	} else if len(paths) != 1 || paths[0] != "/repos/mrz1836/codepipeline-to-github/pulls/12/files?per_page=100&page=1" {
+		t.Fatal("paths were not as expected", paths)
+	}
+
+	// A push changing none of the paths skips both pipelines with a success status on their contexts
+	mockPipeline.started, paths = nil, nil
+	body = `{"ref":"refs/heads/main","after":"bbb222","repository":{"full_name":"mrz1836/codepipeline-to-github"},` +
+		`"commits":[{"modified":["README.md"]}]}`
+	if response := send(webhookEventPush, "", body); response.StatusCode != http.StatusOK || response.Body != "started 0 pipelines, skipped 2" {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(mockPipeline.started) != 0 || len(posted) != 2 {
+		t.Fatal("statuses were not as expected", mustJSON(posted))
+	} else if paths[0] != "/repos/mrz1836/codepipeline-to-github/statuses/bbb222" || posted[0].Context != "continuous-integration/codepipeline" ||
+		posted[0].State != githubStateSuccess || posted[0].Description != triggerSkipped {
+		t.Fatal("status was not as expected", paths, mustJSON(posted[0]))
+	}
+
+	// With the Checks API the skipped pipelines get a neutral check run
+	h.cfg.UseChecksAPI = true
+	var runs []checkRun
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var received checkRun
+			_ = json.NewDecoder(r.Body).Decode(&received)
+			runs = append(runs, received)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		_, _ = w.Write([]byte(`{"check_runs":[]}`))
+	})
+	if response := send(webhookEventPush, "", body); response.StatusCode != http.StatusOK {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(runs) != 2 || runs[0].Conclusion != checkConclusionNeutral || runs[0].Status != checkStatusCompleted ||
+		runs[0].HeadSHA != "bbb222" || runs[0].Output.Title != triggerSkipped {
+		t.Fatal("check runs were not as expected", mustJSON(runs))
+	}
+	h.cfg.UseChecksAPI = false
+
+	// Other repositories, deleted branches, tags and closed pull requests start nothing
+	mockPipeline.started = nil
+	var tests = []struct {
+		eventType    string
+		body         string
+		expectedBody string
+	}{
+		{webhookEventPush, `{"ref":"refs/heads/main","after":"25c0c3e","repository":{"full_name":"mrz1836/other"},` +
+			`"commits":[{"added":["docs/usage.md"]}]}`, "started 0 pipelines, skipped 0"},
+		{webhookEventPush, `{"ref":"refs/heads/main","deleted":true}`, "ignored"},
+		{webhookEventPush, `{"ref":"refs/tags/v1.0.0","after":"25c0c3e"}`, "ignored"},
+		{webhookEventPullRequest, `{"action":"closed","number":12}`, "ignored"},
+	}
+	for _, test := range tests {
+		if response := send(test.eventType, "", test.body); response.Body != test.expectedBody {
+			t.Errorf("%s Failed: [%s] inputted and [%s] expected, but got [%s]", t.Name(), test.body, test.expectedBody, response.Body)
+		}
+	}
+	if len(mockPipeline.started) != 0 {
+		t.Fatal("started executions were not as expected", mustJSON(mockPipeline.started))
+	}
+
+	// Nothing is started without triggers
+	h.pipelines = nil
+	if response := send(webhookEventPush, "", body); response.StatusCode != http.StatusAccepted {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	}
+}
@@ -0,0 +1,110 @@
+package pipelinestatus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Usage defaults
+const (
+	actionProviderCodeBuild = "CodeBuild"
+	usagePeriodFormat       = "2006-01"
+)
+
+// usageRecord is the usage of a single pipeline for a period (month)
+type usageRecord struct {
+	CodeBuildSeconds int64  `dynamodbav:"codebuild_seconds" json:"codebuild_seconds"`
+	GithubCalls      int64  `dynamodbav:"github_calls" json:"github_calls"`
+	Invocations      int64  `dynamodbav:"invocations" json:"invocations"`
+	Period           string `dynamodbav:"period" json:"period"`
+	Pipeline         string `dynamodbav:"pipeline" json:"pipeline"`
+}
+
+// finalStates are the execution states that end an execution
+var finalStates = map[string]bool{
+	"CANCELED":   true,
+	"FAILED":     true,
+	"STOPPED":    true,
+	"SUCCEEDED":  true,
+	"SUPERSEDED": true,
+}
+
+// usagePeriod will return the period (month) the usage is recorded under
+func usagePeriod(now time.Time) string {
+	return now.UTC().Format(usagePeriodFormat)
+}
+
+// recordUsage will add an invocation, its GitHub calls and CodeBuild time to the pipeline's usage for the period
+func recordUsage(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, pipelineName string, githubCalls, codeBuildSeconds int64,
+	now time.Time) (err error) {
+	_, err = dynamoSvc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":calls":   {N: aws.String(strconv.FormatInt(githubCalls, 10))},
+			":one":     {N: aws.String("1")},
+			":seconds": {N: aws.String(strconv.FormatInt(codeBuildSeconds, 10))},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"period":   {S: aws.String(usagePeriod(now))},
+			"pipeline": {S: aws.String(pipelineName)},
+		},
+		TableName:        aws.String(table),
+		UpdateExpression: aws.String("ADD invocations :one, github_calls :calls, codebuild_seconds :seconds"),
+	})
+	return
+}
+
+// codeBuildSeconds will return the total time spent in CodeBuild actions for an execution
+func codeBuildSeconds(ctx context.Context, pipelineName, executionID string,
+	pipeline CodePipelineAPI) (seconds int64, err error) {
+	paginator := codepipeline.NewListActionExecutionsPaginator(pipeline, &codepipeline.ListActionExecutionsInput{
+		Filter: &types.ActionExecutionFilter{
+			PipelineExecutionId: aws.String(executionID),
+		},
+		PipelineName: aws.String(pipelineName),
+	})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListActionExecutionsOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, detail := range page.ActionExecutionDetails {
+			if detail.Input == nil || detail.Input.ActionTypeId == nil ||
+				aws.StringValue(detail.Input.ActionTypeId.Provider) != actionProviderCodeBuild ||
+				detail.StartTime == nil || detail.LastUpdateTime == nil {
+				continue
+			}
+			seconds += int64(detail.LastUpdateTime.Sub(*detail.StartTime).Seconds())
+		}
+	}
+	return
+}
+
+// getUsage will return the usage of all pipelines for a period
+func getUsage(ctx context.Context, dynamoSvc dynamodbiface.DynamoDBAPI, table, period string) (records []usageRecord, err error) {
+	var pageErr error
+	if err = dynamoSvc.QueryPagesWithContext(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":period": {S: aws.String(period)},
+		},
+		KeyConditionExpression: aws.String("period = :period"),
+		TableName:              aws.String(table),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var pageRecords []usageRecord
+		if pageErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageRecords); pageErr != nil {
+			return false
+		}
+		records = append(records, pageRecords...)
+		return true
+	}); err == nil {
+		err = pageErr
+	}
+	return
+}
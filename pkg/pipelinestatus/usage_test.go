@@ -0,0 +1,101 @@
+package pipelinestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// TestUsagePeriod will test usagePeriod()
+func TestUsagePeriod(t *testing.T) {
+	t.Parallel()
+
+	if period := usagePeriod(time.Date(2020, 5, 31, 23, 0, 0, 0, time.UTC)); period != "2020-05" {
+		t.Fatal("period was not as expected", period)
+	}
+}
+
+// TestRecordUsage will test recordUsage()
+func TestRecordUsage(t *testing.T) {
+	t.Parallel()
+
+	mockDynamo := &mockDynamoClient{}
+	if err := recordUsage(context.Background(), mockDynamo, "usage", "some-pipeline", 2, 150, time.Now()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = recordUsage(context.Background(), mockDynamo, "", "some-pipeline", 2, 150, time.Now()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestCodeBuildSeconds will test codeBuildSeconds()
+func TestCodeBuildSeconds(t *testing.T) {
+	t.Parallel()
+
+	mockPipeline := &mockCodePipelineClient{}
+	if seconds, err := codeBuildSeconds(context.Background(), "status-fail", "12345", mockPipeline); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if seconds != 150 {
+		t.Fatal("seconds was not as expected", seconds)
+	} else if seconds, _ = codeBuildSeconds(context.Background(), "status-succeed", "12345", mockPipeline); seconds != 0 {
+		t.Fatal("seconds was not as expected", seconds)
+	}
+}
+
+// TestCostsCommand will test costsCommand()
+func TestCostsCommand(t *testing.T) {
+
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGION", "us-east-1")
+	_ = os.Setenv("GITHUB_ACCESS_TOKEN", "1234567")
+	_ = os.Setenv("APPLICATION_STAGE_NAME", "testing")
+	defer os.Clearenv()
+
+	mockDynamo := &mockDynamoClient{item: map[string]*dynamodb.AttributeValue{
+		"codebuild_seconds": {N: aws.String("630")},
+		"github_calls":      {N: aws.String("14")},
+		"invocations":       {N: aws.String("7")},
+		"period":            {S: aws.String("2020-05")},
+		"pipeline":          {S: aws.String("some-pipeline")},
+	}}
+
+	// Missing table
+	if err := costsCommand(nil, &bytes.Buffer{}, mockDynamo); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Bad flag
+	_ = os.Setenv("USAGE_TABLE", "usage")
+	if err := costsCommand([]string{"-unknown"}, &bytes.Buffer{}, mockDynamo); err == nil {
+		t.Fatal("error should have occurred")
+	}
+
+	// Valid report
+	var out bytes.Buffer
+	if err := costsCommand([]string{"-period", "2020-05"}, &out, mockDynamo); err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected a header and one row", out.String())
+	} else if strings.Join(strings.Fields(lines[1]), " ") != "some-pipeline 7 14 10.5" {
+		t.Fatal("row was not as expected", lines[1])
+	}
+
+	// JSON report
+	out.Reset()
+	var report costsReport
+	if err := costsCommand([]string{"-period", "2020-05", "-output", outputJSON}, &out, mockDynamo); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if err = json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal("output was not valid json", err.Error())
+	} else if report.Period != "2020-05" || len(report.Pipelines) != 1 || report.Pipelines[0].GithubCalls != 14 {
+		t.Fatal("report was not as expected", out.String())
+	}
+}
@@ -0,0 +1,25 @@
+package pipelinestatus
+
+import (
+	"context"
+	"fmt"
+)
+
+// commitVerification is the part of the GitHub commit response with the signature verification
+type commitVerification struct {
+	Commit struct {
+		Verification struct {
+			Reason   string `json:"reason"`
+			Verified bool   `json:"verified"`
+		} `json:"verification"`
+	} `json:"commit"`
+}
+
+// getVerification will return whether GitHub verified the signature of the commit (and the reason if not)
+func (h *Handler) getVerification(ctx context.Context, owner, repo, commit string) (verified bool, reason string, err error) {
+	var result commitVerification
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, commit), &result); err != nil {
+		return
+	}
+	return result.Commit.Verification.Verified, result.Commit.Verification.Reason, nil
+}
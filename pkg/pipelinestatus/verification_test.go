@@ -0,0 +1,44 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// TestGetVerification will test getVerification()
+func TestGetVerification(t *testing.T) {
+	h := newTestHandler(Config{RequireVerifiedCommits: true})
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/commits/signed":
+			_, _ = w.Write([]byte(`{"commit":{"verification":{"verified":true,"reason":"valid"}}}`))
+		case "/repos/owner/repo/commits/unsigned":
+			_, _ = w.Write([]byte(`{"commit":{"verification":{"verified":false,"reason":"unsigned"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	var tests = []struct {
+		commit         string
+		expected       bool
+		expectedReason string
+		expectedError  bool
+	}{
+		{"signed", true, "valid", false},
+		{"unsigned", false, "unsigned", false},
+		{"missing", false, "", true},
+	}
+
+	for _, test := range tests {
+		verified, reason, err := h.getVerification(context.Background(), "owner", "repo", test.commit)
+		if err == nil && test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, expected to throw an error, but no error", t.Name(), test.commit)
+		} else if err != nil && !test.expectedError {
+			t.Errorf("%s Failed: [%s] inputted, error occurred [%s]", t.Name(), test.commit, err.Error())
+		} else if verified != test.expected || reason != test.expectedReason {
+			t.Errorf("%s Failed: [%s] inputted, expected [%v %s] but got [%v %s]", t.Name(), test.commit, test.expected, test.expectedReason, verified, reason)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package pipelinestatus
+
+import (
+	"context"
+	"time"
+)
+
+// Warm-up of a new container (WARM_UP)
+const (
+	metricWarmUpDuration = "WarmUpDuration"
+	warmUpTimeout        = 5 * time.Second // within the 10 seconds of the init phase
+)
+
+// warmUp will do the slow work of the first event during the init phase of the container: load the
+// configuration and decrypt (or fetch) the token, verify its permissions and resolve the GitHub API root,
+// which leaves an open HTTPS connection for the first event (failures are retried by the first event)
+func warmUp(ctx context.Context) error {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+	defer cancel()
+
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return err
+	} else if err = h.resolveGithubRoot(ctx); err != nil {
+		return err
+	}
+	printMetric(metricWarmUpDuration, float64(time.Since(started)/time.Millisecond), "Milliseconds", nil, time.Now())
+	logf(ctx, "warmed up in %s", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// resolveGithubRoot will read the root of the GitHub API (the connection stays open in the pool of the client)
+func (h *Handler) resolveGithubRoot(ctx context.Context) error {
+	var root map[string]interface{}
+	return h.githubGet(ctx, "/", &root)
+}
@@ -0,0 +1,59 @@
+package pipelinestatus
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// TestWarmUp will test warmUp() failing without a configuration
+func TestWarmUp(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+
+	if err := warmUp(context.Background()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestResolveGithubRoot will test Handler.resolveGithubRoot()
+func TestResolveGithubRoot(t *testing.T) {
+	h := newTestHandler(Config{GithubAccessToken: "1234567"})
+
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") != "token 1234567" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"current_user_url":"https://api.github.com/user"}`))
+	})
+
+	if err := h.resolveGithubRoot(context.Background()); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if len(paths) != 1 || paths[0] != "/" {
+		t.Fatal("paths were not as expected", paths)
+	}
+
+	// Invalid token
+	h.cfg.GithubAccessToken = "invalid"
+	if err := h.resolveGithubRoot(context.Background()); err == nil {
+		t.Fatal("error should have occurred")
+	}
+}
+
+// TestCachedGithubClient will test cachedGithubClient() creating the client once per settings
+func TestCachedGithubClient(t *testing.T) {
+	client, err := cachedGithubClient(Config{GithubInsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal("error occurred", err.Error())
+	}
+	var again *http.Client
+	if again, err = cachedGithubClient(Config{GithubInsecureSkipVerify: true}); err != nil {
+		t.Fatal("error occurred", err.Error())
+	} else if again != client {
+		t.Fatal("client should have been reused")
+	}
+}
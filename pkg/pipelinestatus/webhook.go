@@ -0,0 +1,283 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// GitHub webhooks received from a function URL or an HTTP API (INGESTION_MODE=webhook)
+const (
+	ingestionModeWebhook             = "webhook"
+	webhookBackfillExecutions        = 100 // recent executions of a pipeline searched for the head commits
+	webhookEventBranchProtectionRule = "branch_protection_rule"
+	webhookEventPing                 = "ping"
+	webhookSignaturePrefix           = "sha256="
+)
+
+// branchProtectionRuleEvent is the part of a branch_protection_rule webhook used for the backfill
+type branchProtectionRuleEvent struct {
+	Action     string `json:"action"`
+	Repository struct {
+		HTMLURL string `json:"html_url"`
+		Name    string `json:"name"`
+		Owner   struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Rule struct {
+		Name                 string   `json:"name"` // branch name pattern (IE: release/*)
+		RequiredStatusChecks []string `json:"required_status_checks"`
+	} `json:"rule"`
+}
+
+// pullRequestHead is the part of an open pull request of the GitHub API used for the backfill
+type pullRequestHead struct {
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// ProcessWebhookEvent is triggered by a GitHub webhook (function URL or HTTP API payload version 2.0)
+func ProcessWebhookEvent(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	h, err := handlerFromEnvironment(ctx)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	defer h.pushMetrics(ctx)
+	return h.ProcessWebhook(ctx, request), nil
+}
+
+// ProcessWebhook will verify the signature of the webhook and handle its event, errors are returned as a 500
+// so GitHub shows the delivery as failed (and it can be redelivered)
+func (h *Handler) ProcessWebhook(ctx context.Context, request events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPResponse {
+	ctx = h.withSettings(ctx)
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return webhookResponse(http.StatusBadRequest, "invalid body")
+		}
+	}
+	if !verifyWebhookSignature(h.cfg.GithubWebhookSecret, body, webhookHeader(request.Headers, "X-Hub-Signature-256")) {
+		logWarnf(ctx, "invalid signature of the webhook delivery: %s", webhookHeader(request.Headers, "X-GitHub-Delivery"))
+		return webhookResponse(http.StatusUnauthorized, "invalid signature")
+	}
+
+	switch eventType := webhookHeader(request.Headers, "X-GitHub-Event"); eventType {
+	case webhookEventPing:
+		return webhookResponse(http.StatusOK, "pong")
+	case webhookEventBranchProtectionRule:
+		var ev branchProtectionRuleEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return webhookResponse(http.StatusBadRequest, "invalid payload")
+		} else if ev.Action != "created" {
+			return webhookResponse(http.StatusAccepted, "ignored")
+		}
+		ctx = withLogCommit(ctx, ev.Repository.Owner.Login, ev.Repository.Name, "")
+		posted, err := h.backfillProtectedContexts(ctx, ev)
+		if err != nil {
+			logErrorf(ctx, "unable to backfill the statuses of the branch protection rule %s: %s", ev.Rule.Name, err.Error())
+			return webhookResponse(http.StatusInternalServerError, "backfill failed")
+		}
+		return webhookResponse(http.StatusOK, fmt.Sprintf("backfilled %d statuses", posted))
+	case webhookEventPullRequest, webhookEventPush:
+		if !h.hasTriggers() && len(h.cfg.ClosedExecutions) == 0 {
+			return webhookResponse(http.StatusAccepted, "ignored")
+		}
+		change, ok, err := h.webhookChange(ctx, eventType, body)
+		if err != nil {
+			logErrorf(ctx, "unable to read the changes of the %s webhook: %s", eventType, err.Error())
+			return webhookResponse(http.StatusInternalServerError, "invalid changes")
+		} else if !ok {
+			return webhookResponse(http.StatusAccepted, "ignored")
+		}
+		owner, repo, _ := strings.Cut(change.Repository, "/")
+		ctx = withLogCommit(ctx, owner, repo, change.Commit)
+		if change.Closed {
+			stopped, err := h.stopClosedExecutions(ctx, change)
+			if err != nil {
+				logErrorf(ctx, "unable to stop the executions of %s: %s", change.Commit, err.Error())
+				return webhookResponse(http.StatusInternalServerError, "stop failed")
+			}
+			return webhookResponse(http.StatusOK, fmt.Sprintf("stopped %d executions", stopped))
+		}
+		started, skipped, err := h.startTriggeredPipelines(ctx, change, webhookHeader(request.Headers, "X-GitHub-Delivery"))
+		if err != nil {
+			logErrorf(ctx, "unable to start the pipelines of %s: %s", change.Commit, err.Error())
+			return webhookResponse(http.StatusInternalServerError, "start failed")
+		}
+		for _, pipelineName := range skipped {
+			if err = h.postSkippedStatus(ctx, change, pipelineName); err != nil {
+				logErrorf(ctx, "unable to post the skipped status of pipeline %s: %s", pipelineName, err.Error())
+				return webhookResponse(http.StatusInternalServerError, "status failed")
+			}
+		}
+		return webhookResponse(http.StatusOK, fmt.Sprintf("started %d pipelines, skipped %d", len(started), len(skipped)))
+	default:
+		return webhookResponse(http.StatusAccepted, "ignored")
+	}
+}
+
+// webhookResponse will return a plain text response to GitHub
+func webhookResponse(statusCode int, body string) events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		Body:       body,
+		Headers:    map[string]string{"Content-Type": "text/plain"},
+		StatusCode: statusCode,
+	}
+}
+
+// webhookHeader will return a header of the request (function URLs and HTTP APIs lowercase the names)
+func webhookHeader(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// verifyWebhookSignature will return true if the body is signed with the secret of the webhook (X-Hub-Signature-256)
+func verifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if len(secret) == 0 || !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, webhookSignaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// backfillProtectedContexts will post the current state of the pipelines whose contexts a new branch protection
+// rule requires onto the head commits of the open pull requests of its branches, so the pull requests are not
+// blocked on statuses "expected" from executions that already ran (head commits without an execution are left alone).
+// The states are mapped like the events (stopped executions are errors, superseded ones are skipped) and the statuses
+// already reported are skipped (DEDUP_TABLE)
+func (h *Handler) backfillProtectedContexts(ctx context.Context, ev branchProtectionRuleEvent) (posted int, err error) {
+	owner, repo := ev.Repository.Owner.Login, ev.Repository.Name
+	if len(ev.Rule.RequiredStatusChecks) == 0 {
+		return
+	}
+
+	// Find the pipelines posting the required contexts
+	required := make(map[string]bool)
+	for _, statusCtx := range ev.Rule.RequiredStatusChecks {
+		required[statusCtx] = true
+	}
+	contexts := make(map[string]string)
+	paginator := codepipeline.NewListPipelinesPaginator(h.deps.CodePipeline, &codepipeline.ListPipelinesInput{})
+	for paginator.HasMorePages() {
+		var page *codepipeline.ListPipelinesOutput
+		if page, err = paginator.NextPage(ctx); err != nil {
+			return
+		}
+		for _, pipeline := range page.Pipelines {
+			name := aws.StringValue(pipeline.Name)
+			var pipelineARN, statusCtx string
+			if len(h.cfg.ContextPrefixTag) > 0 {
+				if pipelineARN, err = getPipelineARN(ctx, name, h.deps.CodePipeline); err != nil {
+					return
+				}
+			}
+			if statusCtx, err = h.statusContext(ctx, name, pipelineARN); err != nil {
+				return
+			} else if required[statusCtx] {
+				contexts[name] = statusCtx
+			}
+		}
+	}
+	if len(contexts) == 0 {
+		return
+	}
+
+	// Find the head commits of the open pull requests of the protected branches
+	var pulls []pullRequestHead
+	if err = h.githubGet(ctx, fmt.Sprintf("/repos/%s/%s/pulls?state=open&per_page=100", owner, repo), &pulls); err != nil {
+		return
+	}
+	heads := make(map[string]bool)
+	for _, pull := range pulls {
+		if matched, _ := path.Match(ev.Rule.Name, pull.Base.Ref); matched {
+			heads[pull.Head.SHA] = true
+		}
+	}
+	if len(heads) == 0 {
+		return
+	}
+
+	// Post the state of the latest execution of each pipeline for each head commit
+	var revisionURL *url.URL
+	if revisionURL, err = url.Parse(ev.Repository.HTMLURL); err != nil {
+		return
+	}
+	for pipelineName, statusCtx := range contexts {
+		var output *codepipeline.ListPipelineExecutionsOutput
+		if output, err = h.deps.CodePipeline.ListPipelineExecutions(ctx, &codepipeline.ListPipelineExecutionsInput{
+			MaxResults:   aws.Int32(webhookBackfillExecutions),
+			PipelineName: aws.String(pipelineName),
+		}); err != nil {
+			return
+		} else if output == nil {
+			continue
+		}
+		synced := make(map[string]bool)
+		for _, execution := range output.PipelineExecutionSummaries {
+			eventState := executionEventStates[execution.Status]
+			state, ok := stageStates[eventState]
+			if !ok {
+				continue
+			}
+			executionID := aws.StringValue(execution.PipelineExecutionId)
+			for _, revision := range execution.SourceRevisions {
+				commit := aws.StringValue(revision.RevisionId)
+				if !heads[commit] || synced[commit] {
+					continue
+				}
+				synced[commit] = true
+				first, forget := h.firstReport(ctx, Event{Detail: &Detail{
+					ExecutionID: executionID,
+					Pipeline:    pipelineName,
+					State:       eventState,
+				}}, statusCtx)
+				if !first {
+					continue
+				}
+				if err = h.postStatus(ctx, pipelineName, revisionURL, StatusUpdate{
+					Commit:      commit,
+					Context:     statusCtx,
+					Description: joinDescription("backfilled: " + strings.ToLower(eventState)),
+					Owner:       owner,
+					Repo:        repo,
+					State:       state,
+					TargetURL: consoleURL(configPartition(h.cfg), h.cfg.AWSRegion, fmt.Sprintf(
+						"/codesuite/codepipeline/pipelines/%s/executions/%s", pipelineName, executionID)),
+				}); err != nil {
+					forget()
+					return
+				}
+				posted++
+			}
+		}
+	}
+	logf(ctx, "backfilled %d statuses of the branch protection rule %s", posted, ev.Rule.Name)
+	return
+}
@@ -0,0 +1,145 @@
+package pipelinestatus
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// mockWebhookCodePipelineClient lists the pipelines and their executions of the backfill
+type mockWebhookCodePipelineClient struct {
+	mockCodePipelineClient
+}
+
+// ListPipelines is a mock request for codepipeline
+func (m *mockWebhookCodePipelineClient) ListPipelines(_ context.Context, input *codepipeline.ListPipelinesInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelinesOutput, error) {
+	return &codepipeline.ListPipelinesOutput{Pipelines: []types.PipelineSummary{
+		{Name: aws.String("web")},
+		{Name: aws.String("docs")},
+	}}, nil
+}
+
+// ListPipelineExecutions is a mock request for codepipeline (newest first, the head commit ran three times)
+func (m *mockWebhookCodePipelineClient) ListPipelineExecutions(_ context.Context, input *codepipeline.ListPipelineExecutionsInput,
+	_ ...func(*codepipeline.Options)) (*codepipeline.ListPipelineExecutionsOutput, error) {
+	return &codepipeline.ListPipelineExecutionsOutput{PipelineExecutionSummaries: []types.PipelineExecutionSummary{
+		{PipelineExecutionId: aws.String("5"), Status: types.PipelineExecutionStatusStopped, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("eee555")}}},
+		{PipelineExecutionId: aws.String("4"), Status: types.PipelineExecutionStatusSuperseded, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+		{PipelineExecutionId: aws.String("3"), Status: types.PipelineExecutionStatusSucceeded, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+		{PipelineExecutionId: aws.String("2"), Status: types.PipelineExecutionStatusFailed, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("aaa111")}}},
+		{PipelineExecutionId: aws.String("1"), Status: types.PipelineExecutionStatusSucceeded, SourceRevisions: []types.SourceRevision{{RevisionId: aws.String("ccc333")}}},
+	}}, nil
+}
+
+// signWebhook will sign a webhook body like GitHub (X-Hub-Signature-256)
+func signWebhook(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(body))
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestVerifyWebhookSignature will test verifyWebhookSignature()
+func TestVerifyWebhookSignature(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		secret    string
+		body      string
+		signature string
+		expected  bool
+	}{
+		{"secret", `{"zen":"Keep it simple."}`, signWebhook("secret", `{"zen":"Keep it simple."}`), true},
+		{"secret", `{"zen":"Keep it simple."}`, signWebhook("other", `{"zen":"Keep it simple."}`), false},
+		{"secret", `{"zen":"Keep it simple."}`, signWebhook("secret", `{"zen":"Changed."}`), false},
+		{"secret", `{"zen":"Keep it simple."}`, "sha256=not-hex", false},
+		{"secret", `{"zen":"Keep it simple."}`, "", false},
+		{"", `{"zen":"Keep it simple."}`, signWebhook("", `{"zen":"Keep it simple."}`), false},
+	}
+
+	for _, test := range tests {
+		if output := verifyWebhookSignature(test.secret, []byte(test.body), test.signature); output != test.expected {
+			t.Errorf("%s Failed: [%s] [%s] inputted and [%t] expected, but got [%t]", t.Name(), test.secret, test.signature, test.expected, output)
+		}
+	}
+}
+
+// TestProcessWebhookBranchProtectionRule will test Handler.ProcessWebhook() backfilling the statuses of a new rule
+func TestProcessWebhookBranchProtectionRule(t *testing.T) {
+	h := newTestHandler(Config{
+		ContextPrefixTag:     "github-context-prefix",
+		DedupTable:           "dedup",
+		GithubAccessToken:    "1234567",
+		GithubMaxConcurrency: 1,
+		GithubWebhookSecret:  "secret",
+		Stage:                stageTesting,
+	})
+	h.deps.CodePipeline = &mockWebhookCodePipelineClient{}
+	h.deps.DynamoDB = &mockDedupDynamoClient{keys: make(map[string]bool)}
+
+	var posted []payload
+	var paths []string
+	newGithubServer(t, h, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"base":{"ref":"main"},"head":{"sha":"aaa111"}},{"base":{"ref":"feature"},"head":{"sha":"ccc333"}},` +
+				`{"base":{"ref":"main"},"head":{"sha":"ddd444"}},{"base":{"ref":"main"},"head":{"sha":"eee555"}}]`))
+			return
+		}
+		var received payload
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		posted = append(posted, received)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	body := `{"action":"created","repository":{"html_url":"https://github.com/mrz1836/codepipeline-to-github",` +
+		`"name":"codepipeline-to-github","owner":{"login":"mrz1836"}},"rule":{"name":"main","required_status_checks":["team-search/ci/web"]}}`
+	request := events.APIGatewayV2HTTPRequest{Body: body, Headers: map[string]string{
+		"x-github-event":      webhookEventBranchProtectionRule,
+		"x-hub-signature-256": signWebhook("secret", body),
+	}}
+
+	// Only the latest execution of the required pipeline (the context of its tag) is posted, on the head commits of
+	// the protected branch: superseded executions are skipped and stopped ones are errors
+	if response := h.ProcessWebhook(context.Background(), request); response.StatusCode != http.StatusOK {
+		t.Fatal("response was not as expected", response.StatusCode, response.Body)
+	} else if len(posted) != 2 || paths[0] != "/repos/mrz1836/codepipeline-to-github/statuses/eee555" ||
+		paths[1] != "/repos/mrz1836/codepipeline-to-github/statuses/aaa111" {
+		t.Fatal("statuses were not as expected", paths)
+	} else if posted[0].Context != "team-search/ci/web" || posted[0].State != githubStateError || posted[0].Description != "backfilled: stopped" {
+		t.Fatal("status was not as expected", posted[0])
+	} else if posted[1].State != githubStateSuccess || posted[1].Description != "backfilled: succeeded" {
+		t.Fatal("status was not as expected", posted[1])
+	}
+
+	// The statuses already reported are skipped
+	if response := h.ProcessWebhook(context.Background(), request); response.StatusCode != http.StatusOK || len(posted) != 2 {
+		t.Fatal("statuses should have been skipped", response.StatusCode, paths)
+	}
+
+	// Invalid signature
+	request.Headers["x-hub-signature-256"] = signWebhook("other", body)
+	if response := h.ProcessWebhook(context.Background(), request); response.StatusCode != http.StatusUnauthorized {
+		t.Fatal("response was not as expected", response.StatusCode)
+	}
+
+	// Other events are acknowledged
+	request.Headers["x-hub-signature-256"] = signWebhook("secret", body)
+	request.Headers["x-github-event"] = "push"
+	if response := h.ProcessWebhook(context.Background(), request); response.StatusCode != http.StatusAccepted || len(posted) != 2 {
+		t.Fatal("event should have been ignored", response.StatusCode)
+	}
+	request.Headers["x-github-event"] = webhookEventPing
+	if response := h.ProcessWebhook(context.Background(), request); response.StatusCode != http.StatusOK {
+		t.Fatal("ping should have been answered", response.StatusCode)
+	}
+}